@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+// client is one simulated player.
+type client struct {
+	cfg   *config
+	n     int
+	stats *stats
+
+	userID string
+	token  string
+}
+
+// run signs the client up and plays its games.
+func (c *client) run(ctx context.Context) error {
+	if err := c.signUp(ctx); err != nil {
+		return fmt.Errorf("client %d: sign up: %w", c.n, err)
+	}
+	for range c.cfg.games {
+		if err := c.playGame(ctx); err != nil {
+			return fmt.Errorf("client %d: %w", c.n, err)
+		}
+	}
+	return nil
+}
+
+func (c *client) signUp(ctx context.Context) error {
+	creds := map[string]string{
+		"email":    fmt.Sprintf("loadtest-%d-%d@loadtest.invalid", c.cfg.run, c.n),
+		"password": fmt.Sprintf("loadtest-%d", c.cfg.run),
+		"username": fmt.Sprintf("loadtest%d", c.n),
+	}
+	var user struct {
+		ID string `json:"id"`
+	}
+	if err := c.post(ctx, "/user/create", creds, &user); err != nil {
+		return err
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := c.post(ctx, "/user/login", creds, &login); err != nil {
+		return err
+	}
+	c.userID, c.token = user.ID, login.Token
+	return nil
+}
+
+// post sends a JSON request to the API and decodes the response into out. A 429 is retried once the
+// server's Retry-After has passed.
+func (c *client) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, _ := json.Marshal(body)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.baseURL+"/v1"+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			c.stats.rateLimited.Add(1)
+			select {
+			case <-time.After(time.Duration(max(wait, 1)) * time.Second):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("POST %s: %s: %s", path, resp.Status, bytes.TrimSpace(data))
+		}
+		return json.Unmarshal(data, out)
+	}
+}
+
+// playGame starts a practice game and plays it to the end.
+func (c *client) playGame(ctx context.Context) error {
+	var started struct {
+		GameID string `json:"gameID"`
+	}
+	if err := c.post(ctx, "/game/practice", map[string]int{"bots": c.cfg.bots}, &started); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.gameTimeout)
+	defer cancel()
+
+	wsURL := "ws" + strings.TrimPrefix(c.cfg.baseURL, "http") + "/v1/game/ws/" + started.GameID
+	conn, _, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{
+		HTTPHeader:   http.Header{"Authorization": {"Bearer " + c.token}},
+		Subprotocols: []string{"game.v1"},
+	})
+	if err != nil {
+		return fmt.Errorf("game %s: %w", started.GameID, err)
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(1 << 20)
+
+	p := &player{client: c, conn: conn, ctx: ctx, pending: make(map[string]time.Time)}
+	// the game started before the socket opened, so its first events are fetched with a resync
+	p.send("resync_from", map[string]interface{}{"seq": 0})
+	if err := p.play(); err != nil {
+		return fmt.Errorf("game %s: %w", started.GameID, err)
+	}
+	c.stats.games.Add(1)
+	conn.Close(websocket.StatusNormalClosure, "done")
+	return nil
+}
+
+// event is the part of a game event the client looks at.
+type event struct {
+	Type string `json:"type"`
+	User string `json:"user"`
+	Card *struct {
+		ID string `json:"id"`
+	} `json:"card"`
+	Seq uint64 `json:"seq"`
+}
+
+// frame is a message from the game socket: an event, or a reply to one of the client's messages.
+type frame struct {
+	event
+	ReqID  string  `json:"req_id"`
+	TS     int64   `json:"ts"`
+	Events []event `json:"events"`
+}
+
+// move is a message the client will send once it has thought about it.
+type move struct {
+	typ    string
+	fields map[string]interface{}
+}
+
+// player plays one game on one socket.
+type player struct {
+	*client
+	conn *websocket.Conn
+	ctx  context.Context
+
+	mu      sync.Mutex
+	pending map[string]time.Time // when each unanswered message was sent, by req_id
+	nextReq int
+
+	synced  bool    // whether the resync reply has arrived
+	early   []event // events that came in before it
+	lastSeq uint64
+	turns   int
+	next    *move
+	over    bool
+}
+
+// play reads the socket until the game is over.
+func (p *player) play() error {
+	for !p.over {
+		_, data, err := p.conn.Read(p.ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("didn't finish within %v", p.cfg.gameTimeout)
+			}
+			return err
+		}
+		var f frame
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("bad frame %s: %w", data, err)
+		}
+		p.receive(f)
+		if p.next != nil && !p.over {
+			m := p.next
+			p.next = nil
+			time.AfterFunc(p.cfg.think, func() { p.send(m.typ, m.fields) })
+		}
+	}
+	return nil
+}
+
+func (p *player) receive(f frame) {
+	if f.ReqID != "" {
+		p.answered(f.ReqID, f.Type == "error")
+	}
+	switch f.Type {
+	case "ping":
+		p.send("pong", map[string]interface{}{"ts": f.TS})
+	case "resync":
+		p.synced = true
+		for _, ev := range append(f.Events, p.early...) {
+			p.handle(ev)
+		}
+		p.early = nil
+	case "game_handshake", "ack", "error", "pong":
+	default:
+		if !p.synced {
+			p.early = append(p.early, f.event)
+			return
+		}
+		p.handle(f.event)
+	}
+}
+
+// handle follows one game event, noting missed events and choosing the client's next move.
+func (p *player) handle(ev event) {
+	if ev.Seq != 0 {
+		if ev.Seq <= p.lastSeq {
+			return
+		}
+		p.stats.events.Add(1)
+		if gap := ev.Seq - p.lastSeq - 1; gap > 0 {
+			p.stats.dropped.Add(gap)
+		}
+		p.lastSeq = ev.Seq
+	}
+	switch game.GameEventType(ev.Type) {
+	case game.EventPlayerTurn:
+		p.next = nil
+		if ev.User != p.userID {
+			return
+		}
+		p.turns++
+		if p.turns > p.cfg.cambiaAfter {
+			p.next = &move{typ: "action_cambia"}
+		} else {
+			p.next = &move{typ: "action_draw_stockpile"}
+		}
+	case game.EventPrivateDrawStock:
+		if ev.Card != nil {
+			p.next = &move{typ: "action_discard", fields: map[string]interface{}{"card": map[string]string{"id": ev.Card.ID}}}
+		}
+	case game.EventPlayerSpecialChoice:
+		if ev.User == p.userID {
+			p.next = &move{typ: "action_special", fields: map[string]interface{}{"special": "skip"}}
+		}
+	case game.EventRoundReveal, game.EventGameHalted:
+		p.over = true
+	}
+}
+
+// send writes a message with a fresh req_id, and times the server's reply to it.
+func (p *player) send(typ string, fields map[string]interface{}) {
+	p.mu.Lock()
+	p.nextReq++
+	reqID := strconv.Itoa(p.nextReq)
+	p.pending[reqID] = time.Now()
+	p.mu.Unlock()
+
+	msg := map[string]interface{}{"type": typ, "req_id": reqID}
+	for k, v := range fields {
+		msg[k] = v
+	}
+	data, _ := json.Marshal(msg)
+	if err := p.conn.Write(p.ctx, websocket.MessageText, data); err != nil {
+		p.mu.Lock()
+		delete(p.pending, reqID)
+		p.mu.Unlock()
+	}
+}
+
+// answered records the latency of the reply to a message.
+func (p *player) answered(reqID string, rejected bool) {
+	p.mu.Lock()
+	sent, ok := p.pending[reqID]
+	delete(p.pending, reqID)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	p.stats.observe(time.Since(sent))
+	if rejected {
+		p.stats.rejected.Add(1)
+	}
+}
@@ -0,0 +1,91 @@
+// cmd/loadtest/main.go
+
+// Command loadtest plays scripted games against a running server with a swarm of simulated clients, and
+// reports how long the server took to acknowledge their moves and how many events they missed.
+//
+// Each client signs up, starts a practice game against bots, and plays it over the game socket: it draws
+// and discards, skips card abilities, and calls Cambia after a few turns. The server's sign-up and
+// practice game rate limits should be raised for the run, e.g.
+//
+//	RATE_LIMIT_AUTH_IP=100000/1s RATE_LIMIT_LOBBY_CREATE_IP=100000/1s RATE_LIMIT_LOBBY_CREATE_USER=1000/1s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.baseURL, "url", "http://localhost:8080", "base URL of the server")
+	flag.IntVar(&cfg.clients, "clients", 100, "number of simulated clients")
+	flag.IntVar(&cfg.games, "games", 1, "games each client plays, one after another")
+	flag.IntVar(&cfg.bots, "bots", 1, "bots in each practice game (1-3)")
+	flag.IntVar(&cfg.cambiaAfter, "cambia-after", 3, "turns a client plays before calling Cambia")
+	flag.DurationVar(&cfg.think, "think", 200*time.Millisecond, "pause before each move")
+	flag.DurationVar(&cfg.ramp, "ramp", 10*time.Second, "time over which the clients are started")
+	flag.DurationVar(&cfg.gameTimeout, "game-timeout", 2*time.Minute, "how long a game may take before the client gives up on it")
+	timeout := flag.Duration("timeout", 10*time.Minute, "how long the whole run may take")
+	flag.Parse()
+	cfg.baseURL = strings.TrimSuffix(cfg.baseURL, "/")
+	if cfg.clients < 1 || cfg.games < 1 || cfg.bots < 1 || cfg.bots > 3 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg.run = time.Now().UnixNano()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	log.Printf("starting %d clients against %s over %v", cfg.clients, cfg.baseURL, cfg.ramp)
+	st := newStats()
+	started := time.Now()
+	var wg sync.WaitGroup
+	for i := range cfg.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delay := time.Duration(0)
+			if cfg.clients > 1 {
+				delay = cfg.ramp * time.Duration(i) / time.Duration(cfg.clients-1)
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			c := &client{cfg: &cfg, n: i, stats: st}
+			if err := c.run(ctx); err != nil {
+				st.fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Print(st.report(time.Since(started)))
+	if st.failed() {
+		os.Exit(1)
+	}
+}
+
+// config is the run's settings, shared by every client.
+type config struct {
+	baseURL     string
+	clients     int
+	games       int
+	bots        int
+	cambiaAfter int
+	think       time.Duration
+	ramp        time.Duration
+	gameTimeout time.Duration
+	// run tells this run's accounts apart from those of earlier runs against the same server.
+	run int64
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxFailuresShown caps how many client failures the report lists.
+const maxFailuresShown = 10
+
+// stats collects the results of every client.
+type stats struct {
+	games       atomic.Int64
+	events      atomic.Uint64
+	dropped     atomic.Uint64 // events skipped over, going by their seq
+	rejected    atomic.Int64  // messages answered with an error frame
+	rateLimited atomic.Int64  // HTTP requests retried after a 429
+
+	mu        sync.Mutex
+	latencies []time.Duration // from sending a message to its reply
+	failures  []error
+}
+
+func newStats() *stats {
+	return &stats{}
+}
+
+func (s *stats) observe(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, d)
+}
+
+func (s *stats) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, err)
+}
+
+// failed reports whether the run should count as a regression: a client gave up, or events went missing.
+func (s *stats) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.failures) > 0 || s.dropped.Load() > 0
+}
+
+// percentile returns the latency below which p (0-1) of the sorted latencies fall.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// report summarizes the run.
+func (s *stats) report(elapsed time.Duration) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sorted := slices.Clone(s.latencies)
+	slices.Sort(sorted)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "elapsed:        %v\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "games played:   %d\n", s.games.Load())
+	fmt.Fprintf(&b, "clients failed: %d\n", len(s.failures))
+	fmt.Fprintf(&b, "events:         %d received, %d dropped\n", s.events.Load(), s.dropped.Load())
+	fmt.Fprintf(&b, "messages:       %d answered, %d rejected\n", len(sorted), s.rejected.Load())
+	fmt.Fprintf(&b, "rate limited:   %d\n", s.rateLimited.Load())
+	if len(sorted) > 0 {
+		fmt.Fprintf(&b, "reply latency:  p50 %v, p90 %v, p99 %v, max %v\n",
+			percentile(sorted, 0.50), percentile(sorted, 0.90), percentile(sorted, 0.99), sorted[len(sorted)-1])
+	}
+	for i, err := range s.failures {
+		if i == maxFailuresShown {
+			fmt.Fprintf(&b, "  ... and %d more\n", len(s.failures)-maxFailuresShown)
+			break
+		}
+		fmt.Fprintf(&b, "  %v\n", err)
+	}
+	return b.String()
+}
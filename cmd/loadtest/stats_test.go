@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.99: 99 * time.Millisecond, 1: 100 * time.Millisecond, 0: time.Millisecond} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("expected p%v to be %v, got %v", p*100, want, got)
+		}
+	}
+	if percentile(nil, 0.5) != 0 {
+		t.Error("expected 0 for no samples")
+	}
+}
+
+func TestDroppedEventsFailTheRun(t *testing.T) {
+	s := newStats()
+	c := &client{cfg: &config{}, userID: "me", stats: s}
+	p := &player{client: c, synced: true}
+	for _, seq := range []uint64{1, 2, 5, 5, 6} {
+		p.handle(event{Type: "player_discard", Seq: seq})
+	}
+	if s.events.Load() != 4 || s.dropped.Load() != 2 {
+		t.Fatalf("expected 4 events and 2 dropped, got %d and %d", s.events.Load(), s.dropped.Load())
+	}
+	if !s.failed() || !strings.Contains(s.report(time.Second), "2 dropped") {
+		t.Fatalf("expected the run to fail on dropped events, got:\n%s", s.report(time.Second))
+	}
+}
@@ -2,67 +2,251 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/jason-s-yu/cambia/internal/analytics"
 	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/cluster"
 	"github.com/jason-s-yu/cambia/internal/database"
-	"github.com/jason-s-yu/cambia/internal/game"
 	"github.com/jason-s-yu/cambia/internal/handlers"
+	"github.com/jason-s-yu/cambia/internal/logging"
+	"github.com/jason-s-yu/cambia/internal/mail"
 	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/presence"
+	"github.com/jason-s-yu/cambia/internal/router"
+	"github.com/jason-s-yu/cambia/internal/season"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/sirupsen/logrus"
 )
 
+// apiVersion prefixes every HTTP and WebSocket endpoint.
+const apiVersion = "/v1"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		database.ConnectDB()
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	auth.Init()
 	database.ConnectDB()
+	migrateOnStart()
+	// session revocation, bans, and login fingerprints live in Postgres
+	if _, inMemory := database.Users.(*database.Memory); !inMemory {
+		auth.SessionValidator = database.CheckSessionNotRevoked
+		handlers.GuestBanCheck = database.FingerprintBanned
+	}
+	go season.RunScheduler(context.Background(), time.Minute)
+	startAnalytics()
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.DebugLevel)
+	// handlers outside the request path log through the standard logrus logger
+	logControl := logging.NewControl(os.Getenv("LOG_FILE"), logger, logrus.StandardLogger())
 
-	mux := http.NewServeMux()
+	api := router.New()
+	api.Use(middleware.LogMiddleware(logger), middleware.Recover(logger))
+	admin := api.With(middleware.RequireRole(auth.RoleAdmin))
+	mod := api.With(middleware.RequireRole(auth.RoleModerator))
+
+	// rate limits on the endpoints a single client could abuse; each group shares one set of buckets
+	authLimited := api.With(middleware.RateLimit(
+		middleware.RateRuleFromEnv("RATE_LIMIT_AUTH_IP", middleware.RateRule{Burst: 10, Per: time.Minute}),
+		middleware.RateRule{},
+	))
+	lobbyCreateLimited := api.With(middleware.RateLimit(
+		middleware.RateRuleFromEnv("RATE_LIMIT_LOBBY_CREATE_IP", middleware.RateRule{Burst: 20, Per: time.Minute}),
+		middleware.RateRuleFromEnv("RATE_LIMIT_LOBBY_CREATE_USER", middleware.RateRule{Burst: 5, Per: time.Minute}),
+	))
+	lobbyJoinLimited := api.With(middleware.RateLimit(
+		middleware.RateRuleFromEnv("RATE_LIMIT_LOBBY_JOIN_IP", middleware.RateRule{Burst: 60, Per: time.Minute}),
+		middleware.RateRuleFromEnv("RATE_LIMIT_LOBBY_JOIN_USER", middleware.RateRule{Burst: 20, Per: time.Minute}),
+	))
 
 	// user endpoints
-	mux.HandleFunc("/user/create", handlers.CreateUserHandler)
-	mux.HandleFunc("/user/login", handlers.LoginHandler)
+	authLimited.HandleFunc("/user/create", handlers.CreateUserHandler)
+	authLimited.HandleFunc("/user/login", handlers.LoginHandler)
+	api.HandleFunc("/user/delete", handlers.DeleteAccountHandler)
+	api.HandleFunc("/user/export", handlers.ExportAccountHandler)
+	api.HandleFunc("/user/penalty", handlers.PenaltyStatusHandler)
+	authLimited.HandleFunc("/user/password/reset", handlers.PasswordResetRequestHandler(mail.FromEnv()))
+	authLimited.HandleFunc("/user/password/reset/confirm", handlers.PasswordResetConfirmHandler)
+	api.HandleFunc("/user/notifications", handlers.NotificationsHandler)
+	api.HandleFunc("/user/notifications/read", handlers.MarkNotificationsReadHandler)
+	api.HandleFunc("/user/messages", handlers.DirectMessagesHandler)
+	api.HandleFunc("/user/messages/", handlers.DirectMessagesHandler)
+	api.HandleFunc("/user/", handlers.UserHandler)
 
 	// friend endpoints
-	mux.HandleFunc("/friends/add", handlers.AddFriendHandler)
-	mux.HandleFunc("/friends/accept", handlers.AcceptFriendHandler)
-	mux.HandleFunc("/friends/list", handlers.ListFriendsHandler)
-	mux.HandleFunc("/friends/remove", handlers.RemoveFriendHandler)
+	api.HandleFunc("/friends/add", handlers.AddFriendHandler)
+	api.HandleFunc("/friends/accept", handlers.AcceptFriendHandler)
+	api.HandleFunc("/friends/list", handlers.ListFriendsHandler)
+	api.HandleFunc("/friends/remove", handlers.RemoveFriendHandler)
 
 	// game websocket
 	srv := handlers.NewGameServer()
+	sharePresence(srv.Presence)
+	shareGames(srv)
+	restored := srv.RestoreGames(context.Background())
+	if len(restored) > 0 {
+		log.Printf("restored %d games from their checkpoints", len(restored))
+	}
+	if n, err := database.Games.AbandonInterruptedGames(context.Background(), restored); err != nil {
+		log.Printf("%v", err)
+	} else if n > 0 {
+		log.Printf("marked %d games interrupted by the last shutdown as abandoned", n)
+	}
 
-	// lobby manager
-	ls := game.NewLobbyStore()
+	api.HandleFunc("GET /game/ws/{game_id}", handlers.GameWSHandler(logger, srv))
+	api.HandleFunc("GET /game/spectate/{game_id}", handlers.SpectateWSHandler(logger, srv))
+	api.HandleFunc("POST /game/reconnect/{game_id}", handlers.ReconnectGameHandler(srv))
+	admin.HandleFunc("POST /game/create", handlers.CreateGameHandler(srv))
+	lobbyCreateLimited.HandleFunc("POST /game/practice", handlers.PracticeGameHandler(srv))
+	lobbyCreateLimited.HandleFunc("POST /game/hotseat", handlers.HotSeatGameHandler(srv))
+	lobbyCreateLimited.HandleFunc("POST /challenge/daily", handlers.StartDailyChallengeHandler(srv))
+	api.HandleFunc("GET /challenge/daily", handlers.DailyChallengeBoardHandler)
+	api.HandleFunc("/game/", handlers.GameResultHandler)
 
-	mux.Handle("/game/ws/", middleware.LogMiddleware(logger)(http.HandlerFunc(
-		handlers.GameWSHandler(logger, srv),
-	)))
+	// per-user notifications and friend presence
+	api.HandleFunc("GET /user/ws", handlers.UserWSHandler(logger, srv))
+
+	// block list
+	api.HandleFunc("/user/block", handlers.BlockUserHandler(srv))
+	api.HandleFunc("/user/unblock", handlers.UnblockUserHandler(srv))
+	api.HandleFunc("/user/blocks", handlers.ListBlocksHandler)
+
+	// saved house rule templates
+	api.HandleFunc("GET /user/rule-templates", handlers.ListRuleTemplatesHandler)
+	api.HandleFunc("POST /user/rule-templates", handlers.SaveRuleTemplateHandler)
+	api.HandleFunc("DELETE /user/rule-templates/{template_id}", handlers.DeleteRuleTemplateHandler)
+
+	// matchmaking
+	go srv.Matchmaker.Run(context.Background())
+	api.HandleFunc("GET /matchmaking/ws", handlers.MatchmakingWSHandler(logger, srv))
+	api.HandleFunc("GET /matchmaking/preview", handlers.RatingPreviewHandler(srv))
 
 	// lobby endpoints
-	mux.Handle("/lobby/create", middleware.LogMiddleware(logger)(http.HandlerFunc(
-		handlers.CreateLobbyHandler(srv),
-	)))
-	mux.Handle("/lobby/list", middleware.LogMiddleware(logger)(http.HandlerFunc(
-		handlers.ListLobbiesHandler(srv),
-	)))
+	lobbyCreateLimited.HandleFunc("POST /lobby/create", handlers.CreateLobbyHandler(srv))
+	api.HandleFunc("PATCH /lobby/{lobby_id}", handlers.UpdateLobbyHandler(srv))
+	admin.HandleFunc("GET /lobby/list", handlers.ListLobbiesHandler(srv))
+	api.HandleFunc("GET /lobby/public", handlers.PublicLobbiesHandler(srv))
+	api.HandleFunc("GET /lobby/ranked-profiles", handlers.RankedProfilesHandler)
+	api.HandleFunc("GET /lobby/presets", handlers.RulePresetsHandler)
+
+	// leaderboard endpoints
+	api.HandleFunc("/leaderboard/", handlers.LeaderboardHandler)
+	api.HandleFunc("/season/", handlers.SeasonHandler)
+
+	// public site-wide stats
+	api.HandleFunc("GET /stats", handlers.PublicStatsHandler())
+
+	// tournament endpoints
+	api.HandleFunc("/tournament/", handlers.TournamentHandler(srv))
+
+	// admin & moderator endpoints
+	admin.HandleFunc("/admin/lobby/", handlers.AdminDeleteLobbyHandler(srv))
+	admin.HandleFunc("/admin/game/", handlers.AdminInspectGameHandler(srv))
+	admin.HandleFunc("GET /admin/games", handlers.AdminListGamesHandler(srv))
+	admin.HandleFunc("GET /admin/game/{game_id}/state", handlers.AdminGameStateHandler(srv))
+	admin.HandleFunc("POST /admin/game/{game_id}/end", handlers.AdminEndGameHandler(srv))
+	admin.HandleFunc("POST /admin/user/{user_id}/disconnect", handlers.AdminDisconnectUserHandler(srv))
+	admin.HandleFunc("/admin/user/role", handlers.AdminSetUserRoleHandler)
+	admin.HandleFunc("/admin/season", handlers.AdminCreateSeasonHandler)
+	admin.HandleFunc("/admin/bans", handlers.AdminBansHandler(srv))
+	admin.HandleFunc("/admin/bans/", handlers.AdminBansHandler(srv))
+	admin.HandleFunc("GET /admin/metrics/sockets", handlers.AdminSocketMetricsHandler)
+	admin.HandleFunc("GET /admin/audit", handlers.AdminAuditLogHandler)
+	admin.HandleFunc("GET /admin/reports/play-stats", handlers.AdminPlayStatsHandler)
+	admin.HandleFunc("GET /admin/features", handlers.AdminFeaturesHandler)
+	admin.HandleFunc("/admin/features/{name}", handlers.AdminSetFeatureHandler)
+	admin.HandleFunc("/admin/logging", handlers.AdminLoggingHandler(logControl))
+	admin.HandleFunc("POST /admin/announcement", handlers.AdminAnnouncementHandler(srv))
+	mod.HandleFunc("/mod/lobby/", handlers.ModKickLobbyUserHandler(srv))
+	mod.HandleFunc("/mod/reports", handlers.ModReportsHandler(srv))
+	mod.HandleFunc("/mod/reports/", handlers.ModReportsHandler(srv))
+	mod.HandleFunc("/mod/user/", handlers.ModSanctionUserHandler(srv))
+	mod.HandleFunc("GET /mod/anticheat", handlers.ModAnticheatFlagsHandler)
+	mod.HandleFunc("GET /mod/smurf-signals", handlers.ModSmurfSignalsHandler)
 
 	// lobby ws
-	mux.Handle("/lobby/ws/", middleware.LogMiddleware(logger)(http.HandlerFunc(
-		handlers.LobbyWSHandler(logger, ls, srv),
-	)))
+	lobbyJoinLimited.HandleFunc("GET /lobby/ws/{lobby_id}", handlers.LobbyWSHandler(logger, srv))
+
+	// every endpoint is served under the API version prefix; the unversioned paths remain as deprecated
+	// aliases until clients have moved over
+	mux := http.NewServeMux()
+	router.Mount(mux, apiVersion, api)
+	mux.Handle("/", middleware.Deprecated(apiVersion)(api))
 
 	addr := ":8080"
+	if len(autocertDomains()) > 0 {
+		addr = ":443"
+	}
 	if port := os.Getenv("PORT"); port != "" {
 		addr = ":" + port
 	}
 	logger.Infof("Running on %s", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	if err := serve(addr, middleware.CORS(middleware.AllowedOrigins)(mux)); err != nil {
 		log.Fatalf("server exited: %v", err)
 	}
 }
+
+// sharePresence shares presence with the server's other nodes through Redis when REDIS_ADDR is set. Nodes
+// are told apart by NODE_ID, which defaults to the host name and should stay the same across restarts.
+func sharePresence(t *presence.Tracker) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return
+	}
+	node := os.Getenv("NODE_ID")
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+	ctx := context.Background()
+	shared, err := presence.NewRedisShared(ctx, addr, os.Getenv("REDIS_PASSWORD"), node)
+	if err != nil {
+		log.Fatalf("unable to share presence: %v", err)
+	}
+	t.Shared = shared
+	go func() {
+		if err := shared.Listen(ctx, t); err != nil {
+			log.Printf("stopped receiving presence from other nodes: %v", err)
+		}
+	}()
+	log.Printf("Sharing presence through redis at %s as node %s", addr, node)
+}
+
+// startAnalytics sends gameplay analytics events to the sink ANALYTICS_SINK names, if any; see
+// analytics.FromEnv.
+func startAnalytics() {
+	sink, err := analytics.FromEnv()
+	if err != nil {
+		log.Fatalf("unable to start analytics: %v", err)
+	}
+	if sink == nil {
+		return
+	}
+	analytics.Use(analytics.NewPipeline(sink))
+	log.Printf("Sending analytics events to the %s sink", os.Getenv("ANALYTICS_SINK"))
+}
+
+// shareGames registers the games this node hosts in Redis when REDIS_ADDR is set, so game sockets that
+// reach another node are relayed here. NODE_URL is the base URL other nodes reach this one at.
+func shareGames(srv *handlers.GameServer) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return
+	}
+	nodeURL := os.Getenv("NODE_URL")
+	if nodeURL == "" {
+		log.Fatalf("NODE_URL must be set when REDIS_ADDR is")
+	}
+	registry, err := cluster.NewRedisRegistry(context.Background(), addr, os.Getenv("REDIS_PASSWORD"), nodeURL)
+	if err != nil {
+		log.Fatalf("unable to register games: %v", err)
+	}
+	srv.Registry = registry
+}
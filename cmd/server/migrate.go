@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    revert the last n applied migrations (default 1)
+  status      list migrations and whether each is applied
+  force <v>   record migrations up to and including v as applied without running them,
+              for a database whose schema was set up by hand`
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	all, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		log.Printf("failed to load migrations: %v", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, all)
+		if err != nil {
+			log.Printf("migrate up: %v", err)
+			return 1
+		}
+		log.Printf("%d migration(s) applied", len(applied))
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		reverted, err := database.MigrateDown(ctx, all, n)
+		if err != nil {
+			log.Printf("migrate down: %v", err)
+			return 1
+		}
+		log.Printf("%d migration(s) reverted", len(reverted))
+	case "status":
+		applied, err := database.AppliedMigrations(ctx)
+		if err != nil {
+			log.Printf("migrate status: %v", err)
+			return 1
+		}
+		appliedAt := make(map[int]string, len(applied))
+		for _, m := range applied {
+			appliedAt[m.Version] = m.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		for _, m := range all {
+			status, ok := appliedAt[m.Version]
+			if !ok {
+				status = "pending"
+			}
+			fmt.Printf("%4d  %-24s %s\n", m.Version, m.Name, status)
+		}
+	case "force":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if err := database.ForceMigrations(ctx, all, version); err != nil {
+			log.Printf("migrate force: %v", err)
+			return 1
+		}
+		log.Printf("migrations up to %d recorded as applied", version)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+// migrateOnStart applies pending migrations before the server starts serving, if MIGRATE_ON_START=true.
+func migrateOnStart() {
+	if os.Getenv("MIGRATE_ON_START") != "true" {
+		return
+	}
+	all, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := database.MigrateUp(context.Background(), all); err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// cmd/server/tls.go
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultAutocertCache is where Let's Encrypt certificates are kept between restarts, unless
+// TLS_AUTOCERT_CACHE says otherwise.
+const defaultAutocertCache = "certs"
+
+// serve runs the server on addr until it fails. It serves HTTPS when configured to:
+//
+//   - TLS_CERT_FILE and TLS_KEY_FILE name a certificate and its key in PEM.
+//   - TLS_AUTOCERT_DOMAINS lists the domains, comma-separated, to get certificates for from Let's Encrypt.
+//     HTTPS is served on addr, and plain HTTP on :80 answers the ACME challenge and redirects everything
+//     else to HTTPS. TLS_AUTOCERT_EMAIL is given to Let's Encrypt for expiry notices, and certificates are
+//     cached in TLS_AUTOCERT_CACHE.
+//
+// Otherwise it serves plain HTTP, e.g. behind a reverse proxy that terminates TLS.
+func serve(addr string, h http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	domains := autocertDomains()
+
+	switch {
+	case certFile != "" || keyFile != "":
+		if certFile == "" || keyFile == "" {
+			log.Fatalf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		if len(domains) > 0 {
+			log.Fatalf("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+		}
+		log.Printf("serving HTTPS with the certificate in %s", certFile)
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	case len(domains) > 0:
+		cache := os.Getenv("TLS_AUTOCERT_CACHE")
+		if cache == "" {
+			cache = defaultAutocertCache
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cache),
+			Email:      os.Getenv("TLS_AUTOCERT_EMAIL"),
+		}
+		srv.TLSConfig = m.TLSConfig()
+		go func() {
+			challenge := &http.Server{Addr: ":80", Handler: m.HTTPHandler(nil), ReadHeaderTimeout: 10 * time.Second}
+			if err := challenge.ListenAndServe(); err != nil {
+				log.Fatalf("ACME challenge server exited: %v", err)
+			}
+		}()
+		log.Printf("serving HTTPS with Let's Encrypt certificates for %s", strings.Join(domains, ", "))
+		return srv.ListenAndServeTLS("", "")
+	default:
+		return srv.ListenAndServe()
+	}
+}
+
+// autocertDomains returns the domains in TLS_AUTOCERT_DOMAINS.
+func autocertDomains() []string {
+	var domains []string
+	for _, d := range strings.Split(os.Getenv("TLS_AUTOCERT_DOMAINS"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
@@ -3,14 +3,15 @@ module github.com/jason-s-yu/cambia
 go 1.24.0
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/google/uuid v1.6.0
 	github.com/jackc/pgx/v5 v5.7.2
 	github.com/joho/godotenv v1.5.1
+	github.com/sirupsen/logrus v1.9.3
 )
 
 require (
-	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
-	github.com/sirupsen/logrus v1.9.3 // indirect
+	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
 
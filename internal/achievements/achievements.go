@@ -0,0 +1,126 @@
+// internal/achievements/achievements.go
+package achievements
+
+import (
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+// Definition describes an achievement and how a finished game advances a player towards it.
+type Definition struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Target is the progress at which the achievement unlocks.
+	Target int `json:"target"`
+
+	// progress returns how much the game counts towards the achievement for the player.
+	progress func(rec *database.GameRecord, playerID uuid.UUID) int
+}
+
+// Definitions lists every achievement, in display order.
+var Definitions = []Definition{
+	{
+		ID:          "first_win",
+		Name:        "First Blood",
+		Description: "Win a game.",
+		Target:      1,
+		progress:    func(rec *database.GameRecord, id uuid.UUID) int { return boolInt(won(rec, id)) },
+	},
+	{
+		ID:          "veteran",
+		Name:        "Veteran",
+		Description: "Play 100 games.",
+		Target:      100,
+		progress:    func(*database.GameRecord, uuid.UUID) int { return 1 },
+	},
+	{
+		ID:          "in_the_red",
+		Name:        "In the Red",
+		Description: "Win a game with a negative score.",
+		Target:      1,
+		progress: func(rec *database.GameRecord, id uuid.UUID) int {
+			return boolInt(won(rec, id) && rec.FinalScores[id] < 0)
+		},
+	},
+	{
+		ID:          "called_it",
+		Name:        "Called It",
+		Description: "Win 10 games after calling Cambia.",
+		Target:      10,
+		progress: func(rec *database.GameRecord, id uuid.UUID) int {
+			return boolInt(rec.CambiaCallerID == id && won(rec, id))
+		},
+	},
+	{
+		ID:          "quick_hands",
+		Name:        "Quick Hands",
+		Description: "Snap successfully 100 times.",
+		Target:      100,
+		progress: func(rec *database.GameRecord, id uuid.UUID) int {
+			return countActions(rec, id, "player_snap_success", "")
+		},
+	},
+	{
+		ID:          "kingmaker",
+		Name:        "Kingmaker",
+		Description: "Swap cards with a King 50 times.",
+		Target:      50,
+		progress: func(rec *database.GameRecord, id uuid.UUID) int {
+			return countActions(rec, id, "player_special_action", "swap_peek_swap")
+		},
+	},
+}
+
+// Lookup returns the definition with the given ID.
+func Lookup(id string) (Definition, bool) {
+	for _, d := range Definitions {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Evaluate returns, by achievement ID, the progress a finished game earned the player. Achievements the
+// game didn't advance are omitted.
+func Evaluate(rec *database.GameRecord, playerID uuid.UUID) map[string]int {
+	out := make(map[string]int)
+	for _, d := range Definitions {
+		if n := d.progress(rec, playerID); n > 0 {
+			out[d.ID] = n
+		}
+	}
+	return out
+}
+
+func won(rec *database.GameRecord, id uuid.UUID) bool {
+	for _, w := range rec.Winners {
+		if w == id {
+			return true
+		}
+	}
+	return false
+}
+
+// countActions counts the player's logged actions of the given type and, if special is set, special action.
+func countActions(rec *database.GameRecord, id uuid.UUID, actionType, special string) int {
+	n := 0
+	for _, a := range rec.Actions {
+		if a.ActorID != id || a.ActionType != actionType {
+			continue
+		}
+		if special != "" && a.Payload["special"] != special {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package achievements
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	winner, loser := uuid.New(), uuid.New()
+	rec := &database.GameRecord{
+		FinalScores:    map[uuid.UUID]int{winner: -1, loser: 12},
+		Winners:        []uuid.UUID{winner},
+		CambiaCallerID: winner,
+		Actions: []models.GameAction{
+			{ActionType: "player_snap_success", ActorID: loser},
+			{ActionType: "player_snap_success", ActorID: loser},
+			{ActionType: "player_snap_fail", ActorID: winner},
+			{ActionType: "player_special_action", ActorID: winner, Payload: map[string]interface{}{"special": "swap_peek_swap"}},
+			{ActionType: "player_special_action", ActorID: winner, Payload: map[string]interface{}{"special": "swap_blind"}},
+		},
+	}
+
+	got := Evaluate(rec, winner)
+	want := map[string]int{"first_win": 1, "veteran": 1, "in_the_red": 1, "called_it": 1, "kingmaker": 1}
+	if len(got) != len(want) {
+		t.Fatalf("winner: expected %v, got %v", want, got)
+	}
+	for id, n := range want {
+		if got[id] != n {
+			t.Fatalf("winner: expected %v, got %v", want, got)
+		}
+	}
+
+	got = Evaluate(rec, loser)
+	if len(got) != 2 || got["veteran"] != 1 || got["quick_hands"] != 2 {
+		t.Fatalf("loser: expected veteran and two snaps, got %v", got)
+	}
+}
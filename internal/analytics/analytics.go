@@ -0,0 +1,154 @@
+// internal/analytics/analytics.go
+
+// Package analytics emits structured gameplay events — actions and how long they took to handle, game
+// starts and ends, and the house rules games were played under — to a pluggable sink, for product analysis
+// outside the application logs. Events are batched and written in the background; emitting never blocks
+// gameplay, and events are dropped rather than queued without bound if the sink falls behind.
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The types of Event.
+const (
+	EventGameStart = "game_start"
+	EventGameEnd   = "game_end"
+	EventAction    = "action"
+)
+
+const (
+	// queueSize is how many events wait for the sink before new ones are dropped.
+	queueSize = 4096
+	// batchSize is the most events written to the sink at once.
+	batchSize = 100
+	// flushInterval is the longest an event waits for its batch to fill.
+	flushInterval = time.Second
+	// writeTimeout bounds a single write to the sink.
+	writeTimeout = 10 * time.Second
+)
+
+// Event is one gameplay analytics event. Fields that don't apply to its type are left out.
+type Event struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	GameID uuid.UUID `json:"gameID"`
+	UserID uuid.UUID `json:"userID,omitzero"`
+
+	// Action is the message type of an action, e.g. "action_draw_stockpile", and LatencyMs how long the
+	// server took to handle it.
+	Action    string  `json:"action,omitempty"`
+	LatencyMs float64 `json:"latencyMs,omitzero"`
+
+	// DurationMs is how long a finished game lasted.
+	DurationMs int64 `json:"durationMs,omitzero"`
+	Players    int   `json:"players,omitzero"`
+	Ranked     bool  `json:"ranked,omitempty"`
+	Practice   bool  `json:"practice,omitempty"`
+	// HouseRules are the rules a game was played under.
+	HouseRules interface{} `json:"houseRules,omitempty"`
+
+	// Detail holds anything else particular to the event, e.g. the step of a card ability.
+	Detail map[string]interface{} `json:"detail,omitempty"`
+}
+
+// Sink stores batches of events, e.g. in a file or a Kafka topic.
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+// Pipeline batches emitted events and writes them to its sink in the background.
+type Pipeline struct {
+	sink    Sink
+	events  chan Event
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// NewPipeline starts a pipeline writing to sink.
+func NewPipeline(sink Sink) *Pipeline {
+	p := &Pipeline{sink: sink, events: make(chan Event, queueSize), done: make(chan struct{})}
+	go p.run()
+	return p
+}
+
+// Emit queues an event, stamping it with the current time if it has none. If the queue is full, the event
+// is dropped.
+func (p *Pipeline) Emit(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	select {
+	case p.events <- ev:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events were dropped because the queue was full.
+func (p *Pipeline) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Close writes the events still queued and stops the pipeline. Nothing may be emitted after it's closed.
+func (p *Pipeline) Close() {
+	p.once.Do(func() { close(p.events) })
+	<-p.done
+}
+
+// run writes queued events in batches of up to batchSize, at least every flushInterval, until the
+// pipeline is closed.
+func (p *Pipeline) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]Event, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		defer cancel()
+		if err := p.sink.Write(ctx, batch); err != nil {
+			log.Printf("failed to write %d analytics events: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case ev, ok := <-p.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, ev)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// current is the pipeline Emit sends to, if any.
+var current atomic.Pointer[Pipeline]
+
+// Use makes p the pipeline events are emitted to. With nil, events are discarded.
+func Use(p *Pipeline) {
+	current.Store(p)
+}
+
+// Emit sends an event to the pipeline in use, if any.
+func Emit(ev Event) {
+	if p := current.Load(); p != nil {
+		p.Emit(ev)
+	}
+}
@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memorySink keeps the batches written to it.
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]Event
+}
+
+func (s *memorySink) Write(_ context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]Event(nil), events...))
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	sink := &memorySink{}
+	p := NewPipeline(sink)
+	gameID := uuid.New()
+	for i := 0; i < batchSize+1; i++ {
+		p.Emit(Event{Type: EventAction, GameID: gameID, Action: "action_draw_stockpile"})
+	}
+	p.Close()
+
+	if len(sink.batches) != 2 || len(sink.batches[0]) != batchSize || len(sink.batches[1]) != 1 {
+		t.Fatalf("expected a full batch and the rest flushed on close, got %d batches", len(sink.batches))
+	}
+	if sink.batches[0][0].Time.IsZero() {
+		t.Fatal("expected emitted events to be stamped with the time")
+	}
+}
+
+func TestEmitWithoutPipeline(t *testing.T) {
+	Use(nil)
+	Emit(Event{Type: EventGameStart}) // must not panic
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analytics.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{{Type: EventGameStart, GameID: uuid.New(), Players: 4}, {Type: EventGameEnd, GameID: uuid.New(), DurationMs: 1000}}
+	if err := sink.Write(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], "userID") || strings.Contains(lines[0], "durationMs") {
+		t.Fatalf("expected one line per event without unset fields, got %q", lines)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var path, contentType string
+	var body struct {
+		Records []struct {
+			Key   string `json:"key"`
+			Value Event  `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+	}))
+	defer srv.Close()
+
+	gameID := uuid.New()
+	sink := &KafkaSink{URL: srv.URL, Topic: "cambia-analytics"}
+	if err := sink.Write(context.Background(), []Event{{Type: EventAction, GameID: gameID, Action: "action_cambia"}}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/topics/cambia-analytics" || contentType != "application/vnd.kafka.json.v2+json" {
+		t.Fatalf("expected a produce request to the topic, got %s (%s)", path, contentType)
+	}
+	if len(body.Records) != 1 || body.Records[0].Key != gameID.String() || body.Records[0].Value.Action != "action_cambia" {
+		t.Fatalf("expected the event keyed by its game, got %+v", body.Records)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if err := (&HTTPSink{URL: failing.URL}).Write(context.Background(), []Event{{Type: EventAction}}); err == nil {
+		t.Fatal("expected a non-2xx status to fail the write")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("ANALYTICS_SINK", "")
+	if sink, err := FromEnv(); sink != nil || err != nil {
+		t.Fatalf("expected no sink by default, got %v, %v", sink, err)
+	}
+	t.Setenv("ANALYTICS_SINK", "http")
+	t.Setenv("ANALYTICS_URL", "")
+	if _, err := FromEnv(); err == nil {
+		t.Fatal("expected the http sink to need ANALYTICS_URL")
+	}
+	t.Setenv("ANALYTICS_SINK", "carrier-pigeon")
+	if _, err := FromEnv(); err == nil {
+		t.Fatal("expected an unknown sink to be refused")
+	}
+}
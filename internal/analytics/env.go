@@ -0,0 +1,47 @@
+// internal/analytics/env.go
+package analytics
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// FromEnv returns the sink ANALYTICS_SINK names, or nil if it isn't set:
+//
+//	file   appends JSON lines to ANALYTICS_FILE (default "analytics.jsonl")
+//	http   posts JSON arrays to ANALYTICS_URL
+//	kafka  produces to ANALYTICS_KAFKA_TOPIC (default "cambia-analytics") through the Kafka REST proxy at
+//	       ANALYTICS_KAFKA_URL
+func FromEnv() (Sink, error) {
+	client := &http.Client{Timeout: writeTimeout}
+	switch kind := os.Getenv("ANALYTICS_SINK"); kind {
+	case "":
+		return nil, nil
+	case "file":
+		path := os.Getenv("ANALYTICS_FILE")
+		if path == "" {
+			path = "analytics.jsonl"
+		}
+		return NewFileSink(path)
+	case "http":
+		target := os.Getenv("ANALYTICS_URL")
+		if target == "" {
+			return nil, fmt.Errorf("ANALYTICS_URL must be set for the http analytics sink")
+		}
+		return &HTTPSink{URL: target, Client: client}, nil
+	case "kafka":
+		target := strings.TrimSuffix(os.Getenv("ANALYTICS_KAFKA_URL"), "/")
+		if target == "" {
+			return nil, fmt.Errorf("ANALYTICS_KAFKA_URL must be set for the kafka analytics sink")
+		}
+		topic := os.Getenv("ANALYTICS_KAFKA_TOPIC")
+		if topic == "" {
+			topic = "cambia-analytics"
+		}
+		return &KafkaSink{URL: target, Topic: topic, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown analytics sink %q", kind)
+	}
+}
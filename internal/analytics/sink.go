@@ -0,0 +1,108 @@
+// internal/analytics/sink.go
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// FileSink appends events to a file as JSON lines.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open analytics file: %w", err)
+	}
+	return &FileSink{f: f}, nil
+}
+
+// Write appends the events, one per line.
+func (s *FileSink) Write(_ context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := bufio.NewWriter(s.f)
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// HTTPSink posts each batch of events as a JSON array to URL.
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// Write posts the events.
+func (s *HTTPSink) Write(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.URL, "application/json", body)
+}
+
+// KafkaSink produces events to a Kafka topic through a Kafka REST proxy, such as Confluent's, at URL.
+// Events are keyed by game, so each game's events stay in order on one partition.
+type KafkaSink struct {
+	URL    string
+	Topic  string
+	Client *http.Client
+}
+
+// kafkaRecord is a record in a REST proxy produce request.
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// Write produces the events to the topic.
+func (s *KafkaSink) Write(ctx context.Context, events []Event) error {
+	records := make([]kafkaRecord, 0, len(events))
+	for _, ev := range events {
+		records = append(records, kafkaRecord{Key: ev.GameID.String(), Value: ev})
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.URL+"/topics/"+url.PathEscape(s.Topic), "application/vnd.kafka.json.v2+json", body)
+}
+
+// post sends body to target, failing on any status but 2xx.
+func post(ctx context.Context, client *http.Client, target, contentType string, body []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("analytics sink returned %s", resp.Status)
+	}
+	return nil
+}
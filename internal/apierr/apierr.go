@@ -0,0 +1,103 @@
+// internal/apierr/apierr.go
+package apierr
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Code is a machine-readable error code, stable across releases so clients can branch on it.
+type Code string
+
+// Generic codes, one per HTTP status. Error picks these when no more specific code applies.
+const (
+	CodeInvalidRequest   Code = "invalid_request"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodeGone             Code = "gone"
+	CodeRateLimited      Code = "rate_limited"
+	CodeInternal         Code = "internal_error"
+	CodeUnavailable      Code = "unavailable"
+)
+
+// Specific codes.
+const (
+	CodeInvalidPayload   Code = "invalid_payload"
+	CodeMissingToken     Code = "missing_token"
+	CodeInvalidToken     Code = "invalid_token"
+	CodeAccountBanned    Code = "account_banned"
+	CodeAuthFailed       Code = "authentication_failed"
+	CodeInsufficientRole Code = "insufficient_role"
+	CodeEmailTaken       Code = "email_taken"
+	CodeLobbyNotFound    Code = "lobby_not_found"
+	CodeGameNotFound     Code = "game_not_found"
+	CodeInvalidLobby     Code = "invalid_lobby_settings"
+	CodeRankedSuspended  Code = "ranked_suspended"
+	CodeUnsupportedRules Code = "unsupported_rules_revision"
+	CodeLobbyLimit       Code = "lobby_limit_reached"
+	CodeGameLimit        Code = "game_limit_reached"
+)
+
+// requestIDHeader is the response header middleware.LogMiddleware puts the request's ID in.
+const requestIDHeader = "X-Request-ID"
+
+// Response is the body of every error response.
+type Response struct {
+	Code    Code                   `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	// RequestID is the ID the request was logged under, for quoting in bug reports.
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// FromStatus returns the generic code for an HTTP status.
+func FromStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// Write sends an error response with the given status, code, and human-readable message.
+func Write(w http.ResponseWriter, status int, code Code, message string) {
+	WriteDetails(w, status, code, message, nil)
+}
+
+// WriteDetails is Write with extra structured details, e.g. the field that failed validation.
+func WriteDetails(w http.ResponseWriter, status int, code Code, message string, details map[string]interface{}) {
+	h := w.Header()
+	requestID := h.Get(requestIDHeader)
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Code: code, Message: message, Details: details, RequestID: requestID})
+}
+
+// Error is a drop-in replacement for http.Error that sends the JSON envelope with the status's generic code.
+func Error(w http.ResponseWriter, message string, status int) {
+	Write(w, status, FromStatus(status), message)
+}
@@ -0,0 +1,44 @@
+package apierr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteDetails(rec, http.StatusBadRequest, CodeInvalidLobby, "invalid game mode", map[string]interface{}{"field": "gameMode"})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got %q", ct)
+	}
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != CodeInvalidLobby || resp.Message != "invalid game mode" || resp.Details["field"] != "gameMode" {
+		t.Fatalf("unexpected envelope %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	Error(rec, "lobby not found", http.StatusNotFound)
+	resp = Response{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != CodeNotFound || resp.Details != nil || resp.RequestID != "" {
+		t.Fatalf("expected generic not_found, got %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("X-Request-ID", "abc-123")
+	Error(rec, "lobby not found", http.StatusNotFound)
+	resp = Response{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.RequestID != "abc-123" {
+		t.Fatalf("expected the request ID in the envelope, got %+v", resp)
+	}
+}
@@ -32,7 +32,7 @@ type params struct {
 var Params = &params{
 	memory:      64 * 1024,
 	iterations:  5,
-	parallelism: uint8(runtime.NumCPU() / 2),
+	parallelism: uint8(max(1, runtime.NumCPU()/2)),
 	saltLength:  16,
 	keyLength:   32,
 }
@@ -0,0 +1,52 @@
+// internal/auth/request.go
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// wsTokenSubprotocolPrefix lets WebSocket clients that can't set headers pass their token as an extra
+// offered subprotocol, e.g. Sec-WebSocket-Protocol: lobby, token.{jwt}
+const wsTokenSubprotocolPrefix = "token."
+
+// ExtractCookieToken extracts a named cookie value from "Cookie" header, or returns empty if not found.
+func ExtractCookieToken(cookieHeader, cookieName string) string {
+	parts := strings.Split(cookieHeader, cookieName+"=")
+	if len(parts) < 2 {
+		return ""
+	}
+	token := parts[1]
+	if idx := strings.Index(token, ";"); idx != -1 {
+		token = token[:idx]
+	}
+	return token
+}
+
+// RequestToken returns the auth token from the "Authorization: Bearer" header, falling back to the
+// auth_token cookie. Returns empty if neither is present.
+func RequestToken(r *http.Request) string {
+	if authz := r.Header.Get("Authorization"); authz != "" {
+		if token, ok := strings.CutPrefix(authz, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ExtractCookieToken(r.Header.Get("Cookie"), "auth_token")
+}
+
+// WSRequestToken returns the auth token for a WebSocket upgrade request. In addition to the bearer header
+// and cookie, non-browser clients may pass ?token={jwt} or offer a "token.{jwt}" subprotocol.
+func WSRequestToken(r *http.Request) string {
+	if token := RequestToken(r); token != "" {
+		return token
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	for _, proto := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if token, ok := strings.CutPrefix(strings.TrimSpace(proto), wsTokenSubprotocolPrefix); ok {
+			return token
+		}
+	}
+	return ""
+}
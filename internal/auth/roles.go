@@ -0,0 +1,31 @@
+// internal/auth/roles.go
+package auth
+
+// Roles a user can hold, from least to most privileged.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether a user holding role `have` is allowed to act as `need`.
+// Roles are hierarchical: admins can do anything moderators can.
+func HasRole(have, need string) bool {
+	h, ok := roleRank[have]
+	if !ok {
+		return false
+	}
+	return h >= roleRank[need]
+}
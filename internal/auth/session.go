@@ -2,7 +2,9 @@
 package auth
 
 import (
+	"context"
 	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -62,11 +64,32 @@ func InitFromPath(privatePath, publicPath string) error {
 	return nil
 }
 
-// CreateJWT creates a signed JWT token with "sub" = userID, exp = now + 72h by default
-// (unless overridden by TOKEN_EXPIRE_TIME_SEC).
+// Claims are the identity claims carried by a session token.
+type Claims struct {
+	UserID   string
+	Role     string
+	IssuedAt time.Time // zero for tokens issued before "iat" was added
+}
+
+// SessionValidator, if set, is consulted after a token's signature and expiry check out, e.g. to reject
+// tokens issued before the user's sessions were revoked. A non-nil error rejects the token.
+var SessionValidator func(ctx context.Context, claims *Claims) error
+
+// ErrBanned is returned by SessionValidator (and so token authentication) for users under a global ban.
+var ErrBanned = errors.New("user is banned")
+
+// CreateJWT creates a signed JWT token with "sub" = userID and the default user role.
 func CreateJWT(userID string) (string, error) {
+	return CreateJWTWithRole(userID, RoleUser)
+}
+
+// CreateJWTWithRole creates a signed JWT token with "sub" = userID and "role" = role, exp = now + TOKEN_EXPIRE_TIME_SEC
+// (no exp claim if TOKEN_EXPIRE_TIME_SEC is 0).
+func CreateJWTWithRole(userID, role string) (string, error) {
 	claims := jwt.MapClaims{
-		"sub": userID,
+		"sub":  userID,
+		"role": role,
+		"iat":  time.Now().Unix(),
 	}
 
 	if TOKEN_EXPIRE_TIME_SEC > 0 {
@@ -81,6 +104,16 @@ func CreateJWT(userID string) (string, error) {
 
 // AuthenticateJWT verifies a JWT string, returns the "sub" field if valid, else an error.
 func AuthenticateJWT(tokenString string) (string, error) {
+	claims, err := AuthenticateJWTClaims(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
+}
+
+// AuthenticateJWTClaims verifies a JWT string and returns its subject and role.
+// Tokens issued before roles existed carry no "role" claim and are treated as RoleUser.
+func AuthenticateJWTClaims(tokenString string) (*Claims, error) {
 	t, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
@@ -89,21 +122,37 @@ func AuthenticateJWT(tokenString string) (string, error) {
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("jwt parse error: %w", err)
+		return nil, fmt.Errorf("jwt parse error: %w", err)
 	}
 	if !t.Valid {
-		return "", fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	claims, ok := t.Claims.(jwt.MapClaims)
+	mc, ok := t.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", fmt.Errorf("invalid jwt claims")
+		return nil, fmt.Errorf("invalid jwt claims")
 	}
 
-	userID, ok := claims["sub"].(string)
+	userID, ok := mc["sub"].(string)
 	if !ok {
-		return "", fmt.Errorf("missing sub in jwt")
+		return nil, fmt.Errorf("missing sub in jwt")
+	}
+
+	role, _ := mc["role"].(string)
+	if !ValidRole(role) {
+		role = RoleUser
+	}
+
+	claims := &Claims{UserID: userID, Role: role}
+	if iat, err := mc.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+
+	if SessionValidator != nil {
+		if err := SessionValidator(context.Background(), claims); err != nil {
+			return nil, err
+		}
 	}
 
-	return userID, nil
+	return claims, nil
 }
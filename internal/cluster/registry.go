@@ -0,0 +1,65 @@
+// internal/cluster/registry.go
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/redis"
+)
+
+// gameOwnershipTTL bounds how long a game is routed to its node; games don't outlive it.
+const gameOwnershipTTL = 24 * time.Hour
+
+// Registry records which server node hosts each live game, so a node that gets a connection for a game it
+// doesn't host can pass it on. Nodes are identified by the base URL other nodes reach them at.
+type Registry interface {
+	// Claim records this node as the game's host.
+	Claim(gameID uuid.UUID) error
+	// Owner returns the URL of the node hosting the game, or "" if no node has claimed it.
+	Owner(gameID uuid.UUID) (string, error)
+	// Self returns this node's URL.
+	Self() string
+}
+
+// RedisRegistry keeps game ownership in Redis under cluster:game:{id}.
+type RedisRegistry struct {
+	conn    *redis.Conn
+	nodeURL string
+}
+
+// NewRedisRegistry connects to Redis as the node reachable at nodeURL.
+func NewRedisRegistry(ctx context.Context, addr, password, nodeURL string) (*RedisRegistry, error) {
+	conn, err := redis.Dial(ctx, addr, password)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisRegistry{conn: conn, nodeURL: nodeURL}, nil
+}
+
+func gameKey(gameID uuid.UUID) string {
+	return "cluster:game:" + gameID.String()
+}
+
+func (r *RedisRegistry) Claim(gameID uuid.UUID) error {
+	ttl := fmt.Sprint(int(gameOwnershipTTL.Seconds()))
+	if _, err := r.conn.Do("SET", gameKey(gameID), r.nodeURL, "EX", ttl); err != nil {
+		return fmt.Errorf("failed to claim game %v: %w", gameID, err)
+	}
+	return nil
+}
+
+func (r *RedisRegistry) Owner(gameID uuid.UUID) (string, error) {
+	reply, err := r.conn.Do("GET", gameKey(gameID))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up owner of game %v: %w", gameID, err)
+	}
+	owner, _ := reply.([]byte)
+	return string(owner), nil
+}
+
+func (r *RedisRegistry) Self() string {
+	return r.nodeURL
+}
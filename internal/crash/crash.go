@@ -0,0 +1,31 @@
+// internal/crash/crash.go
+
+// Package crash keeps a panic in one goroutine from taking down the whole server. Request handlers are
+// covered by middleware.Recover; timers and background goroutines defer Recover themselves.
+package crash
+
+import (
+	"log"
+	"runtime/debug"
+)
+
+// Recover, deferred at the top of a goroutine or timer callback, recovers a panic and logs it with its
+// stack. where names the goroutine in the log line.
+func Recover(where string) {
+	if v := recover(); v != nil {
+		Log(where, v)
+	}
+}
+
+// Go runs fn in a new goroutine that recovers and logs a panic instead of crashing the server.
+func Go(where string, fn func()) {
+	go func() {
+		defer Recover(where)
+		fn()
+	}()
+}
+
+// Log logs a recovered panic value with the stack of the goroutine that recovered it.
+func Log(where string, v interface{}) {
+	log.Printf("panic in %s: %v\n%s", where, v, debug.Stack())
+}
@@ -0,0 +1,233 @@
+// internal/database/account.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// deletedUsername replaces the username of a deleted account in other players' match history.
+const deletedUsername = "Deleted User"
+
+// MatchHistoryEntry is one game a user played, as included in a data export.
+type MatchHistoryEntry struct {
+	GameID    uuid.UUID  `json:"game_id"`
+	Status    string     `json:"status"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Score     *int       `json:"score,omitempty"`
+	DidWin    *bool      `json:"did_win,omitempty"`
+	Ranking   *int16     `json:"ranking,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RatingHistoryEntry is a single rating change, as included in a data export.
+type RatingHistoryEntry struct {
+	GameID    *uuid.UUID `json:"game_id,omitempty"`
+	Mode      string     `json:"rating_mode"`
+	OldRating int        `json:"old_rating"`
+	NewRating int        `json:"new_rating"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ChatLogEntry is a persisted lobby chat message, as included in a data export.
+type ChatLogEntry struct {
+	ID        uuid.UUID `json:"id"`
+	LobbyID   uuid.UUID `json:"lobby_id"`
+	Msg       string    `json:"msg"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserExport is everything we store about a user.
+type UserExport struct {
+	ExportedAt     time.Time              `json:"exported_at"`
+	Profile        *models.User           `json:"profile"`
+	Friends        []models.Friend        `json:"friends"`
+	MatchHistory   []MatchHistoryEntry    `json:"match_history"`
+	RatingHistory  []RatingHistoryEntry   `json:"rating_history"`
+	ChatLogs       []ChatLogEntry         `json:"chat_logs"`
+	DirectMessages []models.DirectMessage `json:"direct_messages"`
+	Notifications  []models.Notification  `json:"notifications"`
+	RuleTemplates  []RuleTemplate         `json:"rule_templates"`
+}
+
+// ExportUserData collects a user's profile, friends, match history, rating history, persisted chat logs,
+// direct messages sent and received, notifications, and saved rule templates.
+func ExportUserData(ctx context.Context, userID uuid.UUID) (*UserExport, error) {
+	u, err := GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	u.Password = ""
+
+	friends, err := ListFriends(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch friends: %w", err)
+	}
+
+	export := &UserExport{
+		ExportedAt:    time.Now().UTC(),
+		Profile:       u,
+		Friends:       friends,
+		MatchHistory:  []MatchHistoryEntry{},
+		RatingHistory: []RatingHistoryEntry{},
+		ChatLogs:      []ChatLogEntry{},
+	}
+
+	rows, err := DB.Query(ctx, `
+		SELECT gr.game_id, g.status, g.start_time, g.end_time, gr.score, gr.did_win, gr.ranking, gr.created_at
+		FROM game_results gr
+		JOIN games g ON g.id = gr.game_id
+		WHERE gr.player_id=$1
+		ORDER BY gr.created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch match history: %w", err)
+	}
+	export.MatchHistory, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (MatchHistoryEntry, error) {
+		var e MatchHistoryEntry
+		err := row.Scan(&e.GameID, &e.Status, &e.StartTime, &e.EndTime, &e.Score, &e.DidWin, &e.Ranking, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan match history: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT game_id, rating_mode, old_rating, new_rating, created_at
+		FROM ratings
+		WHERE user_id=$1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rating history: %w", err)
+	}
+	export.RatingHistory, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (RatingHistoryEntry, error) {
+		var e RatingHistoryEntry
+		err := row.Scan(&e.GameID, &e.Mode, &e.OldRating, &e.NewRating, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rating history: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT id, lobby_id, msg, created_at
+		FROM lobby_chat_messages
+		WHERE user_id=$1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chat logs: %w", err)
+	}
+	export.ChatLogs, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (ChatLogEntry, error) {
+		var e ChatLogEntry
+		err := row.Scan(&e.ID, &e.LobbyID, &e.Msg, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan chat logs: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT id, sender_id, recipient_id, body, read_at, created_at
+		FROM direct_messages
+		WHERE sender_id=$1 OR recipient_id=$1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch direct messages: %w", err)
+	}
+	export.DirectMessages, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.DirectMessage, error) {
+		var m models.DirectMessage
+		err := row.Scan(&m.ID, &m.SenderID, &m.RecipientID, &m.Body, &m.ReadAt, &m.CreatedAt)
+		return m, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan direct messages: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT id, user_id, type, payload, read_at, created_at
+		FROM notifications
+		WHERE user_id=$1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notifications: %w", err)
+	}
+	export.Notifications, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Notification, error) {
+		var n models.Notification
+		err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.Payload, &n.ReadAt, &n.CreatedAt)
+		return n, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notifications: %w", err)
+	}
+
+	if export.RuleTemplates, err = ListRuleTemplates(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to fetch rule templates: %w", err)
+	}
+
+	return export, nil
+}
+
+// DeleteUserAccount removes a user's personal data. Friendships, chat messages, direct messages (both ways),
+// notifications (theirs, and others' that name them), rule templates, login fingerprints, and smurf signals
+// on either side of a link are deleted outright; the users row is kept but anonymized so game results,
+// actions, and ratings still reference a valid player and other users' histories and aggregates are unchanged.
+func DeleteUserAccount(ctx context.Context, userID uuid.UUID) error {
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM friends WHERE user1_id=$1 OR user2_id=$1`, userID); err != nil {
+			return fmt.Errorf("failed to delete friends: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM lobby_chat_messages WHERE user_id=$1`, userID); err != nil {
+			return fmt.Errorf("failed to delete chat messages: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM direct_messages WHERE sender_id=$1 OR recipient_id=$1`, userID); err != nil {
+			return fmt.Errorf("failed to delete direct messages: %w", err)
+		}
+		// invites and friend requests from the user sit in other users' inboxes
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM notifications
+			WHERE user_id=$1 OR payload->>'from' = $1::text OR payload->>'userID' = $1::text
+		`, userID); err != nil {
+			return fmt.Errorf("failed to delete notifications: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM rule_templates WHERE user_id=$1`, userID); err != nil {
+			return fmt.Errorf("failed to delete rule templates: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM smurf_signals WHERE user_id=$1 OR linked_user_id=$1`, userID); err != nil {
+			return fmt.Errorf("failed to delete smurf signals: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM login_fingerprints WHERE user_id=$1`, userID); err != nil {
+			return fmt.Errorf("failed to delete login fingerprints: %w", err)
+		}
+
+		// email is UNIQUE, so each tombstone gets its own placeholder rather than NULL or ''
+		tag, err := tx.Exec(ctx, `
+			UPDATE users
+			SET email='deleted+' || id::text || '@invalid',
+			    password='',
+			    username=$2,
+			    is_admin=FALSE,
+			    role='user',
+			    deleted_at=NOW(),
+			    sessions_revoked_at=date_trunc('second', NOW() AT TIME ZONE 'UTC')
+			WHERE id=$1 AND deleted_at IS NULL
+		`, userID, deletedUsername)
+		if err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("user %v not found or already deleted", userID)
+		}
+		return nil
+	})
+}
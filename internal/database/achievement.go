@@ -0,0 +1,64 @@
+// internal/database/achievement.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AchievementProgress is a user's stored progress towards one achievement.
+type AchievementProgress struct {
+	AchievementID string
+	Progress      int
+	UnlockedAt    *time.Time
+}
+
+// AddAchievementProgress adds delta to a user's progress towards an achievement, unlocking it once progress
+// reaches target. It reports whether this call unlocked it.
+func AddAchievementProgress(ctx context.Context, userID uuid.UUID, achievementID string, delta, target int) (bool, error) {
+	q := `
+		INSERT INTO user_achievements (user_id, achievement_id, progress, unlocked_at)
+		VALUES ($1, $2, $3, CASE WHEN $3 >= $4 THEN NOW() END)
+		ON CONFLICT (user_id, achievement_id) DO UPDATE SET
+			progress = user_achievements.progress + $3,
+			unlocked_at = COALESCE(user_achievements.unlocked_at,
+				CASE WHEN user_achievements.progress + $3 >= $4 THEN NOW() END),
+			updated_at = NOW()
+		RETURNING progress
+	`
+	var progress int
+	if err := DB.QueryRow(ctx, q, userID, achievementID, delta, target).Scan(&progress); err != nil {
+		return false, fmt.Errorf("failed to update achievement %s: %w", achievementID, err)
+	}
+	return progress >= target && progress-delta < target, nil
+}
+
+// ListAchievementProgress returns a user's stored achievement progress, by achievement ID.
+func ListAchievementProgress(ctx context.Context, userID uuid.UUID) (map[string]AchievementProgress, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT achievement_id, progress, unlocked_at
+		FROM user_achievements
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list achievements: %w", err)
+	}
+	list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (AchievementProgress, error) {
+		var a AchievementProgress
+		err := row.Scan(&a.AchievementID, &a.Progress, &a.UnlockedAt)
+		return a, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]AchievementProgress, len(list))
+	for _, a := range list {
+		out[a.AchievementID] = a
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+// internal/database/anticheat.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// InsertAnticheatFlag records a flag raised by the game server.
+func InsertAnticheatFlag(ctx context.Context, f *models.AnticheatFlag) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	f.ID = id
+	f.CreatedAt = time.Now().UTC()
+	if f.Detail == nil {
+		f.Detail = map[string]interface{}{}
+	}
+	_, err = DB.Exec(ctx, `
+		INSERT INTO anticheat_flags (id, user_id, game_id, kind, turn, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, f.ID, f.UserID, f.GameID, f.Kind, f.Turn, f.Detail, f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert anti-cheat flag: %w", err)
+	}
+	return nil
+}
+
+// ListAnticheatFlags returns up to limit flags, newest first, before the flag with ID before (or from the
+// newest if before is uuid.Nil). If userID isn't uuid.Nil, only that user's flags are returned.
+func ListAnticheatFlags(ctx context.Context, userID, before uuid.UUID, limit int) ([]models.AnticheatFlag, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT id, user_id, game_id, kind, turn, detail, created_at
+		FROM anticheat_flags
+		WHERE ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR user_id = $1)
+		  AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anti-cheat flags: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AnticheatFlag, error) {
+		var f models.AnticheatFlag
+		err := row.Scan(&f.ID, &f.UserID, &f.GameID, &f.Kind, &f.Turn, &f.Detail, &f.CreatedAt)
+		return f, err
+	})
+}
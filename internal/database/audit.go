@@ -0,0 +1,64 @@
+// internal/database/audit.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// InsertAuditEntry appends an entry to the audit log.
+func InsertAuditEntry(ctx context.Context, e *models.AuditEntry) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	e.CreatedAt = time.Now().UTC()
+	if e.Payload == nil {
+		e.Payload = map[string]interface{}{}
+	}
+	_, err = DB.Exec(ctx, `
+		INSERT INTO audit_log (id, actor_id, action, target_type, target_id, payload, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, e.ID, e.ActorID, e.Action, e.TargetType, e.TargetID, e.Payload, e.RequestID, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// AuditFilter narrows ListAuditEntries. Zero fields match everything.
+type AuditFilter struct {
+	ActorID  uuid.UUID
+	TargetID uuid.UUID
+	Action   string
+}
+
+// ListAuditEntries returns up to limit audit entries matching f, newest first, older than the entry with
+// ID before (or from the newest if before is uuid.Nil).
+func ListAuditEntries(ctx context.Context, f AuditFilter, before uuid.UUID, limit int) ([]models.AuditEntry, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT id, actor_id, action, target_type, target_id, payload, request_id, created_at
+		FROM audit_log
+		WHERE ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR actor_id = $1)
+		  AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR target_id = $2)
+		  AND ($3 = '' OR action = $3)
+		  AND ($4 = '00000000-0000-0000-0000-000000000000'::uuid OR id < $4)
+		ORDER BY id DESC
+		LIMIT $5
+	`, f.ActorID, f.TargetID, f.Action, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AuditEntry, error) {
+		var e models.AuditEntry
+		err := row.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.Payload, &e.RequestID, &e.CreatedAt)
+		return e, err
+	})
+}
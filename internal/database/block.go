@@ -0,0 +1,67 @@
+// internal/database/block.go
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// BlockUser records that blocker has blocked blocked, and ends any friendship or pending request between them.
+func BlockUser(ctx context.Context, blocker, blocked uuid.UUID) error {
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO user_blocks (blocker_id, blocked_id)
+			VALUES ($1, $2)
+			ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+		`, blocker, blocked); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			DELETE FROM friends
+			WHERE (user1_id=$1 AND user2_id=$2)
+			   OR (user1_id=$2 AND user2_id=$1)
+		`, blocker, blocked)
+		return err
+	})
+}
+
+// UnblockUser removes a block.
+func UnblockUser(ctx context.Context, blocker, blocked uuid.UUID) error {
+	_, err := DB.Exec(ctx, `DELETE FROM user_blocks WHERE blocker_id=$1 AND blocked_id=$2`, blocker, blocked)
+	return err
+}
+
+// ListBlockedIDs returns the users blocker has blocked.
+func ListBlockedIDs(ctx context.Context, blocker uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := DB.Query(ctx, `SELECT blocked_id FROM user_blocks WHERE blocker_id=$1 ORDER BY created_at`, blocker)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// ListBlockRelations returns everyone userID has blocked or been blocked by, i.e. the users they must
+// never be matched with.
+func ListBlockRelations(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT blocked_id FROM user_blocks WHERE blocker_id=$1
+		UNION
+		SELECT blocker_id FROM user_blocks WHERE blocked_id=$1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// HasBlocked reports whether blocker has blocked blocked.
+func HasBlocked(ctx context.Context, blocker, blocked uuid.UUID) (bool, error) {
+	var ok bool
+	err := DB.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id=$1 AND blocked_id=$2)
+	`, blocker, blocked).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,135 @@
+// internal/database/challenge.go
+
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DailyChallengeEntry is one finished attempt on a day's challenge leaderboard. Lower scores rank higher,
+// then fewer turns, then whoever finished first.
+type DailyChallengeEntry struct {
+	Rank       int       `json:"rank"`
+	UserID     uuid.UUID `json:"userID"`
+	Username   string    `json:"username"`
+	Score      int       `json:"score"`
+	Won        bool      `json:"won"`
+	Turns      int       `json:"turns"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// DailyChallengeSeed returns the deal seed of day's challenge, drawing it if nobody has asked for it yet.
+func DailyChallengeSeed(ctx context.Context, day time.Time) ([]byte, error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	// a node racing to draw the same day's seed leaves it to whichever inserted first
+	var out []byte
+	err := DB.QueryRow(ctx, `
+		WITH ins AS (
+			INSERT INTO daily_challenges (day, seed) VALUES ($1, $2)
+			ON CONFLICT (day) DO NOTHING
+			RETURNING seed
+		)
+		SELECT seed FROM ins
+		UNION ALL
+		SELECT seed FROM daily_challenges WHERE day = $1
+		LIMIT 1
+	`, day, seed).Scan(&out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily challenge: %w", err)
+	}
+	return out, nil
+}
+
+// GetDailyChallengeSeed returns the deal seed of day's challenge, or nil if it was never drawn.
+func GetDailyChallengeSeed(ctx context.Context, day time.Time) ([]byte, error) {
+	var seed []byte
+	err := DB.QueryRow(ctx, `SELECT seed FROM daily_challenges WHERE day = $1`, day).Scan(&seed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily challenge: %w", err)
+	}
+	return seed, nil
+}
+
+// StartDailyChallenge records userID's attempt at day's challenge in gameID. It reports false, without
+// recording anything, if they've already had their attempt.
+func StartDailyChallenge(ctx context.Context, day time.Time, userID, gameID uuid.UUID) (bool, error) {
+	tag, err := DB.Exec(ctx, `
+		INSERT INTO daily_challenge_results (day, user_id, game_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (day, user_id) DO NOTHING
+	`, day, userID, gameID)
+	if err != nil {
+		return false, fmt.Errorf("failed to start daily challenge: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// FinishDailyChallenge records the outcome of userID's attempt at day's challenge.
+func FinishDailyChallenge(ctx context.Context, day time.Time, userID uuid.UUID, score int, won bool, turns int) error {
+	_, err := DB.Exec(ctx, `
+		UPDATE daily_challenge_results
+		SET score = $3, won = $4, turns = $5, finished_at = NOW()
+		WHERE day = $1 AND user_id = $2 AND finished_at IS NULL
+	`, day, userID, score, won, turns)
+	if err != nil {
+		return fmt.Errorf("failed to finish daily challenge: %w", err)
+	}
+	return nil
+}
+
+// dailyChallengeBoard ranks registered users' finished attempts at day $1's challenge.
+const dailyChallengeBoard = `
+	SELECT RANK() OVER (ORDER BY r.score, r.turns, r.finished_at) AS rank,
+		r.user_id, u.username, r.score, r.won, r.turns, r.finished_at
+	FROM daily_challenge_results r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.day = $1 AND r.score IS NOT NULL AND NOT u.is_ephemeral AND u.deleted_at IS NULL
+`
+
+func scanDailyChallengeEntry(row pgx.CollectableRow) (DailyChallengeEntry, error) {
+	var e DailyChallengeEntry
+	err := row.Scan(&e.Rank, &e.UserID, &e.Username, &e.Score, &e.Won, &e.Turns, &e.FinishedAt)
+	return e, err
+}
+
+// GetDailyChallengeBoard returns the top limit finished attempts at day's challenge.
+func GetDailyChallengeBoard(ctx context.Context, day time.Time, limit int) ([]DailyChallengeEntry, error) {
+	rows, err := DB.Query(ctx, dailyChallengeBoard+` ORDER BY rank, r.user_id LIMIT $2`, day, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily challenge board: %w", err)
+	}
+	entries, err := pgx.CollectRows(rows, scanDailyChallengeEntry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan daily challenge board: %w", err)
+	}
+	return entries, nil
+}
+
+// GetDailyChallengeRank returns userID's entry on day's board, or nil if they haven't finished an attempt.
+func GetDailyChallengeRank(ctx context.Context, day time.Time, userID uuid.UUID) (*DailyChallengeEntry, error) {
+	rows, err := DB.Query(ctx, `SELECT * FROM (`+dailyChallengeBoard+`) b WHERE b.user_id = $2`, day, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch daily challenge entry: %w", err)
+	}
+	e, err := pgx.CollectExactlyOneRow(rows, scanDailyChallengeEntry)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch daily challenge entry: %w", err)
+	}
+	return &e, nil
+}
@@ -0,0 +1,31 @@
+// internal/database/chat.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// InsertLobbyChatMessage persists a lobby chat message.
+func InsertLobbyChatMessage(ctx context.Context, id, lobbyID, userID uuid.UUID, msg string, ts time.Time) error {
+	q := `
+		INSERT INTO lobby_chat_messages (id, lobby_id, user_id, msg, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, q, id, lobbyID, userID, msg, ts)
+		return err
+	})
+}
+
+// SetLobbyChatReactions overwrites the stored reactions for a chat message (emoji => user ids).
+func SetLobbyChatReactions(ctx context.Context, msgID uuid.UUID, reactions map[string][]uuid.UUID) error {
+	q := `UPDATE lobby_chat_messages SET reactions=$1 WHERE id=$2`
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, q, reactions, msgID)
+		return err
+	})
+}
@@ -26,12 +26,23 @@ func ConnectDB() {
 	if err != nil {
 		log.Fatalf("unable to parse pgx config: %v", err)
 	}
+	parseQueryTimeout()
+	config.ConnConfig.Tracer = timeoutTracer{}
 
 	DB, err = pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		log.Fatalf("unable to create pgx pool: %v", err)
 	}
 
+	// With DB_DRIVER=memory, accounts, game results, and lobbies and their chat live in memory; everything
+	// else still queries the pool, which only connects on first use, so those features fail until Postgres
+	// is up.
+	if os.Getenv("DB_DRIVER") == "memory" {
+		UseMemory()
+		log.Printf("Using in-memory repositories; other features need a database at %s", connStr)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := DB.Ping(ctx); err != nil {
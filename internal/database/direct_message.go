@@ -0,0 +1,90 @@
+// internal/database/direct_message.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// InsertDirectMessage stores a message from sender to recipient and returns it.
+func InsertDirectMessage(ctx context.Context, sender, recipient uuid.UUID, body string) (*models.DirectMessage, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	m := &models.DirectMessage{ID: id, SenderID: sender, RecipientID: recipient, Body: body, CreatedAt: time.Now().UTC()}
+	_, err = DB.Exec(ctx, `
+		INSERT INTO direct_messages (id, sender_id, recipient_id, body, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, m.ID, m.SenderID, m.RecipientID, m.Body, m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert direct message: %w", err)
+	}
+	return m, nil
+}
+
+// ListConversation returns up to limit messages between a and b, newest first, older than before (or from
+// the newest if before is uuid.Nil).
+func ListConversation(ctx context.Context, a, b uuid.UUID, before uuid.UUID, limit int) ([]models.DirectMessage, error) {
+	q := `
+		SELECT id, sender_id, recipient_id, body, read_at, created_at
+		FROM direct_messages
+		WHERE LEAST(sender_id, recipient_id) = LEAST($1::uuid, $2::uuid)
+		  AND GREATEST(sender_id, recipient_id) = GREATEST($1::uuid, $2::uuid)
+		  AND ($3 = '00000000-0000-0000-0000-000000000000'::uuid OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	rows, err := DB.Query(ctx, q, a, b, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list direct messages: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.DirectMessage, error) {
+		var m models.DirectMessage
+		err := row.Scan(&m.ID, &m.SenderID, &m.RecipientID, &m.Body, &m.ReadAt, &m.CreatedAt)
+		return m, err
+	})
+}
+
+// CountUnreadMessages returns, for each sender with unread messages to userID, how many there are.
+func CountUnreadMessages(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT sender_id, COUNT(*)
+		FROM direct_messages
+		WHERE recipient_id = $1 AND read_at IS NULL
+		GROUP BY sender_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread direct messages: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var sender uuid.UUID
+		var n int
+		if err := rows.Scan(&sender, &n); err != nil {
+			return nil, err
+		}
+		counts[sender] = n
+	}
+	return counts, rows.Err()
+}
+
+// MarkConversationRead marks every unread message from sender to reader as read, returning how many changed.
+func MarkConversationRead(ctx context.Context, reader, sender uuid.UUID) (int64, error) {
+	tag, err := DB.Exec(ctx, `
+		UPDATE direct_messages SET read_at = NOW()
+		WHERE recipient_id = $1 AND sender_id = $2 AND read_at IS NULL
+	`, reader, sender)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark direct messages read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -52,7 +52,7 @@ func AcceptFriend(ctx context.Context, user1, user2 uuid.UUID) error {
 func ListFriends(ctx context.Context, userID uuid.UUID) ([]models.Friend, error) {
 	// return rows matching (user1_id=userID or user2_id=userID), including pending or accepted
 	q := `
-		SELECT user1_id, user2_id, status, updated_at
+		SELECT user1_id, user2_id, status
 		FROM friends
 		WHERE user1_id=$1 OR user2_id=$1
 	`
@@ -86,3 +86,31 @@ func RemoveFriend(ctx context.Context, user1, user2 uuid.UUID) error {
 		return err
 	})
 }
+
+// AreFriends reports whether two users have an accepted friendship, in either direction.
+func AreFriends(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1 FROM friends
+			WHERE status='accepted'
+			  AND ((user1_id=$1 AND user2_id=$2) OR (user1_id=$2 AND user2_id=$1))
+		)
+	`
+	var ok bool
+	err := DB.QueryRow(ctx, q, a, b).Scan(&ok)
+	return ok, err
+}
+
+// ListFriendIDs returns the IDs of everyone with an accepted friendship with userID.
+func ListFriendIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	q := `
+		SELECT CASE WHEN user1_id=$1 THEN user2_id ELSE user1_id END
+		FROM friends
+		WHERE status='accepted' AND (user1_id=$1 OR user2_id=$1)
+	`
+	rows, err := DB.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -11,19 +12,136 @@ import (
 	"github.com/jason-s-yu/cambia/internal/rating"
 )
 
-// RecordGameAndResults persists the final outcome of a game, plus updates rating (1v1, 4p, 7p/8p).
+// RatingModeForPlayers returns the rating mode for a game with n players, or "" if it isn't rated.
+func RatingModeForPlayers(n int) string {
+	switch n {
+	case 2:
+		return "1v1"
+	case 4:
+		return "4p"
+	case 7, 8:
+		return "7p8p"
+	default:
+		return ""
+	}
+}
+
+// GameRecord is the final outcome of a game, as persisted by RecordGameAndResults.
+type GameRecord struct {
+	GameID         uuid.UUID
+	EngineVersion  string
+	RulesRevision  int
+	Ranked         bool
+	RoundIndex     int // round of the circuit series this game was played in, if any
+	StartedAt      time.Time
+	EndedAt        time.Time
+	Players        []*models.Player // hands as revealed at the end of the game
+	FinalScores    map[uuid.UUID]int
+	Winners        []uuid.UUID
+	CambiaCallerID uuid.UUID
+	Actions        []models.GameAction // the game's public event log, in order
+}
+
+// RecordGameStart stores a game as in progress when it starts, from the GameID, EngineVersion,
+// RulesRevision, Ranked, RoundIndex, and StartedAt of rec. It never overwrites a game that's already
+// recorded, so it's harmless if RecordGameAndResults gets there first.
+func RecordGameStart(ctx context.Context, rec GameRecord) error {
+	q := `
+		INSERT INTO games (id, status, engine_version, rules_revision, ranked, rating_mode, start_time, round_index)
+		VALUES ($1, 'in_progress', $2, $3, $4, NULLIF($5, ''), $6, $7)
+		ON CONFLICT (id) DO NOTHING
+	`
+	_, err := DB.Exec(ctx, q, rec.GameID, rec.EngineVersion, rec.RulesRevision, rec.Ranked,
+		RatingModeForPlayers(len(rec.Players)), rec.StartedAt.UTC(), rec.RoundIndex)
+	if err != nil {
+		return fmt.Errorf("failed to record game start: %w", err)
+	}
+	return nil
+}
+
+// AbandonInterruptedGames marks games still in progress as abandoned, ending them now, except for those in
+// keep. Games live in memory while they're played, so any left in progress when the server starts were
+// cut off by a restart; keep lists the ones that were restored from their checkpoints.
+func AbandonInterruptedGames(ctx context.Context, keep []uuid.UUID) (int64, error) {
+	if keep == nil {
+		keep = []uuid.UUID{}
+	}
+	tag, err := DB.Exec(ctx, `
+		UPDATE games SET status='abandoned', end_time=NOW(), checkpoint=NULL, updated_at=NOW()
+		WHERE status='in_progress' AND NOT (id = ANY($1))
+	`, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to abandon interrupted games: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// AbandonGame marks a single game still in progress as abandoned and drops its checkpoint, so it isn't
+// restored on the next start.
+func AbandonGame(ctx context.Context, gameID uuid.UUID) error {
+	_, err := DB.Exec(ctx, `
+		UPDATE games SET status='abandoned', end_time=NOW(), checkpoint=NULL, updated_at=NOW()
+		WHERE id=$1 AND status='in_progress'
+	`, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to abandon game %v: %w", gameID, err)
+	}
+	return nil
+}
+
+// SaveGameCheckpoint stores the state of an in-progress game as of the start of the given turn, unless a
+// later turn's is already stored.
+func SaveGameCheckpoint(ctx context.Context, gameID uuid.UUID, turn int, state []byte) error {
+	q := `
+		UPDATE games SET checkpoint=$2, checkpoint_turn=$3, updated_at=NOW()
+		WHERE id=$1 AND status='in_progress' AND (checkpoint_turn IS NULL OR checkpoint_turn < $3)
+	`
+	if _, err := DB.Exec(ctx, q, gameID, state, turn); err != nil {
+		return fmt.Errorf("failed to save checkpoint of game %v: %w", gameID, err)
+	}
+	return nil
+}
+
+// LoadGameCheckpoints returns the latest checkpoint of every game still in progress, by game ID.
+func LoadGameCheckpoints(ctx context.Context) (map[uuid.UUID][]byte, error) {
+	rows, err := DB.Query(ctx, `SELECT id, checkpoint FROM games WHERE status='in_progress' AND checkpoint IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game checkpoints: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[uuid.UUID][]byte)
+	for rows.Next() {
+		var id uuid.UUID
+		var state []byte
+		if err := rows.Scan(&id, &state); err != nil {
+			return nil, fmt.Errorf("failed to scan game checkpoint: %w", err)
+		}
+		out[id] = state
+	}
+	return out, rows.Err()
+}
+
+// RecordGameAndResults persists the final outcome of a game, plus updates rating (1v1, 4p, 7p/8p) if it was ranked.
 // We do a basic approach: if players == 2 => "1v1", if 4 => "4p", if 7 or 8 => "7p8p" else no rating update.
-func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*models.Player, finalScores map[uuid.UUID]int, winners []uuid.UUID) error {
+// The engine version and rules revision the game was played under are stored with the game row, and each
+// player's final hand is stored as round 0 of the game's round breakdown, and the game's event log goes to
+// game_actions.
+func RecordGameAndResults(ctx context.Context, rec GameRecord) error {
+	gameID, players, finalScores, winners := rec.GameID, rec.Players, rec.FinalScores, rec.Winners
+	ratingMode := RatingModeForPlayers(len(players))
+
 	// Insert or update games row
 	err := pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
 		// upsert game row if not exist
 		upsertGame := `
-			INSERT INTO games (id, status)
-			VALUES ($1, 'completed')
+			INSERT INTO games (id, status, engine_version, rules_revision, ranked, rating_mode, start_time, end_time, round_index)
+			VALUES ($1, 'completed', $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
 			ON CONFLICT (id) 
-			DO UPDATE SET status = 'completed'
+			DO UPDATE SET status = 'completed', engine_version = $2, rules_revision = $3, ranked = $4,
+				rating_mode = NULLIF($5, ''), start_time = $6, end_time = $7, round_index = $8, checkpoint = NULL
 		`
-		if _, e := tx.Exec(ctx, upsertGame, gameID); e != nil {
+		if _, e := tx.Exec(ctx, upsertGame, gameID, rec.EngineVersion, rec.RulesRevision, rec.Ranked, ratingMode,
+			rec.StartedAt.UTC(), rec.EndedAt.UTC(), rec.RoundIndex); e != nil {
 			return e
 		}
 
@@ -38,14 +156,38 @@ func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*mode
 				}
 			}
 			q := `
-				INSERT INTO game_results (game_id, player_id, score, did_win)
-				VALUES ($1, $2, $3, $4)
+				INSERT INTO game_results (game_id, player_id, score, did_win, ranking)
+				VALUES ($1, $2, $3, $4, $5)
 				ON CONFLICT (game_id, player_id)
-				DO UPDATE SET score=$3, did_win=$4
+				DO UPDATE SET score=$3, did_win=$4, ranking=$5
 			`
-			if _, e2 := tx.Exec(ctx, q, gameID, pl.ID, score, didWin); e2 != nil {
+			if _, e2 := tx.Exec(ctx, q, gameID, pl.ID, score, didWin, placement(pl.ID, finalScores, winners)); e2 != nil {
 				return e2
 			}
+			roundQ := `
+				INSERT INTO game_rounds (game_id, round_index, player_id, score, hand, called_cambia)
+				VALUES ($1, 0, $2, $3, $4, $5)
+				ON CONFLICT (game_id, round_index, player_id)
+				DO UPDATE SET score=$3, hand=$4, called_cambia=$5
+			`
+			if _, e3 := tx.Exec(ctx, roundQ, gameID, pl.ID, score, pl.Hand, pl.ID == rec.CambiaCallerID); e3 != nil {
+				return e3
+			}
+		}
+
+		for i, a := range rec.Actions {
+			var actor *uuid.UUID
+			if a.ActorID != uuid.Nil {
+				actor = &a.ActorID
+			}
+			actionQ := `
+				INSERT INTO game_actions (game_id, action_index, actor_user_id, action_type, action_payload)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (game_id, action_index) DO NOTHING
+			`
+			if _, e4 := tx.Exec(ctx, actionQ, gameID, i, actor, a.ActionType, a.Payload); e4 != nil {
+				return e4
+			}
 		}
 		return nil
 	})
@@ -53,17 +195,8 @@ func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*mode
 		return fmt.Errorf("tx upsert game or results: %w", err)
 	}
 
-	// figure out rating mode
-	var ratingMode string
-	switch len(players) {
-	case 2:
-		ratingMode = "1v1"
-	case 4:
-		ratingMode = "4p"
-	case 7, 8:
-		ratingMode = "7p8p"
-	default:
-		ratingMode = ""
+	if !rec.Ranked {
+		return nil
 	}
 
 	if ratingMode == "" {
@@ -71,9 +204,8 @@ func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*mode
 		return nil
 	}
 
-	// fetch user objects from DB, then run rating.FinalizeRatings
-	// we assume .Elo1v1 for 1v1, but similarly we might do .Elo4p or .Elo7p8p
-	// For brevity, we always do .Elo1v1 in this example
+	// fetch user objects from DB, then run rating.FinalizeRatings. It rates from .Elo1v1, so each copy
+	// carries the rating of the game's mode there.
 	var userList []models.User
 	for _, p := range players {
 		u, err := GetUserByID(ctx, p.ID)
@@ -81,8 +213,13 @@ func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*mode
 			log.Printf("user not found for rating: %v\n", p.ID)
 			continue
 		}
+		u.Elo1v1 = ModeRating(u, ratingMode)
 		userList = append(userList, *u)
 	}
+	oldRatings := make([]int, len(userList))
+	for i, u := range userList {
+		oldRatings[i] = u.Elo1v1
+	}
 
 	// build finalScores => userID => score
 	smap := make(map[uuid.UUID]int)
@@ -94,21 +231,16 @@ func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*mode
 	// store updated rating in DB
 	err = pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
 		for i, uNew := range updated {
-			uOld := userList[i]
-			oldElo := uOld.Elo1v1
+			oldElo := oldRatings[i]
 			newElo := uNew.Elo1v1
 
 			// update user row
-			updQ := `UPDATE users SET elo_1v1=$1 WHERE id=$2`
+			updQ := fmt.Sprintf(`UPDATE users SET %s=$1 WHERE id=$2`, leaderboardColumns[ratingMode])
 			if _, e := tx.Exec(ctx, updQ, newElo, uNew.ID); e != nil {
 				return e
 			}
 			// insert rating record
-			insQ := `
-				INSERT INTO ratings (user_id, game_id, old_rating, new_rating, rating_mode)
-				VALUES ($1, $2, $3, $4, $5)
-			`
-			if _, e2 := tx.Exec(ctx, insQ, uNew.ID, gameID, oldElo, newElo, ratingMode); e2 != nil {
+			if e2 := insertRatingRecordTx(ctx, tx, uNew.ID, gameID, oldElo, newElo, ratingMode); e2 != nil {
 				return e2
 			}
 		}
@@ -120,3 +252,22 @@ func RecordGameAndResults(ctx context.Context, gameID uuid.UUID, players []*mode
 
 	return nil
 }
+
+// placement is a player's finishing position: winners place first, everyone else places after all players
+// with a strictly lower score (and after the winners).
+func placement(playerID uuid.UUID, scores map[uuid.UUID]int, winners []uuid.UUID) int {
+	isWinner := make(map[uuid.UUID]bool, len(winners))
+	for _, w := range winners {
+		isWinner[w] = true
+	}
+	if isWinner[playerID] {
+		return 1
+	}
+	ahead := len(winners)
+	for pid, s := range scores {
+		if !isWinner[pid] && s < scores[playerID] {
+			ahead++
+		}
+	}
+	return ahead + 1
+}
@@ -0,0 +1,225 @@
+// internal/database/leaderboard.go
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// leaderboardColumns maps a rating mode to the users column holding the current rating.
+// Only these values are ever interpolated into leaderboard queries.
+var leaderboardColumns = map[string]string{
+	"1v1":  "elo_1v1",
+	"4p":   "elo_4p",
+	"7p8p": "elo_7p8p",
+}
+
+// ValidLeaderboardMode reports whether mode has a leaderboard.
+func ValidLeaderboardMode(mode string) bool {
+	_, ok := leaderboardColumns[mode]
+	return ok
+}
+
+// ModeRating is u's current rating in a rating mode, which is 1v1 for modes it doesn't know.
+func ModeRating(u *models.User, mode string) int {
+	switch mode {
+	case "4p":
+		return u.Elo4p
+	case "7p8p":
+		return u.Elo7p8p
+	default:
+		return u.Elo1v1
+	}
+}
+
+// LeaderboardEntry is one ranked row on a leaderboard.
+type LeaderboardEntry struct {
+	Rank     int       `json:"rank"`
+	UserID   uuid.UUID `json:"userID"`
+	Username string    `json:"username"`
+	Rating   int       `json:"rating"`
+}
+
+// LeaderboardCursor is the position after the last entry of a page: boards are ordered by rating
+// descending, then user ID ascending.
+type LeaderboardCursor struct {
+	Rating int
+	UserID uuid.UUID
+}
+
+// leaderboardFilter restricts a board to registered, non-deleted users who have played the mode.
+const leaderboardFilter = `
+	NOT u.is_ephemeral AND u.deleted_at IS NULL
+	AND EXISTS (SELECT 1 FROM ratings r WHERE r.user_id = u.id AND r.rating_mode = $1)
+`
+
+// GetLeaderboardPage returns up to limit entries following after (or from the top if after is nil).
+func GetLeaderboardPage(ctx context.Context, mode string, after *LeaderboardCursor, limit int) ([]LeaderboardEntry, error) {
+	col, ok := leaderboardColumns[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard mode %q", mode)
+	}
+
+	// rank of the first row on the page is one more than the number of rows before the cursor
+	startRank := 1
+	args := []interface{}{mode, limit}
+	cursorClause := ""
+	if after != nil {
+		q := fmt.Sprintf(`
+			SELECT COUNT(*) FROM users u
+			WHERE %s AND (u.%s > $2 OR (u.%s = $2 AND u.id <= $3))
+		`, leaderboardFilter, col, col)
+		var before int
+		if err := DB.QueryRow(ctx, q, mode, after.Rating, after.UserID).Scan(&before); err != nil {
+			return nil, fmt.Errorf("failed to count leaderboard offset: %w", err)
+		}
+		startRank = before + 1
+		cursorClause = fmt.Sprintf(`AND (u.%s < $3 OR (u.%s = $3 AND u.id > $4))`, col, col)
+		args = append(args, after.Rating, after.UserID)
+	}
+
+	q := fmt.Sprintf(`
+		SELECT u.id, u.username, u.%s
+		FROM users u
+		WHERE %s %s
+		ORDER BY u.%s DESC, u.id ASC
+		LIMIT $2
+	`, col, leaderboardFilter, cursorClause, col)
+	rows, err := DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leaderboard: %w", err)
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (LeaderboardEntry, error) {
+		var e LeaderboardEntry
+		err := row.Scan(&e.UserID, &e.Username, &e.Rating)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan leaderboard: %w", err)
+	}
+	for i := range entries {
+		entries[i].Rank = startRank + i
+	}
+	return entries, nil
+}
+
+// GetLeaderboardRank returns a user's own entry on a board, or nil if they aren't on it.
+func GetLeaderboardRank(ctx context.Context, mode string, userID uuid.UUID) (*LeaderboardEntry, error) {
+	col, ok := leaderboardColumns[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard mode %q", mode)
+	}
+
+	var e LeaderboardEntry
+	q := fmt.Sprintf(`SELECT u.id, u.username, u.%s FROM users u WHERE u.id = $2 AND %s`, col, leaderboardFilter)
+	err := DB.QueryRow(ctx, q, mode, userID).Scan(&e.UserID, &e.Username, &e.Rating)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leaderboard entry: %w", err)
+	}
+
+	q = fmt.Sprintf(`
+		SELECT COUNT(*) FROM users u
+		WHERE %s AND (u.%s > $2 OR (u.%s = $2 AND u.id < $3))
+	`, leaderboardFilter, col, col)
+	var above int
+	if err := DB.QueryRow(ctx, q, mode, e.Rating, userID).Scan(&above); err != nil {
+		return nil, fmt.Errorf("failed to compute leaderboard rank: %w", err)
+	}
+	e.Rank = above + 1
+	return &e, nil
+}
+
+// seasonLeaderboardFilter restricts a season board to users who finished placement in the mode.
+const seasonLeaderboardFilter = `
+	sr.season_id = $1 AND sr.rating_mode = $2 AND sr.games_played >= $3
+	AND NOT u.is_ephemeral AND u.deleted_at IS NULL
+`
+
+// GetSeasonLeaderboardPage is GetLeaderboardPage for a season's ratings. Past seasons keep their final order.
+func GetSeasonLeaderboardPage(ctx context.Context, seasonID uuid.UUID, mode string, after *LeaderboardCursor, limit int) ([]LeaderboardEntry, error) {
+	if !ValidLeaderboardMode(mode) {
+		return nil, fmt.Errorf("unknown leaderboard mode %q", mode)
+	}
+
+	startRank := 1
+	args := []interface{}{seasonID, mode, SeasonPlacementGames, limit}
+	cursorClause := ""
+	if after != nil {
+		q := `
+			SELECT COUNT(*) FROM season_ratings sr JOIN users u ON u.id = sr.user_id
+			WHERE ` + seasonLeaderboardFilter + ` AND (sr.rating > $4 OR (sr.rating = $4 AND sr.user_id <= $5))
+		`
+		var before int
+		if err := DB.QueryRow(ctx, q, seasonID, mode, SeasonPlacementGames, after.Rating, after.UserID).Scan(&before); err != nil {
+			return nil, fmt.Errorf("failed to count season leaderboard offset: %w", err)
+		}
+		startRank = before + 1
+		cursorClause = `AND (sr.rating < $5 OR (sr.rating = $5 AND sr.user_id > $6))`
+		args = append(args, after.Rating, after.UserID)
+	}
+
+	q := `
+		SELECT u.id, u.username, sr.rating
+		FROM season_ratings sr JOIN users u ON u.id = sr.user_id
+		WHERE ` + seasonLeaderboardFilter + cursorClause + `
+		ORDER BY sr.rating DESC, sr.user_id ASC
+		LIMIT $4
+	`
+	rows, err := DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query season leaderboard: %w", err)
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (LeaderboardEntry, error) {
+		var e LeaderboardEntry
+		err := row.Scan(&e.UserID, &e.Username, &e.Rating)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan season leaderboard: %w", err)
+	}
+	for i := range entries {
+		entries[i].Rank = startRank + i
+	}
+	return entries, nil
+}
+
+// GetSeasonLeaderboardRank is GetLeaderboardRank for a season's ratings.
+func GetSeasonLeaderboardRank(ctx context.Context, seasonID uuid.UUID, mode string, userID uuid.UUID) (*LeaderboardEntry, error) {
+	if !ValidLeaderboardMode(mode) {
+		return nil, fmt.Errorf("unknown leaderboard mode %q", mode)
+	}
+
+	var e LeaderboardEntry
+	q := `
+		SELECT u.id, u.username, sr.rating
+		FROM season_ratings sr JOIN users u ON u.id = sr.user_id
+		WHERE ` + seasonLeaderboardFilter + ` AND sr.user_id = $4
+	`
+	err := DB.QueryRow(ctx, q, seasonID, mode, SeasonPlacementGames, userID).Scan(&e.UserID, &e.Username, &e.Rating)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch season leaderboard entry: %w", err)
+	}
+
+	q = `
+		SELECT COUNT(*) FROM season_ratings sr JOIN users u ON u.id = sr.user_id
+		WHERE ` + seasonLeaderboardFilter + ` AND (sr.rating > $4 OR (sr.rating = $4 AND sr.user_id < $5))
+	`
+	var above int
+	if err := DB.QueryRow(ctx, q, seasonID, mode, SeasonPlacementGames, e.Rating, userID).Scan(&above); err != nil {
+		return nil, fmt.Errorf("failed to compute season leaderboard rank: %w", err)
+	}
+	e.Rank = above + 1
+	return &e, nil
+}
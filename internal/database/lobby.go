@@ -0,0 +1,48 @@
+// internal/database/lobby.go
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LobbyRecord is a lobby and its current settings. Its rules and settings are kept as JSON, in the shape of
+// game.Lobby's houseRules, and of its circuit, series, and lobbySettings under those keys.
+type LobbyRecord struct {
+	ID         uuid.UUID
+	HostUserID uuid.UUID
+	Type       string
+	GameMode   string
+	Ranked     bool
+	HouseRules json.RawMessage
+	Settings   json.RawMessage
+	CreatedAt  time.Time
+}
+
+// InsertLobby records a newly created lobby.
+func InsertLobby(ctx context.Context, rec LobbyRecord) error {
+	_, err := DB.Exec(ctx, `
+		INSERT INTO lobbies (id, host_user_id, type, game_mode, ranked, house_rules, settings, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, rec.ID, rec.HostUserID, rec.Type, rec.GameMode, rec.Ranked, rec.HouseRules, rec.Settings, rec.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record lobby %v: %w", rec.ID, err)
+	}
+	return nil
+}
+
+// UpdateLobby records a lobby's new settings. Its ID, host, and creation time don't change.
+func UpdateLobby(ctx context.Context, rec LobbyRecord) error {
+	_, err := DB.Exec(ctx, `
+		UPDATE lobbies SET type=$2, game_mode=$3, ranked=$4, house_rules=$5, settings=$6
+		WHERE id=$1
+	`, rec.ID, rec.Type, rec.GameMode, rec.Ranked, rec.HouseRules, rec.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to update lobby %v: %w", rec.ID, err)
+	}
+	return nil
+}
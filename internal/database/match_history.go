@@ -0,0 +1,219 @@
+// internal/database/match_history.go
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// MatchParticipant is one player's result in a finished game.
+type MatchParticipant struct {
+	UserID      uuid.UUID `json:"userID"`
+	Username    string    `json:"username"`
+	Score       int       `json:"score"`
+	DidWin      bool      `json:"didWin"`
+	Ranking     int       `json:"ranking"`
+	RatingDelta *int      `json:"ratingDelta,omitempty"`
+}
+
+// MatchSummary is one game in a user's match history.
+type MatchSummary struct {
+	GameID        uuid.UUID          `json:"gameID"`
+	Mode          *string            `json:"mode,omitempty"`
+	Ranked        bool               `json:"ranked"`
+	RulesRevision int                `json:"rulesRevision"`
+	StartedAt     *time.Time         `json:"startedAt,omitempty"`
+	EndedAt       time.Time          `json:"endedAt"`
+	DurationSec   int                `json:"durationSec"`
+	Score         int                `json:"score"`
+	DidWin        bool               `json:"didWin"`
+	Ranking       int                `json:"ranking"`
+	RatingDelta   *int               `json:"ratingDelta,omitempty"`
+	Participants  []MatchParticipant `json:"participants"`
+}
+
+// MatchCursor is the position after the last game of a page: history is ordered by end time descending,
+// then game ID descending.
+type MatchCursor struct {
+	EndedAt time.Time
+	GameID  uuid.UUID
+}
+
+// RoundResult is one player's outcome in a single round.
+type RoundResult struct {
+	UserID       uuid.UUID      `json:"userID"`
+	Score        int            `json:"score"`
+	Hand         []*models.Card `json:"hand"`
+	CalledCambia bool           `json:"calledCambia"`
+}
+
+// GameRound is the breakdown of a single round of a game.
+type GameRound struct {
+	Index   int           `json:"index"`
+	Results []RoundResult `json:"results"`
+}
+
+// GameResult is the full recorded outcome of a finished game.
+type GameResult struct {
+	GameID        uuid.UUID          `json:"gameID"`
+	Mode          *string            `json:"mode,omitempty"`
+	Ranked        bool               `json:"ranked"`
+	EngineVersion *string            `json:"engineVersion,omitempty"`
+	RulesRevision int                `json:"rulesRevision"`
+	StartedAt     *time.Time         `json:"startedAt,omitempty"`
+	EndedAt       *time.Time         `json:"endedAt,omitempty"`
+	DurationSec   int                `json:"durationSec"`
+	Players       []MatchParticipant `json:"players"`
+	Rounds        []GameRound        `json:"rounds"`
+}
+
+// participantColumns selects a MatchParticipant from game_results gr joined with users u; the rating delta
+// comes from the game's rating record, if any.
+const participantColumns = `
+	gr.player_id, u.username, COALESCE(gr.score, 0), COALESCE(gr.did_win, FALSE), COALESCE(gr.ranking, 0),
+	(SELECT r.new_rating - r.old_rating FROM ratings r WHERE r.game_id = gr.game_id AND r.user_id = gr.player_id LIMIT 1)
+`
+
+func durationSec(start, end *time.Time) int {
+	if start == nil || end == nil {
+		return 0
+	}
+	return int(end.Sub(*start).Seconds())
+}
+
+// GetMatchHistory returns up to limit completed games userID played, most recent first, following after
+// (or from the most recent if after is nil).
+func GetMatchHistory(ctx context.Context, userID uuid.UUID, after *MatchCursor, limit int) ([]MatchSummary, error) {
+	args := []interface{}{userID, limit}
+	cursorClause := ""
+	if after != nil {
+		cursorClause = `AND (g.end_time, g.id) < ($3, $4)`
+		args = append(args, after.EndedAt.UTC(), after.GameID)
+	}
+	q := `
+		SELECT g.id, g.rating_mode, g.ranked, g.rules_revision, g.start_time, g.end_time, ` + participantColumns + `
+		FROM game_results gr
+		JOIN games g ON g.id = gr.game_id
+		JOIN users u ON u.id = gr.player_id
+		WHERE gr.player_id = $1 AND g.status = 'completed' AND g.end_time IS NOT NULL ` + cursorClause + `
+		ORDER BY g.end_time DESC, g.id DESC
+		LIMIT $2
+	`
+	rows, err := DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch match history: %w", err)
+	}
+	matches, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (MatchSummary, error) {
+		var m MatchSummary
+		var me MatchParticipant
+		err := row.Scan(&m.GameID, &m.Mode, &m.Ranked, &m.RulesRevision, &m.StartedAt, &m.EndedAt,
+			&me.UserID, &me.Username, &me.Score, &me.DidWin, &me.Ranking, &me.RatingDelta)
+		m.Score, m.DidWin, m.Ranking, m.RatingDelta = me.Score, me.DidWin, me.Ranking, me.RatingDelta
+		m.DurationSec = durationSec(m.StartedAt, &m.EndedAt)
+		m.Participants = []MatchParticipant{}
+		return m, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch match history: %w", err)
+	}
+	if len(matches) == 0 {
+		return matches, nil
+	}
+
+	// fill in everyone who played in the page's games in one query
+	ids := make([]uuid.UUID, len(matches))
+	byID := make(map[uuid.UUID]*MatchSummary, len(matches))
+	for i := range matches {
+		ids[i] = matches[i].GameID
+		byID[matches[i].GameID] = &matches[i]
+	}
+	rows, err = DB.Query(ctx, `
+		SELECT gr.game_id, `+participantColumns+`
+		FROM game_results gr
+		JOIN users u ON u.id = gr.player_id
+		WHERE gr.game_id = ANY($1)
+		ORDER BY gr.game_id, gr.ranking, gr.player_id
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch match participants: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var gameID uuid.UUID
+		var p MatchParticipant
+		if err := rows.Scan(&gameID, &p.UserID, &p.Username, &p.Score, &p.DidWin, &p.Ranking, &p.RatingDelta); err != nil {
+			return nil, fmt.Errorf("failed to scan match participant: %w", err)
+		}
+		if m, ok := byID[gameID]; ok {
+			m.Participants = append(m.Participants, p)
+		}
+	}
+	return matches, rows.Err()
+}
+
+// GetGameResult returns the recorded outcome of a game, including its per-round breakdown, or nil if the
+// game has no recorded result.
+func GetGameResult(ctx context.Context, gameID uuid.UUID) (*GameResult, error) {
+	res := &GameResult{GameID: gameID, Players: []MatchParticipant{}, Rounds: []GameRound{}}
+	err := DB.QueryRow(ctx, `
+		SELECT rating_mode, ranked, engine_version, rules_revision, start_time, end_time
+		FROM games
+		WHERE id = $1 AND status = 'completed'
+	`, gameID).Scan(&res.Mode, &res.Ranked, &res.EngineVersion, &res.RulesRevision, &res.StartedAt, &res.EndedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch game: %w", err)
+	}
+	res.DurationSec = durationSec(res.StartedAt, res.EndedAt)
+
+	rows, err := DB.Query(ctx, `
+		SELECT `+participantColumns+`
+		FROM game_results gr
+		JOIN users u ON u.id = gr.player_id
+		WHERE gr.game_id = $1
+		ORDER BY gr.ranking, gr.player_id
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch game results: %w", err)
+	}
+	res.Players, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (MatchParticipant, error) {
+		var p MatchParticipant
+		err := row.Scan(&p.UserID, &p.Username, &p.Score, &p.DidWin, &p.Ranking, &p.RatingDelta)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch game results: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT round_index, player_id, score, hand, called_cambia
+		FROM game_rounds
+		WHERE game_id = $1
+		ORDER BY round_index, score, player_id
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch game rounds: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var idx int
+		var rr RoundResult
+		if err := rows.Scan(&idx, &rr.UserID, &rr.Score, &rr.Hand, &rr.CalledCambia); err != nil {
+			return nil, fmt.Errorf("failed to scan game round: %w", err)
+		}
+		if n := len(res.Rounds); n == 0 || res.Rounds[n-1].Index != idx {
+			res.Rounds = append(res.Rounds, GameRound{Index: idx})
+		}
+		res.Rounds[len(res.Rounds)-1].Results = append(res.Rounds[len(res.Rounds)-1].Results, rr)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,352 @@
+// internal/database/memory.go
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Memory implements the repositories in process memory, for unit tests and local development without
+// Postgres. Nothing survives a restart, and ranked games don't update ratings.
+type Memory struct {
+	mu      sync.Mutex
+	users   map[uuid.UUID]models.User
+	byEmail map[string]uuid.UUID
+	games   map[uuid.UUID]GameResult
+	started map[uuid.UUID]*memoryCheckpoint // games in progress
+	lobbies map[uuid.UUID]LobbyRecord
+	chat    map[uuid.UUID]memoryChatMessage
+}
+
+// memoryCheckpoint is a game in progress and its latest checkpoint, if any.
+type memoryCheckpoint struct {
+	turn  int
+	state []byte
+}
+
+type memoryChatMessage struct {
+	LobbyID   uuid.UUID
+	UserID    uuid.UUID
+	Msg       string
+	TS        time.Time
+	Reactions map[string][]uuid.UUID
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		users:   make(map[uuid.UUID]models.User),
+		byEmail: make(map[string]uuid.UUID),
+		games:   make(map[uuid.UUID]GameResult),
+		started: make(map[uuid.UUID]*memoryCheckpoint),
+		lobbies: make(map[uuid.UUID]LobbyRecord),
+		chat:    make(map[uuid.UUID]memoryChatMessage),
+	}
+}
+
+func (m *Memory) CreateUser(ctx context.Context, user *models.User) error {
+	if user.ID == uuid.Nil {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return fmt.Errorf("failed to generate user id: %w", err)
+		}
+		user.ID = id
+	}
+	hash, err := auth.CreateHash(user.Password, auth.Params)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if user.Role == "" {
+		user.Role = auth.RoleUser
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[user.ID]; ok {
+		return fmt.Errorf("failed to insert user: id %v already exists", user.ID)
+	}
+	if _, ok := m.byEmail[user.Email]; ok && user.Email != "" {
+		return ErrEmailTaken
+	}
+	user.Password = hash
+	m.users[user.ID] = *user
+	if user.Email != "" {
+		m.byEmail[user.Email] = user.ID
+	}
+	return nil
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &u, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.byEmail[email]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	u := m.users[id]
+	return &u, nil
+}
+
+func (m *Memory) UpdateUserCredentials(ctx context.Context, u *models.User) error {
+	hashed, err := auth.CreateHash(u.Password, auth.Params)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.users[u.ID]
+	if !ok {
+		return nil // like an UPDATE that matches no rows
+	}
+	if owner, taken := m.byEmail[u.Email]; taken && owner != u.ID && u.Email != "" {
+		return fmt.Errorf("failed to update user credentials: %w", ErrEmailTaken)
+	}
+	if stored.Email != "" {
+		delete(m.byEmail, stored.Email)
+	}
+	stored.Email, stored.Password, stored.IsEphemeral = u.Email, hashed, u.IsEphemeral
+	m.users[u.ID] = stored
+	if stored.Email != "" {
+		m.byEmail[stored.Email] = u.ID
+	}
+	return nil
+}
+
+func (m *Memory) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return fmt.Errorf("user %v not found", userID)
+	}
+	u.Role, u.IsAdmin = role, role == auth.RoleAdmin
+	m.users[userID] = u
+	return nil
+}
+
+func (m *Memory) RecordGameStart(ctx context.Context, rec GameRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, done := m.games[rec.GameID]
+	if _, started := m.started[rec.GameID]; !done && !started {
+		m.started[rec.GameID] = &memoryCheckpoint{}
+	}
+	return nil
+}
+
+func (m *Memory) AbandonInterruptedGames(ctx context.Context, keep []uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id := range m.started {
+		if !slices.Contains(keep, id) {
+			delete(m.started, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) AbandonGame(ctx context.Context, gameID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.started, gameID)
+	return nil
+}
+
+func (m *Memory) SaveGameCheckpoint(ctx context.Context, gameID uuid.UUID, turn int, state []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cp, ok := m.started[gameID]; ok && (cp.state == nil || cp.turn < turn) {
+		cp.turn, cp.state = turn, state
+	}
+	return nil
+}
+
+func (m *Memory) LoadGameCheckpoints(ctx context.Context) (map[uuid.UUID][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[uuid.UUID][]byte)
+	for id, cp := range m.started {
+		if cp.state != nil {
+			out[id] = cp.state
+		}
+	}
+	return out, nil
+}
+
+func (m *Memory) RecordGameAndResults(ctx context.Context, rec GameRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.started, rec.GameID)
+
+	startedAt, endedAt := rec.StartedAt.UTC(), rec.EndedAt.UTC()
+	res := GameResult{
+		GameID:        rec.GameID,
+		Ranked:        rec.Ranked,
+		EngineVersion: &rec.EngineVersion,
+		RulesRevision: rec.RulesRevision,
+		StartedAt:     &startedAt,
+		EndedAt:       &endedAt,
+		Players:       []MatchParticipant{},
+		Rounds:        []GameRound{},
+	}
+	if mode := RatingModeForPlayers(len(rec.Players)); mode != "" {
+		res.Mode = &mode
+	}
+	res.DurationSec = durationSec(res.StartedAt, res.EndedAt)
+
+	round := GameRound{Index: 0}
+	for _, pl := range rec.Players {
+		score := rec.FinalScores[pl.ID]
+		res.Players = append(res.Players, MatchParticipant{
+			UserID:   pl.ID,
+			Username: m.users[pl.ID].Username,
+			Score:    score,
+			DidWin:   slices.Contains(rec.Winners, pl.ID),
+			Ranking:  placement(pl.ID, rec.FinalScores, rec.Winners),
+		})
+		round.Results = append(round.Results, RoundResult{
+			UserID:       pl.ID,
+			Score:        score,
+			Hand:         pl.Hand,
+			CalledCambia: pl.ID == rec.CambiaCallerID,
+		})
+	}
+	slices.SortFunc(res.Players, func(a, b MatchParticipant) int {
+		return cmp.Or(cmp.Compare(a.Ranking, b.Ranking), compareUUIDs(a.UserID, b.UserID))
+	})
+	slices.SortFunc(round.Results, func(a, b RoundResult) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), compareUUIDs(a.UserID, b.UserID))
+	})
+	if len(round.Results) > 0 {
+		res.Rounds = append(res.Rounds, round)
+	}
+	m.games[rec.GameID] = res
+	return nil
+}
+
+func compareUUIDs(a, b uuid.UUID) int {
+	return slices.Compare(a[:], b[:])
+}
+
+// copyResult returns a copy of res whose slices the caller may modify.
+func copyResult(res GameResult) *GameResult {
+	res.Players = slices.Clone(res.Players)
+	res.Rounds = slices.Clone(res.Rounds)
+	for i := range res.Rounds {
+		res.Rounds[i].Results = slices.Clone(res.Rounds[i].Results)
+	}
+	return &res
+}
+
+func (m *Memory) GetGameResult(ctx context.Context, gameID uuid.UUID) (*GameResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res, ok := m.games[gameID]
+	if !ok {
+		return nil, nil
+	}
+	return copyResult(res), nil
+}
+
+func (m *Memory) GetMatchHistory(ctx context.Context, userID uuid.UUID, after *MatchCursor, limit int) ([]MatchSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := []MatchSummary{}
+	for _, res := range m.games {
+		i := slices.IndexFunc(res.Players, func(p MatchParticipant) bool { return p.UserID == userID })
+		if i < 0 || res.EndedAt == nil {
+			continue
+		}
+		if after != nil {
+			if c := cmp.Or(res.EndedAt.Compare(after.EndedAt), compareUUIDs(res.GameID, after.GameID)); c >= 0 {
+				continue
+			}
+		}
+		me := res.Players[i]
+		matches = append(matches, MatchSummary{
+			GameID:        res.GameID,
+			Mode:          res.Mode,
+			Ranked:        res.Ranked,
+			RulesRevision: res.RulesRevision,
+			StartedAt:     res.StartedAt,
+			EndedAt:       *res.EndedAt,
+			DurationSec:   res.DurationSec,
+			Score:         me.Score,
+			DidWin:        me.DidWin,
+			Ranking:       me.Ranking,
+			Participants:  slices.Clone(res.Players),
+		})
+	}
+	slices.SortFunc(matches, func(a, b MatchSummary) int {
+		return cmp.Or(b.EndedAt.Compare(a.EndedAt), compareUUIDs(b.GameID, a.GameID))
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (m *Memory) InsertLobby(ctx context.Context, rec LobbyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lobbies[rec.ID]; ok {
+		return fmt.Errorf("lobby %v already exists", rec.ID)
+	}
+	m.lobbies[rec.ID] = rec
+	return nil
+}
+
+func (m *Memory) UpdateLobby(ctx context.Context, rec LobbyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.lobbies[rec.ID]
+	if !ok {
+		return fmt.Errorf("lobby %v not found", rec.ID)
+	}
+	rec.HostUserID, rec.CreatedAt = old.HostUserID, old.CreatedAt
+	m.lobbies[rec.ID] = rec
+	return nil
+}
+
+func (m *Memory) InsertLobbyChatMessage(ctx context.Context, id, lobbyID, userID uuid.UUID, msg string, ts time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chat[id]; ok {
+		return fmt.Errorf("chat message %v already exists", id)
+	}
+	m.chat[id] = memoryChatMessage{LobbyID: lobbyID, UserID: userID, Msg: msg, TS: ts}
+	return nil
+}
+
+func (m *Memory) SetLobbyChatReactions(ctx context.Context, msgID uuid.UUID, reactions map[string][]uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if msg, ok := m.chat[msgID]; ok {
+		msg.Reactions = reactions
+		m.chat[msgID] = msg
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestMemoryUsers(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	u := models.User{Email: "a@example.com", Password: "hunter22", Username: "a"}
+	if err := m.CreateUser(ctx, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID == uuid.Nil || u.Role != auth.RoleUser {
+		t.Fatalf("expected an id and the default role, got %+v", u)
+	}
+	dup := models.User{Email: "a@example.com", Password: "x"}
+	if err := m.CreateUser(ctx, &dup); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+
+	got, err := m.GetUserByEmail(ctx, "a@example.com")
+	if err != nil || got.ID != u.ID {
+		t.Fatalf("expected to find %v by email, got %+v, %v", u.ID, got, err)
+	}
+	if ok, _ := auth.ComparePasswordAndHash("hunter22", got.Password); !ok {
+		t.Fatalf("expected the stored password to be hashed")
+	}
+	if _, err := m.GetUserByID(ctx, uuid.New()); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected pgx.ErrNoRows for a missing user, got %v", err)
+	}
+
+	got.Email, got.Password = "b@example.com", "hunter33"
+	if err := m.UpdateUserCredentials(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetUserByEmail(ctx, "a@example.com"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected the old email to be released, got %v", err)
+	}
+	if err := m.SetUserRole(ctx, u.ID, auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.GetUserByID(ctx, u.ID); got.Email != "b@example.com" || !got.IsAdmin {
+		t.Fatalf("expected the new email and admin role, got %+v", got)
+	}
+}
+
+func TestMemoryGames(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	alice, bob := uuid.New(), uuid.New()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var games []uuid.UUID
+	for i := range 3 {
+		id := uuid.New()
+		games = append(games, id)
+		err := m.RecordGameAndResults(ctx, GameRecord{
+			GameID:      id,
+			StartedAt:   start.Add(time.Duration(i) * time.Hour),
+			EndedAt:     start.Add(time.Duration(i)*time.Hour + 10*time.Minute),
+			Players:     []*models.Player{{ID: alice}, {ID: bob}},
+			FinalScores: map[uuid.UUID]int{alice: 3, bob: 9},
+			Winners:     []uuid.UUID{alice},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unfinished, restored := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{unfinished, restored, games[0]} {
+		if err := m.RecordGameStart(ctx, GameRecord{GameID: id, StartedAt: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.SaveGameCheckpoint(ctx, restored, 2, []byte(`{"turn":2}`))
+	m.SaveGameCheckpoint(ctx, restored, 1, []byte(`{"turn":1}`))
+	if cps, _ := m.LoadGameCheckpoints(ctx); len(cps) != 1 || string(cps[restored]) != `{"turn":2}` {
+		t.Fatalf("expected the latest checkpoint of the restored game, got %q", cps)
+	}
+	if n, _ := m.AbandonInterruptedGames(ctx, []uuid.UUID{restored}); n != 1 {
+		t.Fatalf("expected only the unfinished game to be abandoned, got %d", n)
+	}
+
+	res, err := m.GetGameResult(ctx, games[0])
+	if err != nil || res == nil {
+		t.Fatalf("expected a result, got %v, %v", res, err)
+	}
+	if res.DurationSec != 600 || len(res.Players) != 2 || res.Players[0].UserID != alice || res.Players[1].Ranking != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if res, _ := m.GetGameResult(ctx, uuid.New()); res != nil {
+		t.Fatalf("expected no result for an unknown game, got %+v", res)
+	}
+
+	page, _ := m.GetMatchHistory(ctx, bob, nil, 2)
+	if len(page) != 2 || page[0].GameID != games[2] || page[1].GameID != games[1] || page[0].DidWin {
+		t.Fatalf("expected bob's two latest losses, got %+v", page)
+	}
+	last := page[1]
+	page, _ = m.GetMatchHistory(ctx, bob, &MatchCursor{EndedAt: last.EndedAt, GameID: last.GameID}, 2)
+	if len(page) != 1 || page[0].GameID != games[0] {
+		t.Fatalf("expected the oldest game on the second page, got %+v", page)
+	}
+}
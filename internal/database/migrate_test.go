@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/jason-s-yu/cambia/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"10_b.up.sql":  {Data: []byte("CREATE TABLE b ();")},
+		"2_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"2_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+	ms, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Version != 2 || ms[0].Down == "" || ms[1].Version != 10 || ms[1].Down != "" {
+		t.Fatalf("expected 2_a (reversible) then 10_b, got %+v", ms)
+	}
+
+	fsys["3_c.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE c;")}
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Fatalf("expected a down file without an up file to be rejected")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range ms {
+		if m.Version != i {
+			t.Fatalf("expected migration versions to run 0 through %d without gaps, got %d at %d", len(ms)-1, m.Version, i)
+		}
+		if m.Down == "" {
+			t.Fatalf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// internal/database/moderation.go
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// ErrAlreadyReported is returned when the reporter already has an open report against the offender.
+var ErrAlreadyReported = errors.New("you already have an open report against this player")
+
+// ErrReportNotFound is returned for unknown reports, or ones that were already resolved.
+var ErrReportNotFound = errors.New("report not found or already resolved")
+
+// InsertReport files a player report.
+func InsertReport(ctx context.Context, rep *models.PlayerReport) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	rep.ID = id
+	rep.Status = models.ReportOpen
+	rep.CreatedAt = time.Now().UTC()
+	if rep.ChatSnapshot == nil {
+		rep.ChatSnapshot = []models.ReportedChatMessage{}
+	}
+	tag, err := DB.Exec(ctx, `
+		INSERT INTO player_reports (id, reporter_id, offender_id, reason, lobby_id, game_id, chat_snapshot, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (reporter_id, offender_id) WHERE status = 'open' DO NOTHING
+	`, rep.ID, rep.ReporterID, rep.OffenderID, rep.Reason, rep.LobbyID, rep.GameID, rep.ChatSnapshot, rep.Status, rep.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert report: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyReported
+	}
+	return nil
+}
+
+const reportColumns = `id, reporter_id, offender_id, reason, lobby_id, game_id, chat_snapshot, status, resolved_by, resolution, resolved_at, created_at`
+
+func scanReport(row pgx.CollectableRow) (models.PlayerReport, error) {
+	var r models.PlayerReport
+	err := row.Scan(&r.ID, &r.ReporterID, &r.OffenderID, &r.Reason, &r.LobbyID, &r.GameID, &r.ChatSnapshot,
+		&r.Status, &r.ResolvedBy, &r.Resolution, &r.ResolvedAt, &r.CreatedAt)
+	return r, err
+}
+
+// ListReports returns up to limit reports with the given status, oldest first, after the report with ID
+// after (or from the oldest if after is uuid.Nil).
+func ListReports(ctx context.Context, status string, after uuid.UUID, limit int) ([]models.PlayerReport, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT `+reportColumns+`
+		FROM player_reports
+		WHERE status = $1 AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR id > $2)
+		ORDER BY id
+		LIMIT $3
+	`, status, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	return pgx.CollectRows(rows, scanReport)
+}
+
+// GetReport returns a single report.
+func GetReport(ctx context.Context, id uuid.UUID) (*models.PlayerReport, error) {
+	rows, err := DB.Query(ctx, `SELECT `+reportColumns+` FROM player_reports WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report: %w", err)
+	}
+	r, err := pgx.CollectExactlyOneRow(rows, scanReport)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReportNotFound
+	}
+	return &r, err
+}
+
+// ResolveReport closes an open report with the given status ("actioned" or "dismissed"). If sanction is
+// non-nil it's issued against the offender in the same transaction.
+func ResolveReport(ctx context.Context, reportID, moderatorID uuid.UUID, status, resolution string, sanction *models.Sanction) (*models.PlayerReport, error) {
+	var rep *models.PlayerReport
+	err := pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE player_reports
+			SET status = $3, resolved_by = $2, resolution = $4, resolved_at = $5
+			WHERE id = $1 AND status = 'open'
+			RETURNING `+reportColumns, reportID, moderatorID, status, resolution, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		r, err := pgx.CollectExactlyOneRow(rows, scanReport)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrReportNotFound
+		}
+		if err != nil {
+			return err
+		}
+		rep = &r
+		if sanction == nil {
+			return nil
+		}
+		sanction.UserID = r.OffenderID
+		sanction.ReportID = &r.ID
+		return insertSanctionTx(ctx, tx, sanction)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// IssueSanction records a mute or ban.
+func IssueSanction(ctx context.Context, s *models.Sanction) error {
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		return insertSanctionTx(ctx, tx, s)
+	})
+}
+
+func insertSanctionTx(ctx context.Context, tx pgx.Tx, s *models.Sanction) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	s.ID = id
+	s.CreatedAt = time.Now().UTC()
+	if s.Kind == models.SanctionBan && s.Scope == "" {
+		s.Scope = models.BanScopeGlobal
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_sanctions (id, user_id, kind, scope, reason, issued_by, report_id, expires_at, appeal_notes, created_at)
+		VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'global'), $5, $6, $7, $8, $9, $10)
+	`, s.ID, s.UserID, s.Kind, s.Scope, s.Reason, s.IssuedBy, s.ReportID, s.ExpiresAt, s.AppealNotes, s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert sanction: %w", err)
+	}
+	return nil
+}
+
+// activeSanctionFilter matches sanctions of kind $2 against user $1 that are in effect.
+const activeSanctionFilter = `
+	user_id = $1 AND kind = $2 AND revoked_at IS NULL
+	AND (expires_at IS NULL OR expires_at > NOW() AT TIME ZONE 'UTC')
+`
+
+// activeBanFilter matches bans in effect against user $1 ($2 = 'ban') with a scope in $3.
+const activeBanFilter = activeSanctionFilter + ` AND scope = ANY($3)`
+
+const sanctionColumns = `id, user_id, kind, scope, reason, issued_by, report_id, expires_at, appeal_notes, revoked_at, revoked_by, created_at`
+
+func scanSanction(row pgx.CollectableRow) (models.Sanction, error) {
+	var s models.Sanction
+	var issuedBy *uuid.UUID
+	err := row.Scan(&s.ID, &s.UserID, &s.Kind, &s.Scope, &s.Reason, &issuedBy, &s.ReportID, &s.ExpiresAt,
+		&s.AppealNotes, &s.RevokedAt, &s.RevokedBy, &s.CreatedAt)
+	if issuedBy != nil {
+		s.IssuedBy = *issuedBy
+	}
+	if s.Kind != models.SanctionBan {
+		s.Scope = ""
+	}
+	return s, err
+}
+
+// IsSanctioned reports whether a user is currently under a sanction of the given kind.
+func IsSanctioned(ctx context.Context, userID uuid.UUID, kind string) (bool, error) {
+	var ok bool
+	err := DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM user_sanctions WHERE `+activeSanctionFilter+`)`, userID, kind).Scan(&ok)
+	return ok, err
+}
+
+// ActiveBan returns the longest-running ban in effect against a user with one of the given scopes, or nil.
+func ActiveBan(ctx context.Context, userID uuid.UUID, scopes ...string) (*models.Sanction, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT `+sanctionColumns+`
+		FROM user_sanctions
+		WHERE `+activeBanFilter+`
+		ORDER BY expires_at DESC NULLS FIRST
+		LIMIT 1
+	`, userID, models.SanctionBan, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bans: %w", err)
+	}
+	ban, err := pgx.CollectExactlyOneRow(rows, scanSanction)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bans: %w", err)
+	}
+	return &ban, nil
+}
+
+// ListBans returns up to limit bans, newest first: every user's if userID is uuid.Nil, and only those still
+// in effect if activeOnly is set.
+func ListBans(ctx context.Context, userID uuid.UUID, activeOnly bool, limit int) ([]models.Sanction, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT `+sanctionColumns+`
+		FROM user_sanctions
+		WHERE kind = 'ban'
+		  AND ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR user_id = $1)
+		  AND ($2 = FALSE OR (revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW() AT TIME ZONE 'UTC')))
+		ORDER BY id DESC
+		LIMIT $3
+	`, userID, activeOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+	return pgx.CollectRows(rows, scanSanction)
+}
+
+// ErrBanNotFound is returned for unknown bans.
+var ErrBanNotFound = errors.New("ban not found")
+
+// updateBan applies an update to a single ban and returns it.
+func updateBan(ctx context.Context, banID uuid.UUID, set string, args ...interface{}) (*models.Sanction, error) {
+	rows, err := DB.Query(ctx, `
+		UPDATE user_sanctions SET `+set+`
+		WHERE id = $1 AND kind = 'ban'
+		RETURNING `+sanctionColumns, append([]interface{}{banID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update ban: %w", err)
+	}
+	ban, err := pgx.CollectExactlyOneRow(rows, scanSanction)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update ban: %w", err)
+	}
+	return &ban, nil
+}
+
+// LiftBan ends a ban early. Lifting a ban that already ended is a no-op.
+func LiftBan(ctx context.Context, banID, liftedBy uuid.UUID) (*models.Sanction, error) {
+	return updateBan(ctx, banID, `
+		revoked_at = COALESCE(revoked_at, NOW() AT TIME ZONE 'UTC'),
+		revoked_by = COALESCE(revoked_by, $2)
+	`, liftedBy)
+}
+
+// SetBanAppealNotes replaces the notes on a ban's appeal.
+func SetBanAppealNotes(ctx context.Context, banID uuid.UUID, notes string) (*models.Sanction, error) {
+	return updateBan(ctx, banID, `appeal_notes = $2`, notes)
+}
+
+// RevokeSanctions lifts every active sanction of the given kind against a user, returning how many there were.
+func RevokeSanctions(ctx context.Context, userID, revokedBy uuid.UUID, kind string) (int64, error) {
+	tag, err := DB.Exec(ctx, `
+		UPDATE user_sanctions SET revoked_at = NOW() AT TIME ZONE 'UTC', revoked_by = $3
+		WHERE `+activeSanctionFilter, userID, kind, revokedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sanctions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,78 @@
+// internal/database/notification.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// InsertNotification adds a notification to a user's inbox and returns it.
+func InsertNotification(ctx context.Context, userID uuid.UUID, typ string, payload map[string]interface{}) (*models.Notification, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	n := &models.Notification{ID: id, UserID: userID, Type: typ, Payload: payload, CreatedAt: time.Now().UTC()}
+	_, err = DB.Exec(ctx, `
+		INSERT INTO notifications (id, user_id, type, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, n.ID, n.UserID, n.Type, n.Payload, n.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return n, nil
+}
+
+// ListNotifications returns up to limit of a user's notifications, newest first, older than before (or from
+// the newest if before is uuid.Nil). If unreadOnly is set, read notifications are skipped.
+func ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, before uuid.UUID, limit int) ([]models.Notification, error) {
+	q := `
+		SELECT id, user_id, type, payload, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL) AND ($3 = '00000000-0000-0000-0000-000000000000'::uuid OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	rows, err := DB.Query(ctx, q, userID, unreadOnly, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Notification, error) {
+		var n models.Notification
+		err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.Payload, &n.ReadAt, &n.CreatedAt)
+		return n, err
+	})
+}
+
+// CountUnreadNotifications returns how many unread notifications are in a user's inbox.
+func CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := DB.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// MarkNotificationsRead marks the given notifications (or, if ids is empty, all of them) in a user's inbox as
+// read, returning how many changed.
+func MarkNotificationsRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	q := `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND (cardinality($2::uuid[]) = 0 OR id = ANY($2))
+	`
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+	tag, err := DB.Exec(ctx, q, userID, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
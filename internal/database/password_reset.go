@@ -0,0 +1,110 @@
+// internal/database/password_reset.go
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// ErrInvalidResetToken is returned when a reset token is unknown, expired, or already used.
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// InsertPasswordResetToken stores the hash of a new reset token for a user.
+func InsertPasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	q := `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, q, tokenHash, userID, expiresAt)
+		return err
+	})
+}
+
+// ResetPassword consumes a reset token, sets the user's new password, and revokes every session token
+// issued so far. Other outstanding reset tokens for the user are invalidated as well.
+func ResetPassword(ctx context.Context, tokenHash, newPassword string) (uuid.UUID, error) {
+	hash, err := auth.CreateHash(newPassword, auth.Params)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var userID uuid.UUID
+	err = pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE password_reset_tokens
+			SET used_at=NOW()
+			WHERE token_hash=$1 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING user_id
+		`, tokenHash).Scan(&userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE password_reset_tokens SET used_at=NOW() WHERE user_id=$1 AND used_at IS NULL
+		`, userID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE users
+			SET password=$2, sessions_revoked_at=date_trunc('second', NOW() AT TIME ZONE 'UTC')
+			WHERE id=$1
+		`, userID, hash)
+		return err
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// CheckSessionNotRevoked rejects session tokens issued before the user's sessions were last revoked, and
+// tokens of users who are currently banned. The role in the claims is replaced with the user's current
+// one, so a role change takes effect on tokens that are already out. It is meant to be installed as
+// auth.SessionValidator.
+func CheckSessionNotRevoked(ctx context.Context, claims *auth.Claims) error {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user id in token: %w", err)
+	}
+	var revokedAt *time.Time
+	var banned bool
+	var role string
+	q := `
+		SELECT sessions_revoked_at,
+		       EXISTS (SELECT 1 FROM user_sanctions WHERE ` + activeBanFilter + `),
+		       role
+		FROM users
+		WHERE id=$1
+	`
+	err = DB.QueryRow(ctx, q, userID, models.SanctionBan, []string{models.BanScopeGlobal}).Scan(&revokedAt, &banned, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("user %v not found", userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check session revocation: %w", err)
+	}
+	if revokedAt != nil && claims.IssuedAt.Before(*revokedAt) {
+		return fmt.Errorf("session revoked")
+	}
+	if banned {
+		return auth.ErrBanned
+	}
+	claims.Role = auth.RoleUser
+	if auth.ValidRole(role) {
+		claims.Role = role
+	}
+	return nil
+}
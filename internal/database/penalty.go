@@ -0,0 +1,101 @@
+// internal/database/penalty.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/penalty"
+)
+
+// RecordAbandon logs an abandoned ranked game and applies the escalating penalty for it: a queue cooldown
+// or ranked ban, and a rating forfeit in mode (if mode is rated). Recording the same game twice is a no-op.
+func RecordAbandon(ctx context.Context, userID, gameID uuid.UUID, mode string) (*penalty.Penalty, error) {
+	var applied *penalty.Penalty
+	err := pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		col, rated := leaderboardColumns[mode]
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO game_abandons (user_id, game_id, rating_mode)
+			VALUES ($1, $2, NULLIF($3, ''))
+			ON CONFLICT (user_id, game_id) DO NOTHING
+		`, userID, gameID, mode)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		var recent int
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM game_abandons WHERE user_id=$1 AND created_at > NOW() - make_interval(secs => $2)
+		`, userID, penalty.Window.Seconds()).Scan(&recent); err != nil {
+			return err
+		}
+		p := penalty.For(recent)
+		applied = &p
+
+		now := time.Now().UTC()
+		if p.QueueCooldown > 0 {
+			if _, err := tx.Exec(ctx, `
+				UPDATE users SET queue_cooldown_until = GREATEST(COALESCE(queue_cooldown_until, $2), $2) WHERE id=$1
+			`, userID, now.Add(p.QueueCooldown)); err != nil {
+				return err
+			}
+		}
+		if p.RankedBan > 0 {
+			if _, err := tx.Exec(ctx, `
+				UPDATE users SET ranked_ban_until = GREATEST(COALESCE(ranked_ban_until, $2), $2) WHERE id=$1
+			`, userID, now.Add(p.RankedBan)); err != nil {
+				return err
+			}
+		}
+
+		if !rated || p.RatingForfeit == 0 {
+			return nil
+		}
+		var oldRating int
+		q := fmt.Sprintf(`UPDATE users SET %s = %s - $2 WHERE id=$1 RETURNING %s + $2`, col, col, col)
+		if err := tx.QueryRow(ctx, q, userID, p.RatingForfeit).Scan(&oldRating); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE game_abandons SET rating_forfeited=$3 WHERE user_id=$1 AND game_id=$2
+		`, userID, gameID, p.RatingForfeit); err != nil {
+			return err
+		}
+		return insertRatingRecordTx(ctx, tx, userID, gameID, oldRating, oldRating-p.RatingForfeit, mode)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record abandon: %w", err)
+	}
+	return applied, nil
+}
+
+// GetPenaltyStatus returns a user's current cooldown, ban, and recent abandon count. Expired cooldowns
+// and bans are omitted.
+func GetPenaltyStatus(ctx context.Context, userID uuid.UUID) (*penalty.Status, error) {
+	var s penalty.Status
+	err := DB.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM game_abandons WHERE user_id=$1 AND created_at > NOW() - make_interval(secs => $2)),
+			queue_cooldown_until,
+			ranked_ban_until
+		FROM users WHERE id=$1
+	`, userID, penalty.Window.Seconds()).Scan(&s.RecentAbandons, &s.QueueCooldownUntil, &s.RankedBanUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load penalty status: %w", err)
+	}
+	now := time.Now()
+	if s.QueueCooldownUntil != nil && !now.Before(*s.QueueCooldownUntil) {
+		s.QueueCooldownUntil = nil
+	}
+	if s.RankedBanUntil != nil && !now.Before(*s.RankedBanUntil) {
+		s.RankedBanUntil = nil
+	}
+	return &s, nil
+}
@@ -0,0 +1,221 @@
+// internal/database/play_stats.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// abilityExpr names the ability a player_special_action in game_actions a used. A king's ability is logged
+// twice, once as it reveals and again if it swaps, so the reveal stands for it and the swap is left out
+// with abilityFilter.
+const (
+	abilityExpr   = `CASE a.action_payload->>'special' WHEN 'swap_peek_reveal' THEN 'swap_peek' ELSE a.action_payload->>'special' END`
+	abilityFilter = `a.action_type = 'player_special_action' AND a.action_payload->>'special' <> 'swap_peek_swap'`
+)
+
+// cambiaTurns lists each player's first Cambia call in each game from game_actions, with the turn of
+// theirs they called it on: one more than the times they'd drawn, since calling Cambia takes the place of
+// a draw.
+const cambiaTurns = `
+	SELECT DISTINCT ON (c.game_id, c.actor_user_id) c.actor_user_id, c.created_at,
+	       1 + (SELECT COUNT(*) FROM game_actions d
+	            WHERE d.game_id = c.game_id AND d.actor_user_id = c.actor_user_id AND d.action_index < c.action_index
+	              AND d.action_type IN ('player_draw_stockpile', 'player_draw_discardpile')) AS turn
+	FROM game_actions c
+	WHERE c.action_type = 'player_cambia'
+	ORDER BY c.game_id, c.actor_user_id, c.action_index
+`
+
+// PlayStats is how a player uses abilities, snaps, and calls Cambia, from the actions of their games.
+type PlayStats struct {
+	UserID    uuid.UUID      `json:"userID"`
+	Username  string         `json:"username,omitempty"`
+	Abilities map[string]int `json:"abilities"` // uses of each ability, e.g. "peek_self"
+
+	SnapAttempts int     `json:"snapAttempts"`
+	SnapAccuracy float64 `json:"snapAccuracy"` // successful snaps / snap attempts
+
+	CambiaCalls       int     `json:"cambiaCalls"`
+	AverageCambiaTurn float64 `json:"averageCambiaTurn"` // which of their own turns they called Cambia on, on average
+
+	snapSuccesses, cambiaTurnSum int
+}
+
+// finish works out the ratios from the counts.
+func (s *PlayStats) finish() {
+	s.SnapAccuracy = ratio(s.snapSuccesses, s.SnapAttempts)
+	if s.CambiaCalls > 0 {
+		s.AverageCambiaTurn = float64(s.cambiaTurnSum) / float64(s.CambiaCalls)
+	}
+}
+
+// add counts o's play into s.
+func (s *PlayStats) add(o *PlayStats) {
+	for ability, n := range o.Abilities {
+		s.Abilities[ability] += n
+	}
+	s.SnapAttempts += o.SnapAttempts
+	s.snapSuccesses += o.snapSuccesses
+	s.CambiaCalls += o.CambiaCalls
+	s.cambiaTurnSum += o.cambiaTurnSum
+}
+
+// PlayStatsReport is the operator-wide report on play: everyone's stats together and a page of players'.
+type PlayStatsReport struct {
+	Since   time.Time    `json:"since"`
+	Overall PlayStats    `json:"overall"`
+	Players []*PlayStats `json:"players"`
+}
+
+// GetPlayStats computes a player's stats over all of their games.
+func GetPlayStats(ctx context.Context, userID uuid.UUID) (*PlayStats, error) {
+	stats, err := playStats(ctx, time.Time{}, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := stats[userID]; ok {
+		return s, nil
+	}
+	return &PlayStats{UserID: userID, Abilities: map[string]int{}}, nil
+}
+
+// GetPlayStatsReport computes the stats of play since the given time: overall, and for up to limit of the
+// players who played, in user ID order after the cursor after (uuid.Nil for the first page).
+func GetPlayStatsReport(ctx context.Context, since time.Time, after uuid.UUID, limit int) (*PlayStatsReport, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT DISTINCT a.actor_user_id
+		FROM game_actions a
+		WHERE a.actor_user_id IS NOT NULL AND a.created_at >= $1 AND a.actor_user_id > $2
+		ORDER BY 1
+		LIMIT $3
+	`, since.UTC(), after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list players: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list players: %w", err)
+	}
+
+	report := &PlayStatsReport{Since: since.UTC(), Overall: PlayStats{Abilities: map[string]int{}}, Players: []*PlayStats{}}
+	everyone, err := playStats(ctx, since, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range everyone {
+		report.Overall.add(s)
+	}
+	report.Overall.finish()
+	if len(ids) == 0 {
+		return report, nil
+	}
+
+	page, err := playStats(ctx, since, ids)
+	if err != nil {
+		return nil, err
+	}
+	names, err := DB.Query(ctx, `SELECT id, COALESCE(username, '') FROM users WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usernames: %w", err)
+	}
+	usernames := make(map[uuid.UUID]string, len(ids))
+	var id uuid.UUID
+	var name string
+	if _, err := pgx.ForEachRow(names, []any{&id, &name}, func() error {
+		usernames[id] = name
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load usernames: %w", err)
+	}
+	for _, id := range ids {
+		s, ok := page[id]
+		if !ok {
+			s = &PlayStats{UserID: id, Abilities: map[string]int{}}
+		}
+		s.Username = usernames[id]
+		report.Players = append(report.Players, s)
+	}
+	return report, nil
+}
+
+// playStats computes the stats of play since the given time by player, for the players in users, or for
+// every player if users is nil.
+func playStats(ctx context.Context, since time.Time, users []uuid.UUID) (map[uuid.UUID]*PlayStats, error) {
+	stats := make(map[uuid.UUID]*PlayStats)
+	get := func(id uuid.UUID) *PlayStats {
+		s, ok := stats[id]
+		if !ok {
+			s = &PlayStats{UserID: id, Abilities: map[string]int{}}
+			stats[id] = s
+		}
+		return s
+	}
+	since = since.UTC()
+	filter := `a.actor_user_id IS NOT NULL AND a.created_at >= $1 AND ($2::uuid[] IS NULL OR a.actor_user_id = ANY($2))`
+
+	var (
+		id     uuid.UUID
+		name   string
+		n, sum int
+	)
+	rows, err := DB.Query(ctx, `
+		SELECT a.actor_user_id, `+abilityExpr+`, COUNT(*)
+		FROM game_actions a
+		WHERE `+abilityFilter+` AND `+filter+`
+		GROUP BY 1, 2
+	`, since, users)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ability stats: %w", err)
+	}
+	if _, err := pgx.ForEachRow(rows, []any{&id, &name, &n}, func() error {
+		get(id).Abilities[name] = n
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load ability stats: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT a.actor_user_id, COUNT(*) FILTER (WHERE a.action_type = 'player_snap_success'), COUNT(*)
+		FROM game_actions a
+		WHERE a.action_type IN ('player_snap_success', 'player_snap_fail') AND `+filter+`
+		GROUP BY 1
+	`, since, users)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snap stats: %w", err)
+	}
+	if _, err := pgx.ForEachRow(rows, []any{&id, &n, &sum}, func() error {
+		s := get(id)
+		s.snapSuccesses, s.SnapAttempts = n, sum
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load snap stats: %w", err)
+	}
+
+	rows, err = DB.Query(ctx, `
+		SELECT a.actor_user_id, COUNT(*), COALESCE(SUM(a.turn), 0)
+		FROM (`+cambiaTurns+`) a
+		WHERE `+filter+`
+		GROUP BY 1
+	`, since, users)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cambia stats: %w", err)
+	}
+	if _, err := pgx.ForEachRow(rows, []any{&id, &n, &sum}, func() error {
+		s := get(id)
+		s.CambiaCalls, s.cambiaTurnSum = n, sum
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load cambia stats: %w", err)
+	}
+
+	for _, s := range stats {
+		s.finish()
+	}
+	return stats, nil
+}
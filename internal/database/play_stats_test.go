@@ -0,0 +1,32 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPlayStatsTotals(t *testing.T) {
+	a := &PlayStats{UserID: uuid.New(), Abilities: map[string]int{"peek_self": 3}, SnapAttempts: 4, snapSuccesses: 3, CambiaCalls: 1, cambiaTurnSum: 6}
+	b := &PlayStats{UserID: uuid.New(), Abilities: map[string]int{"peek_self": 1, "swap_peek": 2}, SnapAttempts: 4, snapSuccesses: 1, CambiaCalls: 3, cambiaTurnSum: 6}
+
+	total := PlayStats{Abilities: map[string]int{}}
+	total.add(a)
+	total.add(b)
+	total.finish()
+	if total.Abilities["peek_self"] != 4 || total.Abilities["swap_peek"] != 2 {
+		t.Fatalf("expected ability uses to add up, got %v", total.Abilities)
+	}
+	if total.SnapAccuracy != 0.5 {
+		t.Fatalf("expected a snap accuracy of 4/8, got %v", total.SnapAccuracy)
+	}
+	if total.AverageCambiaTurn != 3 {
+		t.Fatalf("expected Cambia to be called on turn 12/4 on average, got %v", total.AverageCambiaTurn)
+	}
+
+	var none PlayStats
+	none.finish()
+	if none.SnapAccuracy != 0 || none.AverageCambiaTurn != 0 {
+		t.Fatal("expected no play to give zero ratios")
+	}
+}
@@ -0,0 +1,127 @@
+// internal/database/profile.go
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUserNotFound is returned for users that don't exist or have been deleted.
+var ErrUserNotFound = errors.New("user not found")
+
+// ModeStats is a player's record in one rating mode; unrated player counts are grouped as "unrated".
+type ModeStats struct {
+	Mode        string  `json:"mode"`
+	GamesPlayed int     `json:"gamesPlayed"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"winRate"`
+}
+
+// PlayerProfile is a user's public profile with aggregate stats over their completed games.
+type PlayerProfile struct {
+	UserID       uuid.UUID   `json:"userID"`
+	Username     string      `json:"username"`
+	AvatarURL    *string     `json:"avatarURL,omitempty"`
+	GamesPlayed  int         `json:"gamesPlayed"`
+	Wins         int         `json:"wins"`
+	WinRate      float64     `json:"winRate"`
+	AverageScore float64     `json:"averageScore"`
+	Modes        []ModeStats `json:"modes"`
+
+	Abilities map[string]int `json:"abilities"` // uses of each ability, e.g. "peek_self"
+
+	SnapAttempts int     `json:"snapAttempts"`
+	SnapAccuracy float64 `json:"snapAccuracy"` // successful snaps / snap attempts
+
+	CambiaCalls       int     `json:"cambiaCalls"`
+	CambiaSuccessRate float64 `json:"cambiaSuccessRate"` // games won after calling Cambia / Cambia calls
+	AverageCambiaTurn float64 `json:"averageCambiaTurn"` // which of their own turns they called Cambia on, on average
+}
+
+// ratio returns n/d, or 0 if d is 0.
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// GetPlayerProfile computes a user's profile from their persisted game results, rounds, and actions; see
+// GetPlayStats.
+func GetPlayerProfile(ctx context.Context, userID uuid.UUID) (*PlayerProfile, error) {
+	p := &PlayerProfile{UserID: userID, Modes: []ModeStats{}}
+	err := DB.QueryRow(ctx, `SELECT COALESCE(username, ''), avatar_url FROM users WHERE id=$1 AND deleted_at IS NULL`, userID).
+		Scan(&p.Username, &p.AvatarURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	rows, err := DB.Query(ctx, `
+		SELECT COALESCE(g.rating_mode, 'unrated'), COUNT(*), COUNT(*) FILTER (WHERE gr.did_win), COALESCE(SUM(gr.score), 0)
+		FROM game_results gr
+		JOIN games g ON g.id = gr.game_id
+		WHERE gr.player_id = $1 AND g.status = 'completed'
+		GROUP BY 1
+		ORDER BY 1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game stats: %w", err)
+	}
+	var totalScore int
+	modes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ModeStats, error) {
+		var m ModeStats
+		var score int
+		if err := row.Scan(&m.Mode, &m.GamesPlayed, &m.Wins, &score); err != nil {
+			return m, err
+		}
+		m.WinRate = ratio(m.Wins, m.GamesPlayed)
+		p.GamesPlayed += m.GamesPlayed
+		p.Wins += m.Wins
+		totalScore += score
+		return m, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game stats: %w", err)
+	}
+	p.Modes = append(p.Modes, modes...)
+	p.WinRate = ratio(p.Wins, p.GamesPlayed)
+	if p.GamesPlayed > 0 {
+		p.AverageScore = float64(totalScore) / float64(p.GamesPlayed)
+	}
+
+	play, err := GetPlayStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Abilities = play.Abilities
+	p.SnapAttempts, p.SnapAccuracy = play.SnapAttempts, play.SnapAccuracy
+	p.AverageCambiaTurn = play.AverageCambiaTurn
+
+	var cambiaWins int
+	err = DB.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE res.did_win)
+		FROM game_rounds rd
+		JOIN game_results res ON res.game_id = rd.game_id AND res.player_id = rd.player_id
+		WHERE rd.player_id = $1 AND rd.called_cambia
+	`, userID).Scan(&p.CambiaCalls, &cambiaWins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cambia stats: %w", err)
+	}
+	p.CambiaSuccessRate = ratio(cambiaWins, p.CambiaCalls)
+
+	return p, nil
+}
+
+// SetAvatarURL sets (or, with nil, clears) a user's avatar.
+func SetAvatarURL(ctx context.Context, userID uuid.UUID, url *string) error {
+	_, err := DB.Exec(ctx, `UPDATE users SET avatar_url=$2 WHERE id=$1`, userID, url)
+	return err
+}
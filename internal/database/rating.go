@@ -17,15 +17,10 @@ func UpdateUser1v1Rating(ctx context.Context, userID uuid.UUID, newRating int) e
 	})
 }
 
-// InsertRatingRecord logs a rating change in the 'ratings' table
+// InsertRatingRecord logs a rating change in the 'ratings' table, and against the active season if any
 func InsertRatingRecord(ctx context.Context, userID, gameID uuid.UUID, oldRating, newRating int, mode string) error {
-	q := `
-		INSERT INTO ratings (user_id, game_id, old_rating, new_rating, rating_mode)
-		VALUES ($1, $2, $3, $4, $5)
-	`
 	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
-		_, err := tx.Exec(ctx, q, userID, gameID, oldRating, newRating, mode)
-		return err
+		return insertRatingRecordTx(ctx, tx, userID, gameID, oldRating, newRating, mode)
 	})
 }
 
@@ -38,14 +33,10 @@ func Commit1v1MatchResults(ctx context.Context, winnerID, loserID, gameID uuid.U
 		if _, e2 := tx.Exec(ctx, `UPDATE users SET elo_1v1 = $1 WHERE id = $2`, newLRating, loserID); e2 != nil {
 			return e2
 		}
-		_, e3 := tx.Exec(ctx, `
-			INSERT INTO ratings (user_id, game_id, old_rating, new_rating, rating_mode)
-			VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10)
-		`,
-			winnerID, gameID, oldWRating, newWRating, "1v1",
-			loserID, gameID, oldLRating, newLRating, "1v1",
-		)
-		return e3
+		if e3 := insertRatingRecordTx(ctx, tx, winnerID, gameID, oldWRating, newWRating, "1v1"); e3 != nil {
+			return e3
+		}
+		return insertRatingRecordTx(ctx, tx, loserID, gameID, oldLRating, newLRating, "1v1")
 	})
 	if err != nil {
 		return fmt.Errorf("failed to commit 1v1 match results: %w", err)
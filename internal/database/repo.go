@@ -0,0 +1,132 @@
+// internal/database/repo.go
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Repositories. Accounts, finished games, and lobbies and their chat are reached through these interfaces so the server
+// and its tests can run on the in-memory implementation (see Memory) instead of Postgres. Lookups of a
+// missing user return pgx.ErrNoRows from either implementation.
+
+// ErrEmailTaken is returned by CreateUser when another account already has the email.
+var ErrEmailTaken = errors.New("email already exists")
+
+// UserRepo stores accounts.
+type UserRepo interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUserCredentials(ctx context.Context, u *models.User) error
+	SetUserRole(ctx context.Context, userID uuid.UUID, role string) error
+}
+
+// GameRepo stores games as they start and the outcomes of finished ones.
+type GameRepo interface {
+	RecordGameStart(ctx context.Context, rec GameRecord) error
+	AbandonInterruptedGames(ctx context.Context, keep []uuid.UUID) (int64, error)
+	AbandonGame(ctx context.Context, gameID uuid.UUID) error
+	SaveGameCheckpoint(ctx context.Context, gameID uuid.UUID, turn int, state []byte) error
+	LoadGameCheckpoints(ctx context.Context) (map[uuid.UUID][]byte, error)
+	RecordGameAndResults(ctx context.Context, rec GameRecord) error
+	GetGameResult(ctx context.Context, gameID uuid.UUID) (*GameResult, error)
+	GetMatchHistory(ctx context.Context, userID uuid.UUID, after *MatchCursor, limit int) ([]MatchSummary, error)
+}
+
+// LobbyRepo stores lobbies and their settings, and their chat.
+type LobbyRepo interface {
+	InsertLobby(ctx context.Context, rec LobbyRecord) error
+	UpdateLobby(ctx context.Context, rec LobbyRecord) error
+	InsertLobbyChatMessage(ctx context.Context, id, lobbyID, userID uuid.UUID, msg string, ts time.Time) error
+	SetLobbyChatReactions(ctx context.Context, msgID uuid.UUID, reactions map[string][]uuid.UUID) error
+}
+
+// The repositories in use. They default to Postgres; UseMemory swaps them all for an in-memory store.
+var (
+	Users   UserRepo  = Postgres{}
+	Games   GameRepo  = Postgres{}
+	Lobbies LobbyRepo = Postgres{}
+)
+
+// Postgres implements the repositories on DB.
+type Postgres struct{}
+
+func (Postgres) CreateUser(ctx context.Context, user *models.User) error {
+	return CreateUser(ctx, user)
+}
+
+func (Postgres) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return GetUserByID(ctx, id)
+}
+
+func (Postgres) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return GetUserByEmail(ctx, email)
+}
+
+func (Postgres) UpdateUserCredentials(ctx context.Context, u *models.User) error {
+	return UpdateUserCredentials(ctx, u)
+}
+
+func (Postgres) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return SetUserRole(ctx, userID, role)
+}
+
+func (Postgres) RecordGameStart(ctx context.Context, rec GameRecord) error {
+	return RecordGameStart(ctx, rec)
+}
+
+func (Postgres) AbandonInterruptedGames(ctx context.Context, keep []uuid.UUID) (int64, error) {
+	return AbandonInterruptedGames(ctx, keep)
+}
+
+func (Postgres) AbandonGame(ctx context.Context, gameID uuid.UUID) error {
+	return AbandonGame(ctx, gameID)
+}
+
+func (Postgres) SaveGameCheckpoint(ctx context.Context, gameID uuid.UUID, turn int, state []byte) error {
+	return SaveGameCheckpoint(ctx, gameID, turn, state)
+}
+
+func (Postgres) LoadGameCheckpoints(ctx context.Context) (map[uuid.UUID][]byte, error) {
+	return LoadGameCheckpoints(ctx)
+}
+
+func (Postgres) RecordGameAndResults(ctx context.Context, rec GameRecord) error {
+	return RecordGameAndResults(ctx, rec)
+}
+
+func (Postgres) GetGameResult(ctx context.Context, gameID uuid.UUID) (*GameResult, error) {
+	return GetGameResult(ctx, gameID)
+}
+
+func (Postgres) GetMatchHistory(ctx context.Context, userID uuid.UUID, after *MatchCursor, limit int) ([]MatchSummary, error) {
+	return GetMatchHistory(ctx, userID, after, limit)
+}
+
+func (Postgres) InsertLobby(ctx context.Context, rec LobbyRecord) error {
+	return InsertLobby(ctx, rec)
+}
+
+func (Postgres) UpdateLobby(ctx context.Context, rec LobbyRecord) error {
+	return UpdateLobby(ctx, rec)
+}
+
+func (Postgres) InsertLobbyChatMessage(ctx context.Context, id, lobbyID, userID uuid.UUID, msg string, ts time.Time) error {
+	return InsertLobbyChatMessage(ctx, id, lobbyID, userID, msg, ts)
+}
+
+func (Postgres) SetLobbyChatReactions(ctx context.Context, msgID uuid.UUID, reactions map[string][]uuid.UUID) error {
+	return SetLobbyChatReactions(ctx, msgID, reactions)
+}
+
+// UseMemory switches every repository to a fresh in-memory store and returns it.
+func UseMemory() *Memory {
+	m := NewMemory()
+	Users, Games, Lobbies = m, m, m
+	return m
+}
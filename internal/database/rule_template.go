@@ -0,0 +1,108 @@
+// internal/database/rule_template.go
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxRuleTemplates is how many rule templates a user may save.
+const MaxRuleTemplates = 20
+
+// ErrTooManyRuleTemplates is returned when saving a new template would exceed MaxRuleTemplates.
+var ErrTooManyRuleTemplates = errors.New("too many rule templates")
+
+// RuleTemplate is a named bundle of house rules a user saved. The rules are kept as JSON, in the shape of
+// game.HouseRules.
+type RuleTemplate struct {
+	ID         uuid.UUID       `json:"id"`
+	Name       string          `json:"name"`
+	HouseRules json.RawMessage `json:"houseRules"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+// SaveRuleTemplate saves rules under name for userID, replacing the user's template of the same name if
+// there is one.
+func SaveRuleTemplate(ctx context.Context, userID uuid.UUID, name string, rules json.RawMessage) (*RuleTemplate, error) {
+	t := &RuleTemplate{}
+	err := pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		var count int
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM rule_templates WHERE user_id=$1 AND name<>$2
+		`, userID, name).Scan(&count); err != nil {
+			return err
+		}
+		if count >= MaxRuleTemplates {
+			return ErrTooManyRuleTemplates
+		}
+		id, _ := uuid.NewV7()
+		return tx.QueryRow(ctx, `
+			INSERT INTO rule_templates (id, user_id, name, house_rules)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, name) DO UPDATE SET house_rules=EXCLUDED.house_rules, updated_at=NOW()
+			RETURNING id, name, house_rules, created_at, updated_at
+		`, id, userID, name, rules).Scan(&t.ID, &t.Name, &t.HouseRules, &t.CreatedAt, &t.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, ErrTooManyRuleTemplates) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save rule template: %w", err)
+	}
+	return t, nil
+}
+
+// ListRuleTemplates returns userID's rule templates, by name.
+func ListRuleTemplates(ctx context.Context, userID uuid.UUID) ([]RuleTemplate, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT id, name, house_rules, created_at, updated_at
+		FROM rule_templates
+		WHERE user_id=$1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanRuleTemplate)
+}
+
+// GetRuleTemplate returns one of userID's rule templates. It returns pgx.ErrNoRows if the template doesn't
+// exist or belongs to someone else.
+func GetRuleTemplate(ctx context.Context, userID, templateID uuid.UUID) (*RuleTemplate, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT id, name, house_rules, created_at, updated_at
+		FROM rule_templates
+		WHERE id=$1 AND user_id=$2
+	`, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := pgx.CollectExactlyOneRow(rows, scanRuleTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// DeleteRuleTemplate deletes one of userID's rule templates, reporting whether it existed.
+func DeleteRuleTemplate(ctx context.Context, userID, templateID uuid.UUID) (bool, error) {
+	tag, err := DB.Exec(ctx, `DELETE FROM rule_templates WHERE id=$1 AND user_id=$2`, templateID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanRuleTemplate(row pgx.CollectableRow) (RuleTemplate, error) {
+	var t RuleTemplate
+	err := row.Scan(&t.ID, &t.Name, &t.HouseRules, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
@@ -0,0 +1,258 @@
+// internal/database/season.go
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// SeasonPlacementGames is how many games in a mode a user must play in a season before they are ranked.
+const SeasonPlacementGames = 5
+
+// ErrSeasonOverlap is returned when a new season would overlap an existing one.
+var ErrSeasonOverlap = errors.New("season overlaps an existing season")
+
+// Season timestamps are stored as UTC in TIMESTAMP columns, so compare against NOW() in UTC.
+const nowUTC = `(NOW() AT TIME ZONE 'UTC')`
+
+const seasonColumns = `id, name, starts_at, ends_at, finalized_at`
+
+func scanSeason(row pgx.Row) (*models.Season, error) {
+	var s models.Season
+	if err := row.Scan(&s.ID, &s.Name, &s.StartsAt, &s.EndsAt, &s.FinalizedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSeason inserts a new season. Seasons may not overlap.
+func CreateSeason(ctx context.Context, s *models.Season) error {
+	if s.ID == uuid.Nil {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate season id: %w", err)
+		}
+		s.ID = id
+	}
+	s.StartsAt, s.EndsAt = s.StartsAt.UTC(), s.EndsAt.UTC()
+
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+		var overlaps bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM seasons WHERE starts_at < $2 AND ends_at > $1)
+		`, s.StartsAt, s.EndsAt).Scan(&overlaps); err != nil {
+			return err
+		}
+		if overlaps {
+			return ErrSeasonOverlap
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO seasons (id, name, starts_at, ends_at)
+			VALUES ($1, $2, $3, $4)
+		`, s.ID, s.Name, s.StartsAt, s.EndsAt)
+		return err
+	})
+}
+
+// GetSeason fetches a season by ID.
+func GetSeason(ctx context.Context, id uuid.UUID) (*models.Season, error) {
+	return scanSeason(DB.QueryRow(ctx, `SELECT `+seasonColumns+` FROM seasons WHERE id=$1`, id))
+}
+
+// GetActiveSeason returns the season in progress, or nil if there is none.
+func GetActiveSeason(ctx context.Context) (*models.Season, error) {
+	s, err := scanSeason(DB.QueryRow(ctx, `
+		SELECT `+seasonColumns+` FROM seasons
+		WHERE starts_at <= `+nowUTC+` AND ends_at > `+nowUTC+`
+	`))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// ListSeasons returns all seasons, newest first.
+func ListSeasons(ctx context.Context) ([]models.Season, error) {
+	rows, err := DB.Query(ctx, `SELECT `+seasonColumns+` FROM seasons ORDER BY starts_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Season, error) {
+		s, err := scanSeason(row)
+		if err != nil {
+			return models.Season{}, err
+		}
+		return *s, nil
+	})
+}
+
+// ListDueSeasons returns seasons that have ended but haven't been finalized, oldest first.
+func ListDueSeasons(ctx context.Context) ([]models.Season, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT `+seasonColumns+` FROM seasons
+		WHERE ends_at <= `+nowUTC+` AND finalized_at IS NULL
+		ORDER BY ends_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Season, error) {
+		s, err := scanSeason(row)
+		if err != nil {
+			return models.Season{}, err
+		}
+		return *s, nil
+	})
+}
+
+// GetUserSeasonRatings returns a user's ratings for each mode they've played in a season.
+func GetUserSeasonRatings(ctx context.Context, seasonID, userID uuid.UUID) ([]models.SeasonRating, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT season_id, user_id, rating_mode, rating, games_played, final_rank, reward_tier
+		FROM season_ratings
+		WHERE season_id=$1 AND user_id=$2
+		ORDER BY rating_mode
+	`, seasonID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SeasonRating, error) {
+		var sr models.SeasonRating
+		err := row.Scan(&sr.SeasonID, &sr.UserID, &sr.Mode, &sr.Rating, &sr.GamesPlayed, &sr.FinalRank, &sr.RewardTier)
+		sr.InPlacement = sr.GamesPlayed < SeasonPlacementGames
+		return sr, err
+	})
+}
+
+// insertRatingRecordTx logs a rating change in the 'ratings' table and, if a season is in progress,
+// records the new rating against that season.
+func insertRatingRecordTx(ctx context.Context, tx pgx.Tx, userID, gameID uuid.UUID, oldRating, newRating int, mode string) error {
+	var seasonID *uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT id FROM seasons WHERE starts_at <= `+nowUTC+` AND ends_at > `+nowUTC+`
+	`).Scan(&seasonID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ratings (user_id, game_id, old_rating, new_rating, rating_mode, season_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, gameID, oldRating, newRating, mode, seasonID); err != nil {
+		return err
+	}
+
+	if seasonID == nil {
+		return nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO season_ratings (season_id, user_id, rating_mode, rating, games_played)
+		VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (season_id, rating_mode, user_id)
+		DO UPDATE SET rating = $4, games_played = season_ratings.games_played + 1
+	`, *seasonID, userID, mode, newRating)
+	return err
+}
+
+// maxChampions caps how many players of a mode are named champion in a season.
+const maxChampions = 10
+
+// rewardTier maps a final rank to the season reward a player earns, given how many players placed. The
+// tiers go by percentile: champion is the top 1% (at least the first player, and at most maxChampions),
+// then diamond the top 5%, gold 20%, and silver 50%.
+func rewardTier(rank, placed int) string {
+	pct := float64(rank) / float64(placed)
+	champions := min(max(placed/100, 1), maxChampions)
+	switch {
+	case rank <= champions:
+		return "champion"
+	case pct <= 0.05:
+		return "diamond"
+	case pct <= 0.20:
+		return "gold"
+	case pct <= 0.50:
+		return "silver"
+	default:
+		return "bronze"
+	}
+}
+
+// FinalizeSeason computes final ranks and reward tiers for every placed player in each mode, then
+// soft-resets current ratings halfway back to the 1500 baseline (and widens 1v1 rating deviation) so
+// the next season starts fresh without discarding skill entirely. Finalizing is idempotent.
+func FinalizeSeason(ctx context.Context, seasonID uuid.UUID) error {
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		var finalizedAt *time.Time
+		if err := tx.QueryRow(ctx, `SELECT finalized_at FROM seasons WHERE id=$1 FOR UPDATE`, seasonID).Scan(&finalizedAt); err != nil {
+			return fmt.Errorf("failed to lock season: %w", err)
+		}
+		if finalizedAt != nil {
+			return nil
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE season_ratings sr
+			SET final_rank = ranked.rank
+			FROM (
+				SELECT user_id, rating_mode,
+				       ROW_NUMBER() OVER (PARTITION BY rating_mode ORDER BY rating DESC, user_id) AS rank
+				FROM season_ratings
+				WHERE season_id = $1 AND games_played >= $2
+			) ranked
+			WHERE sr.season_id = $1 AND sr.user_id = ranked.user_id AND sr.rating_mode = ranked.rating_mode
+		`, seasonID, SeasonPlacementGames); err != nil {
+			return fmt.Errorf("failed to rank season: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT user_id, rating_mode, final_rank, COUNT(*) OVER (PARTITION BY rating_mode)
+			FROM season_ratings
+			WHERE season_id = $1 AND final_rank IS NOT NULL
+		`, seasonID)
+		if err != nil {
+			return fmt.Errorf("failed to load season ranks: %w", err)
+		}
+		type placed struct {
+			userID      uuid.UUID
+			mode        string
+			rank, total int
+		}
+		all, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (placed, error) {
+			var p placed
+			err := row.Scan(&p.userID, &p.mode, &p.rank, &p.total)
+			return p, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan season ranks: %w", err)
+		}
+		for _, p := range all {
+			if _, err := tx.Exec(ctx, `
+				UPDATE season_ratings SET reward_tier=$4
+				WHERE season_id=$1 AND user_id=$2 AND rating_mode=$3
+			`, seasonID, p.userID, p.mode, rewardTier(p.rank, p.total)); err != nil {
+				return fmt.Errorf("failed to set reward tier: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE users
+			SET elo_1v1   = 1500 + (elo_1v1 - 1500) / 2,
+			    elo_4p    = 1500 + (elo_4p - 1500) / 2,
+			    elo_7p8p  = 1500 + (elo_7p8p - 1500) / 2,
+			    phi_1v1   = LEAST(phi_1v1 * 1.5, 350.0)
+			WHERE deleted_at IS NULL
+		`); err != nil {
+			return fmt.Errorf("failed to reset ratings: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE seasons SET finalized_at=`+nowUTC+` WHERE id=$1`, seasonID)
+		return err
+	})
+}
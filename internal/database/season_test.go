@@ -0,0 +1,24 @@
+package database
+
+import "testing"
+
+func TestRewardTier(t *testing.T) {
+	for _, tc := range []struct {
+		rank, placed int
+		want         string
+	}{
+		{1, 5, "champion"},
+		{2, 5, "silver"},
+		{5, 5, "bronze"},
+		{1, 250, "champion"},
+		{2, 250, "champion"},
+		{3, 250, "diamond"},
+		{10, 5000, "champion"},
+		{11, 5000, "diamond"},
+		{40, 200, "gold"},
+	} {
+		if got := rewardTier(tc.rank, tc.placed); got != tc.want {
+			t.Errorf("rank %d of %d: got %s, want %s", tc.rank, tc.placed, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// internal/database/smurf.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+const (
+	// smurfWindow is how far back logins are compared.
+	smurfWindow = 30 * 24 * time.Hour
+	// smurfMinSharedLogins is how many of another account's logins must share an IP or device with the
+	// user's before the two are linked.
+	smurfMinSharedLogins = 2
+	// smurfRatingGap is how far above the user's 1v1 rating another account must be to count as much
+	// higher rated.
+	smurfRatingGap = 400
+)
+
+// RecordLogin stores the hashed IP address and device ID a user logged in from (deviceHash may be ""), then
+// refreshes their smurf signals: links to banned or much higher rated accounts that logged in from the same
+// IP or device at least smurfMinSharedLogins times in the last smurfWindow.
+func RecordLogin(ctx context.Context, userID uuid.UUID, ipHash, deviceHash string) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	return pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO login_fingerprints (id, user_id, ip_hash, device_hash)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+		`, id, userID, ipHash, deviceHash)
+		if err != nil {
+			return fmt.Errorf("failed to record login: %w", err)
+		}
+
+		since := time.Now().UTC().Add(-smurfWindow)
+		rows, err := tx.Query(ctx, `
+			WITH mine AS (
+				SELECT ip_hash, device_hash FROM login_fingerprints WHERE user_id = $1 AND created_at > $2
+			)
+			SELECT f.user_id, COUNT(*)
+			FROM login_fingerprints f
+			WHERE f.user_id <> $1 AND f.created_at > $2
+			  AND (f.ip_hash IN (SELECT ip_hash FROM mine)
+			       OR f.device_hash IN (SELECT device_hash FROM mine WHERE device_hash IS NOT NULL))
+			GROUP BY f.user_id
+			HAVING COUNT(*) >= $3
+		`, userID, since, smurfMinSharedLogins)
+		if err != nil {
+			return fmt.Errorf("failed to find shared logins: %w", err)
+		}
+		type link struct {
+			userID uuid.UUID
+			shared int
+		}
+		links, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (link, error) {
+			var l link
+			err := row.Scan(&l.userID, &l.shared)
+			return l, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find shared logins: %w", err)
+		}
+
+		for _, l := range links {
+			var banned, higher bool
+			err := tx.QueryRow(ctx, `
+				SELECT
+					EXISTS (SELECT 1 FROM user_sanctions WHERE `+activeBanFilter+`),
+					(SELECT elo_1v1 FROM users WHERE id = $1) >= (SELECT elo_1v1 FROM users WHERE id = $4) + $5
+			`, l.userID, models.SanctionBan, []string{models.BanScopeGlobal}, userID, smurfRatingGap).Scan(&banned, &higher)
+			if err != nil {
+				return fmt.Errorf("failed to check linked account: %w", err)
+			}
+			var reasons []string
+			if banned {
+				reasons = append(reasons, models.SmurfBannedAccount)
+			}
+			if higher {
+				reasons = append(reasons, models.SmurfHigherRated)
+			}
+			for _, reason := range reasons {
+				signalID, err := uuid.NewV7()
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec(ctx, `
+					INSERT INTO smurf_signals (id, user_id, linked_user_id, reason, shared_logins)
+					VALUES ($1, $2, $3, $4, $5)
+					ON CONFLICT (user_id, linked_user_id, reason)
+					DO UPDATE SET shared_logins = EXCLUDED.shared_logins, last_seen = NOW()
+				`, signalID, userID, l.userID, reason, l.shared)
+				if err != nil {
+					return fmt.Errorf("failed to record smurf signal: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// FingerprintBanned reports whether an account under a global ban logged in from the hashed device at least
+// smurfMinSharedLogins times in the last smurfWindow. Addresses aren't compared, since one address can be a
+// whole NAT or campus.
+func FingerprintBanned(ctx context.Context, deviceHash string) (bool, error) {
+	if deviceHash == "" {
+		return false, nil
+	}
+	var banned bool
+	err := DB.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM login_fingerprints f
+			JOIN user_sanctions s ON s.user_id = f.user_id
+			WHERE f.created_at > $1 AND f.device_hash = $2
+			  AND s.kind = $3 AND s.scope = ANY($4) AND s.revoked_at IS NULL
+			  AND (s.expires_at IS NULL OR s.expires_at > NOW() AT TIME ZONE 'UTC')
+			GROUP BY f.user_id
+			HAVING COUNT(DISTINCT f.id) >= $5
+		)
+	`, time.Now().UTC().Add(-smurfWindow), deviceHash, models.SanctionBan, []string{models.BanScopeGlobal}, smurfMinSharedLogins).Scan(&banned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check fingerprint bans: %w", err)
+	}
+	return banned, nil
+}
+
+// ListSmurfSignals returns up to limit signals, newest first, before the signal with ID before (or from the
+// newest if before is uuid.Nil). If userID isn't uuid.Nil, only signals on that user are returned.
+func ListSmurfSignals(ctx context.Context, userID, before uuid.UUID, limit int) ([]models.SmurfSignal, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT id, user_id, linked_user_id, reason, shared_logins, first_seen, last_seen
+		FROM smurf_signals
+		WHERE ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR user_id = $1)
+		  AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list smurf signals: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SmurfSignal, error) {
+		var s models.SmurfSignal
+		err := row.Scan(&s.ID, &s.UserID, &s.LinkedUserID, &s.Reason, &s.SharedLogins, &s.FirstSeen, &s.LastSeen)
+		return s, err
+	})
+}
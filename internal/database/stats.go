@@ -0,0 +1,85 @@
+// internal/database/stats.go
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// statsWindow is how far back the average game length and ability usage in GlobalStats look.
+const statsWindow = 7 * 24 * time.Hour
+
+// topAbilities is how many abilities GlobalStats lists.
+const topAbilities = 5
+
+// AbilityUsage is how many times a card ability was used.
+type AbilityUsage struct {
+	Ability string `json:"ability"`
+	Uses    int    `json:"uses"`
+}
+
+// GlobalStats are site-wide aggregates over all players, for the public landing page.
+type GlobalStats struct {
+	GamesToday         int            `json:"gamesToday"`         // games completed since midnight UTC
+	GamesInProgress    int            `json:"gamesInProgress"`    // games being played right now
+	ActivePlayers      int            `json:"activePlayers"`      // distinct players in games completed today
+	AverageGameSeconds float64        `json:"averageGameSeconds"` // over games completed in the last week
+	TopAbilities       []AbilityUsage `json:"topAbilities"`       // most-used abilities in the last week
+	UpdatedAt          time.Time      `json:"updatedAt"`
+}
+
+// GetGlobalStats computes the site-wide stats as of now.
+func GetGlobalStats(ctx context.Context) (*GlobalStats, error) {
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	since := now.Add(-statsWindow)
+	s := &GlobalStats{TopAbilities: []AbilityUsage{}, UpdatedAt: now}
+
+	err := DB.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE g.status = 'completed' AND g.end_time >= $1),
+		       COUNT(*) FILTER (WHERE g.status = 'in_progress'),
+		       COALESCE(AVG(EXTRACT(EPOCH FROM g.end_time - g.start_time))
+		                FILTER (WHERE g.status = 'completed' AND g.end_time >= $2 AND g.start_time IS NOT NULL), 0)
+		FROM games g
+		WHERE g.status = 'in_progress' OR g.end_time >= $2
+	`, today, since).Scan(&s.GamesToday, &s.GamesInProgress, &s.AverageGameSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game stats: %w", err)
+	}
+
+	err = DB.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT gr.player_id)
+		FROM game_results gr
+		JOIN games g ON g.id = gr.game_id
+		WHERE g.status = 'completed' AND g.end_time >= $1
+	`, today).Scan(&s.ActivePlayers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load player stats: %w", err)
+	}
+
+	rows, err := DB.Query(ctx, `
+		SELECT `+abilityExpr+`, COUNT(*)
+		FROM game_actions a
+		WHERE `+abilityFilter+` AND a.created_at >= $1
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT $2
+	`, since, topAbilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ability stats: %w", err)
+	}
+	abilities, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (AbilityUsage, error) {
+		var u AbilityUsage
+		err := row.Scan(&u.Ability, &u.Uses)
+		return u, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ability stats: %w", err)
+	}
+	s.TopAbilities = append(s.TopAbilities, abilities...)
+	return s, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultQueryTimeout bounds each query when DB_QUERY_TIMEOUT isn't set.
+const defaultQueryTimeout = 5 * time.Second
+
+// QueryTimeout bounds each query, and each wait for a pooled connection, on top of whatever deadline the
+// caller's context has. Zero disables it.
+var QueryTimeout = defaultQueryTimeout
+
+// parseQueryTimeout reads DB_QUERY_TIMEOUT, a duration such as "3s"; "0" disables the timeout.
+func parseQueryTimeout() {
+	s := os.Getenv("DB_QUERY_TIMEOUT")
+	if s == "" {
+		return
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		log.Fatalf("invalid DB_QUERY_TIMEOUT %q", s)
+	}
+	QueryTimeout = d
+}
+
+type (
+	cancelKey    struct{}
+	noTimeoutKey struct{}
+)
+
+// withoutQueryTimeout exempts the queries run with ctx from QueryTimeout, for work such as migrations that
+// may legitimately take longer.
+func withoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+// timeoutTracer gives every query and connection acquire its own deadline. pgx runs the query with the
+// context returned by the Start hook and calls the End hook once the query's rows are closed, which
+// releases the deadline.
+type timeoutTracer struct{}
+
+func withQueryTimeout(ctx context.Context) context.Context {
+	if QueryTimeout <= 0 || ctx.Value(noTimeoutKey{}) != nil {
+		return ctx
+	}
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	return context.WithValue(ctx, cancelKey{}, cancel)
+}
+
+func releaseQueryTimeout(ctx context.Context) {
+	if cancel, ok := ctx.Value(cancelKey{}).(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+func (timeoutTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return withQueryTimeout(ctx)
+}
+
+func (timeoutTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	releaseQueryTimeout(ctx)
+}
+
+func (timeoutTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	return withQueryTimeout(ctx)
+}
+
+func (timeoutTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireEndData) {
+	releaseQueryTimeout(ctx)
+}
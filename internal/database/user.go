@@ -2,10 +2,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jason-s-yu/cambia/internal/auth"
 	"github.com/jason-s-yu/cambia/internal/models"
 )
@@ -25,16 +27,24 @@ func CreateUser(ctx context.Context, user *models.User) error {
 	}
 	user.Password = hash
 
-	q := `INSERT INTO users (id, email, password, username, is_ephemeral, is_admin)
-	      VALUES ($1, $2, $3, $4, $5, $6)`
+	if user.Role == "" {
+		user.Role = auth.RoleUser
+	}
+
+	q := `INSERT INTO users (id, email, password, username, is_ephemeral, is_admin, role)
+	      VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
 	err = pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
 		_, execErr := tx.Exec(ctx, q,
 			user.ID, user.Email, user.Password, user.Username,
-			user.IsEphemeral, user.IsAdmin,
+			user.IsEphemeral, user.IsAdmin, user.Role,
 		)
 		return execErr
 	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrEmailTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert user: %w", err)
 	}
@@ -44,7 +54,7 @@ func CreateUser(ctx context.Context, user *models.User) error {
 func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var u models.User
 	q := `
-	SELECT id, email, password, username, is_ephemeral, is_admin,
+	SELECT id, email, password, username, is_ephemeral, is_admin, role,
 	       elo_1v1, elo_4p, elo_7p8p,
 	       phi_1v1, sigma_1v1
 	FROM users
@@ -52,7 +62,7 @@ func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	`
 	err := DB.QueryRow(ctx, q, email).Scan(
 		&u.ID, &u.Email, &u.Password, &u.Username,
-		&u.IsEphemeral, &u.IsAdmin,
+		&u.IsEphemeral, &u.IsAdmin, &u.Role,
 		&u.Elo1v1, &u.Elo4p, &u.Elo7p8p,
 		&u.Phi1v1, &u.Sigma1v1,
 	)
@@ -65,7 +75,7 @@ func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 func GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var u models.User
 	q := `
-	SELECT id, email, password, username, is_ephemeral, is_admin,
+	SELECT id, email, password, username, is_ephemeral, is_admin, role,
 	       elo_1v1, elo_4p, elo_7p8p,
 	       phi_1v1, sigma_1v1
 	FROM users
@@ -73,7 +83,7 @@ func GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	`
 	err := DB.QueryRow(ctx, q, id).Scan(
 		&u.ID, &u.Email, &u.Password, &u.Username,
-		&u.IsEphemeral, &u.IsAdmin,
+		&u.IsEphemeral, &u.IsAdmin, &u.Role,
 		&u.Elo1v1, &u.Elo4p, &u.Elo7p8p,
 		&u.Phi1v1, &u.Sigma1v1,
 	)
@@ -84,7 +94,7 @@ func GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 }
 
 func AuthenticateUser(ctx context.Context, email, password string) (string, error) {
-	user, err := GetUserByEmail(ctx, email)
+	user, err := Users.GetUserByEmail(ctx, email)
 	if err != nil {
 		return "", fmt.Errorf("user not found or db error: %w", err)
 	}
@@ -94,7 +104,7 @@ func AuthenticateUser(ctx context.Context, email, password string) (string, erro
 		return "", fmt.Errorf("invalid credentials")
 	}
 
-	token, err := auth.CreateJWT(user.ID.String())
+	token, err := auth.CreateJWTWithRole(user.ID.String(), user.Role)
 	if err != nil {
 		return "", fmt.Errorf("failed to create jwt: %w", err)
 	}
@@ -102,6 +112,19 @@ func AuthenticateUser(ctx context.Context, email, password string) (string, erro
 	return token, nil
 }
 
+// SetUserRole updates a user's role. is_admin is kept in sync for older readers of that column.
+func SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	q := `UPDATE users SET role=$2, is_admin=($2 = 'admin'), updated_at=NOW() WHERE id=$1`
+	tag, err := DB.Exec(ctx, q, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user %v not found", userID)
+	}
+	return nil
+}
+
 // SaveUserGlicko1v1 stores the user's ELO, phi, and sigma in the DB
 func SaveUserGlicko1v1(ctx context.Context, u *models.User) error {
 	q := `
@@ -132,3 +155,94 @@ func UpdateUserCredentials(ctx context.Context, u *models.User) error {
 	}
 	return nil
 }
+
+// MergeEphemeralUser moves a guest's game history, rating records, achievements, friendships, and moderation
+// record (sanctions, abandons, anti-cheat flags, and any queue cooldown or ranked ban) onto a permanent
+// account, then deletes the guest row. Everything happens in one transaction so nothing is orphaned
+// if the merge fails partway through, and a sanctioned guest can't shed a ban by registering.
+//
+// If the target account has no rating history of its own, it also inherits the guest's ratings.
+func MergeEphemeralUser(ctx context.Context, guestID, targetID uuid.UUID) error {
+	if guestID == targetID {
+		return fmt.Errorf("cannot merge user %v into itself", guestID)
+	}
+	err := pgx.BeginTxFunc(ctx, DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		var isEphemeral bool
+		if err := tx.QueryRow(ctx, `SELECT is_ephemeral FROM users WHERE id=$1 FOR UPDATE`, guestID).Scan(&isEphemeral); err != nil {
+			return fmt.Errorf("lookup guest: %w", err)
+		}
+		if !isEphemeral {
+			return fmt.Errorf("user %v is not ephemeral", guestID)
+		}
+
+		var targetHasRatings bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ratings WHERE user_id=$1)`, targetID).Scan(&targetHasRatings); err != nil {
+			return fmt.Errorf("check target ratings: %w", err)
+		}
+		if !targetHasRatings {
+			q := `
+				UPDATE users t
+				SET elo_1v1=g.elo_1v1, elo_4p=g.elo_4p, elo_7p8p=g.elo_7p8p,
+				    phi_1v1=g.phi_1v1, sigma_1v1=g.sigma_1v1
+				FROM users g
+				WHERE t.id=$2 AND g.id=$1
+			`
+			if _, err := tx.Exec(ctx, q, guestID, targetID); err != nil {
+				return fmt.Errorf("copy ratings: %w", err)
+			}
+		}
+
+		moves := []string{
+			`UPDATE game_results SET player_id=$2 WHERE player_id=$1`,
+			`UPDATE ratings SET user_id=$2 WHERE user_id=$1`,
+			`UPDATE game_actions SET actor_user_id=$2 WHERE actor_user_id=$1`,
+			`UPDATE game_rounds SET player_id=$2 WHERE player_id=$1`,
+			// achievement progress adds up
+			`
+			INSERT INTO user_achievements (user_id, achievement_id, progress, unlocked_at)
+			SELECT $2, achievement_id, progress, unlocked_at
+			FROM user_achievements
+			WHERE user_id=$1
+			ON CONFLICT (user_id, achievement_id) DO UPDATE SET
+				progress = user_achievements.progress + EXCLUDED.progress,
+				unlocked_at = COALESCE(user_achievements.unlocked_at, EXCLUDED.unlocked_at)
+			`,
+			// re-point friendships, skipping any that would friend the target with itself or duplicate an existing row
+			`
+			INSERT INTO friends (user1_id, user2_id, status)
+			SELECT CASE WHEN user1_id=$1 THEN $2 ELSE user1_id END,
+			       CASE WHEN user2_id=$1 THEN $2 ELSE user2_id END,
+			       status
+			FROM friends
+			WHERE (user1_id=$1 OR user2_id=$1) AND user1_id<>$2 AND user2_id<>$2
+			ON CONFLICT (user1_id, user2_id) DO NOTHING
+			`,
+			`DELETE FROM friends WHERE user1_id=$1 OR user2_id=$1`,
+			`UPDATE user_sanctions SET user_id=$2 WHERE user_id=$1`,
+			`UPDATE anticheat_flags SET user_id=$2 WHERE user_id=$1`,
+			`
+			UPDATE game_abandons SET user_id=$2
+			WHERE user_id=$1 AND game_id NOT IN (SELECT game_id FROM game_abandons WHERE user_id=$2)
+			`,
+			// the later of the two accounts' cooldowns and ranked bans wins
+			`
+			UPDATE users t
+			SET queue_cooldown_until = GREATEST(t.queue_cooldown_until, g.queue_cooldown_until),
+			    ranked_ban_until = GREATEST(t.ranked_ban_until, g.ranked_ban_until)
+			FROM users g
+			WHERE t.id=$2 AND g.id=$1
+			`,
+			`DELETE FROM users WHERE id=$1`,
+		}
+		for _, q := range moves {
+			if _, err := tx.Exec(ctx, q, guestID, targetID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge ephemeral user %v into %v: %w", guestID, targetID, err)
+	}
+	return nil
+}
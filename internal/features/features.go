@@ -0,0 +1,136 @@
+// Package features gates experimental features behind flags, so they can be turned on or off per
+// environment, and at runtime by an admin, without a redeploy.
+//
+// Each flag has a built-in default, which FEATURE_FLAGS can override at startup, e.g.
+// "protobuf_encoding=off,matchmaking_parties=on". An admin can then override that at runtime with Set, until
+// Reset or a restart. Runtime overrides are kept in memory, so on a multi-node deployment each node is set
+// separately.
+package features
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Flag names a feature that can be turned on or off.
+type Flag string
+
+// The flags. Each is on by default so existing deployments keep their behavior.
+const (
+	// ProtobufEncoding lets game sockets negotiate the binary protobuf encoding.
+	ProtobufEncoding Flag = "protobuf_encoding"
+	// MatchmakingParties lets players form parties and queue together.
+	MatchmakingParties Flag = "matchmaking_parties"
+	// SnapRaceRule lets lobby hosts turn on the snapRace house rule.
+	SnapRaceRule Flag = "snap_race_rule"
+)
+
+type flagInfo struct {
+	description string
+	def         bool
+}
+
+var known = map[Flag]flagInfo{
+	ProtobufEncoding:   {"game sockets may use the binary protobuf encoding", true},
+	MatchmakingParties: {"players may form parties and queue together", true},
+	SnapRaceRule:       {"lobbies may turn on the snapRace house rule", true},
+}
+
+var state = struct {
+	sync.RWMutex
+	configured map[Flag]bool // from FEATURE_FLAGS
+	overrides  map[Flag]bool // set at runtime
+}{configured: parseEnv(os.Getenv("FEATURE_FLAGS")), overrides: make(map[Flag]bool)}
+
+// parseEnv reads a FEATURE_FLAGS value. An unknown flag or value is fatal, so a typo doesn't silently leave
+// a feature in the wrong state.
+func parseEnv(s string) map[Flag]bool {
+	out := make(map[Flag]bool)
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		name, val, _ := strings.Cut(kv, "=")
+		f := Flag(strings.TrimSpace(name))
+		if _, ok := known[f]; !ok {
+			log.Fatalf("FEATURE_FLAGS: unknown flag %q", f)
+		}
+		switch strings.TrimSpace(val) {
+		case "on", "true", "1":
+			out[f] = true
+		case "off", "false", "0":
+			out[f] = false
+		default:
+			log.Fatalf("FEATURE_FLAGS: invalid value %q for %s, want on or off", val, f)
+		}
+	}
+	return out
+}
+
+// Enabled reports whether f is on.
+func Enabled(f Flag) bool {
+	state.RLock()
+	defer state.RUnlock()
+	if on, ok := state.overrides[f]; ok {
+		return on
+	}
+	if on, ok := state.configured[f]; ok {
+		return on
+	}
+	return known[f].def
+}
+
+// Set turns f on or off until Reset or a restart.
+func Set(f Flag, on bool) error {
+	if _, ok := known[f]; !ok {
+		return fmt.Errorf("unknown feature flag %q", f)
+	}
+	state.Lock()
+	defer state.Unlock()
+	state.overrides[f] = on
+	return nil
+}
+
+// Reset drops the runtime override of f, returning it to its configured state.
+func Reset(f Flag) error {
+	if _, ok := known[f]; !ok {
+		return fmt.Errorf("unknown feature flag %q", f)
+	}
+	state.Lock()
+	defer state.Unlock()
+	delete(state.overrides, f)
+	return nil
+}
+
+// Status describes a flag's current state.
+type Status struct {
+	Name        Flag   `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Default     bool   `json:"default"`    // the built-in default, or FEATURE_FLAGS's value
+	Overridden  bool   `json:"overridden"` // whether an admin has set it at runtime
+}
+
+// All returns the state of every flag, by name.
+func All() []Status {
+	state.RLock()
+	defer state.RUnlock()
+	out := make([]Status, 0, len(known))
+	for f, info := range known {
+		def := info.def
+		if on, ok := state.configured[f]; ok {
+			def = on
+		}
+		on, overridden := state.overrides[f]
+		if !overridden {
+			on = def
+		}
+		out = append(out, Status{Name: f, Description: info.description, Enabled: on, Default: def, Overridden: overridden})
+	}
+	slices.SortFunc(out, func(a, b Status) int { return strings.Compare(string(a.Name), string(b.Name)) })
+	return out
+}
@@ -0,0 +1,31 @@
+package features
+
+import "testing"
+
+func TestFlags(t *testing.T) {
+	if !Enabled(MatchmakingParties) {
+		t.Fatalf("expected flags to default on")
+	}
+	if err := Set(MatchmakingParties, false); err != nil || Enabled(MatchmakingParties) {
+		t.Fatalf("expected the override to turn the flag off, got %v", err)
+	}
+	for _, s := range All() {
+		if s.Name == MatchmakingParties && (s.Enabled || !s.Default || !s.Overridden) {
+			t.Fatalf("unexpected status %+v", s)
+		}
+	}
+	Reset(MatchmakingParties)
+	if !Enabled(MatchmakingParties) {
+		t.Fatalf("expected Reset to restore the default")
+	}
+	if err := Set("no_such_flag", true); err == nil {
+		t.Fatalf("expected an unknown flag to be rejected")
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	got := parseEnv(" protobuf_encoding=off, snap_race_rule=on ")
+	if len(got) != 2 || got[ProtobufEncoding] || !got[SnapRaceRule] {
+		t.Fatalf("unexpected flags %v", got)
+	}
+}
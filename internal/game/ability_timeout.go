@@ -0,0 +1,154 @@
+package game
+
+import (
+	"log"
+	"math/rand"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// What happens to an ability whose targets aren't picked in time; see HouseRules.AbilityTimeout.
+const (
+	AbilityTimeoutSkip   = "skip"
+	AbilityTimeoutRandom = "random"
+)
+
+// EventPlayerAbilityTimeout tells players how an ability whose targets weren't picked in time was resolved.
+const EventPlayerAbilityTimeout GameEventType = "player_ability_timeout"
+
+// validAbilityTimeout reports whether s is an ability timeout resolution; "" means skip.
+func validAbilityTimeout(s string) bool {
+	return s == "" || s == AbilityTimeoutSkip || s == AbilityTimeoutRandom
+}
+
+// resolveAbilityTimeout ends the turn of a player who didn't pick their ability's targets in time. The
+// ability is skipped or, under the "random" rule, used on random targets. Callers must hold g.Mu.
+func (g *CambiaGame) resolveAbilityTimeout(playerID uuid.UUID) {
+	sa := g.SpecialAction
+	g.SpecialAction = SpecialActionState{}
+	g.consecutiveTimeouts[playerID]++
+
+	resolution := AbilityTimeoutSkip
+	if g.HouseRules.AbilityTimeout == AbilityTimeoutRandom && g.useAbilityAtRandom(playerID, sa) {
+		resolution = AbilityTimeoutRandom
+	}
+	log.Printf("Player %v timed out picking %s targets; resolved by %s\n", playerID, rankToSpecial(sa.CardRank), resolution)
+	g.fireEvent(GameEvent{
+		Type:   EventPlayerAbilityTimeout,
+		UserID: playerID,
+		Other:  map[string]interface{}{"special": rankToSpecial(sa.CardRank), "resolution": resolution},
+	})
+	g.advanceTurn()
+}
+
+// useAbilityAtRandom carries out the rest of the pending ability sa on random targets, broadcasting it as
+// if the player had picked them. A king whose cards were already revealed swaps them on a coin flip. It
+// reports false if there was nothing to target.
+func (g *CambiaGame) useAbilityAtRandom(playerID uuid.UUID, sa SpecialActionState) bool {
+	var self *models.Player
+	for _, p := range g.Players {
+		if p.ID == playerID {
+			self = p
+		}
+	}
+	if self == nil {
+		return false
+	}
+
+	switch sa.CardRank {
+	case "7", "8":
+		c := randomCard(self.Hand)
+		if c == nil {
+			return false
+		}
+		g.FireEventPrivateSuccess(playerID, "peek_self", c, nil)
+		g.FireEventPlayerSpecialAction(playerID, "peek_self", c, nil, nil)
+
+	case "9", "10":
+		target, c := g.randomOpponentCard(playerID, false)
+		if c == nil {
+			return false
+		}
+		g.FireEventPrivateSuccess(playerID, "peek_other", c, nil)
+		g.FireEventPlayerSpecialAction(playerID, "peek_other", c, nil, map[string]interface{}{"user": target.String()})
+
+	case "Q", "J":
+		own := randomCard(self.Hand)
+		target, other := g.randomOpponentCard(playerID, true)
+		if own == nil || other == nil {
+			return false
+		}
+		g.swapCards(playerID, own, target, other)
+		g.FireEventPlayerSpecialAction(playerID, "swap_blind", own, other, map[string]interface{}{
+			"userA": playerID.String(),
+			"userB": target.String(),
+		})
+
+	case "K":
+		if !sa.FirstStepDone {
+			sa.Card1, sa.Card1Owner = randomCard(self.Hand), playerID
+			sa.Card2Owner, sa.Card2 = g.randomOpponentCard(playerID, false)
+			if sa.Card1 == nil || sa.Card2 == nil {
+				return false
+			}
+			extra := map[string]interface{}{"userA": sa.Card1Owner.String(), "userB": sa.Card2Owner.String()}
+			g.FireEventPlayerSpecialAction(playerID, "swap_peek_reveal", sa.Card1, sa.Card2, extra)
+			g.FireEventPrivateSuccess(playerID, "swap_peek_reveal", sa.Card1, sa.Card2)
+		}
+		locked := g.CambiaCalled && (sa.Card1Owner == g.CambiaCallerID || sa.Card2Owner == g.CambiaCallerID)
+		if !locked && rand.Intn(2) == 0 {
+			g.swapCards(sa.Card1Owner, sa.Card1, sa.Card2Owner, sa.Card2)
+			g.FireEventPlayerSpecialAction(playerID, "swap_peek_swap", sa.Card1, sa.Card2, map[string]interface{}{
+				"userA": sa.Card1Owner.String(),
+				"userB": sa.Card2Owner.String(),
+			})
+		}
+
+	default:
+		return false
+	}
+	return true
+}
+
+// randomCard returns a random card from hand, or nil if it's empty.
+func randomCard(hand []*models.Card) *models.Card {
+	if len(hand) == 0 {
+		return nil
+	}
+	return hand[rand.Intn(len(hand))]
+}
+
+// randomOpponentCard returns a random card from a random opponent of playerID with cards in hand, and its
+// owner. For a swap, the Cambia caller's locked hand is left out.
+func (g *CambiaGame) randomOpponentCard(playerID uuid.UUID, swap bool) (uuid.UUID, *models.Card) {
+	var candidates []*models.Player
+	for _, p := range g.Players {
+		if p.ID == playerID || len(p.Hand) == 0 || (swap && g.CambiaCalled && p.ID == g.CambiaCallerID) {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	if len(candidates) == 0 {
+		return uuid.Nil, nil
+	}
+	p := candidates[rand.Intn(len(candidates))]
+	return p.ID, randomCard(p.Hand)
+}
+
+// swapCards exchanges card a in userA's hand with card b in userB's hand, keeping their positions.
+func (g *CambiaGame) swapCards(userA uuid.UUID, a *models.Card, userB uuid.UUID, b *models.Card) {
+	var slotA, slotB **models.Card
+	for _, p := range g.Players {
+		for i, c := range p.Hand {
+			if p.ID == userA && c == a {
+				slotA = &p.Hand[i]
+			} else if p.ID == userB && c == b {
+				slotB = &p.Hand[i]
+			}
+		}
+	}
+	if slotA != nil && slotB != nil {
+		*slotA, *slotB = b, a
+	}
+}
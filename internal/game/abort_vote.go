@@ -0,0 +1,79 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+// EventAbortVote tells players someone voted to abort the game, and how many of them have so far.
+const EventAbortVote GameEventType = "game_abort_vote"
+
+// AbortVoteReason is the game_halted reason of a game its players voted to abort.
+const AbortVoteReason = "aborted by vote"
+
+// VoteAbort records playerID's vote to abort the game. Once every player still at the table, connected or
+// not, has voted, the game is stopped without a result or rating changes, as Abort does, and VoteAbort
+// reports true; the caller tells the lobby. Players who left the game (see abandoners) don't vote, but in a
+// ranked game they still get OnAbandon, so leaving and having the rest abort doesn't spare them the
+// penalty. OnAborted is then called, e.g. to score a tournament pairing. Votes aren't game actions, so
+// they aren't logged.
+func (g *CambiaGame) VoteAbort(playerID uuid.UUID) (aborted bool, err error) {
+	g.Do(func() { aborted, err = g.voteAbort(playerID) })
+	return aborted, err
+}
+
+func (g *CambiaGame) voteAbort(playerID uuid.UUID) (aborted bool, err error) {
+	if g.GameOver {
+		return false, fmt.Errorf("the game is over")
+	}
+	seated := false
+	for _, p := range g.Players {
+		if p.ID == playerID {
+			seated = true
+			break
+		}
+	}
+	if !seated {
+		return false, fmt.Errorf("only players can vote to abort the game")
+	}
+	left := g.abandoners()
+	if slices.Contains(left, playerID) {
+		return false, fmt.Errorf("players who left the game can't vote to abort it")
+	}
+
+	g.abortVotes[playerID] = true
+	votes, needed := 0, 0
+	for _, p := range g.Players {
+		// local seats vote through the connection playing them
+		if p.Bot || p.Controller != uuid.Nil || slices.Contains(left, p.ID) {
+			continue
+		}
+		needed++
+		if g.abortVotes[p.ID] {
+			votes++
+		}
+	}
+	if votes < needed {
+		if g.BroadcastFn != nil {
+			g.BroadcastFn(GameEvent{
+				Type:   EventAbortVote,
+				UserID: playerID,
+				Other:  map[string]interface{}{"votes": votes, "needed": needed},
+			})
+		}
+		return false, nil
+	}
+	if g.Ranked && g.OnAbandon != nil {
+		for _, pid := range left {
+			g.OnAbandon(g.ID, pid)
+		}
+	}
+	g.halt(AbortVoteReason, websocket.StatusNormalClosure)
+	if g.OnAborted != nil {
+		g.OnAborted(g.ID)
+	}
+	return true, nil
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// actionDedupWindow is how long a client action ID is remembered after the action was applied.
+const actionDedupWindow = 2 * time.Minute
+
+type actionKey struct {
+	playerID, actionID uuid.UUID
+}
+
+// actionRecord notes which of the player's events an action produced: those numbered after fromSeq, up to
+// and including toSeq.
+type actionRecord struct {
+	fromSeq, toSeq uint64
+	at             time.Time
+}
+
+// ApplyOnce runs apply for an action the client identified with actionID, unless the same player already
+// sent an action with that ID within actionDedupWindow, e.g. because a network retry submitted it twice.
+// For such a replay, apply isn't run; instead it returns the events the original action delivered to the
+// player, so the client gets the same result. complete is false if some of those have aged out of the
+// player's event log.
+func (g *CambiaGame) ApplyOnce(playerID, actionID uuid.UUID, apply func()) (replayed bool, events []GameEvent, complete bool) {
+	g.actionsMu.Lock()
+	defer g.actionsMu.Unlock()
+	now := time.Now()
+	for key, rec := range g.actions {
+		if now.Sub(rec.at) > actionDedupWindow {
+			delete(g.actions, key)
+		}
+	}
+
+	key := actionKey{playerID: playerID, actionID: actionID}
+	if rec, ok := g.actions[key]; ok {
+		events, _, complete := g.Resync(playerID, rec.fromSeq)
+		for i, ev := range events {
+			if ev.Seq > rec.toSeq {
+				events = events[:i]
+				break
+			}
+		}
+		return true, events, complete
+	}
+
+	from := g.latestSeq(playerID)
+	apply()
+	if g.actions == nil {
+		g.actions = make(map[actionKey]actionRecord)
+	}
+	g.actions[key] = actionRecord{fromSeq: from, toSeq: g.latestSeq(playerID), at: now}
+	return false, nil, true
+}
+
+// latestSeq returns the number of the last event delivered to a player.
+func (g *CambiaGame) latestSeq(playerID uuid.UUID) uint64 {
+	g.Mu.Lock()
+	el, ok := g.eventLogs[playerID]
+	g.Mu.Unlock()
+	if !ok {
+		return 0
+	}
+	return el.Latest()
+}
@@ -0,0 +1,40 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyOnce(t *testing.T) {
+	g := NewCambiaGame()
+	playerID, actionID := uuid.New(), uuid.New()
+	applied := 0
+	apply := func() {
+		applied++
+		g.Mu.Lock()
+		g.SequenceFor(playerID, GameEvent{Type: EventPrivateDrawStock})
+		g.Mu.Unlock()
+	}
+
+	if replayed, _, _ := g.ApplyOnce(playerID, actionID, apply); replayed || applied != 1 {
+		t.Fatalf("expected the first submission to be applied")
+	}
+	// an unrelated event after the action isn't part of its result
+	g.Mu.Lock()
+	g.SequenceFor(playerID, GameEvent{Type: EventPlayerTurn})
+	g.Mu.Unlock()
+
+	replayed, events, complete := g.ApplyOnce(playerID, actionID, apply)
+	if !replayed || applied != 1 {
+		t.Fatalf("expected the retry to be deduplicated, applied %d times", applied)
+	}
+	if !complete || len(events) != 1 || events[0].Type != EventPrivateDrawStock || events[0].Seq != 1 {
+		t.Fatalf("expected the original draw event back, got %+v", events)
+	}
+
+	// the same ID from another player is a different action
+	if replayed, _, _ := g.ApplyOnce(uuid.New(), actionID, apply); replayed || applied != 2 {
+		t.Fatalf("expected another player's action to be applied")
+	}
+}
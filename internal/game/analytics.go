@@ -0,0 +1,39 @@
+package game
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/analytics"
+)
+
+// emitGameStart reports the game starting, with the rules it's played under. Callers must hold g.Mu.
+func (g *CambiaGame) emitGameStart() {
+	analytics.Emit(analytics.Event{
+		Type:       analytics.EventGameStart,
+		Time:       g.StartedAt,
+		GameID:     g.ID,
+		Players:    len(g.Players),
+		Ranked:     g.Ranked,
+		Practice:   g.Practice,
+		HouseRules: g.HouseRules,
+	})
+}
+
+// emitGameEnd reports the game ending, with how long it lasted. Callers must hold g.Mu.
+func (g *CambiaGame) emitGameEnd(winners []uuid.UUID) {
+	analytics.Emit(analytics.Event{
+		Type:       analytics.EventGameEnd,
+		GameID:     g.ID,
+		DurationMs: time.Since(g.StartedAt).Milliseconds(),
+		Players:    len(g.Players),
+		Ranked:     g.Ranked,
+		Practice:   g.Practice,
+		HouseRules: g.HouseRules,
+		Detail: map[string]interface{}{
+			"turns":        g.TurnID,
+			"winners":      len(winners),
+			"cambiaCalled": g.CambiaCalled,
+		},
+	})
+}
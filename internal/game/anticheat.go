@@ -0,0 +1,82 @@
+package game
+
+import (
+	"log"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// The kinds of Anomaly the game flags.
+const (
+	// AnomalyBlindSnap is a correct snap of a card the player never saw, beyond blindSnapAllowance in a game.
+	AnomalyBlindSnap = "blind_snap"
+	// AnomalyBlindCambia is a winning Cambia call by a player who hadn't seen any card in their hand.
+	AnomalyBlindCambia = "blind_cambia"
+)
+
+// blindSnapAllowance is how many correct snaps of unseen cards a player makes in a game before they're
+// flagged; a lucky one now and then is expected.
+const blindSnapAllowance = 1
+
+// Anomaly is play suggesting a player knew cards they never legitimately saw, flagged for moderators.
+// The play itself stands, since it's possible by luck.
+type Anomaly struct {
+	GameID uuid.UUID
+	UserID uuid.UUID
+	Kind   string
+	Turn   int
+	Detail map[string]interface{}
+}
+
+// knows reports whether playerID has seen the face of the card cardID. Callers must hold g.Mu.
+func (g *CambiaGame) knows(playerID, cardID uuid.UUID) bool {
+	return g.known[playerID][cardID]
+}
+
+// seenInHand counts the cards in playerID's hand whose face they've seen. Callers must hold g.Mu.
+func (g *CambiaGame) seenInHand(playerID uuid.UUID) int {
+	n := 0
+	for _, p := range g.Players {
+		if p.ID != playerID {
+			continue
+		}
+		for _, c := range p.Hand {
+			if g.knows(playerID, c.ID) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// checkSnap flags playerID if card, which they just snapped correctly, is one too many they never saw.
+// It must be called before the snap is broadcast, since that shows the card to everyone. Callers must hold
+// g.Mu.
+func (g *CambiaGame) checkSnap(playerID uuid.UUID, card *models.Card) {
+	if g.knows(playerID, card.ID) {
+		return
+	}
+	g.blindSnaps[playerID]++
+	if n := g.blindSnaps[playerID]; n > blindSnapAllowance {
+		g.flag(playerID, AnomalyBlindSnap, map[string]interface{}{"cardID": card.ID, "rank": card.Rank, "blindSnaps": n})
+	}
+}
+
+// checkCambia flags the Cambia caller if they won without having seen any card in their hand when they
+// called. Callers must hold g.Mu.
+func (g *CambiaGame) checkCambia(winners []uuid.UUID) {
+	if !g.CambiaCalled || g.cambiaCallerSeen > 0 || !slices.Contains(winners, g.CambiaCallerID) {
+		return
+	}
+	g.flag(g.CambiaCallerID, AnomalyBlindCambia, nil)
+}
+
+// flag reports an Anomaly through OnAnomaly. Callers must hold g.Mu.
+func (g *CambiaGame) flag(playerID uuid.UUID, kind string, detail map[string]interface{}) {
+	log.Printf("Flagged %s by player %v in game %v\n", kind, playerID, g.ID)
+	if g.OnAnomaly != nil {
+		g.OnAnomaly(Anomaly{GameID: g.ID, UserID: playerID, Kind: kind, Turn: g.TurnID, Detail: detail})
+	}
+}
@@ -0,0 +1,94 @@
+package game
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+const (
+	// botThinkTime is how long a bot waits before moving, so players can follow along.
+	botThinkTime = 1200 * time.Millisecond
+	// botKeepValue is the highest drawn card a bot keeps in place of a card it hasn't seen.
+	botKeepValue = 4
+	// botCambiaScore is the hand total at or under which a bot that has seen its whole hand calls Cambia.
+	botCambiaScore = 5
+	// botCambiaTurn is the turn from which a bot calls Cambia regardless, so a practice game its player left
+	// still ends.
+	botCambiaTurn = 60
+)
+
+// NewBot returns a seat for a computer player. Bots play only from the cards they've seen, like anyone else,
+// and skip card abilities; see playBotTurn.
+func NewBot() (*models.Player, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Player{ID: id, Hand: []*models.Card{}, Connected: true, Bot: true}, nil
+}
+
+// scheduleBotTurn has the current player move after botThinkTime, if they're a bot. Callers must hold g.Mu.
+func (g *CambiaGame) scheduleBotTurn() {
+	p := g.Players[g.CurrentPlayerIndex]
+	if !p.Bot || g.GameOver {
+		return
+	}
+	turn := g.TurnID
+	time.AfterFunc(botThinkTime, func() {
+		g.post(func() {
+			defer g.HaltOnPanic("bot turn")
+			if g.GameOver || g.TurnID != turn {
+				return
+			}
+			g.playBotTurn(p)
+		})
+	})
+}
+
+// playBotTurn makes a bot's move. It calls Cambia once it has seen its whole hand and the total is at most
+// botCambiaScore, or from botCambiaTurn on. Otherwise it draws from the stockpile and keeps the card in place of the highest card it
+// has seen, if the new card is lower, or of one it hasn't seen, if the new card is at most botKeepValue;
+// failing both, the card is discarded. Any card ability that comes up is skipped. Callers must hold g.Mu.
+func (g *CambiaGame) playBotTurn(p *models.Player) {
+	worst, unseen, total := -1, -1, 0
+	for i, c := range p.Hand {
+		if !g.knows(p.ID, c.ID) {
+			if unseen < 0 {
+				unseen = i
+			}
+			continue
+		}
+		total += c.Value
+		if worst < 0 || c.Value > p.Hand[worst].Value {
+			worst = i
+		}
+	}
+	if !g.CambiaCalled && (unseen < 0 && total <= botCambiaScore || g.TurnID >= botCambiaTurn) {
+		g.handleCallCambia(p.ID)
+		return
+	}
+
+	g.handleDrawFrom(p.ID, "stockpile")
+	drawn := p.DrawnCard
+	if drawn == nil {
+		// the stockpile ran out, which may have ended the round
+		if !g.GameOver {
+			g.advanceTurn()
+		}
+		return
+	}
+	switch {
+	case worst >= 0 && drawn.Value < p.Hand[worst].Value:
+		g.handleReplace(p.ID, map[string]interface{}{"idx": float64(worst)})
+	case unseen >= 0 && drawn.Value <= botKeepValue:
+		g.handleReplace(p.ID, map[string]interface{}{"idx": float64(unseen)})
+	default:
+		g.handleDiscard(p.ID, map[string]interface{}{"id": drawn.ID.String()})
+	}
+	if g.SpecialAction.Active && g.SpecialAction.PlayerID == p.ID {
+		g.SpecialAction = SpecialActionState{}
+		g.advanceTurn()
+	}
+}
@@ -0,0 +1,41 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestBotTurn(t *testing.T) {
+	g := NewCambiaGame()
+	g.Practice = true
+	bot, err := NewBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	high := &models.Card{ID: uuid.New(), Rank: "Q", Suit: "S", Value: 12}
+	low := &models.Card{ID: uuid.New(), Rank: "A", Suit: "H", Value: 1}
+	unseen := &models.Card{ID: uuid.New(), Rank: "2", Suit: "C", Value: 2}
+	bot.Hand = []*models.Card{low, high, unseen}
+	human := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "5", Suit: "D", Value: 5}}}
+	g.Players = []*models.Player{bot, human}
+	g.learn(bot.ID, low, high)
+	drawn := &models.Card{ID: uuid.New(), Rank: "2", Suit: "D", Value: 2}
+	g.Deck = []*models.Card{drawn}
+
+	g.playBotTurn(bot)
+	if bot.Hand[1] != drawn || g.DiscardPile[len(g.DiscardPile)-1] != high {
+		t.Fatalf("expected the bot to replace its highest known card, got hand %v", bot.Hand)
+	}
+	if g.Players[g.CurrentPlayerIndex] != human {
+		t.Fatalf("expected the turn to pass to the human")
+	}
+
+	g.CurrentPlayerIndex = 0
+	g.learn(bot.ID, unseen)
+	g.playBotTurn(bot)
+	if !g.CambiaCalled || g.CambiaCallerID != bot.ID {
+		t.Fatalf("expected the bot to call Cambia once it knew its hand totals %d", 1+2+2)
+	}
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+const (
+	// CircuitModeElimination eliminates players whose points reach the target score; the last one standing wins.
+	CircuitModeElimination = "elimination"
+	// CircuitModeMaxRounds plays a fixed number of rounds; the fewest points wins.
+	CircuitModeMaxRounds = "max_rounds"
+
+	// defaultCircuitMaxRounds is used by max_rounds circuits that don't set MaxRounds.
+	defaultCircuitMaxRounds = 5
+)
+
+// DefaultCircuitRules are the circuit rules used by circuit lobbies that don't specify their own.
+var DefaultCircuitRules = CircuitRules{
+	TargetScore:            100,
+	WinBonus:               -1,
+	FalseCambiaPenalty:     5,
+	FreezeUserOnDisconnect: false, // i.e. user is automatically eliminated
+}
+
+// IsCircuitGameMode reports whether a lobby game mode is played as a circuit.
+func IsCircuitGameMode(mode string) bool {
+	return strings.HasPrefix(mode, "circuit_")
+}
+
+// CircuitStanding is one player's position in a circuit series.
+type CircuitStanding struct {
+	UserID     uuid.UUID `json:"userID"`
+	Points     int       `json:"points"`
+	RoundsWon  int       `json:"roundsWon"`
+	Eliminated bool      `json:"eliminated"`
+	Frozen     bool      `json:"frozen"` // disconnected with FreezeUserOnDisconnect; sits out until they rejoin
+}
+
+// CircuitState tracks a circuit series across the games played in a lobby.
+type CircuitState struct {
+	mu        sync.Mutex
+	Round     int
+	Standings map[uuid.UUID]*CircuitStanding
+	Finished  bool
+	WinnerID  uuid.UUID
+}
+
+// ValidateCircuit checks the lobby's circuit settings, enabling circuit play (with default rules if none were
+// given) for the circuit game modes.
+func (lobby *Lobby) ValidateCircuit() error {
+	if IsCircuitGameMode(lobby.GameMode) {
+		lobby.Circuit.Enabled = true
+		if lobby.Circuit.Rules == (CircuitRules{}) {
+			lobby.Circuit.Rules = DefaultCircuitRules
+		}
+	}
+	if !lobby.Circuit.Enabled {
+		return nil
+	}
+	switch lobby.Circuit.Mode {
+	case "":
+		lobby.Circuit.Mode = CircuitModeElimination
+	case CircuitModeElimination, CircuitModeMaxRounds:
+	default:
+		return fmt.Errorf("unknown circuit mode %q", lobby.Circuit.Mode)
+	}
+	if lobby.Circuit.Mode == CircuitModeElimination && lobby.Circuit.Rules.TargetScore <= 0 {
+		return fmt.Errorf("elimination circuits need a positive target score")
+	}
+	if lobby.Circuit.Rules.MaxRounds < 0 {
+		return fmt.Errorf("max rounds cannot be negative")
+	}
+	return nil
+}
+
+// CircuitParticipants filters the players seated for the next game of a circuit: eliminated players are
+// dropped, and frozen players sit out unless they're connected to the lobby again. A finished series is
+// reset so the next game starts a new one. It returns the round index of the next game.
+// Lobbies without circuit play get all players back at round 0.
+func (lobby *Lobby) CircuitParticipants(players []*models.Player) ([]*models.Player, int) {
+	if !lobby.Circuit.Enabled {
+		return players, 0
+	}
+	if lobby.CircuitState == nil || lobby.CircuitState.Finished {
+		lobby.CircuitState = &CircuitState{Standings: make(map[uuid.UUID]*CircuitStanding)}
+	}
+	cs := lobby.CircuitState
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var seated []*models.Player
+	for _, p := range players {
+		st, ok := cs.Standings[p.ID]
+		if !ok {
+			if cs.Round > 0 {
+				// the series is underway; late joiners wait for the next one
+				continue
+			}
+			st = &CircuitStanding{UserID: p.ID}
+			cs.Standings[p.ID] = st
+		}
+		if st.Eliminated {
+			continue
+		}
+		if st.Frozen {
+			if _, connected := lobby.Connection(p.ID); !connected {
+				continue
+			}
+			st.Frozen = false
+		}
+		seated = append(seated, p)
+	}
+	return seated, cs.Round
+}
+
+// RecordCircuitRound applies a finished game to the lobby's circuit: each player's hand score is added to
+// their points, the round winner gets the win bonus, and a Cambia caller who didn't win takes the false
+// Cambia penalty. It then eliminates or freezes players as the rules require, and checks whether the
+// series is over. Callers must hold g.Mu.
+func (lobby *Lobby) RecordCircuitRound(g *CambiaGame, scores map[uuid.UUID]int) {
+	cs := lobby.CircuitState
+	if !lobby.Circuit.Enabled || cs == nil {
+		return
+	}
+	rules := lobby.Circuit.Rules
+	winners := g.findWinnersWithCambiaTiebreak(scores)
+	won := make(map[uuid.UUID]bool, len(winners))
+	for _, w := range winners {
+		won[w] = true
+	}
+
+	cs.mu.Lock()
+	cs.Round++
+	for _, p := range g.Players {
+		st, ok := cs.Standings[p.ID]
+		if !ok {
+			continue
+		}
+		if !p.Connected {
+			if rules.FreezeUserOnDisconnect {
+				st.Frozen = true
+			} else {
+				st.Eliminated = true
+			}
+			continue
+		}
+		st.Points += scores[p.ID]
+		if won[p.ID] {
+			st.Points += rules.WinBonus
+			st.RoundsWon++
+		} else if p.ID == g.CambiaCallerID {
+			st.Points += rules.FalseCambiaPenalty
+		}
+		if lobby.Circuit.Mode == CircuitModeElimination && st.Points >= rules.TargetScore {
+			st.Eliminated = true
+		}
+	}
+	cs.checkFinishedLocked(lobby.Circuit)
+	msg := cs.standingsMessageLocked()
+	cs.mu.Unlock()
+
+	lobby.BroadcastAll(msg)
+}
+
+// checkFinishedLocked ends the series if it has met its end condition. Callers must hold cs.mu.
+func (cs *CircuitState) checkFinishedLocked(c Circuit) {
+	standing := 0
+	for _, st := range cs.Standings {
+		if !st.Eliminated {
+			standing++
+		}
+	}
+	switch c.Mode {
+	case CircuitModeMaxRounds:
+		maxRounds := c.Rules.MaxRounds
+		if maxRounds == 0 {
+			maxRounds = defaultCircuitMaxRounds
+		}
+		cs.Finished = cs.Round >= maxRounds || standing <= 1
+	default:
+		cs.Finished = standing <= 1
+	}
+	if cs.Finished {
+		if ranked := cs.sortedLocked(); len(ranked) > 0 {
+			cs.WinnerID = ranked[0].UserID
+		}
+	}
+}
+
+// sortedLocked returns the standings best first: players still standing, then fewest points, then most
+// rounds won. Callers must hold cs.mu.
+func (cs *CircuitState) sortedLocked() []CircuitStanding {
+	out := make([]CircuitStanding, 0, len(cs.Standings))
+	for _, st := range cs.Standings {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Eliminated != out[j].Eliminated {
+			return !out[i].Eliminated
+		}
+		if out[i].Points != out[j].Points {
+			return out[i].Points < out[j].Points
+		}
+		if out[i].RoundsWon != out[j].RoundsWon {
+			return out[i].RoundsWon > out[j].RoundsWon
+		}
+		return out[i].UserID.String() < out[j].UserID.String()
+	})
+	return out
+}
+
+// standingsMessageLocked builds the "circuit_standings" broadcast. Callers must hold cs.mu.
+func (cs *CircuitState) standingsMessageLocked() map[string]interface{} {
+	msg := map[string]interface{}{
+		"type":      "circuit_standings",
+		"round":     cs.Round,
+		"standings": cs.sortedLocked(),
+		"finished":  cs.Finished,
+	}
+	if cs.Finished {
+		msg["winner"] = cs.WinnerID.String()
+	}
+	return msg
+}
+
+// CircuitStandings returns the lobby's current circuit standings message, or nil if no series has started.
+func (lobby *Lobby) CircuitStandings() map[string]interface{} {
+	cs := lobby.CircuitState
+	if cs == nil {
+		return nil
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.standingsMessageLocked()
+}
@@ -0,0 +1,148 @@
+package game
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The game speeds a lobby can pick with the speed house rule. Each sets the turn and ability timers and
+// the time bank; see speeds.
+const (
+	SpeedCasual   = "casual"
+	SpeedStandard = "standard"
+	SpeedBlitz    = "blitz"
+)
+
+// ClockSettings are a game's time limits.
+type ClockSettings struct {
+	Turn     time.Duration // to finish a turn
+	Ability  time.Duration // to pick a card ability's targets, restarted at each step of the ability
+	TimeBank time.Duration // extra time each player can draw on over the whole game once a timer runs out
+}
+
+var speeds = map[string]ClockSettings{
+	SpeedCasual:   {Turn: 30 * time.Second, Ability: 20 * time.Second, TimeBank: 60 * time.Second},
+	SpeedStandard: {Turn: 15 * time.Second, Ability: 10 * time.Second, TimeBank: 30 * time.Second},
+	SpeedBlitz:    {Turn: 5 * time.Second, Ability: 4 * time.Second, TimeBank: 20 * time.Second},
+}
+
+// validSpeed reports whether s is a game speed; "" leaves the timers to turnTimerSec.
+func validSpeed(s string) bool {
+	_, ok := speeds[s]
+	return ok || s == ""
+}
+
+// Clock returns the time limits the rules set. A speed sets all of them, taking precedence over
+// turnTimerSec and abilityTimerSec; without one, turns get turnTimerSec, ability steps get abilityTimerSec
+// (or turnTimerSec if it's 0), and there is no time bank.
+func (rules HouseRules) Clock() ClockSettings {
+	if c, ok := speeds[rules.Speed]; ok {
+		return c
+	}
+	c := ClockSettings{Turn: time.Duration(rules.TurnTimerSec) * time.Second}
+	c.Ability = c.Turn
+	if rules.AbilityTimerSec > 0 {
+		c.Ability = time.Duration(rules.AbilityTimerSec) * time.Second
+	}
+	return c
+}
+
+// EventClockSync tells players whose clock is running and when it runs out, each time it's restarted.
+const EventClockSync GameEventType = "game_clock"
+
+// The phases of a player's clock, as reported in EventClockSync.
+const (
+	clockPhaseTurn     = "turn"
+	clockPhaseAbility  = "ability"
+	clockPhaseTimeBank = "time_bank"
+)
+
+// startClock restarts the current player's clock at d, charging any time bank they were using, and tells
+// players when it runs out. When it does, the player draws on their time bank, if they have any left,
+// before timing out. A d of 0 means no time limit. Callers must hold g.Mu.
+func (g *CambiaGame) startClock(d time.Duration, phase string) {
+	g.stopClock()
+	if d == 0 || len(g.Players) == 0 {
+		return
+	}
+	g.armClock(g.Players[g.CurrentPlayerIndex].ID, d, phase)
+}
+
+// armClock sets the timer for playerID to run out after d. Callers must hold g.Mu.
+func (g *CambiaGame) armClock(playerID uuid.UUID, d time.Duration, phase string) {
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		g.post(func() {
+			defer g.HaltOnPanic("turn timer")
+			// the timer may have fired while being replaced
+			if g.turnTimer != t || g.GameOver {
+				return
+			}
+			g.clockExpired(playerID)
+		})
+	})
+	g.turnTimer = t
+	g.turnDeadline = time.Now().Add(d)
+	g.fireClockSync(playerID, phase)
+}
+
+// stopClock stops the running timer, if any, and charges the current player for the time bank they used.
+// Callers must hold g.Mu.
+func (g *CambiaGame) stopClock() {
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+		g.turnTimer = nil
+	}
+	if g.bankPlayer != uuid.Nil {
+		g.timeBanks[g.bankPlayer] = g.timeBankLeft(g.bankPlayer)
+		g.bankPlayer = uuid.Nil
+	}
+}
+
+// clockExpired moves playerID onto their time bank, or times them out if it's empty. Callers must hold g.Mu.
+func (g *CambiaGame) clockExpired(playerID uuid.UUID) {
+	g.turnTimer = nil
+	if g.bankPlayer == uuid.Nil && g.timeBanks[playerID] > 0 {
+		g.bankPlayer, g.bankSince = playerID, time.Now()
+		g.armClock(playerID, g.timeBanks[playerID], clockPhaseTimeBank)
+		return
+	}
+	if g.bankPlayer == playerID {
+		g.timeBanks[playerID] = 0
+		g.bankPlayer = uuid.Nil
+	}
+	if g.SpecialAction.Active && g.SpecialAction.PlayerID == playerID {
+		g.resolveAbilityTimeout(playerID)
+		return
+	}
+	g.handleTimeout(playerID)
+}
+
+// timeBankLeft returns how much of playerID's time bank is left, less what they're using right now.
+func (g *CambiaGame) timeBankLeft(playerID uuid.UUID) time.Duration {
+	bank := g.timeBanks[playerID]
+	if playerID == g.bankPlayer {
+		bank = max(0, bank-time.Since(g.bankSince))
+	}
+	return bank
+}
+
+// fireClockSync broadcasts the running clock and every player's time bank left, in milliseconds.
+func (g *CambiaGame) fireClockSync(playerID uuid.UUID, phase string) {
+	banks := make(map[string]int64, len(g.Players))
+	for _, p := range g.Players {
+		banks[p.ID.String()] = g.timeBankLeft(p.ID).Milliseconds()
+	}
+	g.fireEvent(GameEvent{
+		Type:   EventClockSync,
+		UserID: playerID,
+		Other: map[string]interface{}{
+			"phase":       phase,
+			"deadline":    g.turnDeadline.UnixMilli(),
+			"remainingMs": time.Until(g.turnDeadline).Milliseconds(),
+			"serverTime":  time.Now().UnixMilli(),
+			"timeBanks":   banks,
+		},
+	})
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestTimeBank(t *testing.T) {
+	g := NewCambiaGame()
+	p1 := &models.Player{ID: uuid.New(), Hand: []*models.Card{}}
+	p2 := &models.Player{ID: uuid.New(), Hand: []*models.Card{}}
+	g.Players = []*models.Player{p1, p2}
+	g.timeBanks[p1.ID] = 10 * time.Second
+	var clocks []GameEvent
+	g.BroadcastFn = func(ev GameEvent) {
+		if ev.Type == EventClockSync {
+			clocks = append(clocks, ev)
+		}
+	}
+	defer g.stopClock()
+
+	g.startClock(time.Hour, clockPhaseTurn)
+	g.clockExpired(p1.ID)
+	if g.bankPlayer != p1.ID || g.CurrentPlayerIndex != 0 {
+		t.Fatalf("expected the player to move onto their time bank")
+	}
+	if last := clocks[len(clocks)-1]; last.Other["phase"] != clockPhaseTimeBank || last.Other["remainingMs"].(int64) > 10000 {
+		t.Fatalf("expected a time bank clock sync, got %+v", last.Other)
+	}
+
+	// acting 4s into the bank charges it
+	g.bankSince = time.Now().Add(-4 * time.Second)
+	g.startClock(time.Hour, clockPhaseAbility)
+	if left := g.timeBanks[p1.ID]; left > 6*time.Second || left < 5*time.Second {
+		t.Fatalf("expected about 6s left in the time bank, got %v", left)
+	}
+
+	// running out of the bank times the player out
+	g.clockExpired(p1.ID)
+	g.bankSince = time.Now().Add(-time.Minute)
+	g.clockExpired(p1.ID)
+	if g.timeBanks[p1.ID] != 0 || g.CurrentPlayerIndex != 1 {
+		t.Fatalf("expected the player to time out once their bank ran out")
+	}
+}
+
+func TestSpeedClock(t *testing.T) {
+	rules := HouseRules{TurnTimerSec: 15}
+	if c := rules.Clock(); c.Turn != 15*time.Second || c.Ability != 15*time.Second || c.TimeBank != 0 {
+		t.Fatalf("expected turnTimerSec without a speed, got %+v", c)
+	}
+	if err := rules.Update(map[string]interface{}{"speed": SpeedBlitz}); err != nil {
+		t.Fatal(err)
+	}
+	if c := rules.Clock(); c != speeds[SpeedBlitz] {
+		t.Fatalf("expected the blitz clock, got %+v", c)
+	}
+	if err := rules.Update(map[string]interface{}{"speed": "ludicrous"}); err == nil {
+		t.Fatalf("expected an unknown speed to be refused")
+	}
+}
+
+func TestAbilityTimeout(t *testing.T) {
+	for _, rule := range []string{"", AbilityTimeoutRandom} {
+		g := NewCambiaGame()
+		own := &models.Card{ID: uuid.New(), Rank: "2"}
+		other := &models.Card{ID: uuid.New(), Rank: "3"}
+		p1 := &models.Player{ID: uuid.New(), Hand: []*models.Card{own}}
+		p2 := &models.Player{ID: uuid.New(), Hand: []*models.Card{other}}
+		g.Players = []*models.Player{p1, p2}
+		g.HouseRules.AbilityTimeout = rule
+		g.SpecialAction = SpecialActionState{Active: true, PlayerID: p1.ID, CardRank: "Q"}
+		var resolution interface{}
+		g.BroadcastFn = func(ev GameEvent) {
+			if ev.Type == EventPlayerAbilityTimeout {
+				resolution = ev.Other["resolution"]
+			}
+		}
+
+		g.clockExpired(p1.ID)
+		g.stopClock()
+		swapped := p1.Hand[0] == other && p2.Hand[0] == own
+		if g.SpecialAction.Active || g.CurrentPlayerIndex != 1 {
+			t.Fatalf("%q: expected the ability to end the turn", rule)
+		}
+		if rule == AbilityTimeoutRandom && (!swapped || resolution != AbilityTimeoutRandom) {
+			t.Fatalf("expected a random blind swap, got resolution %v", resolution)
+		}
+		if rule == "" && (swapped || resolution != AbilityTimeoutSkip) {
+			t.Fatalf("expected the ability to be skipped, got resolution %v", resolution)
+		}
+	}
+}
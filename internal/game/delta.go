@@ -0,0 +1,120 @@
+package game
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// spectatorCheckpointInterval is how many game_delta messages spectators get between full game_snapshot
+// checkpoints.
+const spectatorCheckpointInterval = 25
+
+// StateDelta is the "game_delta" message: the parts of the public view that changed since the previous
+// delta. Every field holds its new value rather than a difference, so a delta can be applied on top of any
+// snapshot or delta with a lower version; older ones are simply skipped.
+type StateDelta struct {
+	Type    string    `json:"type"`
+	GameID  uuid.UUID `json:"gameID"`
+	Version int       `json:"version"`
+
+	Started        *bool      `json:"started,omitempty"`
+	GameOver       *bool      `json:"gameOver,omitempty"`
+	TurnID         *int       `json:"turn,omitempty"`
+	CurrentPlayer  *uuid.UUID `json:"currentPlayer,omitempty"`
+	StockpileSize  *int       `json:"stockpileSize,omitempty"`
+	CambiaCalled   *bool      `json:"cambiaCalled,omitempty"`
+	CambiaCallerID *uuid.UUID `json:"cambiaCaller,omitempty"`
+
+	// DiscardFrom and DiscardCards replace the discard pile from index DiscardFrom onwards, so a draw from
+	// the pile and a discard onto it only cost the cards that moved.
+	DiscardFrom  *int           `json:"discardFrom,omitempty"`
+	DiscardCards []*models.Card `json:"discardCards,omitempty"`
+
+	// Players lists only the players whose hand or connection changed.
+	Players []PublicPlayerView `json:"players,omitempty"`
+}
+
+// diffViews returns the delta that takes prev to next, and whether anything changed.
+func diffViews(prev, next PublicGameView) (StateDelta, bool) {
+	d := StateDelta{Type: "game_delta", GameID: next.GameID, Version: next.Version}
+	changed := false
+	set := func(differs bool, apply func()) {
+		if differs {
+			apply()
+			changed = true
+		}
+	}
+	set(prev.Started != next.Started, func() { d.Started = &next.Started })
+	set(prev.GameOver != next.GameOver, func() { d.GameOver = &next.GameOver })
+	set(prev.TurnID != next.TurnID, func() { d.TurnID = &next.TurnID })
+	set(prev.CurrentPlayer != next.CurrentPlayer, func() { d.CurrentPlayer = &next.CurrentPlayer })
+	set(prev.StockpileSize != next.StockpileSize, func() { d.StockpileSize = &next.StockpileSize })
+	set(prev.CambiaCalled != next.CambiaCalled, func() { d.CambiaCalled = &next.CambiaCalled })
+	set(prev.CambiaCallerID != next.CambiaCallerID, func() { d.CambiaCallerID = &next.CambiaCallerID })
+
+	common := 0
+	for common < len(prev.DiscardPile) && common < len(next.DiscardPile) && prev.DiscardPile[common].ID == next.DiscardPile[common].ID {
+		common++
+	}
+	set(common < len(prev.DiscardPile) || common < len(next.DiscardPile), func() {
+		d.DiscardFrom = &common
+		d.DiscardCards = next.DiscardPile[common:]
+	})
+
+	before := make(map[uuid.UUID]PublicPlayerView, len(prev.Players))
+	for _, p := range prev.Players {
+		before[p.ID] = p
+	}
+	for _, p := range next.Players {
+		old, ok := before[p.ID]
+		set(!ok || old.Connected != p.Connected || !slices.Equal(old.HandCardIDs, p.HandCardIDs), func() {
+			d.Players = append(d.Players, p)
+		})
+	}
+	return d, changed
+}
+
+// publishSpectatorState sends spectators a game_delta for whatever the last public event changed, or a full
+// game_snapshot every spectatorCheckpointInterval deltas so clients that went astray can recover. Callers
+// must hold g.Mu.
+func (g *CambiaGame) publishSpectatorState() {
+	view := g.publicView()
+	// publicView shares the discard pile's backing array, which later moves overwrite in place.
+	view.DiscardPile = slices.Clone(view.DiscardPile)
+	prev := g.lastSpectatorView
+	g.lastSpectatorView = &view
+	// a delayed game's output is prepared even with nobody watching, for whoever joins before it's due
+	if g.SpectatorCount() == 0 && g.SpectatorDelay <= 0 {
+		return
+	}
+
+	var data []byte
+	if prev == nil || g.deltasSinceCheckpoint >= spectatorCheckpointInterval {
+		snapshot, err := g.cachedPublicSnapshot()
+		if err != nil {
+			return
+		}
+		data = snapshot
+		g.deltasSinceCheckpoint = 0
+	} else {
+		delta, changed := diffViews(*prev, view)
+		if !changed {
+			return
+		}
+		data, _ = json.Marshal(delta)
+		g.deltasSinceCheckpoint++
+	}
+	g.toSpectators(func() {
+		g.spectatorView = &view
+		conns := g.SpectatorConns()
+		deliveries := make([]Delivery, 0, len(conns))
+		for _, conn := range conns {
+			deliveries = append(deliveries, Delivery{Conn: conn, Type: websocket.MessageText, Data: data})
+		}
+		WriteAll(deliveries)
+	})
+}
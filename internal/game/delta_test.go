@@ -0,0 +1,49 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestDiffViews(t *testing.T) {
+	p1, p2 := uuid.New(), uuid.New()
+	a, b, c := &models.Card{ID: uuid.New()}, &models.Card{ID: uuid.New()}, &models.Card{ID: uuid.New()}
+	prev := PublicGameView{
+		Version:       3,
+		StockpileSize: 40,
+		CurrentPlayer: p1,
+		DiscardPile:   []*models.Card{a, b},
+		Players: []PublicPlayerView{
+			{ID: p1, Connected: true, HandCardIDs: []uuid.UUID{c.ID}},
+			{ID: p2, Connected: true, HandCardIDs: []uuid.UUID{}},
+		},
+	}
+	if _, changed := diffViews(prev, prev); changed {
+		t.Fatalf("expected no delta between identical views")
+	}
+
+	// p1 draws b from the discard pile and discards c in its place
+	next := prev
+	next.Version = 5
+	next.CurrentPlayer = p2
+	next.DiscardPile = []*models.Card{a, c}
+	next.Players = []PublicPlayerView{
+		{ID: p1, Connected: true, HandCardIDs: []uuid.UUID{b.ID}},
+		prev.Players[1],
+	}
+	d, changed := diffViews(prev, next)
+	if !changed || d.Version != 5 {
+		t.Fatalf("expected a delta to version 5, got %+v", d)
+	}
+	if d.CurrentPlayer == nil || *d.CurrentPlayer != p2 || d.StockpileSize != nil {
+		t.Fatalf("expected only the current player to change, got %+v", d)
+	}
+	if d.DiscardFrom == nil || *d.DiscardFrom != 1 || len(d.DiscardCards) != 1 || d.DiscardCards[0] != c {
+		t.Fatalf("expected the discard pile to be replaced from index 1 with c, got %v %v", d.DiscardFrom, d.DiscardCards)
+	}
+	if len(d.Players) != 1 || d.Players[0].ID != p1 {
+		t.Fatalf("expected only p1 in the delta, got %+v", d.Players)
+	}
+}
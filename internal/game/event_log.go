@@ -0,0 +1,49 @@
+package game
+
+import "sync"
+
+// eventLogSize is how many recent messages are kept per recipient for resync.
+const eventLogSize = 256
+
+// EventLog numbers the messages sent to a single recipient and keeps the most recent ones, so a client that
+// notices a gap in the sequence numbers can ask for what it missed instead of reconnecting.
+type EventLog[T any] struct {
+	mu     sync.Mutex
+	seq    uint64
+	events []T // the last len(events) messages, numbered seq-len(events)+1 through seq
+}
+
+// Record assigns the next sequence number, lets stamp build the message carrying it, and keeps the result.
+func (l *EventLog[T]) Record(stamp func(seq uint64) T) T {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	ev := stamp(l.seq)
+	l.events = append(l.events, ev)
+	if len(l.events) > eventLogSize {
+		l.events = l.events[len(l.events)-eventLogSize:]
+	}
+	return ev
+}
+
+// Latest returns the sequence number of the most recent message, or 0 if there are none yet.
+func (l *EventLog[T]) Latest() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// Since returns the messages numbered after seq, and the latest sequence number. complete is false if some
+// of them have already been dropped from the log, in which case the client needs a full resync.
+func (l *EventLog[T]) Since(seq uint64) (events []T, latest uint64, complete bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if seq >= l.seq {
+		return []T{}, l.seq, true
+	}
+	oldest := l.seq - uint64(len(l.events)) + 1
+	if seq+1 < oldest {
+		return []T{}, l.seq, false
+	}
+	return append([]T{}, l.events[seq+1-oldest:]...), l.seq, true
+}
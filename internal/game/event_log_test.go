@@ -0,0 +1,25 @@
+package game
+
+import "testing"
+
+func TestEventLogResync(t *testing.T) {
+	var l EventLog[uint64]
+	for i := 0; i < eventLogSize+10; i++ {
+		l.Record(func(seq uint64) uint64 { return seq })
+	}
+
+	events, latest, complete := l.Since(uint64(eventLogSize + 5))
+	if !complete || latest != eventLogSize+10 || len(events) != 5 || events[0] != eventLogSize+6 {
+		t.Fatalf("expected the last 5 events, got %v (latest %d, complete %v)", events, latest, complete)
+	}
+	if events, _, complete := l.Since(latest); !complete || len(events) != 0 {
+		t.Fatalf("expected nothing new, got %v", events)
+	}
+	// the first 10 events have aged out
+	if _, _, complete := l.Since(10); !complete {
+		t.Fatalf("expected seq 10 to still be resyncable")
+	}
+	if events, _, complete := l.Since(9); complete || len(events) != 0 {
+		t.Fatalf("expected seq 9 to need a full resync, got %v", events)
+	}
+}
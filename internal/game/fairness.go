@@ -0,0 +1,122 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Events of the shuffle commitment; see commitShuffle.
+const (
+	EventShuffleCommitment GameEventType = "game_shuffle_commitment"
+	EventShuffleReveal     GameEventType = "game_shuffle_reveal"
+)
+
+// shuffleAlgorithm names the way decks are shuffled from a seed, for verifiers; see shuffleWithSeed.
+const shuffleAlgorithm = "sha256-fisher-yates-v1"
+
+// newShuffleSeed draws a fresh secret seed, and a public salt for each player, to shuffle the deck with.
+// With a DealSeed, that's the secret seed and each salt is derived from it and the player's seat instead;
+// see seatSalt. Callers must hold g.Mu.
+func (g *CambiaGame) newShuffleSeed() {
+	g.shuffleSeed = randomBytes(32)
+	if g.DealSeed != nil {
+		g.shuffleSeed = g.DealSeed
+	}
+	g.shuffleSalts = make(map[string][]byte, len(g.Players))
+	for i, p := range g.Players {
+		salt := randomBytes(16)
+		if g.DealSeed != nil {
+			salt = seatSalt(g.DealSeed, i)
+		}
+		g.shuffleSalts[p.ID.String()] = salt
+	}
+}
+
+// seatSalt is the salt of the player in seat i of a game with a fixed deal: the first 16 bytes of the
+// SHA-256 of the deal seed, "seat" and i as a big-endian uint32.
+func seatSalt(seed []byte, i int) []byte {
+	h := sha256.New()
+	h.Write(seed)
+	h.Write([]byte("seat"))
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	return h.Sum(nil)[:16]
+}
+
+// deckSeed is the seed the deck is shuffled with: the SHA-256 of the secret seed followed by the players'
+// salts in seat order. Callers must hold g.Mu.
+func (g *CambiaGame) deckSeed() []byte {
+	h := sha256.New()
+	h.Write(g.shuffleSeed)
+	for _, p := range g.Players {
+		h.Write(g.shuffleSalts[p.ID.String()])
+	}
+	return h.Sum(nil)
+}
+
+// reshuffleSeed is the seed of the nth reshuffle of the discard pile into the stockpile: the SHA-256 of the
+// deck seed, "reshuffle" and n as a big-endian uint32. Callers must hold g.Mu.
+func (g *CambiaGame) reshuffleSeed(n int) []byte {
+	h := sha256.New()
+	h.Write(g.deckSeed())
+	h.Write([]byte("reshuffle"))
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	return h.Sum(nil)
+}
+
+// commitShuffle tells players the SHA-256 of the secret seed and their salts, before any card is dealt.
+// Once the game ends, fireShuffleReveal reveals the seed, so they can check it against the commitment and
+// recompute every shuffle of the game. Callers must hold g.Mu.
+func (g *CambiaGame) commitShuffle() {
+	commitment := sha256.Sum256(g.shuffleSeed)
+	salts := make(map[string]string, len(g.shuffleSalts))
+	for id, salt := range g.shuffleSalts {
+		salts[id] = hex.EncodeToString(salt)
+	}
+	g.fireEvent(GameEvent{
+		Type: EventShuffleCommitment,
+		Other: map[string]interface{}{
+			"commitment": hex.EncodeToString(commitment[:]),
+			"salts":      salts,
+			"algorithm":  shuffleAlgorithm,
+		},
+	})
+}
+
+// fireShuffleReveal reveals the secret seed committed to at the start. A DealSeed still shared with games
+// yet to be played stays secret, and the reveal only says when it can be; see DealSeedSecretUntil. Callers
+// must hold g.Mu.
+func (g *CambiaGame) fireShuffleReveal() {
+	other := map[string]interface{}{"reshuffles": g.reshuffles}
+	if g.DealSeed != nil && time.Now().Before(g.DealSeedSecretUntil) {
+		other["seedRevealedAt"] = g.DealSeedSecretUntil.UnixMilli()
+	} else {
+		other["seed"] = hex.EncodeToString(g.shuffleSeed)
+	}
+	g.fireEvent(GameEvent{Type: EventShuffleReveal, Other: other})
+}
+
+// shuffleWithSeed shuffles cards in place, deterministically from seed: a Fisher-Yates shuffle that, for i
+// from the last index down to 1, swaps card i with card j, where j is the first 8 bytes of the SHA-256 of
+// seed followed by i as a big-endian uint32, read as a big-endian uint64, modulo i+1.
+func shuffleWithSeed(cards []*models.Card, seed []byte) {
+	buf := make([]byte, len(seed)+4)
+	copy(buf, seed)
+	for i := len(cards) - 1; i > 0; i-- {
+		binary.BigEndian.PutUint32(buf[len(seed):], uint32(i))
+		sum := sha256.Sum256(buf)
+		j := binary.BigEndian.Uint64(sum[:8]) % uint64(i+1)
+		cards[i], cards[j] = cards[j], cards[i]
+	}
+}
+
+// randomBytes returns n bytes from the system's secure random source.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
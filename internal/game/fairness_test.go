@@ -0,0 +1,87 @@
+package game
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestShuffleCommitment(t *testing.T) {
+	g := NewCambiaGame()
+	g.Players = []*models.Player{{ID: uuid.New()}, {ID: uuid.New()}}
+	var events []GameEvent
+	g.BroadcastFn = func(ev GameEvent) { events = append(events, ev) }
+	g.newShuffleSeed()
+	g.initializeDeck()
+	g.commitShuffle()
+	dealt := append([]*models.Card(nil), g.Deck...)
+	g.fireShuffleReveal()
+
+	commit, reveal := events[0].Other, events[1].Other
+	seed, err := hex.DecodeString(reveal["seed"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(seed); hex.EncodeToString(sum[:]) != commit["commitment"] {
+		t.Fatalf("expected the revealed seed to match the commitment")
+	}
+
+	// a verifier rebuilds the deck from the seed and the published salts
+	h := sha256.New()
+	h.Write(seed)
+	for _, p := range g.Players {
+		salt, _ := hex.DecodeString(commit["salts"].(map[string]string)[p.ID.String()])
+		h.Write(salt)
+	}
+	deck := buildDeck(g.HouseRules)
+	shuffleWithSeed(deck, h.Sum(nil))
+	for i := range deck {
+		if deck[i].Rank != dealt[i].Rank || deck[i].Suit != dealt[i].Suit {
+			t.Fatalf("card %d: expected %s of %s, dealt %s of %s", i, deck[i].Rank, deck[i].Suit, dealt[i].Rank, dealt[i].Suit)
+		}
+	}
+}
+
+func TestDealSeed(t *testing.T) {
+	seed := randomBytes(32)
+	deal := func() []string {
+		g := NewCambiaGame()
+		g.DealSeed = seed
+		g.Players = []*models.Player{{ID: uuid.New()}, {ID: uuid.New()}}
+		g.newShuffleSeed()
+		g.initializeDeck()
+		faces := make([]string, len(g.Deck))
+		for i, c := range g.Deck {
+			faces[i] = c.Rank + c.Suit
+		}
+		return faces
+	}
+	if a, b := deal(), deal(); !slices.Equal(a, b) {
+		t.Fatalf("expected games with the same deal seed to be dealt the same cards")
+	}
+}
+
+func TestDealSeedSecret(t *testing.T) {
+	g := NewCambiaGame()
+	g.DealSeed = randomBytes(32)
+	g.DealSeedSecretUntil = time.Now().Add(time.Hour)
+	g.Players = []*models.Player{{ID: uuid.New()}}
+	g.newShuffleSeed()
+	var reveal GameEvent
+	g.BroadcastFn = func(ev GameEvent) { reveal = ev }
+
+	g.fireShuffleReveal()
+	if _, ok := reveal.Other["seed"]; ok {
+		t.Fatal("expected a deal seed still in use to stay secret")
+	}
+	g.DealSeedSecretUntil = time.Now().Add(-time.Second)
+	g.fireShuffleReveal()
+	if reveal.Other["seed"] != hex.EncodeToString(g.DealSeed) {
+		t.Fatalf("expected the deal seed once it's no longer in use, got %v", reveal.Other)
+	}
+}
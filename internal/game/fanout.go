@@ -0,0 +1,228 @@
+package game
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/jason-s-yu/cambia/internal/crash"
+)
+
+// Broadcast fan-out. A broadcast is marshaled once and the same bytes go to every recipient; where each gets
+// its own "seq", it's spliced into the shared payload with WithSeq. Game and spectator frames are then
+// written by a pool of fanoutWorkers goroutines shared by every game, so one slow socket holds up neither
+// the other recipients of a broadcast nor the game for longer than its own write, and the number of writes
+// in flight stays bounded however many games are running. A socket's frames are written one job at a time,
+// in the order they were handed to the pool.
+const fanoutWorkers = 64
+
+// maxBatch caps how many events are merged into one batched frame.
+const maxBatch = 64
+
+// Delivery is one frame for one socket. Batch marks a JSON event the recipient accepts as part of a batch;
+// see WriteAll.
+type Delivery struct {
+	Conn  *websocket.Conn
+	Type  websocket.MessageType
+	Data  []byte
+	Batch bool
+}
+
+// fanoutJob is the frames for one socket, written in order. done, if set, is told once they have been.
+type fanoutJob struct {
+	conn   *websocket.Conn
+	frames []Delivery
+	done   *sync.WaitGroup
+}
+
+var (
+	fanoutOnce sync.Once
+	fanoutJobs chan fanoutJob
+
+	// fanoutQueued holds the jobs waiting for each socket that a worker is writing to, so that a socket's
+	// jobs are written one after another, in the order they were handed to the pool.
+	fanoutMu     sync.Mutex
+	fanoutQueued = make(map[*websocket.Conn][]fanoutJob)
+)
+
+// WriteAll writes the deliveries with WriteFrame, each socket's in order and different sockets concurrently
+// on the fan-out pool, and returns once all of them have been written or have failed. Consecutive Batch
+// deliveries to a socket are merged into one frame holding a JSON array of the events.
+func WriteAll(ds []Delivery) {
+	var wg sync.WaitGroup
+	dispatch(ds, &wg)
+	wg.Wait()
+}
+
+// QueueAll hands the deliveries to the fan-out pool like WriteAll, but returns without waiting for them to
+// be written. They still reach each socket before anything handed to the pool for it afterwards.
+func QueueAll(ds []Delivery) {
+	dispatch(ds, nil)
+}
+
+// dispatch splits the deliveries into a job per socket and hands them to the pool, adding them to done.
+func dispatch(ds []Delivery, done *sync.WaitGroup) {
+	if len(ds) == 0 {
+		return
+	}
+	var (
+		order  []*websocket.Conn
+		byConn = make(map[*websocket.Conn][]Delivery)
+	)
+	for _, d := range ds {
+		if _, ok := byConn[d.Conn]; !ok {
+			order = append(order, d.Conn)
+		}
+		byConn[d.Conn] = append(byConn[d.Conn], d)
+	}
+	fanoutOnce.Do(startFanout)
+	if done != nil {
+		done.Add(len(order))
+	}
+	for _, conn := range order {
+		job := fanoutJob{conn: conn, frames: byConn[conn], done: done}
+		fanoutMu.Lock()
+		if queued, busy := fanoutQueued[conn]; busy {
+			// the worker writing to the socket picks it up after the jobs ahead of it
+			fanoutQueued[conn] = append(queued, job)
+			fanoutMu.Unlock()
+			continue
+		}
+		fanoutQueued[conn] = nil
+		fanoutMu.Unlock()
+		fanoutJobs <- job
+	}
+}
+
+// batchFrames merges each run of consecutive Batch deliveries into one frame, as a JSON array of up to
+// maxBatch events. A run of one is left as it is.
+func batchFrames(frames []Delivery) []Delivery {
+	out := make([]Delivery, 0, len(frames))
+	for i := 0; i < len(frames); {
+		j := i + 1
+		if frames[i].Batch && frames[i].Type == websocket.MessageText {
+			for j < len(frames) && j-i < maxBatch && frames[j].Batch && frames[j].Type == websocket.MessageText {
+				j++
+			}
+		}
+		if j-i == 1 {
+			out = append(out, frames[i])
+			i = j
+			continue
+		}
+		size := 1
+		for _, f := range frames[i:j] {
+			size += len(f.Data) + 1
+		}
+		data := make([]byte, 0, size)
+		data = append(data, '[')
+		for k, f := range frames[i:j] {
+			if k > 0 {
+				data = append(data, ',')
+			}
+			data = append(data, f.Data...)
+		}
+		data = append(data, ']')
+		out = append(out, Delivery{Conn: frames[i].Conn, Type: websocket.MessageText, Data: data, Batch: true})
+		i = j
+	}
+	return out
+}
+
+// writeFrames writes a socket's frames in order, stopping at the first that fails.
+func writeFrames(conn *websocket.Conn, frames []Delivery) {
+	for _, f := range batchFrames(frames) {
+		if WriteFrame(conn, f.Type, f.Data) != nil {
+			return
+		}
+	}
+}
+
+func startFanout() {
+	fanoutJobs = make(chan fanoutJob, fanoutWorkers)
+	for range fanoutWorkers {
+		go func() {
+			for job := range fanoutJobs {
+				writeJob(job)
+			}
+		}()
+	}
+}
+
+// writeJob writes a job's frames, then those of any jobs queued for its socket meanwhile.
+func writeJob(job fanoutJob) {
+	for {
+		writeJobFrames(job)
+		fanoutMu.Lock()
+		queued := fanoutQueued[job.conn]
+		if len(queued) == 0 {
+			delete(fanoutQueued, job.conn)
+			fanoutMu.Unlock()
+			return
+		}
+		job, fanoutQueued[job.conn] = queued[0], queued[1:]
+		fanoutMu.Unlock()
+	}
+}
+
+func writeJobFrames(job fanoutJob) {
+	if job.done != nil {
+		defer job.done.Done()
+	}
+	defer crash.Recover("broadcast fan-out")
+	writeFrames(job.conn, job.frames)
+}
+
+// WithSeq returns a copy of a JSON object marshaled without a "seq", with "seq" added. It lets a broadcast
+// be marshaled once and numbered for each recipient.
+func WithSeq(body []byte, seq uint64) []byte {
+	if len(body) < 2 || body[len(body)-1] != '}' {
+		return body
+	}
+	out := make([]byte, 0, len(body)+28)
+	out = append(out, body[:len(body)-1]...)
+	if len(body) > 2 {
+		out = append(out, ',')
+	}
+	out = append(out, `"seq":`...)
+	out = strconv.AppendUint(out, seq, 10)
+	return append(out, '}')
+}
+
+// encodedKey holds, in the copy of a lobby broadcast queued for one recipient, the broadcast as marshaled
+// once for everyone; see EncodeFrame. It's never sent as a field.
+const encodedKey = "\x00encoded"
+
+// encodedFrame is a lobby broadcast marshaled without its "seq", and the recipient's seq.
+type encodedFrame struct {
+	body []byte
+	seq  uint64
+}
+
+// EncodeFrame marshals a lobby message for the socket. Broadcasts reuse the bytes marshaled once for all
+// recipients, unless the message was changed since, e.g. by StampRequestID.
+func EncodeFrame(msg map[string]interface{}) ([]byte, error) {
+	ef, ok := msg[encodedKey].(encodedFrame)
+	if !ok {
+		return json.Marshal(msg)
+	}
+	if _, stamped := msg["request_id"]; !stamped {
+		return WithSeq(ef.body, ef.seq), nil
+	}
+	return json.Marshal(withoutEncoding(msg))
+}
+
+// withoutEncoding returns msg without the shared encoding EncodeFrame uses, e.g. to hand it back on resync.
+func withoutEncoding(msg map[string]interface{}) map[string]interface{} {
+	if _, ok := msg[encodedKey]; !ok {
+		return msg
+	}
+	out := make(map[string]interface{}, len(msg)-1)
+	for k, v := range msg {
+		if k != encodedKey {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestWithSeq(t *testing.T) {
+	ev := GameEvent{Type: EventPlayerDiscard, UserID: uuid.New(), Card: &models.Card{ID: uuid.New(), Rank: "7"}}
+	body, _ := json.Marshal(ev)
+	ev.Seq = 42
+	want, _ := json.Marshal(ev)
+
+	var got, expected map[string]interface{}
+	if err := json.Unmarshal(WithSeq(body, 42), &got); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	json.Unmarshal(want, &expected)
+	if got["seq"] != expected["seq"] || got["type"] != expected["type"] || len(got) != len(expected) {
+		t.Fatalf("expected %s, got %s", want, WithSeq(body, 42))
+	}
+	if string(WithSeq([]byte(`{}`), 1)) != `{"seq":1}` {
+		t.Fatalf("expected seq in an empty object, got %s", WithSeq([]byte(`{}`), 1))
+	}
+}
+
+func TestLobbyBroadcastEncodedOnce(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.Type = "public"
+	id := uuid.New()
+	conn := &LobbyConnection{UserID: id, OutChan: make(chan map[string]interface{}, 4), RequestID: "r-1"}
+	if err := lobby.AddConnection(id, conn); err != nil {
+		t.Fatal(err)
+	}
+
+	lobby.BroadcastAll(map[string]interface{}{"type": "lobby_countdown_start", "seconds": 10})
+	data, err := EncodeFrame(<-conn.OutChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+	if got["type"] != "lobby_countdown_start" || got["seq"] != float64(1) || len(got) != 3 {
+		t.Fatalf("expected the countdown with seq 1, got %s", data)
+	}
+
+	// a stamped error frame is marshaled again, so it carries the request ID
+	lobby.BroadcastAll(map[string]interface{}{"type": "error", "message": "nope"})
+	data, _ = EncodeFrame(<-conn.OutChan)
+	json.Unmarshal(data, &got)
+	if got["request_id"] != "r-1" || got["seq"] != float64(2) {
+		t.Fatalf("expected a stamped error with seq 2, got %s", data)
+	}
+
+	msgs, _, _ := lobby.Resync(id, 0)
+	for _, msg := range msgs {
+		if _, ok := msg[encodedKey]; ok {
+			t.Fatalf("expected resync to drop the shared encoding, got %v", msg)
+		}
+	}
+}
+
+func BenchmarkLobbyBroadcast(b *testing.B) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.Type = "public"
+	var conns []*LobbyConnection
+	for range 8 {
+		id := uuid.New()
+		conn := &LobbyConnection{UserID: id, OutChan: make(chan map[string]interface{}, 1)}
+		lobby.AddConnection(id, conn)
+		conns = append(conns, conn)
+	}
+	msg := map[string]interface{}{"type": "lobby_update", "ready_map": lobby.ReadyMap(), "seats": map[string]int{}}
+	b.ResetTimer()
+	for range b.N {
+		lobby.BroadcastAll(msg)
+		for _, conn := range conns {
+			for _, m := range append([]map[string]interface{}{<-conn.OutChan}, conn.TakeCoalesced()...) {
+				EncodeFrame(m)
+			}
+		}
+	}
+}
+
+func TestBatchFrames(t *testing.T) {
+	frames := []Delivery{
+		{Type: websocket.MessageText, Data: []byte(`{"seq":1}`), Batch: true},
+		{Type: websocket.MessageText, Data: []byte(`{"seq":2}`), Batch: true},
+		{Type: websocket.MessageBinary, Data: []byte{0x30, 0x03}},
+		{Type: websocket.MessageText, Data: []byte(`{"seq":4}`), Batch: true},
+		{Type: websocket.MessageText, Data: []byte(`{"seq":5}`)},
+	}
+	got := batchFrames(frames)
+	if len(got) != 4 || string(got[0].Data) != `[{"seq":1},{"seq":2}]` {
+		t.Fatalf("expected the first two events merged, got %d frames starting %s", len(got), got[0].Data)
+	}
+	if string(got[2].Data) != `{"seq":4}` || string(got[3].Data) != `{"seq":5}` {
+		t.Fatalf("expected a lone batchable event to stay a plain frame, got %s and %s", got[2].Data, got[3].Data)
+	}
+
+	var many []Delivery
+	for range maxBatch + 1 {
+		many = append(many, Delivery{Type: websocket.MessageText, Data: []byte(`{}`), Batch: true})
+	}
+	if got := batchFrames(many); len(got) != 2 {
+		t.Fatalf("expected a batch capped at %d events, got %d frames", maxBatch, len(got))
+	}
+}
+
+func TestSendBatchesEventsOfOneCommand(t *testing.T) {
+	accepted := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		accepted <- c
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, _, err := websocket.Dial(ctx, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseNow()
+	conn := <-accepted
+
+	g := NewCambiaGame()
+	g.Do(func() {
+		for i := range 3 {
+			g.Send([]Delivery{{Conn: conn, Type: websocket.MessageText, Data: WithSeq([]byte(`{"type":"x"}`), uint64(i+1)), Batch: true}})
+		}
+		if len(g.loop.outbox) != 3 {
+			t.Errorf("expected the events held until the command is done, got %d", len(g.loop.outbox))
+		}
+	})
+	_, data, err := client.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batch []map[string]interface{}
+	if err := json.Unmarshal(data, &batch); err != nil || len(batch) != 3 || batch[2]["seq"] != float64(3) {
+		t.Fatalf("expected one frame with the 3 events in order, got %s", data)
+	}
+}
+
+func TestQueuedFramesKeepTheirOrder(t *testing.T) {
+	accepted := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		accepted <- c
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, _, err := websocket.Dial(ctx, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseNow()
+	conn := <-accepted
+
+	g := NewCambiaGame()
+	g.Players = []*models.Player{}
+	spectator := uuid.New()
+	if err := g.HydrateSpectator(spectator, conn); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		QueueAll([]Delivery{{Conn: conn, Type: websocket.MessageText, Data: []byte(`{"n":` + strconv.Itoa(i) + `}`)}})
+	}
+	WriteAll([]Delivery{{Conn: conn, Type: websocket.MessageText, Data: []byte(`{"n":5}`)}})
+
+	_, data, err := client.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil || snapshot["type"] != "game_snapshot" {
+		t.Fatalf("expected the snapshot first, got %s", data)
+	}
+	for i := range 6 {
+		_, data, err := client.Read(ctx)
+		var frame map[string]interface{}
+		if err != nil || json.Unmarshal(data, &frame) != nil || frame["n"] != float64(i) {
+			t.Fatalf("expected frame %d next, got %s (%v)", i, data, err)
+		}
+	}
+}
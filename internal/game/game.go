@@ -4,11 +4,13 @@ package game
 import (
 	"context"
 	"log"
-	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/coder/websocket"
 	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/crash"
 	"github.com/jason-s-yu/cambia/internal/database"
 	"github.com/jason-s-yu/cambia/internal/models"
 )
@@ -20,14 +22,15 @@ type OnGameEndFunc func(lobbyID uuid.UUID, winner uuid.UUID, scores map[uuid.UUI
 type GameEventType string
 
 const (
-	EventSnapSuccess      GameEventType = "player_snap_success"
-	EventSnapFail         GameEventType = "player_snap_fail"
-	EventSnapPenalty      GameEventType = "player_snap_penalty"
-	EventReshuffle        GameEventType = "game_reshuffle_stockpile"
-	EventPlayerDrawStock  GameEventType = "player_draw_stockpile"
-	EventPrivateDrawStock GameEventType = "private_draw_stockpile"
-	EventPlayerDiscard    GameEventType = "player_discard"
-	EventPlayerReplace    GameEventType = "player_replace"
+	EventSnapSuccess       GameEventType = "player_snap_success"
+	EventSnapFail          GameEventType = "player_snap_fail"
+	EventSnapPenalty       GameEventType = "player_snap_penalty"
+	EventReshuffle         GameEventType = "game_reshuffle_stockpile"
+	EventPlayerDrawStock   GameEventType = "player_draw_stockpile"
+	EventPlayerDrawDiscard GameEventType = "player_draw_discardpile"
+	EventPrivateDrawStock  GameEventType = "private_draw_stockpile"
+	EventPlayerDiscard     GameEventType = "player_discard"
+	EventPlayerReplace     GameEventType = "player_replace"
 
 	EventPlayerSpecialChoice      GameEventType = "player_special_choice"
 	EventPlayerSpecialAction      GameEventType = "player_special_action"
@@ -36,6 +39,15 @@ const (
 
 	EventPlayerCambia GameEventType = "player_cambia"
 	EventPlayerTurn   GameEventType = "player_turn"
+
+	EventSpectatorWinProbability GameEventType = "spectator_win_probability"
+
+	EventGameHalted GameEventType = "game_halted"
+
+	EventSystemAnnouncement GameEventType = "system_announcement"
+
+	EventChat  GameEventType = "chat"
+	EventEmote GameEventType = "emote"
 )
 
 // GameEvent holds data about an event that can be broadcast to the clients in a consistent format.
@@ -45,6 +57,12 @@ type GameEvent struct {
 	Card   *models.Card           `json:"card,omitempty"`
 	Card2  *models.Card           `json:"card2,omitempty"`
 	Other  map[string]interface{} `json:"other,omitempty"`
+
+	// Seq numbers the events delivered to each player, starting at 1; see SequenceFor.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// Hidden lists users the event isn't delivered to, e.g. those who blocked a chat message's sender.
+	Hidden map[uuid.UUID]bool `json:"-"`
 }
 
 // SpecialActionState holds temporary info about a pending special action.
@@ -66,29 +84,128 @@ type CambiaGame struct {
 	LobbyID uuid.UUID // references the lobby that spawned this game
 
 	HouseRules HouseRules
+	Ranked     bool // whether the result of this game counts towards ratings
+	// Tournament marks a tournament pairing, whose result the tournament is waiting on.
+	Tournament bool
+	// Practice marks a solo game against bots, or a hot-seat game. It isn't saved, so it's neither recorded
+	// nor restored after a restart.
+	Practice bool
+	// DealSeed, if set, fixes the shuffle: games with the same seed, rules, and number of seats are dealt
+	// the same cards, e.g. a daily challenge. See newShuffleSeed.
+	DealSeed []byte
+	// DealSeedSecretUntil keeps a DealSeed out of the shuffle reveal until then, since other games are
+	// dealt from it, e.g. the rest of a daily challenge's day.
+	DealSeedSecretUntil time.Time
+	// ChatDisabled turns off chat messages, though not emotes, e.g. in a ranked game whose host disabled it.
+	ChatDisabled bool
+	// CircuitRound is this game's round index within its lobby's circuit series (0 outside circuits).
+	CircuitRound int
+
+	// EngineVersion and RulesRevision are fixed when the game is created; the revision decides the
+	// capabilities the game offers. See Revisions.
+	EngineVersion string
+	RulesRevision int
 
 	Players     []*models.Player
 	Deck        []*models.Card
 	DiscardPile []*models.Card
+	// Teams is each player's team in a team game, nil otherwise; see SeatTeams.
+	Teams map[uuid.UUID]int
 
 	CurrentPlayerIndex int
 	Started            bool
+	StartedAt          time.Time
 	GameOver           bool
 
-	lastSeen     map[uuid.UUID]time.Time
-	turnTimer    *time.Timer
+	lastSeen map[uuid.UUID]time.Time
+	// offline marks players whose connection closed and who haven't reconnected.
+	offline map[uuid.UUID]bool
+	// abortVotes are the players who voted to abort the game; see VoteAbort.
+	abortVotes map[uuid.UUID]bool
+	// known holds the IDs of the cards each player has seen the face of; see learn.
+	known map[uuid.UUID]map[uuid.UUID]bool
+	// blindSnaps counts each player's correct snaps of cards they never saw, and cambiaCallerSeen how
+	// many cards in their hand the Cambia caller had seen; see checkSnap and checkCambia.
+	blindSnaps       map[uuid.UUID]int
+	cambiaCallerSeen int
+	// consecutiveTimeouts counts turns in a row each player let time out; any action resets it.
+	consecutiveTimeouts map[uuid.UUID]int
+	turnTimer           *time.Timer
+	reshuffles          int // times the discard pile has been shuffled into the stockpile
+	// shuffleSeed is the secret the deck is shuffled with, revealed when the game ends, and shuffleSalts
+	// each player's public salt, by user ID; see commitShuffle.
+	shuffleSeed  []byte
+	shuffleSalts map[string][]byte
+
 	TurnID       int
 	TurnDuration time.Duration
 
+	// clock holds the turn and ability time limits; see startClock. timeBanks is each player's time bank
+	// left. While the current player's turn runs on their bank, bankPlayer is set, since bankSince.
+	clock        ClockSettings
+	turnDeadline time.Time
+	timeBanks    map[uuid.UUID]time.Duration
+	bankPlayer   uuid.UUID
+	bankSince    time.Time
+
+	// Actions is the log of public game events, persisted to game_actions when the game ends.
 	Actions []models.GameAction
 
-	OnGameEnd   OnGameEndFunc
+	OnGameEnd OnGameEndFunc
+	// OnAbandon is called when a ranked game ends, once per player who abandoned it.
+	OnAbandon func(gameID, userID uuid.UUID)
+	// OnAnomaly is called when a player's play suggests they know cards they never saw; see Anomaly.
+	OnAnomaly func(a Anomaly)
+	// OnHalted is called when a panic in the game's logic stops the game; see HaltOnPanic.
+	OnHalted func(lobbyID uuid.UUID)
+	// OnAborted is called when the players vote to abort the game; see VoteAbort.
+	OnAborted func(gameID uuid.UUID)
+	// OnRecorded is called, in the background, once the game's results have been persisted.
+	OnRecorded  func(rec database.GameRecord)
 	BroadcastFn func(ev GameEvent) // callback to broadcast game events
 
+	// spectators are read-only connections watching the game, guarded by spectatorsMu rather than Mu so
+	// spectators coming and going don't wait on gameplay. They receive public events and spectator-only
+	// annotations via SpectatorFn, never private_* events.
+	spectatorsMu sync.Mutex
+	spectators   map[uuid.UUID]*websocket.Conn
+	SpectatorFn  func(ev GameEvent)
+	// SpectatorDelay holds spectator output back, e.g. in ranked games so streamed players can't be
+	// sniped; 0 means live. spectatorQueue is the output held back, and spectatorView the public view
+	// spectators have been brought up to; see toSpectators.
+	SpectatorDelay time.Duration
+	spectatorQueue []delayedFrame
+	spectatorTimer *time.Timer
+	spectatorView  *PublicGameView
+
+	// latency is each player's round-trip time, from their pings; see RecordLatency.
+	latency map[uuid.UUID]*playerLatency
+
+	// eventLogs number and keep the recent events delivered to each player, for resync.
+	eventLogs map[uuid.UUID]*EventLog[GameEvent]
+
+	// actions remembers recent client action IDs, so retried actions aren't applied twice; see ApplyOnce.
+	actionsMu sync.Mutex
+	actions   map[actionKey]actionRecord
+
+	// stateVersion is bumped on every state change; snapshotCache holds the marshaled public
+	// projection for snapshotVersion so spectators joining at the same time share one build.
+	stateVersion    int
+	snapshotVersion int
+	snapshotCache   []byte
+
+	// lastSpectatorView is the public view spectators were last brought up to, which the next game_delta
+	// is computed against; deltasSinceCheckpoint counts deltas since the last full snapshot.
+	lastSpectatorView     *PublicGameView
+	deltasSinceCheckpoint int
+
 	// SpecialAction is used for multi-step card logic (K, Q, J, etc.)
 	SpecialAction SpecialActionState
 
-	Mu sync.Mutex
+	// Mu guards the game state, players' connections included. Changes are made by commands on the
+	// game's loop, which hold it; see loop.go. When both are needed, take Mu before spectatorsMu.
+	Mu   sync.Mutex
+	loop gameLoop
 
 	// CambiaCalled tracks if a player has invoked "Cambia". If so, we do a final round logic.
 	CambiaCalled       bool
@@ -100,19 +217,30 @@ type CambiaGame struct {
 func NewCambiaGame() *CambiaGame {
 	id, _ := uuid.NewV7()
 	g := &CambiaGame{
-		ID:                 id,
-		Deck:               []*models.Card{},
-		DiscardPile:        []*models.Card{},
-		lastSeen:           make(map[uuid.UUID]time.Time),
-		TurnID:             0,
-		CurrentPlayerIndex: 0,
-		Started:            false,
-		GameOver:           false,
-		TurnDuration:       15 * time.Second,
-		SpecialAction:      SpecialActionState{},
-		CambiaCalled:       false,
-		CambiaFinalCounter: 0,
-	}
+		ID:                  id,
+		EngineVersion:       EngineVersion,
+		RulesRevision:       DefaultRulesRevision(),
+		Deck:                []*models.Card{},
+		DiscardPile:         []*models.Card{},
+		lastSeen:            make(map[uuid.UUID]time.Time),
+		offline:             make(map[uuid.UUID]bool),
+		abortVotes:          make(map[uuid.UUID]bool),
+		known:               make(map[uuid.UUID]map[uuid.UUID]bool),
+		blindSnaps:          make(map[uuid.UUID]int),
+		consecutiveTimeouts: make(map[uuid.UUID]int),
+		timeBanks:           make(map[uuid.UUID]time.Duration),
+		spectators:          make(map[uuid.UUID]*websocket.Conn),
+		eventLogs:           make(map[uuid.UUID]*EventLog[GameEvent]),
+		TurnID:              0,
+		CurrentPlayerIndex:  0,
+		Started:             false,
+		GameOver:            false,
+		TurnDuration:        15 * time.Second,
+		SpecialAction:       SpecialActionState{},
+		CambiaCalled:        false,
+		CambiaFinalCounter:  0,
+	}
+	g.newShuffleSeed()
 	g.initializeDeck()
 	return g
 }
@@ -121,85 +249,160 @@ func NewCambiaGameFromLobby(ctx context.Context, lobby *Lobby) *CambiaGame {
 	g := NewCambiaGame()
 	g.LobbyID = lobby.ID
 	g.HouseRules = lobby.HouseRules
+	g.Ranked = lobby.Ranked
 	return g
 }
 
-// AddPlayer merges the logic from old AddPlayer. If the player already exists, update the conn.
-func (g *CambiaGame) AddPlayer(p *models.Player) {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
+// StatusSuperseded is the close code sent to a player's game connection when the same player connects
+// again, e.g. from another device.
+const StatusSuperseded websocket.StatusCode = 4009
+
+// AddPlayer merges the logic from old AddPlayer. If the player already exists, the new connection takes
+// over: it returns the connection being replaced, if still open, for the caller to close with
+// StatusSuperseded.
+func (g *CambiaGame) AddPlayer(p *models.Player) (superseded *websocket.Conn) {
+	g.Do(func() { superseded = g.addPlayer(p) })
+	return superseded
+}
+
+func (g *CambiaGame) addPlayer(p *models.Player) (superseded *websocket.Conn) {
 	for i, pl := range g.Players {
 		if pl.ID == p.ID {
 			// reconnect
+			if pl.Connected && pl.Conn != p.Conn {
+				superseded = pl.Conn
+			}
 			g.Players[i].Conn = p.Conn
+			g.Players[i].ProtocolVersion = p.ProtocolVersion
+			g.Players[i].Encoding = p.Encoding
+			g.Players[i].LegalActionHints = p.LegalActionHints
+			g.Players[i].BatchEvents = p.BatchEvents
 			g.Players[i].Connected = true
 			g.lastSeen[p.ID] = time.Now()
-			return
+			delete(g.offline, p.ID)
+			g.markStateChanged()
+			return superseded
 		}
 	}
 	g.Players = append(g.Players, p)
 	g.lastSeen[p.ID] = time.Now()
+	g.markStateChanged()
+	return nil
+}
+
+// IsActiveConn reports whether conn is the player's current connection, i.e. it hasn't been superseded.
+func (g *CambiaGame) IsActiveConn(playerID uuid.UUID, conn *websocket.Conn) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	return g.isActiveConnLocked(playerID, conn)
 }
 
-// initializeDeck sets up a standard Cambia deck, including jokers, red kings = -1, etc.
+func (g *CambiaGame) isActiveConnLocked(playerID uuid.UUID, conn *websocket.Conn) bool {
+	for _, pl := range g.Players {
+		if pl.ID == playerID {
+			return pl.Conn == conn
+		}
+	}
+	return false
+}
+
+// RemoveSpectator drops a spectator connection.
+func (g *CambiaGame) RemoveSpectator(userID uuid.UUID) {
+	g.spectatorsMu.Lock()
+	defer g.spectatorsMu.Unlock()
+	delete(g.spectators, userID)
+}
+
+// initializeDeck sets up a Cambia deck as configured by the house rules, shuffled with the deck seed; see
+// newShuffleSeed.
 func (g *CambiaGame) initializeDeck() {
+	deck := buildDeck(g.HouseRules)
+	shuffleWithSeed(deck, g.deckSeed())
+	g.Deck = deck
+}
+
+// buildDeck returns an unshuffled deck: one standard 52 card deck, or two if DoubleDeck is set, plus the
+// configured number of jokers.
+func buildDeck(rules HouseRules) []*models.Card {
 	suits := []string{"Hearts", "Diamonds", "Clubs", "Spades"}
 	ranks := []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
-	values := map[string]int{
-		"A": 1, "2": 2, "3": 3, "4": 4,
-		"5": 5, "6": 6, "7": 7, "8": 8,
-		"9": 9, "10": 10, "J": 11, "Q": 12,
-		"K": 13,
-	}
-
-	var deck []*models.Card
-	for _, suit := range suits {
-		for _, rank := range ranks {
-			val := values[rank]
-			if rank == "K" && (suit == "Hearts" || suit == "Diamonds") {
-				val = -1 // set red kings to -1
+	decks := 1
+	if rules.DoubleDeck {
+		decks = 2
+	}
+
+	deck := make([]*models.Card, 0, decks*len(suits)*len(ranks)+rules.Jokers)
+	for d := 0; d < decks; d++ {
+		for _, suit := range suits {
+			for _, rank := range ranks {
+				cid, _ := uuid.NewRandom()
+				deck = append(deck, &models.Card{
+					ID:    cid,
+					Suit:  suit,
+					Rank:  rank,
+					Value: cardValue(rank, suit),
+				})
 			}
-			cid, _ := uuid.NewRandom()
-			card := &models.Card{
-				ID:    cid,
-				Suit:  suit,
-				Rank:  rank,
-				Value: val,
-			}
-			deck = append(deck, card)
 		}
 	}
-	// 2 jokers
-	for i := 0; i < 2; i++ {
+	for i := 0; i < rules.Jokers; i++ {
 		cid, _ := uuid.NewRandom()
 		deck = append(deck, &models.Card{
 			ID:    cid,
 			Suit:  "Joker",
 			Rank:  "Joker",
-			Value: 0,
+			Value: cardValue("Joker", "Joker"),
 		})
 	}
+	return deck
+}
 
-	rand.Shuffle(len(deck), func(i, j int) {
-		deck[i], deck[j] = deck[j], deck[i]
-	})
-	g.Deck = deck
+// cardValue returns the points a card counts for at the end of the game: face value, with jokers worth 0
+// and red kings -1.
+func cardValue(rank, suit string) int {
+	switch rank {
+	case "Joker":
+		return 0
+	case "A":
+		return 1
+	case "J":
+		return 11
+	case "Q":
+		return 12
+	case "K":
+		if suit == "Hearts" || suit == "Diamonds" {
+			return -1
+		}
+		return 13
+	}
+	n, _ := strconv.Atoi(rank)
+	return n
 }
 
 // Start sets up the game state: deal initial cards, start turn timers, etc.
 func (g *CambiaGame) Start() {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
+	g.Do(g.start)
+}
 
+func (g *CambiaGame) start() {
 	if g.Started || g.GameOver {
 		return
 	}
 	g.Started = true
+	g.StartedAt = time.Now()
+	// the deck was built before the house rules and players were set, so rebuild it to their composition,
+	// with a seed salted by the players
+	g.newShuffleSeed()
+	g.initializeDeck()
+	if len(g.Players) > 0 && !g.Practice {
+		go g.persistStart(g.gameRecord(nil, nil))
+	}
+	g.emitGameStart()
 
-	if g.HouseRules.TurnTimerSec > 0 {
-		g.TurnDuration = time.Duration(g.HouseRules.TurnTimerSec) * time.Second
-	} else {
-		g.TurnDuration = 0
+	g.clock = g.HouseRules.Clock()
+	g.TurnDuration = g.clock.Turn
+	for _, p := range g.Players {
+		g.timeBanks[p.ID] = g.clock.TimeBank
 	}
 
 	// deal 4 cards each
@@ -213,37 +416,16 @@ func (g *CambiaGame) Start() {
 			p.Hand = append(p.Hand, card)
 		}
 	}
+	g.saveCheckpoint()
+	g.commitShuffle()
 	g.scheduleNextTurnTimer()
 	g.broadcastPlayerTurn()
 }
 
-// drawTopStockpile draws the top card from the stockpile, re-shuffling discard if needed.
-// If broadcast is true, we send a "player_draw_stockpile" event, else skip that.
+// drawTopStockpile draws the top card from the stockpile, refilling it first if it ran out; see
+// refillStockpile. If broadcast is true, we send a "player_draw_stockpile" event, else skip that.
 func (g *CambiaGame) drawTopStockpile(broadcast bool) *models.Card {
-	if len(g.Deck) == 0 {
-		if len(g.DiscardPile) == 0 {
-			// no cards left => forced game end?
-			g.EndGame()
-			return nil
-		}
-
-		// reshuffle discard
-		g.Deck = append(g.Deck, g.DiscardPile...)
-		g.DiscardPile = []*models.Card{}
-		rand.Shuffle(len(g.Deck), func(i, j int) {
-			g.Deck[i], g.Deck[j] = g.Deck[j], g.Deck[i]
-		})
-
-		// broadcast reshuffle
-		g.fireEvent(GameEvent{
-			Type: EventReshuffle,
-			Other: map[string]interface{}{
-				"stockpileSize": len(g.Deck),
-			},
-		})
-	}
-
-	if len(g.Deck) == 0 {
+	if !g.refillStockpile() {
 		return nil
 	}
 
@@ -279,37 +461,39 @@ func (g *CambiaGame) drawTopDiscard() *models.Card {
 
 // scheduleNextTurnTimer restarts a turn timer for the current player if turnDuration > 0
 func (g *CambiaGame) scheduleNextTurnTimer() {
-	if g.TurnDuration == 0 {
-		return
-	}
-	if g.turnTimer != nil {
-		g.turnTimer.Stop()
-	}
-	curPID := g.Players[g.CurrentPlayerIndex].ID
-	g.turnTimer = time.AfterFunc(g.TurnDuration, func() {
-		g.Mu.Lock()
-		defer g.Mu.Unlock()
-		g.handleTimeout(curPID)
-	})
+	g.startClock(g.TurnDuration, clockPhaseTurn)
 }
 
 // handleTimeout forcibly draws & discards for the current player if they time out.
 func (g *CambiaGame) handleTimeout(playerID uuid.UUID) {
 	log.Printf("Player %v timed out. Force draw & discard.\n", playerID)
+	g.consecutiveTimeouts[playerID]++
 	// If there's a special action in progress for them, skip it
 	if g.SpecialAction.Active && g.SpecialAction.PlayerID == playerID {
 		log.Printf("Timeout skipping special action for player %v", playerID)
 		g.SpecialAction = SpecialActionState{}
 	}
 
-	// forcibly draw top of stock
-	card := g.drawTopStockpile(true)
+	// a card the player already drew is discarded; a card taken from the discard pile goes back on top
+	var card *models.Card
+	for _, p := range g.Players {
+		if p.ID == playerID && p.DrawnCard != nil {
+			card = p.DrawnCard
+			p.DrawnCard, p.DrawnFromDiscard = nil, false
+		}
+	}
+	if card == nil {
+		// forcibly draw top of stock
+		card = g.drawTopStockpile(true)
+		if card != nil {
+			g.fireEvent(GameEvent{
+				Type:   EventPrivateDrawStock,
+				UserID: playerID,
+				Card:   &models.Card{ID: card.ID, Rank: card.Rank, Suit: card.Suit, Value: card.Value},
+			})
+		}
+	}
 	if card != nil {
-		g.fireEvent(GameEvent{
-			Type:   EventPrivateDrawStock,
-			UserID: playerID,
-			Card:   &models.Card{ID: card.ID, Rank: card.Rank, Suit: card.Suit, Value: card.Value},
-		})
 		// discard immediately
 		g.DiscardPile = append(g.DiscardPile, card)
 		g.fireEvent(GameEvent{
@@ -323,18 +507,92 @@ func (g *CambiaGame) handleTimeout(playerID uuid.UUID) {
 
 // broadcastPlayerTurn notifies all players whose turn it is now.
 func (g *CambiaGame) broadcastPlayerTurn() {
-	currentPID := g.Players[g.CurrentPlayerIndex].ID
+	current := g.Players[g.CurrentPlayerIndex]
 	g.fireEvent(GameEvent{
 		Type:   EventPlayerTurn,
-		UserID: currentPID,
+		UserID: current.ID,
 	})
+	g.showSeatView(current)
+	g.hintLegalActions()
+	g.scheduleBotTurn()
 }
 
-// fireEvent is a helper that calls BroadcastFn if non-nil
+// fireEvent is a helper that calls BroadcastFn if non-nil. Private events go only to the player they're
+// about; see route. Public events are also relayed to spectators through SpectatorFn.
 func (g *CambiaGame) fireEvent(ev GameEvent) {
+	g.markStateChanged()
+	ev = g.route(ev)
 	if g.BroadcastFn != nil {
 		g.BroadcastFn(ev)
 	}
+	if ev.Private() {
+		return
+	}
+	g.logAction(ev)
+	g.spectate(ev)
+	g.publishSpectatorState()
+}
+
+// Announce sends a message from the server's operators to the game's players and spectators, e.g. a
+// maintenance warning. It isn't part of the game, so it isn't logged as an action. other is the event's
+// payload.
+func (g *CambiaGame) Announce(other map[string]interface{}) {
+	g.Do(func() {
+		if g.GameOver {
+			return
+		}
+		ev := GameEvent{Type: EventSystemAnnouncement, Other: other}
+		if g.BroadcastFn != nil {
+			g.BroadcastFn(ev)
+		}
+		if g.SpectatorFn != nil {
+			g.SpectatorFn(ev)
+		}
+	})
+}
+
+// SequenceFor numbers an event for delivery to a player and keeps it for Resync. BroadcastFn calls it once
+// per recipient, so each player sees an unbroken sequence. Callers must hold g.Mu.
+func (g *CambiaGame) SequenceFor(userID uuid.UUID, ev GameEvent) GameEvent {
+	el, ok := g.eventLogs[userID]
+	if !ok {
+		el = &EventLog[GameEvent]{}
+		g.eventLogs[userID] = el
+	}
+	return el.Record(func(seq uint64) GameEvent {
+		ev.Seq = seq
+		return ev
+	})
+}
+
+// Resync returns the events delivered to a player after seq, and the latest sequence number. complete is
+// false if some have aged out, in which case the player should start over from a snapshot.
+func (g *CambiaGame) Resync(userID uuid.UUID, seq uint64) (events []GameEvent, latest uint64, complete bool) {
+	g.Mu.Lock()
+	el, ok := g.eventLogs[userID]
+	g.Mu.Unlock()
+	if !ok {
+		return []GameEvent{}, 0, seq == 0
+	}
+	return el.Since(seq)
+}
+
+// logAction appends a public event to the game's action log. Turn changes and clock syncs aren't logged.
+func (g *CambiaGame) logAction(ev GameEvent) {
+	if ev.Type == EventPlayerTurn || ev.Type == EventClockSync {
+		return
+	}
+	payload := make(map[string]interface{}, len(ev.Other)+2)
+	for k, v := range ev.Other {
+		payload[k] = v
+	}
+	if ev.Card != nil {
+		payload["card"] = *ev.Card
+	}
+	if ev.Card2 != nil {
+		payload["card2"] = *ev.Card2
+	}
+	g.Actions = append(g.Actions, models.GameAction{ActionType: string(ev.Type), Payload: payload, ActorID: ev.UserID})
 }
 
 // Advance turn to next player
@@ -352,39 +610,55 @@ func (g *CambiaGame) advanceTurn() {
 			g.CambiaFinalCounter++
 			// If all others have played => end now
 			if g.CambiaFinalCounter >= len(g.Players)-1 {
-				g.EndGame()
+				g.endGame()
 				return
 			}
 		}
 	}
 
+	// under the end_round rule, the round ends with the turn that took the last card
+	if len(g.Deck) == 0 && g.HouseRules.StockpileEmpty == StockpileEndRound {
+		g.exhaustStockpile()
+		return
+	}
+
 	g.CurrentPlayerIndex = (g.CurrentPlayerIndex + 1) % len(g.Players)
+	g.TurnID++
+	g.saveCheckpoint()
 	g.scheduleNextTurnTimer()
 	g.broadcastPlayerTurn()
+	g.broadcastWinProbabilities()
 }
 
-// HandleDisconnect logic
-func (g *CambiaGame) HandleDisconnect(playerID uuid.UUID) {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
-	if g.HouseRules.ForfeitOnDisconnect {
-		g.markPlayerAsDisconnected(playerID)
-	} else {
-		g.lastSeen[playerID] = time.Now()
-	}
+// HandleDisconnect handles a player's connection closing. It's a no-op for a connection that was
+// superseded, since the player is still connected through its successor.
+func (g *CambiaGame) HandleDisconnect(playerID uuid.UUID, conn *websocket.Conn) {
+	g.Do(func() {
+		if !g.isActiveConnLocked(playerID, conn) {
+			return
+		}
+		g.offline[playerID] = true
+		if g.HouseRules.ForfeitOnDisconnect {
+			g.markPlayerAsDisconnected(playerID)
+		} else {
+			g.lastSeen[playerID] = time.Now()
+		}
+	})
 }
 
 // HandleReconnect sets the player as reconnected
 func (g *CambiaGame) HandleReconnect(playerID uuid.UUID) {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
-	g.lastSeen[playerID] = time.Now()
-	for i := range g.Players {
-		if g.Players[i].ID == playerID {
-			g.Players[i].Connected = true
-			break
+	g.Do(func() {
+		g.lastSeen[playerID] = time.Now()
+		delete(g.offline, playerID)
+		for i := range g.Players {
+			if g.Players[i].ID == playerID {
+				g.Players[i].Connected = true
+				g.markStateChanged()
+				break
+			}
 		}
-	}
+	})
 }
 
 // markPlayerAsDisconnected forcibly sets them as disconnected
@@ -392,6 +666,7 @@ func (g *CambiaGame) markPlayerAsDisconnected(playerID uuid.UUID) {
 	for i := range g.Players {
 		if g.Players[i].ID == playerID {
 			g.Players[i].Connected = false
+			g.markStateChanged()
 			break
 		}
 	}
@@ -413,21 +688,15 @@ func (g *CambiaGame) drawCardFromLocation(playerID uuid.UUID, location string) *
 	} else if location == "discardpile" && g.HouseRules.AllowDrawFromDiscardPile {
 		card := g.drawTopDiscard()
 		if card != nil {
-			// broadcast to all
+			// the discard pile is face up, so everyone sees the card
 			g.fireEvent(GameEvent{
-				Type:   EventPlayerDrawStock,
+				Type:   EventPlayerDrawDiscard,
 				UserID: playerID,
-				Card:   &models.Card{ID: card.ID},
+				Card:   &models.Card{ID: card.ID, Rank: card.Rank, Suit: card.Suit, Value: card.Value},
 				Other: map[string]interface{}{
 					"discardSize": len(g.DiscardPile),
 				},
 			})
-			// private reveal
-			g.fireEvent(GameEvent{
-				Type:   EventPrivateDrawStock,
-				UserID: playerID,
-				Card:   &models.Card{ID: card.ID, Rank: card.Rank, Suit: card.Suit, Value: card.Value},
-			})
 		}
 		return card
 	}
@@ -436,8 +705,11 @@ func (g *CambiaGame) drawCardFromLocation(playerID uuid.UUID, location string) *
 
 // HandlePlayerAction interprets draw, discard, snap, cambia, replace, etc.
 func (g *CambiaGame) HandlePlayerAction(playerID uuid.UUID, action models.GameAction) {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
+	g.Do(func() { g.handlePlayerAction(playerID, action) })
+}
+
+func (g *CambiaGame) handlePlayerAction(playerID uuid.UUID, action models.GameAction) {
+	defer g.HaltOnPanic("player action")
 
 	if g.GameOver {
 		return
@@ -448,13 +720,14 @@ func (g *CambiaGame) HandlePlayerAction(playerID uuid.UUID, action models.GameAc
 	if action.ActionType != "action_snap" && playerID != currentPID {
 		return
 	}
+	delete(g.consecutiveTimeouts, playerID)
 
 	switch action.ActionType {
 	case "action_snap":
 		g.handleSnap(playerID, action.Payload)
 	case "action_draw_stockpile":
 		g.handleDrawFrom(playerID, "stockpile")
-	case "action_draw_discard":
+	case "action_draw_discardpile", "action_draw_discard":
 		g.handleDrawFrom(playerID, "discardpile")
 	case "action_discard":
 		g.handleDiscard(playerID, action.Payload)
@@ -474,6 +747,12 @@ func (g *CambiaGame) handleDrawFrom(playerID uuid.UUID, location string) {
 		log.Printf("Player %v tried to draw while special in progress.\n", playerID)
 		return
 	}
+	// one card at a time: a second draw would drop the first
+	for _, p := range g.Players {
+		if p.ID == playerID && p.DrawnCard != nil {
+			return
+		}
+	}
 	card := g.drawCardFromLocation(playerID, location)
 	if card == nil {
 		// invalid draw location i.e. deck or card is empty/nil
@@ -483,9 +762,11 @@ func (g *CambiaGame) handleDrawFrom(playerID uuid.UUID, location string) {
 	for i := range g.Players {
 		if g.Players[i].ID == playerID {
 			g.Players[i].DrawnCard = card
+			g.Players[i].DrawnFromDiscard = location == "discardpile"
 			break
 		}
 	}
+	g.hintLegalActions()
 }
 
 // handleDiscard discards the drawnCard or a card from the player's hand
@@ -509,6 +790,10 @@ func (g *CambiaGame) handleDiscard(playerID uuid.UUID, payload map[string]interf
 		if g.Players[i].ID == playerID {
 			p := g.Players[i]
 			if p.DrawnCard != nil && p.DrawnCard.ID == cardID {
+				if p.DrawnFromDiscard {
+					// a card taken from the discard pile has to replace one in hand
+					return
+				}
 				discarded = p.DrawnCard
 				p.DrawnCard = nil
 				break
@@ -550,14 +835,15 @@ func (g *CambiaGame) handleReplace(playerID uuid.UUID, payload map[string]interf
 	idx := int(idxFloat)
 	var replaced *models.Card
 	var fresh *models.Card
+	fromDiscard := false
 	for i := range g.Players {
 		if g.Players[i].ID == playerID {
 			p := g.Players[i]
-			if p.DrawnCard != nil {
-				fresh = p.DrawnCard
+			// the drawn card stays drawn if there's no card at idx to replace
+			if p.DrawnCard != nil && idx >= 0 && idx < len(p.Hand) {
+				fresh, fromDiscard = p.DrawnCard, p.DrawnFromDiscard
 				p.DrawnCard = nil
-			}
-			if idx >= 0 && idx < len(p.Hand) && fresh != nil {
+				p.DrawnFromDiscard = false
 				replaced = p.Hand[idx]
 				p.Hand[idx] = fresh
 			}
@@ -569,10 +855,15 @@ func (g *CambiaGame) handleReplace(playerID uuid.UUID, payload map[string]interf
 	}
 	// replaced card goes to discard pile
 	g.DiscardPile = append(g.DiscardPile, replaced)
+	// the card going into the hand is face down, unless everyone saw it on the discard pile
+	shown := &models.Card{ID: fresh.ID}
+	if fromDiscard {
+		shown = &models.Card{ID: fresh.ID, Rank: fresh.Rank, Suit: fresh.Suit, Value: fresh.Value}
+	}
 	g.fireEvent(GameEvent{
 		Type:   EventPlayerReplace,
 		UserID: playerID,
-		Card:   &models.Card{ID: fresh.ID, Rank: fresh.Rank, Suit: fresh.Suit, Value: fresh.Value},
+		Card:   shown,
 		Other: map[string]interface{}{
 			"replaceIdx": idx,
 		},
@@ -604,13 +895,16 @@ func (g *CambiaGame) handleSnap(playerID uuid.UUID, payload map[string]interface
 	}
 	lastDiscard := g.DiscardPile[len(g.DiscardPile)-1]
 	var snapCard *models.Card
+	var owner uuid.UUID
 
 playerloop:
 	for i := range g.Players {
-		if g.Players[i].ID == playerID {
+		// with the snapPartner rule, a player may snap from their partner's hand as well as their own
+		if g.Players[i].ID == playerID || g.HouseRules.SnapPartner && g.partners(playerID, g.Players[i].ID) {
 			for h := 0; h < len(g.Players[i].Hand); h++ {
 				if g.Players[i].Hand[h].ID == cardID {
 					snapCard = g.Players[i].Hand[h]
+					owner = g.Players[i].ID
 					break playerloop
 				}
 			}
@@ -622,13 +916,18 @@ playerloop:
 	}
 	if snapCard.Rank == lastDiscard.Rank {
 		log.Printf("Player %v snap success with rank %s", playerID, snapCard.Rank)
-		g.removeCardFromPlayerHand(playerID, cardID)
+		g.checkSnap(playerID, snapCard)
+		g.removeCardFromPlayerHand(owner, cardID)
 		g.DiscardPile = append(g.DiscardPile, snapCard)
-		g.fireEvent(GameEvent{
+		ev := GameEvent{
 			Type:   EventSnapSuccess,
 			UserID: playerID,
 			Card:   &models.Card{ID: snapCard.ID, Rank: snapCard.Rank, Suit: snapCard.Suit, Value: snapCard.Value},
-		})
+		}
+		if owner != playerID {
+			ev.Other = map[string]interface{}{"owner": owner}
+		}
+		g.fireEvent(ev)
 	} else {
 		g.penalizeSnapFail(playerID, snapCard)
 	}
@@ -693,6 +992,7 @@ func (g *CambiaGame) handleCallCambia(playerID uuid.UUID) {
 		g.CambiaCalled = true
 		g.CambiaCallerID = playerID
 		g.CambiaFinalCounter = 0
+		g.cambiaCallerSeen = g.seenInHand(playerID)
 	}
 	// we forcibly end the caller's turn, so next player gets a turn
 	g.advanceTurn()
@@ -718,6 +1018,7 @@ func (g *CambiaGame) applySpecialAbilityIfFreshlyDrawn(c *models.Card, playerID
 			Card:   &models.Card{ID: c.ID, Rank: c.Rank},
 			Other:  map[string]interface{}{"special": rankToSpecial(c.Rank)},
 		})
+		g.hintLegalActions()
 	} else {
 		// no special
 		g.advanceTurn()
@@ -747,36 +1048,38 @@ func rankToSpecial(rank string) string {
 	}
 }
 
-// resetTurnTimer resets the turn timer to the full length
+// resetTurnTimer restarts the current player's timer for the next step of a card ability.
 func (g *CambiaGame) resetTurnTimer() {
-	if g.turnTimer != nil {
-		g.turnTimer.Stop()
-		g.turnTimer = nil
-	}
-	if g.TurnDuration > 0 {
-		curPID := g.Players[g.CurrentPlayerIndex].ID
-		g.turnTimer = time.AfterFunc(g.TurnDuration, func() {
-			g.Mu.Lock()
-			defer g.Mu.Unlock()
-			g.handleTimeout(curPID)
-		})
+	d := g.clock.Ability
+	if d == 0 {
+		d = g.TurnDuration
 	}
+	g.startClock(d, clockPhaseAbility)
 }
 
 // EndGame finalizes scoring, sets GameOver, and calls OnGameEnd if present.
+// It runs on the game's loop; use endGame from code already running there.
 func (g *CambiaGame) EndGame() {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
+	g.Do(g.endGame)
+}
 
+// endGame is the lock-free body of EndGame.
+func (g *CambiaGame) endGame() {
 	if g.GameOver {
 		return
 	}
 	g.GameOver = true
+	g.stopClock()
 	log.Printf("Ending game %v, computing final scores...", g.ID)
 
 	finalScores := g.computeScores()
 	winners := g.findWinnersWithCambiaTiebreak(finalScores)
 
+	g.fireRoundReveal(finalScores, winners)
+	g.fireShuffleReveal()
+	g.checkCambia(winners)
+	g.emitGameEnd(winners)
+
 	var firstWinner uuid.UUID
 	if len(winners) > 0 {
 		firstWinner = winners[0]
@@ -784,6 +1087,50 @@ func (g *CambiaGame) EndGame() {
 	if g.OnGameEnd != nil {
 		g.OnGameEnd(g.LobbyID, firstWinner, finalScores)
 	}
+	if g.Ranked && g.OnAbandon != nil {
+		for _, pid := range g.abandoners() {
+			g.OnAbandon(g.ID, pid)
+		}
+	}
+	if len(g.Players) > 0 && !g.Practice {
+		go g.persistResults(g.gameRecord(finalScores, winners))
+	}
+}
+
+// gameRecord captures the final outcome for persistence. Hands are copied so the record can be written
+// without holding g.Mu. Callers must hold g.Mu.
+func (g *CambiaGame) gameRecord(finalScores map[uuid.UUID]int, winners []uuid.UUID) database.GameRecord {
+	players := make([]*models.Player, 0, len(g.Players))
+	for _, p := range g.Players {
+		players = append(players, &models.Player{ID: p.ID, Hand: append([]*models.Card(nil), p.Hand...)})
+	}
+	return database.GameRecord{
+		GameID:         g.ID,
+		EngineVersion:  g.EngineVersion,
+		RulesRevision:  g.RulesRevision,
+		Ranked:         g.Ranked,
+		RoundIndex:     g.CircuitRound,
+		StartedAt:      g.StartedAt,
+		EndedAt:        time.Now(),
+		Players:        players,
+		FinalScores:    finalScores,
+		Winners:        winners,
+		CambiaCallerID: g.CambiaCallerID,
+		Actions:        append([]models.GameAction(nil), g.Actions...),
+	}
+}
+
+// abandoners lists players who were disconnected when the game ended, or who let AutoKickTurnCount
+// turns in a row time out.
+func (g *CambiaGame) abandoners() []uuid.UUID {
+	var out []uuid.UUID
+	for _, p := range g.Players {
+		kicked := g.HouseRules.AutoKickTurnCount > 0 && g.consecutiveTimeouts[p.ID] >= g.HouseRules.AutoKickTurnCount
+		if !p.Connected || kicked {
+			out = append(out, p.ID)
+		}
+	}
+	return out
 }
 
 // computeScores calculates each player's sum of hand
@@ -800,8 +1147,11 @@ func (g *CambiaGame) computeScores() map[uuid.UUID]int {
 }
 
 // findWinnersWithCambiaTiebreak is a custom function that returns either the Cambia caller if they tie for best
-// or else returns all players who share the best score.
+// or else returns all players who share the best score. Team games are won by teams; see findTeamWinners.
 func (g *CambiaGame) findWinnersWithCambiaTiebreak(scores map[uuid.UUID]int) []uuid.UUID {
+	if g.Teams != nil {
+		return g.findTeamWinners(scores)
+	}
 	var best int
 	first := true
 	for _, s := range scores {
@@ -829,12 +1179,26 @@ func (g *CambiaGame) findWinnersWithCambiaTiebreak(scores map[uuid.UUID]int) []u
 	return tied
 }
 
-// persistResults is called optionally to store game results in DB
-func (g *CambiaGame) persistResults(finalScores map[uuid.UUID]int, winners []uuid.UUID) {
+// persistStart records the game as in progress, so a restart that cuts it off leaves it marked abandoned
+// rather than missing from the DB.
+func (g *CambiaGame) persistStart(rec database.GameRecord) {
+	defer crash.Recover("recording game start")
+	if err := database.Games.RecordGameStart(context.Background(), rec); err != nil {
+		log.Printf("Error recording start of game %v: %v", rec.GameID, err)
+	}
+}
+
+// persistResults stores the game's results, round breakdown, and rating changes in the DB.
+func (g *CambiaGame) persistResults(rec database.GameRecord) {
+	defer crash.Recover("recording game results")
 	ctx := context.Background()
-	err := database.RecordGameAndResults(ctx, g.ID, g.Players, finalScores, winners)
+	err := database.Games.RecordGameAndResults(ctx, rec)
 	if err != nil {
 		log.Printf("Error persisting results: %v", err)
+		return
+	}
+	if g.OnRecorded != nil {
+		g.OnRecorded(rec)
 	}
 }
 
@@ -906,11 +1270,9 @@ func (g *CambiaGame) FireEventPlayerSpecialAction(userID uuid.UUID, special stri
 	g.fireEvent(ev)
 }
 
-// AdvanceTurn calls the CambiaadvanceTurn exported
+// AdvanceTurn calls the CambiaadvanceTurn exported. It must be called from a command on the game's loop.
 func (g *CambiaGame) AdvanceTurn() {
-	g.Mu.Unlock() // we are inside a locked section, so we must unlock, call the method, and re-lock
 	g.advanceTurn()
-	g.Mu.Lock()
 }
 
 // ResetTurnTimer calls the resetTurnTimer
@@ -0,0 +1,24 @@
+package game
+
+import "fmt"
+
+// SendChat sends a chat message or emote from a player to the game's players and spectators, except those
+// in ev.Hidden. Like Announce, it isn't logged as an action, but spectators get it after SpectatorDelay.
+func (g *CambiaGame) SendChat(ev GameEvent) (err error) {
+	g.Do(func() { err = g.sendChat(ev) })
+	return err
+}
+
+func (g *CambiaGame) sendChat(ev GameEvent) error {
+	if g.GameOver {
+		return fmt.Errorf("the game is over")
+	}
+	if ev.Type == EventChat && g.ChatDisabled {
+		return fmt.Errorf("chat is disabled in this game")
+	}
+	if g.BroadcastFn != nil {
+		g.BroadcastFn(ev)
+	}
+	g.spectate(ev)
+	return nil
+}
@@ -6,45 +6,98 @@ import (
 	"github.com/google/uuid"
 )
 
+// gameStoreShards is how many independently locked maps a GameStore splits its games across, so lookups
+// for different games rarely wait on each other.
+const gameStoreShards = 32
+
+// GameStore holds the games in memory, sharded by game ID.
 type GameStore struct {
-	mu    sync.Mutex
+	shards [gameStoreShards]gameShard
+}
+
+type gameShard struct {
+	mu    sync.RWMutex
 	games map[uuid.UUID]*CambiaGame
 }
 
 func NewGameStore() *GameStore {
-	return &GameStore{
-		games: make(map[uuid.UUID]*CambiaGame),
+	s := &GameStore{}
+	for i := range s.shards {
+		s.shards[i].games = make(map[uuid.UUID]*CambiaGame)
 	}
+	return s
+}
+
+// shard returns the shard holding a game. Game IDs are UUIDv7s, whose leading bytes are a timestamp, so
+// the shard is picked by the trailing random byte.
+func (s *GameStore) shard(id uuid.UUID) *gameShard {
+	return &s.shards[int(id[len(id)-1])%gameStoreShards]
 }
 
 func (s *GameStore) AddGame(game *CambiaGame) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.games[game.ID] = game
+	sh := s.shard(game.ID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.games[game.ID] = game
 }
 
 func (s *GameStore) GetGame(id uuid.UUID) (*CambiaGame, bool) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	g, exists := s.games[id]
+	sh := s.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	g, exists := sh.games[id]
 	return g, exists
 }
 
 func (s *GameStore) DeleteGame(id uuid.UUID) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.games, id)
+	sh := s.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	delete(sh.games, id)
 }
 
 // GetGameByLobbyID returns a game that references a given lobby ID, or nil if none is found
 // This requires that each CambiaGame store a LobbyID.
-func (store *GameStore) GetGameByLobbyID(lobbyID uuid.UUID) *CambiaGame {
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	for _, g := range store.games {
-		if g.LobbyID == lobbyID {
-			return g
+func (s *GameStore) GetGameByLobbyID(lobbyID uuid.UUID) *CambiaGame {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for _, g := range sh.games {
+			if g.LobbyID == lobbyID {
+				sh.mu.RUnlock()
+				return g
+			}
 		}
+		sh.mu.RUnlock()
 	}
 	return nil
 }
+
+// GetGames returns every game in memory, finished or not. Shards are visited one at a time, so a game added
+// or deleted meanwhile may or may not be included.
+func (s *GameStore) GetGames() []*CambiaGame {
+	var games []*CambiaGame
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for _, g := range sh.games {
+			games = append(games, g)
+		}
+		sh.mu.RUnlock()
+	}
+	if games == nil {
+		games = []*CambiaGame{}
+	}
+	return games
+}
+
+// LiveGames counts the unfinished games userID is seated at.
+func (s *GameStore) LiveGames(userID uuid.UUID) int {
+	n := 0
+	for _, g := range s.GetGames() {
+		if g.IsLivePlayer(userID) {
+			n++
+		}
+	}
+	return n
+}
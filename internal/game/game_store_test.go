@@ -0,0 +1,63 @@
+package game
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestGameStoreConcurrentAccess(t *testing.T) {
+	s := NewGameStore()
+	var wg sync.WaitGroup
+	games := make([]*CambiaGame, 100)
+	for i := range games {
+		games[i] = &CambiaGame{ID: uuid.Must(uuid.NewV7()), LobbyID: uuid.New()}
+		wg.Add(1)
+		go func(g *CambiaGame) {
+			defer wg.Done()
+			s.AddGame(g)
+			if got, ok := s.GetGame(g.ID); !ok || got != g {
+				t.Errorf("expected to find game %v", g.ID)
+			}
+		}(games[i])
+	}
+	wg.Wait()
+
+	if n := len(s.GetGames()); n != len(games) {
+		t.Fatalf("expected %d games, got %d", len(games), n)
+	}
+	if g := s.GetGameByLobbyID(games[42].LobbyID); g != games[42] {
+		t.Fatalf("expected the game of lobby %v, got %v", games[42].LobbyID, g)
+	}
+	s.DeleteGame(games[42].ID)
+	if _, ok := s.GetGame(games[42].ID); ok {
+		t.Fatal("expected the deleted game to be gone")
+	}
+	if g := s.GetGameByLobbyID(games[42].LobbyID); g != nil {
+		t.Fatalf("expected no game for a deleted game's lobby, got %v", g.ID)
+	}
+}
+
+func TestGameStoreLiveGames(t *testing.T) {
+	s := NewGameStore()
+	userID := uuid.New()
+	seated := func(over bool) *CambiaGame {
+		g := NewCambiaGame()
+		g.Players = []*models.Player{{ID: userID}, {ID: uuid.New()}}
+		g.GameOver = over
+		s.AddGame(g)
+		return g
+	}
+	seated(false)
+	seated(true)
+	s.AddGame(NewCambiaGame())
+
+	if n := s.LiveGames(userID); n != 1 {
+		t.Fatalf("expected 1 live game, got %d", n)
+	}
+	if n := s.LiveGames(uuid.New()); n != 0 {
+		t.Fatalf("expected no live games for a user who isn't seated, got %d", n)
+	}
+}
@@ -0,0 +1,112 @@
+package game
+
+import (
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Hot-seat play. Several players at one device share a single game connection: the user who opened it
+// plays their own seat, and local seats for the others sit at the same table with no connection of their
+// own. Actions name the seat they're for. Since everyone at the device sees the screen, a seat's private
+// events are delivered only while it's the active seat, i.e. the one whose turn it is; a seat coming up
+// to play gets its own view first, with EventPrivateSeatView.
+
+// EventPrivateSeatView gives the shared connection a local seat's view when its turn starts: the faces of
+// the cards in play it has seen, as in PlayerGameView.
+const EventPrivateSeatView GameEventType = "private_seat_view"
+
+// NewLocalSeat returns a seat played over controller's connection.
+func NewLocalSeat(controller uuid.UUID) (*models.Player, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Player{ID: id, Hand: []*models.Card{}, Connected: true, Controller: controller}, nil
+}
+
+// controller returns the player whose connection plays p: p itself, unless it's a local seat. Callers must
+// hold g.Mu.
+func (g *CambiaGame) controller(p *models.Player) *models.Player {
+	if p.Controller == uuid.Nil {
+		return p
+	}
+	for _, pl := range g.Players {
+		if pl.ID == p.Controller {
+			return pl
+		}
+	}
+	return p
+}
+
+// sharesConnection reports whether p plays over a connection shared with other seats. Callers must hold
+// g.Mu.
+func (g *CambiaGame) sharesConnection(p *models.Player) bool {
+	if p.Controller != uuid.Nil {
+		return true
+	}
+	for _, pl := range g.Players {
+		if pl.Controller == p.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// Controls reports whether userID's connection plays seatID: it's their own seat, or one of their local
+// seats.
+func (g *CambiaGame) Controls(userID, seatID uuid.UUID) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	for _, p := range g.Players {
+		if p.ID == seatID {
+			return p.ID == userID || p.Controller == userID
+		}
+	}
+	return false
+}
+
+// LocalSeats returns the seats played over userID's connection besides their own, in seat order.
+func (g *CambiaGame) LocalSeats(userID uuid.UUID) []uuid.UUID {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	var seats []uuid.UUID
+	for _, p := range g.Players {
+		if p.Controller == userID {
+			seats = append(seats, p.ID)
+		}
+	}
+	return seats
+}
+
+// routeShared redirects a private event about a seat on a shared connection: it goes to the connection
+// if the seat is active, and to no one otherwise. Callers must hold g.Mu.
+func (g *CambiaGame) routeShared(ev GameEvent) GameEvent {
+	var subject *models.Player
+	for _, p := range g.Players {
+		if p.ID == ev.UserID {
+			subject = p
+		}
+	}
+	if subject == nil || !g.sharesConnection(subject) {
+		return ev
+	}
+	ctrl := g.controller(subject)
+	if len(g.Players) > 0 && g.Players[g.CurrentPlayerIndex].ID == subject.ID {
+		delete(ev.Hidden, ctrl.ID)
+	} else {
+		ev.Hidden[ctrl.ID] = true
+	}
+	return ev
+}
+
+// showSeatView sends a seat on a shared connection its view as its turn starts. Callers must hold g.Mu.
+func (g *CambiaGame) showSeatView(p *models.Player) {
+	if !g.sharesConnection(p) {
+		return
+	}
+	g.fireEvent(GameEvent{
+		Type:   EventPrivateSeatView,
+		UserID: p.ID,
+		Other:  map[string]interface{}{"known": g.playerView(p.ID).Known},
+	})
+}
@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestHotSeatRouting(t *testing.T) {
+	g := NewCambiaGame()
+	owner := &models.Player{ID: uuid.New(), Hand: []*models.Card{}, Connected: true}
+	local, err := NewLocalSeat(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Players = []*models.Player{owner, local}
+
+	if !g.Controls(owner.ID, local.ID) || g.Controls(local.ID, owner.ID) {
+		t.Fatal("expected the owner's connection, and only theirs, to play the local seat")
+	}
+	if seats := g.LocalSeats(owner.ID); len(seats) != 1 || seats[0] != local.ID {
+		t.Fatalf("expected the local seat, got %v", seats)
+	}
+
+	var delivered []GameEvent
+	g.BroadcastFn = func(ev GameEvent) {
+		if !ev.Hidden[owner.ID] {
+			delivered = append(delivered, ev)
+		}
+	}
+	draw := func(p *models.Player) {
+		g.fireEvent(GameEvent{Type: EventPrivateDrawStock, UserID: p.ID, Card: &models.Card{ID: uuid.New(), Rank: "5", Suit: "S", Value: 5}})
+	}
+
+	// the owner's seat is active: the local seat's private events are held back
+	draw(owner)
+	draw(local)
+	if len(delivered) != 1 || delivered[0].UserID != owner.ID {
+		t.Fatalf("expected only the active seat's draw to be delivered, got %+v", delivered)
+	}
+
+	// the local seat's turn: it's shown what it has seen, and its private events get through
+	delivered = nil
+	g.Do(func() {
+		g.CurrentPlayerIndex = 1
+		g.broadcastPlayerTurn()
+	})
+	draw(owner)
+	draw(local)
+	var types []GameEventType
+	for _, ev := range delivered {
+		types = append(types, ev.Type)
+	}
+	if len(delivered) != 3 || delivered[1].Type != EventPrivateSeatView || delivered[2].UserID != local.ID {
+		t.Fatalf("expected the turn, the local seat's view and its draw, got %v", types)
+	}
+	if known := delivered[1].Other["known"].([]*models.Card); len(known) != 0 {
+		t.Fatalf("expected the local seat to have seen no cards in hands, got %v", known)
+	}
+}
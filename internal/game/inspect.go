@@ -0,0 +1,94 @@
+package game
+
+import (
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+// GameSummary is an operator's overview of a live game.
+type GameSummary struct {
+	ID            uuid.UUID           `json:"id"`
+	LobbyID       uuid.UUID           `json:"lobbyID"`
+	Ranked        bool                `json:"ranked"`
+	Started       bool                `json:"started"`
+	StartedAt     time.Time           `json:"startedAt"`
+	GameOver      bool                `json:"gameOver"`
+	TurnID        int                 `json:"turn"`
+	CurrentPlayer uuid.UUID           `json:"currentPlayer"`
+	Players       []GameSummaryPlayer `json:"players"`
+	Spectators    int                 `json:"spectators"`
+}
+
+// GameSummaryPlayer is a player's part of a GameSummary.
+type GameSummaryPlayer struct {
+	ID        uuid.UUID `json:"id"`
+	Connected bool      `json:"connected"`
+}
+
+// Summary returns an overview of the game for operators.
+func (g *CambiaGame) Summary() GameSummary {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	s := GameSummary{
+		ID:         g.ID,
+		LobbyID:    g.LobbyID,
+		Ranked:     g.Ranked,
+		Started:    g.Started,
+		StartedAt:  g.StartedAt,
+		GameOver:   g.GameOver,
+		TurnID:     g.TurnID,
+		Players:    make([]GameSummaryPlayer, 0, len(g.Players)),
+		Spectators: g.SpectatorCount(),
+	}
+	if len(g.Players) > 0 {
+		s.CurrentPlayer = g.Players[g.CurrentPlayerIndex].ID
+	}
+	for _, p := range g.Players {
+		s.Players = append(s.Players, GameSummaryPlayer{ID: p.ID, Connected: p.Connected})
+	}
+	return s
+}
+
+// IsLivePlayer reports whether userID is seated at the game and it isn't over.
+func (g *CambiaGame) IsLivePlayer(userID uuid.UUID) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.GameOver {
+		return false
+	}
+	for _, p := range g.Players {
+		if p.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns the game's full state, hidden cards included, for operators debugging a game.
+func (g *CambiaGame) State() Checkpoint {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	return g.checkpoint()
+}
+
+// Disconnect closes the user's player and spectator sockets to the game, if any, and reports whether
+// there were any. A player can rejoin through /game/ws as after any other disconnect.
+func (g *CambiaGame) Disconnect(userID uuid.UUID) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	var conns []*websocket.Conn
+	for _, p := range g.Players {
+		if p.ID == userID && p.Conn != nil {
+			conns = append(conns, p.Conn)
+		}
+	}
+	if conn, ok := g.SpectatorConns()[userID]; ok {
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		go conn.Close(websocket.StatusPolicyViolation, "disconnected by the server")
+	}
+	return len(conns) > 0
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventLatency tells the table how a player's connection is doing, as a coarse indicator, whenever it
+// changes. Spectators don't get it, and it isn't logged as an action.
+const EventLatency GameEventType = "game_latency"
+
+// The latency indicators, by smoothed round-trip time; see latencyLevel.
+const (
+	LatencyGood = "good"
+	LatencyFair = "fair"
+	LatencyPoor = "poor"
+)
+
+// latencyFairRTT and latencyPoorRTT are the round-trip times at which a connection stops being good and
+// fair.
+const (
+	latencyFairRTT = 150 * time.Millisecond
+	latencyPoorRTT = 400 * time.Millisecond
+)
+
+// playerLatency is a player's smoothed round-trip time and the indicator last sent for it.
+type playerLatency struct {
+	rtt   time.Duration
+	level string
+}
+
+// latencyLevel returns the indicator for a round-trip time.
+func latencyLevel(rtt time.Duration) string {
+	switch {
+	case rtt < latencyFairRTT:
+		return LatencyGood
+	case rtt < latencyPoorRTT:
+		return LatencyFair
+	default:
+		return LatencyPoor
+	}
+}
+
+// RecordLatency folds a round-trip time measured on playerID's connection into their smoothed one, like
+// TCP's SRTT, so one slow ping doesn't flip their indicator. If the indicator changes, the table is told.
+func (g *CambiaGame) RecordLatency(playerID uuid.UUID, rtt time.Duration) {
+	g.Do(func() { g.recordLatency(playerID, rtt) })
+}
+
+func (g *CambiaGame) recordLatency(playerID uuid.UUID, rtt time.Duration) {
+	if g.latency == nil {
+		g.latency = make(map[uuid.UUID]*playerLatency)
+	}
+	l, ok := g.latency[playerID]
+	if !ok {
+		l = &playerLatency{rtt: rtt}
+		g.latency[playerID] = l
+	} else {
+		l.rtt += (rtt - l.rtt) / 8
+	}
+	level := latencyLevel(l.rtt)
+	if level == l.level {
+		return
+	}
+	l.level = level
+	if g.GameOver || g.BroadcastFn == nil {
+		return
+	}
+	g.BroadcastFn(GameEvent{
+		Type:   EventLatency,
+		UserID: playerID,
+		Other:  map[string]interface{}{"latency": level},
+	})
+}
+
+// Latency returns every player's latency indicator, for those measured so far.
+func (g *CambiaGame) Latency() map[uuid.UUID]string {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	out := make(map[uuid.UUID]string, len(g.latency))
+	for id, l := range g.latency {
+		out[id] = l.level
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRecordLatency(t *testing.T) {
+	g := NewCambiaGame()
+	var sent []string
+	g.BroadcastFn = func(ev GameEvent) {
+		if ev.Type == EventLatency {
+			sent = append(sent, ev.Other["latency"].(string))
+		}
+	}
+	id := uuid.New()
+
+	g.RecordLatency(id, 50*time.Millisecond)
+	g.RecordLatency(id, 60*time.Millisecond)
+	if len(sent) != 1 || sent[0] != LatencyGood {
+		t.Fatalf("expected a single good indicator, got %v", sent)
+	}
+
+	// one slow ping is smoothed over
+	g.RecordLatency(id, 500*time.Millisecond)
+	if len(sent) != 1 {
+		t.Fatalf("expected one slow ping not to change the indicator, got %v", sent)
+	}
+	for i := 0; i < 15; i++ {
+		g.RecordLatency(id, 500*time.Millisecond)
+	}
+	if last := sent[len(sent)-1]; last != LatencyPoor {
+		t.Fatalf("expected a run of slow pings to end up poor, got %v", sent)
+	}
+	if g.Latency()[id] != LatencyPoor {
+		t.Fatalf("expected the poor indicator to be kept, got %v", g.Latency())
+	}
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// EventLegalActions tells the current player what they may do next. It's only sent to clients that declared
+// CapLegalActions; see hintLegalActions.
+const EventLegalActions GameEventType = "legal_actions"
+
+// hintLegalActions sends the current player the actions open to them, if their client asked for hints. Like
+// Announce, it isn't logged as an action, and spectators don't get it.
+func (g *CambiaGame) hintLegalActions() {
+	if g.GameOver || g.BroadcastFn == nil || len(g.Players) == 0 {
+		return
+	}
+	p := g.Players[g.CurrentPlayerIndex]
+	// a local seat's hints go to the connection playing it
+	recipient := g.controller(p)
+	if !recipient.LegalActionHints {
+		return
+	}
+	hidden := make(map[uuid.UUID]bool, len(g.Players)-1)
+	for _, pl := range g.Players {
+		if pl.ID != recipient.ID {
+			hidden[pl.ID] = true
+		}
+	}
+	g.BroadcastFn(GameEvent{
+		Type:   EventLegalActions,
+		UserID: p.ID,
+		Other:  g.legalActions(p),
+		Hidden: hidden,
+	})
+}
+
+// HintLegalActions calls hintLegalActions, for callers already holding g.Mu, e.g. after a King's first step.
+func (g *CambiaGame) HintLegalActions() {
+	g.hintLegalActions()
+}
+
+// legalActions lists what p may do now: the action types they may send and, while one of their card
+// abilities is pending, its steps and the cards those may target, by owner. Snapping is listed whenever
+// there's a discard to snap, with its rank.
+func (g *CambiaGame) legalActions(p *models.Player) map[string]interface{} {
+	out := map[string]interface{}{"turn": g.TurnID}
+	actions := []string{}
+	switch {
+	case g.SpecialAction.Active && g.SpecialAction.PlayerID == p.ID:
+		steps, targets := g.abilitySteps(p.ID)
+		out["special"] = rankToSpecial(g.SpecialAction.CardRank)
+		out["steps"] = steps
+		if len(targets) > 0 {
+			out["targets"] = targets
+		}
+	case p.DrawnCard != nil:
+		// a card taken from the discard pile has to replace one in hand
+		if !p.DrawnFromDiscard {
+			actions = append(actions, "action_discard")
+		}
+		actions = append(actions, "action_replace")
+	default:
+		actions = append(actions, "action_draw_stockpile")
+		if g.HouseRules.AllowDrawFromDiscardPile && len(g.DiscardPile) > 0 {
+			actions = append(actions, "action_draw_discardpile")
+		}
+		if !g.CambiaCalled {
+			actions = append(actions, "action_cambia")
+		}
+	}
+	if len(g.DiscardPile) > 0 {
+		actions = append(actions, "action_snap")
+		out["snapRank"] = g.DiscardPile[len(g.DiscardPile)-1].Rank
+	}
+	out["actions"] = actions
+	return out
+}
+
+// abilitySteps returns the action_special steps open to the player with the pending ability, and the cards
+// they may pick, by owner. A peek_other only needs the owner; it always shows their first card.
+func (g *CambiaGame) abilitySteps(playerID uuid.UUID) (steps []string, targets map[uuid.UUID][]uuid.UUID) {
+	sa := g.SpecialAction
+	// the Cambia caller's hand is locked against swaps, though a King may still peek at it
+	swappable := func(owner uuid.UUID) bool {
+		return !g.CambiaCalled || owner != g.CambiaCallerID
+	}
+	hands := func(include func(owner uuid.UUID) bool) map[uuid.UUID][]uuid.UUID {
+		targets := map[uuid.UUID][]uuid.UUID{}
+		for _, pl := range g.Players {
+			if !include(pl.ID) {
+				continue
+			}
+			for _, c := range pl.Hand {
+				targets[pl.ID] = append(targets[pl.ID], c.ID)
+			}
+		}
+		return targets
+	}
+
+	switch sa.CardRank {
+	case "7", "8":
+		return []string{"peek_self", "skip"}, nil
+	case "9", "10":
+		return []string{"peek_other", "skip"}, hands(func(owner uuid.UUID) bool { return owner != playerID })
+	case "Q", "J":
+		return []string{"swap_blind", "skip"}, hands(swappable)
+	case "K":
+		if !sa.FirstStepDone {
+			return []string{"swap_peek", "skip"}, hands(func(uuid.UUID) bool { return true })
+		}
+		if swappable(sa.Card1Owner) && swappable(sa.Card2Owner) {
+			return []string{"swap_peek_swap", "skip"}, nil
+		}
+	}
+	return []string{"skip"}, nil
+}
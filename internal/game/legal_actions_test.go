@@ -0,0 +1,53 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestLegalActionHints(t *testing.T) {
+	g := NewCambiaGame()
+	g.HouseRules.AllowDrawFromDiscardPile = true
+	caller := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "4", Suit: "D", Value: 4}}}
+	p := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "2", Suit: "S", Value: 2}}, LegalActionHints: true}
+	g.Players = []*models.Player{caller, p}
+	g.CurrentPlayerIndex = 1
+	g.CambiaCalled, g.CambiaCallerID = true, caller.ID
+	jack := &models.Card{ID: uuid.New(), Rank: "J", Suit: "H", Value: 11}
+	g.Deck = []*models.Card{jack}
+	g.DiscardPile = []*models.Card{{ID: uuid.New(), Rank: "6", Suit: "C", Value: 6}}
+	var hints []GameEvent
+	g.BroadcastFn = func(ev GameEvent) {
+		if ev.Type == EventLegalActions {
+			if !ev.Hidden[caller.ID] || ev.Hidden[p.ID] {
+				t.Fatalf("expected the hint to go only to the current player, hidden from %v", ev.Hidden)
+			}
+			hints = append(hints, ev)
+		}
+	}
+
+	g.broadcastPlayerTurn()
+	if got := hints[0].Other["actions"]; !slices.Equal(got.([]string), []string{"action_draw_stockpile", "action_draw_discardpile", "action_snap"}) {
+		t.Fatalf("expected draws and a snap after Cambia was called, got %v", got)
+	}
+	g.HandlePlayerAction(p.ID, models.GameAction{ActionType: "action_draw_stockpile"})
+	if got := hints[1].Other["actions"]; !slices.Equal(got.([]string), []string{"action_discard", "action_replace", "action_snap"}) {
+		t.Fatalf("expected discard or replace after drawing, got %v", got)
+	}
+	g.HandlePlayerAction(p.ID, models.GameAction{ActionType: "action_discard", Payload: map[string]interface{}{"id": jack.ID.String()}})
+	hint := hints[len(hints)-1].Other
+	targets := hint["targets"].(map[uuid.UUID][]uuid.UUID)
+	if hint["special"] != "swap_blind" || len(targets) != 1 || len(targets[p.ID]) != 1 {
+		t.Fatalf("expected a blind swap sparing the Cambia caller's hand, got %+v", hint)
+	}
+
+	p.LegalActionHints = false
+	n := len(hints)
+	g.hintLegalActions()
+	if len(hints) != n {
+		t.Fatalf("expected no hints for a client that didn't ask")
+	}
+}
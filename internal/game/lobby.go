@@ -3,22 +3,39 @@ package game
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/coder/websocket"
 	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/crash"
 )
 
 type Lobby struct {
 	ID         uuid.UUID `json:"id"`
 	HostUserID uuid.UUID `json:"hostUserID"`
 	Type       string    `json:"type"`     // one of: "private", "public", "matchmaking"; defaults to "private"; private matches are invite or link only
-	GameMode   string    `json:"gameMode"` // one of: "head_to_head", "group_of_4", "circuit_4p", "circuit_7p8p", "custom"
+	GameMode   string    `json:"gameMode"` // one of: "head_to_head", "group_of_4", "teams_2v2", "circuit_4p", "circuit_7p8p", "custom"
+	Ranked     bool      `json:"ranked"`   // ranked lobbies must use a house rule profile from RankedProfiles
+	CreatedAt  time.Time `json:"createdAt"`
 
+	// Users, Connections, ReadyStates, Seats, and Teams are guarded by membersMu; outside this package, use the
+	// accessors such as Connection, Connected, IsMember, and ReadyMap.
 	Users map[uuid.UUID]bool `json:"-"` // false if user is not in the lobby
 
 	Connections map[uuid.UUID]*LobbyConnection `json:"-"`
 	ReadyStates map[uuid.UUID]bool             `json:"-"`
+	Seats       map[uuid.UUID]int              `json:"-"` // seat numbers of connected users, from 0
+	Teams       map[uuid.UUID]int              `json:"-"` // teams of seated users in a team game; see TeamMap
+
+	// membersMu makes joining and leaving atomic: the invite check, capacity check, and seat assignment of
+	// a join happen together, so simultaneous joins can't overfill the lobby or share a seat.
+	membersMu sync.Mutex
 
 	// GmaeInstaceCreated tracks whether a game instance has been initiated
 	GameInstanceCreated bool      `json:"-"`
@@ -27,11 +44,33 @@ type Lobby struct {
 	// InGame indicates whether a game is currently active. If so, we might block further starts.
 	InGame bool `json:"inGame"`
 
+	// CountdownTimer is the running auto-start countdown, if any, guarded by countdownMu.
 	CountdownTimer *time.Timer `json:"-"`
+	countdownMu    sync.Mutex
+
+	// ChatHistory holds the most recent chat messages (with reactions), guarded by chatMu.
+	ChatHistory []*ChatMessage `json:"-"`
+	chatMu      sync.Mutex
 
 	HouseRules    HouseRules    `json:"houseRules"`
 	Circuit       Circuit       `json:"circuit"`
+	Series        Series        `json:"series"`
 	LobbySettings LobbySettings `json:"lobbySettings"`
+
+	// settingsMu orders changes to the host-chosen settings (Type, GameMode, Ranked, and the settings above)
+	// with InGame changing, so a game is never started under half-changed settings; see UpdateSettings.
+	settingsMu sync.Mutex
+
+	// CircuitState tracks the circuit series in progress, if Circuit is enabled.
+	CircuitState *CircuitState `json:"-"`
+	// SeriesState tracks the best-of-N series in progress, if Series is enabled.
+	SeriesState *SeriesState `json:"-"`
+
+	// eventLogs number and keep the recent broadcasts delivered to each user, for resync.
+	eventsMu  sync.Mutex
+	eventLogs map[uuid.UUID]*EventLog[map[string]interface{}]
+	// onChange is set by the LobbyStore holding the lobby, and called when its listing changes.
+	onChange func()
 }
 
 // LobbyConnection wraps a single user's active WebSocket connection for the lobby.
@@ -40,11 +79,58 @@ type LobbyConnection struct {
 	Cancel  context.CancelFunc
 	OutChan chan map[string]interface{}
 	IsHost  bool
+
+	// ProtocolVersion is the socket protocol version negotiated with the client; see protocol.Socket.
+	ProtocolVersion int
+	// RequestID is the ID of the request that opened the socket; error frames carry it.
+	RequestID string
+
+	// blocked holds the users this connection's user has blocked; their chat isn't delivered here.
+	blockedMu sync.Mutex
+	blocked   map[uuid.UUID]bool
+
+	// Backpressure state for Write; see outbox.go.
+	drops        atomic.Int64
+	tooSlow      atomic.Bool
+	pendingMu    sync.Mutex
+	pending      map[string]map[string]interface{}
+	pendingOrder []string
+	flushOnce    sync.Once
+	flush        chan struct{}
 }
 
-// Write will push a message to the user's message channel.
-func (conn *LobbyConnection) Write(msg map[string]interface{}) {
-	conn.OutChan <- msg
+// SetBlocked replaces the set of users whose chat this connection doesn't receive.
+func (conn *LobbyConnection) SetBlocked(ids []uuid.UUID) {
+	conn.blockedMu.Lock()
+	defer conn.blockedMu.Unlock()
+	conn.blocked = make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		conn.blocked[id] = true
+	}
+}
+
+// Block stops delivering a user's chat to this connection; Unblock resumes it.
+func (conn *LobbyConnection) Block(userID uuid.UUID) {
+	conn.blockedMu.Lock()
+	defer conn.blockedMu.Unlock()
+	if conn.blocked == nil {
+		conn.blocked = make(map[uuid.UUID]bool)
+	}
+	conn.blocked[userID] = true
+}
+
+// Unblock resumes delivering a user's chat to this connection.
+func (conn *LobbyConnection) Unblock(userID uuid.UUID) {
+	conn.blockedMu.Lock()
+	defer conn.blockedMu.Unlock()
+	delete(conn.blocked, userID)
+}
+
+// HasBlocked reports whether this connection's user has blocked userID.
+func (conn *LobbyConnection) HasBlocked(userID uuid.UUID) bool {
+	conn.blockedMu.Lock()
+	defer conn.blockedMu.Unlock()
+	return conn.blocked[userID]
 }
 
 // WriteError will push an error message to the user's message channel.
@@ -52,18 +138,19 @@ func (conn *LobbyConnection) Write(msg map[string]interface{}) {
 //
 //	{
 //	 "type": "error",
-//	 "message": msg
+//	 "message": msg,
+//	 "request_id": "{the socket's request ID}"
 //	}
 func (conn *LobbyConnection) WriteError(msg string) {
-	conn.OutChan <- map[string]interface{}{
+	conn.Write(map[string]interface{}{
 		"type":    "error",
 		"message": msg,
-	}
+	})
 }
 
 type Circuit struct {
 	Enabled bool         `json:"enabled"` // whether to enable Circuit mode
-	Mode    string       `json:"mode"`    // one of: "elimination", "max_rounds"
+	Mode    string       `json:"mode"`    // one of: "elimination", "max_rounds"; defaults to "elimination"
 	Rules   CircuitRules `json:"rules"`
 }
 
@@ -72,10 +159,29 @@ type CircuitRules struct {
 	WinBonus               int  `json:"winBonus"`               // constant added to the winner's running score if they win
 	FalseCambiaPenalty     int  `json:"falseCambiaPenalty"`     // penalty for a player who calls Cambia but doesn't win
 	FreezeUserOnDisconnect bool `json:"freezeUserOnDisconnect"` // if true, freeze the user's score on disconnect and keep them out of the rounds; they can rejoin
+	MaxRounds              int  `json:"maxRounds"`              // number of rounds in a "max_rounds" circuit; defaults to 5
 }
 
 type LobbySettings struct {
-	AutoStart bool `json:"autoStart"` // default true
+	AutoStart         bool `json:"autoStart"`         // default true
+	DisableRankedChat bool `json:"disableRankedChat"` // turn off in-game chat, though not emotes, in ranked games
+}
+
+// Update updates the settings given in newSettings, leaving the others as they are.
+func (settings *LobbySettings) Update(newSettings map[string]interface{}) error {
+	var ok bool
+
+	if val, exists := newSettings["autoStart"]; exists && val != nil {
+		if settings.AutoStart, ok = val.(bool); !ok {
+			return fmt.Errorf("invalid type for autoStart")
+		}
+	}
+	if val, exists := newSettings["disableRankedChat"]; exists && val != nil {
+		if settings.DisableRankedChat, ok = val.(bool); !ok {
+			return fmt.Errorf("invalid type for disableRankedChat")
+		}
+	}
+	return nil
 }
 
 // NewLobby creates a new non-circuit Lobby under the specified host user.
@@ -89,6 +195,8 @@ type LobbySettings struct {
 // - `PenaltyDrawCount`: `1`
 // - `AutoKickTurnCount`: `3`
 // - `TurnTimerSec`: `15`
+// - `Jokers`: `2`
+// - `DoubleDeck`: `false`
 //
 // Additionally, `autoStart` is enabled by default.
 //
@@ -98,15 +206,7 @@ type LobbySettings struct {
 // your communications on your own.
 func NewLobbyWithDefaults(hostID uuid.UUID) *Lobby {
 	var (
-		defaultHouseRules = HouseRules{
-			AllowDrawFromDiscardPile: false,
-			AllowReplaceAbilities:    false,
-			SnapRace:                 false,
-			ForfeitOnDisconnect:      true,
-			PenaltyDrawCount:         1,
-			AutoKickTurnCount:        3,
-			TurnTimerSec:             15,
-		}
+		defaultHouseRules      = DefaultHouseRules()
 		defaultCircuitSettings = Circuit{Enabled: false}
 		defaultLobbySettings   = LobbySettings{AutoStart: true}
 	)
@@ -116,14 +216,31 @@ func NewLobbyWithDefaults(hostID uuid.UUID) *Lobby {
 	return &Lobby{
 		ID:            lobbyID,
 		HostUserID:    hostID,
+		Users:         make(map[uuid.UUID]bool),
 		Connections:   make(map[uuid.UUID]*LobbyConnection),
 		ReadyStates:   make(map[uuid.UUID]bool),
+		Seats:         make(map[uuid.UUID]int),
+		CreatedAt:     time.Now().UTC(),
 		HouseRules:    defaultHouseRules,
 		Circuit:       defaultCircuitSettings,
 		LobbySettings: defaultLobbySettings,
 	}
 }
 
+// DefaultHouseRules returns the house rules new lobbies start with.
+func DefaultHouseRules() HouseRules {
+	return HouseRules{
+		AllowDrawFromDiscardPile: false,
+		AllowReplaceAbilities:    false,
+		SnapRace:                 false,
+		ForfeitOnDisconnect:      true,
+		PenaltyDrawCount:         1,
+		AutoKickTurnCount:        3,
+		TurnTimerSec:             15,
+		Jokers:                   2,
+	}
+}
+
 // NewLobby creates a new Lobby under the specified host user.
 // Returns a pointer to the lobby
 func NewCircuitWithDefaults(hostID uuid.UUID) *Lobby {
@@ -136,16 +253,12 @@ func NewCircuitWithDefaults(hostID uuid.UUID) *Lobby {
 			ForfeitOnDisconnect:      true,
 			AutoKickTurnCount:        3,
 			TurnTimerSec:             15,
+			Jokers:                   2,
 		}
 		defaultCircuitSettings = Circuit{
 			Enabled: true,
-			Mode:    "elimination",
-			Rules: CircuitRules{
-				TargetScore:            100,
-				WinBonus:               -1,
-				FalseCambiaPenalty:     5,
-				FreezeUserOnDisconnect: false, // i.e. user is automatically eliminated
-			},
+			Mode:    CircuitModeElimination,
+			Rules:   DefaultCircuitRules,
 		}
 		defaultLobbySettings = LobbySettings{AutoStart: true}
 	)
@@ -155,8 +268,11 @@ func NewCircuitWithDefaults(hostID uuid.UUID) *Lobby {
 	return &Lobby{
 		ID:            lobbyID,
 		HostUserID:    hostID,
+		Users:         make(map[uuid.UUID]bool),
 		Connections:   make(map[uuid.UUID]*LobbyConnection),
 		ReadyStates:   make(map[uuid.UUID]bool),
+		Seats:         make(map[uuid.UUID]int),
+		CreatedAt:     time.Now().UTC(),
 		HouseRules:    defaultHouseRules,
 		Circuit:       defaultCircuitSettings,
 		LobbySettings: defaultLobbySettings,
@@ -169,36 +285,203 @@ func NewLobbyWithSettings(hostID uuid.UUID, houseRules HouseRules, circuit Circu
 	return &Lobby{
 		ID:            lobbyID,
 		HostUserID:    hostID,
+		Users:         make(map[uuid.UUID]bool),
 		Connections:   make(map[uuid.UUID]*LobbyConnection),
 		ReadyStates:   make(map[uuid.UUID]bool),
+		Seats:         make(map[uuid.UUID]int),
+		CreatedAt:     time.Now().UTC(),
 		HouseRules:    houseRules,
 		Circuit:       circuit,
 		LobbySettings: lobbySettings,
 	}
 }
 
+// StatusLobbyFull is the close code for a lobby join that lost the race for the last seat; it's the
+// WebSocket counterpart of HTTP 409 Conflict.
+const StatusLobbyFull websocket.StatusCode = 4409
+
+var (
+	ErrLobbyFull  = errors.New("lobby is full")
+	ErrNotInvited = errors.New("not invited to the private lobby")
+	ErrInGame     = errors.New("the lobby's game has already started")
+)
+
+// UpdateSettings runs update, which changes the lobby's settings, unless its game has started, in which
+// case it fails with ErrInGame. A game starting meanwhile waits for update to finish.
+func (lobby *Lobby) UpdateSettings(update func() error) error {
+	lobby.settingsMu.Lock()
+	defer lobby.settingsMu.Unlock()
+	if lobby.InGame {
+		return ErrInGame
+	}
+	return update()
+}
+
+// SetInGame marks whether the lobby's game is being played. Once it's marked in game, its settings don't
+// change until it's marked out of game again.
+func (lobby *Lobby) SetInGame(inGame bool) {
+	lobby.settingsMu.Lock()
+	defer lobby.settingsMu.Unlock()
+	lobby.InGame = inGame
+}
+
+// Capacity returns how many users can be seated in the lobby at once, which depends on its game mode.
+func (lobby *Lobby) Capacity() int {
+	switch lobby.GameMode {
+	case "head_to_head":
+		return 2
+	case "group_of_4", "teams_2v2", "circuit_4p":
+		return 4
+	default:
+		return 8
+	}
+}
+
 // InviteUser grants "permission" to a user to join this lobby. This only has an effect if the Type is "private".
 func (lobby *Lobby) InviteUser(userID uuid.UUID) {
-	lobby.Users[userID] = false
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	if _, ok := lobby.Users[userID]; !ok {
+		lobby.Users[userID] = false
+	}
 }
 
-// AddConnection registers a user's connection to the lobby and sets their ready status.
-// This is effectively a "join lobby" operation.
+// AddConnection registers a user's connection to the lobby, seats them in the lowest free seat, and sets
+// their ready status. This is effectively a "join lobby" operation. It fails with ErrNotInvited or
+// ErrLobbyFull; a user who is already seated keeps their seat.
 func (lobby *Lobby) AddConnection(userID uuid.UUID, conn *LobbyConnection) error {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+
 	if lobby.Type == "private" {
 		if _, ok := lobby.Users[userID]; !ok {
-			// user not invited
-			return fmt.Errorf("user %s not invited to the private lobby", userID)
+			return fmt.Errorf("user %s: %w", userID, ErrNotInvited)
+		}
+	}
+	if lobby.Seats == nil {
+		lobby.Seats = make(map[uuid.UUID]int)
+	}
+	if _, seated := lobby.Seats[userID]; !seated {
+		if len(lobby.Seats) >= lobby.Capacity() {
+			return ErrLobbyFull
 		}
+		taken := make(map[int]bool, len(lobby.Seats))
+		for _, seat := range lobby.Seats {
+			taken[seat] = true
+		}
+		seat := 0
+		for taken[seat] {
+			seat++
+		}
+		lobby.Seats[userID] = seat
 	}
 
 	lobby.Users[userID] = true
 	lobby.Connections[userID] = conn
 	lobby.ReadyStates[userID] = false
+	lobby.Changed()
 
 	return nil
 }
 
+// Occupancy returns the number of seated users.
+func (lobby *Lobby) Occupancy() int {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	return len(lobby.Seats)
+}
+
+// Changed notes that the lobby's settings or membership changed, so listings of it get refreshed.
+func (lobby *Lobby) Changed() {
+	if lobby.onChange != nil {
+		lobby.onChange()
+	}
+}
+
+// Seat returns the seat number of a connected user.
+func (lobby *Lobby) Seat(userID uuid.UUID) (int, bool) {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	seat, ok := lobby.Seats[userID]
+	return seat, ok
+}
+
+// lobbyHoldTime is how long a new lobby counts against its host's lobby limit before they take a seat in it.
+const lobbyHoldTime = 5 * time.Minute
+
+// HeldBy reports whether the lobby counts against userID's lobby limit: they're seated in it, or they
+// created it within lobbyHoldTime and haven't joined it yet.
+func (lobby *Lobby) HeldBy(userID uuid.UUID) bool {
+	if _, seated := lobby.Seat(userID); seated {
+		return true
+	}
+	return lobby.HostUserID == userID && time.Since(lobby.CreatedAt) < lobbyHoldTime
+}
+
+// SeatedUsers returns the seated users in seat order.
+func (lobby *Lobby) SeatedUsers() []uuid.UUID {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	return lobby.seatOrder()
+}
+
+// seatOrder is the lock-free body of SeatedUsers.
+func (lobby *Lobby) seatOrder() []uuid.UUID {
+	users := make([]uuid.UUID, 0, len(lobby.Seats))
+	for id := range lobby.Seats {
+		users = append(users, id)
+	}
+	slices.SortFunc(users, func(a, b uuid.UUID) int { return lobby.Seats[a] - lobby.Seats[b] })
+	return users
+}
+
+// members snapshots the seat and ready maps for a lobby_update.
+func (lobby *Lobby) members() (seats map[string]int, ready map[uuid.UUID]bool) {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	seats = make(map[string]int, len(lobby.Seats))
+	for id, seat := range lobby.Seats {
+		seats[id.String()] = seat
+	}
+	ready = make(map[uuid.UUID]bool, len(lobby.ReadyStates))
+	for id, r := range lobby.ReadyStates {
+		ready[id] = r
+	}
+	return seats, ready
+}
+
+// Connected snapshots the connected users, for broadcasting without holding membersMu.
+func (lobby *Lobby) Connected() map[uuid.UUID]*LobbyConnection {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	conns := make(map[uuid.UUID]*LobbyConnection, len(lobby.Connections))
+	for id, conn := range lobby.Connections {
+		conns[id] = conn
+	}
+	return conns
+}
+
+// Connection returns a user's connection to the lobby, if they're connected.
+func (lobby *Lobby) Connection(userID uuid.UUID) (*LobbyConnection, bool) {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	conn, ok := lobby.Connections[userID]
+	return conn, ok
+}
+
+// IsMember reports whether a user is in the lobby, as opposed to only invited to it.
+func (lobby *Lobby) IsMember(userID uuid.UUID) bool {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	return lobby.Users[userID]
+}
+
+// ReadyMap returns a copy of the connected users' ready states.
+func (lobby *Lobby) ReadyMap() map[uuid.UUID]bool {
+	_, ready := lobby.members()
+	return ready
+}
+
 // JoinUser is an alias for AddConnection
 func (lobby *Lobby) JoinUser(userID uuid.UUID, conn *LobbyConnection) error {
 	return lobby.AddConnection(userID, conn)
@@ -208,6 +491,8 @@ func (lobby *Lobby) JoinUser(userID uuid.UUID, conn *LobbyConnection) error {
 //
 // seconds is how long the countdown lasts. After it finishes, we call OnCountdownFinish, if set.
 func (lobby *Lobby) StartCountdown(seconds int, callback func(uuid.UUID)) bool {
+	lobby.countdownMu.Lock()
+	defer lobby.countdownMu.Unlock()
 	// If already in a game or countdown is running, do nothing
 	if lobby.InGame {
 		return false
@@ -222,6 +507,16 @@ func (lobby *Lobby) StartCountdown(seconds int, callback func(uuid.UUID)) bool {
 	})
 
 	lobby.CountdownTimer = time.AfterFunc(time.Duration(seconds)*time.Second, func() {
+		defer func() {
+			if v := recover(); v != nil {
+				crash.Log(fmt.Sprintf("countdown of lobby %v", lobby.ID), v)
+				lobby.BroadcastAll(map[string]interface{}{
+					"type":    "error",
+					"code":    "internal_error",
+					"message": "failed to start the game",
+				})
+			}
+		}()
 		callback(lobby.ID)
 	})
 
@@ -230,6 +525,8 @@ func (lobby *Lobby) StartCountdown(seconds int, callback func(uuid.UUID)) bool {
 
 // CancelCountdown stops an active countdown if present.
 func (lobby *Lobby) CancelCountdown() {
+	lobby.countdownMu.Lock()
+	defer lobby.countdownMu.Unlock()
 	if lobby.CountdownTimer != nil {
 		lobby.CountdownTimer.Stop()
 		lobby.CountdownTimer = nil
@@ -238,27 +535,46 @@ func (lobby *Lobby) CancelCountdown() {
 
 // MarkUserReady sets a user's ready state if they're connected.
 func (lobby *Lobby) MarkUserReady(userID uuid.UUID) {
+	lobby.membersMu.Lock()
 	if _, ok := lobby.Connections[userID]; !ok {
 		// user not truly connected
+		lobby.membersMu.Unlock()
 		return
 	}
 	lobby.ReadyStates[userID] = true
+	lobby.membersMu.Unlock()
 	lobby.BroadcastReadyState(userID, true)
 }
 
 // MarkUserUnready unsets a user's ready state, then cancels the countdown if any.
 func (lobby *Lobby) MarkUserUnready(userID uuid.UUID) {
+	lobby.membersMu.Lock()
 	if _, ok := lobby.Connections[userID]; !ok {
 		// user not truly connected
+		lobby.membersMu.Unlock()
 		return
 	}
 	lobby.ReadyStates[userID] = false
+	lobby.membersMu.Unlock()
 	lobby.BroadcastReadyState(userID, false)
 	lobby.CancelCountdown()
 }
 
+// ResetReadyStates marks every connected user not ready, e.g. when a game ends early, so the lobby waits for
+// everyone to ready up again.
+func (lobby *Lobby) ResetReadyStates() {
+	lobby.membersMu.Lock()
+	for uid := range lobby.Connections {
+		lobby.ReadyStates[uid] = false
+	}
+	lobby.membersMu.Unlock()
+	lobby.CancelCountdown()
+}
+
 // AreAllReady returns true if all known participants are ready.
 func (lobby *Lobby) AreAllReady() bool {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
 	if len(lobby.ReadyStates) == 0 {
 		return false
 	}
@@ -271,6 +587,8 @@ func (lobby *Lobby) AreAllReady() bool {
 }
 
 func (lobby *Lobby) WhoIsReady() []uuid.UUID {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
 	var readyUsers []uuid.UUID
 	for userID, ready := range lobby.ReadyStates {
 		if ready {
@@ -281,6 +599,8 @@ func (lobby *Lobby) WhoIsReady() []uuid.UUID {
 }
 
 func (lobby *Lobby) WhoIsNotReady() []uuid.UUID {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
 	var notReadyUsers []uuid.UUID
 	for userID, ready := range lobby.ReadyStates {
 		if !ready {
@@ -290,19 +610,86 @@ func (lobby *Lobby) WhoIsNotReady() []uuid.UUID {
 	return notReadyUsers
 }
 
-// BroadcastAll sends a JSON object to all connected users' OutChan.
+// BroadcastAll sends a JSON object to all connected users, numbered for each with sequenceFor. It's
+// marshaled once for all of them.
 func (lobby *Lobby) BroadcastAll(msg map[string]interface{}) {
-	for _, conn := range lobby.Connections {
-		conn.OutChan <- msg
+	body, _ := json.Marshal(msg)
+	for userID, conn := range lobby.Connected() {
+		conn.Write(lobby.sequenceFor(userID, msg, body))
 	}
 }
 
+// sequenceFor numbers a broadcast for delivery to one user and keeps it for Resync. The message is copied
+// so that each recipient gets their own "seq". body, if not nil, is msg marshaled, which the copy carries
+// for EncodeFrame to reuse.
+func (lobby *Lobby) sequenceFor(userID uuid.UUID, msg map[string]interface{}, body []byte) map[string]interface{} {
+	lobby.eventsMu.Lock()
+	if lobby.eventLogs == nil {
+		lobby.eventLogs = make(map[uuid.UUID]*EventLog[map[string]interface{}])
+	}
+	el, ok := lobby.eventLogs[userID]
+	if !ok {
+		el = &EventLog[map[string]interface{}]{}
+		lobby.eventLogs[userID] = el
+	}
+	lobby.eventsMu.Unlock()
+
+	return el.Record(func(seq uint64) map[string]interface{} {
+		out := make(map[string]interface{}, len(msg)+2)
+		for k, v := range msg {
+			out[k] = v
+		}
+		out["seq"] = seq
+		if body != nil {
+			out[encodedKey] = encodedFrame{body: body, seq: seq}
+		}
+		return out
+	})
+}
+
+// Resync returns the broadcasts delivered to a user after seq, and the latest sequence number. complete is
+// false if some have aged out, in which case the user should rejoin the lobby.
+func (lobby *Lobby) Resync(userID uuid.UUID, seq uint64) (msgs []map[string]interface{}, latest uint64, complete bool) {
+	lobby.eventsMu.Lock()
+	el, ok := lobby.eventLogs[userID]
+	lobby.eventsMu.Unlock()
+	if !ok {
+		return []map[string]interface{}{}, 0, seq == 0
+	}
+	msgs, latest, complete = el.Since(seq)
+	for i, msg := range msgs {
+		msgs[i] = withoutEncoding(msg)
+	}
+	return msgs, latest, complete
+}
+
 // BroadcastJoin sends a "lobby_update" message indicating a user joined.
 func (lobby *Lobby) BroadcastJoin(userID uuid.UUID) {
-	lobby.BroadcastAll(map[string]interface{}{
+	seats, ready := lobby.members()
+	msg := map[string]interface{}{
 		"type":      "lobby_update",
 		"user_join": userID.String(),
-		"ready_map": lobby.ReadyStates,
+		"ready_map": ready,
+		"seats":     seats,
+	}
+	if lobby.TeamGame() {
+		msg["teams"] = lobby.teamsByID()
+	}
+	lobby.BroadcastAll(msg)
+}
+
+// BroadcastSettings sends everyone in the lobby its current settings, after the host changed them.
+func (lobby *Lobby) BroadcastSettings() {
+	// snapshot the lobby, since the event log keeps the message for resyncs
+	lobby.settingsMu.Lock()
+	data, err := json.Marshal(lobby)
+	lobby.settingsMu.Unlock()
+	if err != nil {
+		return
+	}
+	lobby.BroadcastAll(map[string]interface{}{
+		"type":  "lobby_settings",
+		"lobby": json.RawMessage(data),
 	})
 }
 
@@ -317,29 +704,50 @@ func (lobby *Lobby) BroadcastReadyState(userID uuid.UUID, ready bool) {
 
 // BroadcastLeave sends a "lobby_update" message indicating a user left.
 func (lobby *Lobby) BroadcastLeave(userID uuid.UUID) {
-	lobby.BroadcastAll(map[string]interface{}{
+	seats, ready := lobby.members()
+	msg := map[string]interface{}{
 		"type":      "lobby_update",
 		"user_left": userID.String(),
-		"ready_map": lobby.ReadyStates,
-	})
+		"ready_map": ready,
+		"seats":     seats,
+	}
+	if lobby.TeamGame() {
+		msg["teams"] = lobby.teamsByID()
+	}
+	lobby.BroadcastAll(msg)
 }
 
-// BroadcastChat sends a chat message from a given user.
-func (lobby *Lobby) BroadcastChat(userID uuid.UUID, msg string) {
-	lobby.BroadcastAll(map[string]interface{}{
+// BroadcastChat records a chat message from a given user in the lobby's history and broadcasts it to
+// everyone who hasn't blocked the sender. The message ID can be used to react to the message.
+func (lobby *Lobby) BroadcastChat(userID uuid.UUID, msg string) *ChatMessage {
+	m := lobby.appendChat(userID, msg)
+	out := map[string]interface{}{
 		"type":    "chat",
+		"msg_id":  m.ID.String(),
 		"user_id": userID.String(),
 		"msg":     msg,
-		"ts":      time.Now().Unix(),
-	})
+		"ts":      m.TS,
+	}
+	body, _ := json.Marshal(out)
+	for recipient, conn := range lobby.Connected() {
+		if !conn.HasBlocked(userID) {
+			conn.Write(lobby.sequenceFor(recipient, out, body))
+		}
+	}
+	return m
 }
 
 // RemoveUser removes a user from Connections & ReadyStates (if the user
 // unexpectedly disconnects). It's used in readPump's defer if we see an error or close.
 func (lobby *Lobby) RemoveUser(userID uuid.UUID) {
+	lobby.membersMu.Lock()
 	delete(lobby.Users, userID)
 	delete(lobby.Connections, userID)
 	delete(lobby.ReadyStates, userID)
+	delete(lobby.Seats, userID)
+	delete(lobby.Teams, userID)
+	lobby.membersMu.Unlock()
+	lobby.Changed()
 
 	lobby.CancelCountdown()
 }
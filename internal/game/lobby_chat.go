@@ -0,0 +1,130 @@
+package game
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxChatHistory is how many recent chat messages a lobby keeps in memory.
+	maxChatHistory = 100
+	// maxReactionLen bounds the size of a single reaction (a short emoji sequence).
+	maxReactionLen = 8
+	// maxReactionsPerMessage bounds how many distinct reactions a single message can collect.
+	maxReactionsPerMessage = 20
+)
+
+// ChatMessage is a single lobby chat message, along with the users who reacted to it.
+type ChatMessage struct {
+	ID        uuid.UUID                     `json:"id"`
+	UserID    uuid.UUID                     `json:"userID"`
+	Msg       string                        `json:"msg"`
+	TS        int64                         `json:"ts"`
+	Reactions map[string]map[uuid.UUID]bool `json:"-"`
+}
+
+// ReactionCounts aggregates the reactions on a message into emoji -> count.
+func (m *ChatMessage) ReactionCounts() map[string]int {
+	counts := make(map[string]int, len(m.Reactions))
+	for emoji, users := range m.Reactions {
+		if len(users) > 0 {
+			counts[emoji] = len(users)
+		}
+	}
+	return counts
+}
+
+// appendChat records a chat message in the lobby's bounded history.
+func (lobby *Lobby) appendChat(userID uuid.UUID, msg string) *ChatMessage {
+	lobby.chatMu.Lock()
+	defer lobby.chatMu.Unlock()
+	id, _ := uuid.NewV7()
+	m := &ChatMessage{
+		ID:        id,
+		UserID:    userID,
+		Msg:       msg,
+		TS:        time.Now().Unix(),
+		Reactions: make(map[string]map[uuid.UUID]bool),
+	}
+	lobby.ChatHistory = append(lobby.ChatHistory, m)
+	if len(lobby.ChatHistory) > maxChatHistory {
+		lobby.ChatHistory = lobby.ChatHistory[len(lobby.ChatHistory)-maxChatHistory:]
+	}
+	return m
+}
+
+// SetReaction adds (or removes, if add is false) a user's reaction to a chat message, then broadcasts
+// the message's aggregated reaction counts. It returns the updated message.
+func (lobby *Lobby) SetReaction(userID, msgID uuid.UUID, emoji string, add bool) (*ChatMessage, error) {
+	if emoji == "" || len(emoji) > maxReactionLen*utf8.UTFMax || utf8.RuneCountInString(emoji) > maxReactionLen {
+		return nil, fmt.Errorf("invalid reaction")
+	}
+
+	lobby.chatMu.Lock()
+	var msg *ChatMessage
+	for _, m := range lobby.ChatHistory {
+		if m.ID == msgID {
+			msg = m
+			break
+		}
+	}
+	if msg == nil {
+		lobby.chatMu.Unlock()
+		return nil, fmt.Errorf("chat message %v not found", msgID)
+	}
+	if add {
+		if _, ok := msg.Reactions[emoji]; !ok {
+			if len(msg.Reactions) >= maxReactionsPerMessage {
+				lobby.chatMu.Unlock()
+				return nil, fmt.Errorf("too many distinct reactions on this message")
+			}
+			msg.Reactions[emoji] = make(map[uuid.UUID]bool)
+		}
+		msg.Reactions[emoji][userID] = true
+	} else if users, ok := msg.Reactions[emoji]; ok {
+		delete(users, userID)
+		if len(users) == 0 {
+			delete(msg.Reactions, emoji)
+		}
+	}
+	counts := msg.ReactionCounts()
+	lobby.chatMu.Unlock()
+
+	lobby.BroadcastAll(map[string]interface{}{
+		"type":      "chat_reactions",
+		"msg_id":    msgID.String(),
+		"reactions": counts,
+	})
+	return msg, nil
+}
+
+// ReactionUsers returns a copy of the message's reactions as emoji -> user IDs, e.g. for persistence.
+func (lobby *Lobby) ReactionUsers(m *ChatMessage) map[string][]uuid.UUID {
+	lobby.chatMu.Lock()
+	defer lobby.chatMu.Unlock()
+	out := make(map[string][]uuid.UUID, len(m.Reactions))
+	for emoji, users := range m.Reactions {
+		for uid := range users {
+			out[emoji] = append(out[emoji], uid)
+		}
+	}
+	return out
+}
+
+// RecentChat returns a copy of up to the n most recent chat messages, oldest first, without reactions.
+func (lobby *Lobby) RecentChat(n int) []ChatMessage {
+	lobby.chatMu.Lock()
+	defer lobby.chatMu.Unlock()
+	history := lobby.ChatHistory
+	if len(history) > n {
+		history = history[len(history)-n:]
+	}
+	out := make([]ChatMessage, 0, len(history))
+	for _, m := range history {
+		out = append(out, ChatMessage{ID: m.ID, UserID: m.UserID, Msg: m.Msg, TS: m.TS})
+	}
+	return out
+}
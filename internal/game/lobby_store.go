@@ -2,6 +2,7 @@ package game
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +12,9 @@ import (
 type LobbyStore struct {
 	mu      sync.Mutex
 	lobbies map[uuid.UUID]*Lobby
+
+	// version counts changes to the store and its lobbies, so cached listings can tell they're stale.
+	version atomic.Uint64
 }
 
 // NewLobbyStore creates and returns a new LobbyStore.
@@ -28,17 +32,43 @@ func (s *LobbyStore) GetLobby(id uuid.UUID) (*Lobby, bool) {
 	return lobby, exists
 }
 
+// GetLobbies returns a copy of the store's lobbies, safe to range over while lobbies come and go.
 func (s *LobbyStore) GetLobbies() map[uuid.UUID]*Lobby {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.lobbies
+	lobbies := make(map[uuid.UUID]*Lobby, len(s.lobbies))
+	for id, lobby := range s.lobbies {
+		lobbies[id] = lobby
+	}
+	return lobbies
 }
 
 // AddLobby adds a new lobby to the store.
 func (s *LobbyStore) AddLobby(lobby *Lobby) {
+	s.AddLobbyWithin(lobby, 0)
+}
+
+// AddLobbyWithin adds a new lobby unless its host already holds limit lobbies (see Lobby.HeldBy), and
+// reports whether it did. The check and the add happen under one lock, so simultaneous creations can't
+// go over the limit. A limit of 0 means none.
+func (s *LobbyStore) AddLobbyWithin(lobby *Lobby, limit int) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if limit > 0 {
+		held := 0
+		for _, l := range s.lobbies {
+			if l.HeldBy(lobby.HostUserID) {
+				held++
+			}
+		}
+		if held >= limit {
+			return false
+		}
+	}
+	lobby.onChange = s.Touch
 	s.lobbies[lobby.ID] = lobby
+	s.version.Add(1)
+	return true
 }
 
 // DeleteLobby removes a lobby from memory if it exists, e.g. if the lobby is closed or deleted.
@@ -46,5 +76,29 @@ func (s *LobbyStore) AddLobby(lobby *Lobby) {
 func (s *LobbyStore) DeleteLobby(id uuid.UUID) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.lobbies, id)
+	if _, ok := s.lobbies[id]; ok {
+		delete(s.lobbies, id)
+		s.version.Add(1)
+	}
+}
+
+// LobbiesHeldBy counts the lobbies that count against userID's lobby limit; see Lobby.HeldBy.
+func (s *LobbyStore) LobbiesHeldBy(userID uuid.UUID) int {
+	n := 0
+	for _, lobby := range s.GetLobbies() {
+		if lobby.HeldBy(userID) {
+			n++
+		}
+	}
+	return n
+}
+
+// Touch records that one of the store's lobbies changed.
+func (s *LobbyStore) Touch() {
+	s.version.Add(1)
+}
+
+// Version returns a number that changes whenever a lobby is added, removed, or changed.
+func (s *LobbyStore) Version() uint64 {
+	return s.version.Load()
 }
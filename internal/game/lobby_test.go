@@ -0,0 +1,236 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAddConnectionSeatsAtomically(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.GameMode = "group_of_4"
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	joined, full := 0, 0
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := uuid.New()
+			err := lobby.AddConnection(id, &LobbyConnection{UserID: id})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				joined++
+			case errors.Is(err, ErrLobbyFull):
+				full++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if joined != 4 || full != 6 {
+		t.Fatalf("expected 4 joins and 6 rejections, got %d and %d", joined, full)
+	}
+
+	seen := make(map[int]bool)
+	for _, seat := range lobby.Seats {
+		if seen[seat] || seat < 0 || seat >= 4 {
+			t.Fatalf("expected seats 0 through 3 once each, got %v", lobby.Seats)
+		}
+		seen[seat] = true
+	}
+}
+
+func TestAddConnectionReusesSeats(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.GameMode = "head_to_head"
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{a, b} {
+		if err := lobby.AddConnection(id, &LobbyConnection{UserID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lobby.AddConnection(a, &LobbyConnection{UserID: a}); err != nil {
+		t.Fatalf("expected a seated user to reconnect, got %v", err)
+	}
+	if seat, _ := lobby.Seat(a); seat != 0 {
+		t.Fatalf("expected a to keep seat 0, got %d", seat)
+	}
+
+	lobby.RemoveUser(a)
+	if err := lobby.AddConnection(c, &LobbyConnection{UserID: c}); err != nil {
+		t.Fatal(err)
+	}
+	if seat, _ := lobby.Seat(c); seat != 0 {
+		t.Fatalf("expected c to take the freed seat 0, got %d", seat)
+	}
+	if got := lobby.SeatedUsers(); !slices.Equal(got, []uuid.UUID{c, b}) {
+		t.Fatalf("expected c then b in seat order, got %v", got)
+	}
+}
+
+func TestAddConnectionPrivateLobby(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.Type = "private"
+	id := uuid.New()
+	if err := lobby.AddConnection(id, &LobbyConnection{UserID: id}); !errors.Is(err, ErrNotInvited) {
+		t.Fatalf("expected ErrNotInvited, got %v", err)
+	}
+	lobby.InviteUser(id)
+	if err := lobby.AddConnection(id, &LobbyConnection{UserID: id}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLobbyStoreVersion(t *testing.T) {
+	store := NewLobbyStore()
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.Type = "public"
+	store.AddLobby(lobby)
+
+	v := store.Version()
+	id := uuid.New()
+	if err := lobby.AddConnection(id, &LobbyConnection{UserID: id}); err != nil {
+		t.Fatal(err)
+	}
+	if store.Version() == v {
+		t.Fatalf("expected a join to change the store version")
+	}
+	v = store.Version()
+	lobby.RemoveUser(id)
+	if store.Version() == v {
+		t.Fatalf("expected a leave to change the store version")
+	}
+	v = store.Version()
+	store.DeleteLobby(lobby.ID)
+	if store.Version() == v {
+		t.Fatalf("expected a delete to change the store version")
+	}
+}
+
+func TestRelaySignal(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.Type = "public"
+	a, b := uuid.New(), uuid.New()
+	connA := &LobbyConnection{UserID: a, OutChan: make(chan map[string]interface{}, 1)}
+	connB := &LobbyConnection{UserID: b, OutChan: make(chan map[string]interface{}, 1)}
+	for id, conn := range map[uuid.UUID]*LobbyConnection{a: connA, b: connB} {
+		if err := lobby.AddConnection(id, conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := lobby.RelaySignal(a, b, "offer", []byte(`{"sdp": "v=0"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := <-connB.OutChan; got["type"] != "rtc_signal" || got["from"] != a.String() || got["kind"] != "offer" {
+		t.Fatalf("expected an offer from %v, got %v", a, got)
+	}
+	if err := lobby.RelaySignal(a, uuid.New(), "ice", []byte(`[]`)); !errors.Is(err, ErrSignalUnavailable) {
+		t.Fatalf("expected a stranger to be unavailable, got %v", err)
+	}
+	connB.Block(a)
+	if err := lobby.RelaySignal(a, b, "ice", []byte(`[]`)); !errors.Is(err, ErrSignalUnavailable) {
+		t.Fatalf("expected a blocking user to be unavailable, got %v", err)
+	}
+}
+
+func TestLobbyConcurrentJoinLeaveReady(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.Type = "public"
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := uuid.New()
+			conn := &LobbyConnection{UserID: id, OutChan: make(chan map[string]interface{}, 100)}
+			for range 20 {
+				if err := lobby.AddConnection(id, conn); err != nil && !errors.Is(err, ErrLobbyFull) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				lobby.BroadcastJoin(id)
+				lobby.MarkUserReady(id)
+				if lobby.AreAllReady() {
+					lobby.StartCountdown(60, func(uuid.UUID) {})
+				}
+				lobby.ReadyMap()
+				lobby.WhoIsNotReady()
+				lobby.MarkUserUnready(id)
+				lobby.RemoveUser(id)
+				lobby.BroadcastLeave(id)
+			}
+		}()
+	}
+	wg.Wait()
+	lobby.CancelCountdown()
+
+	if n := lobby.Occupancy(); n != 0 {
+		t.Fatalf("expected everyone to have left, got %d seated", n)
+	}
+	if len(lobby.ReadyMap()) != 0 {
+		t.Fatalf("expected no ready states left, got %v", lobby.ReadyMap())
+	}
+}
+
+func TestLobbyHeldBy(t *testing.T) {
+	host, guest := uuid.New(), uuid.New()
+	lobby := NewLobbyWithDefaults(host)
+	lobby.Type = "public"
+	store := NewLobbyStore()
+	store.AddLobby(lobby)
+
+	if store.LobbiesHeldBy(host) != 1 || store.LobbiesHeldBy(guest) != 0 {
+		t.Fatalf("expected a new lobby to count against its host only")
+	}
+	if err := lobby.AddConnection(guest, &LobbyConnection{UserID: guest}); err != nil {
+		t.Fatal(err)
+	}
+	if store.LobbiesHeldBy(guest) != 1 {
+		t.Fatalf("expected a seated guest to hold the lobby")
+	}
+	lobby.RemoveUser(guest)
+	if store.LobbiesHeldBy(guest) != 0 {
+		t.Fatalf("expected a guest who left not to hold the lobby")
+	}
+
+	// a host who never joins stops holding the lobby after lobbyHoldTime
+	lobby.CreatedAt = time.Now().Add(-lobbyHoldTime)
+	if lobby.HeldBy(host) {
+		t.Fatalf("expected an unjoined lobby to stop counting against its host")
+	}
+	if err := lobby.AddConnection(host, &LobbyConnection{UserID: host}); err != nil {
+		t.Fatal(err)
+	}
+	if !lobby.HeldBy(host) {
+		t.Fatalf("expected a seated host to hold the lobby")
+	}
+}
+
+func TestLobbyUpdateSettings(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	if err := lobby.UpdateSettings(func() error {
+		lobby.HouseRules.Jokers = 4
+		return nil
+	}); err != nil || lobby.HouseRules.Jokers != 4 {
+		t.Fatalf("expected the settings to be updated, got %+v, %v", lobby.HouseRules, err)
+	}
+
+	lobby.SetInGame(true)
+	ran := false
+	if err := lobby.UpdateSettings(func() error {
+		ran = true
+		return nil
+	}); !errors.Is(err, ErrInGame) || ran {
+		t.Fatalf("expected settings not to change mid-game, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrSignalUnavailable is returned by RelaySignal when the recipient can't be reached: they aren't
+// connected to the lobby, or they've blocked the sender. The two aren't told apart, so a block stays private.
+var ErrSignalUnavailable = errors.New("user is not available for voice")
+
+// RelaySignal forwards a WebRTC signaling message (an SDP offer or answer, or ICE candidates) from one
+// lobby member to another, as
+//
+//	{"type": "rtc_signal", "from": "{uuid}", "kind": "offer", "data": {...}}
+//
+// Signals only matter while the peers are negotiating, so they aren't numbered or kept for resync.
+func (lobby *Lobby) RelaySignal(from, to uuid.UUID, kind string, data json.RawMessage) error {
+	if from == to {
+		return errors.New("cannot signal yourself")
+	}
+	conns := lobby.Connected()
+	if _, ok := conns[from]; !ok {
+		return ErrSignalUnavailable
+	}
+	conn, ok := conns[to]
+	if !ok || conn.HasBlocked(from) {
+		return ErrSignalUnavailable
+	}
+	conn.Write(map[string]interface{}{
+		"type": "rtc_signal",
+		"from": from.String(),
+		"kind": kind,
+		"data": data,
+	})
+	return nil
+}
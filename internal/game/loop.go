@@ -0,0 +1,116 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jason-s-yu/cambia/internal/crash"
+)
+
+// Event loop. Everything that changes a game — player actions, timer and bot callbacks, connects and
+// disconnects — runs as a command on the game's own goroutine, one at a time and in the order submitted,
+// so the game's logic never runs concurrently with itself. Commands run with g.Mu held, which lets
+// read-only accessors such as Summary and PublicView take a consistent look from other goroutines.
+//
+// The loop goroutine is started by the first command and exits once the game is over and the queue has
+// drained; a later command starts it again.
+type gameLoop struct {
+	mu      sync.Mutex
+	queue   []func()
+	wake    chan struct{}
+	running bool
+	// outbox holds the frames sent by the running command, written once it's done; see Send.
+	outbox    []Delivery
+	inCommand bool
+	// over is whether the game had ended as of the last command.
+	over atomic.Bool
+}
+
+// Do runs fn on the game's loop and waits for it to finish. Handlers use it for multi-step changes to the
+// game, e.g. a card ability. It must not be called from a command already running on the loop, such as a
+// callback like OnGameEnd, since the loop would wait on itself.
+func (g *CambiaGame) Do(fn func()) {
+	done := make(chan struct{})
+	g.post(func() {
+		defer close(done)
+		defer g.flushOutbox()
+		fn()
+	})
+	<-done
+}
+
+// post queues fn to run on the game's loop without waiting for it, e.g. from a timer.
+func (g *CambiaGame) post(fn func()) {
+	l := &g.loop
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queue = append(l.queue, fn)
+	if l.wake == nil {
+		l.wake = make(chan struct{}, 1)
+	}
+	if !l.running {
+		l.running = true
+		go g.runLoop()
+		return
+	}
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runLoop runs queued commands until the game is over and there are none left.
+func (g *CambiaGame) runLoop() {
+	l := &g.loop
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			if l.over.Load() {
+				l.running = false
+				l.mu.Unlock()
+				return
+			}
+			l.mu.Unlock()
+			<-l.wake
+			continue
+		}
+		fn := l.queue[0]
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+		l.mu.Unlock()
+		g.runCommand(fn)
+	}
+}
+
+// runCommand runs one command with g.Mu held. Commands that change the game defer HaltOnPanic themselves;
+// this only keeps any other panic from taking the loop, and the server, down with it.
+func (g *CambiaGame) runCommand(fn func()) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	defer func() { g.loop.over.Store(g.GameOver) }()
+	defer g.flushOutbox()
+	defer crash.Recover(fmt.Sprintf("event loop of game %v", g.ID))
+	g.loop.inCommand = true
+	fn()
+}
+
+// Send writes frames to the game's players. Frames sent by a command on the loop are held until the
+// command is done and then written together, so that a burst of events from one action, e.g. an ability
+// or the end-of-round reveal, goes out in as few frames as the recipients accept; see WriteAll. Outside a
+// command they're written straight away. Callers must hold g.Mu.
+func (g *CambiaGame) Send(ds []Delivery) {
+	if !g.loop.inCommand {
+		WriteAll(ds)
+		return
+	}
+	g.loop.outbox = append(g.loop.outbox, ds...)
+}
+
+// flushOutbox writes the frames held by Send and ends the command's batch. Callers must hold g.Mu.
+func (g *CambiaGame) flushOutbox() {
+	g.loop.inCommand = false
+	ds := g.loop.outbox
+	g.loop.outbox = nil
+	WriteAll(ds)
+}
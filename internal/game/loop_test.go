@@ -0,0 +1,47 @@
+package game
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDoRunsCommandsOneAtATime(t *testing.T) {
+	g := NewCambiaGame()
+	running, max, total := 0, 0, 0
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Do(func() {
+				running++
+				if running > max {
+					max = running
+				}
+				total++
+				running--
+			})
+		}()
+	}
+	wg.Wait()
+	if max != 1 || total != 50 {
+		t.Fatalf("expected 50 commands run one at a time, got %d with up to %d at once", total, max)
+	}
+}
+
+func TestLoopRestartsAfterGameOver(t *testing.T) {
+	g := NewCambiaGame()
+	g.Do(func() { g.GameOver = true })
+	ran := false
+	g.Do(func() { ran = true })
+	if !ran {
+		t.Fatal("expected a command after the game ended to run")
+	}
+	// a panicking command doesn't stop the loop
+	g.Do(func() { panic("boom") })
+	ran = false
+	g.Do(func() { ran = true })
+	if !ran {
+		t.Fatal("expected the loop to survive a panic")
+	}
+}
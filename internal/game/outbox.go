@@ -0,0 +1,166 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Slow consumers. A client that reads slower than the server writes must not hold up the lobby or game
+// sending to it, so writes never block for long:
+//
+//   - Lobby messages are queued on the connection's OutChan. When it's full, state updates that a later
+//     one of the same kind supersedes (see coalesceKey) are coalesced, keeping only the latest; other
+//     frames are dropped, and the client can get them back with "resync_from" once it notices the gap in
+//     "seq". After outboxDropLimit consecutive drops the client is disconnected with StatusTooSlow.
+//   - Game and spectator frames are written directly, and a client that can't take one within
+//     SocketWriteTimeout is disconnected. Players can resync their events after reconnecting.
+const (
+	outboxDropLimit    = 32
+	SocketWriteTimeout = 5 * time.Second
+)
+
+// StatusTooSlow is the close code sent, when possible, to a client disconnected for not keeping up.
+const StatusTooSlow websocket.StatusCode = 4008
+
+// SlowConsumerStats counts backpressure events since the server started.
+type SlowConsumerStats struct {
+	Coalesced    int64 `json:"coalesced"`
+	Dropped      int64 `json:"dropped"`
+	Disconnected int64 `json:"disconnected"`
+}
+
+var slowConsumers struct {
+	coalesced, dropped, disconnected atomic.Int64
+}
+
+// SlowConsumerMetrics returns the backpressure counters, e.g. for the admin metrics endpoint.
+func SlowConsumerMetrics() SlowConsumerStats {
+	return SlowConsumerStats{
+		Coalesced:    slowConsumers.coalesced.Load(),
+		Dropped:      slowConsumers.dropped.Load(),
+		Disconnected: slowConsumers.disconnected.Load(),
+	}
+}
+
+// RecordDroppedFrame counts a frame dropped because a socket's outbox was full. Sockets without a
+// LobbyConnection, like /user/ws, call it themselves.
+func RecordDroppedFrame() {
+	slowConsumers.dropped.Add(1)
+}
+
+// coalesceKey returns the key under which a lobby message supersedes earlier ones, if it does. Each of
+// these carries the full current value of what it describes.
+func coalesceKey(msg map[string]interface{}) (string, bool) {
+	typ, _ := msg["type"].(string)
+	switch typ {
+	case "lobby_update", "lobby_settings", "circuit_standings", "series_scoreboard":
+		return typ, true
+	case "chat_reactions":
+		id, _ := msg["msg_id"].(string)
+		return typ + ":" + id, true
+	}
+	return "", false
+}
+
+// StampRequestID adds "request_id" to an error frame, so a client reporting the error can quote the ID the
+// server logged its socket under. Other frames are returned as they are. The frame is copied rather than
+// modified, since a broadcast shares one frame between recipients.
+func StampRequestID(msg map[string]interface{}, requestID string) map[string]interface{} {
+	if requestID == "" || msg["type"] != "error" {
+		return msg
+	}
+	if _, ok := msg["request_id"]; ok {
+		return msg
+	}
+	stamped := make(map[string]interface{}, len(msg)+1)
+	for k, v := range msg {
+		stamped[k] = v
+	}
+	stamped["request_id"] = requestID
+	return stamped
+}
+
+// Write queues a message for the user without blocking, applying the slow-consumer policy above when the
+// outbox is full.
+func (conn *LobbyConnection) Write(msg map[string]interface{}) {
+	msg = StampRequestID(msg, conn.RequestID)
+	select {
+	case conn.OutChan <- msg:
+		conn.drops.Store(0)
+		return
+	default:
+	}
+
+	if key, ok := coalesceKey(msg); ok {
+		conn.pendingMu.Lock()
+		if conn.pending == nil {
+			conn.pending = make(map[string]map[string]interface{})
+		}
+		if _, queued := conn.pending[key]; !queued {
+			conn.pendingOrder = append(conn.pendingOrder, key)
+		}
+		conn.pending[key] = msg
+		conn.pendingMu.Unlock()
+		slowConsumers.coalesced.Add(1)
+		select {
+		case conn.flushChan() <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	RecordDroppedFrame()
+	if conn.drops.Add(1) == outboxDropLimit && conn.tooSlow.CompareAndSwap(false, true) {
+		slowConsumers.disconnected.Add(1)
+		if conn.Cancel != nil {
+			conn.Cancel()
+		}
+	}
+}
+
+// Coalesced returns a channel that's signaled when a coalesced message is waiting; see TakeCoalesced.
+func (conn *LobbyConnection) Coalesced() <-chan struct{} {
+	return conn.flushChan()
+}
+
+func (conn *LobbyConnection) flushChan() chan struct{} {
+	conn.flushOnce.Do(func() { conn.flush = make(chan struct{}, 1) })
+	return conn.flush
+}
+
+// TakeCoalesced returns and clears the coalesced messages, in the order they were first queued. The write
+// pump sends them once it has drained OutChan.
+func (conn *LobbyConnection) TakeCoalesced() []map[string]interface{} {
+	conn.pendingMu.Lock()
+	defer conn.pendingMu.Unlock()
+	msgs := make([]map[string]interface{}, 0, len(conn.pendingOrder))
+	for _, key := range conn.pendingOrder {
+		msgs = append(msgs, conn.pending[key])
+	}
+	conn.pending, conn.pendingOrder = nil, nil
+	return msgs
+}
+
+// TooSlow reports whether the connection was cancelled for falling too far behind, in which case it
+// should be closed with StatusTooSlow.
+func (conn *LobbyConnection) TooSlow() bool {
+	return conn.tooSlow.Load()
+}
+
+// WriteFrame writes a frame to a game or spectator socket, giving up after SocketWriteTimeout. A client
+// that times out is disconnected with StatusTooSlow; the websocket library has already torn the
+// connection down by then, so the close frame is best effort and sent in the background.
+func WriteFrame(conn *websocket.Conn, typ websocket.MessageType, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), SocketWriteTimeout)
+	defer cancel()
+	err := conn.Write(ctx, typ, data)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slowConsumers.disconnected.Add(1)
+		go conn.Close(StatusTooSlow, "too slow")
+	}
+	return err
+}
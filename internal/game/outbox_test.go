@@ -0,0 +1,43 @@
+package game
+
+import "testing"
+
+func TestLobbyConnectionBackpressure(t *testing.T) {
+	cancelled := false
+	conn := &LobbyConnection{OutChan: make(chan map[string]interface{}, 1), Cancel: func() { cancelled = true }}
+
+	conn.Write(map[string]interface{}{"type": "chat"})
+	conn.Write(map[string]interface{}{"type": "lobby_update", "n": 1})
+	conn.Write(map[string]interface{}{"type": "lobby_update", "n": 2})
+	if pending := conn.TakeCoalesced(); len(pending) != 1 || pending[0]["n"] != 2 {
+		t.Fatalf("expected only the latest lobby_update to be kept, got %v", pending)
+	}
+
+	for i := 0; i < outboxDropLimit-1; i++ {
+		conn.Write(map[string]interface{}{"type": "chat"})
+	}
+	if cancelled || conn.TooSlow() {
+		t.Fatalf("expected the connection to survive %d drops", outboxDropLimit-1)
+	}
+	conn.Write(map[string]interface{}{"type": "chat"})
+	if !cancelled || !conn.TooSlow() {
+		t.Fatalf("expected the connection to be cancelled after %d drops", outboxDropLimit)
+	}
+}
+
+func TestStampRequestID(t *testing.T) {
+	conn := &LobbyConnection{OutChan: make(chan map[string]interface{}, 2), RequestID: "abc-123"}
+	frame := map[string]interface{}{"type": "error", "message": "nope"}
+	conn.Write(frame)
+	conn.Write(map[string]interface{}{"type": "chat"})
+
+	if got := <-conn.OutChan; got["request_id"] != "abc-123" {
+		t.Fatalf("expected the error frame to carry the request ID, got %v", got)
+	}
+	if _, ok := frame["request_id"]; ok {
+		t.Fatalf("expected the shared frame to be left alone")
+	}
+	if got := <-conn.OutChan; got["request_id"] != nil {
+		t.Fatalf("expected other frames to be left alone, got %v", got)
+	}
+}
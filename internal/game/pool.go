@@ -0,0 +1,61 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/coder/websocket"
+)
+
+// Scratch buffers. The socket hot paths, reading a client's frame and marshaling a broadcast, only need a
+// buffer until its bytes have been decoded or copied out, so they borrow one from a shared pool instead of
+// allocating a new one for every message.
+
+// maxPooledBuffer is the largest buffer put back in the pool; one grown past it by an unusually large
+// message is left to the GC rather than held on to.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// GetBuffer returns an empty buffer from the pool.
+func GetBuffer() *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	return b
+}
+
+// PutBuffer returns a buffer to the pool. Nothing may hold on to its bytes afterwards.
+func PutBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(b)
+}
+
+// ReadFrame waits for the next message on conn and reads it into a buffer from the pool, which the caller
+// puts back once the message is decoded. The buffer is only taken once a message arrives, so idle sockets
+// don't hold one. Like conn.Read, it's bound by the socket's read limit.
+func ReadFrame(ctx context.Context, conn *websocket.Conn) (websocket.MessageType, *bytes.Buffer, error) {
+	typ, r, err := conn.Reader(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	buf := GetBuffer()
+	if _, err := buf.ReadFrom(r); err != nil {
+		PutBuffer(buf)
+		return 0, nil, err
+	}
+	return typ, buf, nil
+}
+
+// AppendJSON marshals v into buf, as json.Marshal would.
+func AppendJSON(buf *bytes.Buffer, v interface{}) error {
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	// Encode ends the value with a newline
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
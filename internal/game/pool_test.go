@@ -0,0 +1,78 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestAppendJSONMatchesMarshal(t *testing.T) {
+	ev := GameEvent{Type: EventChat, UserID: uuid.New(), Other: map[string]interface{}{"msg": "<b>gg</b> & more"}}
+	want, _ := json.Marshal(ev)
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	buf.WriteString("stale")
+	buf.Reset()
+	if err := AppendJSON(buf, ev); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("expected %s, got %s", want, buf.Bytes())
+	}
+}
+
+func TestPutBufferDropsLargeBuffers(t *testing.T) {
+	big := bytes.NewBuffer(make([]byte, 0, maxPooledBuffer+1))
+	PutBuffer(big)
+	for range 8 {
+		if GetBuffer() == big {
+			t.Fatal("expected an oversized buffer to be left out of the pool")
+		}
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	frames := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.CloseNow()
+		typ, buf, err := ReadFrame(r.Context(), c)
+		if err != nil || typ != websocket.MessageText {
+			t.Errorf("expected a text frame, got %v, %v", typ, err)
+			return
+		}
+		frames <- bytes.Clone(buf.Bytes())
+		PutBuffer(buf)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, _, err := websocket.Dial(ctx, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseNow()
+
+	msg, _ := json.Marshal(GameEvent{Type: EventPlayerDiscard, Card: &models.Card{Rank: "7"}})
+	client.Write(ctx, websocket.MessageText, msg)
+	select {
+	case got := <-frames:
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("expected %s, got %s", msg, got)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the frame")
+	}
+}
@@ -0,0 +1,67 @@
+package game
+
+// RulePreset is a built-in bundle of house rules that hosts can create a lobby from.
+type RulePreset struct {
+	Key        string     `json:"key"`
+	Name       string     `json:"name"`
+	HouseRules HouseRules `json:"houseRules"`
+}
+
+// RulePresets lists the built-in presets. "Official Circuit" is the ranked circuit profile, so lobbies
+// created from it can be ranked.
+var RulePresets = []RulePreset{
+	{
+		Key:  "official_circuit",
+		Name: "Official Circuit",
+		HouseRules: HouseRules{
+			AllowDrawFromDiscardPile: true,
+			AllowReplaceAbilities:    true,
+			SnapRace:                 true,
+			ForfeitOnDisconnect:      true,
+			PenaltyDrawCount:         1,
+			AutoKickTurnCount:        3,
+			TurnTimerSec:             15,
+			Jokers:                   2,
+		},
+	},
+	{
+		Key:  "casual",
+		Name: "Casual",
+		HouseRules: HouseRules{
+			AllowDrawFromDiscardPile: true,
+			AllowReplaceAbilities:    true,
+			SnapRace:                 false,
+			ForfeitOnDisconnect:      false,
+			PenaltyDrawCount:         1,
+			AutoKickTurnCount:        5,
+			TurnTimerSec:             30,
+			Jokers:                   2,
+			Speed:                    SpeedCasual,
+		},
+	},
+	{
+		Key:  "blitz",
+		Name: "Blitz",
+		HouseRules: HouseRules{
+			AllowDrawFromDiscardPile: false,
+			AllowReplaceAbilities:    false,
+			SnapRace:                 true,
+			ForfeitOnDisconnect:      true,
+			PenaltyDrawCount:         2,
+			AutoKickTurnCount:        2,
+			TurnTimerSec:             5,
+			Jokers:                   2,
+			Speed:                    SpeedBlitz,
+		},
+	},
+}
+
+// FindRulePreset returns the built-in preset with the given key.
+func FindRulePreset(key string) (RulePreset, bool) {
+	for _, p := range RulePresets {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return RulePreset{}, false
+}
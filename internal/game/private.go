@@ -0,0 +1,92 @@
+package game
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Private reports whether the event is meant only for the player it's about, i.e. it's a private_* event.
+// Private events are delivered to that player alone, and never to spectators or the action log.
+func (ev GameEvent) Private() bool {
+	return strings.HasPrefix(string(ev.Type), "private_")
+}
+
+// route restricts a private event to the player it's about, or the connection playing their seat (see
+// routeShared), and records the card faces an event shows
+// in its recipients' knowledge. Callers must hold g.Mu.
+func (g *CambiaGame) route(ev GameEvent) GameEvent {
+	if !ev.Private() {
+		for _, p := range g.Players {
+			g.learn(p.ID, ev.Card, ev.Card2)
+		}
+		return ev
+	}
+	ev.Hidden = make(map[uuid.UUID]bool, len(g.Players))
+	for _, p := range g.Players {
+		if p.ID != ev.UserID {
+			ev.Hidden[p.ID] = true
+		}
+	}
+	g.learn(ev.UserID, ev.Card, ev.Card2)
+	return g.routeShared(ev)
+}
+
+// learn records that playerID has seen the faces of cards. Cards shown by ID only are skipped. Callers
+// must hold g.Mu.
+func (g *CambiaGame) learn(playerID uuid.UUID, cards ...*models.Card) {
+	for _, c := range cards {
+		if c == nil || c.Rank == "" {
+			continue
+		}
+		if g.known[playerID] == nil {
+			g.known[playerID] = make(map[uuid.UUID]bool)
+		}
+		g.known[playerID][c.ID] = true
+	}
+}
+
+// PlayerGameView is a player's own view of the game: the public view plus the faces of the cards in
+// play they've seen, wherever those cards are now, and the card they drew, if any.
+type PlayerGameView struct {
+	PublicGameView
+	Known     []*models.Card `json:"known"`
+	DrawnCard *models.Card   `json:"drawnCard,omitempty"`
+}
+
+// playerView builds userID's view of the game. Callers must hold g.Mu.
+func (g *CambiaGame) playerView(userID uuid.UUID) PlayerGameView {
+	view := PlayerGameView{PublicGameView: g.publicView(), Known: []*models.Card{}}
+	view.Type = "game_player_snapshot"
+	for _, p := range g.Players {
+		for _, c := range p.Hand {
+			if g.known[userID][c.ID] {
+				view.Known = append(view.Known, c)
+			}
+		}
+		if p.ID == userID {
+			view.DrawnCard = p.DrawnCard
+		}
+	}
+	return view
+}
+
+// PlayerView returns userID's own view of the game, e.g. to bring them back up to date on resync.
+func (g *CambiaGame) PlayerView(userID uuid.UUID) PlayerGameView {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	return g.playerView(userID)
+}
+
+// PlayerSnapshot returns userID's own view of the game together with the sequence number of the last event
+// delivered to them, so a new connection can carry on from the snapshot, e.g. after a device handoff.
+func (g *CambiaGame) PlayerSnapshot(userID uuid.UUID) (PlayerGameView, uint64) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	var seq uint64
+	if el, ok := g.eventLogs[userID]; ok {
+		seq = el.Latest()
+	}
+	return g.playerView(userID), seq
+}
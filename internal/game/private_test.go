@@ -0,0 +1,71 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestPrivateEvents(t *testing.T) {
+	g := NewCambiaGame()
+	p1 := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "K", Suit: "H", Value: -1}}}
+	p2 := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "3", Suit: "S", Value: 3}}}
+	g.Players = []*models.Player{p1, p2}
+	drawn := &models.Card{ID: uuid.New(), Rank: "9", Suit: "C", Value: 9}
+	g.Deck = []*models.Card{drawn}
+	received := map[uuid.UUID][]GameEvent{}
+	g.BroadcastFn = func(ev GameEvent) {
+		for _, p := range g.Players {
+			if !ev.Hidden[p.ID] {
+				received[p.ID] = append(received[p.ID], ev)
+			}
+		}
+	}
+
+	g.HandlePlayerAction(p1.ID, models.GameAction{ActionType: "action_draw_stockpile"})
+	for _, ev := range received[p2.ID] {
+		if ev.Private() {
+			t.Fatalf("expected the opponent not to get %s", ev.Type)
+		}
+	}
+	g.HandlePlayerAction(p1.ID, models.GameAction{ActionType: "action_replace", Payload: map[string]interface{}{"idx": float64(0)}})
+	for _, ev := range received[p2.ID] {
+		if ev.Card != nil && ev.Card.ID == drawn.ID && ev.Card.Rank != "" {
+			t.Fatalf("expected the drawn card's face to stay hidden from the opponent, got %+v", ev)
+		}
+	}
+
+	if known := g.PlayerView(p1.ID).Known; len(known) != 1 || known[0] != drawn {
+		t.Fatalf("expected the drawer to know the card they kept, got %+v", known)
+	}
+	if known := g.PlayerView(p2.ID).Known; len(known) != 0 {
+		t.Fatalf("expected the opponent to know no cards in play, got %+v", known)
+	}
+	if restored := RestoreGame(g.checkpoint()); !restored.known[p1.ID][drawn.ID] {
+		t.Fatalf("expected knowledge to survive a restart")
+	}
+}
+
+func TestBlindSnapFlagged(t *testing.T) {
+	g := NewCambiaGame()
+	seen := &models.Card{ID: uuid.New(), Rank: "5", Suit: "H", Value: 5}
+	blind1 := &models.Card{ID: uuid.New(), Rank: "5", Suit: "S", Value: 5}
+	blind2 := &models.Card{ID: uuid.New(), Rank: "5", Suit: "C", Value: 5}
+	p := &models.Player{ID: uuid.New(), Hand: []*models.Card{seen, blind1, blind2}}
+	g.Players = []*models.Player{p}
+	g.DiscardPile = []*models.Card{{ID: uuid.New(), Rank: "5", Suit: "D", Value: 5}}
+	g.learn(p.ID, seen)
+	var flagged []Anomaly
+	g.OnAnomaly = func(a Anomaly) { flagged = append(flagged, a) }
+
+	for _, c := range []*models.Card{seen, blind1, blind2} {
+		g.HandlePlayerAction(p.ID, models.GameAction{ActionType: "action_snap", Payload: map[string]interface{}{"id": c.ID.String()}})
+	}
+	if len(p.Hand) != 0 {
+		t.Fatalf("expected every snap to succeed, %d cards left", len(p.Hand))
+	}
+	if len(flagged) != 1 || flagged[0].Kind != AnomalyBlindSnap || flagged[0].Detail["cardID"] != blind2.ID {
+		t.Fatalf("expected only the second blind snap to be flagged, got %+v", flagged)
+	}
+}
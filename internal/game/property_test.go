@@ -0,0 +1,104 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// maxFuzzMoves bounds the moves read from a fuzzed script.
+const maxFuzzMoves = 400
+
+// fuzzMove turns two bytes of a script into a move, legal or not, by any player. Most moves are by the
+// current player; one in four is by a player picked by the byte, out of turn or not.
+func fuzzMove(g *CambiaGame, op, arg byte) (uuid.UUID, models.GameAction, bool) {
+	p := g.Players[g.CurrentPlayerIndex]
+	if op%4 == 0 {
+		p = g.Players[int(arg)%len(g.Players)]
+	}
+	other := g.Players[(int(arg)+1)%len(g.Players)]
+	// pickCard returns a card of the player's, someone else's, or one that isn't in the game
+	pickCard := func() string {
+		switch {
+		case arg%4 == 0 && p.DrawnCard != nil:
+			return p.DrawnCard.ID.String()
+		case arg%4 == 1 && len(p.Hand) > 0:
+			return p.Hand[int(arg/4)%len(p.Hand)].ID.String()
+		case arg%4 == 2 && len(other.Hand) > 0:
+			return other.Hand[int(arg/4)%len(other.Hand)].ID.String()
+		}
+		return uuid.NewString()
+	}
+
+	switch (op / 4) % 8 {
+	case 0:
+		return p.ID, simAction("action_draw_stockpile"), true
+	case 1:
+		return p.ID, simAction("action_draw_discardpile"), true
+	case 2:
+		return p.ID, simAction("action_discard", "id", pickCard()), true
+	case 3:
+		return p.ID, simAction("action_replace", "idx", float64(int(arg%7)-1)), true
+	case 4:
+		return p.ID, simAction("action_snap", "id", pickCard()), true
+	case 5:
+		return p.ID, simAction("action_cambia"), true
+	case 6:
+		// abilities are skipped, as by a "skip" step on the socket
+		if g.SpecialAction.Active && g.SpecialAction.PlayerID == p.ID {
+			g.SpecialAction = SpecialActionState{}
+			g.advanceTurn()
+		}
+	default:
+		g.handleTimeout(g.Players[g.CurrentPlayerIndex].ID)
+	}
+	return uuid.Nil, models.GameAction{}, false
+}
+
+// FuzzGameActions plays a script of arbitrary moves, legal or not and in turn or not, then finishes the
+// game. However it plays out, no card may be lost or duplicated, turns must go round in order, the current
+// player must be seated, and the final scores must add up.
+func FuzzGameActions(f *testing.F) {
+	f.Add(int64(1), uint8(0), uint8(0), []byte{0x00, 0x00, 0x08, 0x01})
+	f.Add(int64(2), uint8(1), uint8(3), []byte{0x10, 0x05, 0x04, 0x02, 0x11, 0x01, 0x14, 0x00})
+	f.Add(int64(3), uint8(2), uint8(5), []byte{0x00, 0x00, 0x0c, 0x03, 0x18, 0x00, 0x00, 0x00, 0x1c, 0x00})
+	f.Add(int64(4), uint8(1), uint8(6), []byte{0x00, 0x00, 0x14, 0x00, 0x10, 0x01, 0x10, 0x02, 0x1d, 0x00})
+	f.Fuzz(func(t *testing.T, seed int64, seats, ruleBits uint8, script []byte) {
+		rules := DefaultHouseRules()
+		rules.AllowDrawFromDiscardPile = ruleBits&1 != 0
+		if ruleBits&2 != 0 {
+			rules.StockpileEmpty = StockpileEndRound
+		}
+		rules.PenaltyDrawCount = int(ruleBits>>2)%3 + 1
+		strategies := make([]simStrategy, int(seats)%3+2)
+		for i := range strategies {
+			strategies[i] = drawDiscard
+		}
+		s := newSimulation(t, seed, rules, strategies...)
+		s.start()
+
+		for i := 0; i+1 < len(script) && i < 2*maxFuzzMoves; i += 2 {
+			over := false
+			s.g.Do(func() {
+				if over = s.g.GameOver; over {
+					return
+				}
+				if playerID, act, ok := fuzzMove(s.g, script[i], script[i+1]); ok {
+					s.g.handlePlayerAction(playerID, act)
+				}
+				if !s.g.GameOver && (s.g.CurrentPlayerIndex < 0 || s.g.CurrentPlayerIndex >= len(s.g.Players)) {
+					t.Errorf("current player index %d is out of range", s.g.CurrentPlayerIndex)
+				}
+			})
+			s.checkCards()
+			if t.Failed() {
+				t.FailNow()
+			}
+			if over {
+				break
+			}
+		}
+		s.finish()
+	})
+}
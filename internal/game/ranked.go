@@ -0,0 +1,67 @@
+package game
+
+import "fmt"
+
+// RankedProfile is a server-approved bundle of house rules. Ranked lobbies must match one of these
+// exactly, so that the rating ladder reflects a consistent rule set.
+type RankedProfile struct {
+	Name       string     `json:"name"`
+	HouseRules HouseRules `json:"houseRules"`
+}
+
+// RankedProfiles lists the house rule profiles that are legal for ranked play.
+// "standard" mirrors the default lobby rules; "circuit" mirrors the default circuit rules.
+var RankedProfiles = []RankedProfile{
+	{
+		Name: "standard",
+		HouseRules: HouseRules{
+			AllowDrawFromDiscardPile: false,
+			AllowReplaceAbilities:    false,
+			SnapRace:                 false,
+			ForfeitOnDisconnect:      true,
+			PenaltyDrawCount:         1,
+			AutoKickTurnCount:        3,
+			TurnTimerSec:             15,
+			Jokers:                   2,
+		},
+	},
+	{
+		Name: "circuit",
+		HouseRules: HouseRules{
+			AllowDrawFromDiscardPile: true,
+			AllowReplaceAbilities:    true,
+			SnapRace:                 true,
+			ForfeitOnDisconnect:      true,
+			PenaltyDrawCount:         1,
+			AutoKickTurnCount:        3,
+			TurnTimerSec:             15,
+			Jokers:                   2,
+		},
+	},
+}
+
+// MatchRankedProfile returns the name of the ranked profile the rules match, or an error if the rules
+// are not ranked-legal.
+func MatchRankedProfile(rules HouseRules) (string, error) {
+	for _, p := range RankedProfiles {
+		if p.HouseRules == rules {
+			return p.Name, nil
+		}
+	}
+	return "", fmt.Errorf("house rules do not match any ranked profile")
+}
+
+// ValidateRanked checks that a lobby flagged as ranked uses an approved house rule profile.
+// Unranked lobbies are always valid.
+func (lobby *Lobby) ValidateRanked() error {
+	if !lobby.Ranked {
+		return nil
+	}
+	if lobby.GameMode == "custom" {
+		return fmt.Errorf("custom game mode cannot be ranked")
+	}
+	if _, err := MatchRankedProfile(lobby.HouseRules); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/crash"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Checkpoint is the full state of a game at the start of a turn. One is saved every turn so a game cut off
+// by a crash or restart can be restored and played on; whatever happened during the turn in progress is
+// lost.
+type Checkpoint struct {
+	ID            uuid.UUID  `json:"id"`
+	LobbyID       uuid.UUID  `json:"lobbyID"`
+	HouseRules    HouseRules `json:"houseRules"`
+	Ranked        bool       `json:"ranked"`
+	Tournament    bool       `json:"tournament"`
+	ChatDisabled  bool       `json:"chatDisabled"`
+	CircuitRound  int        `json:"circuitRound"`
+	EngineVersion string     `json:"engineVersion"`
+	RulesRevision int        `json:"rulesRevision"`
+
+	Players            []CheckpointPlayer          `json:"players"`
+	Deck               []*models.Card              `json:"deck"`
+	DiscardPile        []*models.Card              `json:"discardPile"`
+	CurrentPlayerIndex int                         `json:"currentPlayerIndex"`
+	StartedAt          time.Time                   `json:"startedAt"`
+	TurnID             int                         `json:"turn"`
+	TurnDuration       time.Duration               `json:"turnDuration"`
+	TimeBanks          map[uuid.UUID]time.Duration `json:"timeBanks,omitempty"`
+	ShuffleSeed        []byte                      `json:"shuffleSeed"`
+	ShuffleSalts       map[string][]byte           `json:"shuffleSalts"`
+	Reshuffles         int                         `json:"reshuffles"`
+	Actions            []models.GameAction         `json:"actions"`
+
+	ConsecutiveTimeouts map[uuid.UUID]int `json:"consecutiveTimeouts"`
+	CambiaCalled        bool              `json:"cambiaCalled"`
+	CambiaCallerID      uuid.UUID         `json:"cambiaCaller"`
+	CambiaFinalCounter  int               `json:"cambiaFinalCounter"`
+}
+
+// CheckpointPlayer is a player's part of a Checkpoint.
+type CheckpointPlayer struct {
+	ID              uuid.UUID      `json:"id"`
+	Hand            []*models.Card `json:"hand"`
+	HasCalledCambia bool           `json:"hasCalledCambia"`
+	Known           []uuid.UUID    `json:"known,omitempty"` // the cards whose face the player has seen
+	Team            int            `json:"team,omitempty"`  // the player's team, in a team game
+}
+
+// checkpoint captures the game's state. Slices are copied so it can be encoded without holding g.Mu.
+// Callers must hold g.Mu.
+func (g *CambiaGame) checkpoint() Checkpoint {
+	cp := Checkpoint{
+		ID:                  g.ID,
+		LobbyID:             g.LobbyID,
+		HouseRules:          g.HouseRules,
+		Ranked:              g.Ranked,
+		Tournament:          g.Tournament,
+		ChatDisabled:        g.ChatDisabled,
+		CircuitRound:        g.CircuitRound,
+		EngineVersion:       g.EngineVersion,
+		RulesRevision:       g.RulesRevision,
+		Deck:                append([]*models.Card(nil), g.Deck...),
+		DiscardPile:         append([]*models.Card(nil), g.DiscardPile...),
+		CurrentPlayerIndex:  g.CurrentPlayerIndex,
+		StartedAt:           g.StartedAt,
+		TurnID:              g.TurnID,
+		TurnDuration:        g.TurnDuration,
+		TimeBanks:           make(map[uuid.UUID]time.Duration, len(g.timeBanks)),
+		ShuffleSeed:         g.shuffleSeed,
+		ShuffleSalts:        g.shuffleSalts,
+		Reshuffles:          g.reshuffles,
+		Actions:             append([]models.GameAction(nil), g.Actions...),
+		ConsecutiveTimeouts: make(map[uuid.UUID]int, len(g.consecutiveTimeouts)),
+		CambiaCalled:        g.CambiaCalled,
+		CambiaCallerID:      g.CambiaCallerID,
+		CambiaFinalCounter:  g.CambiaFinalCounter,
+	}
+	for _, p := range g.Players {
+		cpp := CheckpointPlayer{
+			ID:              p.ID,
+			Hand:            append([]*models.Card(nil), p.Hand...),
+			HasCalledCambia: p.HasCalledCambia,
+			Team:            g.Teams[p.ID],
+		}
+		for id := range g.known[p.ID] {
+			cpp.Known = append(cpp.Known, id)
+		}
+		cp.Players = append(cp.Players, cpp)
+	}
+	for id, n := range g.consecutiveTimeouts {
+		cp.ConsecutiveTimeouts[id] = n
+	}
+	for id := range g.timeBanks {
+		cp.TimeBanks[id] = g.timeBankLeft(id)
+	}
+	return cp
+}
+
+// saveCheckpoint stores the game's state in the background, unless it's a practice game. Callers must hold
+// g.Mu.
+func (g *CambiaGame) saveCheckpoint() {
+	if g.Practice {
+		return
+	}
+	cp := g.checkpoint()
+	go func() {
+		defer crash.Recover("saving game checkpoint")
+		data, err := json.Marshal(cp)
+		if err != nil {
+			log.Printf("Error encoding checkpoint of game %v: %v", cp.ID, err)
+			return
+		}
+		if err := database.Games.SaveGameCheckpoint(context.Background(), cp.ID, cp.TurnID, data); err != nil {
+			log.Printf("%v", err)
+		}
+	}()
+}
+
+// RestoreGame rebuilds a started game from its checkpoint. Its players start out disconnected and rejoin
+// through /game/ws; call Resume once the game's callbacks are set to restart the turn timer.
+func RestoreGame(cp Checkpoint) *CambiaGame {
+	g := &CambiaGame{
+		ID:                  cp.ID,
+		LobbyID:             cp.LobbyID,
+		HouseRules:          cp.HouseRules,
+		Ranked:              cp.Ranked,
+		Tournament:          cp.Tournament,
+		ChatDisabled:        cp.ChatDisabled,
+		CircuitRound:        cp.CircuitRound,
+		EngineVersion:       cp.EngineVersion,
+		RulesRevision:       cp.RulesRevision,
+		Deck:                cp.Deck,
+		DiscardPile:         cp.DiscardPile,
+		CurrentPlayerIndex:  cp.CurrentPlayerIndex,
+		Started:             true,
+		StartedAt:           cp.StartedAt,
+		lastSeen:            make(map[uuid.UUID]time.Time),
+		offline:             make(map[uuid.UUID]bool),
+		abortVotes:          make(map[uuid.UUID]bool),
+		known:               make(map[uuid.UUID]map[uuid.UUID]bool),
+		blindSnaps:          make(map[uuid.UUID]int),
+		consecutiveTimeouts: cp.ConsecutiveTimeouts,
+		TurnID:              cp.TurnID,
+		TurnDuration:        cp.TurnDuration,
+		clock:               cp.HouseRules.Clock(),
+		timeBanks:           cp.TimeBanks,
+		shuffleSeed:         cp.ShuffleSeed,
+		shuffleSalts:        cp.ShuffleSalts,
+		reshuffles:          cp.Reshuffles,
+		Actions:             cp.Actions,
+		spectators:          make(map[uuid.UUID]*websocket.Conn),
+		eventLogs:           make(map[uuid.UUID]*EventLog[GameEvent]),
+		CambiaCalled:        cp.CambiaCalled,
+		CambiaCallerID:      cp.CambiaCallerID,
+		CambiaFinalCounter:  cp.CambiaFinalCounter,
+	}
+	if g.Deck == nil {
+		g.Deck = []*models.Card{}
+	}
+	if g.DiscardPile == nil {
+		g.DiscardPile = []*models.Card{}
+	}
+	if g.consecutiveTimeouts == nil {
+		g.consecutiveTimeouts = make(map[uuid.UUID]int)
+	}
+	if g.timeBanks == nil {
+		g.timeBanks = make(map[uuid.UUID]time.Duration)
+	}
+	for _, p := range cp.Players {
+		hand := p.Hand
+		if hand == nil {
+			hand = []*models.Card{}
+		}
+		g.Players = append(g.Players, &models.Player{ID: p.ID, Hand: hand, HasCalledCambia: p.HasCalledCambia})
+		if p.Team != 0 {
+			if g.Teams == nil {
+				g.Teams = make(map[uuid.UUID]int)
+			}
+			g.Teams[p.ID] = p.Team
+		}
+		for _, id := range p.Known {
+			if g.known[p.ID] == nil {
+				g.known[p.ID] = make(map[uuid.UUID]bool)
+			}
+			g.known[p.ID][id] = true
+		}
+	}
+	return g
+}
+
+// Resume restarts the turn timer of a restored game.
+func (g *CambiaGame) Resume() {
+	g.Do(func() {
+		if g.GameOver || len(g.Players) == 0 {
+			return
+		}
+		g.scheduleNextTurnTimer()
+	})
+}
+
+// HaltOnPanic, deferred at the start of a command on the game's loop, recovers a panic in the game's logic. The game's state
+// can't be trusted after one, so the game is stopped: players get a game_halted event and their sockets
+// are closed, and OnHalted tells the lobby. No result is recorded. The game's last checkpoint is kept, so
+// a restart brings it back from the start of the turn that failed.
+func (g *CambiaGame) HaltOnPanic(where string) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	crash.Log(fmt.Sprintf("%s of game %v", where, g.ID), v)
+	if g.GameOver {
+		return
+	}
+	g.halt("internal error", websocket.StatusInternalError)
+	if g.OnHalted != nil {
+		g.OnHalted(g.LobbyID)
+	}
+}
+
+// Abort stops the game without recording a result, as HaltOnPanic does, for an administrator ending a
+// stuck game. OnHalted isn't called; the caller tells the lobby. It reports whether the game was still
+// running.
+func (g *CambiaGame) Abort(reason string) (aborted bool) {
+	g.Do(func() {
+		if g.GameOver {
+			return
+		}
+		g.halt(reason, websocket.StatusNormalClosure)
+		aborted = true
+	})
+	return aborted
+}
+
+// halt stops the game, tells its players why, and closes their sockets with code. Callers must hold g.Mu.
+func (g *CambiaGame) halt(reason string, code websocket.StatusCode) {
+	g.GameOver = true
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+	}
+	func() {
+		// after a panic, the broadcast reads the same state that just failed
+		defer crash.Recover(fmt.Sprintf("halting game %v", g.ID))
+		g.fireEvent(GameEvent{Type: EventGameHalted, Other: map[string]interface{}{"reason": reason}})
+	}()
+	// the sockets are closed below, so what the command has sent so far can't wait for it to finish
+	g.flushOutbox()
+	for _, p := range g.Players {
+		if p.Conn != nil {
+			go p.Conn.Close(code, "game halted")
+		}
+	}
+}
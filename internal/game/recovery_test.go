@@ -0,0 +1,144 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	g := NewCambiaGame()
+	p1, p2 := uuid.New(), uuid.New()
+	g.Players = []*models.Player{
+		{ID: p1, Hand: []*models.Card{{ID: uuid.New(), Rank: "K", Suit: "H", Value: -1}}},
+		{ID: p2, Hand: []*models.Card{}, HasCalledCambia: true},
+	}
+	g.Deck = []*models.Card{{ID: uuid.New(), Rank: "7", Suit: "S", Value: 7}}
+	g.CurrentPlayerIndex = 1
+	g.TurnID = 9
+	g.CambiaCalled, g.CambiaCallerID = true, p2
+	g.consecutiveTimeouts[p1] = 2
+	g.Tournament, g.ChatDisabled = true, true
+
+	data, err := json.Marshal(g.checkpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	r := RestoreGame(cp)
+
+	if r.ID != g.ID || !r.Started || r.TurnID != 9 || r.CurrentPlayerIndex != 1 {
+		t.Fatalf("unexpected restored game %+v", r)
+	}
+	if len(r.Players) != 2 || r.Players[0].Connected || !r.Players[1].HasCalledCambia {
+		t.Fatalf("unexpected restored players %+v", r.Players)
+	}
+	if len(r.Players[0].Hand) != 1 || *r.Players[0].Hand[0] != *g.Players[0].Hand[0] {
+		t.Fatalf("expected the hand to survive, got %+v", r.Players[0].Hand)
+	}
+	if len(r.Deck) != 1 || r.DiscardPile == nil || r.consecutiveTimeouts[p1] != 2 {
+		t.Fatalf("unexpected restored piles or timeouts")
+	}
+	if !r.CambiaCalled || r.CambiaCallerID != p2 {
+		t.Fatalf("expected the cambia call to survive")
+	}
+	if !r.Tournament || !r.ChatDisabled {
+		t.Fatalf("expected the tournament and chat settings to survive")
+	}
+}
+
+func TestHaltOnPanic(t *testing.T) {
+	g := NewCambiaGame()
+	g.Players = []*models.Player{{ID: uuid.New(), Hand: []*models.Card{}}}
+	var events []GameEventType
+	g.BroadcastFn = func(ev GameEvent) { events = append(events, ev.Type) }
+	halted := false
+	g.OnHalted = func(uuid.UUID) { halted = true }
+
+	func() {
+		g.Mu.Lock()
+		defer g.Mu.Unlock()
+		defer g.HaltOnPanic("test")
+		panic("boom")
+	}()
+
+	if !g.GameOver || !halted || len(events) != 1 || events[0] != EventGameHalted {
+		t.Fatalf("expected the game to halt, got over=%v halted=%v events=%v", g.GameOver, halted, events)
+	}
+	// the lock was released
+	g.Mu.Lock()
+	g.Mu.Unlock()
+}
+
+func TestAbort(t *testing.T) {
+	g := NewCambiaGame()
+	g.Players = []*models.Player{{ID: uuid.New(), Hand: []*models.Card{}}}
+	var reason interface{}
+	g.BroadcastFn = func(ev GameEvent) { reason = ev.Other["reason"] }
+
+	if !g.Abort("ended by an administrator") || !g.GameOver || reason != "ended by an administrator" {
+		t.Fatalf("expected the game to be aborted, got over=%v reason=%v", g.GameOver, reason)
+	}
+	if g.Abort("again") {
+		t.Fatalf("expected a finished game not to be aborted twice")
+	}
+}
+
+func TestVoteAbort(t *testing.T) {
+	g := NewCambiaGame()
+	p1 := &models.Player{ID: uuid.New(), Hand: []*models.Card{}, Connected: true}
+	p2 := &models.Player{ID: uuid.New(), Hand: []*models.Card{}, Connected: true}
+	p3 := &models.Player{ID: uuid.New(), Hand: []*models.Card{}, Connected: true}
+	g.Players = []*models.Player{p1, p2, p3}
+	var last GameEvent
+	g.BroadcastFn = func(ev GameEvent) { last = ev }
+
+	if aborted, err := g.VoteAbort(p1.ID); err != nil || aborted || last.Other["needed"] != 3 {
+		t.Fatalf("expected one vote of three, got aborted=%v err=%v %+v", aborted, err, last.Other)
+	}
+	if _, err := g.VoteAbort(uuid.New()); err == nil {
+		t.Fatalf("expected a vote from outside the game to be refused")
+	}
+	// a disconnected player still has to vote
+	g.offline[p3.ID] = true
+	if aborted, err := g.VoteAbort(p2.ID); err != nil || aborted {
+		t.Fatalf("expected the disconnected player's vote to be needed, got aborted=%v err=%v", aborted, err)
+	}
+	if aborted, err := g.VoteAbort(p3.ID); err != nil || !aborted || !g.GameOver || last.Other["reason"] != AbortVoteReason {
+		t.Fatalf("expected the game to be aborted, got aborted=%v err=%v %+v", aborted, err, last)
+	}
+
+	// in a ranked game, a player who left doesn't vote but is still penalized
+	ranked := NewCambiaGame()
+	ranked.Ranked = true
+	leaver := &models.Player{ID: uuid.New(), Hand: []*models.Card{}}
+	ranked.Players = []*models.Player{
+		{ID: p1.ID, Hand: []*models.Card{}, Connected: true},
+		{ID: p2.ID, Hand: []*models.Card{}, Connected: true},
+		leaver,
+	}
+	var penalized []uuid.UUID
+	ranked.OnAbandon = func(_, userID uuid.UUID) { penalized = append(penalized, userID) }
+	abortedID := uuid.Nil
+	ranked.OnAborted = func(gameID uuid.UUID) { abortedID = gameID }
+	if _, err := ranked.VoteAbort(leaver.ID); err == nil {
+		t.Fatalf("expected a vote from a player who left to be refused")
+	}
+	if aborted, err := ranked.VoteAbort(p1.ID); err != nil || aborted {
+		t.Fatalf("expected one vote of two, got aborted=%v err=%v", aborted, err)
+	}
+	if aborted, err := ranked.VoteAbort(p2.ID); err != nil || !aborted {
+		t.Fatalf("expected the ranked game to be aborted, got aborted=%v err=%v", aborted, err)
+	}
+	if len(penalized) != 1 || penalized[0] != leaver.ID {
+		t.Fatalf("expected the leaver to be penalized, got %v", penalized)
+	}
+	if abortedID != ranked.ID {
+		t.Fatalf("expected OnAborted to be called")
+	}
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// EventRoundReveal shows every player's hand and how it scored when the round ends, so clients can animate
+// the reveal.
+const EventRoundReveal GameEventType = "game_round_reveal"
+
+// RevealPlayer is a player's part of the end-of-round reveal.
+type RevealPlayer struct {
+	UserID       uuid.UUID      `json:"userID"`
+	Hand         []*models.Card `json:"hand"` // face up, each with the value it scored
+	Score        int            `json:"score"`
+	RedKings     int            `json:"redKings"` // cards worth -1
+	Jokers       int            `json:"jokers"`   // cards worth 0
+	CalledCambia bool           `json:"calledCambia"`
+	Team         int            `json:"team,omitempty"` // the player's team, in a team game
+	Won          bool           `json:"won"`
+	Place        int            `json:"place"` // 1 for the winners; players with the same score share a place
+}
+
+// fireRoundReveal broadcasts the end-of-round reveal. Callers must hold g.Mu.
+func (g *CambiaGame) fireRoundReveal(scores map[uuid.UUID]int, winners []uuid.UUID) {
+	won := make(map[uuid.UUID]bool, len(winners))
+	for _, w := range winners {
+		won[w] = true
+	}
+	players := make([]RevealPlayer, 0, len(g.Players))
+	for _, p := range g.Players {
+		rp := RevealPlayer{
+			UserID:       p.ID,
+			Hand:         make([]*models.Card, 0, len(p.Hand)),
+			Score:        scores[p.ID],
+			CalledCambia: g.CambiaCalled && p.ID == g.CambiaCallerID,
+			Team:         g.Teams[p.ID],
+			Won:          won[p.ID],
+		}
+		for _, c := range p.Hand {
+			card := *c
+			rp.Hand = append(rp.Hand, &card)
+			switch {
+			case c.Rank == "Joker":
+				rp.Jokers++
+			case c.Value < 0:
+				rp.RedKings++
+			}
+		}
+		players = append(players, rp)
+	}
+
+	// in a team game, players place by their team's score, and partners share a place
+	placeScore := func(rp RevealPlayer) int { return rp.Score }
+	var teamScores map[int]int
+	if g.Teams != nil {
+		teamScores = g.teamScores(scores)
+		placeScore = func(rp RevealPlayer) int { return teamScores[rp.Team] }
+	}
+
+	// winners first, then by score; the Cambia tiebreak can put a winner ahead of others on the same score
+	sort.SliceStable(players, func(i, j int) bool {
+		if players[i].Won != players[j].Won {
+			return players[i].Won
+		}
+		if a, b := placeScore(players[i]), placeScore(players[j]); a != b {
+			return a < b
+		}
+		return players[i].Score < players[j].Score
+	})
+	for i := range players {
+		players[i].Place = i + 1
+		if i > 0 && players[i].Won == players[i-1].Won && placeScore(players[i]) == placeScore(players[i-1]) {
+			players[i].Place = players[i-1].Place
+		}
+	}
+
+	other := map[string]interface{}{"players": players, "winners": winners}
+	if teamScores != nil {
+		other["teamScores"] = teamScores
+	}
+	if g.CambiaCalled {
+		other["cambiaCaller"] = g.CambiaCallerID
+		other["cambiaCallerWon"] = won[g.CambiaCallerID]
+	}
+	g.fireEvent(GameEvent{Type: EventRoundReveal, Other: other})
+}
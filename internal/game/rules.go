@@ -1,15 +1,71 @@
 package game
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+
+	"github.com/jason-s-yu/cambia/internal/features"
+)
 
 type HouseRules struct {
-	AllowDrawFromDiscardPile bool `json:"allowDrawFromDiscardPile"` // allow players to draw from the discard pile
-	AllowReplaceAbilities    bool `json:"allowReplaceAbilities"`    // allow cards discarded from a draw and replace to use their special abilities
-	SnapRace                 bool `json:"snapRace"`                 // only allow the first card snapped to succeed; all others get penalized
-	ForfeitOnDisconnect      bool `json:"forfeitOnDisconnect"`      // if a player disconnects, forfeit their game; if false, players can rejoin
-	PenaltyDrawCount         int  `json:"penaltyDrawCount"`         // num cards to draw on false snap
-	AutoKickTurnCount        int  `json:"autoKickTurnCount"`        // number of Cambia rounds to wait before auto-forfeiting a player that is nonresponsive
-	TurnTimerSec             int  `json:"turnTimerSec"`             // number of seconds to wait for a player to make a move; default is 15 sec
+	AllowDrawFromDiscardPile bool   `json:"allowDrawFromDiscardPile"`  // allow players to draw from the discard pile
+	AllowReplaceAbilities    bool   `json:"allowReplaceAbilities"`     // allow cards discarded from a draw and replace to use their special abilities
+	SnapRace                 bool   `json:"snapRace"`                  // only allow the first card snapped to succeed; all others get penalized
+	ForfeitOnDisconnect      bool   `json:"forfeitOnDisconnect"`       // if a player disconnects, forfeit their game; if false, players can rejoin
+	PenaltyDrawCount         int    `json:"penaltyDrawCount"`          // num cards to draw on false snap
+	AutoKickTurnCount        int    `json:"autoKickTurnCount"`         // number of Cambia rounds to wait before auto-forfeiting a player that is nonresponsive
+	TurnTimerSec             int    `json:"turnTimerSec"`              // number of seconds to wait for a player to make a move; default is 15 sec
+	Jokers                   int    `json:"jokers"`                    // num jokers in the deck; one of 0, 2 or 4
+	DoubleDeck               bool   `json:"doubleDeck"`                // shuffle two standard decks together, for 7-8 player games
+	Speed                    string `json:"speed,omitempty"`           // one of "casual", "standard" or "blitz", setting the timers and time bank; empty uses turnTimerSec
+	AbilityTimerSec          int    `json:"abilityTimerSec,omitempty"` // seconds to pick an ability's targets, without a speed; 0 uses turnTimerSec
+	AbilityTimeout           string `json:"abilityTimeout,omitempty"`  // what happens when an ability's targets aren't picked in time: "skip" (the default) or "random"
+	StockpileEmpty           string `json:"stockpileEmpty,omitempty"`  // what happens when the stockpile runs out: "reshuffle" the discard pile (the default) or "end_round"
+	SnapPartner              bool   `json:"snapPartner"`               // in team games, allow snapping a matching card from your partner's hand
+}
+
+// validJokers reports whether n is a supported number of jokers.
+func validJokers(n int) bool {
+	return n == 0 || n == 2 || n == 4
+}
+
+// wholeNumber reads a numeric rule as an int. Rules decoded from JSON hold float64s, so those are accepted
+// as long as they're whole numbers.
+func wholeNumber(val interface{}) (int, bool) {
+	switch n := val.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n != math.Trunc(n) || math.Abs(n) > math.MaxInt32 {
+			return 0, false
+		}
+		return int(n), true
+	}
+	return 0, false
+}
+
+// Validate checks rules that were set directly, e.g. decoded from a lobby creation request, rather than
+// through Update.
+func (rules HouseRules) Validate() error {
+	switch {
+	case rules.PenaltyDrawCount < 0:
+		return fmt.Errorf("penaltyDrawCount must be greater than or equal to 0; set to 0 for no penalty")
+	case rules.AutoKickTurnCount < 0:
+		return fmt.Errorf("autoKickTurnCount must be at least 0; set to 0 to disable auto-kick")
+	case rules.TurnTimerSec < 0:
+		return fmt.Errorf("turnTimerSec must be at least 0; set to 0 to disable turn timer")
+	case !validJokers(rules.Jokers):
+		return fmt.Errorf("jokers must be 0, 2 or 4")
+	case !validSpeed(rules.Speed):
+		return fmt.Errorf("speed must be casual, standard or blitz")
+	case rules.AbilityTimerSec < 0:
+		return fmt.Errorf("abilityTimerSec must be at least 0; set to 0 to use turnTimerSec")
+	case !validAbilityTimeout(rules.AbilityTimeout):
+		return fmt.Errorf("abilityTimeout must be skip or random")
+	case !validStockpileEmpty(rules.StockpileEmpty):
+		return fmt.Errorf("stockpileEmpty must be reshuffle or end_round")
+	}
+	return nil
 }
 
 // Update will update the house rules with the new rules provided.
@@ -30,6 +86,9 @@ func (rules *HouseRules) Update(newRules map[string]interface{}) error {
 		rules.AllowReplaceAbilities = val.(bool)
 	}
 	if val, exists := newRules["snapRace"]; exists && val != nil {
+		if on, _ := val.(bool); on && !features.Enabled(features.SnapRaceRule) {
+			return fmt.Errorf("snapRace is currently disabled")
+		}
 		if rules.SnapRace, ok = val.(bool); !ok {
 			return fmt.Errorf("invalid type for snapRace")
 		}
@@ -42,31 +101,88 @@ func (rules *HouseRules) Update(newRules map[string]interface{}) error {
 		rules.ForfeitOnDisconnect = val.(bool)
 	}
 	if val, exists := newRules["penaltyDrawCount"]; exists && val != nil {
-		if rules.PenaltyDrawCount, ok = val.(int); !ok {
+		if rules.PenaltyDrawCount, ok = wholeNumber(val); !ok {
 			return fmt.Errorf("invalid type for penaltyDrawCount")
 		}
-		if val.(int) < 0 {
+		if rules.PenaltyDrawCount < 0 {
 			return fmt.Errorf("penaltyDrawCount must be greater than or equal to 0; set to 0 for no penalty")
 		}
-		rules.PenaltyDrawCount = val.(int)
 	}
 	if val, exists := newRules["autoKickTurnCount"]; exists && val != nil {
-		if rules.AutoKickTurnCount, ok = val.(int); !ok {
+		if rules.AutoKickTurnCount, ok = wholeNumber(val); !ok {
 			return fmt.Errorf("invalid type for autoKickTurnCount")
 		}
-		if val.(int) < 0 {
+		if rules.AutoKickTurnCount < 0 {
 			return fmt.Errorf("autoKickTurnCount must be at least 0; set to 0 to disable auto-kick")
 		}
-		rules.AutoKickTurnCount = val.(int)
 	}
 	if val, exists := newRules["turnTimerSec"]; exists && val != nil {
-		if rules.TurnTimerSec, ok = val.(int); !ok {
+		if rules.TurnTimerSec, ok = wholeNumber(val); !ok {
 			return fmt.Errorf("invalid type for turnTimerSec")
 		}
-		if val.(int) < 0 {
+		if rules.TurnTimerSec < 0 {
 			return fmt.Errorf("turnTimerSec must be at least 0; set to 0 to disable turn timer")
 		}
-		rules.TurnTimerSec = val.(int)
+	}
+	if val, exists := newRules["jokers"]; exists && val != nil {
+		n, ok := wholeNumber(val)
+		if !ok {
+			return fmt.Errorf("invalid type for jokers")
+		}
+		if !validJokers(n) {
+			return fmt.Errorf("jokers must be 0, 2 or 4")
+		}
+		rules.Jokers = n
+	}
+	if val, exists := newRules["doubleDeck"]; exists && val != nil {
+		if rules.DoubleDeck, ok = val.(bool); !ok {
+			return fmt.Errorf("invalid type for doubleDeck")
+		}
+	}
+	if val, exists := newRules["speed"]; exists && val != nil {
+		speed, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("invalid type for speed")
+		}
+		if !validSpeed(speed) {
+			return fmt.Errorf("speed must be casual, standard or blitz")
+		}
+		rules.Speed = speed
+	}
+	if val, exists := newRules["abilityTimerSec"]; exists && val != nil {
+		n, ok := wholeNumber(val)
+		if !ok {
+			return fmt.Errorf("invalid type for abilityTimerSec")
+		}
+		if n < 0 {
+			return fmt.Errorf("abilityTimerSec must be at least 0; set to 0 to use turnTimerSec")
+		}
+		rules.AbilityTimerSec = n
+	}
+	if val, exists := newRules["abilityTimeout"]; exists && val != nil {
+		action, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("invalid type for abilityTimeout")
+		}
+		if !validAbilityTimeout(action) {
+			return fmt.Errorf("abilityTimeout must be skip or random")
+		}
+		rules.AbilityTimeout = action
+	}
+	if val, exists := newRules["stockpileEmpty"]; exists && val != nil {
+		action, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("invalid type for stockpileEmpty")
+		}
+		if !validStockpileEmpty(action) {
+			return fmt.Errorf("stockpileEmpty must be reshuffle or end_round")
+		}
+		rules.StockpileEmpty = action
+	}
+	if val, exists := newRules["snapPartner"]; exists && val != nil {
+		if rules.SnapPartner, ok = val.(bool); !ok {
+			return fmt.Errorf("invalid type for snapPartner")
+		}
 	}
 
 	return nil
@@ -98,20 +214,67 @@ func ParseRules(rules map[string]interface{}, current HouseRules) (HouseRules, e
 		}
 	}
 	if val, exists := rules["penaltyDrawCount"]; exists && val != nil {
-		if houseRules.PenaltyDrawCount, ok = val.(int); !ok {
+		if houseRules.PenaltyDrawCount, ok = wholeNumber(val); !ok {
 			return houseRules, fmt.Errorf("invalid type for penaltyDrawCount")
 		}
 	}
 	if val, exists := rules["autoKickTurnCount"]; exists && val != nil {
-		if houseRules.AutoKickTurnCount, ok = val.(int); !ok {
+		if houseRules.AutoKickTurnCount, ok = wholeNumber(val); !ok {
 			return houseRules, fmt.Errorf("invalid type for autoKickTurnCount")
 		}
 	}
 	if val, exists := rules["turnTimerSec"]; exists && val != nil {
-		if houseRules.TurnTimerSec, ok = val.(int); !ok {
+		if houseRules.TurnTimerSec, ok = wholeNumber(val); !ok {
 			return houseRules, fmt.Errorf("invalid type for turnTimerSec")
 		}
 	}
+	if val, exists := rules["jokers"]; exists && val != nil {
+		if houseRules.Jokers, ok = wholeNumber(val); !ok {
+			return houseRules, fmt.Errorf("invalid type for jokers")
+		}
+		if !validJokers(houseRules.Jokers) {
+			return houseRules, fmt.Errorf("jokers must be 0, 2 or 4")
+		}
+	}
+	if val, exists := rules["doubleDeck"]; exists && val != nil {
+		if houseRules.DoubleDeck, ok = val.(bool); !ok {
+			return houseRules, fmt.Errorf("invalid type for doubleDeck")
+		}
+	}
+	if val, exists := rules["speed"]; exists && val != nil {
+		if houseRules.Speed, ok = val.(string); !ok {
+			return houseRules, fmt.Errorf("invalid type for speed")
+		}
+		if !validSpeed(houseRules.Speed) {
+			return houseRules, fmt.Errorf("speed must be casual, standard or blitz")
+		}
+	}
+	if val, exists := rules["abilityTimerSec"]; exists && val != nil {
+		if houseRules.AbilityTimerSec, ok = wholeNumber(val); !ok {
+			return houseRules, fmt.Errorf("invalid type for abilityTimerSec")
+		}
+	}
+	if val, exists := rules["abilityTimeout"]; exists && val != nil {
+		if houseRules.AbilityTimeout, ok = val.(string); !ok {
+			return houseRules, fmt.Errorf("invalid type for abilityTimeout")
+		}
+		if !validAbilityTimeout(houseRules.AbilityTimeout) {
+			return houseRules, fmt.Errorf("abilityTimeout must be skip or random")
+		}
+	}
+	if val, exists := rules["stockpileEmpty"]; exists && val != nil {
+		if houseRules.StockpileEmpty, ok = val.(string); !ok {
+			return houseRules, fmt.Errorf("invalid type for stockpileEmpty")
+		}
+		if !validStockpileEmpty(houseRules.StockpileEmpty) {
+			return houseRules, fmt.Errorf("stockpileEmpty must be reshuffle or end_round")
+		}
+	}
+	if val, exists := rules["snapPartner"]; exists && val != nil {
+		if houseRules.SnapPartner, ok = val.(bool); !ok {
+			return houseRules, fmt.Errorf("invalid type for snapPartner")
+		}
+	}
 
 	return houseRules, nil
 }
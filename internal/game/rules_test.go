@@ -0,0 +1,187 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestDrawFromDiscardPile(t *testing.T) {
+	g := NewCambiaGame()
+	p := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "K", Suit: "H", Value: -1}}}
+	g.Players = []*models.Player{p, {ID: uuid.New(), Hand: []*models.Card{}}}
+	top := &models.Card{ID: uuid.New(), Rank: "2", Suit: "S", Value: 2}
+	g.DiscardPile = []*models.Card{{ID: uuid.New(), Rank: "5", Suit: "D", Value: 5}, top}
+	var events []GameEvent
+	g.BroadcastFn = func(ev GameEvent) { events = append(events, ev) }
+	draw := models.GameAction{ActionType: "action_draw_discardpile"}
+
+	g.HandlePlayerAction(p.ID, draw)
+	if p.DrawnCard != nil || len(g.DiscardPile) != 2 {
+		t.Fatalf("expected the draw to be refused without the house rule")
+	}
+
+	g.HouseRules.AllowDrawFromDiscardPile = true
+	g.HandlePlayerAction(p.ID, draw)
+	if p.DrawnCard != top || len(g.DiscardPile) != 1 {
+		t.Fatalf("expected the top discard to be drawn, got %+v", p.DrawnCard)
+	}
+	if len(events) != 1 || events[0].Type != EventPlayerDrawDiscard || events[0].Card.Rank != "2" {
+		t.Fatalf("expected a public draw event showing the card, got %+v", events)
+	}
+
+	g.HandlePlayerAction(p.ID, models.GameAction{ActionType: "action_discard", Payload: map[string]interface{}{"id": top.ID.String()}})
+	if p.DrawnCard != top {
+		t.Fatalf("expected the card not to be discarded straight back")
+	}
+	g.HandlePlayerAction(p.ID, models.GameAction{ActionType: "action_replace", Payload: map[string]interface{}{"idx": float64(0)}})
+	if p.Hand[0] != top || p.DrawnCard != nil || g.DiscardPile[len(g.DiscardPile)-1].Rank != "K" {
+		t.Fatalf("expected the card to replace the king, got hand %+v", p.Hand)
+	}
+}
+
+func TestDeckComposition(t *testing.T) {
+	for _, tc := range []struct {
+		rules HouseRules
+		size  int
+		score int
+	}{
+		{HouseRules{Jokers: 2}, 54, 4*91 - 2*14},
+		{HouseRules{Jokers: 0}, 52, 4*91 - 2*14},
+		{HouseRules{Jokers: 4, DoubleDeck: true}, 108, 2 * (4*91 - 2*14)},
+	} {
+		deck := buildDeck(tc.rules)
+		score := 0
+		for _, c := range deck {
+			score += c.Value
+		}
+		if len(deck) != tc.size || score != tc.score {
+			t.Errorf("%+v: got %d cards worth %d, want %d worth %d", tc.rules, len(deck), score, tc.size, tc.score)
+		}
+	}
+
+	rules := HouseRules{Jokers: 2}
+	if err := rules.Update(map[string]interface{}{"jokers": 3}); err == nil || rules.Jokers != 2 {
+		t.Fatalf("expected an odd joker count to be refused")
+	}
+	if err := rules.Update(map[string]interface{}{"jokers": 4, "doubleDeck": true}); err != nil || rules.Jokers != 4 || !rules.DoubleDeck {
+		t.Fatalf("expected the deck rules to be updated, got %+v, %v", rules, err)
+	}
+}
+
+func TestDeckRulesFromJSON(t *testing.T) {
+	var update map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"jokers": 4}`), &update); err != nil {
+		t.Fatal(err)
+	}
+	rules := HouseRules{Jokers: 2}
+	if err := rules.Update(update); err != nil || rules.Jokers != 4 {
+		t.Fatalf("expected jokers decoded from JSON to be accepted, got %+v, %v", rules, err)
+	}
+	if parsed, err := ParseRules(update, HouseRules{}); err != nil || parsed.Jokers != 4 {
+		t.Fatalf("expected jokers decoded from JSON to be parsed, got %+v, %v", parsed, err)
+	}
+	if err := rules.Update(map[string]interface{}{"jokers": 2.5}); err == nil || rules.Jokers != 4 {
+		t.Fatalf("expected a fractional joker count to be refused")
+	}
+}
+
+func TestAbilityTimerFromJSON(t *testing.T) {
+	var update map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"abilityTimerSec": 20, "abilityTimeout": "random"}`), &update); err != nil {
+		t.Fatal(err)
+	}
+	var rules HouseRules
+	if err := rules.Update(update); err != nil || rules.AbilityTimerSec != 20 || rules.AbilityTimeout != AbilityTimeoutRandom {
+		t.Fatalf("expected the ability timer decoded from JSON to be accepted, got %+v, %v", rules, err)
+	}
+	if parsed, err := ParseRules(update, HouseRules{}); err != nil || parsed.AbilityTimerSec != 20 {
+		t.Fatalf("expected the ability timer decoded from JSON to be parsed, got %+v, %v", parsed, err)
+	}
+	if err := rules.Update(map[string]interface{}{"abilityTimerSec": float64(-5)}); err == nil || rules.AbilityTimerSec != 20 {
+		t.Fatalf("expected a negative ability timer to be refused")
+	}
+}
+
+func TestParseRulesFromJSON(t *testing.T) {
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"penaltyDrawCount": 2, "autoKickTurnCount": 0, "turnTimerSec": 30, "snapRace": false}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseRules(body, DefaultHouseRules())
+	if err != nil || rules.PenaltyDrawCount != 2 || rules.AutoKickTurnCount != 0 || rules.TurnTimerSec != 30 || rules.Jokers != 2 {
+		t.Fatalf("expected rules decoded from JSON to be parsed over the defaults, got %+v, %v", rules, err)
+	}
+}
+
+func TestOfficialCircuitPresetIsRanked(t *testing.T) {
+	p, ok := FindRulePreset("official_circuit")
+	if !ok {
+		t.Fatalf("expected the official_circuit preset")
+	}
+	if name, err := MatchRankedProfile(p.HouseRules); err != nil || name != "circuit" {
+		t.Fatalf("expected the preset to match the circuit ranked profile, got %q, %v", name, err)
+	}
+}
+
+func TestStockpileExhaustion(t *testing.T) {
+	discards := func() []*models.Card {
+		return []*models.Card{{ID: uuid.New(), Rank: "2"}, {ID: uuid.New(), Rank: "3"}, {ID: uuid.New(), Rank: "4"}}
+	}
+
+	g := NewCambiaGame()
+	g.Players = []*models.Player{{ID: uuid.New(), Hand: []*models.Card{}}}
+	g.Deck = []*models.Card{}
+	g.DiscardPile = discards()
+	top := g.DiscardPile[2]
+	if card := g.drawTopStockpile(false); card == nil || len(g.Deck) != 1 {
+		t.Fatalf("expected the discard pile to be reshuffled into the stockpile")
+	}
+	if len(g.DiscardPile) != 1 || g.DiscardPile[0] != top {
+		t.Fatalf("expected the top discard to stay face up")
+	}
+	if last := g.Actions[len(g.Actions)-1]; last.ActionType != string(EventReshuffle) || last.Payload["seed"] == nil {
+		t.Fatalf("expected the reshuffle seed in the action log, got %+v", last)
+	}
+
+	g = NewCambiaGame()
+	g.Players = []*models.Player{{ID: uuid.New(), Hand: []*models.Card{}}}
+	g.HouseRules.StockpileEmpty = StockpileEndRound
+	g.Deck = []*models.Card{}
+	g.DiscardPile = discards()
+	if card := g.drawTopStockpile(false); card != nil || !g.GameOver {
+		t.Fatalf("expected the round to end when the stockpile ran out")
+	}
+}
+
+func TestRoundReveal(t *testing.T) {
+	g := NewCambiaGame()
+	caller := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "K", Suit: "Hearts", Value: -1}, {ID: uuid.New(), Rank: "5", Suit: "Clubs", Value: 5}}}
+	other := &models.Player{ID: uuid.New(), Hand: []*models.Card{{ID: uuid.New(), Rank: "4", Suit: "Clubs", Value: 4}, {ID: uuid.New(), Rank: "Joker", Suit: "Joker"}}}
+	g.Players = []*models.Player{other, caller}
+	g.CambiaCalled, g.CambiaCallerID = true, caller.ID
+	var reveal *GameEvent
+	g.BroadcastFn = func(ev GameEvent) {
+		if ev.Type == EventRoundReveal {
+			reveal = &ev
+		}
+	}
+
+	g.EndGame()
+	if reveal == nil {
+		t.Fatalf("expected a round reveal")
+	}
+	players := reveal.Other["players"].([]RevealPlayer)
+	first, second := players[0], players[1]
+	if first.UserID != caller.ID || !first.Won || !first.CalledCambia || first.Place != 1 || first.RedKings != 1 {
+		t.Fatalf("expected the Cambia caller to win the tie, got %+v", first)
+	}
+	if second.Score != 4 || second.Place != 2 || second.Jokers != 1 || len(second.Hand) != 2 || second.Hand[0].Rank != "4" {
+		t.Fatalf("unexpected reveal for the other player: %+v", second)
+	}
+	if reveal.Other["cambiaCallerWon"] != true {
+		t.Fatalf("expected the Cambia call to have succeeded")
+	}
+}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxSeriesBestOf bounds the length of a best-of-N series.
+const maxSeriesBestOf = 9
+
+// Series configures a best-of-N series of head_to_head games played in one lobby.
+type Series struct {
+	BestOf int `json:"bestOf"` // odd number of games, e.g. 3 or 5; 0 or 1 plays single games
+}
+
+// WinTarget is the number of game wins that takes the series.
+func (s Series) WinTarget() int {
+	return s.BestOf/2 + 1
+}
+
+// Enabled reports whether the lobby plays a series rather than single games.
+func (s Series) Enabled() bool {
+	return s.BestOf > 1
+}
+
+// SeriesState tracks a best-of-N series in progress in a lobby.
+type SeriesState struct {
+	mu       sync.Mutex
+	ID       uuid.UUID
+	GameIDs  []uuid.UUID
+	Wins     map[uuid.UUID]int
+	Draws    int
+	Finished bool
+	WinnerID uuid.UUID
+}
+
+// ValidateSeries checks the lobby's series settings. Series are only played head to head, and can't be
+// combined with a circuit.
+func (lobby *Lobby) ValidateSeries() error {
+	if !lobby.Series.Enabled() {
+		return nil
+	}
+	if lobby.GameMode != "head_to_head" {
+		return fmt.Errorf("best-of series are only available in head_to_head mode")
+	}
+	if lobby.Circuit.Enabled {
+		return fmt.Errorf("a lobby cannot play both a circuit and a series")
+	}
+	if lobby.Series.BestOf%2 == 0 || lobby.Series.BestOf > maxSeriesBestOf {
+		return fmt.Errorf("bestOf must be an odd number up to %d", maxSeriesBestOf)
+	}
+	return nil
+}
+
+// RecordSeriesGame applies a finished game to the lobby's series, starting a new series if none is in
+// progress, and broadcasts the scoreboard. A game with no single winner is a draw and doesn't count
+// towards the win target. It reports whether the series continues with another game.
+// Callers must hold g.Mu.
+func (lobby *Lobby) RecordSeriesGame(g *CambiaGame, scores map[uuid.UUID]int) bool {
+	if !lobby.Series.Enabled() {
+		return false
+	}
+	if lobby.SeriesState == nil || lobby.SeriesState.Finished {
+		id, _ := uuid.NewV7()
+		lobby.SeriesState = &SeriesState{ID: id, Wins: make(map[uuid.UUID]int)}
+	}
+	ss := lobby.SeriesState
+	winners := g.findWinnersWithCambiaTiebreak(scores)
+
+	ss.mu.Lock()
+	ss.GameIDs = append(ss.GameIDs, g.ID)
+	for _, p := range g.Players {
+		if _, ok := ss.Wins[p.ID]; !ok {
+			ss.Wins[p.ID] = 0
+		}
+	}
+	if len(winners) == 1 {
+		ss.Wins[winners[0]]++
+		if ss.Wins[winners[0]] >= lobby.Series.WinTarget() {
+			ss.Finished = true
+			ss.WinnerID = winners[0]
+		}
+	} else {
+		ss.Draws++
+	}
+	// draws can't extend a series forever; after twice the scheduled games, the most wins takes it
+	if !ss.Finished && len(ss.GameIDs) >= 2*lobby.Series.BestOf {
+		ss.Finished = true
+		best := -1
+		for uid, w := range ss.Wins {
+			if w > best {
+				best, ss.WinnerID = w, uid
+			} else if w == best {
+				ss.WinnerID = uuid.Nil
+			}
+		}
+	}
+	msg := ss.scoreboardLocked(lobby.Series)
+	finished := ss.Finished
+	ss.mu.Unlock()
+
+	lobby.BroadcastAll(msg)
+	return !finished
+}
+
+// scoreboardLocked builds the "series_scoreboard" broadcast. Callers must hold ss.mu.
+func (ss *SeriesState) scoreboardLocked(s Series) map[string]interface{} {
+	wins := make(map[string]int, len(ss.Wins))
+	for uid, w := range ss.Wins {
+		wins[uid.String()] = w
+	}
+	msg := map[string]interface{}{
+		"type":       "series_scoreboard",
+		"series_id":  ss.ID.String(),
+		"best_of":    s.BestOf,
+		"win_target": s.WinTarget(),
+		"games":      ss.GameIDs,
+		"wins":       wins,
+		"draws":      ss.Draws,
+		"finished":   ss.Finished,
+	}
+	if ss.Finished {
+		msg["winner"] = ss.WinnerID.String()
+	}
+	return msg
+}
+
+// SeriesScoreboard returns the lobby's current series scoreboard message, or nil if no series has started.
+func (lobby *Lobby) SeriesScoreboard() map[string]interface{} {
+	ss := lobby.SeriesState
+	if ss == nil {
+		return nil
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.scoreboardLocked(lobby.Series)
+}
@@ -0,0 +1,340 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Headless game simulation. A simulation plays a whole game through the same entry points the socket
+// handlers use, with a fixed deal and scripted players, and checks the rules engine's invariants after
+// every move: no card is lost or duplicated, turns go round the table in seat order, and the final scores
+// and winners are those of the hands left. The same seed always plays out the same game.
+
+// maxSimMoves bounds a simulated game, so a game that never ends fails instead of hanging.
+const maxSimMoves = 2000
+
+// simStrategy picks a player's next move. It's asked once before the player draws and once after, and
+// runs on the game's loop.
+type simStrategy func(r *rand.Rand, g *CambiaGame, p *models.Player, turn int) models.GameAction
+
+// drawDiscard always draws from the stockpile and discards what it drew, calling Cambia on its fourth turn.
+func drawDiscard(r *rand.Rand, g *CambiaGame, p *models.Player, turn int) models.GameAction {
+	if p.DrawnCard != nil {
+		return discardDrawn(p)
+	}
+	if turn >= 4 && !g.CambiaCalled {
+		return simAction("action_cambia")
+	}
+	return simAction("action_draw_stockpile")
+}
+
+// replaceHigh keeps a drawn card worth less than 5 in place of a random card in hand.
+func replaceHigh(r *rand.Rand, g *CambiaGame, p *models.Player, turn int) models.GameAction {
+	if p.DrawnCard == nil {
+		return drawDiscard(r, g, p, turn)
+	}
+	if p.DrawnCard.Value < 5 && len(p.Hand) > 0 {
+		return simAction("action_replace", "idx", float64(r.Intn(len(p.Hand))))
+	}
+	return discardDrawn(p)
+}
+
+// discardDrawn discards the player's drawn card, or, since a card taken from the discard pile can't go
+// straight back, puts it in place of their first card.
+func discardDrawn(p *models.Player) models.GameAction {
+	if p.DrawnFromDiscard && len(p.Hand) > 0 {
+		return simAction("action_replace", "idx", float64(0))
+	}
+	return simAction("action_discard", "id", p.DrawnCard.ID.String())
+}
+
+// chaotic plays at random: it tries snapping a random card of its own, replaces or discards at random,
+// and calls Cambia now and then.
+func chaotic(r *rand.Rand, g *CambiaGame, p *models.Player, turn int) models.GameAction {
+	if p.DrawnCard == nil && len(p.Hand) > 0 && len(g.DiscardPile) > 0 && r.Intn(8) == 0 {
+		return simAction("action_snap", "id", p.Hand[r.Intn(len(p.Hand))].ID.String())
+	}
+	if p.DrawnCard != nil {
+		if len(p.Hand) > 0 && r.Intn(2) == 0 {
+			return simAction("action_replace", "idx", float64(r.Intn(len(p.Hand))))
+		}
+		return discardDrawn(p)
+	}
+	if turn >= 2 && !g.CambiaCalled && r.Intn(10) == 0 {
+		return simAction("action_cambia")
+	}
+	return simAction("action_draw_stockpile")
+}
+
+func simAction(typ string, kv ...interface{}) models.GameAction {
+	act := models.GameAction{ActionType: typ, Payload: map[string]interface{}{}}
+	for i := 0; i+1 < len(kv); i += 2 {
+		act.Payload[kv[i].(string)] = kv[i+1]
+	}
+	return act
+}
+
+// simulation is one headless game.
+type simulation struct {
+	t          *testing.T
+	g          *CambiaGame
+	r          *rand.Rand
+	strategies []simStrategy
+	turns      map[uuid.UUID]int
+
+	cards      map[uuid.UUID]bool // every card dealt, by ID
+	events     []GameEvent
+	transcript []string // the events, with players by seat and cards by face, to compare runs
+	lastTurn   int      // seat of the last player_turn
+}
+
+// newSimulation seats a player for each strategy at a game dealt from seed, with no turn timer.
+func newSimulation(t *testing.T, seed int64, rules HouseRules, strategies ...simStrategy) *simulation {
+	t.Helper()
+	rules.TurnTimerSec = 0
+	rules.Speed = ""
+	g := NewCambiaGame()
+	g.Practice = true
+	g.HouseRules = rules
+	g.DealSeed = []byte(fmt.Sprintf("simulation-%d", seed))
+	for range strategies {
+		g.Players = append(g.Players, &models.Player{ID: uuid.New(), Hand: []*models.Card{}, Connected: true})
+	}
+	s := &simulation{
+		t:          t,
+		g:          g,
+		r:          rand.New(rand.NewSource(seed)),
+		strategies: strategies,
+		turns:      make(map[uuid.UUID]int),
+		lastTurn:   -1,
+	}
+	g.BroadcastFn = s.record
+	return s
+}
+
+func (s *simulation) seat(id uuid.UUID) int {
+	return slices.IndexFunc(s.g.Players, func(p *models.Player) bool { return p.ID == id })
+}
+
+// record keeps each event and checks that turns pass round the table in order.
+func (s *simulation) record(ev GameEvent) {
+	s.events = append(s.events, ev)
+	line := string(ev.Type)
+	if ev.UserID != uuid.Nil {
+		line += fmt.Sprintf(" seat=%d", s.seat(ev.UserID))
+	}
+	if ev.Card != nil && ev.Card.Rank != "" {
+		line += " " + ev.Card.Rank + ev.Card.Suit
+	}
+	s.transcript = append(s.transcript, line)
+
+	if ev.Type != EventPlayerTurn {
+		return
+	}
+	seat := s.seat(ev.UserID)
+	if want := (s.lastTurn + 1) % len(s.g.Players); s.lastTurn >= 0 && seat != want {
+		s.t.Errorf("expected seat %d to play after seat %d, got seat %d", want, s.lastTurn, seat)
+	}
+	s.lastTurn = seat
+}
+
+// run plays the game to its end and checks the final scores.
+func (s *simulation) run() {
+	s.t.Helper()
+	s.start()
+	s.finish()
+}
+
+// start deals the cards and notes them for checkCards.
+func (s *simulation) start() {
+	s.t.Helper()
+	s.g.Start()
+	s.g.Do(func() {
+		s.cards = make(map[uuid.UUID]bool)
+		for _, c := range s.allCards() {
+			s.cards[c.ID] = true
+		}
+	})
+	s.checkCards()
+}
+
+// finish lets the strategies play out the rest of the game, then checks the final scores.
+func (s *simulation) finish() {
+	s.t.Helper()
+	for move := 0; ; move++ {
+		if move == maxSimMoves {
+			s.t.Fatalf("game didn't end within %d moves", maxSimMoves)
+		}
+		over := false
+		s.g.Do(func() {
+			if over = s.g.GameOver; over {
+				return
+			}
+			p := s.g.Players[s.g.CurrentPlayerIndex]
+			if s.g.SpecialAction.Active {
+				// abilities are skipped, as by a "skip" step on the socket
+				s.g.SpecialAction = SpecialActionState{}
+				s.g.advanceTurn()
+				return
+			}
+			if p.DrawnCard == nil {
+				s.turns[p.ID]++
+			}
+			version := s.g.stateVersion
+			s.g.handlePlayerAction(p.ID, s.strategies[s.g.CurrentPlayerIndex](s.r, s.g, p, s.turns[p.ID]))
+			if s.g.stateVersion == version {
+				// the move was refused, e.g. after a fuzzed script left the player stuck; let their clock run out
+				s.g.handleTimeout(p.ID)
+			}
+		})
+		if over {
+			break
+		}
+		s.checkCards()
+		if s.t.Failed() {
+			s.t.FailNow()
+		}
+	}
+	s.checkScores()
+}
+
+// allCards returns every card in the game, wherever it is. Callers must hold g.Mu.
+func (s *simulation) allCards() []*models.Card {
+	cards := append(slices.Clone(s.g.Deck), s.g.DiscardPile...)
+	for _, p := range s.g.Players {
+		cards = append(cards, p.Hand...)
+		if p.DrawnCard != nil {
+			cards = append(cards, p.DrawnCard)
+		}
+	}
+	return cards
+}
+
+// checkCards checks that every card dealt is still in the game exactly once.
+func (s *simulation) checkCards() {
+	s.t.Helper()
+	s.g.Do(func() {
+		seen := make(map[uuid.UUID]bool, len(s.cards))
+		for _, c := range s.allCards() {
+			if seen[c.ID] {
+				s.t.Errorf("card %s%s (%v) is in the game twice", c.Rank, c.Suit, c.ID)
+			}
+			if !s.cards[c.ID] {
+				s.t.Errorf("card %s%s (%v) wasn't dealt", c.Rank, c.Suit, c.ID)
+			}
+			seen[c.ID] = true
+		}
+		if len(seen) != len(s.cards) {
+			s.t.Errorf("expected %d cards in the game, found %d", len(s.cards), len(seen))
+		}
+	})
+}
+
+// simCardValue is what a card scores, worked out independently of the engine's cardValue.
+func simCardValue(c *models.Card) int {
+	switch c.Rank {
+	case "Joker":
+		return 0
+	case "A":
+		return 1
+	case "J":
+		return 11
+	case "Q":
+		return 12
+	case "K":
+		if c.Suit == "Hearts" || c.Suit == "Diamonds" {
+			return -1
+		}
+		return 13
+	}
+	var n int
+	fmt.Sscan(c.Rank, &n)
+	return n
+}
+
+// checkScores checks the end-of-round reveal against the hands left: each player's score is the sum of
+// their cards, and the winners are the lowest scorers, or just the Cambia caller if they're among them.
+func (s *simulation) checkScores() {
+	s.t.Helper()
+	i := slices.IndexFunc(s.events, func(ev GameEvent) bool { return ev.Type == EventRoundReveal })
+	if i < 0 {
+		s.t.Fatal("expected a round reveal at the end of the game")
+	}
+	reveal := s.events[i].Other
+	s.g.Do(func() {
+		best := 0
+		scores := make(map[uuid.UUID]int)
+		for n, p := range s.g.Players {
+			for _, c := range p.Hand {
+				scores[p.ID] += simCardValue(c)
+			}
+			if n == 0 || scores[p.ID] < best {
+				best = scores[p.ID]
+			}
+		}
+		for _, rp := range reveal["players"].([]RevealPlayer) {
+			if rp.Score != scores[rp.UserID] {
+				s.t.Errorf("expected seat %d to score %d, got %d", s.seat(rp.UserID), scores[rp.UserID], rp.Score)
+			}
+		}
+		var want []uuid.UUID
+		for _, p := range s.g.Players {
+			if scores[p.ID] == best {
+				want = append(want, p.ID)
+			}
+		}
+		if s.g.CambiaCalled && slices.Contains(want, s.g.CambiaCallerID) {
+			want = []uuid.UUID{s.g.CambiaCallerID}
+		}
+		got := slices.Clone(reveal["winners"].([]uuid.UUID))
+		less := func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) }
+		slices.SortFunc(want, less)
+		slices.SortFunc(got, less)
+		if !slices.Equal(got, want) {
+			s.t.Errorf("expected winners %v, got %v", want, got)
+		}
+	})
+}
+
+func TestSimulatedGames(t *testing.T) {
+	tables := map[string][]simStrategy{
+		"heads up":     {drawDiscard, replaceHigh},
+		"four chaotic": {chaotic, chaotic, chaotic, chaotic},
+		"mixed":        {chaotic, drawDiscard, replaceHigh},
+	}
+	for name, strategies := range tables {
+		for seed := int64(1); seed <= 20; seed++ {
+			t.Run(fmt.Sprintf("%s/seed=%d", name, seed), func(t *testing.T) {
+				newSimulation(t, seed, DefaultHouseRules(), strategies...).run()
+			})
+		}
+	}
+}
+
+func TestSimulatedGamesUnderEndRoundRule(t *testing.T) {
+	rules := DefaultHouseRules()
+	rules.StockpileEmpty = StockpileEndRound
+	never := func(r *rand.Rand, g *CambiaGame, p *models.Player, turn int) models.GameAction {
+		return drawDiscard(r, g, p, 0)
+	}
+	// nobody calls Cambia, so the game only ends when the stockpile runs out
+	newSimulation(t, 7, rules, never, never, never).run()
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	play := func() []string {
+		s := newSimulation(t, 42, DefaultHouseRules(), chaotic, replaceHigh, chaotic)
+		s.run()
+		return s.transcript
+	}
+	first, second := play(), play()
+	if !slices.Equal(first, second) {
+		t.Fatalf("expected the same seed to play out the same game, got\n%v\nand\n%v", first, second)
+	}
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"encoding/json"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// PublicPlayerView is the spectator-safe view of a player. Hand cards are identified by ID only.
+type PublicPlayerView struct {
+	ID          uuid.UUID   `json:"id"`
+	Connected   bool        `json:"connected"`
+	HandCardIDs []uuid.UUID `json:"hand"`
+	Bot         bool        `json:"bot,omitempty"`
+	Team        int         `json:"team,omitempty"` // in a team game
+}
+
+// PublicGameView is a projection of the game containing only publicly visible information.
+type PublicGameView struct {
+	Type           string             `json:"type"`
+	GameID         uuid.UUID          `json:"gameID"`
+	Version        int                `json:"version"`
+	EngineVersion  string             `json:"engineVersion"`
+	RulesRevision  int                `json:"rulesRevision"`
+	Started        bool               `json:"started"`
+	GameOver       bool               `json:"gameOver"`
+	TurnID         int                `json:"turn"`
+	CurrentPlayer  uuid.UUID          `json:"currentPlayer"`
+	Players        []PublicPlayerView `json:"players"`
+	StockpileSize  int                `json:"stockpileSize"`
+	DiscardPile    []*models.Card     `json:"discardPile"`
+	CambiaCalled   bool               `json:"cambiaCalled"`
+	CambiaCallerID uuid.UUID          `json:"cambiaCaller,omitempty"`
+	TurnDeadline   int64              `json:"turnDeadline,omitempty"` // when the current player's clock runs out, in Unix milliseconds
+}
+
+// markStateChanged bumps the state version so cached projections are rebuilt on next use.
+// Callers must hold g.Mu.
+func (g *CambiaGame) markStateChanged() {
+	g.stateVersion++
+}
+
+// publicView builds the public projection of the current state. Callers must hold g.Mu.
+func (g *CambiaGame) publicView() PublicGameView {
+	view := PublicGameView{
+		Type:           "game_snapshot",
+		GameID:         g.ID,
+		Version:        g.stateVersion,
+		EngineVersion:  g.EngineVersion,
+		RulesRevision:  g.RulesRevision,
+		Started:        g.Started,
+		GameOver:       g.GameOver,
+		TurnID:         g.TurnID,
+		StockpileSize:  len(g.Deck),
+		DiscardPile:    g.DiscardPile,
+		CambiaCalled:   g.CambiaCalled,
+		CambiaCallerID: g.CambiaCallerID,
+	}
+	if len(g.Players) > 0 {
+		view.CurrentPlayer = g.Players[g.CurrentPlayerIndex].ID
+	}
+	if g.turnTimer != nil {
+		view.TurnDeadline = g.turnDeadline.UnixMilli()
+	}
+	for _, p := range g.Players {
+		pv := PublicPlayerView{ID: p.ID, Connected: p.Connected, HandCardIDs: make([]uuid.UUID, 0, len(p.Hand)), Bot: p.Bot, Team: g.Teams[p.ID]}
+		for _, c := range p.Hand {
+			pv.HandCardIDs = append(pv.HandCardIDs, c.ID)
+		}
+		view.Players = append(view.Players, pv)
+	}
+	return view
+}
+
+// cachedPublicSnapshot returns the marshaled public projection, rebuilding it at most once per state
+// change no matter how many spectators ask for it. Callers must hold g.Mu.
+func (g *CambiaGame) cachedPublicSnapshot() ([]byte, error) {
+	if g.snapshotCache != nil && g.snapshotVersion == g.stateVersion {
+		return g.snapshotCache, nil
+	}
+	data, err := json.Marshal(g.publicView())
+	if err != nil {
+		return nil, err
+	}
+	g.snapshotCache = data
+	g.snapshotVersion = g.stateVersion
+	return data, nil
+}
+
+// HydrateSpectator sends the cached public snapshot to a new spectator and registers it, atomically
+// with respect to game events, so the spectator never sees an event that predates its snapshot. The
+// snapshot is built on the game loop but written by the fan-out pool, ahead of any event for the
+// spectator, so a slow socket doesn't hold up the game. In a game with a SpectatorDelay, the snapshot is
+// as far behind as the rest of the spectators' output.
+func (g *CambiaGame) HydrateSpectator(userID uuid.UUID, conn *websocket.Conn) (err error) {
+	g.Do(func() { err = g.hydrateSpectator(userID, conn) })
+	return err
+}
+
+func (g *CambiaGame) hydrateSpectator(userID uuid.UUID, conn *websocket.Conn) error {
+	data, err := g.cachedPublicSnapshot()
+	if g.SpectatorDelay > 0 {
+		data, err = g.delayedSnapshot()
+	}
+	if err != nil {
+		return err
+	}
+	QueueAll([]Delivery{{Conn: conn, Type: websocket.MessageText, Data: data}})
+	g.spectatorsMu.Lock()
+	g.spectators[userID] = conn
+	g.spectatorsMu.Unlock()
+	return nil
+}
+
+// SpectatorCount returns the number of connected spectators.
+func (g *CambiaGame) SpectatorCount() int {
+	g.spectatorsMu.Lock()
+	defer g.spectatorsMu.Unlock()
+	return len(g.spectators)
+}
+
+// SpectatorConns returns a copy of the spectators' connections by user ID, to write to without holding
+// the lock that guards them.
+func (g *CambiaGame) SpectatorConns() map[uuid.UUID]*websocket.Conn {
+	g.spectatorsMu.Lock()
+	defer g.spectatorsMu.Unlock()
+	conns := make(map[uuid.UUID]*websocket.Conn, len(g.spectators))
+	for id, conn := range g.spectators {
+		conns[id] = conn
+	}
+	return conns
+}
+
+// PublicView returns the public projection of the current state, e.g. for admin inspection.
+func (g *CambiaGame) PublicView() PublicGameView {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	return g.publicView()
+}
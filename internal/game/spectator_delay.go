@@ -0,0 +1,75 @@
+package game
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/crash"
+)
+
+// delayedFrame is spectator output held back until due; see toSpectators.
+type delayedFrame struct {
+	due     time.Time
+	deliver func()
+}
+
+// spectate relays a public event to spectators, after SpectatorDelay. Callers must hold g.Mu.
+func (g *CambiaGame) spectate(ev GameEvent) {
+	g.toSpectators(func() {
+		if g.SpectatorFn != nil {
+			g.SpectatorFn(ev)
+		}
+	})
+}
+
+// toSpectators runs deliver, which writes to spectators, once SpectatorDelay has passed, or right away if
+// the game has none. Delayed output keeps its order. Callers must hold g.Mu.
+func (g *CambiaGame) toSpectators(deliver func()) {
+	if g.SpectatorDelay <= 0 {
+		deliver()
+		return
+	}
+	g.spectatorQueue = append(g.spectatorQueue, delayedFrame{due: time.Now().Add(g.SpectatorDelay), deliver: deliver})
+	if g.spectatorTimer == nil {
+		g.spectatorTimer = time.AfterFunc(g.SpectatorDelay, g.postSpectatorRelease)
+	}
+}
+
+// postSpectatorRelease has the game's loop release the delayed output that's due.
+func (g *CambiaGame) postSpectatorRelease() {
+	g.post(g.releaseSpectatorFrames)
+}
+
+// releaseSpectatorFrames delivers the delayed output that's due, then waits for the next. It runs on the
+// game's loop.
+func (g *CambiaGame) releaseSpectatorFrames() {
+	defer crash.Recover("relaying delayed spectator output")
+	g.spectatorTimer = nil
+	now := time.Now()
+	n := 0
+	for n < len(g.spectatorQueue) && !g.spectatorQueue[n].due.After(now) {
+		g.spectatorQueue[n].deliver()
+		n++
+	}
+	g.spectatorQueue = slices.Delete(g.spectatorQueue, 0, n)
+	if len(g.spectatorQueue) > 0 {
+		g.spectatorTimer = time.AfterFunc(time.Until(g.spectatorQueue[0].due), g.postSpectatorRelease)
+	}
+}
+
+// delayedSnapshot returns the marshaled public view spectators of a delayed game have been brought up to,
+// for a new spectator to start from. Before any has been released, that's the game as it was before the
+// deal. Callers must hold g.Mu.
+func (g *CambiaGame) delayedSnapshot() ([]byte, error) {
+	view := g.spectatorView
+	if view == nil {
+		view = &PublicGameView{
+			Type:          "game_snapshot",
+			GameID:        g.ID,
+			EngineVersion: g.EngineVersion,
+			RulesRevision: g.RulesRevision,
+		}
+	}
+	return json.Marshal(view)
+}
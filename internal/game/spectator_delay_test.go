@@ -0,0 +1,34 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpectatorDelay(t *testing.T) {
+	g := NewCambiaGame()
+	got := make(chan GameEventType, 4)
+	g.SpectatorFn = func(ev GameEvent) { got <- ev.Type }
+	g.SpectatorDelay = 50 * time.Millisecond
+
+	g.Mu.Lock()
+	g.spectate(GameEvent{Type: EventPlayerTurn})
+	g.spectate(GameEvent{Type: EventPlayerCambia})
+	g.Mu.Unlock()
+
+	select {
+	case typ := <-got:
+		t.Fatalf("expected %v to be held back", typ)
+	case <-time.After(20 * time.Millisecond):
+	}
+	for _, want := range []GameEventType{EventPlayerTurn, EventPlayerCambia} {
+		select {
+		case typ := <-got:
+			if typ != want {
+				t.Fatalf("expected %v, got %v", want, typ)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %v to be released", want)
+		}
+	}
+}
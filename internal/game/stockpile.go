@@ -0,0 +1,68 @@
+package game
+
+import (
+	"encoding/hex"
+	"log"
+
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// What happens when the stockpile runs out; see HouseRules.StockpileEmpty.
+const (
+	StockpileReshuffle = "reshuffle"
+	StockpileEndRound  = "end_round"
+)
+
+// EventStockpileExhausted tells players the stockpile ran out and the round is over.
+const EventStockpileExhausted GameEventType = "game_stockpile_exhausted"
+
+// validStockpileEmpty reports whether s is a stockpile exhaustion rule; "" means reshuffle.
+func validStockpileEmpty(s string) bool {
+	return s == "" || s == StockpileReshuffle || s == StockpileEndRound
+}
+
+// refillStockpile handles the stockpile running out before a draw. The discard pile, except its face-up top
+// card, is shuffled into a new stockpile; if there's nothing to shuffle, or the house rules say so, the
+// round ends instead. It reports whether there are cards to draw. Callers must hold g.Mu.
+func (g *CambiaGame) refillStockpile() bool {
+	if len(g.Deck) > 0 {
+		return true
+	}
+	if g.HouseRules.StockpileEmpty == StockpileEndRound || len(g.DiscardPile) < 2 {
+		g.exhaustStockpile()
+		return false
+	}
+
+	top := len(g.DiscardPile) - 1
+	g.Deck = append(g.Deck, g.DiscardPile[:top]...)
+	g.DiscardPile = []*models.Card{g.DiscardPile[top]}
+	// each reshuffle gets its own seed, derived from the deck's and kept in the action log so the game can be
+	// audited; it's not sent to players until the game ends, since with the discard pile's order it would
+	// reveal the new stockpile
+	g.reshuffles++
+	seed := g.reshuffleSeed(g.reshuffles)
+	shuffleWithSeed(g.Deck, seed)
+	g.fireEvent(GameEvent{
+		Type: EventReshuffle,
+		Other: map[string]interface{}{
+			"stockpileSize": len(g.Deck),
+			"discardSize":   len(g.DiscardPile),
+			"reshuffle":     g.reshuffles,
+		},
+	})
+	g.Actions[len(g.Actions)-1].Payload["seed"] = hex.EncodeToString(seed)
+	return true
+}
+
+// exhaustStockpile ends the round because the stockpile ran out. Callers must hold g.Mu.
+func (g *CambiaGame) exhaustStockpile() {
+	if g.GameOver {
+		return
+	}
+	log.Printf("Game %v: stockpile exhausted, ending the round\n", g.ID)
+	g.fireEvent(GameEvent{
+		Type:  EventStockpileExhausted,
+		Other: map[string]interface{}{"discardSize": len(g.DiscardPile)},
+	})
+	g.endGame()
+}
@@ -0,0 +1,220 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Team games. In the teams_2v2 game mode, the lobby's four players form two teams of two, numbered 1 and 2.
+// Partners sit across the table from each other, a team scores the sum of its players' hands, and the
+// team with the lowest score wins. Whether a player may snap a card from their partner's hand is the
+// snapPartner house rule.
+
+// teamCount is the number of teams in a team game.
+const teamCount = 2
+
+// TeamGame reports whether the lobby plays in teams.
+func (lobby *Lobby) TeamGame() bool {
+	return lobby.GameMode == "teams_2v2"
+}
+
+// teamSize is how many players make up a full team.
+func (lobby *Lobby) teamSize() int {
+	return lobby.Capacity() / teamCount
+}
+
+// ValidateTeams checks the lobby's settings against its game mode. Team games are unranked, and can't be
+// played as a circuit.
+func (lobby *Lobby) ValidateTeams() error {
+	if !lobby.TeamGame() {
+		return nil
+	}
+	if lobby.Ranked {
+		return fmt.Errorf("teams_2v2 game mode cannot be ranked")
+	}
+	if lobby.Circuit.Enabled {
+		return fmt.Errorf("a lobby cannot play a circuit in teams")
+	}
+	return nil
+}
+
+// balanceTeams puts each seated user who isn't on a team on the team with fewer players, and drops users
+// who have left. Callers must hold membersMu.
+func (lobby *Lobby) balanceTeams() {
+	if lobby.Teams == nil {
+		lobby.Teams = make(map[uuid.UUID]int)
+	}
+	for id := range lobby.Teams {
+		if _, seated := lobby.Seats[id]; !seated {
+			delete(lobby.Teams, id)
+		}
+	}
+	for _, id := range lobby.seatOrder() {
+		if lobby.Teams[id] != 0 {
+			continue
+		}
+		sizes := lobby.teamSizes()
+		team := 1
+		for t := 2; t <= teamCount; t++ {
+			if sizes[t] < sizes[team] {
+				team = t
+			}
+		}
+		lobby.Teams[id] = team
+	}
+}
+
+// teamSizes counts the players on each team. Callers must hold membersMu.
+func (lobby *Lobby) teamSizes() map[int]int {
+	sizes := make(map[int]int, teamCount)
+	for _, team := range lobby.Teams {
+		sizes[team]++
+	}
+	return sizes
+}
+
+// SetTeam moves a seated user to a team, if it has room for them.
+func (lobby *Lobby) SetTeam(userID uuid.UUID, team int) error {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	if !lobby.TeamGame() {
+		return fmt.Errorf("the lobby doesn't play in teams")
+	}
+	if team < 1 || team > teamCount {
+		return fmt.Errorf("team must be between 1 and %d", teamCount)
+	}
+	if _, seated := lobby.Seats[userID]; !seated {
+		return fmt.Errorf("only seated players can pick a team")
+	}
+	lobby.balanceTeams()
+	if lobby.Teams[userID] == team {
+		return nil
+	}
+	if lobby.teamSizes()[team] >= lobby.teamSize() {
+		return fmt.Errorf("team %d is full", team)
+	}
+	lobby.Teams[userID] = team
+	return nil
+}
+
+// TeamMap returns each seated user's team, or nil if the lobby doesn't play in teams.
+func (lobby *Lobby) TeamMap() map[uuid.UUID]int {
+	lobby.membersMu.Lock()
+	defer lobby.membersMu.Unlock()
+	if !lobby.TeamGame() {
+		return nil
+	}
+	lobby.balanceTeams()
+	teams := make(map[uuid.UUID]int, len(lobby.Teams))
+	for id, team := range lobby.Teams {
+		teams[id] = team
+	}
+	return teams
+}
+
+// TeamsReady checks that a team game has full teams to start with.
+func (lobby *Lobby) TeamsReady() error {
+	teams := lobby.TeamMap()
+	if teams == nil {
+		return nil
+	}
+	sizes := make(map[int]int, teamCount)
+	for _, team := range teams {
+		sizes[team]++
+	}
+	for team := 1; team <= teamCount; team++ {
+		if sizes[team] != lobby.teamSize() {
+			return fmt.Errorf("team %d needs %d players, but has %d", team, lobby.teamSize(), sizes[team])
+		}
+	}
+	return nil
+}
+
+// BroadcastTeams sends a "lobby_update" message indicating a user changed teams.
+func (lobby *Lobby) BroadcastTeams(userID uuid.UUID) {
+	seats, ready := lobby.members()
+	lobby.BroadcastAll(map[string]interface{}{
+		"type":        "lobby_update",
+		"team_change": userID.String(),
+		"ready_map":   ready,
+		"seats":       seats,
+		"teams":       lobby.teamsByID(),
+	})
+}
+
+// teamsByID is TeamMap keyed by user ID string, for a lobby_update.
+func (lobby *Lobby) teamsByID() map[string]int {
+	teams := make(map[string]int)
+	for id, team := range lobby.TeamMap() {
+		teams[id.String()] = team
+	}
+	return teams
+}
+
+// SeatTeams makes the game a team game with the given teams, by user ID, and reseats its players so the
+// teams alternate around the table and partners never play back to back. Players missing from teams keep
+// their order at the end.
+func (g *CambiaGame) SeatTeams(teams map[uuid.UUID]int) {
+	g.Teams = teams
+	byTeam := make([][]*models.Player, teamCount+1)
+	for _, p := range g.Players {
+		team := teams[p.ID]
+		if team < 1 || team > teamCount {
+			team = 0
+		}
+		byTeam[team] = append(byTeam[team], p)
+	}
+	seated := make([]*models.Player, 0, len(g.Players))
+	for i := 0; len(seated) < len(g.Players)-len(byTeam[0]); i++ {
+		for team := 1; team <= teamCount; team++ {
+			if i < len(byTeam[team]) {
+				seated = append(seated, byTeam[team][i])
+			}
+		}
+	}
+	g.Players = append(seated, byTeam[0]...)
+}
+
+// partners reports whether two different players are on the same team. Callers must hold g.Mu.
+func (g *CambiaGame) partners(a, b uuid.UUID) bool {
+	return a != b && g.Teams[a] != 0 && g.Teams[a] == g.Teams[b]
+}
+
+// teamScores sums the players' scores by team. Callers must hold g.Mu.
+func (g *CambiaGame) teamScores(scores map[uuid.UUID]int) map[int]int {
+	totals := make(map[int]int, teamCount)
+	for _, p := range g.Players {
+		totals[g.Teams[p.ID]] += scores[p.ID]
+	}
+	return totals
+}
+
+// findTeamWinners returns the players of the team with the lowest combined score. If teams tie, the Cambia
+// caller's team wins if it's among them; otherwise all of them share the win. Callers must hold g.Mu.
+func (g *CambiaGame) findTeamWinners(scores map[uuid.UUID]int) []uuid.UUID {
+	totals := g.teamScores(scores)
+	best, first := 0, true
+	for _, s := range totals {
+		if first || s < best {
+			best, first = s, false
+		}
+	}
+	winning := make(map[int]bool, teamCount)
+	for team, s := range totals {
+		if s == best {
+			winning[team] = true
+		}
+	}
+	if callerTeam := g.Teams[g.CambiaCallerID]; g.CambiaCalled && winning[callerTeam] {
+		winning = map[int]bool{callerTeam: true}
+	}
+	var winners []uuid.UUID
+	for _, p := range g.Players {
+		if winning[g.Teams[p.ID]] {
+			winners = append(winners, p.ID)
+		}
+	}
+	return winners
+}
@@ -0,0 +1,168 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestLobbyTeams(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.GameMode = "teams_2v2"
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range ids[:3] {
+		if err := lobby.AddConnection(id, &LobbyConnection{UserID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if teams := lobby.TeamMap(); teams[ids[0]] != 1 || teams[ids[1]] != 2 || teams[ids[2]] != 1 {
+		t.Fatalf("expected joins to balance the teams, got %v", teams)
+	}
+	if err := lobby.TeamsReady(); err == nil {
+		t.Fatal("expected a team short of players to keep the game from starting")
+	}
+	if err := lobby.SetTeam(ids[1], 1); err == nil {
+		t.Fatal("expected a full team to refuse another player")
+	}
+	if err := lobby.SetTeam(ids[0], 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lobby.AddConnection(ids[3], &LobbyConnection{UserID: ids[3]}); err != nil {
+		t.Fatal(err)
+	}
+	if teams := lobby.TeamMap(); teams[ids[3]] != 1 {
+		t.Fatalf("expected the last player on the team with room, got %v", teams)
+	}
+	if err := lobby.TeamsReady(); err != nil {
+		t.Fatal(err)
+	}
+
+	lobby.RemoveUser(ids[0])
+	if teams := lobby.TeamMap(); len(teams) != 3 {
+		t.Fatalf("expected a player who left to lose their team, got %v", teams)
+	}
+
+	group := NewLobbyWithDefaults(uuid.New())
+	group.GameMode = "group_of_4"
+	if group.TeamMap() != nil || group.SetTeam(uuid.New(), 1) == nil {
+		t.Fatal("expected no teams outside team games")
+	}
+}
+
+func TestValidateTeams(t *testing.T) {
+	lobby := NewLobbyWithDefaults(uuid.New())
+	lobby.GameMode = "teams_2v2"
+	if err := lobby.ValidateTeams(); err != nil {
+		t.Fatal(err)
+	}
+	lobby.Ranked = true
+	if err := lobby.ValidateTeams(); err == nil {
+		t.Fatal("expected a ranked team game to be refused")
+	}
+}
+
+// newTeamGame seats four players, alternating between teams 1 and 2, with the given hands.
+func newTeamGame(hands ...[]*models.Card) *CambiaGame {
+	g := NewCambiaGame()
+	teams := make(map[uuid.UUID]int)
+	for i, hand := range hands {
+		p := &models.Player{ID: uuid.New(), Hand: hand}
+		g.Players = append(g.Players, p)
+		teams[p.ID] = i%2 + 1
+	}
+	g.SeatTeams(teams)
+	return g
+}
+
+func cardsWorth(values ...int) []*models.Card {
+	var cards []*models.Card
+	for _, v := range values {
+		cards = append(cards, &models.Card{ID: uuid.New(), Rank: "5", Suit: "S", Value: v})
+	}
+	return cards
+}
+
+func TestSeatTeams(t *testing.T) {
+	g := NewCambiaGame()
+	var ids []uuid.UUID
+	for range 4 {
+		id := uuid.New()
+		ids = append(ids, id)
+		g.Players = append(g.Players, &models.Player{ID: id})
+	}
+	g.SeatTeams(map[uuid.UUID]int{ids[0]: 1, ids[1]: 1, ids[2]: 2, ids[3]: 2})
+	var seated []uuid.UUID
+	for _, p := range g.Players {
+		seated = append(seated, p.ID)
+	}
+	if want := []uuid.UUID{ids[0], ids[2], ids[1], ids[3]}; !slices.Equal(seated, want) {
+		t.Fatalf("expected the teams to alternate round the table, got %v", seated)
+	}
+}
+
+func TestTeamScoring(t *testing.T) {
+	// team 1 holds 1 + 10, team 2 holds 5 + 5: the best single hand is on the losing team
+	g := newTeamGame(cardsWorth(1), cardsWorth(5), cardsWorth(10), cardsWorth(5))
+	scores := g.computeScores()
+	winners := g.findWinnersWithCambiaTiebreak(scores)
+	if want := []uuid.UUID{g.Players[1].ID, g.Players[3].ID}; !slices.Equal(winners, want) {
+		t.Fatalf("expected team 2 to win on 10 to 11, got %v", winners)
+	}
+
+	// tied on 10: both teams share the win, unless the Cambia caller's team is one of them
+	g = newTeamGame(cardsWorth(1), cardsWorth(5), cardsWorth(9), cardsWorth(5))
+	scores = g.computeScores()
+	if winners := g.findWinnersWithCambiaTiebreak(scores); len(winners) != 4 {
+		t.Fatalf("expected tied teams to share the win, got %v", winners)
+	}
+	g.CambiaCalled, g.CambiaCallerID = true, g.Players[2].ID
+	if want := []uuid.UUID{g.Players[0].ID, g.Players[2].ID}; !slices.Equal(g.findWinnersWithCambiaTiebreak(scores), want) {
+		t.Fatalf("expected the Cambia caller's team to win the tie, got %v", g.findWinnersWithCambiaTiebreak(scores))
+	}
+
+	var reveal GameEvent
+	g.BroadcastFn = func(ev GameEvent) {
+		if ev.Type == EventRoundReveal {
+			reveal = ev
+		}
+	}
+	g.fireRoundReveal(scores, g.findWinnersWithCambiaTiebreak(scores))
+	if totals := reveal.Other["teamScores"].(map[int]int); totals[1] != 10 || totals[2] != 10 {
+		t.Fatalf("expected both teams on 10, got %v", totals)
+	}
+	for _, rp := range reveal.Other["players"].([]RevealPlayer) {
+		if want := map[int]int{1: 1, 2: 3}[rp.Team]; rp.Place != want {
+			t.Fatalf("expected team %d to place %d, got %+v", rp.Team, want, rp)
+		}
+	}
+}
+
+func TestSnapPartner(t *testing.T) {
+	g := newTeamGame(cardsWorth(5), cardsWorth(5), cardsWorth(5), cardsWorth(5))
+	g.DiscardPile = cardsWorth(5)
+	snapper, partner, opponent := g.Players[0], g.Players[2], g.Players[1]
+	snap := func(c *models.Card) {
+		g.HandlePlayerAction(snapper.ID, models.GameAction{ActionType: "action_snap", Payload: map[string]interface{}{"id": c.ID.String()}})
+	}
+
+	partnerCard := partner.Hand[0]
+	snap(partnerCard)
+	if len(partner.Hand) != 1 || len(snapper.Hand) == 1 {
+		t.Fatalf("expected a snap of the partner's card to fail without snapPartner, got hands of %d and %d", len(partner.Hand), len(snapper.Hand))
+	}
+
+	g.HouseRules.SnapPartner = true
+	penalized := len(snapper.Hand)
+	snap(opponent.Hand[0])
+	if len(opponent.Hand) != 1 || len(snapper.Hand) == penalized {
+		t.Fatal("expected snapPartner not to allow snapping an opponent's card")
+	}
+	penalized = len(snapper.Hand)
+	snap(partnerCard)
+	if len(partner.Hand) != 0 || len(snapper.Hand) != penalized || g.DiscardPile[len(g.DiscardPile)-1] != partnerCard {
+		t.Fatal("expected snapPartner to allow snapping the partner's card")
+	}
+}
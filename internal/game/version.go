@@ -0,0 +1,115 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EngineVersion is the version of this game engine build.
+const EngineVersion = "0.3.0"
+
+// Revision describes a rules revision this engine can run, and the protocol capabilities it offers.
+//
+// A game records the revision it started under, which decides the capabilities it offers; a node that
+// doesn't list a game's revision here refuses its sockets. RULES_REVISION controls which revision new games
+// start under. The engine doesn't yet branch on the revision, so a rule change must register a new
+// revision and gate the change on it before older games can keep their rules.
+type Revision struct {
+	RulesRevision int      `json:"rulesRevision"`
+	Capabilities  []string `json:"capabilities"`
+}
+
+// Protocol capabilities. Clients declare the ones they understand during the WS handshake.
+const (
+	CapSnapshot        = "snapshot"         // game_snapshot on (re)connect
+	CapSpectate        = "spectate"         // spectator channel and spectator_* events
+	CapWinProbability  = "win_probability"  // spectator_win_probability annotations
+	CapChatReactions   = "chat_reactions"   // lobby chat message IDs and reactions
+	CapTurnIDInEvents  = "turn_id"          // turn numbers in player_turn events
+	CapVersionedEvents = "versioned_events" // event payloads may vary with the game's rules revision
+	CapLegalActions    = "legal_actions"    // legal_actions hints for the current player; opt-in
+	CapBatch           = "batch"            // events from one action may share a frame, as a JSON array; opt-in
+)
+
+// optInCapabilities are only agreed when a client declares them, not assumed of one that declares nothing.
+var optInCapabilities = []string{CapLegalActions, CapBatch}
+
+// Revisions lists every rules revision this engine can run, oldest first.
+var Revisions = []Revision{
+	{
+		RulesRevision: 1,
+		Capabilities: []string{
+			CapSnapshot, CapSpectate, CapWinProbability, CapChatReactions, CapTurnIDInEvents, CapVersionedEvents,
+			CapLegalActions, CapBatch,
+		},
+	},
+}
+
+// LatestRulesRevision is the newest revision this engine supports.
+var LatestRulesRevision = Revisions[len(Revisions)-1].RulesRevision
+
+var (
+	defaultRevisionOnce sync.Once
+	defaultRevision     int
+)
+
+// LookupRevision returns the registered revision, if this engine supports it.
+func LookupRevision(rulesRevision int) (Revision, bool) {
+	for _, rev := range Revisions {
+		if rev.RulesRevision == rulesRevision {
+			return rev, true
+		}
+	}
+	return Revision{}, false
+}
+
+// DefaultRulesRevision returns the revision new games start under: RULES_REVISION if set and supported,
+// else the latest.
+func DefaultRulesRevision() int {
+	defaultRevisionOnce.Do(func() {
+		defaultRevision = LatestRulesRevision
+		raw := os.Getenv("RULES_REVISION")
+		if raw == "" {
+			return
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			log.Printf("invalid RULES_REVISION %q, using %d", raw, LatestRulesRevision)
+			return
+		}
+		if _, ok := LookupRevision(n); !ok {
+			log.Printf("unsupported RULES_REVISION %d, using %d", n, LatestRulesRevision)
+			return
+		}
+		defaultRevision = n
+	})
+	return defaultRevision
+}
+
+// NegotiateCapabilities intersects the client's declared capabilities with the ones the game's revision
+// offers. A client that declares nothing is assumed to understand everything the revision offers, except
+// the opt-in capabilities.
+func (g *CambiaGame) NegotiateCapabilities(declared []string) ([]string, error) {
+	rev, ok := LookupRevision(g.RulesRevision)
+	if !ok {
+		return nil, fmt.Errorf("game %v uses rules revision %d, which this engine cannot run", g.ID, g.RulesRevision)
+	}
+	if len(declared) == 0 {
+		return slices.DeleteFunc(slices.Clone(rev.Capabilities), func(c string) bool {
+			return slices.Contains(optInCapabilities, c)
+		}), nil
+	}
+	agreed := []string{}
+	for _, c := range declared {
+		c = strings.TrimSpace(c)
+		if slices.Contains(rev.Capabilities, c) && !slices.Contains(agreed, c) {
+			agreed = append(agreed, c)
+		}
+	}
+	return agreed, nil
+}
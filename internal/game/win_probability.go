@@ -0,0 +1,91 @@
+package game
+
+import (
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+const (
+	// winProbTemperature controls how quickly the estimate saturates as the expected hand totals diverge.
+	// A higher value keeps the estimate closer to uniform early in the game.
+	winProbTemperature = 4.0
+	// winProbFinalTemperature is used once Cambia has been called, since there are few turns left
+	// for hands to change.
+	winProbFinalTemperature = 1.5
+	// winProbCardSpread widens the temperature for every card still in a hand, since each one can be
+	// snapped, replaced, or swapped away before scoring.
+	winProbCardSpread = 0.5
+	// winProbBaseRating is the rating assumed for a player whose rating isn't known, e.g. a bot.
+	winProbBaseRating = 1500
+)
+
+// EstimateWinProbabilities returns a lightweight per-player win-probability estimate for the game's
+// current state, from public information only: each player's rating in the game's mode, and how many
+// cards they hold, each counted at the deck's average value. It is a softmax over the players' Elo
+// strengths less their expected hand totals (lower is better in Cambia), with a temperature that
+// shrinks as the game approaches scoring. A Cambia caller gets a small edge, since they win ties.
+//
+// No hidden card is looked at, so the estimate tells a player nothing they couldn't see for themselves.
+// Callers should hold g.Mu.
+func (g *CambiaGame) EstimateWinProbabilities() map[uuid.UUID]float64 {
+	probs := make(map[uuid.UUID]float64, len(g.Players))
+	if len(g.Players) == 0 {
+		return probs
+	}
+
+	temp := winProbTemperature
+	if g.CambiaCalled {
+		temp = winProbFinalTemperature
+	}
+	deck := buildDeck(g.HouseRules)
+	deckTotal := 0
+	for _, c := range deck {
+		deckTotal += c.Value
+	}
+	meanCard := float64(deckTotal) / float64(len(deck))
+	mode := database.RatingModeForPlayers(len(g.Players))
+
+	weights := make(map[uuid.UUID]float64, len(g.Players))
+	var total float64
+	for _, p := range g.Players {
+		rating := winProbBaseRating
+		if p.User != nil {
+			rating = database.ModeRating(p.User, mode)
+		}
+		t := temp + winProbCardSpread*float64(len(p.Hand))
+		// an Elo rating gap of 400 is a factor of 10 in the odds
+		strength := math.Ln10*float64(rating-winProbBaseRating)/400 - meanCard*float64(len(p.Hand))/t
+		if g.CambiaCalled && p.ID == g.CambiaCallerID {
+			strength += 0.5 / t
+		}
+		w := math.Exp(strength)
+		weights[p.ID] = w
+		total += w
+	}
+
+	for id, w := range weights {
+		probs[id] = w / total
+	}
+	return probs
+}
+
+// broadcastWinProbabilities sends the current win-probability estimate to spectators only.
+func (g *CambiaGame) broadcastWinProbabilities() {
+	if g.SpectatorFn == nil {
+		return
+	}
+	probs := g.EstimateWinProbabilities()
+	out := make(map[string]float64, len(probs))
+	for id, p := range probs {
+		out[id.String()] = math.Round(p*1000) / 1000
+	}
+	g.spectate(GameEvent{
+		Type: EventSpectatorWinProbability,
+		Other: map[string]interface{}{
+			"turn":          g.TurnID,
+			"probabilities": out,
+		},
+	})
+}
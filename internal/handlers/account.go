@@ -0,0 +1,90 @@
+// internal/handlers/account.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountHandler handles POST /user/delete. Registered users must confirm with their password;
+// guests can delete without one. On success the auth cookie is cleared.
+//
+// Game records are kept but no longer identify the user; see database.DeleteUserAccount.
+func DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := database.Users.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apierr.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if !u.IsEphemeral {
+		var req deleteAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		match, err := auth.ComparePasswordAndHash(req.Password, u.Password)
+		if err != nil || !match {
+			apierr.Error(w, "invalid credentials", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := database.DeleteUserAccount(r.Context(), userID); err != nil {
+		log.Printf("failed to delete account %v: %v", userID, err)
+		apierr.Error(w, "failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth_token",
+		Value:    "",
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   -1,
+	})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("account deleted"))
+}
+
+// ExportAccountHandler handles GET /user/export, returning the caller's profile, friends, match history,
+// rating history, and chat logs as a JSON download.
+func ExportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := database.ExportUserData(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to export account %v: %v", userID, err)
+		apierr.Error(w, "failed to export account data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="cambia-export-%s.json"`, userID))
+	json.NewEncoder(w).Encode(export)
+}
@@ -0,0 +1,87 @@
+// internal/handlers/achievement.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/achievements"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// awardAchievements advances each player's achievements from a recorded game and sends an
+// "achievement_unlocked" notification for each one that unlocks. It's the games' OnRecorded hook.
+func (gs *GameServer) awardAchievements(rec database.GameRecord) {
+	ctx := context.Background()
+	for _, p := range rec.Players {
+		for id, delta := range achievements.Evaluate(&rec, p.ID) {
+			def, _ := achievements.Lookup(id)
+			unlocked, err := database.AddAchievementProgress(ctx, p.ID, id, delta, def.Target)
+			if err != nil {
+				log.Warnf("failed to record achievement progress for %v: %v", p.ID, err)
+				continue
+			}
+			if unlocked {
+				gs.Notify(p.ID, "achievement_unlocked", map[string]interface{}{
+					"achievementID": def.ID,
+					"name":          def.Name,
+					"description":   def.Description,
+					"gameID":        rec.GameID.String(),
+				})
+			}
+		}
+	}
+}
+
+type achievementStatus struct {
+	achievements.Definition
+	Progress   int        `json:"progress"`
+	Unlocked   bool       `json:"unlocked"`
+	UnlockedAt *time.Time `json:"unlockedAt,omitempty"`
+}
+
+// UserAchievementsHandler handles GET /user/{id}/achievements, listing every achievement with the user's
+// progress towards it. {id} may be "me" for the authenticated caller.
+func UserAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/user/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "achievements" {
+		apierr.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	userID, ok := resolveUserID(w, r, parts[0])
+	if !ok {
+		return
+	}
+
+	progress, err := database.ListAchievementProgress(r.Context(), userID)
+	if err != nil {
+		log.Warnf("failed to load achievements for %v: %v", userID, err)
+		apierr.Error(w, "failed to load achievements", http.StatusInternalServerError)
+		return
+	}
+	out := make([]achievementStatus, 0, len(achievements.Definitions))
+	for _, def := range achievements.Definitions {
+		p := progress[def.ID]
+		out = append(out, achievementStatus{
+			Definition: def,
+			Progress:   min(p.Progress, def.Target),
+			Unlocked:   p.UnlockedAt != nil,
+			UnlockedAt: p.UnlockedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"userID":       userID,
+		"achievements": out,
+	})
+}
@@ -0,0 +1,264 @@
+// internal/handlers/admin.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// These handlers expect to be mounted behind middleware.RequireRole; they do no auth of their own.
+
+// AdminDeleteLobbyHandler handles DELETE /admin/lobby/{lobby_id}. It disconnects everyone in the lobby
+// and removes it from memory.
+func AdminDeleteLobbyHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		lobbyID, err := uuid.Parse(strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/lobby/"), "/"))
+		if err != nil {
+			apierr.Error(w, "invalid lobby_id", http.StatusBadRequest)
+			return
+		}
+		lobby, ok := gs.LobbyStore.GetLobby(lobbyID)
+		if !ok {
+			apierr.Error(w, "lobby not found", http.StatusNotFound)
+			return
+		}
+
+		lobby.CancelCountdown()
+		lobby.BroadcastAll(map[string]interface{}{
+			"type":   "lobby_closed",
+			"reason": "closed by an administrator",
+		})
+		conns := lobby.Connected()
+		for _, conn := range conns {
+			conn.Cancel()
+		}
+		gs.LobbyStore.DeleteLobby(lobbyID)
+		audit(r, models.AuditLobbyDelete, models.AuditTargetLobby, lobbyID, map[string]interface{}{
+			"hostID":  lobby.HostUserID,
+			"members": len(conns),
+		})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminInspectGameHandler handles GET /admin/game/{game_id}, returning the public view of a live game.
+func AdminInspectGameHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		gameID, err := uuid.Parse(strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/game/"), "/"))
+		if err != nil {
+			apierr.Error(w, "invalid game_id", http.StatusBadRequest)
+			return
+		}
+		g, ok := gs.GameStore.GetGame(gameID)
+		if !ok {
+			apierr.Error(w, "game not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.PublicView())
+	}
+}
+
+// AdminListGamesHandler handles GET /admin/games, listing every game in memory with its turn and which
+// players are connected.
+func AdminListGamesHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		games := gs.GameStore.GetGames()
+		summaries := make([]game.GameSummary, 0, len(games))
+		for _, g := range games {
+			summaries = append(summaries, g.Summary())
+		}
+		sort.Slice(summaries, func(i, j int) bool { return summaries[i].StartedAt.Before(summaries[j].StartedAt) })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summaries)
+	}
+}
+
+// AdminGameStateHandler handles GET /admin/game/{game_id}/state, dumping a live game's full state,
+// including every hidden card.
+func AdminGameStateHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := adminGame(gs, w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"summary": g.Summary(),
+			"state":   g.State(),
+		})
+	}
+}
+
+// AdminEndGameHandler handles POST /admin/game/{game_id}/end, stopping a stuck game without a result.
+// Its players are disconnected and the lobby is told; see endAbortedGame.
+func AdminEndGameHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := adminGame(gs, w, r)
+		if !ok {
+			return
+		}
+		if !g.Abort(adminEndGameReason) {
+			apierr.Error(w, "game already over", http.StatusConflict)
+			return
+		}
+		gs.endAbortedGame(r.Context(), g, adminEndGameReason)
+		audit(r, models.AuditGameEnd, models.AuditTargetGame, g.ID, map[string]interface{}{
+			"lobbyID": g.LobbyID,
+			"turn":    g.Summary().TurnID,
+		})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// adminEndGameReason is what players and the lobby are told when an administrator ends their game.
+const adminEndGameReason = "ended by an administrator"
+
+// adminGame looks up the game named by the request's {game_id}, writing an error if there isn't one.
+func adminGame(gs *GameServer, w http.ResponseWriter, r *http.Request) (*game.CambiaGame, bool) {
+	gameID, err := uuid.Parse(r.PathValue("game_id"))
+	if err != nil {
+		apierr.Error(w, "invalid game_id", http.StatusBadRequest)
+		return nil, false
+	}
+	g, ok := gs.GameStore.GetGame(gameID)
+	if !ok {
+		apierr.Error(w, "game not found", http.StatusNotFound)
+		return nil, false
+	}
+	return g, true
+}
+
+// AdminDisconnectUserHandler handles POST /admin/user/{user_id}/disconnect, closing every socket the user
+// has open: lobbies, games, matchmaking, and /user/ws. Nothing stops them reconnecting.
+func AdminDisconnectUserHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("user_id"))
+		if err != nil {
+			apierr.Error(w, "invalid user_id", http.StatusBadRequest)
+			return
+		}
+		if !gs.disconnectUser(userID, "you were disconnected by an administrator") {
+			apierr.Error(w, "user not connected", http.StatusNotFound)
+			return
+		}
+		audit(r, models.AuditUserDisconnect, models.AuditTargetUser, userID, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminSocketMetricsHandler handles GET /admin/metrics/sockets, returning how often slow WebSocket clients
+// have had messages coalesced or dropped, or been disconnected, since the server started.
+func AdminSocketMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"slowConsumers": game.SlowConsumerMetrics(),
+	})
+}
+
+type setUserRoleRequest struct {
+	UserID string `json:"userID"`
+	Role   string `json:"role"`
+}
+
+// AdminSetUserRoleHandler handles POST /admin/user/role. The new role takes effect on the user's next
+// request, since session tokens are checked against the role on the account.
+func AdminSetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req setUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		apierr.Error(w, "invalid userID", http.StatusBadRequest)
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		apierr.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+	if err := database.Users.SetUserRole(r.Context(), userID, req.Role); err != nil {
+		apierr.Error(w, "failed to set role", http.StatusInternalServerError)
+		return
+	}
+	audit(r, models.AuditUserRole, models.AuditTargetUser, userID, map[string]interface{}{"role": req.Role})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("role updated"))
+}
+
+type kickUserRequest struct {
+	UserID string `json:"userID"`
+}
+
+// ModKickLobbyUserHandler handles POST /mod/lobby/{lobby_id}/kick, disconnecting a user from a lobby.
+func ModKickLobbyUserHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/mod/lobby/"), "/")
+		lobbyIDStr, ok := strings.CutSuffix(path, "/kick")
+		if !ok {
+			apierr.Error(w, "unsupported moderator route", http.StatusNotFound)
+			return
+		}
+		lobbyID, err := uuid.Parse(lobbyIDStr)
+		if err != nil {
+			apierr.Error(w, "invalid lobby_id", http.StatusBadRequest)
+			return
+		}
+		var req kickUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			apierr.Error(w, "invalid userID", http.StatusBadRequest)
+			return
+		}
+
+		lobby, ok := gs.LobbyStore.GetLobby(lobbyID)
+		if !ok {
+			apierr.Error(w, "lobby not found", http.StatusNotFound)
+			return
+		}
+		conn, ok := lobby.Connection(userID)
+		if !ok {
+			apierr.Error(w, "user not connected to lobby", http.StatusNotFound)
+			return
+		}
+		conn.WriteError("you were removed from the lobby by a moderator")
+		conn.Cancel()
+		audit(r, models.AuditLobbyKick, models.AuditTargetUser, userID, map[string]interface{}{"lobbyID": lobbyID})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
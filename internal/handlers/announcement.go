@@ -0,0 +1,76 @@
+// internal/handlers/announcement.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// maxAnnouncementLen bounds an announcement's message, in bytes.
+const maxAnnouncementLen = 500
+
+type announcementRequest struct {
+	Message           string `json:"message"`
+	ShutdownInSeconds int    `json:"shutdownInSeconds"` // 0 if no shutdown is planned
+}
+
+// AdminAnnouncementHandler handles POST /admin/announcement, sending a system message to every lobby and
+// game socket on this node. If a shutdown is planned, its time goes along with the message so clients can
+// count down to it; the server doesn't shut itself down.
+func AdminAnnouncementHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req announcementRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		req.Message = strings.TrimSpace(req.Message)
+		if req.Message == "" || len(req.Message) > maxAnnouncementLen {
+			apierr.Error(w, "message must be 1 to 500 bytes", http.StatusBadRequest)
+			return
+		}
+		if req.ShutdownInSeconds < 0 {
+			apierr.Error(w, "shutdownInSeconds must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		lobbyFrame := map[string]interface{}{"type": "system_announcement", "message": req.Message}
+		gameOther := map[string]interface{}{"message": req.Message}
+		var shutdownAt *time.Time
+		if req.ShutdownInSeconds > 0 {
+			at := time.Now().UTC().Add(time.Duration(req.ShutdownInSeconds) * time.Second).Truncate(time.Second)
+			shutdownAt = &at
+			lobbyFrame["shutdown_at"] = at
+			gameOther["shutdownAt"] = at
+		}
+
+		lobbies := gs.LobbyStore.GetLobbies()
+		for _, lobby := range lobbies {
+			lobby.BroadcastAll(lobbyFrame)
+		}
+		games := 0
+		for _, g := range gs.GameStore.GetGames() {
+			if !g.Summary().GameOver {
+				g.Announce(gameOther)
+				games++
+			}
+		}
+		audit(r, models.AuditAnnouncement, models.AuditTargetServer, uuid.Nil, map[string]interface{}{
+			"message":    req.Message,
+			"shutdownAt": shutdownAt,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lobbies":    len(lobbies),
+			"games":      games,
+			"shutdownAt": shutdownAt,
+		})
+	}
+}
@@ -1,33 +1,93 @@
-// internal/handlers/game_server.go
+// internal/handlers/api_server.go
 package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/cluster"
+	"github.com/jason-s-yu/cambia/internal/crash"
 	"github.com/jason-s-yu/cambia/internal/database"
 	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/matchmaking"
 	"github.com/jason-s-yu/cambia/internal/models"
+	"github.com/jason-s-yu/cambia/internal/presence"
+	"github.com/jason-s-yu/cambia/internal/protocol"
+	"github.com/jason-s-yu/cambia/internal/tournament"
 )
 
+// seriesNextGameDelay is the pause between games of a best-of-N series, so players can see the scoreboard.
+const seriesNextGameDelay = 5 * time.Second
+
+// defaultSpectatorDelay is how far spectators of ranked and tournament games trail the game, unless
+// SPECTATOR_DELAY says otherwise.
+const defaultSpectatorDelay = 90 * time.Second
+
+// spectatorDelay returns the spectator delay for ranked and tournament games: SPECTATOR_DELAY, a duration
+// such as "2m", or "0" for none.
+func spectatorDelay() time.Duration {
+	v := os.Getenv("SPECTATOR_DELAY")
+	if v == "" {
+		return defaultSpectatorDelay
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Warnf("invalid SPECTATOR_DELAY %q, using %v", v, defaultSpectatorDelay)
+		return defaultSpectatorDelay
+	}
+	return d
+}
+
 // GameServer is a high-level struct that holds a reference to a GameStore
 // and can create new games from lobbies
 type GameServer struct {
-	Mutex      sync.Mutex
-	LobbyStore *game.LobbyStore
-	GameStore  *game.GameStore
+	Mutex           sync.Mutex
+	LobbyStore      *game.LobbyStore
+	GameStore       *game.GameStore
+	TournamentStore *tournament.Store
+	Matchmaker      *matchmaking.Matchmaker
+
+	// matchmakingConns are the open /matchmaking/ws connections, by user.
+	matchmakingMu    sync.Mutex
+	matchmakingConns map[uuid.UUID]*game.LobbyConnection
+
+	// Presence tracks who is online, in a lobby, or in a game; changes are pushed to friends over /user/ws.
+	Presence *presence.Tracker
+	// userConns are the open /user/ws notification connections, by user.
+	userConnsMu sync.Mutex
+	userConns   map[uuid.UUID]*game.LobbyConnection
+
+	// handoffs are the outstanding tokens for moving a seat to another device mid-game.
+	handoffs handoffStore
+
+	// Registry, if set, records which node hosts each game, so game sockets opened on other nodes are
+	// proxied here.
+	Registry cluster.Registry
 }
 
 func NewGameServer() *GameServer {
-	return &GameServer{
-		LobbyStore: game.NewLobbyStore(),
-		GameStore:  game.NewGameStore(),
-		Mutex:      sync.Mutex{},
+	gs := &GameServer{
+		LobbyStore:       game.NewLobbyStore(),
+		GameStore:        game.NewGameStore(),
+		TournamentStore:  tournament.NewStore(),
+		Matchmaker:       matchmaking.NewMatchmaker(),
+		Mutex:            sync.Mutex{},
+		matchmakingConns: make(map[uuid.UUID]*game.LobbyConnection),
+		Presence:         presence.NewTracker(),
+		userConns:        make(map[uuid.UUID]*game.LobbyConnection),
 	}
+	gs.Presence.OnChange = gs.broadcastPresence
+	gs.Matchmaker.Notify = gs.notifyMatchmaking
+	gs.Matchmaker.OnMatchReady = gs.NewMatchmadeGame
+	return gs
 }
 
 // NewCambiaGameFromLobby fetches participants, creates an in-memory CambiaGame
@@ -35,20 +95,31 @@ func (gs *GameServer) NewCambiaGameFromLobby(ctx context.Context, lobby *game.Lo
 	g := game.NewCambiaGame()
 	g.LobbyID = lobby.ID
 
+	// marked in game first, so the settings can't change while the game is set up from them
+	lobby.SetInGame(true)
 	g.HouseRules = lobby.HouseRules
+	g.Ranked = lobby.Ranked
+	g.ChatDisabled = lobby.Ranked && lobby.LobbySettings.DisableRankedChat
+	if lobby.Ranked {
+		g.SpectatorDelay = spectatorDelay()
+	}
+	g.OnAbandon = gs.recordAbandon
+	g.OnAnomaly = gs.recordAnomaly
+	g.OnRecorded = gs.awardAchievements
+	g.OnHalted = func(uuid.UUID) {
+		lobby.BroadcastAll(protocol.Errorf(protocol.CodeInternal, "the game was stopped after an internal error").Frame())
+	}
 
-	participants, err := fetchLobbyParticipants(ctx, lobby.ID)
-	if err != nil {
-		log.Printf("error fetching participants for lobby %v: %v\n", lobby.ID, err)
+	g.Players, g.CircuitRound = lobby.CircuitParticipants(lobbyParticipants(ctx, lobby))
+	if teams := lobby.TeamMap(); teams != nil {
+		g.SeatTeams(teams)
 	}
-	g.Players = participants
 
 	// Set OnGameEnd callback
 	g.OnGameEnd = func(lobbyID uuid.UUID, winner uuid.UUID, scores map[uuid.UUID]int) {
+		lobby.SetInGame(false)
 		if ls, exists := gs.LobbyStore.GetLobby(lobbyID); exists {
-			for uid := range ls.Connections {
-				ls.ReadyStates[uid] = false
-			}
+			ls.ResetReadyStates()
 		}
 		resultMsg := map[string]interface{}{
 			"type":   "game_results",
@@ -58,52 +129,291 @@ func (gs *GameServer) NewCambiaGameFromLobby(ctx context.Context, lobby *game.Lo
 		for pid, sc := range scores {
 			resultMsg["scores"].(map[string]int)[pid.String()] = sc
 		}
+		if g.Teams != nil {
+			teamScores := map[string]int{}
+			for pid, sc := range scores {
+				teamScores[strconv.Itoa(g.Teams[pid])] += sc
+			}
+			resultMsg["teamScores"] = teamScores
+		}
 		lobby.BroadcastChat(winner, fmt.Sprintf("Game ended, winner is %v", winner))
 		lobby.BroadcastAll(resultMsg)
+		// circuit standings follow the game results, between games of the series
+		lobby.RecordCircuitRound(g, scores)
+		if lobby.RecordSeriesGame(g, scores) {
+			time.AfterFunc(seriesNextGameDelay, func() {
+				defer crash.Recover("starting the next game of a series")
+				next := gs.NewCambiaGameFromLobby(context.Background(), lobby)
+				lobby.BroadcastAll(map[string]interface{}{
+					"type":    "game_start",
+					"game_id": next.ID.String(),
+				})
+			})
+		}
 	}
 
-	gs.GameStore.AddGame(g)
+	gs.addGame(g)
 
 	g.Start()
 
 	return g
 }
 
-// fetchLobbyParticipants from DB
-func fetchLobbyParticipants(ctx context.Context, lobbyID uuid.UUID) ([]*models.Player, error) {
-	q := `
-		SELECT p.user_id, p.seat_position, u.username, u.is_ephemeral
-		FROM lobby_participants p
-		JOIN users u ON p.user_id = u.id
-		WHERE p.lobby_id = $1
-		ORDER BY p.seat_position
-	`
-	rows, err := database.DB.Query(ctx, q, lobbyID)
+// endAbortedGame cleans up after g was stopped without a result, for reason. Its lobby goes back to waiting
+// for everyone to ready up, and is told why. The game is marked abandoned, so it isn't restored after a
+// restart.
+func (gs *GameServer) endAbortedGame(ctx context.Context, g *game.CambiaGame, reason string) {
+	if lobby, ok := gs.LobbyStore.GetLobby(g.LobbyID); ok {
+		lobby.SetInGame(false)
+		lobby.ResetReadyStates()
+		lobby.BroadcastAll(map[string]interface{}{
+			"type":    "game_aborted",
+			"game_id": g.ID,
+			"reason":  reason,
+		})
+	}
+	if err := database.Games.AbandonGame(ctx, g.ID); err != nil {
+		log.Printf("%v", err)
+	}
+	gs.GameStore.DeleteGame(g.ID)
+}
+
+// NewTournamentGame creates and starts a head-to-head game for a tournament pairing. The players are
+// seated up front and attach their connections when they open /game/ws/{game_id}.
+func (gs *GameServer) NewTournamentGame(t *tournament.Tournament, p *tournament.Pairing) (uuid.UUID, error) {
+	g := game.NewCambiaGame()
+	g.HouseRules = t.HouseRules
+	g.Tournament = true
+	g.SpectatorDelay = spectatorDelay()
+	g.OnAnomaly = gs.recordAnomaly
+	g.OnRecorded = gs.awardAchievements
+	for _, uid := range []uuid.UUID{p.PlayerA, p.PlayerB} {
+		g.Players = append(g.Players, seatedPlayer(uid))
+	}
+
+	g.OnGameEnd = func(_ uuid.UUID, winner uuid.UUID, scores map[uuid.UUID]int) {
+		// equal scores are a draw unless the Cambia caller's tiebreak applies
+		if scores[p.PlayerA] == scores[p.PlayerB] && !g.CambiaCalled {
+			winner = uuid.Nil
+		}
+		if err := t.ReportResult(g.ID, winner); err != nil {
+			log.Printf("tournament %v: failed to report game %v: %v\n", t.ID, g.ID, err)
+		}
+	}
+	// an aborted game has no winner, so the pairing is scored as a draw
+	g.OnAborted = func(gameID uuid.UUID) {
+		if err := t.ReportResult(gameID, uuid.Nil); err != nil {
+			log.Printf("tournament %v: failed to report aborted game %v: %v\n", t.ID, gameID, err)
+		}
+	}
+
+	gs.addGame(g)
+	g.Start()
+	return g.ID, nil
+}
+
+// NewMatchmadeGame creates and starts a ranked game for a matchmaking group that passed its ready-check,
+// using the standard ranked house rules. The players attach when they open /game/ws/{game_id}.
+func (gs *GameServer) NewMatchmadeGame(m *matchmaking.Match) (uuid.UUID, error) {
+	g := game.NewCambiaGame()
+	g.HouseRules = game.RankedProfiles[0].HouseRules // "standard"
+	g.Ranked = true
+	g.SpectatorDelay = spectatorDelay()
+	g.OnAbandon = gs.recordAbandon
+	g.OnAnomaly = gs.recordAnomaly
+	g.OnRecorded = gs.awardAchievements
+	for _, uid := range m.UserIDs() {
+		g.Players = append(g.Players, seatedPlayer(uid))
+	}
+
+	gs.addGame(g)
+	g.Start()
+	return g.ID, nil
+}
+
+// NewPracticeGame creates and starts a practice game between userID and the given number of bots, under
+// rules. The player attaches when they open /game/ws/{game_id}.
+func (gs *GameServer) NewPracticeGame(userID uuid.UUID, bots int, rules game.HouseRules) (*game.CambiaGame, error) {
+	g, err := newPracticeGame(userID, bots, rules)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	gs.addGame(g)
+	g.Start()
+	return g, nil
+}
 
-	var players []*models.Player
-	for rows.Next() {
-		var userID uuid.UUID
-		var seatPos int
-		var username string
-		var ephemeral bool
-		if err := rows.Scan(&userID, &seatPos, &username, &ephemeral); err != nil {
+// newPracticeGame seats userID and the given number of bots at a practice game under rules, without
+// starting it.
+func newPracticeGame(userID uuid.UUID, bots int, rules game.HouseRules) (*game.CambiaGame, error) {
+	g := game.NewCambiaGame()
+	g.HouseRules = rules
+	g.Practice = true
+	g.Players = []*models.Player{{ID: userID, Hand: []*models.Card{}}}
+	for i := 0; i < bots; i++ {
+		bot, err := game.NewBot()
+		if err != nil {
 			return nil, err
 		}
-		p := &models.Player{
-			ID:        userID,
-			Connected: true,
-			Hand:      []*models.Card{},
-			User: &models.User{
-				ID:          userID,
-				Username:    username,
-				IsEphemeral: ephemeral,
-			},
+		g.Players = append(g.Players, bot)
+	}
+	return g, nil
+}
+
+// NewHotSeatGame creates and starts a hot-seat game for the given number of players, seating userID and
+// local seats played over their connection.
+func (gs *GameServer) NewHotSeatGame(userID uuid.UUID, players int, rules game.HouseRules) (*game.CambiaGame, error) {
+	g, err := newHotSeatGame(userID, players, rules)
+	if err != nil {
+		return nil, err
+	}
+	gs.addGame(g)
+	g.Start()
+	return g, nil
+}
+
+// newHotSeatGame seats userID and their local seats at a hot-seat game under rules, without starting it.
+func newHotSeatGame(userID uuid.UUID, players int, rules game.HouseRules) (*game.CambiaGame, error) {
+	g := game.NewCambiaGame()
+	g.HouseRules = rules
+	g.Practice = true
+	g.Players = []*models.Player{{ID: userID, Hand: []*models.Card{}}}
+	for len(g.Players) < players {
+		seat, err := game.NewLocalSeat(userID)
+		if err != nil {
+			return nil, err
+		}
+		g.Players = append(g.Players, seat)
+	}
+	return g, nil
+}
+
+// addGame makes a new game available to connect to, claiming it for this node if there are others.
+func (gs *GameServer) addGame(g *game.CambiaGame) {
+	gs.GameStore.AddGame(g)
+	if gs.Registry != nil {
+		if err := gs.Registry.Claim(g.ID); err != nil {
+			log.Warnf("%v", err)
+		}
+	}
+}
+
+// RestoreGames brings back the games a crash or restart cut off, from their latest checkpoints, and returns
+// their IDs. Players rejoin them through /game/ws as usual. The lobbies and tournaments that started them
+// don't survive a restart, so a restored game's result is recorded but not reported back to them.
+func (gs *GameServer) RestoreGames(ctx context.Context) []uuid.UUID {
+	checkpoints, err := database.Games.LoadGameCheckpoints(ctx)
+	if err != nil {
+		log.Warnf("%v", err)
+		return nil
+	}
+	var restored []uuid.UUID
+	for id, data := range checkpoints {
+		var cp game.Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			log.Warnf("cannot restore game %v: %v", id, err)
+			continue
+		}
+		g := game.RestoreGame(cp)
+		if g.Ranked || g.Tournament {
+			g.SpectatorDelay = spectatorDelay()
+		}
+		g.OnAbandon = gs.recordAbandon
+		g.OnAnomaly = gs.recordAnomaly
+		g.OnRecorded = gs.awardAchievements
+		gs.addGame(g)
+		g.Resume()
+		restored = append(restored, g.ID)
+	}
+	return restored
+}
+
+// recordAbandon applies the abandon penalty for a ranked game a player left. It runs in the background
+// since it's called from the game's end-of-game path, which holds the game lock.
+func (gs *GameServer) recordAbandon(gameID, userID uuid.UUID) {
+	mode := ""
+	if g, ok := gs.GameStore.GetGame(gameID); ok {
+		mode = database.RatingModeForPlayers(len(g.Players))
+	}
+	go func() {
+		defer crash.Recover("recording an abandon")
+		p, err := database.RecordAbandon(context.Background(), userID, gameID, mode)
+		if err != nil {
+			log.Printf("failed to record abandon of game %v by %v: %v\n", gameID, userID, err)
+			return
+		}
+		if p != nil {
+			log.Printf("user %v abandoned ranked game %v: %+v\n", userID, gameID, *p)
+		}
+	}()
+}
+
+// recordAnomaly files a flag for moderators, in the background, for play the game found suspicious.
+func (gs *GameServer) recordAnomaly(a game.Anomaly) {
+	go func() {
+		defer crash.Recover("recording an anti-cheat flag")
+		flag := &models.AnticheatFlag{UserID: a.UserID, GameID: a.GameID, Kind: a.Kind, Turn: a.Turn, Detail: a.Detail}
+		if err := database.InsertAnticheatFlag(context.Background(), flag); err != nil {
+			log.Printf("failed to record %s flag for %v in game %v: %v\n", a.Kind, a.UserID, a.GameID, err)
+		}
+	}()
+}
+
+// notifyMatchmaking delivers a matchmaking message to a user's /matchmaking/ws connection, if open, and
+// puts found matches in their inbox in case the queue page isn't in focus.
+// It never blocks; the matchmaker calls it while holding its lock.
+func (gs *GameServer) notifyMatchmaking(userID uuid.UUID, msg map[string]interface{}) {
+	if msg["type"] == "match_found" {
+		gs.Notify(userID, "match_found", map[string]interface{}{
+			"match_id":  msg["match_id"],
+			"mode":      msg["mode"],
+			"accept_by": msg["accept_by"],
+		})
+	}
+	gs.matchmakingMu.Lock()
+	conn, ok := gs.matchmakingConns[userID]
+	gs.matchmakingMu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case conn.OutChan <- msg:
+	default:
+		game.RecordDroppedFrame()
+		log.Printf("dropping matchmaking message %v for %v: outbox full\n", msg["type"], userID)
+	}
+}
+
+// ForceEndGame forces an in-memory game to score immediately, if it exists.
+func (gs *GameServer) ForceEndGame(gameID uuid.UUID) {
+	if g, ok := gs.GameStore.GetGame(gameID); ok {
+		g.EndGame()
+	}
+}
+
+// seatedPlayer returns a player for userID, who attaches their connection when they open
+// /game/ws/{game_id}, with their account loaded for ratings.
+func seatedPlayer(userID uuid.UUID) *models.Player {
+	p := &models.Player{ID: userID, Hand: []*models.Card{}}
+	if u, err := database.Users.GetUserByID(context.Background(), userID); err != nil {
+		log.Printf("error loading user %v: %v\n", userID, err)
+	} else {
+		p.User = u
+	}
+	return p
+}
+
+// lobbyParticipants seats the lobby's seated users, in seat order, as players of its next game.
+func lobbyParticipants(ctx context.Context, lobby *game.Lobby) []*models.Player {
+	var players []*models.Player
+	for _, userID := range lobby.SeatedUsers() {
+		p := &models.Player{ID: userID, Connected: true, Hand: []*models.Card{}}
+		if u, err := database.Users.GetUserByID(ctx, userID); err != nil {
+			log.Printf("error loading user %v for lobby %v: %v\n", userID, lobby.ID, err)
+		} else {
+			p.User = u
 		}
 		players = append(players, p)
 	}
-	return players, nil
+	return players
 }
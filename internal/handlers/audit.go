@@ -0,0 +1,94 @@
+// internal/handlers/audit.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 500
+)
+
+// audit records a privileged action taken by the request's caller against a target. It's called once the
+// action has succeeded. The entry is written even if the caller has since gone away; a failure to write it
+// is logged but doesn't fail the request.
+func audit(r *http.Request, action, targetType string, targetID uuid.UUID, payload map[string]interface{}) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		log.Printf("not auditing %s on %v: no caller", action, targetID)
+		return
+	}
+	actorID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		log.Printf("not auditing %s on %v: invalid caller %q", action, targetID, claims.UserID)
+		return
+	}
+	e := &models.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		Payload:    payload,
+		RequestID:  middleware.RequestID(r.Context()),
+	}
+	if targetID != uuid.Nil {
+		e.TargetID = &targetID
+	}
+	if err := database.InsertAuditEntry(context.WithoutCancel(r.Context()), e); err != nil {
+		log.Printf("failed to audit %s by %v on %v: %v", action, actorID, targetID, err)
+	}
+}
+
+// AdminAuditLogHandler handles GET /admin/audit, listing audit entries newest first. It can be filtered by
+// ?actorID=, ?targetID= and ?action=, and paged with ?limit= and ?cursor=.
+func AdminAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var f database.AuditFilter
+	var before uuid.UUID
+	for name, dst := range map[string]*uuid.UUID{"actorID": &f.ActorID, "targetID": &f.TargetID, "cursor": &before} {
+		if s := q.Get(name); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				apierr.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dst = id
+		}
+	}
+	f.Action = q.Get("action")
+	limit := defaultAuditLogLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxAuditLogLimit)
+	}
+
+	entries, err := database.ListAuditEntries(r.Context(), f, before, limit)
+	if err != nil {
+		log.Printf("failed to list audit entries: %v", err)
+		apierr.Error(w, "failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Entries    []models.AuditEntry `json:"entries"`
+		NextCursor string              `json:"nextCursor,omitempty"`
+	}{Entries: entries}
+	if len(entries) == limit {
+		resp.NextCursor = entries[len(entries)-1].ID.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,174 @@
+// internal/handlers/ban.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+const (
+	defaultBanListLimit = 50
+	maxBanListLimit     = 500
+)
+
+type createBanRequest struct {
+	UserID string `json:"userID"`
+	sanctionRequest
+	AppealNotes string `json:"appealNotes"`
+}
+
+// AdminBansHandler serves ban management. It expects to be mounted behind middleware.RequireRole.
+//
+//	GET  /admin/bans                 bans, newest first (?userID=, ?active=true, ?limit=)
+//	POST /admin/bans                 { "userID", "scope": "global" | "matchmaking", "durationMinutes", "reason", "appealNotes" }
+//	POST /admin/bans/{id}/lift       ends a ban early
+//	POST /admin/bans/{id}/appeal     { "appealNotes": "..." } replaces the ban's appeal notes
+//
+// durationMinutes 0 is permanent. Global bans keep the user from signing in or connecting to any socket;
+// matchmaking bans only keep them out of the ranked queue.
+func AdminBansHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, ok := claimsUserID(w, r)
+		if !ok {
+			return
+		}
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/bans"), "/")
+		if path == "" {
+			switch r.Method {
+			case http.MethodGet:
+				listBans(w, r)
+			case http.MethodPost:
+				gs.createBan(w, r, adminID)
+			default:
+				apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		parts := strings.Split(path, "/")
+		if len(parts) != 2 {
+			apierr.Error(w, "unsupported admin route", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		banID, err := uuid.Parse(parts[0])
+		if err != nil {
+			apierr.Error(w, "invalid ban id", http.StatusBadRequest)
+			return
+		}
+
+		var ban *models.Sanction
+		switch parts[1] {
+		case "lift":
+			ban, err = database.LiftBan(r.Context(), banID, adminID)
+		case "appeal":
+			var req struct {
+				AppealNotes string `json:"appealNotes"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apierr.Error(w, "invalid payload", http.StatusBadRequest)
+				return
+			}
+			ban, err = database.SetBanAppealNotes(r.Context(), banID, req.AppealNotes)
+		default:
+			apierr.Error(w, "unsupported admin route", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, database.ErrBanNotFound) {
+			apierr.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to update ban %v: %v", banID, err)
+			apierr.Error(w, "failed to update ban", http.StatusInternalServerError)
+			return
+		}
+		if parts[1] == "lift" {
+			log.Printf("admin %v lifted ban %v on %v", adminID, banID, ban.UserID)
+			audit(r, models.AuditBanLift, models.AuditTargetBan, banID, map[string]interface{}{"userID": ban.UserID})
+		} else {
+			audit(r, models.AuditBanAppeal, models.AuditTargetBan, banID, map[string]interface{}{
+				"userID":      ban.UserID,
+				"appealNotes": ban.AppealNotes,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ban)
+	}
+}
+
+func listBans(w http.ResponseWriter, r *http.Request) {
+	userID := uuid.Nil
+	if s := r.URL.Query().Get("userID"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid userID", http.StatusBadRequest)
+			return
+		}
+		userID = id
+	}
+	limit := defaultBanListLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxBanListLimit)
+	}
+
+	bans, err := database.ListBans(r.Context(), userID, r.URL.Query().Get("active") == "true", limit)
+	if err != nil {
+		log.Printf("failed to list bans: %v", err)
+		apierr.Error(w, "failed to load bans", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"bans": bans})
+}
+
+func (gs *GameServer) createBan(w http.ResponseWriter, r *http.Request, adminID uuid.UUID) {
+	var req createBanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		apierr.Error(w, "invalid userID", http.StatusBadRequest)
+		return
+	}
+	ban, err := newSanction(models.SanctionBan, adminID, req.sanctionRequest)
+	if err != nil {
+		apierr.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ban.UserID = userID
+	ban.AppealNotes = req.AppealNotes
+
+	if err := database.IssueSanction(r.Context(), ban); err != nil {
+		log.Printf("failed to ban %v: %v", userID, err)
+		apierr.Error(w, "failed to ban user", http.StatusInternalServerError)
+		return
+	}
+	gs.applySanction(ban)
+	log.Printf("admin %v banned %v (%s)", adminID, userID, ban.Scope)
+	audit(r, models.AuditUserBan, models.AuditTargetUser, userID, sanctionPayload(ban))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ban)
+}
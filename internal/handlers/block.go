@@ -0,0 +1,116 @@
+// internal/handlers/block.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+// decodeTargetUser reads { "user_id": "some-uuid-string" } from the request body.
+func decodeTargetUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(req.UserID)
+	if err != nil {
+		apierr.Error(w, "invalid user_id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// BlockUserHandler handles POST /user/block. Blocking a user ends any friendship with them, hides their
+// lobby chat from the blocker, keeps the two out of the same ranked match, and rejects their invites.
+//
+// Request payload: { "user_id": "some-uuid-string" }
+func BlockUserHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		target, ok := decodeTargetUser(w, r)
+		if !ok {
+			return
+		}
+		if target == userID {
+			apierr.Error(w, "cannot block yourself", http.StatusBadRequest)
+			return
+		}
+
+		if err := database.BlockUser(r.Context(), userID, target); err != nil {
+			apierr.Error(w, fmt.Sprintf("failed to block user: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// apply the block to any lobby the blocker is already in
+		for _, lobby := range gs.LobbyStore.GetLobbies() {
+			if conn, ok := lobby.Connection(userID); ok {
+				conn.Block(target)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("user blocked"))
+	}
+}
+
+// UnblockUserHandler handles POST /user/unblock.
+//
+// Request payload: { "user_id": "some-uuid-string" }
+func UnblockUserHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		target, ok := decodeTargetUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := database.UnblockUser(r.Context(), userID, target); err != nil {
+			apierr.Error(w, fmt.Sprintf("failed to unblock user: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for _, lobby := range gs.LobbyStore.GetLobbies() {
+			if conn, ok := lobby.Connection(userID); ok {
+				conn.Unblock(target)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("user unblocked"))
+	}
+}
+
+// ListBlocksHandler handles GET /user/blocks, returning the IDs of the users the caller has blocked.
+func ListBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	ids, err := database.ListBlockedIDs(r.Context(), userID)
+	if err != nil {
+		apierr.Error(w, fmt.Sprintf("failed to list blocks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ids)
+}
@@ -0,0 +1,154 @@
+// internal/handlers/challenge.go
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/crash"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+// dailyChallengeBots is how many bots every daily challenge is played against.
+const dailyChallengeBots = 2
+
+// challengeDay returns the UTC day t falls on, which names the daily challenge.
+func challengeDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// StartDailyChallengeHandler handles POST /challenge/daily, starting the caller's one attempt at today's
+// challenge: a practice game against standard bots under the "standard" ranked rules, dealt the same cards
+// as everyone else's. The caller joins through /game/ws/{game_id}; their final hand score goes on the
+// day's board when the game ends. A second attempt gets 409.
+func StartDailyChallengeHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		if _, limit, reached := gs.gameLimitReached(userID); reached {
+			writeGameLimit(w, userID, limit)
+			return
+		}
+		day := challengeDay(time.Now())
+		seed, err := database.DailyChallengeSeed(r.Context(), day)
+		if err != nil {
+			log.Printf("%v", err)
+			apierr.Error(w, "failed to load today's challenge", http.StatusInternalServerError)
+			return
+		}
+
+		g, err := newPracticeGame(userID, dailyChallengeBots, game.RankedProfiles[0].HouseRules)
+		if err != nil {
+			log.Printf("failed to set up daily challenge for %v: %v", userID, err)
+			apierr.Error(w, "failed to start today's challenge", http.StatusInternalServerError)
+			return
+		}
+		g.DealSeed = seed
+		g.DealSeedSecretUntil = day.Add(24 * time.Hour)
+		g.OnGameEnd = func(_ uuid.UUID, winner uuid.UUID, scores map[uuid.UUID]int) {
+			score, won, turns := scores[userID], winner == userID, g.TurnID
+			go func() {
+				defer crash.Recover("recording a daily challenge result")
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := database.FinishDailyChallenge(ctx, day, userID, score, won, turns); err != nil {
+					log.Printf("%v", err)
+				}
+			}()
+		}
+
+		started, err := database.StartDailyChallenge(r.Context(), day, userID, g.ID)
+		if err != nil {
+			log.Printf("%v", err)
+			apierr.Error(w, "failed to start today's challenge", http.StatusInternalServerError)
+			return
+		}
+		if !started {
+			apierr.Error(w, "you have already played today's challenge", http.StatusConflict)
+			return
+		}
+		gs.addGame(g)
+		g.Start()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"gameID": g.ID,
+			"day":    day.Format(time.DateOnly),
+		})
+	}
+}
+
+// DailyChallengeBoardHandler handles GET /challenge/daily, listing the best finished attempts at a day's
+// challenge: today's, or the one named by ?day=YYYY-MM-DD. ?limit= sets how many, default 50, max 200. If
+// the caller is authenticated, the response also includes their own entry as "me". Once a day is over, its
+// deal seed is included too, for checking the shuffles of its games.
+func DailyChallengeBoardHandler(w http.ResponseWriter, r *http.Request) {
+	day := challengeDay(time.Now())
+	if s := r.URL.Query().Get("day"); s != "" {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			apierr.Error(w, "invalid day", http.StatusBadRequest)
+			return
+		}
+		day = d
+	}
+	limit := defaultLeaderboardLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxLeaderboardLimit)
+	}
+
+	entries, err := database.GetDailyChallengeBoard(r.Context(), day, limit)
+	if err != nil {
+		log.Printf("%v", err)
+		apierr.Error(w, "failed to load the challenge board", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Day     string                         `json:"day"`
+		Seed    string                         `json:"seed,omitempty"`
+		Entries []database.DailyChallengeEntry `json:"entries"`
+		Me      *database.DailyChallengeEntry  `json:"me,omitempty"`
+	}{Day: day.Format(time.DateOnly), Entries: entries}
+
+	// a day's seed stays secret until everyone's had their chance at its deal
+	if day.Before(challengeDay(time.Now())) {
+		seed, err := database.GetDailyChallengeSeed(r.Context(), day)
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		if seed != nil {
+			resp.Seed = hex.EncodeToString(seed)
+		}
+	}
+
+	// as on the rating leaderboards, anonymous callers just don't get their own entry
+	if token := auth.RequestToken(r); token != "" {
+		if userIDStr, err := auth.AuthenticateJWT(token); err == nil {
+			if userID, err := uuid.Parse(userIDStr); err == nil {
+				me, err := database.GetDailyChallengeRank(r.Context(), day, userID)
+				if err != nil {
+					log.Printf("%v", err)
+				}
+				resp.Me = me
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,187 @@
+// internal/handlers/direct_message.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxDirectMessageLength caps the length of a direct message, in characters.
+	maxDirectMessageLength = 1000
+
+	defaultConversationLimit = 50
+	maxConversationLimit     = 200
+)
+
+var errNotFriends = errors.New("direct messages are only allowed between friends")
+
+// SendDirectMessage stores a message from one friend to another and pushes it to the recipient's /user/ws
+// connection, if open. Only users with an accepted friendship can message each other; blocking a user ends
+// the friendship, so it also stops their messages. Muted users can't send messages.
+func (gs *GameServer) SendDirectMessage(ctx context.Context, from, to uuid.UUID, body string) (*models.DirectMessage, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("message is empty")
+	}
+	if utf8.RuneCountInString(body) > maxDirectMessageLength {
+		return nil, errors.New("message is too long")
+	}
+	muted, err := database.IsSanctioned(ctx, from, models.SanctionMute)
+	if err != nil {
+		return nil, err
+	}
+	if muted {
+		return nil, errors.New("you are muted")
+	}
+	friends, err := database.AreFriends(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !friends {
+		return nil, errNotFriends
+	}
+
+	m, err := database.InsertDirectMessage(ctx, from, to, body)
+	if err != nil {
+		return nil, err
+	}
+	gs.notifyUser(to, map[string]interface{}{
+		"type":    "direct_message",
+		"message": m,
+	})
+	return m, nil
+}
+
+// handleDirectMessage handles a "dm_send" packet from a /user/ws connection:
+// {"type": "dm_send", "to": "{uuid}", "body": "..."}. The sender gets the stored message back as "dm_sent".
+func (gs *GameServer) handleDirectMessage(ctx context.Context, conn *game.LobbyConnection, packet map[string]interface{}) {
+	from := conn.UserID
+	toStr, _ := packet["to"].(string)
+	to, err := uuid.Parse(toStr)
+	if err != nil {
+		conn.WriteError("invalid recipient")
+		return
+	}
+	body, _ := packet["body"].(string)
+	m, err := gs.SendDirectMessage(ctx, from, to, body)
+	if err != nil {
+		logrus.Infof("direct message from %v to %v rejected: %v", from, to, err)
+		conn.WriteError(err.Error())
+		return
+	}
+	conn.Write(map[string]interface{}{
+		"type":    "dm_sent",
+		"message": m,
+	})
+}
+
+// DirectMessagesHandler serves the caller's direct messages:
+//
+//	GET  /user/messages              unread message counts, keyed by friend ID
+//	GET  /user/messages/{id}         the conversation with a friend, newest first (?limit=&cursor=)
+//	POST /user/messages/{id}/read    marks the friend's messages to the caller as read
+func DirectMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/user/messages"), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		counts, err := database.CountUnreadMessages(r.Context(), userID)
+		if err != nil {
+			log.Printf("failed to count direct messages for %v: %v", userID, err)
+			apierr.Error(w, "failed to load messages", http.StatusInternalServerError)
+			return
+		}
+		unread := make(map[string]int, len(counts))
+		for id, n := range counts {
+			unread[id.String()] = n
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"unread": unread})
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	friendID, err := uuid.Parse(parts[0])
+	if err != nil {
+		apierr.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		listConversation(w, r, userID, friendID)
+	case len(parts) == 2 && parts[1] == "read" && r.Method == http.MethodPost:
+		marked, err := database.MarkConversationRead(r.Context(), userID, friendID)
+		if err != nil {
+			log.Printf("failed to mark direct messages read for %v: %v", userID, err)
+			apierr.Error(w, "failed to update messages", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"marked": marked})
+	case len(parts) <= 2:
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		apierr.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// listConversation writes a page of the conversation between the caller and a friend.
+func listConversation(w http.ResponseWriter, r *http.Request, userID, friendID uuid.UUID) {
+	limit := defaultConversationLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxConversationLimit)
+	}
+	before := uuid.Nil
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		before = id
+	}
+
+	messages, err := database.ListConversation(r.Context(), userID, friendID, before, limit)
+	if err != nil {
+		log.Printf("failed to list direct messages for %v: %v", userID, err)
+		apierr.Error(w, "failed to load messages", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Messages   []models.DirectMessage `json:"messages"`
+		NextCursor string                 `json:"nextCursor,omitempty"`
+	}{Messages: messages}
+	if len(messages) == limit {
+		resp.NextCursor = messages[len(messages)-1].ID.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
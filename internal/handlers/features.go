@@ -0,0 +1,53 @@
+// internal/handlers/features.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/features"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// AdminFeaturesHandler handles GET /admin/features, listing every feature flag and whether it's on.
+func AdminFeaturesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"features": features.All()})
+}
+
+// AdminSetFeatureHandler serves a single feature flag on this node:
+//
+//	PUT    /admin/features/{name}   { "enabled": true | false } overrides the flag until a restart
+//	DELETE /admin/features/{name}   drops the override, returning the flag to its configured state
+func AdminSetFeatureHandler(w http.ResponseWriter, r *http.Request) {
+	flag := features.Flag(r.PathValue("name"))
+	var err error
+	payload := map[string]interface{}{"name": flag}
+	switch r.Method {
+	case http.MethodPut:
+		var req struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			apierr.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		err = features.Set(flag, *req.Enabled)
+		payload["enabled"] = *req.Enabled
+	case http.MethodDelete:
+		err = features.Reset(flag)
+		payload["reset"] = true
+	default:
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		apierr.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	audit(r, models.AuditFeatureSet, models.AuditTargetFeature, uuid.Nil, payload)
+
+	AdminFeaturesHandler(w, r)
+}
@@ -5,10 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/google/uuid"
-	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/apierr"
 	"github.com/jason-s-yu/cambia/internal/database"
 )
 
@@ -17,21 +16,8 @@ import (
 // Request payload: { "friend_id": "some-uuid-string" }
 // We store a row in the friends table with status='pending'.
 func AddFriendHandler(w http.ResponseWriter, r *http.Request) {
-	cookieHeader := r.Header.Get("Cookie")
-	if !strings.Contains(cookieHeader, "auth_token=") {
-		http.Error(w, "missing auth_token", http.StatusUnauthorized)
-		return
-	}
-	jwtToken := extractCookieToken(cookieHeader, "auth_token")
-
-	userIDStr, err := auth.AuthenticateJWT(jwtToken)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusForbidden)
-		return
-	}
-	userUUID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		http.Error(w, "invalid user id in token", http.StatusBadRequest)
+	userUUID, ok := authenticateRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -39,27 +25,36 @@ func AddFriendHandler(w http.ResponseWriter, r *http.Request) {
 		FriendID string `json:"friend_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
 	friendUUID, err := uuid.Parse(req.FriendID)
 	if err != nil {
-		http.Error(w, "invalid friend_id", http.StatusBadRequest)
+		apierr.Error(w, "invalid friend_id", http.StatusBadRequest)
 		return
 	}
 
 	if userUUID == friendUUID {
-		http.Error(w, "cannot friend yourself", http.StatusBadRequest)
+		apierr.Error(w, "cannot friend yourself", http.StatusBadRequest)
 		return
 	}
 
 	ctx := r.Context()
+	for _, pair := range [][2]uuid.UUID{{friendUUID, userUUID}, {userUUID, friendUUID}} {
+		if blocked, err := database.HasBlocked(ctx, pair[0], pair[1]); err != nil || blocked {
+			apierr.Error(w, "cannot send a friend request to this user", http.StatusForbidden)
+			return
+		}
+	}
+
 	err = database.InsertFriendRequest(ctx, userUUID, friendUUID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to insert friend request: %v", err), http.StatusInternalServerError)
+		apierr.Error(w, fmt.Sprintf("failed to insert friend request: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	notifyFriendEvent("friend_request", userUUID, friendUUID)
+
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte("friend request sent"))
 }
@@ -70,21 +65,8 @@ func AddFriendHandler(w http.ResponseWriter, r *http.Request) {
 // This means the user with friend_id had previously called AddFriendHandler, and now
 // we set status='accepted' for (friend_id -> user).
 func AcceptFriendHandler(w http.ResponseWriter, r *http.Request) {
-	cookieHeader := r.Header.Get("Cookie")
-	if !strings.Contains(cookieHeader, "auth_token=") {
-		http.Error(w, "missing auth_token", http.StatusUnauthorized)
-		return
-	}
-	jwtToken := extractCookieToken(cookieHeader, "auth_token")
-
-	userIDStr, err := auth.AuthenticateJWT(jwtToken)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusForbidden)
-		return
-	}
-	userUUID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		http.Error(w, "invalid user id in token", http.StatusBadRequest)
+	userUUID, ok := authenticateRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -92,22 +74,24 @@ func AcceptFriendHandler(w http.ResponseWriter, r *http.Request) {
 		FriendID string `json:"friend_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
 	friendUUID, err := uuid.Parse(req.FriendID)
 	if err != nil {
-		http.Error(w, "invalid friend_id", http.StatusBadRequest)
+		apierr.Error(w, "invalid friend_id", http.StatusBadRequest)
 		return
 	}
 
 	// The pending request was from friendUUID -> userUUID
 	err = database.AcceptFriend(r.Context(), friendUUID, userUUID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to accept friend: %v", err), http.StatusBadRequest)
+		apierr.Error(w, fmt.Sprintf("failed to accept friend: %v", err), http.StatusBadRequest)
 		return
 	}
 
+	notifyFriendEvent("friend_accepted", userUUID, friendUUID)
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("friend request accepted"))
 }
@@ -115,28 +99,15 @@ func AcceptFriendHandler(w http.ResponseWriter, r *http.Request) {
 // ListFriendsHandler returns a JSON array of all friend relationships (pending or accepted)
 // associated with the authenticated user.
 func ListFriendsHandler(w http.ResponseWriter, r *http.Request) {
-	cookieHeader := r.Header.Get("Cookie")
-	if !strings.Contains(cookieHeader, "auth_token=") {
-		http.Error(w, "missing auth_token", http.StatusUnauthorized)
-		return
-	}
-	jwtToken := extractCookieToken(cookieHeader, "auth_token")
-
-	userIDStr, err := auth.AuthenticateJWT(jwtToken)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusForbidden)
-		return
-	}
-	userUUID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		http.Error(w, "invalid user id in token", http.StatusBadRequest)
+	userUUID, ok := authenticateRequest(w, r)
+	if !ok {
 		return
 	}
 
 	ctx := r.Context()
 	friends, err := database.ListFriends(ctx, userUUID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to list friends: %v", err), http.StatusInternalServerError)
+		apierr.Error(w, fmt.Sprintf("failed to list friends: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -148,21 +119,8 @@ func ListFriendsHandler(w http.ResponseWriter, r *http.Request) {
 //
 // Request payload: { "friend_id": "some-uuid-string" }
 func RemoveFriendHandler(w http.ResponseWriter, r *http.Request) {
-	cookieHeader := r.Header.Get("Cookie")
-	if !strings.Contains(cookieHeader, "auth_token=") {
-		http.Error(w, "missing auth_token", http.StatusUnauthorized)
-		return
-	}
-	jwtToken := extractCookieToken(cookieHeader, "auth_token")
-
-	userIDStr, err := auth.AuthenticateJWT(jwtToken)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusForbidden)
-		return
-	}
-	userUUID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		http.Error(w, "invalid user id in token", http.StatusBadRequest)
+	userUUID, ok := authenticateRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -170,20 +128,22 @@ func RemoveFriendHandler(w http.ResponseWriter, r *http.Request) {
 		FriendID string `json:"friend_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
 	friendUUID, err := uuid.Parse(req.FriendID)
 	if err != nil {
-		http.Error(w, "invalid friend_id", http.StatusBadRequest)
+		apierr.Error(w, "invalid friend_id", http.StatusBadRequest)
 		return
 	}
 
 	err = database.RemoveFriend(r.Context(), userUUID, friendUUID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to remove friend: %v", err), http.StatusInternalServerError)
+		apierr.Error(w, fmt.Sprintf("failed to remove friend: %v", err), http.StatusInternalServerError)
 		return
 	}
+	notifyFriendEvent("friend_removed", userUUID, friendUUID)
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("friend removed"))
 }
@@ -3,66 +3,184 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
-	"strings"
 
 	"github.com/google/uuid"
-	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/apierr"
 	"github.com/jason-s-yu/cambia/internal/game"
 )
 
-// ServeHTTP is a HTTP handler that parses routes to /game/ws and redirects to the appropriate controller.
-//
-// Deprecated: this function no longer handles the read loop. For WS, see game_ws.go's GameWSHandler.
-func (s *GameServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// e.g. POST /game/create
-	if r.URL.Path == "/game/create" && r.Method == http.MethodPost {
-		s.handleCreateGame(w, r)
-		return
-	}
+// CreateGameHandler handles POST /game/create, which creates a bare in-memory CambiaGame for debugging.
+func CreateGameHandler(s *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cg := game.NewCambiaGame()
+		s.GameStore.AddGame(cg)
 
-	// e.g. GET /game/reconnect/{uuid}
-	if strings.HasPrefix(r.URL.Path, "/game/reconnect/") {
-		s.handleReconnect(w, r)
-		return
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"game_id": cg.ID,
+		})
 	}
-
-	// otherwise, if you want WebSocket, see game_ws.go
-	http.Error(w, "unsupported route, use /game/ws/{id} for websockets", http.StatusNotFound)
 }
 
-// handleCreateGame simply creates a new in-memory CambiaGame for debugging.
-func (s *GameServer) handleCreateGame(w http.ResponseWriter, r *http.Request) {
-	cg := game.NewCambiaGame()
-	s.GameStore.AddGame(cg)
+// maxPracticeBots is the most bots a practice game can seat.
+const maxPracticeBots = 3
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"game_id": cg.ID,
-	})
-}
+// PracticeGameHandler handles POST /game/practice, which starts an unranked game between the caller and 1-3
+// bots straight away, without a lobby, e.g. {"bots": 2, "preset": "blitz"}. As for a lobby, the house rules
+// start from the defaults, a preset, or a saved template, and "houseRules" overrides individual rules. The
+// caller joins through /game/ws/{game_id} as usual. Practice games aren't saved.
+func PracticeGameHandler(s *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Bots           int             `json:"bots"`
+			Preset         string          `json:"preset"`
+			RuleTemplateID *uuid.UUID      `json:"ruleTemplateID"`
+			HouseRules     json.RawMessage `json:"houseRules"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad practice game payload")
+			return
+		}
+		if req.Bots < 1 || req.Bots > maxPracticeBots {
+			apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, fmt.Sprintf("bots must be between 1 and %d", maxPracticeBots), map[string]interface{}{"field": "bots"})
+			return
+		}
+		rules, ok := requestedRules(w, r, userID, req.Preset, req.RuleTemplateID, req.HouseRules, "bad practice game payload")
+		if !ok {
+			return
+		}
 
-// handleReconnect is an example route if you want to reconnect a user by HTTP, but the WS approach is recommended.
-func (s *GameServer) handleReconnect(w http.ResponseWriter, r *http.Request) {
-	gameIDStr := strings.TrimPrefix(r.URL.Path, "/game/reconnect/")
-	gameID, err := uuid.Parse(gameIDStr)
-	if err != nil {
-		http.Error(w, "invalid game id", http.StatusBadRequest)
-		return
+		if _, limit, reached := s.gameLimitReached(userID); reached {
+			writeGameLimit(w, userID, limit)
+			return
+		}
+
+		g, err := s.NewPracticeGame(userID, req.Bots, rules)
+		if err != nil {
+			log.Printf("failed to start practice game for %v: %v", userID, err)
+			apierr.Error(w, "failed to start practice game", http.StatusInternalServerError)
+			return
+		}
+		bots := make([]uuid.UUID, 0, req.Bots)
+		g.Do(func() {
+			for _, p := range g.Players {
+				if p.Bot {
+					bots = append(bots, p.ID)
+				}
+			}
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"gameID": g.ID,
+			"bots":   bots,
+		})
 	}
-	g, ok := s.GameStore.GetGame(gameID)
+}
+
+// requestedRules builds the house rules a request for a game without a lobby asks for: the defaults, a
+// preset, or a saved template, with any rules given in raw on top. A bad request is answered with
+// badPayload or the invalid rule, and ok is false.
+func requestedRules(w http.ResponseWriter, r *http.Request, userID uuid.UUID, preset string, templateID *uuid.UUID, raw json.RawMessage, badPayload string) (rules game.HouseRules, ok bool) {
+	rules = game.DefaultHouseRules()
+	base, ok := lobbyBaseRules(w, r, userID, preset, templateID)
 	if !ok {
-		http.Error(w, "game not found", http.StatusNotFound)
-		return
+		return rules, false
+	}
+	if base != nil {
+		rules = *base
 	}
-	token := extractTokenFromCookie(r.Header.Get("Cookie"))
-	userIDStr, err := auth.AuthenticateJWT(token)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusForbidden)
-		return
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &rules); err != nil {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, badPayload)
+			return rules, false
+		}
 	}
-	userUUID, _ := uuid.Parse(userIDStr)
+	if err := rules.Validate(); err != nil {
+		apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, err.Error(), map[string]interface{}{"field": "houseRules"})
+		return rules, false
+	}
+	return rules, true
+}
 
-	g.HandleReconnect(userUUID)
-	w.Write([]byte("Reconnected successfully. Now open WebSocket again to continue."))
+// maxHotSeatPlayers is the most players a hot-seat game can seat at one device.
+const maxHotSeatPlayers = 4
+
+// HotSeatGameHandler handles POST /game/hotseat, which starts an unranked game for 2-4 players sharing the
+// caller's device, e.g. {"players": 3, "preset": "casual"}. House rules are chosen as for a practice game.
+// The caller plays the first seat and the others are local seats, all over the caller's connection to
+// /game/ws/{game_id}; the response lists the seats in order. Hot-seat games aren't saved.
+func HotSeatGameHandler(s *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Players        int             `json:"players"`
+			Preset         string          `json:"preset"`
+			RuleTemplateID *uuid.UUID      `json:"ruleTemplateID"`
+			HouseRules     json.RawMessage `json:"houseRules"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad hot-seat game payload")
+			return
+		}
+		if req.Players < 2 || req.Players > maxHotSeatPlayers {
+			apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, fmt.Sprintf("players must be between 2 and %d", maxHotSeatPlayers), map[string]interface{}{"field": "players"})
+			return
+		}
+		rules, ok := requestedRules(w, r, userID, req.Preset, req.RuleTemplateID, req.HouseRules, "bad hot-seat game payload")
+		if !ok {
+			return
+		}
+
+		if _, limit, reached := s.gameLimitReached(userID); reached {
+			writeGameLimit(w, userID, limit)
+			return
+		}
+
+		g, err := s.NewHotSeatGame(userID, req.Players, rules)
+		if err != nil {
+			log.Printf("failed to start hot-seat game for %v: %v", userID, err)
+			apierr.Error(w, "failed to start hot-seat game", http.StatusInternalServerError)
+			return
+		}
+		seats := append([]uuid.UUID{userID}, g.LocalSeats(userID)...)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"gameID": g.ID,
+			"seats":  seats,
+		})
+	}
+}
+
+// ReconnectGameHandler handles POST /game/reconnect/{game_id}, marking the caller as reconnected over HTTP;
+// reopening the game WebSocket is the usual way back in.
+func ReconnectGameHandler(s *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := uuid.Parse(r.PathValue("game_id"))
+		if err != nil {
+			apierr.Error(w, "invalid game id", http.StatusBadRequest)
+			return
+		}
+		g, ok := s.GameStore.GetGame(gameID)
+		if !ok {
+			apierr.Write(w, http.StatusNotFound, apierr.CodeGameNotFound, "game not found")
+			return
+		}
+		userUUID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+
+		g.HandleReconnect(userUUID)
+		w.Write([]byte("Reconnected successfully. Now open WebSocket again to continue."))
+	}
 }
@@ -0,0 +1,132 @@
+// internal/handlers/game_proxy.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// proxiedHeader marks a game socket relayed from another node, so it's never relayed again.
+const proxiedHeader = "X-Cambia-Proxied"
+
+// proxyReadLimit caps the frames read from the hosting node; snapshots can outgrow the default limit.
+const proxyReadLimit = 1 << 20
+
+// remoteOwner returns the URL of the other node hosting a game this node doesn't have, or "" if the game
+// should be treated as not found.
+func remoteOwner(gs *GameServer, r *http.Request, gameID uuid.UUID, logger logrus.FieldLogger) string {
+	if gs.Registry == nil || r.Header.Get(proxiedHeader) != "" {
+		return ""
+	}
+	owner, err := gs.Registry.Owner(gameID)
+	if err != nil {
+		logger.Warnf("%v", err)
+		return ""
+	}
+	if owner == gs.Registry.Self() {
+		return ""
+	}
+	return owner
+}
+
+// proxyGameSocket relays a game socket to the node hosting the game. The upgrade request is replayed
+// there with the client's credentials, capabilities, and subprotocols, and the host's handshake headers
+// and any error response are passed back, so the client can't tell it was relayed.
+func proxyGameSocket(w http.ResponseWriter, r *http.Request, owner string, logger logrus.FieldLogger) {
+	// r.RequestURI keeps the API version prefix, which routing strips from r.URL
+	target := strings.TrimSuffix(owner, "/") + r.RequestURI
+	target = "ws" + strings.TrimPrefix(target, "http")
+
+	header := http.Header{}
+	for _, name := range []string{"Authorization", "Cookie", "X-Cambia-Capabilities", "X-Cambia-Encoding", handoffHeader, middleware.RequestIDHeader} {
+		if v := r.Header.Values(name); len(v) > 0 {
+			header[name] = v
+		}
+	}
+	header.Set(proxiedHeader, "1")
+	var subprotocols []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			subprotocols = append(subprotocols, strings.TrimSpace(p))
+		}
+	}
+
+	upstream, resp, err := websocket.Dial(r.Context(), target, &websocket.DialOptions{
+		HTTPHeader:   header,
+		Subprotocols: subprotocols,
+	})
+	if resp != nil {
+		for name, v := range resp.Header {
+			switch http.CanonicalHeaderKey(name) {
+			case "Upgrade", "Connection", "Sec-Websocket-Accept", "Sec-Websocket-Protocol", "Sec-Websocket-Extensions":
+			default:
+				w.Header()[name] = v
+			}
+		}
+	}
+	if err != nil {
+		logger.Warnf("failed to relay game socket to %s: %v", owner, err)
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			w.WriteHeader(resp.StatusCode)
+			if resp.Body != nil {
+				io.Copy(w, resp.Body)
+			}
+			return
+		}
+		apierr.Write(w, http.StatusBadGateway, apierr.CodeUnavailable, "game host unavailable")
+		return
+	}
+	upstream.SetReadLimit(proxyReadLimit)
+
+	var accepted []string
+	if p := upstream.Subprotocol(); p != "" {
+		accepted = []string{p}
+	}
+	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols:   accepted,
+		OriginPatterns: middleware.AllowedOrigins,
+	})
+	if err != nil {
+		logger.Warnf("websocket accept error: %v", err)
+		upstream.Close(websocket.StatusGoingAway, "client gone")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 2)
+	go func() { done <- relayFrames(ctx, c, upstream) }()
+	go func() { done <- relayFrames(ctx, upstream, c) }()
+	err = <-done
+
+	// pass whichever side's close on to the other
+	status, reason := websocket.StatusGoingAway, "relay closed"
+	var ce websocket.CloseError
+	if errors.As(err, &ce) {
+		status, reason = ce.Code, ce.Reason
+	}
+	c.Close(status, reason)
+	upstream.Close(status, reason)
+}
+
+// relayFrames copies frames from src to dst until either fails.
+func relayFrames(ctx context.Context, dst, src *websocket.Conn) error {
+	for {
+		typ, data, err := src.Read(ctx)
+		if err != nil {
+			return err
+		}
+		if err := dst.Write(ctx, typ, data); err != nil {
+			return err
+		}
+	}
+}
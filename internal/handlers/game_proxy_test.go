@@ -0,0 +1,73 @@
+// internal/handlers/game_proxy_test.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/sirupsen/logrus"
+)
+
+func TestProxyGameSocketForwardsHandoff(t *testing.T) {
+	got := make(chan http.Header, 1)
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Clone()
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		c.Close(websocket.StatusNormalClosure, "")
+	}))
+	defer host.Close()
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyGameSocket(w, r, host.URL, logrus.New())
+	}))
+	defer node.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	header := http.Header{}
+	header.Set(handoffHeader, "token")
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(node.URL, "http")+"/game/ws/x", &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		t.Fatalf("dial through proxy: %v", err)
+	}
+	defer c.CloseNow()
+
+	select {
+	case h := <-got:
+		if h.Get(handoffHeader) != "token" {
+			t.Fatalf("expected the handoff token to reach the hosting node, got %q", h.Get(handoffHeader))
+		}
+		if h.Get(proxiedHeader) == "" {
+			t.Fatal("expected the relayed request to be marked as proxied")
+		}
+	case <-ctx.Done():
+		t.Fatal("the hosting node never saw the upgrade request")
+	}
+}
+
+func TestProxyGameSocketHostDown(t *testing.T) {
+	host := httptest.NewServer(http.NotFoundHandler())
+	hostURL := host.URL
+	host.Close()
+
+	req := httptest.NewRequest("GET", "/game/ws/x", nil)
+	rec := httptest.NewRecorder()
+	proxyGameSocket(rec, req, hostURL, logrus.New())
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", rec.Code)
+	}
+	var body apierr.Response
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != apierr.CodeUnavailable {
+		t.Fatalf("expected a coded error body, got %+v (%v)", body, err)
+	}
+}
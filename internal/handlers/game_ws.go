@@ -5,173 +5,556 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/coder/websocket"
 	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/analytics"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/crash"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/features"
 	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/middleware"
 	"github.com/jason-s-yu/cambia/internal/models"
+	"github.com/jason-s-yu/cambia/internal/protocol"
 	"github.com/sirupsen/logrus"
 )
 
-// GameMessage is the standardized JSON structure for all incoming game-related commands.
-type GameMessage struct {
-	// Type is the action string, e.g. "action_snap", "action_draw_stockpile", etc.
-	Type string `json:"type"`
-
-	// Payload is a generic map for any extra JSON fields we might parse (optional).
-	Payload map[string]interface{} `json:"payload,omitempty"`
-
-	// Card, Card1, Card2 are optional sub-objects if the action involves one or two specific cards.
-	Card  map[string]interface{} `json:"card,omitempty"`
-	Card1 map[string]interface{} `json:"card1,omitempty"`
-	Card2 map[string]interface{} `json:"card2,omitempty"`
-
-	// Special is used for specifying sub-actions in multi-step special card flow, e.g. "swap_peek", "skip", etc.
-	Special string `json:"special,omitempty"`
-}
-
-// GameWSHandler sets up the WebSocket at /game/ws/{game_id}, subprotocol "game".
+// GameWSHandler sets up the WebSocket at /game/ws/{game_id}, subprotocol "game.v1".
 //
 // This handler:
-//  1. Extracts the {game_id} from the path.
-//  2. Looks up the in-memory CambiaGame from the GameStore.
-//  3. Authenticates the user, falling back to ephemeral user if none is found.
-//  4. Adds that user to the CambiaGame as a Player (with a new WebSocket connection).
-//  5. Spawns a read loop in a separate goroutine using readGameMessages.
+//  1. Reads the {game_id} path wildcard.
+//  2. Looks up the in-memory CambiaGame from the GameStore. A game hosted by another node is relayed there
+//     with proxyGameSocket.
+//  3. Authenticates the user (cookie, bearer header, ?token=, or "token.{jwt}" subprotocol),
+//     falling back to ephemeral user if none is found. A handoff token (?handoff= or X-Cambia-Handoff)
+//     instead takes over a player's seat from another device; see handoffStore.
+//  4. Negotiates protocol capabilities: the client may declare the ones it understands via ?caps=a,b or
+//     the X-Cambia-Capabilities header. The game's engine version and rules revision are returned as
+//     response headers and, with the agreed capabilities, in an initial "game_handshake" message.
+//  5. Adds that user to the CambiaGame as a Player (with a new WebSocket connection). If the user is
+//     already connected, the new connection takes over and the old one is closed as "superseded". In a
+//     hot-seat game, the connection also plays the user's local seats, listed in the handshake. After the
+//     handshake, a handed-off connection gets the player's full state, and every connection a new
+//     handoff token.
+//  6. Spawns a read loop in a separate goroutine using readGameMessages, and pings the client every
+//     gamePingInterval to measure its latency.
 func GameWSHandler(logger *logrus.Logger, gs *GameServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// parse game_id from path
-		pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/game/ws/"), "/")
-		if len(pathParts) < 1 {
-			http.Error(w, "missing game_id", http.StatusBadRequest)
-			return
-		}
-		gameIDStr := pathParts[0]
-		gameID, err := uuid.Parse(gameIDStr)
+		logger := middleware.RequestLogger(r.Context(), logger)
+		gameID, err := uuid.Parse(r.PathValue("game_id"))
 		if err != nil {
-			http.Error(w, "invalid game_id", http.StatusBadRequest)
+			apierr.Error(w, "invalid game_id", http.StatusBadRequest)
 			return
 		}
 
 		// look up in-memory CambiaGame
 		g, ok := gs.GameStore.GetGame(gameID)
 		if !ok {
-			http.Error(w, "game not found", http.StatusNotFound)
+			if owner := remoteOwner(gs, r, gameID, logger); owner != "" {
+				proxyGameSocket(w, r, owner, logger)
+				return
+			}
+			apierr.Write(w, http.StatusNotFound, apierr.CodeGameNotFound, "game not found")
 			return
 		}
 
 		// set the broadcast callback if not present
-		if g.BroadcastFn == nil {
-			g.BroadcastFn = func(ev game.GameEvent) {
-				// broadcast to all players
-				for _, pl := range g.Players {
-					if pl.Conn != nil {
-						data, _ := json.Marshal(ev)
-						pl.Conn.Write(context.Background(), websocket.MessageText, data)
+		g.Do(func() {
+			if g.BroadcastFn == nil {
+				g.BroadcastFn = func(ev game.GameEvent) {
+					// broadcast to all players; events are numbered even for disconnected players, so they
+					// can resync when they're back. The event is encoded once, and each player's seq added.
+					var (
+						jsonData, protoData []byte
+						deliveries          []game.Delivery
+					)
+					// WithSeq copies the event for each player, so the scratch buffer is free once they're queued
+					buf := game.GetBuffer()
+					defer game.PutBuffer(buf)
+					for _, pl := range g.Players {
+						if ev.Hidden[pl.ID] {
+							continue
+						}
+						seq := g.SequenceFor(pl.ID, ev).Seq
+						if pl.Conn == nil {
+							continue
+						}
+						if pl.Encoding == string(protocol.EncodingProtobuf) {
+							if protoData == nil {
+								data, err := protocol.MarshalGameEvent(ev)
+								if err != nil {
+									logger.Warnf("failed to encode %s event for game %v: %v", ev.Type, gameID, err)
+									continue
+								}
+								protoData = data
+							}
+							deliveries = append(deliveries, game.Delivery{Conn: pl.Conn, Type: websocket.MessageBinary, Data: protocol.AppendEventSeq(protoData, seq)})
+							continue
+						}
+						if jsonData == nil {
+							game.AppendJSON(buf, ev)
+							jsonData = buf.Bytes()
+						}
+						deliveries = append(deliveries, game.Delivery{Conn: pl.Conn, Type: websocket.MessageText, Data: game.WithSeq(jsonData, seq), Batch: pl.BatchEvents})
 					}
+					g.Send(deliveries)
 				}
 			}
+		})
+
+		capabilities, err := g.NegotiateCapabilities(declaredCapabilities(r))
+		if err != nil {
+			logger.Warnf("cannot serve game %v: %v", gameID, err)
+			apierr.WriteDetails(w, http.StatusConflict, apierr.CodeUnsupportedRules, "game runs on an unsupported rules revision", map[string]interface{}{"rulesRevision": g.RulesRevision})
+			return
+		}
+		w.Header().Set("X-Cambia-Engine-Version", g.EngineVersion)
+		w.Header().Set("X-Cambia-Rules-Revision", strconv.Itoa(g.RulesRevision))
+		encoding := declaredEncoding(r)
+		w.Header().Set("X-Cambia-Encoding", string(encoding))
+
+		// a handoff token moves a player's seat from another device, without their session
+		var handoffUser uuid.UUID
+		if token := declaredHandoff(r); token != "" {
+			userID, ok := gs.handoffs.redeem(token, gameID)
+			if !ok || !g.IsLivePlayer(userID) {
+				apierr.Write(w, http.StatusUnauthorized, apierr.CodeInvalidToken, "invalid or expired handoff token")
+				return
+			}
+			handoffUser = userID
 		}
 
 		// upgrade ws
 		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-			Subprotocols: []string{"game"},
+			Subprotocols:   protocol.GameSocket.Subprotocols(),
+			OriginPatterns: middleware.AllowedOrigins,
 		})
 		if err != nil {
 			logger.Warnf("websocket accept error: %v", err)
 			return
 		}
-		if c.Subprotocol() != "game" {
-			c.Close(websocket.StatusPolicyViolation, "client must speak the game subprotocol")
+		protoVersion, reason := protocol.GameSocket.Negotiate(r, c.Subprotocol())
+		if reason != "" {
+			c.Close(websocket.StatusPolicyViolation, reason)
 			return
 		}
 
 		// authenticate user by cookie if available; form ephemeral user fallback
-		userID, err := EnsureEphemeralUser(w, r)
-		if err != nil {
-			logger.Warnf("failed ephemeral user logic: %v", err)
-			c.Close(websocket.StatusPolicyViolation, "cannot create or auth ephemeral user")
-			return
+		userID := handoffUser
+		if userID == uuid.Nil {
+			userID, err = EnsureEphemeralUser(w, r)
+			if err != nil {
+				logger.Warnf("failed ephemeral user logic: %v", err)
+				c.Close(websocket.StatusPolicyViolation, "cannot create or auth ephemeral user")
+				return
+			}
 		}
+		middleware.SetUserID(r.Context(), userID)
 
 		// attach the player to the game
 		p := &models.Player{
-			ID:        userID,
-			Hand:      []*models.Card{},
-			Connected: true,
-			Conn:      c,
+			ID:               userID,
+			Hand:             []*models.Card{},
+			Connected:        true,
+			Conn:             c,
+			ProtocolVersion:  protoVersion,
+			Encoding:         string(encoding),
+			LegalActionHints: slices.Contains(capabilities, game.CapLegalActions),
+			BatchEvents:      slices.Contains(capabilities, game.CapBatch),
+		}
+		if old := g.AddPlayer(p); old != nil {
+			logger.Infof("User %v took over their connection to game %v", userID, gameID)
+			go old.Close(game.StatusSuperseded, "superseded")
+		}
+		gs.Presence.EnterGame(userID, gameID)
+		defer func() {
+			// a superseded connection leaves presence to the one that took over
+			if g.IsActiveConn(userID, c) {
+				gs.Presence.LeaveGame(userID, gameID)
+			}
+		}()
+
+		hello := map[string]interface{}{
+			"type":            "game_handshake",
+			"gameID":          gameID,
+			"engineVersion":   g.EngineVersion,
+			"rulesRevision":   g.RulesRevision,
+			"capabilities":    capabilities,
+			"protocolVersion": protoVersion,
+			"encoding":        encoding,
+			"latency":         g.Latency(),
+			"serverTime":      time.Now().UnixMilli(),
+		}
+		if seats := g.LocalSeats(userID); len(seats) > 0 {
+			hello["localSeats"] = seats
+		}
+		handshake, _ := json.Marshal(hello)
+		c.Write(r.Context(), websocket.MessageText, handshake)
+		if handoffUser != uuid.Nil {
+			logger.Infof("User %v handed their seat at game %v off to a new device", userID, gameID)
+			view, seq := g.PlayerSnapshot(userID)
+			snapshot, _ := json.Marshal(map[string]interface{}{
+				"type":     "handoff_complete",
+				"snapshot": view,
+				"seq":      seq,
+			})
+			c.Write(r.Context(), websocket.MessageText, snapshot)
+		}
+		if frame := gs.handoffFrame(gameID, userID); frame != nil {
+			data, _ := json.Marshal(frame)
+			c.Write(r.Context(), websocket.MessageText, data)
 		}
-		g.AddPlayer(p)
-		logger.Infof("User %v joined game %v via WS", userID, gameID)
 
 		// create a context for the read loop
 		ctx, cancel := context.WithCancel(r.Context())
 		defer cancel()
 
 		// read loop
-		readGameMessages(ctx, g, p, logger)
+		readGameMessages(ctx, gs, g, p, logger)
 	}
 }
 
+// gamePingInterval is how often the server pings each player's game socket to measure their round-trip time.
+const gamePingInterval = 10 * time.Second
+
+// gamePinger pings a player's game socket and times the replies. Only the latest ping is outstanding; a pong
+// to an older one is ignored.
+type gamePinger struct {
+	sent atomic.Int64 // the ts of the outstanding ping, in Unix milliseconds, or 0
+}
+
+// run pings conn every gamePingInterval until ctx is done.
+func (pg *gamePinger) run(ctx context.Context, conn *websocket.Conn) {
+	defer crash.Recover("pinging a game socket")
+	ticker := time.NewTicker(gamePingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			ts := now.UnixMilli()
+			pg.sent.Store(ts)
+			data, _ := json.Marshal(map[string]interface{}{"type": "ping", "ts": ts})
+			if game.WriteFrame(conn, websocket.MessageText, data) != nil {
+				return
+			}
+		}
+	}
+}
+
+// rtt returns the round-trip time of the ping a pong echoed ts for, if it's the outstanding one.
+func (pg *gamePinger) rtt(ts int64) (time.Duration, bool) {
+	if ts == 0 || !pg.sent.CompareAndSwap(ts, 0) {
+		return 0, false
+	}
+	return time.Since(time.UnixMilli(ts)), true
+}
+
+// declaredCapabilities returns the protocol capabilities a client declared on the upgrade request, if any.
+func declaredCapabilities(r *http.Request) []string {
+	raw := r.URL.Query().Get("caps")
+	if raw == "" {
+		raw = r.Header.Get("X-Cambia-Capabilities")
+	}
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+// declaredEncoding returns the message encoding a client asked for on the upgrade request via ?encoding= or
+// the X-Cambia-Encoding header. Anything other than "protobuf" gets JSON.
+func declaredEncoding(r *http.Request) protocol.Encoding {
+	raw := r.URL.Query().Get("encoding")
+	if raw == "" {
+		raw = r.Header.Get("X-Cambia-Encoding")
+	}
+	if !features.Enabled(features.ProtobufEncoding) {
+		return protocol.EncodingJSON
+	}
+	return protocol.ParseEncoding(raw)
+}
+
 // readGameMessages continuously reads from the WebSocket for game actions.
-// We parse the "type" and handle "action_*" or "ping" commands.
+// Each message is decoded and validated by protocol.DecodeGame (or protocol.DecodeGameProto for binary
+// frames from protobuf clients); rejected ones get an "error" frame back.
 // On any read error, we close the connection and mark the player disconnected.
-func readGameMessages(ctx context.Context, g *game.CambiaGame, p *models.Player, logger *logrus.Logger) {
+func readGameMessages(ctx context.Context, gs *GameServer, g *game.CambiaGame, p *models.Player, logger logrus.FieldLogger) {
+	// p.Conn moves to the new connection if this one is superseded, so hold on to this one
+	conn := p.Conn
+	limiter := protocol.NewLimiter(p.ID, protocol.GameLimits)
+	pinger := &gamePinger{}
+	go pinger.run(ctx, conn)
+	closeStatus, closeReason := websocket.StatusNormalClosure, "closing"
 	defer func() {
-		p.Conn.Close(websocket.StatusNormalClosure, "closing")
-		g.HandleDisconnect(p.ID)
+		limiter.Close()
+		conn.Close(closeStatus, closeReason)
+		g.HandleDisconnect(p.ID, conn)
 	}()
 
 	for {
-		typ, data, err := p.Conn.Read(ctx)
+		typ, buf, err := game.ReadFrame(ctx, conn)
 		if err != nil {
-			logger.Infof("user %v read err: %v", p.ID, err)
+			logger.Debugf("user %v read err: %v", p.ID, err)
 			return
 		}
-		if typ != websocket.MessageText {
+		received := time.Now()
+		var (
+			env  protocol.Envelope
+			msg  protocol.Message
+			perr *protocol.Error
+		)
+		switch {
+		case typ == websocket.MessageText:
+			env, msg, perr = protocol.DecodeGame(buf.Bytes())
+		case p.Encoding == string(protocol.EncodingProtobuf):
+			env, msg, perr = protocol.DecodeGameProto(buf.Bytes())
+		default:
+			game.PutBuffer(buf)
 			continue
 		}
-
-		var msg GameMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
-			logger.Warnf("invalid json from user %v: %v", p.ID, err)
+		// decoding copies what it keeps of the frame
+		game.PutBuffer(buf)
+		// a message with a req_id always gets exactly one reply carrying it: its error, its own reply, or
+		// an "ack"
+		reply := func(frame map[string]interface{}) {
+			writeGameMessage(ctx, p, env.Reply(frame))
+		}
+		if limited, disconnect := limiter.Allow(env.Type); limited != nil {
+			if disconnect {
+				logger.Warnf("disconnecting %v from game %v: rate limit exceeded", p.ID, g.ID)
+				closeStatus, closeReason = websocket.StatusPolicyViolation, "too many messages"
+				return
+			}
+			reply(limited.Frame())
+			continue
+		}
+		if perr != nil {
+			logger.Debugf("rejected %q message from user %v: %v", env.Type, p.ID, perr)
+			reply(perr.Frame())
 			continue
 		}
 
-		switch msg.Type {
-		case "action_snap", "action_draw_stockpile", "action_draw_discard",
-			"action_discard", "action_replace", "action_cambia":
-			handleSimpleAction(g, p.ID, msg)
+		handleGameMessage(ctx, gs, g, p, pinger, env, msg, reply, logger)
+		emitAction(g, p, env, msg, time.Since(received))
+	}
+}
 
-		case "action_special":
-			handleSpecialAction(g, p.ID, msg)
+// emitAction reports a gameplay action to analytics, with how long it took to handle from when its frame
+// arrived. Other messages, such as chat and pings, aren't reported.
+func emitAction(g *game.CambiaGame, p *models.Player, env protocol.Envelope, msg protocol.Message, latency time.Duration) {
+	ev := analytics.Event{
+		Type:      analytics.EventAction,
+		GameID:    g.ID,
+		UserID:    p.ID,
+		Action:    env.Type,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	switch m := msg.(type) {
+	case *protocol.GameAction:
+	case *protocol.GameSpecial:
+		ev.Detail = map[string]interface{}{"special": m.Special}
+	default:
+		return
+	}
+	analytics.Emit(ev)
+}
 
-		case "ping":
-			_ = p.Conn.Write(ctx, websocket.MessageText, []byte(`{"action":"pong"}`))
+// handleGameMessage carries out a decoded message from a player. A panic is recovered here so one bad
+// message can't end the connection; one in the game's own logic halts the game instead, see
+// game.CambiaGame.HaltOnPanic.
+func handleGameMessage(ctx context.Context, gs *GameServer, g *game.CambiaGame, p *models.Player, pinger *gamePinger, env protocol.Envelope, msg protocol.Message, reply func(map[string]interface{}), logger logrus.FieldLogger) {
+	defer recoverMessage(logger, p.ID, env.Type, reply)
 
-		default:
-			logger.Warnf("Unknown game action '%s' from user %v", msg.Type, p.ID)
+	switch m := msg.(type) {
+	case *protocol.GameAction:
+		if seat, ok := actingSeat(g, p, m.Seat, reply); ok {
+			applyGameAction(ctx, g, p, env, m.ActionID, func() { handleSimpleAction(g, seat, m) })
 		}
+
+	case *protocol.GameSpecial:
+		if seat, ok := actingSeat(g, p, m.Seat, reply); ok {
+			applyGameAction(ctx, g, p, env, m.ActionID, func() { handleSpecialAction(g, seat, m) })
+		}
+
+	case *protocol.GameReport:
+		handleGameReport(ctx, gs, g, p, m, reply)
+
+	case *protocol.GameChat:
+		sendGameChat(ctx, g, p, game.GameEvent{
+			Type:   game.EventChat,
+			UserID: p.ID,
+			Other:  map[string]interface{}{"msg": m.Msg, "ts": time.Now().Unix()},
+		}, reply)
+
+	case *protocol.GameEmote:
+		sendGameChat(ctx, g, p, game.GameEvent{
+			Type:   game.EventEmote,
+			UserID: p.ID,
+			Other:  map[string]interface{}{"emote": m.Emote},
+		}, reply)
+
+	case *protocol.VoteAbort:
+		aborted, err := g.VoteAbort(p.ID)
+		if err != nil {
+			reply(protocol.Errorf(protocol.CodeInvalidState, "%v", err).Frame())
+			return
+		}
+		if aborted {
+			gs.endAbortedGame(ctx, g, game.AbortVoteReason)
+		}
+
+	case *protocol.RequestHandoff:
+		frame := gs.handoffFrame(g.ID, p.ID)
+		if frame == nil {
+			reply(protocol.Errorf(protocol.CodeInternal, "cannot issue a handoff token").Frame())
+			return
+		}
+		reply(frame)
+
+	case *protocol.Ping:
+		pong := map[string]interface{}{"action": "pong", "serverTime": time.Now().UnixMilli()}
+		if m.TS != 0 {
+			pong["ts"] = m.TS
+		}
+		reply(pong)
+
+	case *protocol.Pong:
+		if rtt, ok := pinger.rtt(m.TS); ok {
+			g.RecordLatency(p.ID, rtt)
+		}
+
+	case *protocol.ResyncFrom:
+		events, latest, complete := g.Resync(p.ID, m.Seq)
+		resync := map[string]interface{}{
+			"type":     "resync",
+			"events":   events,
+			"seq":      latest,
+			"complete": complete,
+		}
+		if !complete {
+			resync["snapshot"] = g.PlayerView(p.ID)
+		}
+		reply(resync)
+
+	default:
+		logger.Warnf("unhandled game message %T from user %v", msg, p.ID)
+	}
+}
+
+// actingSeat returns the seat an action is for: the sender's own, or, in a hot-seat game, the seat it
+// names if the sender's connection plays it. Any other seat is refused through reply.
+func actingSeat(g *game.CambiaGame, p *models.Player, seat uuid.UUID, reply func(map[string]interface{})) (uuid.UUID, bool) {
+	if seat == uuid.Nil || seat == p.ID {
+		return p.ID, true
+	}
+	if !g.Controls(p.ID, seat) {
+		reply(protocol.Errorf(protocol.CodeForbidden, "you don't play seat %v", seat).Frame())
+		return uuid.Nil, false
+	}
+	return seat, true
+}
+
+// applyGameAction applies a turn action and acks it if it has a req_id. An action with an action_id is
+// applied at most once: a replay gets an "action_replayed" reply with the events the original produced for
+// the player instead.
+func applyGameAction(ctx context.Context, g *game.CambiaGame, p *models.Player, env protocol.Envelope, actionID uuid.UUID, apply func()) {
+	if actionID == uuid.Nil {
+		apply()
+	} else if replayed, events, complete := g.ApplyOnce(p.ID, actionID, apply); replayed {
+		writeGameMessage(ctx, p, env.Reply(map[string]interface{}{
+			"type":      "action_replayed",
+			"action_id": actionID,
+			"events":    events,
+			"complete":  complete,
+		}))
+		return
+	}
+	if env.ReqID != "" {
+		writeGameMessage(ctx, p, env.Ack())
+	}
+}
+
+// writeGameMessage sends a reply directly to a player's game socket.
+func writeGameMessage(ctx context.Context, p *models.Player, v map[string]interface{}) {
+	data, _ := json.Marshal(game.StampRequestID(v, middleware.RequestID(ctx)))
+	_ = p.Conn.Write(ctx, websocket.MessageText, data)
+}
+
+// handleGameReport files a report against another player in the game, from
+// {"type": "report", "payload": {"userID": "{uuid}", "reason": "..."}}. The game's lobby chat, if any, is
+// captured with the report. The reporter gets back "report_received" or "report_failed" through reply.
+func handleGameReport(ctx context.Context, gs *GameServer, g *game.CambiaGame, p *models.Player, msg *protocol.GameReport, reply func(map[string]interface{})) {
+	offender := msg.Payload.UserID
+	seated := false
+	var lobbyID uuid.UUID
+	g.Do(func() {
+		for _, pl := range g.Players {
+			if pl.ID == offender {
+				seated = true
+				break
+			}
+		}
+		lobbyID = g.LobbyID
+	})
+	if !seated {
+		reply(map[string]interface{}{"type": "report_failed", "message": "can only report players in this game"})
+		return
+	}
+
+	lobby, _ := gs.LobbyStore.GetLobby(lobbyID)
+	gameID := g.ID
+	rep, err := gs.FileReport(ctx, p.ID, offender, msg.Payload.Reason, lobby, &gameID)
+	if err != nil {
+		reply(map[string]interface{}{"type": "report_failed", "message": err.Error()})
+		return
+	}
+	reply(map[string]interface{}{"type": "report_received", "reportID": rep.ID.String()})
+}
+
+// sendGameChat sends a player's chat message or emote to the game, unless they're muted. Users with a block
+// either way between them and the sender don't receive it.
+func sendGameChat(ctx context.Context, g *game.CambiaGame, p *models.Player, ev game.GameEvent, reply func(map[string]interface{})) {
+	if muted, err := database.IsSanctioned(ctx, p.ID, models.SanctionMute); err != nil || muted {
+		reply(protocol.Errorf(protocol.CodeMuted, "you are muted").Frame())
+		return
+	}
+	blocks, err := database.ListBlockRelations(ctx, p.ID)
+	if err != nil {
+		reply(protocol.Errorf(protocol.CodeInternal, "could not send %s", ev.Type).Frame())
+		return
+	}
+	ev.Hidden = make(map[uuid.UUID]bool, len(blocks))
+	for _, id := range blocks {
+		ev.Hidden[id] = true
+	}
+	if err := g.SendChat(ev); err != nil {
+		reply(protocol.Errorf(protocol.CodeInvalidState, "%v", err).Frame())
 	}
 }
 
 // handleSimpleAction processes single-step commands like "snap", "draw_stockpile", "discard", "replace", "cambia".
 //
-// The `msg` is our typed GameMessage struct, which includes Card, Payload, etc. as needed.
-func handleSimpleAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage) {
+// The card, if any, is passed on to the game as the action's "id" and "idx" payload.
+func handleSimpleAction(g *game.CambiaGame, userID uuid.UUID, msg *protocol.GameAction) {
 	// Build a game action from the type & potential card data
 	act := models.GameAction{
 		ActionType: msg.Type,
 		Payload:    map[string]interface{}{},
 	}
 	if msg.Card != nil {
-		// e.g. if there's a card object with "id" or "idx"
-		if idStr, ok := msg.Card["id"].(string); ok && idStr != "" {
-			act.Payload["id"] = idStr
+		if msg.Card.ID != uuid.Nil {
+			act.Payload["id"] = msg.Card.ID.String()
 		}
-		if idxVal, ok := msg.Card["idx"].(float64); ok {
-			act.Payload["idx"] = idxVal
+		if msg.Card.Idx != nil {
+			act.Payload["idx"] = float64(*msg.Card.Idx)
 		}
 	}
 
@@ -181,10 +564,14 @@ func handleSimpleAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage) {
 // handleSpecialAction deals with multi-step logic for K, Q, J, 7,8,9,10.
 //
 // The `msg` struct includes the "special" field for sub-step identification (e.g. "swap_peek").
-func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage) {
-	// lock the game, do special action steps
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
+func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg *protocol.GameSpecial) {
+	// the special action steps run on the game's loop
+	g.Do(func() { applySpecialAction(g, userID, msg) })
+}
+
+// applySpecialAction carries out a special action step. It runs on the game's loop.
+func applySpecialAction(g *game.CambiaGame, userID uuid.UUID, msg *protocol.GameSpecial) {
+	defer g.HaltOnPanic("special action")
 
 	if !g.SpecialAction.Active || g.SpecialAction.PlayerID != userID {
 		g.FireEventPrivateSpecialActionFail(userID, "No special action in progress")
@@ -193,7 +580,7 @@ func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage)
 
 	rank := g.SpecialAction.CardRank
 	step := msg.Special
-	if step == "skip" {
+	if step == protocol.SpecialSkip {
 		g.SpecialAction = game.SpecialActionState{}
 		g.AdvanceTurn()
 		return
@@ -201,7 +588,7 @@ func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage)
 
 	switch rank {
 	case "7", "8":
-		if step != "peek_self" {
+		if step != protocol.SpecialPeekSelf {
 			g.FailSpecialAction(userID, "invalid step for 7/8")
 			return
 		}
@@ -209,7 +596,7 @@ func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage)
 		g.AdvanceTurn()
 
 	case "9", "10":
-		if step != "peek_other" {
+		if step != protocol.SpecialPeekOther {
 			g.FailSpecialAction(userID, "invalid step for 9/10")
 			return
 		}
@@ -217,7 +604,7 @@ func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage)
 		g.AdvanceTurn()
 
 	case "Q", "J":
-		if step != "swap_blind" {
+		if step != protocol.SpecialSwapBlind {
 			g.FailSpecialAction(userID, "invalid step for Q/J")
 			return
 		}
@@ -225,9 +612,9 @@ func handleSpecialAction(g *game.CambiaGame, userID uuid.UUID, msg GameMessage)
 		g.AdvanceTurn()
 
 	case "K":
-		if step == "swap_peek" {
+		if step == protocol.SpecialSwapPeek {
 			doKingFirstStep(g, userID, msg.Card1, msg.Card2)
-		} else if step == "swap_peek_swap" {
+		} else if step == protocol.SpecialSwapPeekSwap {
 			doKingSwapDecision(g, userID, msg.Card1, msg.Card2)
 		} else {
 			g.FailSpecialAction(userID, "invalid step for K")
@@ -257,16 +644,8 @@ func doPeekSelf(g *game.CambiaGame, playerID uuid.UUID) {
 }
 
 // doPeekOther conducts a 9/10 peek_other action.
-func doPeekOther(g *game.CambiaGame, playerID uuid.UUID, card1 map[string]interface{}) {
-	var targetUserID uuid.UUID
-	if card1 != nil {
-		if userMap, ok := card1["user"].(map[string]interface{}); ok {
-			uidStr, _ := userMap["id"].(string)
-			if uid, err := uuid.Parse(uidStr); err == nil {
-				targetUserID = uid
-			}
-		}
-	}
+func doPeekOther(g *game.CambiaGame, playerID uuid.UUID, card1 *protocol.CardRef) {
+	targetUserID := card1.Owner()
 	if targetUserID == uuid.Nil {
 		g.FailSpecialAction(playerID, "No valid target user for peek_other")
 		return
@@ -292,7 +671,7 @@ func doPeekOther(g *game.CambiaGame, playerID uuid.UUID, card1 map[string]interf
 }
 
 // doSwapBlind conducts a J/Q swap_blind action.
-func doSwapBlind(g *game.CambiaGame, playerID uuid.UUID, c1, c2 map[string]interface{}) {
+func doSwapBlind(g *game.CambiaGame, playerID uuid.UUID, c1, c2 *protocol.CardRef) {
 	cardA, userA := pickCardFromMessage(g, c1)
 	cardB, userB := pickCardFromMessage(g, c2)
 	if cardA == nil || cardB == nil {
@@ -314,7 +693,7 @@ func doSwapBlind(g *game.CambiaGame, playerID uuid.UUID, c1, c2 map[string]inter
 }
 
 // doKingFirstStep is "swap_peek" => reveal two chosen cards privately
-func doKingFirstStep(g *game.CambiaGame, playerID uuid.UUID, c1, c2 map[string]interface{}) {
+func doKingFirstStep(g *game.CambiaGame, playerID uuid.UUID, c1, c2 *protocol.CardRef) {
 	cardA, userA := pickCardFromMessage(g, c1)
 	cardB, userB := pickCardFromMessage(g, c2)
 	if cardA == nil || cardB == nil {
@@ -336,10 +715,11 @@ func doKingFirstStep(g *game.CambiaGame, playerID uuid.UUID, c1, c2 map[string]i
 	// private detail
 	g.FireEventPrivateSuccess(playerID, "swap_peek_reveal", cardA, cardB)
 	g.ResetTurnTimer()
+	g.HintLegalActions()
 }
 
 // doKingSwapDecision is "swap_peek_swap" => optionally swap
-func doKingSwapDecision(g *game.CambiaGame, playerID uuid.UUID, c1, c2 map[string]interface{}) {
+func doKingSwapDecision(g *game.CambiaGame, playerID uuid.UUID, c1, c2 *protocol.CardRef) {
 	cardA := g.SpecialAction.Card1
 	cardB := g.SpecialAction.Card2
 	userA := g.SpecialAction.Card1Owner
@@ -363,31 +743,16 @@ func doKingSwapDecision(g *game.CambiaGame, playerID uuid.UUID, c1, c2 map[strin
 }
 
 // pickCardFromMessage finds a card based on ID and returns it, for a swap action.
-func pickCardFromMessage(g *game.CambiaGame, cardMap map[string]interface{}) (*models.Card, uuid.UUID) {
-	if cardMap == nil {
+func pickCardFromMessage(g *game.CambiaGame, ref *protocol.CardRef) (*models.Card, uuid.UUID) {
+	if ref == nil || ref.ID == uuid.Nil {
 		return nil, uuid.Nil
 	}
-	cardIDStr, _ := cardMap["id"].(string)
-	if cardIDStr == "" {
-		return nil, uuid.Nil
-	}
-	cardID, err := uuid.Parse(cardIDStr)
-	if err != nil {
-		return nil, uuid.Nil
-	}
-	var ownerID uuid.UUID
-	if uMap, ok := cardMap["user"].(map[string]interface{}); ok {
-		if uidStr, ok2 := uMap["id"].(string); ok2 {
-			if uid, e2 := uuid.Parse(uidStr); e2 == nil {
-				ownerID = uid
-			}
-		}
-	}
+	ownerID := ref.Owner()
 	var found *models.Card
 	for _, pl := range g.Players {
 		if pl.ID == ownerID {
 			for _, c := range pl.Hand {
-				if c.ID == cardID {
+				if c.ID == ref.ID {
 					found = c
 					break
 				}
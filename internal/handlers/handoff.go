@@ -0,0 +1,115 @@
+// internal/handlers/handoff.go
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// handoffTTL is how long a handoff token stays valid. Tokens are short-lived since they let whoever holds
+// one take over a seat; a connected player can ask for a new one at any time.
+const handoffTTL = 2 * time.Minute
+
+// handoffHeader carries a handoff token on a game socket's upgrade request, as an alternative to ?handoff=.
+const handoffHeader = "X-Cambia-Handoff"
+
+// handoff is the seat a handoff token takes over.
+type handoff struct {
+	gameID  uuid.UUID
+	userID  uuid.UUID
+	expires time.Time
+}
+
+// handoffSeat identifies a player's seat at a game.
+type handoffSeat struct {
+	gameID, userID uuid.UUID
+}
+
+// handoffStore holds the outstanding handoff tokens, which let a player move their seat at a game to
+// another device mid-game. Each seat has at most one token, the last issued, and each token can be
+// redeemed once. Tokens are kept in memory on the node hosting the game, since game sockets opened on
+// other nodes are proxied there. The zero value is ready to use.
+type handoffStore struct {
+	mu      sync.Mutex
+	byToken map[string]handoff
+	bySeat  map[handoffSeat]string
+}
+
+// issue returns a new token for userID's seat at gameID, replacing any earlier one, and when it expires.
+func (s *handoffStore) issue(gameID, userID uuid.UUID) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expires := time.Now().Add(handoffTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byToken == nil {
+		s.byToken = make(map[string]handoff)
+		s.bySeat = make(map[handoffSeat]string)
+	}
+	s.prune()
+	seat := handoffSeat{gameID, userID}
+	delete(s.byToken, s.bySeat[seat])
+	s.byToken[token] = handoff{gameID: gameID, userID: userID, expires: expires}
+	s.bySeat[seat] = token
+	return token, expires, nil
+}
+
+// redeem uses up a token for a seat at gameID, returning the seat's user. It fails if the token is unknown,
+// expired, already used, or for another game.
+func (s *handoffStore) redeem(token string, gameID uuid.UUID) (uuid.UUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.byToken[token]
+	if !ok || h.gameID != gameID {
+		return uuid.Nil, false
+	}
+	delete(s.byToken, token)
+	delete(s.bySeat, handoffSeat{h.gameID, h.userID})
+	if time.Now().After(h.expires) {
+		return uuid.Nil, false
+	}
+	return h.userID, true
+}
+
+// prune drops expired tokens. Callers must hold s.mu.
+func (s *handoffStore) prune() {
+	now := time.Now()
+	for token, h := range s.byToken {
+		if now.After(h.expires) {
+			delete(s.byToken, token)
+			delete(s.bySeat, handoffSeat{h.gameID, h.userID})
+		}
+	}
+}
+
+// declaredHandoff returns the handoff token a new device presented on the upgrade request via ?handoff= or
+// the X-Cambia-Handoff header, if any.
+func declaredHandoff(r *http.Request) string {
+	if token := r.URL.Query().Get("handoff"); token != "" {
+		return token
+	}
+	return r.Header.Get(handoffHeader)
+}
+
+// handoffFrame issues a new handoff token for userID's seat at gameID and returns the frame that gives it to
+// them, or nil if none could be issued.
+func (gs *GameServer) handoffFrame(gameID, userID uuid.UUID) map[string]interface{} {
+	token, expires, err := gs.handoffs.issue(gameID, userID)
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"type":      "handoff_token",
+		"token":     token,
+		"expiresAt": expires.UnixMilli(),
+	}
+}
@@ -0,0 +1,48 @@
+// internal/handlers/handoff_test.go
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandoffStore(t *testing.T) {
+	var s handoffStore
+	gameID, userID := uuid.New(), uuid.New()
+
+	first, _, err := s.issue(gameID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, expires, err := s.issue(gameID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(expires) > handoffTTL {
+		t.Fatalf("expected the token to expire within %v, got %v", handoffTTL, expires)
+	}
+	if _, ok := s.redeem(first, gameID); ok {
+		t.Fatal("expected a newer token to replace the first")
+	}
+	if _, ok := s.redeem(token, uuid.New()); ok {
+		t.Fatal("expected the token to be refused for another game")
+	}
+	if got, ok := s.redeem(token, gameID); !ok || got != userID {
+		t.Fatalf("expected the token to take over the seat, got %v, %v", got, ok)
+	}
+	if _, ok := s.redeem(token, gameID); ok {
+		t.Fatal("expected the token to be single use")
+	}
+
+	token, _, _ = s.issue(gameID, userID)
+	s.mu.Lock()
+	h := s.byToken[token]
+	h.expires = time.Now().Add(-time.Second)
+	s.byToken[token] = h
+	s.mu.Unlock()
+	if _, ok := s.redeem(token, gameID); ok {
+		t.Fatal("expected an expired token to be refused")
+	}
+}
@@ -0,0 +1,144 @@
+// internal/handlers/leaderboard.go
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+const (
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 200
+)
+
+type leaderboardResponse struct {
+	Mode       string                      `json:"mode"`
+	SeasonID   *uuid.UUID                  `json:"seasonID,omitempty"`
+	Entries    []database.LeaderboardEntry `json:"entries"`
+	NextCursor string                      `json:"nextCursor,omitempty"`
+	Me         *database.LeaderboardEntry  `json:"me,omitempty"`
+}
+
+func encodeLeaderboardCursor(e database.LeaderboardEntry) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", e.Rating, e.UserID)))
+}
+
+func decodeLeaderboardCursor(s string) (*database.LeaderboardCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	ratingStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	rating, err := strconv.Atoi(ratingStr)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, err
+	}
+	return &database.LeaderboardCursor{Rating: rating, UserID: id}, nil
+}
+
+// LeaderboardHandler handles GET /leaderboard/{mode}, where mode is one of "1v1", "4p", or "7p8p".
+//
+// Query parameters:
+//
+//	limit   page size, default 50, max 200
+//	cursor  nextCursor from the previous page
+//	season  a season ID, or "current"; without it, the board ranks current (all-time) ratings.
+//	        Season boards only list players who finished placement.
+//
+// If the caller is authenticated, the response also includes their own entry as "me".
+func LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	mode := strings.Trim(strings.TrimPrefix(r.URL.Path, "/leaderboard/"), "/")
+	if !database.ValidLeaderboardMode(mode) {
+		apierr.Error(w, "unknown leaderboard mode", http.StatusNotFound)
+		return
+	}
+
+	limit := defaultLeaderboardLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxLeaderboardLimit)
+	}
+	var after *database.LeaderboardCursor
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeLeaderboardCursor(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		after = c
+	}
+
+	getPage := func(ctx context.Context) ([]database.LeaderboardEntry, error) {
+		return database.GetLeaderboardPage(ctx, mode, after, limit)
+	}
+	getRank := func(ctx context.Context, userID uuid.UUID) (*database.LeaderboardEntry, error) {
+		return database.GetLeaderboardRank(ctx, mode, userID)
+	}
+	var seasonID *uuid.UUID
+	if s := r.URL.Query().Get("season"); s != "" {
+		id, ok := resolveSeasonID(w, r, s)
+		if !ok {
+			return
+		}
+		seasonID = &id
+		getPage = func(ctx context.Context) ([]database.LeaderboardEntry, error) {
+			return database.GetSeasonLeaderboardPage(ctx, id, mode, after, limit)
+		}
+		getRank = func(ctx context.Context, userID uuid.UUID) (*database.LeaderboardEntry, error) {
+			return database.GetSeasonLeaderboardRank(ctx, id, mode, userID)
+		}
+	}
+
+	entries, err := getPage(r.Context())
+	if err != nil {
+		log.Printf("failed to load leaderboard %s: %v", mode, err)
+		apierr.Error(w, "failed to load leaderboard", http.StatusInternalServerError)
+		return
+	}
+	resp := leaderboardResponse{Mode: mode, SeasonID: seasonID, Entries: entries}
+	if len(entries) == limit {
+		resp.NextCursor = encodeLeaderboardCursor(entries[len(entries)-1])
+	}
+
+	// the caller's own rank is a bonus; anonymous callers just don't get one
+	if token := auth.RequestToken(r); token != "" {
+		if userIDStr, err := auth.AuthenticateJWT(token); err == nil {
+			if userID, err := uuid.Parse(userIDStr); err == nil {
+				me, err := getRank(r.Context(), userID)
+				if err != nil {
+					log.Printf("failed to load leaderboard rank for %v: %v", userID, err)
+				}
+				resp.Me = me
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,81 @@
+// internal/handlers/limits.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	log "github.com/sirupsen/logrus"
+)
+
+// Per-user limits, so one account can't tie up the server's lobbies or sit at several games at once.
+// MAX_LOBBIES_PER_USER and MAX_GAMES_PER_USER override the defaults; 0 lifts a limit. Each node enforces
+// them over the lobbies and games it hosts.
+const (
+	defaultMaxLobbiesPerUser = 3
+	defaultMaxGamesPerUser   = 1
+)
+
+func maxLobbiesPerUser() int {
+	return limitFromEnv("MAX_LOBBIES_PER_USER", defaultMaxLobbiesPerUser)
+}
+
+func maxGamesPerUser() int {
+	return limitFromEnv("MAX_GAMES_PER_USER", defaultMaxGamesPerUser)
+}
+
+func limitFromEnv(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Warnf("invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// lobbyLimitReached reports whether userID already holds as many lobbies as allowed (see
+// game.Lobby.HeldBy), and the limit.
+func (gs *GameServer) lobbyLimitReached(userID uuid.UUID) (bool, int) {
+	limit := maxLobbiesPerUser()
+	return limit > 0 && gs.LobbyStore.LobbiesHeldBy(userID) >= limit, limit
+}
+
+// gameLimitReached returns the first of userIDs who is already seated at as many live games as allowed,
+// and the limit.
+func (gs *GameServer) gameLimitReached(userIDs ...uuid.UUID) (uuid.UUID, int, bool) {
+	limit := maxGamesPerUser()
+	if limit == 0 {
+		return uuid.Nil, 0, false
+	}
+	for _, id := range userIDs {
+		if gs.GameStore.LiveGames(id) >= limit {
+			return id, limit, true
+		}
+	}
+	return uuid.Nil, limit, false
+}
+
+func lobbyLimitMessage(limit int) string {
+	return fmt.Sprintf("you can be in at most %d lobbies at once", limit)
+}
+
+func gameLimitMessage(userID uuid.UUID, limit int) string {
+	return fmt.Sprintf("player %v is already in as many live games as allowed at once (%d)", userID, limit)
+}
+
+// writeLobbyLimit and writeGameLimit send the 409 for a request that would go over a limit.
+func writeLobbyLimit(w http.ResponseWriter, limit int) {
+	apierr.WriteDetails(w, http.StatusConflict, apierr.CodeLobbyLimit, lobbyLimitMessage(limit), map[string]interface{}{"limit": limit})
+}
+
+func writeGameLimit(w http.ResponseWriter, userID uuid.UUID, limit int) {
+	apierr.WriteDetails(w, http.StatusConflict, apierr.CodeGameLimit, gameLimitMessage(userID, limit), map[string]interface{}{"limit": limit, "userID": userID})
+}
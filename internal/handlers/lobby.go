@@ -3,12 +3,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
 	"github.com/jason-s-yu/cambia/internal/game"
+	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -20,88 +25,302 @@ var (
 	validGameModes = map[string]bool{
 		"head_to_head": true,
 		"group_of_4":   true,
+		"teams_2v2":    true,
 		"circuit_4p":   true,
 		"circuit_7p8p": true,
 		"custom":       true,
 	}
 )
 
-// CreateLobbyHandler handles the creation of a new lobby and adds it to the lobby store
+// lobbySettableFields points at the parts of a lobby its host chooses, for decoding a request onto it.
+// Fields the request leaves out keep their values, and nested settings are merged field by field. The
+// lobby's ID, host, creation time, and game state are the server's, so no request can set them.
+type lobbySettableFields struct {
+	Type          *string             `json:"type"`
+	GameMode      *string             `json:"gameMode"`
+	Ranked        *bool               `json:"ranked"`
+	HouseRules    *game.HouseRules    `json:"houseRules"`
+	Circuit       *game.Circuit       `json:"circuit"`
+	Series        *game.Series        `json:"series"`
+	LobbySettings *game.LobbySettings `json:"lobbySettings"`
+}
+
+func settableFields(lobby *game.Lobby) *lobbySettableFields {
+	return &lobbySettableFields{
+		Type:          &lobby.Type,
+		GameMode:      &lobby.GameMode,
+		Ranked:        &lobby.Ranked,
+		HouseRules:    &lobby.HouseRules,
+		Circuit:       &lobby.Circuit,
+		Series:        &lobby.Series,
+		LobbySettings: &lobby.LobbySettings,
+	}
+}
+
+// validateLobby checks a lobby's configuration, writing a 400 naming the offending field if it's invalid.
+func validateLobby(w http.ResponseWriter, lobby *game.Lobby) bool {
+	fail := func(message, field string) bool {
+		apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidLobby, message, map[string]interface{}{"field": field})
+		return false
+	}
+	if !validGameTypes[lobby.Type] {
+		return fail("invalid lobby type", "type")
+	}
+	if lobby.GameMode != "" && !validGameModes[lobby.GameMode] {
+		return fail("invalid game mode", "gameMode")
+	}
+	if err := lobby.HouseRules.Validate(); err != nil {
+		return fail(err.Error(), "houseRules")
+	}
+	if err := lobby.ValidateCircuit(); err != nil {
+		return fail(err.Error(), "circuit")
+	}
+	if err := lobby.ValidateSeries(); err != nil {
+		return fail(err.Error(), "series")
+	}
+	if err := lobby.ValidateTeams(); err != nil {
+		return fail(err.Error(), "gameMode")
+	}
+	if err := lobby.ValidateRanked(); err != nil {
+		return fail(err.Error(), "houseRules")
+	}
+	return true
+}
+
+// lobbyRecord is the stored record of a lobby.
+func lobbyRecord(lobby *game.Lobby) (database.LobbyRecord, error) {
+	rules, err := json.Marshal(lobby.HouseRules)
+	if err != nil {
+		return database.LobbyRecord{}, err
+	}
+	settings, err := json.Marshal(map[string]interface{}{
+		"circuit":       lobby.Circuit,
+		"series":        lobby.Series,
+		"lobbySettings": lobby.LobbySettings,
+	})
+	if err != nil {
+		return database.LobbyRecord{}, err
+	}
+	return database.LobbyRecord{
+		ID:         lobby.ID,
+		HostUserID: lobby.HostUserID,
+		Type:       lobby.Type,
+		GameMode:   lobby.GameMode,
+		Ranked:     lobby.Ranked,
+		HouseRules: rules,
+		Settings:   settings,
+		CreatedAt:  lobby.CreatedAt,
+	}, nil
+}
+
+// CreateLobbyHandler handles the creation of a new lobby. The lobby's house rules can start from a built-in
+// preset ("preset": "blitz") or one of the caller's saved rule templates ("ruleTemplateID": "{uuid}")
+// instead of the defaults. The lobby is validated, registered in the lobby store, and recorded, in that
+// order, and a lobby that can't be recorded is unregistered again. The response is the lobby as stored,
+// with its server-assigned ID, host, and creation time.
 func CreateLobbyHandler(gs *GameServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie := r.Header.Get("Coo kie")
-		if !strings.Contains(cookie, "auth_token=") {
-			http.Error(w, "missing auth_token", http.StatusUnauthorized)
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
 			return
 		}
-		token := extractCookieToken(cookie, "auth_token")
 
-		userIDStr, err := auth.AuthenticateJWT(token)
+		lobby := game.NewLobbyWithDefaults(userID)
+
+		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, "invalid token", http.StatusForbidden)
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad lobby request payload")
 			return
 		}
-		userID, err := uuid.Parse(userIDStr)
-		if err != nil {
-			http.Error(w, "invalid user id format in token", http.StatusBadRequest)
+		// a preset or saved template replaces the default house rules; houseRules in the request then
+		// overrides individual rules
+		var base struct {
+			Preset         string     `json:"preset"`
+			RuleTemplateID *uuid.UUID `json:"ruleTemplateID"`
+		}
+		if err := json.Unmarshal(body, &base); err != nil {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad lobby request payload")
 			return
 		}
-
-		lobby := game.NewLobbyWithDefaults(userID)
-
-		if err := json.NewDecoder(r.Body).Decode(lobby); err != nil {
-			http.Error(w, "bad lobby request payload", http.StatusBadRequest)
+		rules, ok := lobbyBaseRules(w, r, userID, base.Preset, base.RuleTemplateID)
+		if !ok {
 			return
 		}
+		if rules != nil {
+			lobby.HouseRules = *rules
+		}
 
-		if lobby.Type != "" && !validGameTypes[lobby.Type] {
-			http.Error(w, "invalid lobby type", http.StatusBadRequest)
+		if err := json.Unmarshal(body, settableFields(lobby)); err != nil {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad lobby request payload")
 			return
 		}
-
-		if lobby.GameMode != "" && !validGameModes[lobby.GameMode] {
-			http.Error(w, "invalid game mode", http.StatusBadRequest)
+		if lobby.Type == "" {
+			lobby.Type = "private"
+		}
+		if !validateLobby(w, lobby) {
 			return
 		}
 
-		// add new lobby to instance store
-		gs.LobbyStore.AddLobby(lobby)
+		if lobby.Ranked {
+			status, err := database.GetPenaltyStatus(r.Context(), userID)
+			if err != nil {
+				apierr.Error(w, "failed to check penalty status", http.StatusInternalServerError)
+				return
+			}
+			if !status.CanPlayRanked(time.Now()) {
+				apierr.Write(w, http.StatusForbidden, apierr.CodeRankedSuspended, "ranked play is suspended for your account")
+				return
+			}
+		}
+
+		// the host may always join their own lobby, private or not
+		lobby.InviteUser(userID)
+
+		rec, err := lobbyRecord(lobby)
+		if err != nil {
+			log.Printf("failed to encode lobby %v: %v", lobby.ID, err)
+			apierr.Error(w, "failed to create lobby", http.StatusInternalServerError)
+			return
+		}
+		limit := maxLobbiesPerUser()
+		if !gs.LobbyStore.AddLobbyWithin(lobby, limit) {
+			writeLobbyLimit(w, limit)
+			return
+		}
+		if err := database.Lobbies.InsertLobby(r.Context(), rec); err != nil {
+			gs.LobbyStore.DeleteLobby(lobby.ID)
+			log.Printf("%v", err)
+			apierr.Error(w, "failed to create lobby", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(lobby)
 	}
 }
 
-// ListLobbiesHandler returns all lobbies in the DB, primarily for debugging or admin usage.
-func ListLobbiesHandler(gs *GameServer) http.HandlerFunc {
+// copySettings copies the host-chosen settings of src onto dst.
+func copySettings(dst, src *game.Lobby) {
+	dst.Type = src.Type
+	dst.GameMode = src.GameMode
+	dst.Ranked = src.Ranked
+	dst.HouseRules = src.HouseRules
+	dst.Circuit = src.Circuit
+	dst.Series = src.Series
+	dst.LobbySettings = src.LobbySettings
+}
+
+// UpdateLobbyHandler handles PATCH /lobby/{lobby_id}, with which the host changes the lobby's settings
+// before its game starts. The body takes the same fields as creation, and only those given change. The
+// changes are validated together and recorded before they take effect. Everyone in the lobby is then sent
+// a "lobby_settings" message, and ready states are reset, since players readied up under the old settings.
+func UpdateLobbyHandler(gs *GameServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie := r.Header.Get("Cookie")
-		if !strings.Contains(cookie, "auth_token=") {
-			http.Error(w, "missing auth_token", http.StatusUnauthorized)
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		lobbyID, err := uuid.Parse(r.PathValue("lobby_id"))
+		if err != nil {
+			apierr.Error(w, "invalid lobby_id", http.StatusBadRequest)
 			return
 		}
-		token := extractTokenFromCookie(cookie)
-		if _, err := auth.AuthenticateJWT(token); err != nil {
-			http.Error(w, "invalid token", http.StatusForbidden)
+		lobby, ok := gs.LobbyStore.GetLobby(lobbyID)
+		if !ok {
+			apierr.Write(w, http.StatusNotFound, apierr.CodeLobbyNotFound, "lobby not found")
+			return
+		}
+		if lobby.HostUserID != userID {
+			apierr.Error(w, "only the host can change the lobby", http.StatusForbidden)
+			return
+		}
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad lobby request payload")
 			return
 		}
 
-		lobbies := gs.LobbyStore.GetLobbies()
+		var updated bool
+		err = lobby.UpdateSettings(func() error {
+			updated = applyLobbyUpdate(w, r, lobby, body)
+			return nil
+		})
+		if errors.Is(err, game.ErrInGame) {
+			apierr.Error(w, "the lobby's game has already started", http.StatusConflict)
+			return
+		}
+		if !updated {
+			return
+		}
+		lobby.Changed()
+		lobby.ResetReadyStates()
+		lobby.BroadcastSettings()
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(lobbies)
+		json.NewEncoder(w).Encode(lobby)
 	}
 }
 
-// extractTokenFromCookie returns the JWT token from the "auth_token" cookie segment.
-func extractTokenFromCookie(cookie string) string {
-	parts := strings.Split(cookie, "auth_token=")
-	if len(parts) < 2 {
-		return ""
+// applyLobbyUpdate applies the changes in body to the lobby's settings, and records them, for
+// UpdateLobbyHandler. If they can't be applied, it writes the error response and returns false. The caller
+// holds the lobby's settings through game.Lobby.UpdateSettings.
+func applyLobbyUpdate(w http.ResponseWriter, r *http.Request, lobby *game.Lobby, body json.RawMessage) bool {
+	// the changes go onto a draft, so the lobby is left as it was if any of them is invalid
+	draft := game.NewLobbyWithDefaults(lobby.HostUserID)
+	copySettings(draft, lobby)
+	if err := json.Unmarshal(body, settableFields(draft)); err != nil {
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "bad lobby request payload")
+		return false
+	}
+	if !validateLobby(w, draft) {
+		return false
 	}
-	token := parts[1]
-	if idx := strings.Index(token, ";"); idx != -1 {
-		token = token[:idx]
+	if n := lobby.Occupancy(); n > draft.Capacity() {
+		apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidLobby, fmt.Sprintf("the game mode seats %d, but %d players are in the lobby", draft.Capacity(), n), map[string]interface{}{"field": "gameMode"})
+		return false
 	}
-	return token
+	if draft.Ranked && !lobby.Ranked {
+		status, err := database.GetPenaltyStatus(r.Context(), lobby.HostUserID)
+		if err != nil {
+			apierr.Error(w, "failed to check penalty status", http.StatusInternalServerError)
+			return false
+		}
+		if !status.CanPlayRanked(time.Now()) {
+			apierr.Write(w, http.StatusForbidden, apierr.CodeRankedSuspended, "ranked play is suspended for your account")
+			return false
+		}
+	}
+
+	draft.ID, draft.CreatedAt = lobby.ID, lobby.CreatedAt
+	rec, err := lobbyRecord(draft)
+	if err != nil {
+		log.Printf("failed to encode lobby %v: %v", lobby.ID, err)
+		apierr.Error(w, "failed to update lobby", http.StatusInternalServerError)
+		return false
+	}
+	if err := database.Lobbies.UpdateLobby(r.Context(), rec); err != nil {
+		log.Printf("%v", err)
+		apierr.Error(w, "failed to update lobby", http.StatusInternalServerError)
+		return false
+	}
+	copySettings(lobby, draft)
+	return true
+}
+
+// ListLobbiesHandler returns all in-memory lobbies, for debugging or admin usage.
+// It is mounted behind middleware.RequireRole(auth.RoleAdmin).
+func ListLobbiesHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobbies := gs.LobbyStore.GetLobbies()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lobbies)
+	}
+}
+
+// RankedProfilesHandler returns the house rule profiles that ranked lobbies must match.
+func RankedProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game.RankedProfiles)
 }
@@ -0,0 +1,182 @@
+// internal/handlers/lobby_browser.go
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+// lobbyListTTL bounds how long a cached listing is served. Listings are also dropped as soon as any lobby
+// changes, so this only caps the cost of a change that slipped past invalidation.
+const lobbyListTTL = 2 * time.Second
+
+// lobbySummary is a public lobby as shown in the lobby browser.
+type lobbySummary struct {
+	ID         uuid.UUID       `json:"id"`
+	HostUserID uuid.UUID       `json:"hostUserID"`
+	GameMode   string          `json:"gameMode"`
+	Ranked     bool            `json:"ranked"`
+	HouseRules game.HouseRules `json:"houseRules"`
+	Players    int             `json:"players"`
+	Capacity   int             `json:"capacity"`
+	InGame     bool            `json:"inGame"`
+}
+
+// lobbyFilter holds the lobby browser's query parameters.
+type lobbyFilter struct {
+	GameMode string
+	Ranked   *bool
+	Open     bool
+}
+
+// key identifies the filter's cached listing.
+func (f lobbyFilter) key() string {
+	ranked := ""
+	if f.Ranked != nil {
+		ranked = strconv.FormatBool(*f.Ranked)
+	}
+	return f.GameMode + "|" + ranked + "|" + strconv.FormatBool(f.Open)
+}
+
+func (f lobbyFilter) matches(s lobbySummary) bool {
+	if f.GameMode != "" && s.GameMode != f.GameMode {
+		return false
+	}
+	if f.Ranked != nil && s.Ranked != *f.Ranked {
+		return false
+	}
+	return !f.Open || (s.Players < s.Capacity && !s.InGame)
+}
+
+// cachedLobbyList is an encoded listing and the store version it was built from.
+type cachedLobbyList struct {
+	body    []byte
+	etag    string
+	version uint64
+	expires time.Time
+}
+
+// lobbyListCache keeps the encoded listing for each filter until the lobby store changes or it expires.
+// Filters are validated, so the number of entries stays small.
+type lobbyListCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedLobbyList
+}
+
+// get returns the listing for the filter, building it if the cached one is stale.
+func (c *lobbyListCache) get(store *game.LobbyStore, f lobbyFilter) cachedLobbyList {
+	version := store.Version()
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[f.key()]; ok && e.version == version && now.Before(e.expires) {
+		return e
+	}
+
+	summaries := []lobbySummary{}
+	for _, lobby := range store.GetLobbies() {
+		if lobby.Type != "public" {
+			continue
+		}
+		s := lobbySummary{
+			ID:         lobby.ID,
+			HostUserID: lobby.HostUserID,
+			GameMode:   lobby.GameMode,
+			Ranked:     lobby.Ranked,
+			HouseRules: lobby.HouseRules,
+			Players:    lobby.Occupancy(),
+			Capacity:   lobby.Capacity(),
+			InGame:     lobby.InGame,
+		}
+		if f.matches(s) {
+			summaries = append(summaries, s)
+		}
+	}
+	// a stable order keeps the ETag the same while nothing changes
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ID.String() < summaries[j].ID.String()
+	})
+
+	body, _ := json.Marshal(summaries)
+	sum := sha256.Sum256(body)
+	e := cachedLobbyList{
+		body:    body,
+		etag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		version: version,
+		expires: now.Add(lobbyListTTL),
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]cachedLobbyList)
+	}
+	c.entries[f.key()] = e
+	return e
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicLobbiesHandler handles GET /lobby/public, the lobby browser's list of public lobbies.
+//
+// Query parameters:
+//
+//	gameMode  only lobbies of this game mode
+//	ranked    "true" or "false", to only list ranked or unranked lobbies
+//	open      "true" to only list lobbies with a free seat that aren't in a game
+//
+// Listings are cached briefly and carry an ETag; a request whose If-None-Match matches gets 304 Not Modified.
+func PublicLobbiesHandler(gs *GameServer) http.HandlerFunc {
+	cache := &lobbyListCache{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := lobbyFilter{GameMode: q.Get("gameMode")}
+		if f.GameMode != "" && !validGameModes[f.GameMode] {
+			apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, "invalid game mode", map[string]interface{}{"field": "gameMode"})
+			return
+		}
+		if s := q.Get("ranked"); s != "" {
+			ranked, err := strconv.ParseBool(s)
+			if err != nil {
+				apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, "invalid ranked filter", map[string]interface{}{"field": "ranked"})
+				return
+			}
+			f.Ranked = &ranked
+		}
+		if s := q.Get("open"); s != "" {
+			open, err := strconv.ParseBool(s)
+			if err != nil {
+				apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, "invalid open filter", map[string]interface{}{"field": "open"})
+				return
+			}
+			f.Open = open
+		}
+
+		list := cache.get(gs.LobbyStore, f)
+		w.Header().Set("ETag", list.etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), list.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(list.body)
+	}
+}
@@ -0,0 +1,145 @@
+// internal/handlers/lobby_test.go
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+func TestCreateLobby(t *testing.T) {
+	auth.Init()
+	database.UseMemory()
+	t.Setenv("MAX_LOBBIES_PER_USER", "2")
+	gs := NewGameServer()
+	hostID := uuid.New()
+	token, _ := auth.CreateJWT(hostID.String())
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/lobby/create", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		CreateLobbyHandler(gs)(w, req)
+		return w
+	}
+
+	// server-assigned fields in the request are ignored
+	forged := uuid.New()
+	w := create(`{"id": "` + forged.String() + `", "hostUserID": "` + forged.String() + `", "inGame": true, "houseRules": {"jokers": 0}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var got struct {
+		ID         uuid.UUID       `json:"id"`
+		HostUserID uuid.UUID       `json:"hostUserID"`
+		Type       string          `json:"type"`
+		InGame     bool            `json:"inGame"`
+		CreatedAt  time.Time       `json:"createdAt"`
+		HouseRules game.HouseRules `json:"houseRules"`
+	}
+	json.NewDecoder(w.Body).Decode(&got)
+	if got.ID == forged || got.HostUserID != hostID || got.InGame || got.CreatedAt.IsZero() {
+		t.Fatalf("expected server-assigned fields, got %+v", got)
+	}
+	if got.Type != "private" || got.HouseRules.Jokers != 0 || got.HouseRules.TurnTimerSec != game.DefaultHouseRules().TurnTimerSec {
+		t.Fatalf("expected a private lobby with jokers overridden and other rules defaulted, got %+v", got)
+	}
+
+	lobby, ok := gs.LobbyStore.GetLobby(got.ID)
+	if !ok {
+		t.Fatalf("expected the lobby to be registered")
+	}
+	if err := lobby.AddConnection(hostID, &game.LobbyConnection{UserID: hostID}); err != nil {
+		t.Fatalf("expected the host to be able to join their private lobby, got %v", err)
+	}
+
+	if w := create(`{"type": "secret"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid type, got %d", w.Code)
+	}
+	if w := create(`{"type": "public"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected a second lobby, got %d: %s", w.Code, w.Body)
+	}
+	if w := create(`{"type": "public"}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 past the lobby limit, got %d: %s", w.Code, w.Body)
+	}
+	if n := len(gs.LobbyStore.GetLobbies()); n != 2 {
+		t.Fatalf("expected 2 lobbies registered, got %d", n)
+	}
+}
+
+func TestUpdateLobby(t *testing.T) {
+	auth.Init()
+	database.UseMemory()
+	gs := NewGameServer()
+	hostID, guestID := uuid.New(), uuid.New()
+
+	lobby := game.NewLobbyWithDefaults(hostID)
+	lobby.Type = "public"
+	gs.LobbyStore.AddLobby(lobby)
+	rec, _ := lobbyRecord(lobby)
+	database.Lobbies.InsertLobby(context.Background(), rec)
+	guest := &game.LobbyConnection{UserID: guestID, OutChan: make(chan map[string]interface{}, 10)}
+	lobby.AddConnection(hostID, &game.LobbyConnection{UserID: hostID, OutChan: make(chan map[string]interface{}, 10)})
+	lobby.AddConnection(guestID, guest)
+	lobby.MarkUserReady(guestID)
+	for len(guest.OutChan) > 0 {
+		<-guest.OutChan
+	}
+
+	patch := func(userID uuid.UUID, body string) *httptest.ResponseRecorder {
+		token, _ := auth.CreateJWT(userID.String())
+		req := httptest.NewRequest(http.MethodPatch, "/lobby/"+lobby.ID.String(), bytes.NewBufferString(body))
+		req.SetPathValue("lobby_id", lobby.ID.String())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		UpdateLobbyHandler(gs)(w, req)
+		return w
+	}
+
+	if w := patch(guestID, `{"gameMode": "group_of_4"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a guest, got %d", w.Code)
+	}
+	if w := patch(hostID, `{"gameMode": "group_of_4", "houseRules": {"turnTimerSec": -1}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid rules, got %d: %s", w.Code, w.Body)
+	}
+	if lobby.GameMode != "" {
+		t.Fatalf("expected an invalid update to leave the lobby as it was, got game mode %q", lobby.GameMode)
+	}
+
+	if w := patch(hostID, `{"gameMode": "group_of_4", "houseRules": {"jokers": 0}}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if lobby.GameMode != "group_of_4" || lobby.HouseRules.Jokers != 0 || lobby.HouseRules.TurnTimerSec != game.DefaultHouseRules().TurnTimerSec || lobby.Type != "public" {
+		t.Fatalf("expected only the given settings to change, got %+v %+v", lobby.GameMode, lobby.HouseRules)
+	}
+	if lobby.ReadyMap()[guestID] {
+		t.Fatalf("expected ready states to be reset")
+	}
+	if msg := <-guest.OutChan; msg["type"] != "lobby_settings" {
+		t.Fatalf("expected a lobby_settings broadcast, got %v", msg)
+	}
+
+	if w := patch(hostID, `{"gameMode": "head_to_head"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a mode that seats everyone, got %d: %s", w.Code, w.Body)
+	}
+	third := uuid.New()
+	lobby.GameMode = "group_of_4"
+	lobby.AddConnection(third, &game.LobbyConnection{UserID: third, OutChan: make(chan map[string]interface{}, 10)})
+	if w := patch(hostID, `{"gameMode": "head_to_head"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a mode that can't seat everyone, got %d: %s", w.Code, w.Body)
+	}
+
+	lobby.InGame = true
+	if w := patch(hostID, `{"ranked": false}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 once the game started, got %d", w.Code)
+	}
+}
@@ -4,70 +4,69 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"os"
+	"time"
 
 	"github.com/coder/websocket"
 	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
 	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/crash"
+	"github.com/jason-s-yu/cambia/internal/database"
 	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/models"
+	"github.com/jason-s-yu/cambia/internal/protocol"
 	"github.com/sirupsen/logrus"
 )
 
-// We'll keep a reference to the GameServer so we can create the game instance upon start game command
-var GameServerForLobbyWS *GameServer
-
 // LobbyWSHandler returns an http.HandlerFunc that upgrades to a WebSocket
-// for the given lobby, subprotocol "lobby". It uses a LobbyStore to track real-time state.
+// for the given lobby, subprotocol "lobby.v1". Lobbies are looked up in the server's LobbyStore.
 // LobbyWSHandler handles WebSocket connections for a game.
 // It performs the following steps:
-// 1. Parses {lobby_id} from the request path.
+// 1. Reads the {lobby_id} path wildcard.
 // 2. Checks if the subprotocol is "lobby".
-// 3. Authenticates the user using the auth_token from the cookie.
+// 3. Authenticates the user using the auth_token cookie, a bearer header, ?token=, or a "token.{jwt}" subprotocol.
 // 4. Verifies if the user is a participant in the specified game.
 // 5. Accepts the WebSocket connection, tracks it in the LobbyStore, and starts the read loop.
 //
 // Parameters:
 // - logger: A logrus.Logger instance for logging.
-// - ls: A LobbyStore instance to manage lobby states.
+// - gs: The GameServer holding the lobbies, which starts their games.
 //
 // Returns:
 // - An http.HandlerFunc that handles the WebSocket connection.
-func LobbyWSHandler(logger *logrus.Logger, ls *game.LobbyStore, gs *GameServer) http.HandlerFunc {
-	GameServerForLobbyWS = gs
+func LobbyWSHandler(logger *logrus.Logger, gs *GameServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/lobby/ws/"), "/")
-		if len(pathParts) < 1 {
-			http.Error(w, "missing lobby_id", http.StatusBadRequest)
-			return
-		}
-		lobbyIDStr := pathParts[0]
-		lobbyUUID, err := uuid.Parse(lobbyIDStr)
+		logger := middleware.RequestLogger(r.Context(), logger)
+		lobbyUUID, err := uuid.Parse(r.PathValue("lobby_id"))
 		if err != nil {
-			http.Error(w, "invalid lobby_id", http.StatusBadRequest)
+			apierr.Error(w, "invalid lobby_id", http.StatusBadRequest)
 			return
 		}
 
 		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-			Subprotocols:   []string{"lobby"},
-			OriginPatterns: []string{"*"},
+			Subprotocols:   protocol.LobbySocket.Subprotocols(),
+			OriginPatterns: middleware.AllowedOrigins,
 		})
 		if err != nil {
 			logger.Warnf("websocket accept error: %v", err)
 			return
 		}
-		if c.Subprotocol() != "lobby" {
-			c.Close(websocket.StatusPolicyViolation, "client must speak the lobby subprotocol")
+		protoVersion, reason := protocol.LobbySocket.Negotiate(r, c.Subprotocol())
+		if reason != "" {
+			c.Close(websocket.StatusPolicyViolation, reason)
 			return
 		}
 
-		token := extractCookieToken(r.Header.Get("Cookie"), "auth_token")
+		token := auth.WSRequestToken(r)
 		userIDStr, err := auth.AuthenticateJWT(token)
 		if err != nil {
 			logger.Warnf("invalid token: %v", err)
-			c.Close(websocket.StatusPolicyViolation, "invalid auth_token")
+			c.Close(websocket.StatusPolicyViolation, wsAuthCloseReason(err))
 			return
 		}
 		userUUID, err := uuid.Parse(userIDStr)
@@ -76,31 +75,69 @@ func LobbyWSHandler(logger *logrus.Logger, ls *game.LobbyStore, gs *GameServer)
 			c.Close(websocket.StatusPolicyViolation, "invalid user ID")
 			return
 		}
+		middleware.SetUserID(r.Context(), userUUID)
 
-		if lobby, exists := ls.GetLobby(lobbyUUID); exists {
+		if lobby, exists := gs.LobbyStore.GetLobby(lobbyUUID); exists {
 
 			ctx, cancel := context.WithCancel(r.Context())
 			conn := &game.LobbyConnection{
-				UserID:  userUUID,
-				Cancel:  cancel,
-				OutChan: make(chan map[string]interface{}, 10),
-				IsHost:  lobby.HostUserID == userUUID,
+				UserID:          userUUID,
+				Cancel:          cancel,
+				OutChan:         make(chan map[string]interface{}, 10),
+				IsHost:          lobby.HostUserID == userUUID,
+				ProtocolVersion: protoVersion,
+				RequestID:       middleware.RequestID(r.Context()),
+			}
+			if blocked, err := database.ListBlockedIDs(ctx, userUUID); err != nil {
+				logger.Warnf("failed to load blocks for %v: %v", userUUID, err)
+			} else {
+				conn.SetBlocked(blocked)
+			}
+
+			if !lobby.HeldBy(userUUID) {
+				if reached, limit := gs.lobbyLimitReached(userUUID); reached {
+					logger.Infof("user %v could not join lobby %v: lobby limit reached", userUUID, lobbyUUID)
+					c.Close(game.StatusLobbyFull, lobbyLimitMessage(limit))
+					return
+				}
+				if lobby.Ranked {
+					if _, suspended, err := rankedSuspended(ctx, userUUID); err != nil {
+						logger.Warnf("%v", err)
+						c.Close(websocket.StatusInternalError, "failed to check penalty status")
+						return
+					} else if suspended {
+						c.Close(websocket.StatusPolicyViolation, "ranked play is suspended for your account")
+						return
+					}
+				}
 			}
 
 			err := lobby.AddConnection(userUUID, conn)
 
+			if errors.Is(err, game.ErrLobbyFull) {
+				logger.Infof("user %v could not join full lobby %v", userUUID, lobbyUUID)
+				c.Close(game.StatusLobbyFull, "lobby is full")
+				return
+			}
 			if err != nil {
 				logger.Warnf("failed to add connection to lobby: %v", err)
 				c.Close(websocket.StatusPolicyViolation, fmt.Sprintf("failed to add connection to lobby: %v", err.Error()))
 				return
 			}
 
-			logger.Infof("User %v connected to lobby %v", userUUID, lobbyUUID)
-
 			go writePump(ctx, c, conn, logger)
 
+			gs.Presence.EnterLobby(userUUID, lobbyUUID)
+			defer gs.Presence.LeaveLobby(userUUID, lobbyUUID)
+
 			lobby.BroadcastJoin(userUUID)
-			readPump(ctx, c, lobby, conn, logger, lobbyUUID)
+			if standings := lobby.CircuitStandings(); standings != nil {
+				conn.Write(standings)
+			}
+			if scoreboard := lobby.SeriesScoreboard(); scoreboard != nil {
+				conn.Write(scoreboard)
+			}
+			readPump(ctx, c, gs, lobby, conn, logger, lobbyUUID)
 		} else {
 			c.Close(websocket.StatusPolicyViolation, "lobby does not exist")
 			return
@@ -108,126 +145,343 @@ func LobbyWSHandler(logger *logrus.Logger, ls *game.LobbyStore, gs *GameServer)
 	}
 }
 
-// readPump reads messages from the websocket until disconnect. We handle JSON commands here.
-func readPump(ctx context.Context, c *websocket.Conn, lobby *game.Lobby, conn *game.LobbyConnection, logger *logrus.Logger, lobbyID uuid.UUID) {
+// readPump reads messages from the websocket until disconnect. Each is decoded and validated by
+// protocol.DecodeLobby; rejected ones get an "error" frame back.
+func readPump(ctx context.Context, c *websocket.Conn, gs *GameServer, lobby *game.Lobby, conn *game.LobbyConnection, logger logrus.FieldLogger, lobbyID uuid.UUID) {
+	limiter := protocol.NewLimiter(conn.UserID, protocol.LobbyLimits)
+	closeStatus, closeReason := websocket.StatusNormalClosure, "closing"
 	defer func() {
+		limiter.Close()
 		lobby.RemoveUser(conn.UserID)
 		conn.Cancel()
-		c.Close(websocket.StatusNormalClosure, "closing")
+		if conn.TooSlow() {
+			logger.Warnf("disconnecting %v from lobby %v: too slow", conn.UserID, lobbyID)
+			c.Close(game.StatusTooSlow, "too slow")
+			return
+		}
+		c.Close(closeStatus, closeReason)
 	}()
 
 	for {
-		typ, msg, err := c.Read(ctx)
+		typ, buf, err := game.ReadFrame(ctx, c)
 		if err != nil {
-			logger.Infof("user %v read err: %v", conn.UserID, err)
+			logger.Debugf("user %v read err: %v", conn.UserID, err)
 			return
 		}
 		if typ != websocket.MessageText {
+			game.PutBuffer(buf)
 			continue
 		}
 
-		var packet map[string]interface{}
-		if err := json.Unmarshal(msg, &packet); err != nil {
-			logger.Warnf("invalid json from user %v: %v", conn.UserID, err)
+		env, packet, perr := protocol.DecodeLobby(buf.Bytes())
+		// decoding copies what it keeps of the frame
+		game.PutBuffer(buf)
+		if limited, disconnect := limiter.Allow(env.Type); limited != nil {
+			if disconnect {
+				logger.Warnf("disconnecting %v from lobby %v: rate limit exceeded", conn.UserID, lobbyID)
+				closeStatus, closeReason = websocket.StatusPolicyViolation, "too many messages"
+				return
+			}
+			conn.Write(env.Reply(limited.Frame()))
+			continue
+		}
+		if perr != nil {
+			logger.Debugf("rejected %q message from user %v: %v", env.Type, conn.UserID, perr)
+			conn.Write(env.Reply(perr.Frame()))
 			continue
 		}
 
-		handleLobbyMessage(packet, lobby, conn, logger, lobbyID)
+		handleLobbyMessage(ctx, gs, env, packet, lobby, conn, logger, lobbyID)
 	}
 }
 
-// handleLobbyMessage interprets the "type" field received by client and updates the lobby or broadcasts accordingly.
-func handleLobbyMessage(packet map[string]interface{}, lobby *game.Lobby, senderConn *game.LobbyConnection, logger *logrus.Logger, lobbyID uuid.UUID) {
-	action, _ := packet["type"].(string)
-	switch action {
-	case "ready":
+// handleLobbyMessage applies a decoded client message to the lobby, replying with an "error" frame if it
+// can't be carried out. A message with a req_id always gets exactly one reply carrying it: its error, its
+// own reply, or an "ack". ctx is the connection's context; database calls made on the sender's behalf are
+// canceled when they disconnect.
+func handleLobbyMessage(ctx context.Context, gs *GameServer, env protocol.Envelope, packet protocol.Message, lobby *game.Lobby, senderConn *game.LobbyConnection, logger logrus.FieldLogger, lobbyID uuid.UUID) {
+	replied := false
+	reply := func(frame map[string]interface{}) {
+		replied = true
+		senderConn.Write(env.Reply(frame))
+	}
+	reject := func(code protocol.Code, format string, args ...interface{}) {
+		reply(protocol.Errorf(code, format, args...).Frame())
+	}
+	defer func() {
+		if !replied && env.ReqID != "" {
+			senderConn.Write(env.Ack())
+		}
+	}()
+	// runs before the ack above, so a recovered panic's error frame is the only reply
+	defer recoverMessage(logger, senderConn.UserID, env.Type, reply)
+
+	switch m := packet.(type) {
+	case *protocol.Ready:
 		lobby.MarkUserReady(senderConn.UserID)
 
 		if lobby.AreAllReady() {
 			// TODO: create and attach the game instance now
 
+			if err := lobby.ValidateRanked(); err != nil {
+				lobby.BroadcastAll(protocol.Errorf(protocol.CodeInvalidState, "cannot start ranked game: %v", err).Frame())
+				return
+			}
+			if err := lobby.TeamsReady(); err != nil {
+				lobby.BroadcastAll(protocol.Errorf(protocol.CodeInvalidState, "cannot start the game: %v", err).Frame())
+				return
+			}
+
 			// check for auto start
 			lobby.StartCountdown(10, func(lobbyID uuid.UUID) {
-				GameServerForLobbyWS.NewCambiaGameFromLobby(context.Background(), lobby)
+				if userID, limit, reached := gs.gameLimitReached(lobby.SeatedUsers()...); reached {
+					lobby.BroadcastAll(protocol.Errorf(protocol.CodeInvalidState, "cannot start the game: %s", gameLimitMessage(userID, limit)).Frame())
+					return
+				}
+				if err := checkRankedSeats(context.Background(), lobby); err != nil {
+					lobby.BroadcastAll(err.Frame())
+					return
+				}
+				gs.NewCambiaGameFromLobby(context.Background(), lobby)
 			})
 		}
-	case "unready":
+	case *protocol.Unready:
 		lobby.MarkUserUnready(senderConn.UserID)
-	case "invite":
-		userToAdd, err := uuid.Parse(packet["userID"].(string))
-
-		if err != nil {
-			logger.Warnf("invalid user ID to invite: %v", packet["userID"])
+	case *protocol.Invite:
+		if blocked, err := database.HasBlocked(ctx, m.UserID, senderConn.UserID); err != nil || blocked {
+			reject(protocol.CodeForbidden, "cannot invite this user")
 			return
 		}
 
-		lobby.InviteUser(userToAdd)
+		lobby.InviteUser(m.UserID)
 
-		// TODO: issue notification to the target user eventually
-	case "leave_lobby":
+		gs.Notify(m.UserID, "lobby_invite", map[string]interface{}{
+			"lobbyID":  lobbyID.String(),
+			"from":     senderConn.UserID.String(),
+			"gameMode": lobby.GameMode,
+		})
+	case *protocol.LeaveLobby:
 		lobby.RemoveUser(senderConn.UserID)
 		lobby.BroadcastLeave(senderConn.UserID)
 		senderConn.Cancel()
-	case "chat":
-		msg, _ := packet["msg"].(string)
-		lobby.BroadcastChat(senderConn.UserID, msg)
-	case "update_rules":
+	case *protocol.Chat:
+		if muted, err := database.IsSanctioned(ctx, senderConn.UserID, models.SanctionMute); err != nil || muted {
+			reject(protocol.CodeMuted, "you are muted")
+			return
+		}
+		msg := lobby.BroadcastChat(senderConn.UserID, m.Msg)
+		if chatPersistenceEnabled() {
+			if err := database.Lobbies.InsertLobbyChatMessage(ctx, msg.ID, lobbyID, msg.UserID, msg.Msg, time.Unix(msg.TS, 0)); err != nil {
+				logger.Warnf("failed to persist chat message %v: %v", msg.ID, err)
+			}
+		}
+	case *protocol.Report:
+		if !reportableInLobby(lobby, m.UserID) {
+			reject(protocol.CodeInvalidField, "can only report players in this lobby")
+			return
+		}
+		rep, err := gs.FileReport(ctx, senderConn.UserID, m.UserID, m.Reason, lobby, nil)
+		if err != nil {
+			reject(protocol.CodeInvalidState, "%v", err)
+			return
+		}
+		reply(map[string]interface{}{
+			"type":     "report_received",
+			"reportID": rep.ID.String(),
+		})
+	case *protocol.RTCSignal:
+		if muted, err := database.IsSanctioned(ctx, senderConn.UserID, models.SanctionMute); err != nil || muted {
+			reject(protocol.CodeMuted, "you are muted")
+			return
+		}
+		if err := lobby.RelaySignal(senderConn.UserID, m.To, m.Kind, m.Data); err != nil {
+			reject(protocol.CodeInvalidState, "%v", err)
+		}
+	case *protocol.ChatReactionAdd:
+		if err := setChatReaction(ctx, lobby, senderConn, m.ChatReaction, true, logger); err != nil {
+			reject(protocol.CodeInvalidField, "%v", err)
+		}
+	case *protocol.ChatReactionRemove:
+		if err := setChatReaction(ctx, lobby, senderConn, m.ChatReaction, false, logger); err != nil {
+			reject(protocol.CodeInvalidField, "%v", err)
+		}
+	case *protocol.UpdateRules:
 		// host can update auto_start, etc.
 		if !senderConn.IsHost {
-			senderConn.Write(map[string]interface{}{
-				"type":    "error",
-				"message": "Only the host can update rules",
-			})
+			reject(protocol.CodeForbidden, "only the host can update rules")
 			return
 		}
 
-		if rules, ok := packet["rules"].(map[string]interface{}); ok {
-			lobby.HouseRules.Update(rules)
+		var rec database.LobbyRecord
+		err := lobby.UpdateSettings(func() error {
+			// the changes are made to copies, so the lobby is left as it was if any of them is invalid
+			rules, settings := lobby.HouseRules, lobby.LobbySettings
+			if err := rules.Update(m.Rules); err != nil {
+				return err
+			}
+			if err := settings.Update(m.Settings); err != nil {
+				return err
+			}
+			if lobby.Ranked {
+				if _, err := game.MatchRankedProfile(rules); err != nil {
+					return err
+				}
+			}
+			lobby.HouseRules, lobby.LobbySettings = rules, settings
+			var err error
+			rec, err = lobbyRecord(lobby)
+			if err != nil {
+				logger.Warnf("%v", err)
+			}
+			return nil
+		})
+		if errors.Is(err, game.ErrInGame) {
+			reject(protocol.CodeInvalidState, "game already in progress")
+			return
 		}
-
-		// TODO: broadcast new rules to lobby
-	case "start_game":
+		if err != nil {
+			reject(protocol.CodeInvalidField, "%v", err)
+			return
+		}
+		lobby.Changed()
+		lobby.BroadcastSettings()
+		if rec.ID != uuid.Nil {
+			if err := database.Lobbies.UpdateLobby(ctx, rec); err != nil {
+				logger.Warnf("%v", err)
+			}
+		}
+	case *protocol.SetTeam:
+		if lobby.InGame {
+			reject(protocol.CodeInvalidState, "game already in progress")
+			return
+		}
+		if err := lobby.SetTeam(senderConn.UserID, m.Team); err != nil {
+			reject(protocol.CodeInvalidState, "%v", err)
+			return
+		}
+		lobby.BroadcastTeams(senderConn.UserID)
+	case *protocol.StartGame:
 		// this message is sent to forcibly start the game, regardless of the timer status
 		// this must be sent to start the game if autoStart == false
 		// check if we're in a game already
 		if lobby.InGame {
-			senderConn.WriteError("game already in progress")
+			reject(protocol.CodeInvalidState, "game already in progress")
 			return
 		}
 		if !lobby.AreAllReady() {
-			senderConn.WriteError("not all users are ready")
+			reject(protocol.CodeInvalidState, "not all users are ready")
+			return
+		}
+		if err := lobby.ValidateRanked(); err != nil {
+			reject(protocol.CodeInvalidState, "cannot start ranked game: %v", err)
+			return
+		}
+		if err := lobby.TeamsReady(); err != nil {
+			reject(protocol.CodeInvalidState, "cannot start the game: %v", err)
+			return
+		}
+		if userID, limit, reached := gs.gameLimitReached(lobby.SeatedUsers()...); reached {
+			reject(protocol.CodeInvalidState, "cannot start the game: %s", gameLimitMessage(userID, limit))
+			return
+		}
+		if err := checkRankedSeats(ctx, lobby); err != nil {
+			reply(err.Frame())
 			return
 		}
 		lobby.CancelCountdown()
 
-		// create game now
-		g := GameServerForLobbyWS.NewCambiaGameFromLobby(context.Background(), lobby)
+		// create game now; it outlives the connection that started it
+		g := gs.NewCambiaGameFromLobby(context.Background(), lobby)
 		lobby.BroadcastAll(map[string]interface{}{
 			"type":    "game_start",
 			"game_id": g.ID.String(),
 		})
+	case *protocol.ResyncFrom:
+		msgs, latest, complete := lobby.Resync(senderConn.UserID, m.Seq)
+		resync := map[string]interface{}{
+			"type":     "resync",
+			"events":   msgs,
+			"seq":      latest,
+			"complete": complete,
+		}
+		if !complete {
+			resync["lobby"] = lobby
+			resync["ready_map"] = lobby.ReadyMap()
+		}
+		reply(resync)
 	default:
-		logger.Warnf("unknown action %s from user %v", action, senderConn.UserID)
+		logger.Warnf("unhandled lobby message %T from user %v", packet, senderConn.UserID)
+	}
+}
+
+// setChatReaction adds or removes the sender's reaction on a chat message.
+func setChatReaction(ctx context.Context, lobby *game.Lobby, senderConn *game.LobbyConnection, r protocol.ChatReaction, add bool, logger logrus.FieldLogger) error {
+	m, err := lobby.SetReaction(senderConn.UserID, r.MsgID, r.Emoji, add)
+	if err != nil {
+		return err
 	}
+	if chatPersistenceEnabled() {
+		if err := database.Lobbies.SetLobbyChatReactions(ctx, m.ID, lobby.ReactionUsers(m)); err != nil {
+			logger.Warnf("failed to persist reactions for chat message %v: %v", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// chatPersistenceEnabled reports whether lobby chat (and reactions) should be written to the database.
+func chatPersistenceEnabled() bool {
+	return os.Getenv("PERSIST_LOBBY_CHAT") == "true"
 }
 
-// writePump writes messages from conn.OutChan to the websocket until context is canceled.
-func writePump(ctx context.Context, c *websocket.Conn, conn *game.LobbyConnection, logger *logrus.Logger) {
+// writePump writes messages from conn.OutChan, and any coalesced updates, to the websocket until context is
+// canceled.
+func writePump(ctx context.Context, c *websocket.Conn, conn *game.LobbyConnection, logger logrus.FieldLogger) {
+	defer crash.Recover("socket writer")
+	write := func(msg map[string]interface{}) bool {
+		data, err := game.EncodeFrame(msg)
+		if err != nil {
+			logger.Warnf("failed to marshal out msg: %v", err)
+			return true
+		}
+		if err := c.Write(ctx, websocket.MessageText, data); err != nil {
+			logger.Warnf("failed to write to ws: %v", err)
+			return false
+		}
+		return true
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case msg := <-conn.OutChan:
-			data, err := json.Marshal(msg)
-			if err != nil {
-				logger.Warnf("failed to marshal out msg: %v", err)
+			if !write(msg) {
+				return
+			}
+			if len(conn.OutChan) > 0 {
 				continue
 			}
-			err = c.Write(ctx, websocket.MessageText, data)
-			if err != nil {
-				logger.Warnf("failed to write to ws: %v", err)
+		case <-conn.Coalesced():
+		}
+		// coalesced updates go out once the queue ahead of them has drained
+		for _, msg := range conn.TakeCoalesced() {
+			if !write(msg) {
 				return
 			}
 		}
 	}
 }
+
+// checkRankedSeats refuses to start a ranked lobby's game while any seated player's ranked play is suspended.
+func checkRankedSeats(ctx context.Context, lobby *game.Lobby) *protocol.Error {
+	if !lobby.Ranked {
+		return nil
+	}
+	userID, suspended, err := rankedSuspended(ctx, lobby.SeatedUsers()...)
+	if err != nil {
+		return protocol.Errorf(protocol.CodeInternal, "failed to check penalty status")
+	}
+	if suspended {
+		return protocol.Errorf(protocol.CodeForbidden, "cannot start ranked game: ranked play is suspended for player %v", userID)
+	}
+	return nil
+}
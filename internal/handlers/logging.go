@@ -0,0 +1,57 @@
+// internal/handlers/logging.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/logging"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// AdminLoggingHandler serves the server's logging configuration on this node:
+//
+//	GET /admin/logging   { "level", "sinkPath", "sinkEnabled" }
+//	PUT /admin/logging   { "level": "debug", "sink": true } changes either or both, until a restart
+//
+// The sink copies every log entry as a JSON line to the file named by LOG_FILE.
+func AdminLoggingHandler(ctl *logging.Control) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req struct {
+				Level *string `json:"level"`
+				Sink  *bool   `json:"sink"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Level == nil && req.Sink == nil) {
+				apierr.Error(w, "invalid payload", http.StatusBadRequest)
+				return
+			}
+			if req.Level != nil {
+				if err := ctl.SetLevel(*req.Level); err != nil {
+					apierr.Error(w, "invalid level", http.StatusBadRequest)
+					return
+				}
+			}
+			if req.Sink != nil {
+				if err := ctl.EnableSink(*req.Sink); err != nil {
+					apierr.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
+			}
+			status := ctl.Status()
+			audit(r, models.AuditLoggingSet, models.AuditTargetLogging, uuid.Nil, map[string]interface{}{
+				"level":       status.Level,
+				"sinkEnabled": status.SinkEnabled,
+			})
+		default:
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ctl.Status())
+	}
+}
@@ -0,0 +1,170 @@
+// internal/handlers/match_history.go
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+const (
+	defaultMatchHistoryLimit = 20
+	maxMatchHistoryLimit     = 100
+)
+
+type matchHistoryResponse struct {
+	UserID     uuid.UUID               `json:"userID"`
+	Matches    []database.MatchSummary `json:"matches"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+func encodeMatchCursor(m database.MatchSummary) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", m.EndedAt.UnixNano(), m.GameID)))
+}
+
+func decodeMatchCursor(s string) (*database.MatchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	tsStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, err
+	}
+	return &database.MatchCursor{EndedAt: time.Unix(0, ts), GameID: id}, nil
+}
+
+// resolveUserID parses the {id} segment of a /user/{id}/... path, where "me" means the authenticated caller.
+func resolveUserID(w http.ResponseWriter, r *http.Request, s string) (uuid.UUID, bool) {
+	if s == "me" {
+		return authenticateRequest(w, r)
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		apierr.Error(w, "invalid user id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// UserHandler routes /user/{id}/... requests to the matches, profile, and achievements handlers.
+func UserHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/user/"), "/"), "/")
+	if len(parts) == 2 {
+		switch parts[1] {
+		case "profile":
+			UserProfileHandler(w, r)
+			return
+		case "achievements":
+			UserAchievementsHandler(w, r)
+			return
+		}
+	}
+	UserMatchesHandler(w, r)
+}
+
+// UserMatchesHandler handles GET /user/{id}/matches, a user's completed games, most recent first.
+// {id} may be "me" for the authenticated caller.
+//
+// Query parameters:
+//
+//	limit   page size, default 20, max 100
+//	cursor  nextCursor from the previous page
+func UserMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/user/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "matches" {
+		apierr.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	userID, ok := resolveUserID(w, r, parts[0])
+	if !ok {
+		return
+	}
+
+	limit := defaultMatchHistoryLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxMatchHistoryLimit)
+	}
+	var after *database.MatchCursor
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeMatchCursor(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		after = c
+	}
+
+	matches, err := database.Games.GetMatchHistory(r.Context(), userID, after, limit)
+	if err != nil {
+		log.Printf("failed to load match history for %v: %v", userID, err)
+		apierr.Error(w, "failed to load match history", http.StatusInternalServerError)
+		return
+	}
+	resp := matchHistoryResponse{UserID: userID, Matches: matches}
+	if len(matches) == limit {
+		resp.NextCursor = encodeMatchCursor(matches[len(matches)-1])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GameResultHandler handles GET /game/{id}/result, the full recorded outcome of a finished game:
+// every player's score, placement, and rating change, plus a per-round breakdown of revealed hands.
+func GameResultHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/game/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "result" {
+		apierr.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	gameID, err := uuid.Parse(parts[0])
+	if err != nil {
+		apierr.Error(w, "invalid game id", http.StatusBadRequest)
+		return
+	}
+
+	res, err := database.Games.GetGameResult(r.Context(), gameID)
+	if err != nil {
+		log.Printf("failed to load result for game %v: %v", gameID, err)
+		apierr.Error(w, "failed to load game result", http.StatusInternalServerError)
+		return
+	}
+	if res == nil {
+		apierr.Error(w, "game result not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
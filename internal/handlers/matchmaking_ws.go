@@ -0,0 +1,261 @@
+// internal/handlers/matchmaking_ws.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/features"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/matchmaking"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/models"
+	"github.com/jason-s-yu/cambia/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// MatchmakingWSHandler sets up the WebSocket at /matchmaking/ws, subprotocol "matchmaking.v1". Guests can't
+// queue for ranked play.
+//
+// Client messages:
+//
+//	{"type": "queue_join", "mode": "1v1" | "4p" | "7p8p"}  (a party leader queues the whole party)
+//	{"type": "queue_leave"}
+//	{"type": "match_accept"}
+//	{"type": "match_decline"}
+//	{"type": "party_create"}
+//	{"type": "party_invite", "userID": "{uuid}"}            (leader only; friends only)
+//	{"type": "party_join", "party_id": "{uuid}"}
+//	{"type": "party_leave"}
+//
+// Server messages: "queue_joined", "queue_left", "match_found" (with match_id, players, accept_by),
+// "match_accept_update", "match_cancelled" (with reason and whether the player was requeued),
+// "match_ready" (with the game_id to open at /game/ws/{game_id}), and "party_update", "party_invite",
+// "party_left", "party_disbanded". Disconnecting leaves the queue and any party.
+func MatchmakingWSHandler(logger *logrus.Logger, gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := middleware.RequestLogger(r.Context(), logger)
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols:   protocol.MatchmakingSocket.Subprotocols(),
+			OriginPatterns: middleware.AllowedOrigins,
+		})
+		if err != nil {
+			logger.Warnf("websocket accept error: %v", err)
+			return
+		}
+		protoVersion, reason := protocol.MatchmakingSocket.Negotiate(r, c.Subprotocol())
+		if reason != "" {
+			c.Close(websocket.StatusPolicyViolation, reason)
+			return
+		}
+
+		userIDStr, err := auth.AuthenticateJWT(auth.WSRequestToken(r))
+		if err != nil {
+			c.Close(websocket.StatusPolicyViolation, wsAuthCloseReason(err))
+			return
+		}
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			c.Close(websocket.StatusPolicyViolation, "invalid user ID")
+			return
+		}
+		middleware.SetUserID(r.Context(), userID)
+		user, err := database.Users.GetUserByID(r.Context(), userID)
+		if err != nil {
+			c.Close(websocket.StatusPolicyViolation, "user not found")
+			return
+		}
+		if user.IsEphemeral {
+			c.Close(websocket.StatusPolicyViolation, "guests cannot queue for ranked play")
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		conn := &game.LobbyConnection{
+			UserID:          userID,
+			Cancel:          cancel,
+			OutChan:         make(chan map[string]interface{}, 16),
+			ProtocolVersion: protoVersion,
+			RequestID:       middleware.RequestID(r.Context()),
+		}
+		gs.matchmakingMu.Lock()
+		if prev, ok := gs.matchmakingConns[userID]; ok {
+			prev.Cancel() // newest connection wins
+		}
+		gs.matchmakingConns[userID] = conn
+		gs.matchmakingMu.Unlock()
+
+		limiter := protocol.NewLimiter(userID, protocol.MatchmakingLimits)
+		defer limiter.Close()
+		closeStatus, closeReason := websocket.StatusNormalClosure, "closing"
+		defer func() {
+			gs.matchmakingMu.Lock()
+			current := gs.matchmakingConns[userID] == conn
+			if current {
+				delete(gs.matchmakingConns, userID)
+			}
+			gs.matchmakingMu.Unlock()
+			// Leave may notify other players, which takes matchmakingMu
+			if current {
+				gs.Matchmaker.Leave(userID)
+				gs.Matchmaker.LeaveParty(userID)
+			}
+			cancel()
+			c.Close(closeStatus, closeReason)
+		}()
+
+		go writePump(ctx, c, conn, logger)
+
+		for {
+			typ, data, err := c.Read(ctx)
+			if err != nil {
+				logger.Debugf("matchmaking user %v read err: %v", userID, err)
+				return
+			}
+			if typ != websocket.MessageText {
+				continue
+			}
+			var packet map[string]interface{}
+			jsonErr := json.Unmarshal(data, &packet)
+			action, _ := packet["type"].(string)
+			if limited, disconnect := limiter.Allow(action); limited != nil {
+				if disconnect {
+					logger.Warnf("disconnecting matchmaking user %v: rate limit exceeded", userID)
+					closeStatus, closeReason = websocket.StatusPolicyViolation, "too many messages"
+					return
+				}
+				conn.Write(limited.Frame())
+				continue
+			}
+			if jsonErr != nil {
+				continue
+			}
+			handleMatchmakingMessage(ctx, gs, user, conn, packet)
+		}
+	}
+}
+
+func handleMatchmakingMessage(ctx context.Context, gs *GameServer, user *models.User, conn *game.LobbyConnection, packet map[string]interface{}) {
+	switch packet["type"] {
+	case "party_create", "party_invite", "party_join":
+		// leaving is always allowed, so no one is stuck in a party when they're turned off
+		if !features.Enabled(features.MatchmakingParties) {
+			conn.WriteError("parties are currently disabled")
+			return
+		}
+	}
+	switch packet["type"] {
+	case "queue_join":
+		mode, _ := packet["mode"].(string)
+		members := gs.Matchmaker.PartyMembers(user.ID)
+		if members == nil {
+			members = []uuid.UUID{user.ID}
+		}
+		if id, limit, reached := gs.gameLimitReached(members...); reached {
+			conn.WriteError(fmt.Sprintf("cannot queue: %s", gameLimitMessage(id, limit)))
+			return
+		}
+		for _, id := range members {
+			ban, err := database.ActiveBan(ctx, id, models.BanScopeGlobal, models.BanScopeMatchmaking)
+			if err != nil {
+				conn.WriteError("failed to check bans")
+				return
+			}
+			if ban != nil {
+				conn.WriteError(fmt.Sprintf("player %v cannot queue: %s", id, banMessage(ban)))
+				return
+			}
+			status, err := database.GetPenaltyStatus(ctx, id)
+			if err != nil {
+				conn.WriteError("failed to check penalty status")
+				return
+			}
+			if !status.CanQueue(time.Now()) {
+				conn.WriteError(fmt.Sprintf("player %v cannot queue yet: abandon penalty in effect", id))
+				return
+			}
+			avoid, err := database.ListBlockRelations(ctx, id)
+			if err != nil {
+				conn.WriteError("failed to load block list")
+				return
+			}
+			gs.Matchmaker.SetAvoid(id, avoid)
+		}
+		var t *matchmaking.Ticket
+		var err error
+		if len(members) > 1 {
+			ratings := make(map[uuid.UUID]int, len(members))
+			for _, id := range members {
+				u, err := database.Users.GetUserByID(ctx, id)
+				if err != nil {
+					conn.WriteError("failed to load party member ratings")
+					return
+				}
+				ratings[id] = database.ModeRating(u, mode)
+			}
+			t, err = gs.Matchmaker.QueueParty(user.ID, mode, ratings)
+		} else {
+			t, err = gs.Matchmaker.Join(user.ID, mode, database.ModeRating(user, mode))
+		}
+		if err != nil {
+			conn.WriteError(err.Error())
+			return
+		}
+		conn.Write(map[string]interface{}{
+			"type":        "queue_joined",
+			"mode":        t.Mode,
+			"enqueued_at": t.EnqueuedAt.Unix(),
+		})
+	case "queue_leave":
+		gs.Matchmaker.Leave(user.ID)
+		conn.Write(map[string]interface{}{"type": "queue_left"})
+	case "match_accept":
+		if err := gs.Matchmaker.Accept(user.ID); err != nil {
+			conn.WriteError(err.Error())
+		}
+	case "match_decline":
+		if err := gs.Matchmaker.Decline(user.ID); err != nil {
+			conn.WriteError(err.Error())
+		}
+	case "party_create":
+		if _, err := gs.Matchmaker.CreateParty(user.ID); err != nil {
+			conn.WriteError(err.Error())
+		}
+	case "party_invite":
+		idStr, _ := packet["userID"].(string)
+		inviteeID, err := uuid.Parse(idStr)
+		if err != nil {
+			conn.WriteError("invalid userID")
+			return
+		}
+		friends, err := database.AreFriends(ctx, user.ID, inviteeID)
+		if err != nil || !friends {
+			conn.WriteError("you can only invite friends to a party")
+			return
+		}
+		if err := gs.Matchmaker.InviteToParty(user.ID, inviteeID); err != nil {
+			conn.WriteError(err.Error())
+		}
+	case "party_join":
+		idStr, _ := packet["party_id"].(string)
+		partyID, err := uuid.Parse(idStr)
+		if err != nil {
+			conn.WriteError("invalid party_id")
+			return
+		}
+		if err := gs.Matchmaker.JoinParty(user.ID, partyID); err != nil {
+			conn.WriteError(err.Error())
+		}
+	case "party_leave":
+		gs.Matchmaker.LeaveParty(user.ID)
+	default:
+		conn.WriteError("unknown matchmaking message type")
+	}
+}
@@ -0,0 +1,518 @@
+// internal/handlers/moderation.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// reportChatSnapshotSize is how many recent lobby chat messages are captured with a report.
+	reportChatSnapshotSize = 50
+	// maxReportReasonLength caps the reason given for a report, in characters.
+	maxReportReasonLength = 500
+
+	defaultReportQueueLimit = 50
+	maxReportQueueLimit     = 200
+)
+
+// FileReport records a report of offender by reporter. If the report is made from a lobby, the lobby's
+// recent chat is captured with it. Errors are safe to show to the reporter.
+func (gs *GameServer) FileReport(ctx context.Context, reporter, offender uuid.UUID, reason string, lobby *game.Lobby, gameID *uuid.UUID) (*models.PlayerReport, error) {
+	reason = strings.TrimSpace(reason)
+	if reporter == offender {
+		return nil, errors.New("cannot report yourself")
+	}
+	if reason == "" {
+		return nil, errors.New("a reason is required")
+	}
+	if utf8.RuneCountInString(reason) > maxReportReasonLength {
+		return nil, errors.New("reason is too long")
+	}
+
+	rep := &models.PlayerReport{ReporterID: reporter, OffenderID: offender, Reason: reason, GameID: gameID}
+	if lobby != nil {
+		lobbyID := lobby.ID
+		rep.LobbyID = &lobbyID
+		for _, m := range lobby.RecentChat(reportChatSnapshotSize) {
+			rep.ChatSnapshot = append(rep.ChatSnapshot, models.ReportedChatMessage{ID: m.ID, UserID: m.UserID, Msg: m.Msg, TS: m.TS})
+		}
+	}
+	if err := database.InsertReport(ctx, rep); err != nil {
+		if errors.Is(err, database.ErrAlreadyReported) {
+			return nil, err
+		}
+		log.Warnf("failed to file report of %v by %v: %v", offender, reporter, err)
+		return nil, errors.New("failed to file report")
+	}
+	log.Infof("user %v reported %v (report %v)", reporter, offender, rep.ID)
+	return rep, nil
+}
+
+// reportableInLobby reports whether a user can be reported from a lobby: they're in it, or they chatted in
+// it recently.
+func reportableInLobby(lobby *game.Lobby, userID uuid.UUID) bool {
+	if lobby.IsMember(userID) {
+		return true
+	}
+	for _, m := range lobby.RecentChat(reportChatSnapshotSize) {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// disconnectUser closes every live connection a user has to the server: lobbies, games, matchmaking, and
+// /user/ws. It reports whether there were any.
+func (gs *GameServer) disconnectUser(userID uuid.UUID, reason string) bool {
+	found := false
+	for _, lobby := range gs.LobbyStore.GetLobbies() {
+		if conn, ok := lobby.Connection(userID); ok {
+			conn.WriteError(reason)
+			conn.Cancel()
+			found = true
+		}
+	}
+	for _, g := range gs.GameStore.GetGames() {
+		if g.Disconnect(userID) {
+			found = true
+		}
+	}
+	gs.matchmakingMu.Lock()
+	if conn, ok := gs.matchmakingConns[userID]; ok {
+		conn.Cancel()
+		found = true
+	}
+	gs.matchmakingMu.Unlock()
+	gs.Matchmaker.Leave(userID)
+	gs.userConnsMu.Lock()
+	if conn, ok := gs.userConns[userID]; ok {
+		conn.Cancel()
+		found = true
+	}
+	gs.userConnsMu.Unlock()
+	return found
+}
+
+// sanctionPayload describes a new sanction for the audit log.
+func sanctionPayload(s *models.Sanction) map[string]interface{} {
+	p := map[string]interface{}{"sanctionID": s.ID, "kind": s.Kind, "reason": s.Reason}
+	if s.Scope != "" {
+		p["scope"] = s.Scope
+	}
+	if s.ExpiresAt != nil {
+		p["expiresAt"] = s.ExpiresAt
+	}
+	return p
+}
+
+type sanctionRequest struct {
+	DurationMinutes int    `json:"durationMinutes"` // 0 for permanent
+	Reason          string `json:"reason"`
+	Scope           string `json:"scope"` // bans only; defaults to "global"
+}
+
+// newSanction builds a sanction of the given kind issued by moderatorID.
+func newSanction(kind string, moderatorID uuid.UUID, req sanctionRequest) (*models.Sanction, error) {
+	if req.DurationMinutes < 0 {
+		return nil, errors.New("durationMinutes must not be negative")
+	}
+	s := &models.Sanction{Kind: kind, Reason: req.Reason, IssuedBy: moderatorID}
+	if kind == models.SanctionBan {
+		s.Scope = req.Scope
+		if s.Scope == "" {
+			s.Scope = models.BanScopeGlobal
+		}
+		if !models.ValidBanScope(s.Scope) {
+			return nil, errors.New("scope must be global or matchmaking")
+		}
+	}
+	if req.DurationMinutes > 0 {
+		expires := time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		s.ExpiresAt = &expires
+	}
+	return s, nil
+}
+
+// applySanction notifies the sanctioned user. Global bans drop their live connections; matchmaking bans
+// take them out of the queue.
+func (gs *GameServer) applySanction(s *models.Sanction) {
+	switch {
+	case s.Kind == models.SanctionBan && s.Scope == models.BanScopeMatchmaking:
+		gs.Matchmaker.Leave(s.UserID)
+		gs.Notify(s.UserID, "matchmaking_banned", map[string]interface{}{"message": banMessage(s)})
+		return
+	case s.Kind == models.SanctionBan:
+		gs.disconnectUser(s.UserID, banMessage(s))
+		return
+	}
+	payload := map[string]interface{}{"reason": s.Reason}
+	if s.ExpiresAt != nil {
+		payload["expiresAt"] = s.ExpiresAt
+	}
+	gs.Notify(s.UserID, "muted", payload)
+}
+
+// banMessage describes a ban to the banned user.
+func banMessage(ban *models.Sanction) string {
+	what := "account banned"
+	if ban.Scope == models.BanScopeMatchmaking {
+		what = "banned from matchmaking"
+	}
+	if ban.ExpiresAt != nil {
+		what += " until " + ban.ExpiresAt.Format(time.RFC3339)
+	}
+	if ban.Reason != "" {
+		what += ": " + ban.Reason
+	}
+	return what
+}
+
+// claimsUserID returns the caller's ID from the claims stored by middleware.RequireRole.
+func claimsUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		apierr.Error(w, "missing auth_token", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		apierr.Error(w, "invalid token", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// ModReportsHandler serves the moderation queue:
+//
+//	GET  /mod/reports                 reports by status (?status=open|actioned|dismissed, default open),
+//	                                  oldest first (?limit=&cursor=)
+//	GET  /mod/reports/{id}            a single report, with its chat snapshot
+//	POST /mod/reports/{id}/resolve    { "action": "dismiss" | "mute" | "ban", "durationMinutes": 0, "note": "" }
+//
+// Muting or banning resolves the report as "actioned" and sanctions the offender; durationMinutes 0 is
+// permanent.
+func ModReportsHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/mod/reports"), "/")
+		if path == "" {
+			if r.Method != http.MethodGet {
+				apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			listReports(w, r)
+			return
+		}
+
+		parts := strings.Split(path, "/")
+		reportID, err := uuid.Parse(parts[0])
+		if err != nil {
+			apierr.Error(w, "invalid report id", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			rep, err := database.GetReport(r.Context(), reportID)
+			if errors.Is(err, database.ErrReportNotFound) {
+				apierr.Error(w, "report not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Warnf("failed to load report %v: %v", reportID, err)
+				apierr.Error(w, "failed to load report", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(rep)
+		case len(parts) == 2 && parts[1] == "resolve" && r.Method == http.MethodPost:
+			gs.resolveReport(w, r, reportID)
+		case len(parts) <= 2:
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		default:
+			apierr.Error(w, "unsupported moderator route", http.StatusNotFound)
+		}
+	}
+}
+
+func listReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.ReportOpen
+	case models.ReportOpen, models.ReportActioned, models.ReportDismissed:
+	default:
+		apierr.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	limit := defaultReportQueueLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxReportQueueLimit)
+	}
+	after := uuid.Nil
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		after = id
+	}
+
+	reports, err := database.ListReports(r.Context(), status, after, limit)
+	if err != nil {
+		log.Warnf("failed to list reports: %v", err)
+		apierr.Error(w, "failed to load reports", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Reports    []models.PlayerReport `json:"reports"`
+		NextCursor string                `json:"nextCursor,omitempty"`
+	}{Reports: reports}
+	if len(reports) == limit {
+		resp.NextCursor = reports[len(reports)-1].ID.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ModAnticheatFlagsHandler handles GET /mod/anticheat, listing the play the game server flagged as
+// suggesting a player knew cards they never saw, newest first. ?userID= narrows it to one player; pages
+// continue from ?cursor=.
+func ModAnticheatFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := uuid.Nil
+	if s := r.URL.Query().Get("userID"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid userID", http.StatusBadRequest)
+			return
+		}
+		userID = id
+	}
+	limit := defaultReportQueueLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxReportQueueLimit)
+	}
+	before := uuid.Nil
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		before = id
+	}
+
+	flags, err := database.ListAnticheatFlags(r.Context(), userID, before, limit)
+	if err != nil {
+		log.Warnf("failed to list anti-cheat flags: %v", err)
+		apierr.Error(w, "failed to load anti-cheat flags", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Flags      []models.AnticheatFlag `json:"flags"`
+		NextCursor string                 `json:"nextCursor,omitempty"`
+	}{Flags: flags}
+	if len(flags) == limit {
+		resp.NextCursor = flags[len(flags)-1].ID.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ModSmurfSignalsHandler handles GET /mod/smurf-signals, listing accounts that keep logging in from the
+// same address or device as a banned or much higher rated account, newest first. ?userID= narrows it to one
+// account; pages continue from ?cursor=.
+func ModSmurfSignalsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := uuid.Nil
+	if s := r.URL.Query().Get("userID"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid userID", http.StatusBadRequest)
+			return
+		}
+		userID = id
+	}
+	limit := defaultReportQueueLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxReportQueueLimit)
+	}
+	before := uuid.Nil
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		before = id
+	}
+
+	signals, err := database.ListSmurfSignals(r.Context(), userID, before, limit)
+	if err != nil {
+		log.Warnf("failed to list smurf signals: %v", err)
+		apierr.Error(w, "failed to load smurf signals", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		Signals    []models.SmurfSignal `json:"signals"`
+		NextCursor string               `json:"nextCursor,omitempty"`
+	}{Signals: signals}
+	if len(signals) == limit {
+		resp.NextCursor = signals[len(signals)-1].ID.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (gs *GameServer) resolveReport(w http.ResponseWriter, r *http.Request, reportID uuid.UUID) {
+	modID, ok := claimsUserID(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+		sanctionRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	status := models.ReportActioned
+	var sanction *models.Sanction
+	switch req.Action {
+	case "dismiss":
+		status = models.ReportDismissed
+	case models.SanctionMute, models.SanctionBan:
+		if req.Reason == "" {
+			req.Reason = req.Note
+		}
+		s, err := newSanction(req.Action, modID, req.sanctionRequest)
+		if err != nil {
+			apierr.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sanction = s
+	default:
+		apierr.Error(w, "action must be dismiss, mute, or ban", http.StatusBadRequest)
+		return
+	}
+
+	rep, err := database.ResolveReport(r.Context(), reportID, modID, status, req.Note, sanction)
+	if errors.Is(err, database.ErrReportNotFound) {
+		apierr.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Warnf("failed to resolve report %v: %v", reportID, err)
+		apierr.Error(w, "failed to resolve report", http.StatusInternalServerError)
+		return
+	}
+	if sanction != nil {
+		gs.applySanction(sanction)
+	}
+	log.Infof("moderator %v resolved report %v: %s", modID, reportID, req.Action)
+	payload := map[string]interface{}{"action": req.Action, "offenderID": rep.OffenderID, "note": req.Note}
+	if sanction != nil {
+		payload["sanctionID"] = sanction.ID
+		payload["durationMinutes"] = req.DurationMinutes
+	}
+	audit(r, models.AuditReportResolve, models.AuditTargetReport, reportID, payload)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rep)
+}
+
+// ModSanctionUserHandler handles POST /mod/user/{user_id}/{mute|ban|unmute|unban}, sanctioning a user
+// outside of a report. Mutes and bans take { "durationMinutes": 0, "reason": "" }; 0 is permanent.
+func ModSanctionUserHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/mod/user/"), "/"), "/")
+		if len(parts) != 2 {
+			apierr.Error(w, "unsupported moderator route", http.StatusNotFound)
+			return
+		}
+		userID, err := uuid.Parse(parts[0])
+		if err != nil {
+			apierr.Error(w, "invalid user_id", http.StatusBadRequest)
+			return
+		}
+		modID, ok := claimsUserID(w, r)
+		if !ok {
+			return
+		}
+
+		switch action := parts[1]; action {
+		case models.SanctionMute, models.SanctionBan:
+			var req sanctionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apierr.Error(w, "invalid payload", http.StatusBadRequest)
+				return
+			}
+			s, err := newSanction(action, modID, req)
+			if err != nil {
+				apierr.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s.UserID = userID
+			if err := database.IssueSanction(r.Context(), s); err != nil {
+				log.Warnf("failed to sanction %v: %v", userID, err)
+				apierr.Error(w, "failed to sanction user", http.StatusInternalServerError)
+				return
+			}
+			gs.applySanction(s)
+			log.Infof("moderator %v issued %s against %v", modID, action, userID)
+			audit(r, "user."+action, models.AuditTargetUser, userID, sanctionPayload(s))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s)
+		case "unmute", "unban":
+			kind := strings.TrimPrefix(action, "un")
+			n, err := database.RevokeSanctions(r.Context(), userID, modID, kind)
+			if err != nil {
+				log.Warnf("failed to lift %s on %v: %v", kind, userID, err)
+				apierr.Error(w, "failed to update sanctions", http.StatusInternalServerError)
+				return
+			}
+			log.Infof("moderator %v lifted %s on %v", modID, kind, userID)
+			audit(r, "user."+action, models.AuditTargetUser, userID, map[string]interface{}{"revoked": n})
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"revoked": n})
+		default:
+			apierr.Error(w, "unsupported moderator route", http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// internal/handlers/notification.go
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+const (
+	defaultInboxLimit = 50
+	maxInboxLimit     = 200
+)
+
+type inboxResponse struct {
+	Notifications []models.Notification `json:"notifications"`
+	Unread        int                   `json:"unread"`
+	NextCursor    string                `json:"nextCursor,omitempty"`
+}
+
+// NotificationsHandler handles GET /user/notifications, the caller's notification inbox, newest first.
+//
+// Query parameters:
+//
+//	unread  "true" to list only unread notifications
+//	limit   page size, default 50, max 200
+//	cursor  nextCursor from the previous page
+func NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultInboxLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxInboxLimit)
+	}
+	before := uuid.Nil
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		before = id
+	}
+
+	notifications, err := database.ListNotifications(r.Context(), userID, r.URL.Query().Get("unread") == "true", before, limit)
+	if err != nil {
+		log.Printf("failed to list notifications for %v: %v", userID, err)
+		apierr.Error(w, "failed to load notifications", http.StatusInternalServerError)
+		return
+	}
+	unread, err := database.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to count notifications for %v: %v", userID, err)
+		apierr.Error(w, "failed to load notifications", http.StatusInternalServerError)
+		return
+	}
+	resp := inboxResponse{Notifications: notifications, Unread: unread}
+	if len(notifications) == limit {
+		resp.NextCursor = notifications[len(notifications)-1].ID.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// MarkNotificationsReadHandler handles POST /user/notifications/read.
+//
+// Request payload: { "ids": ["{uuid}", ...] }, or { "all": true } to mark the whole inbox read.
+func MarkNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		IDs []uuid.UUID `json:"ids"`
+		All bool        `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 && !req.All {
+		apierr.Error(w, "either ids or all is required", http.StatusBadRequest)
+		return
+	}
+	if req.All {
+		req.IDs = nil
+	}
+
+	marked, err := database.MarkNotificationsRead(r.Context(), userID, req.IDs)
+	if err != nil {
+		log.Printf("failed to mark notifications read for %v: %v", userID, err)
+		apierr.Error(w, "failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"marked": marked})
+}
@@ -0,0 +1,127 @@
+// internal/handlers/password_reset.go
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/crash"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/mail"
+)
+
+// passwordResetTTL is how long an emailed reset token stays valid.
+const passwordResetTTL = time.Hour
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type passwordResetConfirm struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// hashResetToken returns the form of a reset token we store; the raw token only ever exists in the email.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PasswordResetRequestHandler handles POST /user/password/reset, emailing a single-use reset token to the
+// account's address. It responds 202 whether or not the email belongs to an account, so it can't be used
+// to discover registered emails.
+//
+// The link in the email is PASSWORD_RESET_URL with ?token={token} appended.
+func PasswordResetRequestHandler(sender mail.Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req passwordResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+			apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid payload")
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("if the account exists, a reset email has been sent"))
+
+		u, err := database.Users.GetUserByEmail(r.Context(), req.Email)
+		if err != nil || u.IsEphemeral {
+			return
+		}
+
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			log.Printf("failed to generate reset token: %v", err)
+			return
+		}
+		token := base64.RawURLEncoding.EncodeToString(raw)
+		if err := database.InsertPasswordResetToken(r.Context(), u.ID, hashResetToken(token), time.Now().Add(passwordResetTTL)); err != nil {
+			log.Printf("failed to store reset token for %v: %v", u.ID, err)
+			return
+		}
+
+		link := os.Getenv("PASSWORD_RESET_URL") + "?token=" + url.QueryEscape(token)
+		body := fmt.Sprintf("Someone asked to reset the password for your Cambia account.\n\n"+
+			"Use this link within %v to choose a new password:\n%s\n\n"+
+			"If this wasn't you, you can ignore this email.\n", passwordResetTTL, link)
+
+		// send in the background so response time doesn't reveal whether the account exists
+		go func() {
+			defer crash.Recover("sending a password reset email")
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := sender.Send(ctx, u.Email, "Reset your Cambia password", body); err != nil {
+				log.Printf("failed to send reset email to %v: %v", u.ID, err)
+			}
+		}()
+	}
+}
+
+// PasswordResetConfirmHandler handles POST /user/password/reset/confirm. It consumes the token, sets the
+// new password, and revokes every existing session for the account.
+func PasswordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req passwordResetConfirm
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid payload")
+		return
+	}
+	if req.Password == "" {
+		apierr.Error(w, "password must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := database.ResetPassword(r.Context(), hashResetToken(req.Token), req.Password)
+	if errors.Is(err, database.ErrInvalidResetToken) {
+		apierr.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to reset password: %v", err)
+		apierr.Error(w, "failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("password reset for user %v; existing sessions revoked", userID)
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("password updated"))
+}
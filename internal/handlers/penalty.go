@@ -0,0 +1,49 @@
+// internal/handlers/penalty.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+// PenaltyStatusHandler handles GET /user/penalty, returning the caller's recent abandon count and any
+// active queue cooldown or ranked ban.
+func PenaltyStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	status, err := database.GetPenaltyStatus(r.Context(), userID)
+	if err != nil {
+		log.Printf("failed to load penalty status for %v: %v", userID, err)
+		apierr.Error(w, "failed to load penalty status", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// rankedSuspended returns the first of userIDs whose ranked play is suspended, if any is.
+func rankedSuspended(ctx context.Context, userIDs ...uuid.UUID) (uuid.UUID, bool, error) {
+	for _, id := range userIDs {
+		status, err := database.GetPenaltyStatus(ctx, id)
+		if err != nil {
+			return uuid.Nil, false, err
+		}
+		if !status.CanPlayRanked(time.Now()) {
+			return id, true, nil
+		}
+	}
+	return uuid.Nil, false, nil
+}
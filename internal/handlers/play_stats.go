@@ -0,0 +1,77 @@
+// internal/handlers/play_stats.go
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+const (
+	defaultPlayStatsDays  = 30
+	maxPlayStatsDays      = 365
+	defaultPlayStatsLimit = 50
+	maxPlayStatsLimit     = 200
+)
+
+// AdminPlayStatsHandler handles GET /admin/reports/play-stats, the operator-wide report on how players use
+// abilities, snap, and call Cambia: everyone's stats together, and a page of players' own.
+//
+// Query parameters:
+//
+//	days    how far back to look, default 30, max 365
+//	limit   players per page, default 50, max 200
+//	cursor  nextCursor from the previous page
+func AdminPlayStatsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	days := defaultPlayStatsDays
+	if s := q.Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid days", http.StatusBadRequest)
+			return
+		}
+		days = min(n, maxPlayStatsDays)
+	}
+	limit := defaultPlayStatsLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			apierr.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxPlayStatsLimit)
+	}
+	var after uuid.UUID
+	if s := q.Get("cursor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		after = id
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := database.GetPlayStatsReport(r.Context(), since, after, limit)
+	if err != nil {
+		log.Printf("failed to build play stats report: %v", err)
+		apierr.Error(w, "failed to load play stats", http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		*database.PlayStatsReport
+		NextCursor string `json:"nextCursor,omitempty"`
+	}{PlayStatsReport: report}
+	if len(report.Players) == limit {
+		resp.NextCursor = report.Players[len(report.Players)-1].UserID.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
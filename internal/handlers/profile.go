@@ -0,0 +1,85 @@
+// internal/handlers/profile.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+// maxAvatarURLLength caps the length of a profile avatar URL.
+const maxAvatarURLLength = 512
+
+// UserProfileHandler serves /user/{id}/profile. {id} may be "me" for the authenticated caller.
+//
+// GET returns the user's display name, avatar, and stats over their completed games: games played, win
+// rate overall and per rating mode, average score, snap accuracy, and how often calling Cambia won the game.
+//
+// POST /user/me/profile updates the caller's avatar: { "avatarURL": "https://..." }, or null to clear it.
+func UserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/user/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "profile" {
+		apierr.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		userID, ok := resolveUserID(w, r, parts[0])
+		if !ok {
+			return
+		}
+		profile, err := database.GetPlayerProfile(r.Context(), userID)
+		if errors.Is(err, database.ErrUserNotFound) {
+			apierr.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to load profile for %v: %v", userID, err)
+			apierr.Error(w, "failed to load profile", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profile)
+
+	case http.MethodPost:
+		if parts[0] != "me" {
+			apierr.Error(w, "can only update your own profile", http.StatusForbidden)
+			return
+		}
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			AvatarURL *string `json:"avatarURL"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		if req.AvatarURL != nil {
+			u, err := url.Parse(*req.AvatarURL)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(*req.AvatarURL) > maxAvatarURLLength {
+				apierr.Error(w, "invalid avatarURL", http.StatusBadRequest)
+				return
+			}
+		}
+		if err := database.SetAvatarURL(r.Context(), userID, req.AvatarURL); err != nil {
+			log.Printf("failed to update avatar for %v: %v", userID, err)
+			apierr.Error(w, "failed to update profile", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("profile updated"))
+
+	default:
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,69 @@
+// internal/handlers/rating_preview.go
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/matchmaking"
+	"github.com/jason-s-yu/cambia/internal/rating"
+)
+
+// ratingPreview is what a player stands to gain or lose by playing a game in a ranked mode's queue.
+type ratingPreview struct {
+	Mode        string              `json:"mode"`
+	Rating      int                 `json:"rating"`
+	Opponents   []int               `json:"opponents"`
+	PoolSize    int                 `json:"poolSize"`
+	Projections []rating.Projection `json:"projections"`
+}
+
+// previewRating projects userID's rating change in mode against the players queued there. Seats the queue
+// can't fill are taken by opponents rated like them.
+func previewRating(mm *matchmaking.Matchmaker, mode string, userID uuid.UUID, current int) ratingPreview {
+	opponents, pool := mm.Opponents(mode, userID, current)
+	for len(opponents) < matchmaking.ModeSizes[mode]-1 {
+		opponents = append(opponents, current)
+	}
+	return ratingPreview{
+		Mode:        mode,
+		Rating:      current,
+		Opponents:   opponents,
+		PoolSize:    pool,
+		Projections: rating.Preview(current, opponents),
+	}
+}
+
+// RatingPreviewHandler handles GET /matchmaking/preview?mode={mode}, where mode is one of "1v1", "4p", or
+// "7p8p". It projects the caller's rating after a game in that mode's queue for each place they could
+// finish in, against the queued players they'd likely be matched with.
+func RatingPreviewHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		mode := r.URL.Query().Get("mode")
+		if _, ok := matchmaking.ModeSizes[mode]; !ok {
+			apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidRequest, "unknown matchmaking mode", map[string]interface{}{"field": "mode"})
+			return
+		}
+		user, err := database.Users.GetUserByID(r.Context(), userID)
+		if err != nil {
+			log.Printf("failed to load user %v for a rating preview: %v", userID, err)
+			apierr.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if user.IsEphemeral {
+			apierr.Write(w, http.StatusForbidden, apierr.CodeForbidden, "guests cannot queue for ranked play")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(previewRating(gs.Matchmaker, mode, userID, database.ModeRating(user, mode)))
+	}
+}
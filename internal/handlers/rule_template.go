@@ -0,0 +1,149 @@
+// internal/handlers/rule_template.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+// maxRuleTemplateName is the longest name a rule template may have, in bytes.
+const maxRuleTemplateName = 64
+
+// RulePresetsHandler handles GET /lobby/presets, returning the built-in house rule presets a lobby can be
+// created from.
+func RulePresetsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game.RulePresets)
+}
+
+// ListRuleTemplatesHandler handles GET /user/rule-templates, returning the caller's saved rule templates.
+func ListRuleTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	templates, err := database.ListRuleTemplates(r.Context(), userID)
+	if err != nil {
+		apierr.Error(w, fmt.Sprintf("failed to list rule templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// SaveRuleTemplateHandler handles POST /user/rule-templates, saving a named bundle of house rules to the
+// caller's account. Saving under a name the caller already used replaces that template. Rules left out of
+// houseRules take the lobby defaults.
+//
+// Request payload: { "name": "Friday night", "houseRules": { "turnTimerSec": 30, ... } }
+func SaveRuleTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name       string          `json:"name"`
+		HouseRules json.RawMessage `json:"houseRules"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid payload")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxRuleTemplateName {
+		apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidPayload, fmt.Sprintf("name must be 1 to %d bytes", maxRuleTemplateName), map[string]interface{}{"field": "name"})
+		return
+	}
+
+	rules := game.NewLobbyWithDefaults(userID).HouseRules
+	if len(req.HouseRules) > 0 {
+		if err := json.Unmarshal(req.HouseRules, &rules); err != nil {
+			apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid houseRules", map[string]interface{}{"field": "houseRules"})
+			return
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidPayload, err.Error(), map[string]interface{}{"field": "houseRules"})
+		return
+	}
+	raw, _ := json.Marshal(rules)
+
+	t, err := database.SaveRuleTemplate(r.Context(), userID, req.Name, raw)
+	if errors.Is(err, database.ErrTooManyRuleTemplates) {
+		apierr.Write(w, http.StatusConflict, apierr.CodeConflict, fmt.Sprintf("at most %d rule templates can be saved", database.MaxRuleTemplates))
+		return
+	}
+	if err != nil {
+		apierr.Error(w, fmt.Sprintf("failed to save rule template: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// DeleteRuleTemplateHandler handles DELETE /user/rule-templates/{template_id}.
+func DeleteRuleTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	templateID, err := uuid.Parse(r.PathValue("template_id"))
+	if err != nil {
+		apierr.Error(w, "invalid template id", http.StatusBadRequest)
+		return
+	}
+	found, err := database.DeleteRuleTemplate(r.Context(), userID, templateID)
+	if err != nil {
+		apierr.Error(w, fmt.Sprintf("failed to delete rule template: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		apierr.Error(w, "rule template not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lobbyBaseRules returns the house rules a new lobby starts from: the preset or the caller's saved
+// template named in the creation request, if any. Rules the request sets explicitly are applied on top.
+func lobbyBaseRules(w http.ResponseWriter, r *http.Request, userID uuid.UUID, preset string, templateID *uuid.UUID) (*game.HouseRules, bool) {
+	switch {
+	case preset != "" && templateID != nil:
+		apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidLobby, "preset and ruleTemplateID are mutually exclusive", map[string]interface{}{"field": "preset"})
+		return nil, false
+	case preset != "":
+		p, ok := game.FindRulePreset(preset)
+		if !ok {
+			apierr.WriteDetails(w, http.StatusBadRequest, apierr.CodeInvalidLobby, "unknown preset", map[string]interface{}{"field": "preset"})
+			return nil, false
+		}
+		return &p.HouseRules, true
+	case templateID != nil:
+		t, err := database.GetRuleTemplate(r.Context(), userID, *templateID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierr.WriteDetails(w, http.StatusNotFound, apierr.CodeNotFound, "rule template not found", map[string]interface{}{"field": "ruleTemplateID"})
+			return nil, false
+		}
+		if err != nil {
+			apierr.Error(w, "failed to load rule template", http.StatusInternalServerError)
+			return nil, false
+		}
+		// rules added since the template was saved take their defaults
+		rules := game.NewLobbyWithDefaults(userID).HouseRules
+		if err := json.Unmarshal(t.HouseRules, &rules); err != nil {
+			apierr.Error(w, "failed to load rule template", http.StatusInternalServerError)
+			return nil, false
+		}
+		return &rules, true
+	}
+	return nil, true
+}
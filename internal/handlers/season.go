@@ -0,0 +1,143 @@
+// internal/handlers/season.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// resolveSeasonID parses a season query value, which is either a season ID or "current".
+// On failure it writes the error response and returns false.
+func resolveSeasonID(w http.ResponseWriter, r *http.Request, s string) (uuid.UUID, bool) {
+	if s != "current" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			apierr.Error(w, "invalid season", http.StatusBadRequest)
+			return uuid.Nil, false
+		}
+		return id, true
+	}
+	season, err := database.GetActiveSeason(r.Context())
+	if err != nil {
+		log.Printf("failed to load active season: %v", err)
+		apierr.Error(w, "failed to load season", http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	if season == nil {
+		apierr.Error(w, "no season in progress", http.StatusNotFound)
+		return uuid.Nil, false
+	}
+	return season.ID, true
+}
+
+// SeasonHandler routes the /season/ endpoints:
+//
+//	GET /season/              all seasons, newest first
+//	GET /season/{id|current}  a single season
+//	GET /season/{id|current}/me  the caller's ratings, placement status, and (after the season) final rank and reward
+func SeasonHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/season"), "/")
+	if path == "" {
+		seasons, err := database.ListSeasons(r.Context())
+		if err != nil {
+			log.Printf("failed to list seasons: %v", err)
+			apierr.Error(w, "failed to list seasons", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(seasons)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	seasonID, ok := resolveSeasonID(w, r, parts[0])
+	if !ok {
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		season, err := database.GetSeason(r.Context(), seasonID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apierr.Error(w, "season not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			apierr.Error(w, "failed to load season", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(season)
+	case len(parts) == 2 && parts[1] == "me":
+		userID, ok := authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		ratings, err := database.GetUserSeasonRatings(r.Context(), seasonID, userID)
+		if err != nil {
+			log.Printf("failed to load season ratings for %v: %v", userID, err)
+			apierr.Error(w, "failed to load season ratings", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ratings)
+	default:
+		apierr.Error(w, "unsupported season route", http.StatusNotFound)
+	}
+}
+
+type createSeasonRequest struct {
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+// AdminCreateSeasonHandler handles POST /admin/season, scheduling a new season.
+func AdminCreateSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req createSeasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		apierr.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		apierr.Error(w, "endsAt must be after startsAt", http.StatusBadRequest)
+		return
+	}
+
+	season := &models.Season{Name: req.Name, StartsAt: req.StartsAt, EndsAt: req.EndsAt}
+	if err := database.CreateSeason(r.Context(), season); err != nil {
+		if errors.Is(err, database.ErrSeasonOverlap) {
+			apierr.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("failed to create season: %v", err)
+		apierr.Error(w, "failed to create season", http.StatusInternalServerError)
+		return
+	}
+	audit(r, models.AuditSeasonCreate, models.AuditTargetSeason, season.ID, map[string]interface{}{
+		"name":     season.Name,
+		"startsAt": season.StartsAt,
+		"endsAt":   season.EndsAt,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(season)
+}
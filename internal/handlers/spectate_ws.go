@@ -0,0 +1,130 @@
+// internal/handlers/spectate_ws.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// spectatorHydrationJitterPerViewer scales the random delay before a snapshot with the audience size.
+	spectatorHydrationJitterPerViewer = 5 * time.Millisecond
+	// spectatorHydrationMaxJitter caps the random delay before a snapshot is sent.
+	spectatorHydrationMaxJitter = 2 * time.Second
+)
+
+// hydrateSpectator staggers delivery of the initial snapshot by a random delay that grows with the
+// number of spectators already watching, so a featured game going live doesn't cause a thundering herd of
+// snapshot sends, then queues the game's shared cached snapshot and registers the connection.
+func hydrateSpectator(ctx context.Context, g *game.CambiaGame, userID uuid.UUID, c *websocket.Conn) error {
+	maxJitter := time.Duration(g.SpectatorCount()) * spectatorHydrationJitterPerViewer
+	if maxJitter > spectatorHydrationMaxJitter {
+		maxJitter = spectatorHydrationMaxJitter
+	}
+	if maxJitter > 0 {
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(maxJitter)))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return g.HydrateSpectator(userID, c)
+}
+
+// SpectateWSHandler sets up the read-only WebSocket at /game/spectate/{game_id}, subprotocol "spectate.v1".
+//
+// On connect, spectators receive a "game_snapshot" of the public state, then every public (non-private_*)
+// game event plus spectator-only annotations such as "spectator_win_probability". Any messages they send
+// are ignored.
+func SpectateWSHandler(logger *logrus.Logger, gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := middleware.RequestLogger(r.Context(), logger)
+		gameID, err := uuid.Parse(r.PathValue("game_id"))
+		if err != nil {
+			apierr.Error(w, "invalid game_id", http.StatusBadRequest)
+			return
+		}
+
+		g, ok := gs.GameStore.GetGame(gameID)
+		if !ok {
+			apierr.Write(w, http.StatusNotFound, apierr.CodeGameNotFound, "game not found")
+			return
+		}
+
+		// set the spectator broadcast callback if not present
+		g.Do(func() {
+			if g.SpectatorFn == nil {
+				g.SpectatorFn = func(ev game.GameEvent) {
+					data, _ := json.Marshal(ev)
+					var deliveries []game.Delivery
+					for id, conn := range g.SpectatorConns() {
+						if ev.Hidden[id] {
+							continue
+						}
+						deliveries = append(deliveries, game.Delivery{Conn: conn, Type: websocket.MessageText, Data: data})
+					}
+					game.WriteAll(deliveries)
+				}
+			}
+		})
+
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols:   protocol.SpectateSocket.Subprotocols(),
+			OriginPatterns: middleware.AllowedOrigins,
+		})
+		if err != nil {
+			logger.Warnf("websocket accept error: %v", err)
+			return
+		}
+		_, reason := protocol.SpectateSocket.Negotiate(r, c.Subprotocol())
+		if reason != "" {
+			c.Close(websocket.StatusPolicyViolation, reason)
+			return
+		}
+
+		userID, err := EnsureEphemeralUser(w, r)
+		if err != nil {
+			logger.Warnf("failed ephemeral user logic: %v", err)
+			c.Close(websocket.StatusPolicyViolation, "cannot create or auth ephemeral user")
+			return
+		}
+		middleware.SetUserID(r.Context(), userID)
+		// the spectator feed runs ahead of what a player is shown, e.g. without a broadcast delay
+		if g.IsLivePlayer(userID) {
+			c.Close(websocket.StatusPolicyViolation, "players can't spectate their own game")
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		if err := hydrateSpectator(ctx, g, userID, c); err != nil {
+			logger.Warnf("failed to hydrate spectator %v for game %v: %v", userID, gameID, err)
+			c.Close(websocket.StatusInternalError, "failed to send game snapshot")
+			return
+		}
+
+		// spectators are read-only; we only read to notice the disconnect
+		defer func() {
+			g.RemoveSpectator(userID)
+			c.Close(websocket.StatusNormalClosure, "closing")
+		}()
+		for {
+			if _, _, err := c.Read(ctx); err != nil {
+				logger.Debugf("spectator %v read err: %v", userID, err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// internal/handlers/stats.go
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+// publicStatsTTL is how long the global stats are cached, here and by clients and CDNs. The stats cover
+// whole days and weeks, so a minute-old answer is as good as a fresh one.
+const publicStatsTTL = time.Minute
+
+// cachedStats is the encoded stats and when they were built.
+type cachedStats struct {
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// statsCache keeps the encoded global stats until they expire. Only one request at a time rebuilds them;
+// if that fails, the last stats built are served until the next attempt.
+type statsCache struct {
+	load func(ctx context.Context) (*database.GlobalStats, error)
+
+	mu      sync.Mutex
+	current *cachedStats
+}
+
+// get returns the stats, rebuilding them if they've expired.
+func (c *statsCache) get(ctx context.Context) (*cachedStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.current != nil && now.Before(c.current.expires) {
+		return c.current, nil
+	}
+
+	stats, err := c.load(ctx)
+	if err != nil {
+		if c.current != nil {
+			log.Printf("serving stale global stats: %v", err)
+			return c.current, nil
+		}
+		return nil, err
+	}
+	body, _ := json.Marshal(stats)
+	sum := sha256.Sum256(body)
+	c.current = &cachedStats{
+		body:    body,
+		etag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		expires: now.Add(publicStatsTTL),
+	}
+	return c.current, nil
+}
+
+// PublicStatsHandler handles GET /stats, the site-wide stats for the landing page: games completed today,
+// games in progress, active players, average game length and the most-used abilities. It needs no
+// authentication. Stats are cached for a minute and carry an ETag; a request whose If-None-Match matches
+// gets 304 Not Modified.
+func PublicStatsHandler() http.HandlerFunc {
+	return publicStatsHandler(database.GetGlobalStats)
+}
+
+func publicStatsHandler(load func(ctx context.Context) (*database.GlobalStats, error)) http.HandlerFunc {
+	cache := &statsCache{load: load}
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := cache.get(r.Context())
+		if err != nil {
+			log.Printf("failed to load global stats: %v", err)
+			apierr.Error(w, "failed to load stats", http.StatusInternalServerError)
+			return
+		}
+		maxAge := int(time.Until(stats.expires).Seconds())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		w.Header().Set("ETag", stats.etag)
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+		if etagMatches(r.Header.Get("If-None-Match"), stats.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(stats.body)
+	}
+}
@@ -0,0 +1,60 @@
+// internal/handlers/stats_test.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+func TestPublicStatsCache(t *testing.T) {
+	loads := 0
+	var fail error
+	handler := publicStatsHandler(func(ctx context.Context) (*database.GlobalStats, error) {
+		loads++
+		if fail != nil {
+			return nil, fail
+		}
+		return &database.GlobalStats{GamesToday: loads, TopAbilities: []database.AbilityUsage{}}, nil
+	})
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := get("")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == "" {
+		t.Fatalf("expected stats with an ETag, got %d: %s", w.Code, w.Body)
+	}
+	if w = get(w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+	if loads != 1 {
+		t.Fatalf("expected the stats to be loaded once while cached, got %d loads", loads)
+	}
+
+	// once expired, a failed rebuild serves the last stats
+	fail = errors.New("database down")
+	handler = publicStatsHandler(func(ctx context.Context) (*database.GlobalStats, error) { return nil, fail })
+	if w = get(""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 with no stats to fall back on, got %d", w.Code)
+	}
+	cache := &statsCache{load: func(ctx context.Context) (*database.GlobalStats, error) {
+		return &database.GlobalStats{}, nil
+	}}
+	first, _ := cache.get(context.Background())
+	first.expires = first.expires.Add(-2 * publicStatsTTL)
+	cache.load = func(ctx context.Context) (*database.GlobalStats, error) { return nil, fail }
+	if stale, err := cache.get(context.Background()); err != nil || stale != first {
+		t.Fatalf("expected the stale stats, got %v, %v", stale, err)
+	}
+}
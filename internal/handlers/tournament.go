@@ -0,0 +1,260 @@
+// internal/handlers/tournament.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/tournament"
+)
+
+type createTournamentRequest struct {
+	Name         string                 `json:"name"`
+	Rounds       int                    `json:"rounds"`
+	RoundMinutes int                    `json:"roundMinutes"`
+	HouseRules   map[string]interface{} `json:"houseRules,omitempty"`
+}
+
+// TournamentHandler routes the /tournament/ endpoints:
+//
+//	POST /tournament/create          create a Swiss tournament, the caller is the organizer
+//	POST /tournament/{id}/join       register for a tournament
+//	POST /tournament/{id}/start      (organizer) close registration and pair round 1
+//	GET  /tournament/{id}            full tournament state, rounds, and standings
+//	GET  /tournament/{id}/standings  standings only, ordered by score then Buchholz
+//
+// Organizer controls, each recorded in the tournament's audit log:
+//
+//	POST /tournament/{id}/report      {"table": 2, "winner": "{uuid}"} force a result; omit winner for a draw
+//	POST /tournament/{id}/disqualify  {"userID": "{uuid}", "reason": "..."} forfeit and drop a player
+//	POST /tournament/{id}/pause       stop the round clock and hold the next round
+//	POST /tournament/{id}/resume      restart the clock, or pair the next round if this one finished
+//	POST /tournament/{id}/extend      {"minutes": 5} add time to the current round
+//	GET  /tournament/{id}/audit       the audit log
+func TournamentHandler(gs *GameServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/tournament/"), "/")
+		if path == "create" && r.Method == http.MethodPost {
+			createTournament(gs, w, r)
+			return
+		}
+
+		parts := strings.Split(path, "/")
+		tournamentID, err := uuid.Parse(parts[0])
+		if err != nil {
+			apierr.Error(w, "invalid tournament id", http.StatusBadRequest)
+			return
+		}
+		t, ok := gs.TournamentStore.Get(tournamentID)
+		if !ok {
+			apierr.Error(w, "tournament not found", http.StatusNotFound)
+			return
+		}
+
+		action := ""
+		if len(parts) > 1 {
+			action = parts[1]
+		}
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(t.Snapshot())
+		case action == "standings" && r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(t.Standings())
+		case action == "join" && r.Method == http.MethodPost:
+			userID, ok := authenticateRequest(w, r)
+			if !ok {
+				return
+			}
+			if err := t.Join(userID); err != nil {
+				apierr.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("joined tournament"))
+		case action == "start" && r.Method == http.MethodPost:
+			userID, ok := authenticateRequest(w, r)
+			if !ok {
+				return
+			}
+			if userID != t.OrganizerID {
+				apierr.Error(w, "only the organizer can start the tournament", http.StatusForbidden)
+				return
+			}
+			if err := t.Start(); err != nil {
+				apierr.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(t.Snapshot())
+		case organizerActions[action]:
+			userID, ok := authenticateRequest(w, r)
+			if !ok {
+				return
+			}
+			if userID != t.OrganizerID {
+				apierr.Error(w, "only the organizer can manage the tournament", http.StatusForbidden)
+				return
+			}
+			organizerAction(t, userID, action, w, r)
+		default:
+			apierr.Error(w, "unsupported tournament route", http.StatusNotFound)
+		}
+	}
+}
+
+// organizerActions are the organizer-only tournament routes handled by organizerAction.
+var organizerActions = map[string]bool{
+	"report":     true,
+	"disqualify": true,
+	"pause":      true,
+	"resume":     true,
+	"extend":     true,
+	"audit":      true,
+}
+
+type organizerActionRequest struct {
+	Table   int    `json:"table"`
+	Winner  string `json:"winner"`
+	UserID  string `json:"userID"`
+	Reason  string `json:"reason"`
+	Minutes int    `json:"minutes"`
+}
+
+// organizerAction handles an organizer control on a tournament. The caller must be the organizer.
+func organizerAction(t *tournament.Tournament, organizerID uuid.UUID, action string, w http.ResponseWriter, r *http.Request) {
+	if action == "audit" {
+		if r.Method != http.MethodGet {
+			apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.Audit())
+		return
+	}
+	if r.Method != http.MethodPost {
+		apierr.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req organizerActionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierr.Error(w, "bad request payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var err error
+	switch action {
+	case "report":
+		winner := uuid.Nil
+		if req.Winner != "" {
+			if winner, err = uuid.Parse(req.Winner); err != nil {
+				apierr.Error(w, "invalid winner", http.StatusBadRequest)
+				return
+			}
+		}
+		err = t.ForceReport(organizerID, req.Table, winner)
+	case "disqualify":
+		userID, perr := uuid.Parse(req.UserID)
+		if perr != nil {
+			apierr.Error(w, "invalid userID", http.StatusBadRequest)
+			return
+		}
+		err = t.Disqualify(organizerID, userID, req.Reason)
+	case "pause":
+		err = t.Pause(organizerID)
+	case "resume":
+		err = t.Resume(organizerID)
+	case "extend":
+		err = t.ExtendRound(organizerID, time.Duration(req.Minutes)*time.Minute)
+	}
+	if err != nil {
+		apierr.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Snapshot())
+}
+
+// createTournament handles POST /tournament/create.
+func createTournament(gs *GameServer, w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req createTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierr.Error(w, "bad tournament request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Rounds < 1 {
+		apierr.Error(w, "rounds must be at least 1", http.StatusBadRequest)
+		return
+	}
+	if req.RoundMinutes < 0 {
+		apierr.Error(w, "roundMinutes must be at least 0; set to 0 to disable the round clock", http.StatusBadRequest)
+		return
+	}
+
+	rules := game.NewLobbyWithDefaults(userID).HouseRules
+	if req.HouseRules != nil {
+		parsed, err := game.ParseRules(req.HouseRules, rules)
+		if err == nil {
+			err = parsed.Validate()
+		}
+		if err != nil {
+			apierr.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rules = parsed
+	}
+
+	t := tournament.NewSwissTournament(userID, req.Name, req.Rounds, time.Duration(req.RoundMinutes)*time.Minute, rules)
+	t.CreateGameFn = gs.NewTournamentGame
+	t.ForceEndFn = gs.ForceEndGame
+	t.OnRoundStart = gs.notifyTournamentRound
+	gs.TournamentStore.Add(t)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t.Snapshot())
+}
+
+// notifyTournamentRound sends every player in a new round a "tournament_round" notification with their
+// table and game. It runs under the tournament's lock; Notify doesn't block.
+func (gs *GameServer) notifyTournamentRound(t *tournament.Tournament, round *tournament.Round) {
+	for _, p := range round.Pairings {
+		for _, seat := range [][2]uuid.UUID{{p.PlayerA, p.PlayerB}, {p.PlayerB, p.PlayerA}} {
+			if seat[0] == uuid.Nil {
+				continue
+			}
+			payload := map[string]interface{}{
+				"tournamentID": t.ID.String(),
+				"name":         t.Name,
+				"round":        round.Number,
+				"table":        p.Table,
+				"bye":          p.PlayerB == uuid.Nil,
+			}
+			if seat[1] != uuid.Nil {
+				payload["opponent"] = seat[1].String()
+			}
+			if p.GameID != uuid.Nil {
+				payload["gameID"] = p.GameID.String()
+			}
+			if !round.Deadline.IsZero() {
+				payload["deadline"] = round.Deadline.Unix()
+			}
+			gs.Notify(seat[0], "tournament_round", payload)
+		}
+	}
+}
@@ -2,69 +2,43 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jason-s-yu/cambia/internal/apierr"
 	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/crash"
 	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/middleware"
 	"github.com/jason-s-yu/cambia/internal/models"
 )
 
+// GuestBanCheck, if set, reports whether a banned account keeps logging in from the hashed device
+// (deviceHash may be ""). New guests from there are refused, so a banned player can't come back as a guest
+// by dropping their token.
+var GuestBanCheck func(ctx context.Context, deviceHash string) (bool, error)
+
 // If user arrives without a token, create ephemeral user
 func EnsureEphemeralUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, error) {
-	cookieHeader := r.Header.Get("Cookie")
-	var token string
-	if strings.Contains(cookieHeader, "auth_token=") { // ensure the user has a token
-		token = extractTokenFromCookie(cookieHeader)
-	} else {
-		// create the temp user
-		ephemeralUser := models.User{
-			Email:       "",
-			Password:    "",
-			Username:    "Guest",
-			IsEphemeral: true,
-		}
-		if err := database.CreateUser(context.Background(), &ephemeralUser); err != nil {
-			return uuid.Nil, fmt.Errorf("failed to create ephemeral user: %w", err)
-		}
-		newToken, err := auth.CreateJWT(ephemeralUser.ID.String())
-		if err != nil {
-			return uuid.Nil, fmt.Errorf("failed to create ephemeral JWT: %w", err)
-		}
-		http.SetCookie(w, &http.Cookie{
-			Name:     "auth_token",
-			Value:    newToken,
-			HttpOnly: true,
-			Path:     "/",
-		})
-		return ephemeralUser.ID, nil
+	token := auth.WSRequestToken(r)
+	if token == "" {
+		return createGuest(w, r)
 	}
 
 	userID, err := auth.AuthenticateJWT(token)
+	if errors.Is(err, auth.ErrBanned) {
+		return uuid.Nil, err
+	}
 	if err != nil {
-		ephemeralUser := models.User{
-			Email:       "",
-			Password:    "",
-			Username:    "Guest",
-			IsEphemeral: true,
-		}
-		if createErr := database.CreateUser(context.Background(), &ephemeralUser); createErr != nil {
-			return uuid.Nil, fmt.Errorf("failed to create ephemeral user: %w", createErr)
-		}
-		newToken, _ := auth.CreateJWT(ephemeralUser.ID.String())
-		http.SetCookie(w, &http.Cookie{
-			Name:     "auth_token",
-			Value:    newToken,
-			HttpOnly: true,
-			Path:     "/",
-		})
-		return ephemeralUser.ID, nil
+		return createGuest(w, r)
 	}
 
 	uuidVal, parseErr := uuid.Parse(userID)
@@ -74,6 +48,102 @@ func EnsureEphemeralUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, err
 	return uuidVal, nil
 }
 
+// createGuest creates an ephemeral user for the request and sets its auth_token cookie. It fails with
+// auth.ErrBanned if GuestBanCheck ties the request's device to a banned account.
+func createGuest(w http.ResponseWriter, r *http.Request) (uuid.UUID, error) {
+	if GuestBanCheck != nil {
+		_, deviceHash := requestFingerprint(r)
+		banned, err := GuestBanCheck(r.Context(), deviceHash)
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		if banned {
+			return uuid.Nil, auth.ErrBanned
+		}
+	}
+
+	ephemeralUser := models.User{
+		Email:       "",
+		Password:    "",
+		Username:    "Guest",
+		IsEphemeral: true,
+	}
+	if err := database.Users.CreateUser(context.Background(), &ephemeralUser); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create ephemeral user: %w", err)
+	}
+	newToken, err := auth.CreateJWT(ephemeralUser.ID.String())
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create ephemeral JWT: %w", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth_token",
+		Value:    newToken,
+		HttpOnly: true,
+		Path:     "/",
+	})
+	return ephemeralUser.ID, nil
+}
+
+// mergeGuestSession checks whether the request still carries a guest's auth_token. If so, the guest's
+// game history, ratings, and friendships are migrated onto targetID so that registering or logging in
+// from a guest session doesn't orphan them.
+func mergeGuestSession(ctx context.Context, r *http.Request, targetID uuid.UUID) {
+	token := auth.RequestToken(r)
+	if token == "" {
+		return
+	}
+	guestIDStr, err := auth.AuthenticateJWT(token)
+	if err != nil {
+		return
+	}
+	guestID, err := uuid.Parse(guestIDStr)
+	if err != nil || guestID == targetID {
+		return
+	}
+	guest, err := database.Users.GetUserByID(ctx, guestID)
+	if err != nil || !guest.IsEphemeral {
+		return
+	}
+	if err := database.MergeEphemeralUser(ctx, guestID, targetID); err != nil {
+		log.Printf("failed to merge guest %v into %v: %v", guestID, targetID, err)
+		return
+	}
+	log.Printf("merged guest %v into user %v", guestID, targetID)
+}
+
+// deviceHeader carries an ID the client generates once and keeps, so logins from one device can be told
+// apart from others behind the same address.
+const deviceHeader = "X-Cambia-Device"
+
+// recordLoginFingerprint hashes the address and device a login came from and stores them in the
+// background, refreshing the user's smurf signals for moderators.
+func recordLoginFingerprint(r *http.Request, userID uuid.UUID) {
+	ipHash, deviceHash := requestFingerprint(r)
+	go func() {
+		defer crash.Recover("recording a login fingerprint")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := database.RecordLogin(ctx, userID, ipHash, deviceHash); err != nil {
+			log.Printf("failed to record login fingerprint for %v: %v", userID, err)
+		}
+	}()
+}
+
+// requestFingerprint hashes the address and device (if the client sent one, else "") a request came from.
+func requestFingerprint(r *http.Request) (ipHash, deviceHash string) {
+	ipHash = fingerprintHash(middleware.ClientIP(r))
+	if device := strings.TrimSpace(r.Header.Get(deviceHeader)); device != "" {
+		deviceHash = fingerprintHash(device)
+	}
+	return ipHash, deviceHash
+}
+
+// fingerprintHash returns the hex SHA-256 digest of s.
+func fingerprintHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
 // Extend ephemeral claim logic to handle optional username changes
 type claimEphemeralRequest struct {
 	Email    string `json:"email"`
@@ -82,31 +152,24 @@ type claimEphemeralRequest struct {
 }
 
 func ClaimEphemeralHandler(w http.ResponseWriter, r *http.Request) {
-	token := extractTokenFromCookie(r.Header.Get("Cookie"))
-	userIDStr, err := auth.AuthenticateJWT(token)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusForbidden)
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		http.Error(w, "invalid user id in token", http.StatusForbidden)
+	userID, ok := authenticateRequest(w, r)
+	if !ok {
 		return
 	}
 
-	u, err := database.GetUserByID(r.Context(), userID)
+	u, err := database.Users.GetUserByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		apierr.Error(w, "user not found", http.StatusNotFound)
 		return
 	}
 	if !u.IsEphemeral {
-		http.Error(w, "user is not ephemeral", http.StatusBadRequest)
+		apierr.Error(w, "user is not ephemeral", http.StatusBadRequest)
 		return
 	}
 
 	var req claimEphemeralRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid claim payload", http.StatusBadRequest)
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid claim payload")
 		return
 	}
 
@@ -117,9 +180,9 @@ func ClaimEphemeralHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	u.IsEphemeral = false
 
-	err = database.UpdateUserCredentials(r.Context(), u)
+	err = database.Users.UpdateUserCredentials(r.Context(), u)
 	if err != nil {
-		http.Error(w, "failed to finalize ephemeral user", http.StatusInternalServerError)
+		apierr.Error(w, "failed to finalize ephemeral user", http.StatusInternalServerError)
 		return
 	}
 
@@ -135,7 +198,7 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		Username string `json:"username"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid payload")
 		return
 	}
 
@@ -148,18 +211,17 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	err := database.CreateUser(ctx, &user)
+	err := database.Users.CreateUser(ctx, &user)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				http.Error(w, "email already exists", http.StatusConflict)
-				return
-			}
+		if errors.Is(err, database.ErrEmailTaken) {
+			apierr.Write(w, http.StatusConflict, apierr.CodeEmailTaken, "email already exists")
+			return
 		}
-		http.Error(w, "error creating user", http.StatusInternalServerError)
+		apierr.Error(w, "error creating user", http.StatusInternalServerError)
 		return
 	}
+	mergeGuestSession(ctx, r, user.ID)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
 }
@@ -194,16 +256,34 @@ type loginResponse struct {
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidPayload, "invalid request payload")
 		return
 	}
 
 	token, err := database.AuthenticateUser(context.Background(), req.Email, req.Password)
 	if err != nil {
 		log.Printf("failed to authenticate user: %v", err)
-		http.Error(w, "authentication failed", http.StatusForbidden)
+		apierr.Write(w, http.StatusForbidden, apierr.CodeAuthFailed, "authentication failed")
 		return
 	}
+	// the fresh token only fails validation if the account is banned
+	userIDStr, err := auth.AuthenticateJWT(token)
+	if err != nil {
+		log.Printf("rejected login for %s: %v", req.Email, err)
+		msg := "account banned"
+		if u, err := database.Users.GetUserByEmail(r.Context(), req.Email); err == nil {
+			if ban, err := database.ActiveBan(r.Context(), u.ID, models.BanScopeGlobal); err == nil && ban != nil {
+				msg = banMessage(ban)
+			}
+		}
+		apierr.Write(w, http.StatusForbidden, apierr.CodeAccountBanned, msg)
+		return
+	}
+	if userID, err := uuid.Parse(userIDStr); err == nil {
+		middleware.SetUserID(r.Context(), userID)
+		mergeGuestSession(r.Context(), r, userID)
+		recordLoginFingerprint(r, userID)
+	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "auth_token",
@@ -216,7 +296,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	resp := loginResponse{Token: token}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		apierr.Error(w, "failed to write response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -0,0 +1,32 @@
+// internal/handlers/user_test.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/database"
+)
+
+func TestGuestRefusedFromBannedFingerprint(t *testing.T) {
+	database.UseMemory()
+	auth.Init()
+	bannedDevice := fingerprintHash("banned-device")
+	GuestBanCheck = func(_ context.Context, deviceHash string) (bool, error) {
+		return deviceHash == bannedDevice, nil
+	}
+	defer func() { GuestBanCheck = nil }()
+
+	req := httptest.NewRequest("GET", "/game/ws/x", nil)
+	req.Header.Set(deviceHeader, "banned-device")
+	rec := httptest.NewRecorder()
+	if _, err := EnsureEphemeralUser(rec, req); !errors.Is(err, auth.ErrBanned) {
+		t.Fatalf("expected a guest from a banned device to be refused, got %v", err)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("expected no guest token to be issued")
+	}
+}
@@ -0,0 +1,252 @@
+// internal/handlers/user_ws.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/crash"
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/models"
+	"github.com/jason-s-yu/cambia/internal/presence"
+	"github.com/jason-s-yu/cambia/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// GameServerForUserWS lets the plain REST handlers (e.g. friends) push notifications to connected users.
+var GameServerForUserWS *GameServer
+
+// UserWSHandler sets up the per-user notification WebSocket at /user/ws, subprotocol "notifications.v1".
+// While it's open the user shows as online to their friends.
+//
+// On connect, the server sends a "presence_snapshot" with the presence of each of the user's friends and an
+// "inbox_summary" with the number of unread notifications, then pushes "presence_update" whenever a friend
+// goes online, joins a lobby or game, or goes offline. Inbox notifications (lobby invites, friend requests,
+// matches found, tournament rounds starting, achievements unlocked) arrive as
+// {"type": "notification", "notification": {...}}; ones sent while the user was offline are listed at
+// GET /user/notifications. A friend removing the user arrives as "friend_removed".
+//
+// Friends can message each other by sending {"type": "dm_send", "to": "{uuid}", "body": "..."}; the sender
+// gets the stored message back as "dm_sent" and the recipient receives it as "direct_message". The
+// "inbox_summary" includes "unreadMessages", the unread count per friend; history is at GET /user/messages/{id}.
+// Clients may also send {"type": "ping"}.
+func UserWSHandler(logger *logrus.Logger, gs *GameServer) http.HandlerFunc {
+	GameServerForUserWS = gs
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := middleware.RequestLogger(r.Context(), logger)
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols:   protocol.NotificationsSocket.Subprotocols(),
+			OriginPatterns: middleware.AllowedOrigins,
+		})
+		if err != nil {
+			logger.Warnf("websocket accept error: %v", err)
+			return
+		}
+		protoVersion, reason := protocol.NotificationsSocket.Negotiate(r, c.Subprotocol())
+		if reason != "" {
+			c.Close(websocket.StatusPolicyViolation, reason)
+			return
+		}
+
+		userIDStr, err := auth.AuthenticateJWT(auth.WSRequestToken(r))
+		if err != nil {
+			c.Close(websocket.StatusPolicyViolation, wsAuthCloseReason(err))
+			return
+		}
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			c.Close(websocket.StatusPolicyViolation, "invalid user ID")
+			return
+		}
+		middleware.SetUserID(r.Context(), userID)
+
+		ctx, cancel := context.WithCancel(r.Context())
+		conn := &game.LobbyConnection{
+			UserID:          userID,
+			Cancel:          cancel,
+			OutChan:         make(chan map[string]interface{}, 32),
+			ProtocolVersion: protoVersion,
+			RequestID:       middleware.RequestID(r.Context()),
+		}
+		gs.userConnsMu.Lock()
+		if prev, ok := gs.userConns[userID]; ok {
+			prev.Cancel() // newest connection wins
+		}
+		gs.userConns[userID] = conn
+		gs.userConnsMu.Unlock()
+
+		limiter := protocol.NewLimiter(userID, protocol.NotificationsLimits)
+		defer limiter.Close()
+		closeStatus, closeReason := websocket.StatusNormalClosure, "closing"
+		defer func() {
+			gs.userConnsMu.Lock()
+			if gs.userConns[userID] == conn {
+				delete(gs.userConns, userID)
+			}
+			gs.userConnsMu.Unlock()
+			gs.Presence.Disconnect(userID)
+			cancel()
+			c.Close(closeStatus, closeReason)
+		}()
+
+		go writePump(ctx, c, conn, logger)
+
+		friends, err := database.ListFriendIDs(ctx, userID)
+		if err != nil {
+			logger.Warnf("failed to list friends of %v: %v", userID, err)
+		}
+		snapshot := make(map[string]presence.Presence, len(friends))
+		for _, id := range friends {
+			snapshot[id.String()] = gs.Presence.Get(id)
+		}
+		conn.Write(map[string]interface{}{
+			"type":    "presence_snapshot",
+			"friends": snapshot,
+		})
+		if unread, err := database.CountUnreadNotifications(ctx, userID); err == nil {
+			summary := map[string]interface{}{
+				"type":   "inbox_summary",
+				"unread": unread,
+			}
+			if counts, err := database.CountUnreadMessages(ctx, userID); err == nil {
+				unreadMessages := make(map[string]int, len(counts))
+				for id, n := range counts {
+					unreadMessages[id.String()] = n
+				}
+				summary["unreadMessages"] = unreadMessages
+			}
+			conn.Write(summary)
+		}
+		gs.Presence.Connect(userID)
+
+		for {
+			typ, data, err := c.Read(ctx)
+			if err != nil {
+				logger.Debugf("notification user %v read err: %v", userID, err)
+				return
+			}
+			if typ != websocket.MessageText {
+				continue
+			}
+			var packet map[string]interface{}
+			jsonErr := json.Unmarshal(data, &packet)
+			action, _ := packet["type"].(string)
+			if limited, disconnect := limiter.Allow(action); limited != nil {
+				if disconnect {
+					logger.Warnf("disconnecting notification user %v: rate limit exceeded", userID)
+					closeStatus, closeReason = websocket.StatusPolicyViolation, "too many messages"
+					return
+				}
+				conn.Write(limited.Frame())
+				continue
+			}
+			if jsonErr != nil {
+				continue
+			}
+			switch packet["type"] {
+			case "ping":
+				conn.Write(map[string]interface{}{"type": "pong"})
+			case "dm_send":
+				gs.handleDirectMessage(ctx, conn, packet)
+			default:
+				conn.WriteError("unknown notification message type")
+			}
+		}
+	}
+}
+
+// notifyUser delivers a message to a user's /user/ws connection, if open. It never blocks.
+func (gs *GameServer) notifyUser(userID uuid.UUID, msg map[string]interface{}) {
+	gs.userConnsMu.Lock()
+	conn, ok := gs.userConns[userID]
+	gs.userConnsMu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case conn.OutChan <- msg:
+	default:
+		game.RecordDroppedFrame()
+		logrus.Warnf("dropping notification %v for %v: outbox full", msg["type"], userID)
+	}
+}
+
+// Notify adds a notification to the user's inbox and pushes it to their /user/ws connection, if open.
+// It never blocks, so it's safe to call while holding locks.
+func (gs *GameServer) Notify(userID uuid.UUID, typ string, payload map[string]interface{}) {
+	go func() {
+		defer crash.Recover("sending a notification")
+		n, err := database.InsertNotification(context.Background(), userID, typ, payload)
+		if err != nil {
+			logrus.Warnf("failed to store %s notification for %v: %v", typ, userID, err)
+			// still deliver it live; it just won't be in the inbox
+			n = &models.Notification{UserID: userID, Type: typ, Payload: payload, CreatedAt: time.Now().UTC()}
+		}
+		gs.notifyUser(userID, map[string]interface{}{
+			"type":         "notification",
+			"notification": n,
+		})
+	}()
+}
+
+// broadcastPresence pushes a user's new presence to their friends. It's the presence tracker's OnChange
+// hook, so the friend lookup runs in the background.
+func (gs *GameServer) broadcastPresence(userID uuid.UUID, p presence.Presence) {
+	go func() {
+		defer crash.Recover("broadcasting presence")
+		friends, err := database.ListFriendIDs(context.Background(), userID)
+		if err != nil {
+			logrus.Warnf("failed to list friends of %v for presence: %v", userID, err)
+			return
+		}
+		msg := presenceMessage(userID, p)
+		for _, id := range friends {
+			gs.notifyUser(id, msg)
+		}
+	}()
+}
+
+// presenceMessage builds the "presence_update" message for a user's presence.
+func presenceMessage(userID uuid.UUID, p presence.Presence) map[string]interface{} {
+	msg := map[string]interface{}{
+		"type":   "presence_update",
+		"userID": userID.String(),
+		"status": p.Status,
+	}
+	if p.LobbyID != nil {
+		msg["lobbyID"] = p.LobbyID.String()
+	}
+	if p.GameID != nil {
+		msg["gameID"] = p.GameID.String()
+	}
+	return msg
+}
+
+// notifyFriendEvent tells the other user about a friend request, acceptance, or removal, and on acceptance
+// shares each user's current presence with the other. Requests and acceptances go to the inbox.
+func notifyFriendEvent(eventType string, from, to uuid.UUID) {
+	gs := GameServerForUserWS
+	if gs == nil {
+		return
+	}
+	if eventType == "friend_removed" {
+		gs.notifyUser(to, map[string]interface{}{
+			"type":   eventType,
+			"userID": from.String(),
+		})
+	} else {
+		gs.Notify(to, eventType, map[string]interface{}{"userID": from.String()})
+	}
+	if eventType == "friend_accepted" {
+		for _, pair := range [][2]uuid.UUID{{from, to}, {to, from}} {
+			gs.notifyUser(pair[1], presenceMessage(pair[0], gs.Presence.Get(pair[0])))
+		}
+	}
+}
@@ -1,16 +1,59 @@
 package handlers
 
-import "strings"
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
 
-// extractCookieToken extracts a named cookie value from "Cookie" header, or returns empty if not found.
-func extractCookieToken(cookieHeader, cookieName string) string {
-	parts := strings.Split(cookieHeader, cookieName+"=")
-	if len(parts) < 2 {
-		return ""
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+	"github.com/jason-s-yu/cambia/internal/middleware"
+	"github.com/jason-s-yu/cambia/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// wsAuthCloseReason is the close reason for a socket whose token failed authentication.
+func wsAuthCloseReason(err error) string {
+	if errors.Is(err, auth.ErrBanned) {
+		return "account banned"
+	}
+	return "invalid auth_token"
+}
+
+// authenticateRequest authenticates the caller of a REST endpoint by bearer token or auth_token cookie.
+// On failure it writes the error response and returns false.
+func authenticateRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	token := auth.RequestToken(r)
+	if token == "" {
+		apierr.Write(w, http.StatusUnauthorized, apierr.CodeMissingToken, "missing auth_token")
+		return uuid.Nil, false
+	}
+	userIDStr, err := auth.AuthenticateJWT(token)
+	if errors.Is(err, auth.ErrBanned) {
+		apierr.Write(w, http.StatusForbidden, apierr.CodeAccountBanned, "account banned")
+		return uuid.Nil, false
 	}
-	token := parts[1]
-	if idx := strings.Index(token, ";"); idx != -1 {
-		token = token[:idx]
+	if err != nil {
+		apierr.Write(w, http.StatusForbidden, apierr.CodeInvalidToken, "invalid token")
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		apierr.Write(w, http.StatusBadRequest, apierr.CodeInvalidToken, "invalid user id in token")
+		return uuid.Nil, false
+	}
+	middleware.SetUserID(r.Context(), userID)
+	return userID, true
+}
+
+// recoverMessage, deferred around the handling of one socket message, recovers a panic so that a bad message
+// can't take down the connection. It logs the stack and replies with an internal_error frame.
+func recoverMessage(logger logrus.FieldLogger, userID uuid.UUID, typ string, reply func(map[string]interface{})) {
+	v := recover()
+	if v == nil {
+		return
 	}
-	return token
+	logger.WithFields(logrus.Fields{"panic": v, "stack": string(debug.Stack())}).Errorf("panic handling %q message from user %v", typ, userID)
+	reply(protocol.Errorf(protocol.CodeInternal, "internal error").Frame())
 }
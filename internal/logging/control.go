@@ -0,0 +1,132 @@
+// Package logging lets admins adjust the server's logrus loggers while it runs: their level, and whether
+// they also write JSON lines to a file.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Control adjusts a set of loggers together.
+type Control struct {
+	mu       sync.Mutex
+	loggers  []*logrus.Logger
+	sinkPath string
+	sink     *fileSink
+}
+
+// NewControl returns a Control for loggers. sinkPath is the file that EnableSink appends to; "" disables
+// the sink. It's fixed at startup so an admin can't point the server at an arbitrary file.
+func NewControl(sinkPath string, loggers ...*logrus.Logger) *Control {
+	return &Control{loggers: loggers, sinkPath: sinkPath}
+}
+
+// Status is the loggers' current configuration.
+type Status struct {
+	Level       string `json:"level"`
+	SinkPath    string `json:"sinkPath,omitempty"`
+	SinkEnabled bool   `json:"sinkEnabled"`
+}
+
+// Status returns the loggers' current configuration.
+func (c *Control) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Status{SinkPath: c.sinkPath, SinkEnabled: c.sink != nil}
+	if len(c.loggers) > 0 {
+		s.Level = c.loggers[0].GetLevel().String()
+	}
+	return s
+}
+
+// SetLevel sets every logger's level, given by name, e.g. "debug" or "warn".
+func (c *Control) SetLevel(name string) error {
+	level, err := logrus.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range c.loggers {
+		l.SetLevel(level)
+	}
+	return nil
+}
+
+// EnableSink starts or stops copying every logger's entries, as JSON lines, to the sink file.
+func (c *Control) EnableSink(on bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if on == (c.sink != nil) {
+		return nil
+	}
+	if !on {
+		for _, l := range c.loggers {
+			removeHook(l, c.sink)
+		}
+		c.sink.close()
+		c.sink = nil
+		return nil
+	}
+	if c.sinkPath == "" {
+		return fmt.Errorf("no log file is configured")
+	}
+	f, err := os.OpenFile(c.sinkPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	c.sink = &fileSink{file: f, formatter: &logrus.JSONFormatter{}}
+	for _, l := range c.loggers {
+		l.AddHook(c.sink)
+	}
+	return nil
+}
+
+// removeHook unregisters h from l, keeping its other hooks.
+func removeHook(l *logrus.Logger, h logrus.Hook) {
+	kept := make(logrus.LevelHooks)
+	for level, hooks := range l.Hooks {
+		for _, hook := range hooks {
+			if hook != h {
+				kept[level] = append(kept[level], hook)
+			}
+		}
+	}
+	l.ReplaceHooks(kept)
+}
+
+// fileSink is a logrus hook that writes entries to a file. Once closed it writes nothing, in case an entry
+// was already being logged as it was removed.
+type fileSink struct {
+	mu        sync.Mutex
+	file      *os.File
+	formatter logrus.Formatter
+}
+
+func (s *fileSink) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (s *fileSink) Fire(e *logrus.Entry) error {
+	line, err := s.formatter.Format(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	_, err = s.file.Write(line)
+	return err
+}
+
+func (s *fileSink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.Close()
+	s.file = nil
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestControl(t *testing.T) {
+	a, b := logrus.New(), logrus.New()
+	path := filepath.Join(t.TempDir(), "server.log")
+	c := NewControl(path, a, b)
+
+	if err := c.SetLevel("warn"); err != nil || b.GetLevel() != logrus.WarnLevel || c.Status().Level != "warning" {
+		t.Fatalf("expected both loggers at warn, got %v %v", b.GetLevel(), err)
+	}
+	if err := c.SetLevel("loud"); err == nil {
+		t.Fatalf("expected an unknown level to be rejected")
+	}
+
+	if err := c.EnableSink(true); err != nil {
+		t.Fatal(err)
+	}
+	b.Warn("to the sink")
+	if err := c.EnableSink(false); err != nil {
+		t.Fatal(err)
+	}
+	b.Warn("not to the sink")
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"to the sink"`) || strings.Contains(string(data), "not to the sink") {
+		t.Fatalf("unexpected sink contents %q", data)
+	}
+	if len(b.Hooks[logrus.WarnLevel]) != 0 {
+		t.Fatalf("expected the sink's hook to be removed")
+	}
+
+	if err := NewControl("").EnableSink(true); err == nil {
+		t.Fatalf("expected the sink to need a configured file")
+	}
+}
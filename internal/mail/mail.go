@@ -0,0 +1,78 @@
+// internal/mail/mail.go
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Sender delivers a plain-text email.
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogSender writes emails to the server log instead of sending them. Useful in development. Bodies can
+// hold secrets such as password reset links, so they're left out unless ShowBody is set.
+type LogSender struct {
+	ShowBody bool
+}
+
+// Send logs the email.
+func (s LogSender) Send(_ context.Context, to, subject, body string) error {
+	if !s.ShowBody {
+		log.Printf("mail to=%s subject=%q (%d byte body withheld; set MAIL_LOG_BODY=true to log it)", to, subject, len(body))
+		return nil
+	}
+	log.Printf("mail to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPSender sends email through an SMTP relay using PLAIN auth.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the email via SMTP.
+func (s *SMTPSender) Send(_ context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	msg := "From: " + s.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	var a smtp.Auth
+	if s.Username != "" {
+		a = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(s.Host+":"+s.Port, a, s.From, []string{to}, []byte(msg))
+}
+
+// FromEnv returns an SMTPSender if SMTP_HOST is set, otherwise a LogSender, which logs bodies only if
+// MAIL_LOG_BODY is "true".
+func FromEnv() Sender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogSender{ShowBody: os.Getenv("MAIL_LOG_BODY") == "true"}
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPSender{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+}
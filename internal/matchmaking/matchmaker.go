@@ -0,0 +1,546 @@
+// internal/matchmaking/matchmaker.go
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/crash"
+)
+
+// ModeSizes is how many players a match in each ranked mode seats.
+var ModeSizes = map[string]int{
+	"1v1":  2,
+	"4p":   4,
+	"7p8p": 8,
+}
+
+const (
+	// DefaultAcceptTimeout is how long players have to accept a found match.
+	DefaultAcceptTimeout = 15 * time.Second
+	// baseRatingWindow is the rating spread allowed in a match for a ticket that just joined.
+	baseRatingWindow = 100
+	// ratingWindowGrowth widens the allowed spread for every ratingWindowStep a ticket has waited.
+	ratingWindowGrowth = 50
+	ratingWindowStep   = 10 * time.Second
+	// basePriorityPenalty is how far back in the queue a player is placed after their first decline;
+	// it doubles with every further decline, up to maxPriorityPenalty.
+	basePriorityPenalty = 30 * time.Second
+	maxPriorityPenalty  = 10 * time.Minute
+	// penaltyDecay is how long a player must go without declining for their decline count to reset.
+	penaltyDecay = time.Hour
+	// partyRatingBonus is added to a party's average rating per member beyond the first, to offset the
+	// advantage a coordinated group has over solo players.
+	partyRatingBonus = 25
+)
+
+// Ticket is a solo player's or a party's place in a mode's queue. A party's members are always placed
+// in the same match.
+type Ticket struct {
+	UserID     uuid.UUID   `json:"userID"` // the solo player, or the party leader
+	PartyID    uuid.UUID   `json:"partyID,omitempty"`
+	Members    []uuid.UUID `json:"members"`
+	Mode       string      `json:"mode"`
+	Rating     int         `json:"rating"` // for parties, the average member rating plus partyRatingBonus
+	EnqueuedAt time.Time   `json:"enqueuedAt"`
+
+	// priorityAt orders the queue; it is EnqueuedAt pushed back by any decline penalty.
+	priorityAt time.Time
+}
+
+// ratingWindow is the rating spread this ticket accepts, widening the longer it has waited.
+func (t *Ticket) ratingWindow(now time.Time) int {
+	return baseRatingWindow + ratingWindowGrowth*int(now.Sub(t.EnqueuedAt)/ratingWindowStep)
+}
+
+// Match is a group the matcher formed that is waiting on every player to accept.
+type Match struct {
+	ID       uuid.UUID          `json:"id"`
+	Mode     string             `json:"mode"`
+	Tickets  []*Ticket          `json:"tickets"`
+	Accepted map[uuid.UUID]bool `json:"accepted"`
+	Deadline time.Time          `json:"deadline"`
+
+	timer *time.Timer
+}
+
+// UserIDs returns every player in the match, party members included.
+func (m *Match) UserIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	for _, t := range m.Tickets {
+		ids = append(ids, t.Members...)
+	}
+	return ids
+}
+
+type penalty struct {
+	declines    int
+	lastDecline time.Time
+}
+
+// Matchmaker holds the ranked queues and the matches pending acceptance.
+type Matchmaker struct {
+	mu sync.Mutex
+
+	queues    map[string][]*Ticket  // ordered by priority, front first
+	queued    map[uuid.UUID]*Ticket // by member
+	pending   map[uuid.UUID]*Match  // by member
+	penalties map[uuid.UUID]*penalty
+
+	parties map[uuid.UUID]*Party // by party ID
+	partyOf map[uuid.UUID]*Party // by member
+
+	// avoid lists, per user, the players they must never share a match with (e.g. blocks).
+	avoid map[uuid.UUID]map[uuid.UUID]bool
+
+	AcceptTimeout time.Duration
+
+	// Notify delivers a message to a queued player's connection.
+	Notify func(userID uuid.UUID, msg map[string]interface{})
+	// OnMatchReady is called once every player accepted; it creates the game and returns its ID.
+	OnMatchReady func(m *Match) (uuid.UUID, error)
+}
+
+// NewMatchmaker creates an empty matchmaker.
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{
+		queues:        make(map[string][]*Ticket),
+		queued:        make(map[uuid.UUID]*Ticket),
+		pending:       make(map[uuid.UUID]*Match),
+		penalties:     make(map[uuid.UUID]*penalty),
+		parties:       make(map[uuid.UUID]*Party),
+		partyOf:       make(map[uuid.UUID]*Party),
+		avoid:         make(map[uuid.UUID]map[uuid.UUID]bool),
+		AcceptTimeout: DefaultAcceptTimeout,
+	}
+}
+
+// SetAvoid replaces the set of players userID must never be matched with. Call it before queueing.
+func (mm *Matchmaker) SetAvoid(userID uuid.UUID, others []uuid.UUID) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if len(others) == 0 {
+		delete(mm.avoid, userID)
+		return
+	}
+	set := make(map[uuid.UUID]bool, len(others))
+	for _, id := range others {
+		set[id] = true
+	}
+	mm.avoid[userID] = set
+}
+
+// conflictsLocked reports whether any member of t must avoid any member of the group, in either direction.
+func (mm *Matchmaker) conflictsLocked(group []*Ticket, t *Ticket) bool {
+	for _, g := range group {
+		for _, a := range g.Members {
+			for _, b := range t.Members {
+				if mm.avoid[a][b] || mm.avoid[b][a] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Join adds a solo player to a mode's queue. Recent declines push the player back in line.
+// Players in a party queue through QueueParty instead.
+func (mm *Matchmaker) Join(userID uuid.UUID, mode string, rating int) (*Ticket, error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if _, ok := mm.partyOf[userID]; ok {
+		return nil, fmt.Errorf("you are in a party; the party leader queues for the party")
+	}
+	return mm.enqueueLocked(&Ticket{
+		UserID:  userID,
+		Members: []uuid.UUID{userID},
+		Mode:    mode,
+		Rating:  rating,
+	})
+}
+
+// Opponents returns the ratings of up to size-1 queued players in mode who a player rated rating would
+// likely be matched with, closest in rating first, and how many players are queued within the rating window
+// a new ticket starts with. Party members count at their ticket's rating; userID's own ticket, or party's,
+// is left out.
+func (mm *Matchmaker) Opponents(mode string, userID uuid.UUID, rating int) (opponents []int, pool int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	var near []int
+	for _, t := range mm.queues[mode] {
+		if slices.Contains(t.Members, userID) || abs(t.Rating-rating) > baseRatingWindow {
+			continue
+		}
+		for range t.Members {
+			near = append(near, t.Rating)
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool {
+		return abs(near[i]-rating) < abs(near[j]-rating)
+	})
+	return near[:min(len(near), ModeSizes[mode]-1)], len(near)
+}
+
+// enqueueLocked validates and queues a new ticket. The ticket's priority is pushed back by the largest
+// decline penalty among its members.
+func (mm *Matchmaker) enqueueLocked(t *Ticket) (*Ticket, error) {
+	size, ok := ModeSizes[t.Mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown matchmaking mode %q", t.Mode)
+	}
+	if len(t.Members) >= size {
+		return nil, fmt.Errorf("a party of %d is too large for %s", len(t.Members), t.Mode)
+	}
+	for _, id := range t.Members {
+		if _, ok := mm.queued[id]; ok {
+			return nil, fmt.Errorf("already queued")
+		}
+		if _, ok := mm.pending[id]; ok {
+			return nil, fmt.Errorf("a match is already waiting for a response")
+		}
+	}
+
+	now := time.Now()
+	t.EnqueuedAt = now
+	var penalty time.Duration
+	for _, id := range t.Members {
+		penalty = max(penalty, mm.priorityPenaltyLocked(id, now))
+	}
+	t.priorityAt = now.Add(penalty)
+	mm.insertLocked(t)
+	return t, nil
+}
+
+// Leave removes a player, and their party if any, from the queue. If a match is waiting on them, leaving
+// counts as declining it.
+func (mm *Matchmaker) Leave(userID uuid.UUID) {
+	mm.mu.Lock()
+	if _, ok := mm.pending[userID]; ok {
+		mm.mu.Unlock()
+		mm.Decline(userID)
+		return
+	}
+	defer mm.mu.Unlock()
+	mm.dequeueLocked(userID, "a party member left the queue")
+}
+
+// dequeueLocked removes the ticket containing userID and tells the rest of its party why.
+func (mm *Matchmaker) dequeueLocked(userID uuid.UUID, reason string) {
+	t := mm.removeLocked(userID)
+	if t == nil {
+		return
+	}
+	for _, id := range t.Members {
+		if id != userID {
+			mm.notify(id, map[string]interface{}{
+				"type":   "queue_left",
+				"reason": reason,
+			})
+		}
+	}
+}
+
+// Accept records a player's acceptance. Once everyone accepted, the game is created and every player is
+// sent "match_ready" with its ID.
+func (mm *Matchmaker) Accept(userID uuid.UUID) error {
+	mm.mu.Lock()
+	m, ok := mm.pending[userID]
+	if !ok {
+		mm.mu.Unlock()
+		return fmt.Errorf("no match is waiting for your response")
+	}
+	m.Accepted[userID] = true
+	players := m.UserIDs()
+	for _, id := range players {
+		mm.notify(id, map[string]interface{}{
+			"type":     "match_accept_update",
+			"match_id": m.ID.String(),
+			"accepted": len(m.Accepted),
+			"total":    len(players),
+		})
+	}
+	if len(m.Accepted) < len(players) {
+		mm.mu.Unlock()
+		return nil
+	}
+
+	m.timer.Stop()
+	for _, id := range players {
+		delete(mm.pending, id)
+	}
+	mm.mu.Unlock()
+
+	if mm.OnMatchReady == nil {
+		return nil
+	}
+	gameID, err := mm.OnMatchReady(m)
+	if err != nil {
+		log.Printf("matchmaking: failed to create game for match %v: %v", m.ID, err)
+		mm.mu.Lock()
+		mm.requeueFrontLocked(m.Tickets)
+		mm.mu.Unlock()
+		for _, id := range m.UserIDs() {
+			mm.notify(id, map[string]interface{}{
+				"type":     "match_cancelled",
+				"match_id": m.ID.String(),
+				"reason":   "failed to create game",
+				"requeued": true,
+			})
+		}
+		return nil
+	}
+	for _, id := range m.UserIDs() {
+		mm.notify(id, map[string]interface{}{
+			"type":     "match_ready",
+			"match_id": m.ID.String(),
+			"game_id":  gameID.String(),
+		})
+	}
+	return nil
+}
+
+// Decline cancels the player's pending match. The other players go back to the front of the queue and
+// the decliner's future queue priority is penalized.
+func (mm *Matchmaker) Decline(userID uuid.UUID) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	m, ok := mm.pending[userID]
+	if !ok {
+		return fmt.Errorf("no match is waiting for your response")
+	}
+	mm.cancelLocked(m, []uuid.UUID{userID}, "a player declined")
+	return nil
+}
+
+// expire cancels a match whose accept window elapsed, treating everyone who hadn't accepted as a decliner.
+func (mm *Matchmaker) expire(m *Match) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.pending[m.Tickets[0].Members[0]] != m {
+		return // already resolved
+	}
+	var missing []uuid.UUID
+	for _, id := range m.UserIDs() {
+		if !m.Accepted[id] {
+			missing = append(missing, id)
+		}
+	}
+	mm.cancelLocked(m, missing, "a player did not accept in time")
+}
+
+// cancelLocked resolves a failed ready-check: decliners are penalized and dropped along with their party,
+// the rest are requeued at the front with their original wait time.
+func (mm *Matchmaker) cancelLocked(m *Match, decliners []uuid.UUID, reason string) {
+	m.timer.Stop()
+	declined := make(map[uuid.UUID]bool, len(decliners))
+	now := time.Now()
+	for _, id := range decliners {
+		declined[id] = true
+		p, ok := mm.penalties[id]
+		if !ok || now.Sub(p.lastDecline) > penaltyDecay {
+			p = &penalty{}
+			mm.penalties[id] = p
+		}
+		p.declines++
+		p.lastDecline = now
+	}
+
+	var requeue []*Ticket
+	dropped := make(map[*Ticket]bool)
+	for _, t := range m.Tickets {
+		for _, id := range t.Members {
+			delete(mm.pending, id)
+			if declined[id] {
+				dropped[t] = true
+			}
+		}
+		if !dropped[t] {
+			requeue = append(requeue, t)
+		}
+	}
+	mm.requeueFrontLocked(requeue)
+
+	for _, t := range m.Tickets {
+		for _, id := range t.Members {
+			mm.notify(id, map[string]interface{}{
+				"type":     "match_cancelled",
+				"match_id": m.ID.String(),
+				"reason":   reason,
+				"requeued": !dropped[t],
+			})
+		}
+	}
+}
+
+// priorityPenaltyLocked is how far back a player's recent declines push them in line.
+func (mm *Matchmaker) priorityPenaltyLocked(userID uuid.UUID, now time.Time) time.Duration {
+	p, ok := mm.penalties[userID]
+	if !ok || p.declines == 0 {
+		return 0
+	}
+	if now.Sub(p.lastDecline) > penaltyDecay {
+		delete(mm.penalties, userID)
+		return 0
+	}
+	d := basePriorityPenalty << (p.declines - 1)
+	if d > maxPriorityPenalty || d <= 0 {
+		d = maxPriorityPenalty
+	}
+	return d
+}
+
+// insertLocked places a ticket in its queue by priority.
+func (mm *Matchmaker) insertLocked(t *Ticket) {
+	q := mm.queues[t.Mode]
+	i := sort.Search(len(q), func(i int) bool { return q[i].priorityAt.After(t.priorityAt) })
+	q = append(q, nil)
+	copy(q[i+1:], q[i:])
+	q[i] = t
+	mm.queues[t.Mode] = q
+	for _, id := range t.Members {
+		mm.queued[id] = t
+	}
+}
+
+// requeueFrontLocked puts tickets back at the very front of their queues, preserving their order.
+func (mm *Matchmaker) requeueFrontLocked(tickets []*Ticket) {
+	for i := len(tickets) - 1; i >= 0; i-- {
+		t := tickets[i]
+		mm.queues[t.Mode] = append([]*Ticket{t}, mm.queues[t.Mode]...)
+		for _, id := range t.Members {
+			mm.queued[id] = t
+			mm.notify(id, map[string]interface{}{
+				"type": "queue_joined",
+				"mode": t.Mode,
+			})
+		}
+	}
+}
+
+// removeLocked takes the ticket containing userID out of the queue; for a party, the whole party leaves.
+// It returns the removed ticket, or nil if the user wasn't queued.
+func (mm *Matchmaker) removeLocked(userID uuid.UUID) *Ticket {
+	t, ok := mm.queued[userID]
+	if !ok {
+		return nil
+	}
+	for _, id := range t.Members {
+		delete(mm.queued, id)
+	}
+	q := mm.queues[t.Mode]
+	for i, qt := range q {
+		if qt == t {
+			mm.queues[t.Mode] = append(q[:i], q[i+1:]...)
+			break
+		}
+	}
+	return t
+}
+
+// Tick runs one matching pass over every queue.
+func (mm *Matchmaker) Tick(now time.Time) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	for mode, size := range ModeSizes {
+		for {
+			group := mm.findGroupLocked(mm.queues[mode], size, now)
+			if group == nil {
+				break
+			}
+			mm.startReadyCheckLocked(mode, group, now)
+		}
+	}
+}
+
+// findGroupLocked picks the highest-priority ticket whose rating window admits enough other tickets to
+// seat exactly size players, and returns it with those tickets in priority order, or nil if no group can
+// be formed. Parties are compared by their adjusted average rating.
+func (mm *Matchmaker) findGroupLocked(q []*Ticket, size int, now time.Time) []*Ticket {
+	for _, anchor := range q {
+		window := anchor.ratingWindow(now)
+		group := []*Ticket{anchor}
+		seated := len(anchor.Members)
+		for _, t := range q {
+			if t == anchor || seated+len(t.Members) > size {
+				continue
+			}
+			if abs(t.Rating-anchor.Rating) <= min(window, t.ratingWindow(now)) && !mm.conflictsLocked(group, t) {
+				group = append(group, t)
+				seated += len(t.Members)
+				if seated == size {
+					return group
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (mm *Matchmaker) startReadyCheckLocked(mode string, group []*Ticket, now time.Time) {
+	id, _ := uuid.NewV7()
+	m := &Match{
+		ID:       id,
+		Mode:     mode,
+		Tickets:  group,
+		Accepted: make(map[uuid.UUID]bool),
+		Deadline: now.Add(mm.AcceptTimeout),
+	}
+	for _, t := range group {
+		mm.removeLocked(t.UserID)
+		for _, id := range t.Members {
+			mm.pending[id] = m
+		}
+	}
+	m.timer = time.AfterFunc(mm.AcceptTimeout, func() {
+		defer crash.Recover("match accept timeout")
+		mm.expire(m)
+	})
+
+	var players []string
+	for _, id := range m.UserIDs() {
+		players = append(players, id.String())
+	}
+	for _, id := range m.UserIDs() {
+		mm.notify(id, map[string]interface{}{
+			"type":      "match_found",
+			"match_id":  m.ID.String(),
+			"mode":      mode,
+			"players":   players,
+			"accept_by": m.Deadline.Unix(),
+		})
+	}
+}
+
+// Run matches players once a second until ctx is cancelled.
+func (mm *Matchmaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			mm.Tick(now)
+		}
+	}
+}
+
+func (mm *Matchmaker) notify(userID uuid.UUID, msg map[string]interface{}) {
+	if mm.Notify != nil {
+		mm.Notify(userID, msg)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,161 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReadyCheckDeclineRequeuesOthersAndPenalizesDecliner(t *testing.T) {
+	mm := NewMatchmaker()
+	mm.AcceptTimeout = time.Minute
+	a, b := uuid.New(), uuid.New()
+	if _, err := mm.Join(a, "1v1", 1500); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mm.Join(b, "1v1", 1520); err != nil {
+		t.Fatal(err)
+	}
+
+	mm.Tick(time.Now())
+	if mm.pending[a] == nil || mm.pending[a] != mm.pending[b] {
+		t.Fatalf("expected a and b in the same pending match")
+	}
+
+	if err := mm.Decline(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mm.queued[a]; !ok {
+		t.Fatalf("expected a to be requeued")
+	}
+	if _, ok := mm.queued[b]; ok {
+		t.Fatalf("expected decliner b to be dropped from the queue")
+	}
+	if q := mm.queues["1v1"]; len(q) != 1 || q[0].UserID != a {
+		t.Fatalf("expected a at the front of the queue, got %v", q)
+	}
+
+	c := uuid.New()
+	mm.Join(c, "1v1", 1500)
+	tb, _ := mm.Join(b, "1v1", 1500)
+	if !tb.priorityAt.After(tb.EnqueuedAt) {
+		t.Fatalf("expected decliner's priority to be pushed back")
+	}
+	if q := mm.queues["1v1"]; q[len(q)-1].UserID != b {
+		t.Fatalf("expected penalized b at the back of the queue")
+	}
+}
+
+func TestReadyCheckAllAcceptCreatesGame(t *testing.T) {
+	mm := NewMatchmaker()
+	gameID := uuid.New()
+	var ready []uuid.UUID
+	mm.OnMatchReady = func(m *Match) (uuid.UUID, error) { return gameID, nil }
+	mm.Notify = func(userID uuid.UUID, msg map[string]interface{}) {
+		if msg["type"] == "match_ready" {
+			ready = append(ready, userID)
+		}
+	}
+	a, b := uuid.New(), uuid.New()
+	mm.Join(a, "1v1", 1500)
+	mm.Join(b, "1v1", 1500)
+	mm.Tick(time.Now())
+
+	mm.Accept(a)
+	if len(ready) != 0 {
+		t.Fatalf("game created before everyone accepted")
+	}
+	mm.Accept(b)
+	if len(ready) != 2 {
+		t.Fatalf("expected both players notified of the game, got %d", len(ready))
+	}
+	if len(mm.pending) != 0 || len(mm.queued) != 0 {
+		t.Fatalf("expected no pending or queued players after the match started")
+	}
+}
+
+func TestPartyPlacedInSameMatch(t *testing.T) {
+	mm := NewMatchmaker()
+	leader, friend := uuid.New(), uuid.New()
+	p, err := mm.CreateParty(leader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm.InviteToParty(leader, friend)
+	if err := mm.JoinParty(friend, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mm.Join(friend, "4p", 1500); err == nil {
+		t.Fatalf("party member should not be able to queue solo")
+	}
+	tk, err := mm.QueueParty(leader, "4p", map[uuid.UUID]int{leader: 1480, friend: 1520})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Rating != 1500+partyRatingBonus {
+		t.Fatalf("expected party rating %d, got %d", 1500+partyRatingBonus, tk.Rating)
+	}
+
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	mm.Join(a, "4p", 1510)
+	mm.Join(b, "4p", 1530)
+	mm.Join(c, "4p", 1540)
+	mm.Tick(time.Now())
+
+	m := mm.pending[leader]
+	if m == nil || mm.pending[friend] != m {
+		t.Fatalf("expected party members in the same pending match")
+	}
+	if n := len(m.UserIDs()); n != 4 {
+		t.Fatalf("expected 4 players in the match, got %d", n)
+	}
+	if _, ok := mm.queued[c]; !ok {
+		t.Fatalf("expected one solo player left in the queue")
+	}
+}
+
+func TestBlockedPlayersNeverMatched(t *testing.T) {
+	mm := NewMatchmaker()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	mm.SetAvoid(b, []uuid.UUID{a}) // b blocked a
+	mm.Join(a, "1v1", 1500)
+	mm.Join(b, "1v1", 1500)
+	mm.Tick(time.Now())
+	if mm.pending[a] != nil {
+		t.Fatalf("expected a and b not to be matched")
+	}
+
+	mm.Join(c, "1v1", 1500)
+	mm.Tick(time.Now())
+	if m := mm.pending[a]; m == nil || mm.pending[c] != m {
+		t.Fatalf("expected a to be matched with c instead")
+	}
+	if _, ok := mm.queued[b]; !ok {
+		t.Fatalf("expected b to still be queued")
+	}
+}
+
+func TestOpponents(t *testing.T) {
+	mm := NewMatchmaker()
+	me := uuid.New()
+	for _, r := range []int{1560, 1490, 1700, 1530} {
+		if _, err := mm.Join(uuid.New(), "4p", r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mm.Join(me, "4p", 1500); err != nil {
+		t.Fatal(err)
+	}
+
+	opponents, pool := mm.Opponents("4p", me, 1500)
+	if pool != 3 {
+		t.Fatalf("expected 3 queued players within the rating window, got %d", pool)
+	}
+	if len(opponents) != 3 || opponents[0] != 1490 || opponents[1] != 1530 || opponents[2] != 1560 {
+		t.Fatalf("expected the closest ratings first, leaving out the caller and 1700, got %v", opponents)
+	}
+	if opponents, _ := mm.Opponents("1v1", me, 1500); len(opponents) != 0 {
+		t.Fatalf("expected no opponents in an empty queue, got %v", opponents)
+	}
+}
@@ -0,0 +1,194 @@
+// internal/matchmaking/party.go
+package matchmaking
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// MaxPartySize is the largest party that can queue together.
+const MaxPartySize = 4
+
+// Party is a group of friends that queues together and is always placed in the same match.
+type Party struct {
+	ID       uuid.UUID   `json:"id"`
+	LeaderID uuid.UUID   `json:"leaderID"`
+	Members  []uuid.UUID `json:"members"`
+
+	invited map[uuid.UUID]bool
+}
+
+func (p *Party) view() map[string]interface{} {
+	members := make([]string, 0, len(p.Members))
+	for _, id := range p.Members {
+		members = append(members, id.String())
+	}
+	return map[string]interface{}{
+		"type":      "party_update",
+		"party_id":  p.ID.String(),
+		"leader_id": p.LeaderID.String(),
+		"members":   members,
+	}
+}
+
+// CreateParty starts a party led by userID.
+func (mm *Matchmaker) CreateParty(userID uuid.UUID) (*Party, error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if _, ok := mm.partyOf[userID]; ok {
+		return nil, fmt.Errorf("already in a party")
+	}
+	if _, ok := mm.queued[userID]; ok {
+		return nil, fmt.Errorf("leave the queue before creating a party")
+	}
+	id, _ := uuid.NewV7()
+	p := &Party{ID: id, LeaderID: userID, Members: []uuid.UUID{userID}, invited: make(map[uuid.UUID]bool)}
+	mm.parties[id] = p
+	mm.partyOf[userID] = p
+	mm.notify(userID, p.view())
+	return p, nil
+}
+
+// InviteToParty lets the party leader invite someone; callers are responsible for checking that the
+// invitee is the leader's friend. The invitee is sent "party_invite".
+func (mm *Matchmaker) InviteToParty(leaderID, inviteeID uuid.UUID) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	p, ok := mm.partyOf[leaderID]
+	if !ok || p.LeaderID != leaderID {
+		return fmt.Errorf("only a party leader can invite")
+	}
+	if len(p.Members) >= MaxPartySize {
+		return fmt.Errorf("party is full")
+	}
+	p.invited[inviteeID] = true
+	mm.notify(inviteeID, map[string]interface{}{
+		"type":      "party_invite",
+		"party_id":  p.ID.String(),
+		"leader_id": leaderID.String(),
+	})
+	return nil
+}
+
+// JoinParty adds an invited user to a party. If the party is queued, it leaves the queue first, since its
+// size and rating change.
+func (mm *Matchmaker) JoinParty(userID, partyID uuid.UUID) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	p, ok := mm.parties[partyID]
+	if !ok {
+		return fmt.Errorf("party not found")
+	}
+	if !p.invited[userID] {
+		return fmt.Errorf("not invited to this party")
+	}
+	if _, ok := mm.partyOf[userID]; ok {
+		return fmt.Errorf("already in a party")
+	}
+	if _, ok := mm.queued[userID]; ok {
+		return fmt.Errorf("leave the queue before joining a party")
+	}
+	if _, ok := mm.pending[p.LeaderID]; ok {
+		return fmt.Errorf("the party is in a ready-check")
+	}
+	if len(p.Members) >= MaxPartySize {
+		return fmt.Errorf("party is full")
+	}
+
+	mm.dequeueLocked(p.LeaderID, "the party changed")
+	delete(p.invited, userID)
+	p.Members = append(p.Members, userID)
+	mm.partyOf[userID] = p
+	for _, id := range p.Members {
+		mm.notify(id, p.view())
+	}
+	return nil
+}
+
+// LeaveParty removes a user from their party, taking the party out of the queue. If the leader leaves,
+// the party is disbanded.
+func (mm *Matchmaker) LeaveParty(userID uuid.UUID) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	p, ok := mm.partyOf[userID]
+	if !ok {
+		return
+	}
+	mm.dequeueLocked(userID, "the party changed")
+
+	if p.LeaderID == userID {
+		for _, id := range p.Members {
+			delete(mm.partyOf, id)
+			mm.notify(id, map[string]interface{}{
+				"type":     "party_disbanded",
+				"party_id": p.ID.String(),
+			})
+		}
+		delete(mm.parties, p.ID)
+		return
+	}
+
+	delete(mm.partyOf, userID)
+	p.Members = slices.DeleteFunc(p.Members, func(id uuid.UUID) bool { return id == userID })
+	mm.notify(userID, map[string]interface{}{
+		"type":     "party_left",
+		"party_id": p.ID.String(),
+	})
+	for _, id := range p.Members {
+		mm.notify(id, p.view())
+	}
+}
+
+// QueueParty queues the leader's whole party for a mode. ratings holds each member's rating for the mode;
+// the party is matched on its average plus partyRatingBonus per extra member.
+func (mm *Matchmaker) QueueParty(leaderID uuid.UUID, mode string, ratings map[uuid.UUID]int) (*Ticket, error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	p, ok := mm.partyOf[leaderID]
+	if !ok || p.LeaderID != leaderID {
+		return nil, fmt.Errorf("only the party leader can queue the party")
+	}
+
+	total := 0
+	for _, id := range p.Members {
+		r, ok := ratings[id]
+		if !ok {
+			return nil, fmt.Errorf("missing rating for party member %v", id)
+		}
+		total += r
+	}
+	rating := total/len(p.Members) + partyRatingBonus*(len(p.Members)-1)
+
+	t, err := mm.enqueueLocked(&Ticket{
+		UserID:  leaderID,
+		PartyID: p.ID,
+		Members: slices.Clone(p.Members),
+		Mode:    mode,
+		Rating:  rating,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range p.Members {
+		if id != leaderID {
+			mm.notify(id, map[string]interface{}{
+				"type":        "queue_joined",
+				"mode":        mode,
+				"enqueued_at": t.EnqueuedAt.Unix(),
+			})
+		}
+	}
+	return t, nil
+}
+
+// PartyMembers returns the members of the user's party, or nil if they aren't in one.
+func (mm *Matchmaker) PartyMembers(userID uuid.UUID) []uuid.UUID {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if p, ok := mm.partyOf[userID]; ok {
+		return slices.Clone(p.Members)
+	}
+	return nil
+}
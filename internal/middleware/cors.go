@@ -0,0 +1,89 @@
+// internal/middleware/cors.go
+
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// AllowedOrigins lists the host patterns of the browser origins allowed to call the API from another site,
+// read from CORS_ALLOWED_ORIGINS as a comma-separated list such as "cambia.gg,*.cambia.gg,localhost:*".
+// Patterns match an origin's host and port with path.Match, the same way coder/websocket checks
+// OriginPatterns, so the one list governs both: CORS applies it to REST requests and WebSocket handlers
+// pass it as their OriginPatterns. Same-origin requests are always allowed. "*" allows every origin.
+var AllowedOrigins = parseOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"))
+
+func parseOrigins(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Request and response headers cross-origin callers may use.
+const (
+	corsAllowMethods  = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, If-None-Match, X-Request-ID, X-Cambia-Capabilities, X-Cambia-Encoding, X-Cambia-Device"
+	corsExposeHeaders = "X-Request-ID, ETag, Retry-After, Deprecation, Link"
+	corsMaxAge        = "600"
+)
+
+// OriginAllowed reports whether origin, the value of an Origin header, matches one of patterns.
+func OriginAllowed(origin string, patterns []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS is an HTTP middleware that lets browsers on the origins in patterns call the API with credentials.
+// It answers preflight requests itself, so it must wrap the whole mux rather than individual routes, whose
+// method patterns don't match OPTIONS. Requests from other origins get no CORS headers, and the browser
+// blocks them.
+func CORS(patterns []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+			allowed := OriginAllowed(origin, patterns)
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				if allowed {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+					w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+					w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+					w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	called := false
+	h := CORS(parseOrigins("cambia.gg, *.cambia.gg,localhost:*"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	call := func(method, origin string) *httptest.ResponseRecorder {
+		called = false
+		req := httptest.NewRequest(method, "/v1/lobby/create", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(http.MethodOptions, "https://app.cambia.gg")
+	if rec.Code != http.StatusNoContent || called || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.cambia.gg" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("expected the preflight to be answered, got %d %v", rec.Code, rec.Header())
+	}
+	rec = call(http.MethodPost, "http://localhost:3000")
+	if !called || rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Fatalf("expected an allowed request through with CORS headers, got %v", rec.Header())
+	}
+	rec = call(http.MethodPost, "https://evil.example")
+	if !called || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected no CORS headers for another origin, got %v", rec.Header())
+	}
+	rec = call(http.MethodOptions, "https://evil.example")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected the preflight to be refused, got %v", rec.Header())
+	}
+}
@@ -0,0 +1,17 @@
+// internal/middleware/deprecation.go
+
+package middleware
+
+import "net/http"
+
+// Deprecated marks responses as coming from a deprecated route, pointing clients at the same path under
+// successorPrefix (e.g. "/v1") via the Deprecation and Link headers.
+func Deprecated(successorPrefix string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successorPrefix+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,51 +3,171 @@
 package middleware
 
 import (
+	"bufio"
+	"context"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
-// LogMiddleware is an HTTP middleware that logs incoming requests using Logrus.
-// Logs the method, path, and duration of each request.
+// RequestIDHeader carries a request's ID. A client or proxy may supply one; otherwise LogMiddleware assigns
+// it. Either way it's echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds the length of a client-supplied request ID.
+const maxRequestIDLen = 128
+
+type (
+	requestIDKey struct{}
+	accessKey    struct{}
+)
+
+// accessEntry holds what handlers learn about a request that belongs in its access log line.
+type accessEntry struct {
+	mu     sync.Mutex
+	userID uuid.UUID
+}
+
+// RequestID returns the ID LogMiddleware gave the request, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestLogger returns logger with the request's ID attached to every line, for handlers that log about
+// the request, such as WebSocket handlers logging over a connection's lifetime.
+func RequestLogger(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	return logger.WithField("request_id", RequestID(ctx))
+}
+
+// SetUserID records the authenticated caller of a request for its access log line. It does nothing for
+// requests that didn't pass through LogMiddleware.
+func SetUserID(ctx context.Context, userID uuid.UUID) {
+	if e, ok := ctx.Value(accessKey{}).(*accessEntry); ok {
+		e.mu.Lock()
+		e.userID = userID
+		e.mu.Unlock()
+	}
+}
+
+// LogMiddleware logs every request as structured fields: method, path, status, latency, request ID,
+// remote address, and the user ID if a handler recorded one with SetUserID. A WebSocket upgrade is logged
+// twice, once when the socket is accepted and once when it closes, with the connection's lifetime as its
+// latency.
 func LogMiddleware(logger *logrus.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			path := r.URL.Path
-			method := r.Method
-
-			next.ServeHTTP(w, r)
-
-			duration := time.Since(start)
-			logger.WithFields(logrus.Fields{
-				"method":   method,
-				"path":     path,
-				"duration": duration,
-				"remote":   r.RemoteAddr,
-			}).Info("HTTP Request")
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			entry := &accessEntry{}
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = context.WithValue(ctx, accessKey{}, entry)
+
+			// r.URL has had the API version prefix stripped, and the query may carry a token
+			path, _, _ := strings.Cut(r.RequestURI, "?")
+			fields := func(status int) logrus.Fields {
+				f := logrus.Fields{
+					"method":     r.Method,
+					"path":       path,
+					"status":     status,
+					"latency":    time.Since(start),
+					"request_id": id,
+					"remote":     r.RemoteAddr,
+				}
+				entry.mu.Lock()
+				if entry.userID != uuid.Nil {
+					f["user_id"] = entry.userID
+				}
+				entry.mu.Unlock()
+				return f
+			}
+
+			rec := &statusRecorder{ResponseWriter: w}
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				rec.onHijack = func() {
+					logger.WithFields(fields(http.StatusSwitchingProtocols)).Info("WebSocket connected")
+				}
+			}
+
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			if rec.hijacked {
+				logger.WithFields(fields(http.StatusSwitchingProtocols)).Info("WebSocket disconnected")
+				return
+			}
+			logger.WithFields(fields(rec.statusCode())).Info("HTTP Request")
 		})
 	}
 }
 
-// LogWebSocketConnect logs a message when a WebSocket client connects.
-// Typically called in your WebSocket handler once you accept an upgrade.
-func LogWebSocketConnect(logger *logrus.Logger, remoteAddr string, path string) {
-	logger.WithFields(logrus.Fields{
-		"remote": remoteAddr,
-		"path":   path,
-	}).Info("WebSocket connected")
+// validRequestID reports whether a client-supplied request ID is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder remembers the status a handler wrote, and whether it took over the connection.
+type statusRecorder struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+	onHijack func()
 }
 
-// LogWebSocketDisconnect logs a message when a WebSocket client disconnects.
-func LogWebSocketDisconnect(logger *logrus.Logger, remoteAddr string, path string, err error) {
-	fields := logrus.Fields{
-		"remote": remoteAddr,
-		"path":   path,
+func (rec *statusRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
 	}
-	if err != nil {
-		fields["error"] = err
+	return rec.status
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
 	}
-	logger.WithFields(fields).Info("WebSocket disconnected")
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Hijack lets WebSocket upgrades through.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rec.ResponseWriter).Hijack()
+	if err == nil {
+		rec.hijacked = true
+		if rec.onHijack != nil {
+			rec.onHijack()
+		}
+	}
+	return conn, brw, err
+}
+
+func (rec *statusRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
 }
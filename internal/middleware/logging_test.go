@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogMiddleware(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	userID := uuid.New()
+	var gotID string
+	h := LogMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = RequestID(r.Context())
+		SetUserID(r.Context(), userID)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest("GET", "/v1/lobby/public?token=secret", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if gotID != "abc-123" || rec.Header().Get(RequestIDHeader) != "abc-123" {
+		t.Fatalf("expected the request ID to be echoed, got %q", rec.Header().Get(RequestIDHeader))
+	}
+	e := hook.LastEntry()
+	if e == nil || e.Data["status"] != http.StatusTeapot || e.Data["user_id"] != userID ||
+		e.Data["path"] != "/v1/lobby/public" || e.Data["request_id"] != "abc-123" {
+		t.Fatalf("unexpected log entry %+v", e)
+	}
+
+	// an unusable ID is replaced
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if id := rec.Header().Get(RequestIDHeader); id == "" || strings.Contains(id, "\n") {
+		t.Fatalf("expected a generated request ID, got %q", id)
+	}
+}
+
+func TestLogMiddlewareWebSocket(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	h := LogMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		c.Read(r.Context())
+		c.Close(websocket.StatusNormalClosure, "")
+	}))
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer srv.Close()
+
+	c, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close(websocket.StatusNormalClosure, "")
+	<-done
+
+	var msgs []string
+	for _, e := range hook.AllEntries() {
+		if e.Level == logrus.InfoLevel {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	if len(msgs) != 2 || msgs[0] != "WebSocket connected" || msgs[1] != "WebSocket disconnected" {
+		t.Fatalf("expected a connect and a disconnect line, got %v", msgs)
+	}
+}
@@ -0,0 +1,169 @@
+// internal/middleware/ratelimit.go
+
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+)
+
+// RateRule sizes a token bucket: Burst requests back to back, refilling completely over Per.
+type RateRule struct {
+	Burst int
+	Per   time.Duration
+}
+
+// ParseRateRule parses a rule written as "{burst}/{duration}", e.g. "10/1m".
+func ParseRateRule(s string) (RateRule, error) {
+	burst, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateRule{}, fmt.Errorf("invalid rate limit %q, want {burst}/{duration}", s)
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 1 {
+		return RateRule{}, fmt.Errorf("invalid burst in rate limit %q", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return RateRule{}, fmt.Errorf("invalid duration in rate limit %q", s)
+	}
+	return RateRule{Burst: n, Per: d}, nil
+}
+
+// RateRuleFromEnv returns the rule in the environment variable name, or def if it isn't set. An invalid
+// value is fatal.
+func RateRuleFromEnv(name string, def RateRule) RateRule {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	rule, err := ParseRateRule(s)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return rule
+}
+
+// TrustProxy makes RateLimit take the client's IP from the last X-Forwarded-For entry, which the reverse
+// proxy in front of the server appends. Only set it behind such a proxy; otherwise clients can pick their
+// own IP. It's read from TRUST_PROXY=true.
+var TrustProxy = os.Getenv("TRUST_PROXY") == "true"
+
+// RateLimit is an HTTP middleware that limits how often one client may call the endpoints it wraps. Every
+// request draws from a token bucket for its IP address sized by byIP, and requests carrying a valid token
+// also draw from one for the user sized by byUser, so signing in doesn't lift the IP limit and switching
+// addresses doesn't lift the user's. A zero rule is not enforced. A request that finds a bucket empty gets
+// 429 with Retry-After set to the seconds until it would be let through.
+//
+// Each call makes its own buckets, so endpoints wrapped separately are limited separately.
+func RateLimit(byIP, byUser RateRule) func(next http.Handler) http.Handler {
+	ips := newBuckets(byIP)
+	users := newBuckets(byUser)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			wait := ips.take(ClientIP(r), now)
+			if userID := tokenUserID(r); userID != "" {
+				wait = max(wait, users.take(userID, now))
+			}
+			if wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				apierr.Write(w, http.StatusTooManyRequests, apierr.CodeRateLimited, "too many requests; slow down")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address a request came from, without its port.
+func ClientIP(r *http.Request) string {
+	if TrustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			parts := strings.Split(xff, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tokenUserID returns the user ID in the request's token, or "" if it has no valid one. It doesn't reject
+// anything; that's left to the handler.
+func tokenUserID(r *http.Request) string {
+	token := auth.RequestToken(r)
+	if token == "" {
+		return ""
+	}
+	claims, err := auth.AuthenticateJWTClaims(token)
+	if err != nil {
+		return ""
+	}
+	return claims.UserID
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// buckets holds one token bucket per key. Buckets that have refilled completely are forgotten, since a
+// fresh one behaves the same.
+type buckets struct {
+	rule      RateRule
+	mu        sync.Mutex
+	byKey     map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets(rule RateRule) *buckets {
+	return &buckets{rule: rule, byKey: make(map[string]*bucket)}
+}
+
+// take spends a token from key's bucket. It returns 0 if there was one, or else how long until there
+// will be.
+func (bs *buckets) take(key string, now time.Time) time.Duration {
+	if bs.rule.Burst <= 0 {
+		return 0
+	}
+	rate := float64(bs.rule.Burst) / bs.rule.Per.Seconds() // tokens per second
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if now.Sub(bs.lastSweep) >= bs.rule.Per {
+		for k, b := range bs.byKey {
+			if now.Sub(b.last) >= bs.rule.Per {
+				delete(bs.byKey, k)
+			}
+		}
+		bs.lastSweep = now
+	}
+
+	b, ok := bs.byKey[key]
+	if !ok {
+		b = &bucket{tokens: float64(bs.rule.Burst)}
+		bs.byKey[key] = b
+	} else {
+		b.tokens = min(float64(bs.rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
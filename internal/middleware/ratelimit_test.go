@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(RateRule{Burst: 2, Per: time.Minute}, RateRule{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	call := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/lobby/create", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := call("10.0.0.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("expected request %d within the burst to pass, got %d", i, rec.Code)
+		}
+	}
+	rec := call("10.0.0.1:5678")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := call("10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("expected another IP to have its own bucket, got %d", rec.Code)
+	}
+}
+
+func TestBucketsRefill(t *testing.T) {
+	bs := newBuckets(RateRule{Burst: 1, Per: time.Second})
+	now := time.Now()
+	if bs.take("a", now) != 0 || bs.take("a", now) == 0 {
+		t.Fatalf("expected a burst of one")
+	}
+	if wait := bs.take("a", now.Add(time.Second)); wait != 0 {
+		t.Fatalf("expected the bucket to refill, still waiting %v", wait)
+	}
+	bs.take("b", now.Add(3*time.Second))
+	if _, ok := bs.byKey["a"]; ok {
+		t.Fatalf("expected the idle bucket to be forgotten")
+	}
+}
+
+func TestParseRateRule(t *testing.T) {
+	if r, err := ParseRateRule("10/1m"); err != nil || r.Burst != 10 || r.Per != time.Minute {
+		t.Fatalf("unexpected rule %+v, %v", r, err)
+	}
+	for _, s := range []string{"10", "0/1m", "5/soon", "5/-1s"} {
+		if _, err := ParseRateRule(s); err == nil {
+			t.Fatalf("expected %q to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// internal/middleware/recover.go
+
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/sirupsen/logrus"
+)
+
+// Recover is an HTTP middleware that turns a panic in a handler into a logged stack trace and, if nothing
+// was written yet, a 500 response, instead of a dropped connection. Deferred cleanup in the handler, such
+// as closing a WebSocket and leaving its lobby, still runs as the panic unwinds. Register it after
+// LogMiddleware so the request's access log line shows the 500.
+func Recover(logger *logrus.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				logger.WithFields(logrus.Fields{
+					"request_id": RequestID(r.Context()),
+					"panic":      v,
+					"stack":      string(debug.Stack()),
+				}).Error("panic serving request")
+				if rec.status == 0 && !rec.hijacked {
+					apierr.Error(rec, "internal server error", http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRecover(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	h := LogMiddleware(logger)(Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	entries := hook.AllEntries()
+	if len(entries) != 2 || entries[0].Data["panic"] != "boom" || entries[1].Data["status"] != http.StatusInternalServerError {
+		t.Fatalf("expected the panic and a 500 access log line, got %v", entries)
+	}
+}
@@ -0,0 +1,51 @@
+// internal/middleware/roles.go
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/apierr"
+	"github.com/jason-s-yu/cambia/internal/auth"
+)
+
+type claimsContextKey struct{}
+
+// RequireRole is an HTTP middleware that only lets through callers with at least the given role. The role
+// is the one on the caller's account once auth.SessionValidator has checked the token against it, not
+// just the one the token was issued with. The caller's claims are stored on the request context; see
+// ClaimsFromContext.
+//
+// Responds 401 if no token is present and 403 if the token is invalid or the role is insufficient.
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := auth.RequestToken(r)
+			if token == "" {
+				apierr.Write(w, http.StatusUnauthorized, apierr.CodeMissingToken, "missing auth_token")
+				return
+			}
+			claims, err := auth.AuthenticateJWTClaims(token)
+			if err != nil {
+				apierr.Write(w, http.StatusForbidden, apierr.CodeInvalidToken, "invalid token")
+				return
+			}
+			if !auth.HasRole(claims.Role, role) {
+				apierr.Write(w, http.StatusForbidden, apierr.CodeInsufficientRole, "insufficient role")
+				return
+			}
+			if userID, err := uuid.Parse(claims.UserID); err == nil {
+				SetUserID(r.Context(), userID)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey{}, claims)))
+		})
+	}
+}
+
+// ClaimsFromContext returns the claims stored by RequireRole, if any.
+func ClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*auth.Claims)
+	return claims, ok
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/auth"
+)
+
+func TestRequireRoleUsesCurrentRole(t *testing.T) {
+	auth.Init()
+	token, err := auth.CreateJWTWithRole(uuid.NewString(), auth.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := RequireRole(auth.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func() int {
+		req := httptest.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("expected the admin token to be let through, got %d", code)
+	}
+
+	// the account has since been demoted
+	auth.SessionValidator = func(_ context.Context, claims *auth.Claims) error {
+		claims.Role = auth.RoleUser
+		return nil
+	}
+	defer func() { auth.SessionValidator = nil }()
+	if code := request(); code != http.StatusForbidden {
+		t.Fatalf("expected a demoted user's old token to be refused, got %d", code)
+	}
+}
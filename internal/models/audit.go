@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited actions.
+const (
+	AuditLobbyDelete    = "lobby.delete"
+	AuditLobbyKick      = "lobby.kick"
+	AuditGameEnd        = "game.end"
+	AuditUserDisconnect = "user.disconnect"
+	AuditUserRole       = "user.role"
+	AuditUserMute       = "user.mute"
+	AuditUserUnmute     = "user.unmute"
+	AuditUserBan        = "user.ban"
+	AuditUserUnban      = "user.unban"
+	AuditBanLift        = "ban.lift"
+	AuditBanAppeal      = "ban.appeal"
+	AuditReportResolve  = "report.resolve"
+	AuditSeasonCreate   = "season.create"
+	AuditFeatureSet     = "feature.set"
+	AuditLoggingSet     = "logging.set"
+	AuditAnnouncement   = "server.announce"
+)
+
+// Kinds of audit targets.
+const (
+	AuditTargetUser    = "user"
+	AuditTargetLobby   = "lobby"
+	AuditTargetGame    = "game"
+	AuditTargetBan     = "ban"
+	AuditTargetReport  = "report"
+	AuditTargetSeason  = "season"
+	AuditTargetFeature = "feature"
+	AuditTargetLogging = "logging"
+	AuditTargetServer  = "server"
+)
+
+// AuditEntry records a privileged or destructive action: who took it, what it was taken against, and its
+// details.
+type AuditEntry struct {
+	ID         uuid.UUID              `json:"id"`
+	ActorID    uuid.UUID              `json:"actorID"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"targetType"`
+	TargetID   *uuid.UUID             `json:"targetID,omitempty"`
+	Payload    map[string]interface{} `json:"payload"`
+	RequestID  string                 `json:"requestID,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DirectMessage is a private message between two friends, pushed live over /user/ws.
+type DirectMessage struct {
+	ID          uuid.UUID  `json:"id"`
+	SenderID    uuid.UUID  `json:"senderID"`
+	RecipientID uuid.UUID  `json:"recipientID"`
+	Body        string     `json:"body"`
+	ReadAt      *time.Time `json:"readAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
@@ -1,7 +1,13 @@
 package models
 
+import "github.com/google/uuid"
+
 // GameAction captures a player's in-game move
 type GameAction struct {
 	ActionType string                 `json:"action_type"`
 	Payload    map[string]interface{} `json:"payload"`
+
+	// ActorID is the player the action is attributed to in the game's persisted action log.
+	// It's set by the server, never read from clients.
+	ActorID uuid.UUID `json:"-"`
 }
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report statuses.
+const (
+	ReportOpen      = "open"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+// Sanction kinds. Muted users can't chat or send direct messages; banned users can't sign in.
+const (
+	SanctionMute = "mute"
+	SanctionBan  = "ban"
+)
+
+// ReportedChatMessage is a lobby chat message captured in a report.
+type ReportedChatMessage struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"userID"`
+	Msg    string    `json:"msg"`
+	TS     int64     `json:"ts"`
+}
+
+// PlayerReport is a report of one player by another, queued for moderator review.
+type PlayerReport struct {
+	ID           uuid.UUID             `json:"id"`
+	ReporterID   uuid.UUID             `json:"reporterID"`
+	OffenderID   uuid.UUID             `json:"offenderID"`
+	Reason       string                `json:"reason"`
+	LobbyID      *uuid.UUID            `json:"lobbyID,omitempty"`
+	GameID       *uuid.UUID            `json:"gameID,omitempty"`
+	ChatSnapshot []ReportedChatMessage `json:"chatSnapshot"`
+	Status       string                `json:"status"`
+	ResolvedBy   *uuid.UUID            `json:"resolvedBy,omitempty"`
+	Resolution   *string               `json:"resolution,omitempty"`
+	ResolvedAt   *time.Time            `json:"resolvedAt,omitempty"`
+	CreatedAt    time.Time             `json:"createdAt"`
+}
+
+// Ban scopes. A global ban keeps the user from signing in or opening any socket; a matchmaking ban only
+// keeps them out of the ranked queue.
+const (
+	BanScopeGlobal      = "global"
+	BanScopeMatchmaking = "matchmaking"
+)
+
+// ValidBanScope reports whether scope is a known ban scope.
+func ValidBanScope(scope string) bool {
+	return scope == BanScopeGlobal || scope == BanScopeMatchmaking
+}
+
+// Sanction is a mute or ban issued by a moderator or admin. A nil ExpiresAt is permanent.
+type Sanction struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"userID"`
+	Kind        string     `json:"kind"`
+	Scope       string     `json:"scope,omitempty"` // bans only
+	Reason      string     `json:"reason"`
+	IssuedBy    uuid.UUID  `json:"issuedBy"`
+	ReportID    *uuid.UUID `json:"reportID,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	AppealNotes string     `json:"appealNotes"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy   *uuid.UUID `json:"revokedBy,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// AnticheatFlag is play the game server flagged as suggesting a player knew cards they never saw.
+type AnticheatFlag struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    uuid.UUID              `json:"userID"`
+	GameID    uuid.UUID              `json:"gameID"`
+	Kind      string                 `json:"kind"`
+	Turn      int                    `json:"turn"`
+	Detail    map[string]interface{} `json:"detail"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+// Smurf signal reasons.
+const (
+	SmurfBannedAccount = "banned_account"
+	SmurfHigherRated   = "higher_rated"
+)
+
+// SmurfSignal links an account to a banned or much higher rated one it keeps logging in alongside, from the
+// same IP address or device. It's a lead for moderators, not proof of anything.
+type SmurfSignal struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"userID"`
+	LinkedUserID uuid.UUID `json:"linkedUserID"`
+	Reason       string    `json:"reason"`
+	SharedLogins int       `json:"sharedLogins"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification is an entry in a user's inbox, also pushed live over /user/ws.
+type Notification struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    uuid.UUID              `json:"userID"`
+	Type      string                 `json:"type"` // e.g. "lobby_invite", "friend_request", "match_found", "tournament_round"
+	Payload   map[string]interface{} `json:"payload"`
+	ReadAt    *time.Time             `json:"readAt,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
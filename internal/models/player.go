@@ -10,10 +10,24 @@ type Player struct {
 	Hand            []*Card         `json:"hand"`
 	Connected       bool            `json:"connected"`
 	Conn            *websocket.Conn `json:"-"`
+	ProtocolVersion int             `json:"-"` // game socket protocol version negotiated with the client
+	Encoding        string          `json:"-"` // "json" or "protobuf"; see protocol.Encoding
 	HasCalledCambia bool            `json:"hasCalledCambia"`
+	Bot             bool            `json:"bot,omitempty"` // a computer player; see game.NewBot
+	// Controller is the player whose connection plays this seat, for a local seat in a hot-seat game; see
+	// game.NewLocalSeat.
+	Controller uuid.UUID `json:"-"`
 
 	User *User `json:"-"`
 
 	// DrawnCard holds the most recently drawn card (not yet discarded or swapped).
 	DrawnCard *Card `json:"-"`
+	// DrawnFromDiscard is set when DrawnCard was taken from the discard pile. Such a card must replace a
+	// card in the player's hand; it can't be discarded straight back.
+	DrawnFromDiscard bool `json:"-"`
+
+	// LegalActionHints is set when the client asked to be told the actions open to it each turn.
+	LegalActionHints bool `json:"-"`
+	// BatchEvents is set when the client accepts several game events in one frame, as a JSON array.
+	BatchEvents bool `json:"-"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Season is a ranked season. Ratings, leaderboards, and placement status reset when a season ends.
+type Season struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	StartsAt    time.Time  `json:"startsAt"`
+	EndsAt      time.Time  `json:"endsAt"`
+	FinalizedAt *time.Time `json:"finalizedAt,omitempty"`
+}
+
+// SeasonRating is a user's rating in one mode for one season.
+type SeasonRating struct {
+	SeasonID    uuid.UUID `json:"seasonID"`
+	UserID      uuid.UUID `json:"userID"`
+	Mode        string    `json:"mode"`
+	Rating      int       `json:"rating"`
+	GamesPlayed int       `json:"gamesPlayed"`
+	InPlacement bool      `json:"inPlacement"`
+	FinalRank   *int      `json:"finalRank,omitempty"`
+	RewardTier  *string   `json:"rewardTier,omitempty"`
+}
@@ -11,6 +11,9 @@ type User struct {
 	IsEphemeral bool `json:"is_ephemeral"`
 	IsAdmin     bool `json:"is_admin"`
 
+	// Role is one of "user", "moderator", or "admin"; see auth.HasRole.
+	Role string `json:"role"`
+
 	Elo1v1  int `json:"elo_1v1"`
 	Elo4p   int `json:"elo_4p"`
 	Elo7p8p int `json:"elo_7p8p"`
@@ -0,0 +1,55 @@
+// internal/penalty/penalty.go
+package penalty
+
+import "time"
+
+// Window is how far back abandons count towards escalation.
+const Window = 30 * 24 * time.Hour
+
+// RatingForfeit is how many rating points an abandon costs in the game's mode.
+const RatingForfeit = 15
+
+// Penalty is what a player receives for an abandon, given how many they've had within Window.
+type Penalty struct {
+	QueueCooldown time.Duration // time before the player may queue again
+	RankedBan     time.Duration // time before the player may play ranked at all
+	RatingForfeit int
+}
+
+// escalation is indexed by the number of abandons within Window, minus one; the last entry repeats.
+var escalation = []Penalty{
+	{QueueCooldown: 5 * time.Minute},
+	{QueueCooldown: 30 * time.Minute},
+	{QueueCooldown: 2 * time.Hour},
+	{RankedBan: 24 * time.Hour},
+	{RankedBan: 3 * 24 * time.Hour},
+	{RankedBan: 7 * 24 * time.Hour},
+}
+
+// For returns the penalty for a player's nth abandon within Window (n >= 1). Every abandon also forfeits
+// RatingForfeit rating points.
+func For(n int) Penalty {
+	if n < 1 {
+		return Penalty{}
+	}
+	p := escalation[min(n, len(escalation))-1]
+	p.RatingForfeit = RatingForfeit
+	return p
+}
+
+// Status is a player's current penalty state.
+type Status struct {
+	RecentAbandons     int        `json:"recentAbandons"` // within Window
+	QueueCooldownUntil *time.Time `json:"queueCooldownUntil,omitempty"`
+	RankedBanUntil     *time.Time `json:"rankedBanUntil,omitempty"`
+}
+
+// CanQueue reports whether the player may enter ranked matchmaking at now.
+func (s *Status) CanQueue(now time.Time) bool {
+	return s.CanPlayRanked(now) && (s.QueueCooldownUntil == nil || !now.Before(*s.QueueCooldownUntil))
+}
+
+// CanPlayRanked reports whether the player may take part in ranked games at now.
+func (s *Status) CanPlayRanked(now time.Time) bool {
+	return s.RankedBanUntil == nil || !now.Before(*s.RankedBanUntil)
+}
@@ -0,0 +1,206 @@
+// internal/presence/presence.go
+package presence
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Status is what a user is currently doing, as shown to their friends.
+type Status string
+
+const (
+	Offline Status = "offline"
+	Online  Status = "online"
+	InLobby Status = "in_lobby"
+	InGame  Status = "in_game"
+)
+
+// Presence is a user's status, plus the lobby or game they're in, if any.
+type Presence struct {
+	Status  Status     `json:"status"`
+	LobbyID *uuid.UUID `json:"lobbyID,omitempty"`
+	GameID  *uuid.UUID `json:"gameID,omitempty"`
+}
+
+type state struct {
+	conns   int // open notification connections
+	lobbyID uuid.UUID
+	gameID  uuid.UUID
+}
+
+// presence derives the public presence: being in a game beats being in a lobby, which beats being online.
+func (s *state) presence() Presence {
+	switch {
+	case s.gameID != uuid.Nil:
+		id := s.gameID
+		return Presence{Status: InGame, GameID: &id}
+	case s.lobbyID != uuid.Nil:
+		id := s.lobbyID
+		return Presence{Status: InLobby, LobbyID: &id}
+	case s.conns > 0:
+		return Presence{Status: Online}
+	default:
+		return Presence{Status: Offline}
+	}
+}
+
+// rank orders statuses by precedence.
+func (p Presence) rank() int {
+	switch p.Status {
+	case InGame:
+		return 3
+	case InLobby:
+		return 2
+	case Online:
+		return 1
+	}
+	return 0
+}
+
+// merge combines a user's presence on several server nodes into the one that takes precedence.
+func merge(p Presence, others []Presence) Presence {
+	for _, o := range others {
+		if o.rank() > p.rank() {
+			p = o
+		}
+	}
+	return p
+}
+
+// Shared shares presence between server nodes, so a user whose connections are spread over several
+// nodes still has a single presence.
+type Shared interface {
+	// Store records this node's view of the user's presence.
+	Store(userID uuid.UUID, p Presence)
+	// Others returns the user's presence on the other nodes.
+	Others(userID uuid.UUID) []Presence
+	// Announce tells the other nodes that the user's overall presence changed; they pass it to
+	// Tracker.NotifyRemote.
+	Announce(userID uuid.UUID, p Presence)
+}
+
+// Tracker keeps the presence of every user connected to this node in memory.
+type Tracker struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]*state
+
+	// OnChange is called, without the tracker's lock held, whenever a user's presence changes.
+	OnChange func(userID uuid.UUID, p Presence)
+
+	// Shared, if set, merges in the user's presence on other nodes. Set it before the tracker is used.
+	Shared Shared
+}
+
+// NewTracker creates an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{users: make(map[uuid.UUID]*state)}
+}
+
+// update applies fn to the user's state and reports the change, if any.
+func (t *Tracker) update(userID uuid.UUID, fn func(s *state)) {
+	t.mu.Lock()
+	s, ok := t.users[userID]
+	if !ok {
+		s = &state{}
+		t.users[userID] = s
+	}
+	before := s.presence()
+	fn(s)
+	after := s.presence()
+	if after.Status == Offline {
+		delete(t.users, userID)
+	}
+	if t.Shared != nil && !samePresence(before, after) {
+		// stored under the lock so this node's updates reach the other nodes in order
+		t.Shared.Store(userID, after)
+		others := t.Shared.Others(userID)
+		before, after = merge(before, others), merge(after, others)
+	}
+	onChange := t.OnChange
+	t.mu.Unlock()
+
+	if samePresence(before, after) {
+		return
+	}
+	if t.Shared != nil {
+		t.Shared.Announce(userID, after)
+	}
+	if onChange != nil {
+		onChange(userID, after)
+	}
+}
+
+// NotifyRemote reports a presence change announced by another node.
+func (t *Tracker) NotifyRemote(userID uuid.UUID, p Presence) {
+	t.mu.Lock()
+	onChange := t.OnChange
+	t.mu.Unlock()
+	if onChange != nil {
+		onChange(userID, p)
+	}
+}
+
+func samePresence(a, b Presence) bool {
+	eq := func(x, y *uuid.UUID) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+	return a.Status == b.Status && eq(a.LobbyID, b.LobbyID) && eq(a.GameID, b.GameID)
+}
+
+// Connect records a new notification connection for the user.
+func (t *Tracker) Connect(userID uuid.UUID) {
+	t.update(userID, func(s *state) { s.conns++ })
+}
+
+// Disconnect records a closed notification connection for the user.
+func (t *Tracker) Disconnect(userID uuid.UUID) {
+	t.update(userID, func(s *state) {
+		if s.conns > 0 {
+			s.conns--
+		}
+	})
+}
+
+// EnterLobby marks the user as in a lobby.
+func (t *Tracker) EnterLobby(userID, lobbyID uuid.UUID) {
+	t.update(userID, func(s *state) { s.lobbyID = lobbyID })
+}
+
+// LeaveLobby clears the user's lobby, if it's still lobbyID.
+func (t *Tracker) LeaveLobby(userID, lobbyID uuid.UUID) {
+	t.update(userID, func(s *state) {
+		if s.lobbyID == lobbyID {
+			s.lobbyID = uuid.Nil
+		}
+	})
+}
+
+// EnterGame marks the user as in a game.
+func (t *Tracker) EnterGame(userID, gameID uuid.UUID) {
+	t.update(userID, func(s *state) { s.gameID = gameID })
+}
+
+// LeaveGame clears the user's game, if it's still gameID.
+func (t *Tracker) LeaveGame(userID, gameID uuid.UUID) {
+	t.update(userID, func(s *state) {
+		if s.gameID == gameID {
+			s.gameID = uuid.Nil
+		}
+	})
+}
+
+// Get returns the user's current presence.
+func (t *Tracker) Get(userID uuid.UUID) Presence {
+	t.mu.Lock()
+	p := Presence{Status: Offline}
+	if s, ok := t.users[userID]; ok {
+		p = s.presence()
+	}
+	t.mu.Unlock()
+	if t.Shared != nil {
+		p = merge(p, t.Shared.Others(userID))
+	}
+	return p
+}
@@ -0,0 +1,80 @@
+package presence
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPresencePrecedenceAndChanges(t *testing.T) {
+	tr := NewTracker()
+	var changes []Status
+	tr.OnChange = func(_ uuid.UUID, p Presence) { changes = append(changes, p.Status) }
+
+	user, lobby, g := uuid.New(), uuid.New(), uuid.New()
+	tr.Connect(user)
+	tr.EnterLobby(user, lobby)
+	tr.EnterGame(user, g)
+	if got := tr.Get(user); got.Status != InGame || *got.GameID != g {
+		t.Fatalf("expected in_game, got %+v", got)
+	}
+
+	// leaving a stale lobby doesn't change anything
+	tr.LeaveLobby(user, uuid.New())
+	tr.LeaveGame(user, g)
+	if got := tr.Get(user).Status; got != InLobby {
+		t.Fatalf("expected back in the lobby after the game, got %v", got)
+	}
+	tr.LeaveLobby(user, lobby)
+	tr.Disconnect(user)
+	if got := tr.Get(user).Status; got != Offline {
+		t.Fatalf("expected offline, got %v", got)
+	}
+
+	want := []Status{Online, InLobby, InGame, InLobby, Online, Offline}
+	if len(changes) != len(want) {
+		t.Fatalf("expected changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("expected changes %v, got %v", want, changes)
+		}
+	}
+}
+
+// fakeShared is the presence of a user on one other node.
+type fakeShared struct {
+	other     Presence
+	announced []Status
+}
+
+func (f *fakeShared) Store(uuid.UUID, Presence)        {}
+func (f *fakeShared) Others(uuid.UUID) []Presence      { return []Presence{f.other} }
+func (f *fakeShared) Announce(_ uuid.UUID, p Presence) { f.announced = append(f.announced, p.Status) }
+
+func TestPresenceMergesOtherNodes(t *testing.T) {
+	tr := NewTracker()
+	shared := &fakeShared{other: Presence{Status: InLobby}}
+	tr.Shared = shared
+	user := uuid.New()
+
+	// connecting here doesn't change anything while the user is in a lobby on another node
+	tr.Connect(user)
+	if got := tr.Get(user).Status; got != InLobby {
+		t.Fatalf("expected the other node's in_lobby, got %v", got)
+	}
+	g := uuid.New()
+	tr.EnterGame(user, g)
+	shared.other = Presence{Status: Offline}
+	tr.LeaveGame(user, g)
+	tr.Disconnect(user)
+	want := []Status{InGame, Online, Offline}
+	if len(shared.announced) != len(want) {
+		t.Fatalf("expected %v to be announced, got %v", want, shared.announced)
+	}
+	for i := range want {
+		if shared.announced[i] != want[i] {
+			t.Fatalf("expected %v to be announced, got %v", want, shared.announced)
+		}
+	}
+}
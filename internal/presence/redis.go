@@ -0,0 +1,113 @@
+// internal/presence/redis.go
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/redis"
+)
+
+// RedisShared shares presence between server nodes through Redis. Each node keeps its view of a user in
+// the hash presence:user:{id}, keyed by node ID, and lists the users it has entries for in the set
+// presence:node:{node} so it can clear them after a restart. Changes are announced on the "presence"
+// channel.
+type RedisShared struct {
+	conn     *redis.Conn
+	addr     string
+	password string
+	node     string
+}
+
+const presenceChannel = "presence"
+
+type announcement struct {
+	Node     string    `json:"node"`
+	UserID   uuid.UUID `json:"userID"`
+	Presence Presence  `json:"presence"`
+}
+
+// NewRedisShared connects to Redis as the given node and clears whatever a previous run of the node left
+// behind.
+func NewRedisShared(ctx context.Context, addr, password, node string) (*RedisShared, error) {
+	conn, err := redis.Dial(ctx, addr, password)
+	if err != nil {
+		return nil, err
+	}
+	s := &RedisShared{conn: conn, addr: addr, password: password, node: node}
+	users, err := redis.Strings(conn.Do("SMEMBERS", s.nodeKey()))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for _, id := range users {
+		conn.Do("HDEL", "presence:user:"+id, node)
+	}
+	conn.Do("DEL", s.nodeKey())
+	return s, nil
+}
+
+func (s *RedisShared) nodeKey() string {
+	return "presence:node:" + s.node
+}
+
+func (s *RedisShared) Store(userID uuid.UUID, p Presence) {
+	key := "presence:user:" + userID.String()
+	if p.Status == Offline {
+		if _, err := s.conn.Do("HDEL", key, s.node); err != nil {
+			log.Printf("failed to clear presence of %v: %v", userID, err)
+		}
+		s.conn.Do("SREM", s.nodeKey(), userID.String())
+		return
+	}
+	data, _ := json.Marshal(p)
+	if _, err := s.conn.Do("HSET", key, s.node, string(data)); err != nil {
+		log.Printf("failed to store presence of %v: %v", userID, err)
+		return
+	}
+	s.conn.Do("SADD", s.nodeKey(), userID.String())
+}
+
+func (s *RedisShared) Others(userID uuid.UUID) []Presence {
+	fields, err := redis.Strings(s.conn.Do("HGETALL", "presence:user:"+userID.String()))
+	if err != nil {
+		log.Printf("failed to load presence of %v: %v", userID, err)
+		return nil
+	}
+	var out []Presence
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == s.node {
+			continue
+		}
+		var p Presence
+		if json.Unmarshal([]byte(fields[i+1]), &p) == nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (s *RedisShared) Announce(userID uuid.UUID, p Presence) {
+	data, _ := json.Marshal(announcement{Node: s.node, UserID: userID, Presence: p})
+	if _, err := s.conn.Do("PUBLISH", presenceChannel, string(data)); err != nil {
+		log.Printf("failed to announce presence of %v: %v", userID, err)
+	}
+}
+
+// Listen passes the other nodes' announcements to t until ctx is done or the subscription fails.
+func (s *RedisShared) Listen(ctx context.Context, t *Tracker) error {
+	msgs, err := redis.Subscribe(ctx, s.addr, s.password, presenceChannel)
+	if err != nil {
+		return err
+	}
+	for msg := range msgs {
+		var a announcement
+		if json.Unmarshal(msg.Payload, &a) != nil || a.Node == s.node {
+			continue
+		}
+		t.NotifyRemote(a.UserID, a.Presence)
+	}
+	return ctx.Err()
+}
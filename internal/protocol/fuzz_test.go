@@ -0,0 +1,118 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+var errorCodes = []Code{
+	CodeInvalidJSON, CodeInvalidProto, CodeUnknownType, CodeMissingField, CodeInvalidField, CodeForbidden,
+	CodeInvalidState, CodeMuted, CodeRateLimited, CodeInternal,
+}
+
+// checkDecoded checks what a decoder made of arbitrary input: either a message of a registered type that
+// passes its own validation, or an error the client can be sent.
+func checkDecoded(t *testing.T, registry map[string]func() Message, env Envelope, msg Message, perr *Error) {
+	t.Helper()
+	if (msg == nil) == (perr == nil) {
+		t.Fatalf("expected a message or an error, got %+v and %v", msg, perr)
+	}
+	if len(env.ReqID) > maxReqIDLen {
+		t.Fatalf("expected a req_id of at most %d characters, got %d", maxReqIDLen, len(env.ReqID))
+	}
+	if perr != nil {
+		if !slices.Contains(errorCodes, perr.Code) {
+			t.Fatalf("unexpected error code %q", perr.Code)
+		}
+		if _, err := json.Marshal(env.Reply(perr.Frame())); err != nil {
+			t.Fatalf("error frame can't be sent: %v", err)
+		}
+		return
+	}
+	if _, ok := registry[env.Type]; !ok {
+		t.Fatalf("decoded a message of unregistered type %q", env.Type)
+	}
+	if err := msg.Validate(); err != nil {
+		t.Fatalf("decoded a message that fails validation: %v", err)
+	}
+}
+
+// checkJSONRoundTrip checks that a decoded message, encoded again, decodes to the same message.
+func checkJSONRoundTrip(t *testing.T, decodeFn func([]byte) (Envelope, Message, *Error), env Envelope, msg Message) {
+	t.Helper()
+	fields := map[string]interface{}{}
+	encoded, _ := json.Marshal(msg)
+	json.Unmarshal(encoded, &fields)
+	fields["type"] = env.Type
+	data, _ := json.Marshal(fields)
+	env2, msg2, perr := decodeFn(data)
+	if perr != nil || env2.Type != env.Type {
+		t.Fatalf("expected %s to decode again, got %+v %v", data, env2, perr)
+	}
+	if again, _ := json.Marshal(msg2); !bytes.Equal(again, encoded) {
+		t.Fatalf("expected %s after a round trip, got %s", encoded, again)
+	}
+}
+
+func FuzzDecodeGame(f *testing.F) {
+	for _, seed := range []string{
+		`{"type": "action_draw_stockpile", "req_id": "r1"}`,
+		`{"type": "action_snap", "card": {"id": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e"}, "action_id": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4f"}`,
+		`{"type": "action_replace", "card": {"idx": 2}}`,
+		`{"type": "action_special", "special": "swap_blind", "card1": {"id": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e", "user": {"id": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4f"}}, "card2": {"idx": 0}}`,
+		`{"type": "report", "payload": {"userID": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e", "reason": "rude"}}`,
+		`{"type": "chat", "msg": "gg"}`,
+		`{"type": "emote", "emote": "wow"}`,
+		`{"type": "pong", "ts": 1760523600000}`,
+		`{"type": "request_handoff", "req_id": "h1"}`,
+		`{"type": "resync_from", "seq": 41}`,
+		`{"type": 5}`,
+		`[1, 2]`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		env, msg, perr := DecodeGame(data)
+		checkDecoded(t, gameMessages, env, msg, perr)
+		if msg != nil {
+			checkJSONRoundTrip(t, DecodeGame, env, msg)
+		}
+	})
+}
+
+func FuzzDecodeLobby(f *testing.F) {
+	for _, seed := range []string{
+		`{"type": "ready", "req_id": "r1"}`,
+		`{"type": "invite", "userID": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e"}`,
+		`{"type": "chat", "msg": "hello"}`,
+		`{"type": "chat_reaction_add", "msg_id": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e", "emoji": "👍"}`,
+		`{"type": "update_rules", "rules": {"turnTimerSec": 30, "jokers": 2}, "settings": {"autoStart": true}}`,
+		`{"type": "rtc_signal", "to": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e", "kind": "offer", "data": {"sdp": "v=0"}}`,
+		`{"type": "set_team", "team": 2}`,
+		`{"type": "resync_from", "seq": 0}`,
+		`{"type": "report", "userID": "0196a3a8-3c6e-7c1e-8d5e-6f0a1b2c3d4e", "reason": "spam"}`,
+		`{"req_id": 7}`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		env, msg, perr := DecodeLobby(data)
+		checkDecoded(t, lobbyMessages, env, msg, perr)
+		if msg != nil {
+			checkJSONRoundTrip(t, DecodeLobby, env, msg)
+		}
+	})
+}
+
+func FuzzDecodeGameProto(f *testing.F) {
+	f.Add(appendStringField(nil, 1, "action_draw_stockpile"))
+	f.Add(appendMessageField(appendStringField(nil, 1, "action_replace"), 2, appendTag(nil, 2, wireVarint)))
+	f.Add(appendStringField(appendStringField(nil, 1, "chat"), 11, "gg"))
+	f.Add([]byte{0x0a, 0x10})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		env, msg, perr := DecodeGameProto(data)
+		checkDecoded(t, gameMessages, env, msg, perr)
+	})
+}
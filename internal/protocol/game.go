@@ -0,0 +1,213 @@
+// internal/protocol/game.go
+package protocol
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// UserRef identifies a player, e.g. the owner of a card.
+type UserRef struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// CardRef identifies a card, by ID or by index in the sender's hand, and optionally its owner.
+type CardRef struct {
+	ID   uuid.UUID `json:"id"`
+	Idx  *int      `json:"idx,omitempty"`
+	User *UserRef  `json:"user,omitempty"`
+}
+
+// Owner returns the card's owner, or uuid.Nil if none was given.
+func (c *CardRef) Owner() uuid.UUID {
+	if c == nil || c.User == nil {
+		return uuid.Nil
+	}
+	return c.User.ID
+}
+
+// GameAction is a single-step turn action, e.g. {"type": "action_snap", "card": {"id": "{uuid}"}}.
+// action_snap and action_discard need card.id, and action_replace needs card.idx.
+type GameAction struct {
+	Type string   `json:"type"`
+	Card *CardRef `json:"card,omitempty"`
+	// ActionID optionally identifies the action, so a retried submission isn't applied twice.
+	ActionID uuid.UUID `json:"action_id"`
+	// Seat is the player the action is for, in a hot-seat game where one connection plays several seats;
+	// uuid.Nil means the sender's own seat.
+	Seat uuid.UUID `json:"seat"`
+}
+
+// Special steps accepted by action_special.
+const (
+	SpecialSkip         = "skip"
+	SpecialPeekSelf     = "peek_self"
+	SpecialPeekOther    = "peek_other"
+	SpecialSwapBlind    = "swap_blind"
+	SpecialSwapPeek     = "swap_peek"
+	SpecialSwapPeekSwap = "swap_peek_swap"
+)
+
+// GameSpecial is a step of a special card action, e.g.
+// {"type": "action_special", "special": "swap_blind", "card1": {...}, "card2": {...}}.
+type GameSpecial struct {
+	Special string   `json:"special"`
+	Card1   *CardRef `json:"card1,omitempty"`
+	Card2   *CardRef `json:"card2,omitempty"`
+	// ActionID optionally identifies the step, and Seat the player it's for, as for GameAction.
+	ActionID uuid.UUID `json:"action_id"`
+	Seat     uuid.UUID `json:"seat"`
+}
+
+// GameReport reports another player in the game:
+// {"type": "report", "payload": {"userID": "{uuid}", "reason": "..."}}.
+type GameReport struct {
+	Payload struct {
+		UserID uuid.UUID `json:"userID"`
+		Reason string    `json:"reason"`
+	} `json:"payload"`
+}
+
+// GameChat sends a chat message to the game's players and spectators: {"type": "chat", "msg": "..."}.
+type GameChat struct {
+	Msg string `json:"msg"`
+}
+
+// MaxGameChatLength is the longest chat message accepted on the game socket, in characters.
+const MaxGameChatLength = 200
+
+// Emotes are the quick emotes a player can send during a game.
+var Emotes = []string{"gg", "good_luck", "nice", "wow", "oops", "thinking", "hurry", "thanks"}
+
+// GameEmote sends one of the Emotes to the game: {"type": "emote", "emote": "gg"}.
+type GameEmote struct {
+	Emote string `json:"emote"`
+}
+
+// VoteAbort votes to end the game without a result: {"type": "vote_abort"}.
+type VoteAbort struct{}
+
+// RequestHandoff asks for a new handoff token, to move the game to another device: {"type": "request_handoff"}.
+type RequestHandoff struct{}
+
+// Ping asks the server for a "pong": {"type": "ping", "ts": 1760523600000}. The optional ts, the client's
+// clock in Unix milliseconds, is echoed back.
+type Ping struct {
+	TS int64 `json:"ts,omitempty"`
+}
+
+// Pong answers the server's "ping", echoing its ts: {"type": "pong", "ts": 1760523600000}.
+type Pong struct {
+	TS int64 `json:"ts"`
+}
+
+func (m *GameAction) Validate() *Error {
+	switch m.Type {
+	case "action_snap", "action_discard":
+		if m.Card == nil || m.Card.ID == uuid.Nil {
+			return missing("card.id")
+		}
+	case "action_replace":
+		if m.Card == nil || m.Card.Idx == nil {
+			return missing("card.idx")
+		}
+		if *m.Card.Idx < 0 {
+			return invalid("card.idx", "must not be negative")
+		}
+	}
+	return nil
+}
+
+func (m *GameSpecial) Validate() *Error {
+	switch m.Special {
+	case "":
+		return missing("special")
+	case SpecialSkip, SpecialPeekSelf, SpecialSwapPeekSwap:
+		return nil
+	case SpecialPeekOther:
+		if m.Card1.Owner() == uuid.Nil {
+			return missing("card1.user.id")
+		}
+		return nil
+	case SpecialSwapBlind, SpecialSwapPeek:
+		for i, c := range []*CardRef{m.Card1, m.Card2} {
+			field := fmt.Sprintf("card%d", i+1)
+			if c == nil || c.ID == uuid.Nil {
+				return missing(field + ".id")
+			}
+			if c.Owner() == uuid.Nil {
+				return missing(field + ".user.id")
+			}
+		}
+		return nil
+	default:
+		return invalid("special", "unknown step "+m.Special)
+	}
+}
+
+func (m *GameReport) Validate() *Error {
+	if m.Payload.UserID == uuid.Nil {
+		return missing("payload.userID")
+	}
+	return nil
+}
+
+func (m *GameChat) Validate() *Error {
+	if strings.TrimSpace(m.Msg) == "" {
+		return missing("msg")
+	}
+	if utf8.RuneCountInString(m.Msg) > MaxGameChatLength {
+		return invalid("msg", fmt.Sprintf("must be at most %d characters", MaxGameChatLength))
+	}
+	return nil
+}
+
+func (m *GameEmote) Validate() *Error {
+	if m.Emote == "" {
+		return missing("emote")
+	}
+	if !slices.Contains(Emotes, m.Emote) {
+		return invalid("emote", "unknown emote "+m.Emote)
+	}
+	return nil
+}
+
+func (VoteAbort) Validate() *Error      { return nil }
+func (RequestHandoff) Validate() *Error { return nil }
+func (Ping) Validate() *Error           { return nil }
+
+func (m *Pong) Validate() *Error {
+	if m.TS <= 0 {
+		return missing("ts")
+	}
+	return nil
+}
+
+var gameMessages = map[string]func() Message{
+	"action_snap":             func() Message { return &GameAction{} },
+	"action_draw_stockpile":   func() Message { return &GameAction{} },
+	"action_draw_discardpile": func() Message { return &GameAction{} },
+	"action_draw_discard":     func() Message { return &GameAction{} }, // older name of action_draw_discardpile
+	"action_discard":          func() Message { return &GameAction{} },
+	"action_replace":          func() Message { return &GameAction{} },
+	"action_cambia":           func() Message { return &GameAction{} },
+	"action_special":          func() Message { return &GameSpecial{} },
+	"report":                  func() Message { return &GameReport{} },
+	"chat":                    func() Message { return &GameChat{} },
+	"emote":                   func() Message { return &GameEmote{} },
+	"vote_abort":              func() Message { return &VoteAbort{} },
+	"request_handoff":         func() Message { return &RequestHandoff{} },
+	"ping":                    func() Message { return &Ping{} },
+	"pong":                    func() Message { return &Pong{} },
+	"resync_from":             func() Message { return &ResyncFrom{} },
+}
+
+// DecodeGame decodes and validates a message from the game socket, returning its envelope and the
+// typed message, e.g. *GameAction for "action_snap".
+func DecodeGame(data []byte) (Envelope, Message, *Error) {
+	return decode(data, gameMessages)
+}
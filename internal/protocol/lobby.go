@@ -0,0 +1,170 @@
+// internal/protocol/lobby.go
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Ready marks the sender ready: {"type": "ready"}.
+type Ready struct{}
+
+// Unready clears the sender's ready state: {"type": "unready"}.
+type Unready struct{}
+
+// LeaveLobby removes the sender from the lobby: {"type": "leave_lobby"}.
+type LeaveLobby struct{}
+
+// StartGame starts the game now, without waiting for the countdown: {"type": "start_game"}.
+type StartGame struct{}
+
+// Invite invites a user to the lobby: {"type": "invite", "userID": "{uuid}"}.
+type Invite struct {
+	UserID uuid.UUID `json:"userID"`
+}
+
+// Chat sends a chat message to the lobby: {"type": "chat", "msg": "..."}.
+type Chat struct {
+	Msg string `json:"msg"`
+}
+
+// Report reports another player in the lobby: {"type": "report", "userID": "{uuid}", "reason": "..."}.
+type Report struct {
+	UserID uuid.UUID `json:"userID"`
+	Reason string    `json:"reason"`
+}
+
+// ChatReaction identifies a reaction on a chat message.
+type ChatReaction struct {
+	MsgID uuid.UUID `json:"msg_id"`
+	Emoji string    `json:"emoji"`
+}
+
+// ChatReactionAdd reacts to a chat message: {"type": "chat_reaction_add", "msg_id": "{uuid}", "emoji": "..."}.
+type ChatReactionAdd struct{ ChatReaction }
+
+// ChatReactionRemove takes back a reaction: {"type": "chat_reaction_remove", "msg_id": "{uuid}", "emoji": "..."}.
+type ChatReactionRemove struct{ ChatReaction }
+
+// UpdateRules changes the lobby's house rules and/or lobby settings (host only):
+// {"type": "update_rules", "rules": {...}, "settings": {...}}.
+type UpdateRules struct {
+	Rules    map[string]interface{} `json:"rules"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// SetTeam moves the sender to a team, in a team game: {"type": "set_team", "team": 2}. Teams are numbered
+// from 1.
+type SetTeam struct {
+	Team int `json:"team"`
+}
+
+// RTCSignal relays a WebRTC signaling message to another user in the lobby, so clients can set up
+// peer-to-peer voice: {"type": "rtc_signal", "to": "{uuid}", "kind": "offer", "data": {...}}. kind is one
+// of "offer", "answer", or "ice"; data is passed through as it is.
+type RTCSignal struct {
+	To   uuid.UUID       `json:"to"`
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// maxRTCSignalLen bounds the data of an rtc_signal; an SDP offer is a few kilobytes.
+const maxRTCSignalLen = 16 << 10
+
+func (Ready) Validate() *Error      { return nil }
+func (Unready) Validate() *Error    { return nil }
+func (LeaveLobby) Validate() *Error { return nil }
+func (StartGame) Validate() *Error  { return nil }
+
+func (m *Invite) Validate() *Error {
+	if m.UserID == uuid.Nil {
+		return missing("userID")
+	}
+	return nil
+}
+
+func (m *Chat) Validate() *Error {
+	if strings.TrimSpace(m.Msg) == "" {
+		return missing("msg")
+	}
+	return nil
+}
+
+func (m *Report) Validate() *Error {
+	if m.UserID == uuid.Nil {
+		return missing("userID")
+	}
+	return nil
+}
+
+func (m *ChatReaction) Validate() *Error {
+	if m.MsgID == uuid.Nil {
+		return missing("msg_id")
+	}
+	if m.Emoji == "" {
+		return missing("emoji")
+	}
+	return nil
+}
+
+func (m *SetTeam) Validate() *Error {
+	if m.Team == 0 {
+		return missing("team")
+	}
+	if m.Team < 0 {
+		return invalid("team", "must be at least 1")
+	}
+	return nil
+}
+
+func (m *RTCSignal) Validate() *Error {
+	if m.To == uuid.Nil {
+		return missing("to")
+	}
+	switch m.Kind {
+	case "offer", "answer", "ice":
+	case "":
+		return missing("kind")
+	default:
+		return invalid("kind", `must be "offer", "answer", or "ice"`)
+	}
+	if len(m.Data) == 0 || string(m.Data) == "null" {
+		return missing("data")
+	}
+	if len(m.Data) > maxRTCSignalLen {
+		return invalid("data", fmt.Sprintf("must be at most %d bytes", maxRTCSignalLen))
+	}
+	return nil
+}
+
+func (m *UpdateRules) Validate() *Error {
+	if len(m.Rules) == 0 && len(m.Settings) == 0 {
+		return missing("rules")
+	}
+	return nil
+}
+
+var lobbyMessages = map[string]func() Message{
+	"ready":                func() Message { return &Ready{} },
+	"unready":              func() Message { return &Unready{} },
+	"leave_lobby":          func() Message { return &LeaveLobby{} },
+	"start_game":           func() Message { return &StartGame{} },
+	"invite":               func() Message { return &Invite{} },
+	"chat":                 func() Message { return &Chat{} },
+	"report":               func() Message { return &Report{} },
+	"chat_reaction_add":    func() Message { return &ChatReactionAdd{} },
+	"chat_reaction_remove": func() Message { return &ChatReactionRemove{} },
+	"update_rules":         func() Message { return &UpdateRules{} },
+	"set_team":             func() Message { return &SetTeam{} },
+	"resync_from":          func() Message { return &ResyncFrom{} },
+	"rtc_signal":           func() Message { return &RTCSignal{} },
+}
+
+// DecodeLobby decodes and validates a message from the lobby socket, returning its envelope and the
+// typed message, e.g. *Invite for "invite".
+func DecodeLobby(data []byte) (Envelope, Message, *Error) {
+	return decode(data, lobbyMessages)
+}
@@ -0,0 +1,366 @@
+// internal/protocol/proto.go
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// Encoding is how game messages are framed on the socket.
+type Encoding string
+
+const (
+	// EncodingJSON sends every message as a JSON text frame. It's the default.
+	EncodingJSON Encoding = "json"
+	// EncodingProtobuf sends game events as binary GameEvent frames (see game.proto) and accepts binary
+	// ClientMessage frames alongside JSON ones. Control messages (handshake, errors, pong, reports) stay JSON.
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// ParseEncoding maps a client's requested encoding to a supported one, falling back to JSON.
+func ParseEncoding(s string) Encoding {
+	if Encoding(s) == EncodingProtobuf {
+		return EncodingProtobuf
+	}
+	return EncodingJSON
+}
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendStringField(b []byte, field int, s string) []byte {
+	return appendBytesField(b, field, []byte(s))
+}
+
+func appendUUIDField(b []byte, field int, id uuid.UUID) []byte {
+	if id == uuid.Nil {
+		return b
+	}
+	return appendBytesField(b, field, id[:])
+}
+
+// appendMessageField writes an embedded message, even an empty one, so its presence survives.
+func appendMessageField(b []byte, field int, msg []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+func marshalCard(c *models.Card) []byte {
+	var b []byte
+	b = appendUUIDField(b, 1, c.ID)
+	b = appendStringField(b, 2, c.Suit)
+	b = appendStringField(b, 3, c.Rank)
+	if c.Value != 0 {
+		b = appendTag(b, 4, wireVarint)
+		b = binary.AppendVarint(b, int64(c.Value)) // sint32: zigzag
+	}
+	return b
+}
+
+// MarshalGameEvent encodes a game event as a GameEvent protobuf message.
+func MarshalGameEvent(ev game.GameEvent) ([]byte, error) {
+	var b []byte
+	b = appendStringField(b, 1, string(ev.Type))
+	b = appendUUIDField(b, 2, ev.UserID)
+	if ev.Card != nil {
+		b = appendMessageField(b, 3, marshalCard(ev.Card))
+	}
+	if ev.Card2 != nil {
+		b = appendMessageField(b, 4, marshalCard(ev.Card2))
+	}
+	if len(ev.Other) > 0 {
+		other, err := json.Marshal(ev.Other)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytesField(b, 5, other)
+	}
+	if ev.Seq != 0 {
+		b = AppendEventSeq(b, ev.Seq)
+	}
+	return b, nil
+}
+
+// AppendEventSeq adds a seq to a GameEvent encoded without one, returning a new slice. It lets an event be
+// encoded once and numbered for each recipient.
+func AppendEventSeq(event []byte, seq uint64) []byte {
+	b := make([]byte, len(event), len(event)+1+binary.MaxVarintLen64)
+	copy(b, event)
+	b = appendTag(b, 6, wireVarint)
+	return binary.AppendUvarint(b, seq)
+}
+
+var errTruncated = errors.New("truncated message")
+
+// protoReader walks the fields of an encoded protobuf message.
+type protoReader struct {
+	b []byte
+}
+
+// next returns the next field's number and wire type; ok is false at the end of the message.
+func (r *protoReader) next() (field, wire int, ok bool, err error) {
+	if len(r.b) == 0 {
+		return 0, 0, false, nil
+	}
+	tag, err := r.varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return int(tag >> 3), int(tag & 7), true, nil
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.b)) < n {
+		return nil, errTruncated
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+// skip discards a field the reader doesn't know, for forward compatibility.
+func (r *protoReader) skip(wire int) error {
+	var n int
+	switch wire {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wireFixed64:
+		n = 8
+	case wireFixed32:
+		n = 4
+	default:
+		return fmt.Errorf("unsupported wire type %d", wire)
+	}
+	if len(r.b) < n {
+		return errTruncated
+	}
+	r.b = r.b[n:]
+	return nil
+}
+
+// malformed reports a ClientMessage that isn't valid protobuf.
+func malformed(err error) *Error {
+	return &Error{Code: CodeInvalidProto, Message: "malformed protobuf message: " + err.Error()}
+}
+
+func parseUUID(field string, v []byte) (uuid.UUID, *Error) {
+	id, err := uuid.FromBytes(v)
+	if err != nil {
+		return uuid.Nil, invalid(field, "expected 16 bytes")
+	}
+	return id, nil
+}
+
+func unmarshalCardRef(field string, data []byte) (*CardRef, *Error) {
+	ref := &CardRef{}
+	r := protoReader{b: data}
+	for {
+		num, wire, ok, err := r.next()
+		if err != nil {
+			return nil, invalid(field, err.Error())
+		}
+		if !ok {
+			return ref, nil
+		}
+		switch {
+		case num == 1 && wire == wireBytes:
+			v, err := r.bytes()
+			if err != nil {
+				return nil, invalid(field+".id", err.Error())
+			}
+			var perr *Error
+			if ref.ID, perr = parseUUID(field+".id", v); perr != nil {
+				return nil, perr
+			}
+		case num == 2 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, invalid(field+".idx", err.Error())
+			}
+			idx := int(int32(v))
+			ref.Idx = &idx
+		case num == 3 && wire == wireBytes:
+			v, err := r.bytes()
+			if err != nil {
+				return nil, invalid(field+".user.id", err.Error())
+			}
+			id, perr := parseUUID(field+".user.id", v)
+			if perr != nil {
+				return nil, perr
+			}
+			ref.User = &UserRef{ID: id}
+		default:
+			if err := r.skip(wire); err != nil {
+				return nil, invalid(field, err.Error())
+			}
+		}
+	}
+}
+
+// clientMessage holds the decoded fields of a ClientMessage.
+type clientMessage struct {
+	typ                string
+	special            string
+	card, card1, card2 *CardRef
+	reportUser         uuid.UUID
+	reportReason       string
+	resyncSeq          uint64
+	reqID              string
+	actionID           uuid.UUID
+	seat               uuid.UUID
+	msg                string
+	ts                 int64
+}
+
+func unmarshalClientMessage(data []byte) (*clientMessage, *Error) {
+	m := &clientMessage{}
+	r := protoReader{b: data}
+	for {
+		num, wire, ok, err := r.next()
+		if err != nil {
+			return nil, malformed(err)
+		}
+		if !ok {
+			return m, nil
+		}
+		if num == 8 && wire == wireVarint {
+			if m.resyncSeq, err = r.varint(); err != nil {
+				return nil, malformed(err)
+			}
+			continue
+		}
+		if num == 12 && wire == wireVarint {
+			ts, err := r.varint()
+			if err != nil {
+				return nil, malformed(err)
+			}
+			m.ts = int64(ts)
+			continue
+		}
+		if wire != wireBytes || num < 1 || num > 13 {
+			if err := r.skip(wire); err != nil {
+				return nil, malformed(err)
+			}
+			continue
+		}
+		v, err := r.bytes()
+		if err != nil {
+			return nil, malformed(err)
+		}
+		var perr *Error
+		switch num {
+		case 1:
+			m.typ = string(v)
+		case 2:
+			m.card, perr = unmarshalCardRef("card", v)
+		case 3:
+			m.special = string(v)
+		case 4:
+			m.card1, perr = unmarshalCardRef("card1", v)
+		case 5:
+			m.card2, perr = unmarshalCardRef("card2", v)
+		case 6:
+			m.reportUser, perr = parseUUID("payload.userID", v)
+		case 7:
+			m.reportReason = string(v)
+		case 9:
+			m.reqID = string(v)
+		case 10:
+			m.actionID, perr = parseUUID("action_id", v)
+		case 11:
+			m.msg = string(v)
+		case 13:
+			m.seat, perr = parseUUID("seat", v)
+		}
+		if perr != nil {
+			return nil, perr
+		}
+	}
+}
+
+// DecodeGameProto decodes and validates a binary ClientMessage from the game socket, into the same envelope
+// and typed messages as DecodeGame.
+func DecodeGameProto(data []byte) (Envelope, Message, *Error) {
+	cm, perr := unmarshalClientMessage(data)
+	if perr != nil {
+		return Envelope{}, nil, perr
+	}
+	envelope := Envelope{Type: cm.typ, ReqID: cm.reqID}
+	if perr := envelope.validate(); perr != nil {
+		envelope.ReqID = ""
+		return envelope, nil, perr
+	}
+	if cm.typ == "" {
+		return envelope, nil, missing("type")
+	}
+	newMsg, ok := gameMessages[cm.typ]
+	if !ok {
+		return envelope, nil, &Error{Code: CodeUnknownType, Message: fmt.Sprintf("unknown message type %q", cm.typ), Field: "type"}
+	}
+
+	msg := newMsg()
+	switch m := msg.(type) {
+	case *GameAction:
+		m.Type, m.Card, m.ActionID, m.Seat = cm.typ, cm.card, cm.actionID, cm.seat
+	case *GameSpecial:
+		m.Special, m.Card1, m.Card2, m.ActionID, m.Seat = cm.special, cm.card1, cm.card2, cm.actionID, cm.seat
+	case *GameReport:
+		m.Payload.UserID, m.Payload.Reason = cm.reportUser, cm.reportReason
+	case *GameChat:
+		m.Msg = cm.msg
+	case *GameEmote:
+		m.Emote = cm.msg
+	case *ResyncFrom:
+		m.Seq = cm.resyncSeq
+	case *Ping:
+		m.TS = cm.ts
+	case *Pong:
+		m.TS = cm.ts
+	}
+	if err := msg.Validate(); err != nil {
+		return envelope, nil, err
+	}
+	return envelope, msg, nil
+}
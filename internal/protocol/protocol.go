@@ -0,0 +1,154 @@
+// internal/protocol/protocol.go
+
+// Package protocol defines the typed client messages accepted on the lobby and game sockets, how they're
+// decoded and validated, and the structured "error" frames sent back when a message is rejected.
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Code is a machine-readable reason carried by an error frame.
+type Code string
+
+const (
+	CodeInvalidJSON  Code = "invalid_json"     // the message isn't a JSON object
+	CodeInvalidProto Code = "invalid_protobuf" // a binary message isn't a valid ClientMessage
+	CodeUnknownType  Code = "unknown_type"     // the "type" isn't one the socket accepts
+	CodeMissingField Code = "missing_field"    // a required field is absent or empty
+	CodeInvalidField Code = "invalid_field"    // a field has the wrong type or an unacceptable value
+	CodeForbidden    Code = "forbidden"        // the sender isn't allowed to do this, e.g. not the host
+	CodeInvalidState Code = "invalid_state"    // not possible right now, e.g. the game already started
+	CodeMuted        Code = "muted"            // the sender is muted
+	CodeRateLimited  Code = "rate_limited"     // the sender is sending too fast; see Limiter
+	CodeInternal     Code = "internal_error"
+)
+
+// Error is a rejected client message. It's sent back to the client as
+//
+//	{"type": "error", "code": "missing_field", "message": "userID is required", "field": "userID"}
+//
+// where "field" is only present for field-level errors.
+type Error struct {
+	Code    Code
+	Message string
+	Field   string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Frame renders the error as the frame sent to the client.
+func (e *Error) Frame() map[string]interface{} {
+	frame := map[string]interface{}{
+		"type":    "error",
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if e.Field != "" {
+		frame["field"] = e.Field
+	}
+	return frame
+}
+
+// Errorf builds an Error with a formatted message.
+func Errorf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// missing reports a required field that was absent or empty.
+func missing(field string) *Error {
+	return &Error{Code: CodeMissingField, Message: field + " is required", Field: field}
+}
+
+// invalid reports a field with an unacceptable value.
+func invalid(field, reason string) *Error {
+	return &Error{Code: CodeInvalidField, Message: fmt.Sprintf("invalid %s: %s", field, reason), Field: field}
+}
+
+// Message is a decoded client message.
+type Message interface {
+	// Validate checks that required fields are present and well-formed.
+	Validate() *Error
+}
+
+// ResyncFrom asks for the broadcasts sent after the last one the client saw: {"type": "resync_from", "seq": 41}.
+// It's accepted on both the lobby and game sockets; seq 0 asks for everything still buffered.
+type ResyncFrom struct {
+	Seq uint64 `json:"seq"`
+}
+
+func (ResyncFrom) Validate() *Error { return nil }
+
+// maxReqIDLen bounds the req_id a client may attach to a message.
+const maxReqIDLen = 64
+
+// Envelope holds the fields every client message may carry, whatever its type.
+type Envelope struct {
+	Type string `json:"type"`
+	// ReqID is an optional ID chosen by the client and echoed as "req_id" in the server's direct reply to
+	// the message: its "error" frame, its own reply (e.g. "pong"), or otherwise an "ack".
+	ReqID string `json:"req_id,omitempty"`
+}
+
+// Reply adds the message's req_id, if it has one, to a direct reply frame.
+func (e Envelope) Reply(frame map[string]interface{}) map[string]interface{} {
+	if e.ReqID != "" {
+		frame["req_id"] = e.ReqID
+	}
+	return frame
+}
+
+// Ack is the reply to a message carried out without a reply of its own, e.g.
+// {"type": "ack", "req_id": "r1", "for": "ready"}. It's only sent for messages with a req_id.
+func (e Envelope) Ack() map[string]interface{} {
+	return e.Reply(map[string]interface{}{"type": "ack", "for": e.Type})
+}
+
+func (e Envelope) validate() *Error {
+	if len(e.ReqID) > maxReqIDLen {
+		return invalid("req_id", fmt.Sprintf("must be at most %d characters", maxReqIDLen))
+	}
+	return nil
+}
+
+// decode reads the message's envelope, unmarshals the message into the type registered for it, and
+// validates it.
+func decode(data []byte, registry map[string]func() Message) (Envelope, Message, *Error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		// a mistyped field still leaves the others decoded, so the error can carry the req_id
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return envelope, nil, &Error{Code: CodeInvalidJSON, Message: "message must be a JSON object"}
+		}
+		return envelope, nil, invalid(typeErr.Field, "expected "+typeErr.Type.String())
+	}
+	if perr := envelope.validate(); perr != nil {
+		envelope.ReqID = ""
+		return envelope, nil, perr
+	}
+	if envelope.Type == "" {
+		return envelope, nil, missing("type")
+	}
+	newMsg, ok := registry[envelope.Type]
+	if !ok {
+		return envelope, nil, &Error{Code: CodeUnknownType, Message: fmt.Sprintf("unknown message type %q", envelope.Type), Field: "type"}
+	}
+
+	msg := newMsg()
+	if err := json.Unmarshal(data, msg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return envelope, nil, invalid(typeErr.Field, "expected "+typeErr.Type.String())
+		}
+		return envelope, nil, &Error{Code: CodeInvalidField, Message: fmt.Sprintf("malformed %s message: %v", envelope.Type, err)}
+	}
+	if err := msg.Validate(); err != nil {
+		return envelope, nil, err
+	}
+	return envelope, msg, nil
+}
@@ -0,0 +1,248 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/game"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+func TestDecodeLobby(t *testing.T) {
+	id := uuid.New()
+	env, msg, err := DecodeLobby([]byte(`{"type": "invite", "req_id": "r1", "userID": "` + id.String() + `"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inv, ok := msg.(*Invite); env.Type != "invite" || !ok || inv.UserID != id {
+		t.Fatalf("expected invite for %v, got %+v %+v", id, env, msg)
+	}
+	if ack := env.Ack(); ack["req_id"] != "r1" || ack["for"] != "invite" {
+		t.Fatalf("expected an ack for r1, got %v", ack)
+	}
+	// errors still carry the req_id when it could be read
+	env, _, err = DecodeLobby([]byte(`{"type": "invite", "req_id": "r2", "userID": 5}`))
+	if err == nil || env.ReqID != "r2" {
+		t.Fatalf("expected an error for r2, got %+v %v", env, err)
+	}
+
+	if _, msg, err = DecodeLobby([]byte(`{"type": "chat_reaction_remove", "msg_id": "` + id.String() + `", "emoji": "👍"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, ok := msg.(*ChatReactionRemove); !ok || r.MsgID != id {
+		t.Fatalf("expected reaction removal, got %+v", msg)
+	}
+
+	cases := []struct {
+		data  string
+		code  Code
+		field string
+	}{
+		{`not json`, CodeInvalidJSON, ""},
+		{`{"msg": "hi"}`, CodeMissingField, "type"},
+		{`{"type": "dance"}`, CodeUnknownType, "type"},
+		{`{"type": "invite"}`, CodeMissingField, "userID"},
+		{`{"type": "invite", "userID": "nope"}`, CodeInvalidField, ""},
+		{`{"type": "chat", "msg": "   "}`, CodeMissingField, "msg"},
+		{`{"type": "chat", "msg": 5}`, CodeInvalidField, "msg"},
+		{`{"type": "chat_reaction_add", "msg_id": "` + id.String() + `"}`, CodeMissingField, "emoji"},
+		{`{"type": "rtc_signal", "to": "` + id.String() + `", "kind": "hello", "data": {}}`, CodeInvalidField, "kind"},
+		{`{"type": "rtc_signal", "to": "` + id.String() + `", "kind": "ice"}`, CodeMissingField, "data"},
+	}
+	for _, c := range cases {
+		_, _, err := DecodeLobby([]byte(c.data))
+		if err == nil || err.Code != c.code || err.Field != c.field {
+			t.Errorf("%s: expected %s on %q, got %+v", c.data, c.code, c.field, err)
+		}
+	}
+}
+
+func TestDecodeGame(t *testing.T) {
+	card, owner := uuid.New(), uuid.New()
+	ref := `{"id": "` + card.String() + `", "user": {"id": "` + owner.String() + `"}}`
+
+	_, msg, err := DecodeGame([]byte(`{"type": "action_special", "special": "swap_blind", "card1": ` + ref + `, "card2": ` + ref + `}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sp, ok := msg.(*GameSpecial); !ok || sp.Card1.ID != card || sp.Card2.Owner() != owner {
+		t.Fatalf("expected swap_blind special, got %+v", msg)
+	}
+
+	if _, msg, err = DecodeGame([]byte(`{"type": "action_replace", "card": {"idx": 2}}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a := msg.(*GameAction); a.Type != "action_replace" || *a.Card.Idx != 2 {
+		t.Fatalf("expected replace at 2, got %+v", a)
+	}
+
+	if _, msg, err = DecodeGame([]byte(`{"type": "emote", "emote": "gg"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, ok := msg.(*GameEmote); !ok || e.Emote != "gg" {
+		t.Fatalf("expected gg emote, got %+v", msg)
+	}
+
+	cases := []struct {
+		data  string
+		code  Code
+		field string
+	}{
+		{`{"type": "action_snap"}`, CodeMissingField, "card.id"},
+		{`{"type": "action_replace", "card": {"idx": -1}}`, CodeInvalidField, "card.idx"},
+		{`{"type": "action_special"}`, CodeMissingField, "special"},
+		{`{"type": "action_special", "special": "juggle"}`, CodeInvalidField, "special"},
+		{`{"type": "action_special", "special": "swap_peek", "card1": ` + ref + `}`, CodeMissingField, "card2.id"},
+		{`{"type": "action_special", "special": "peek_other", "card1": {}}`, CodeMissingField, "card1.user.id"},
+		{`{"type": "report", "payload": {}}`, CodeMissingField, "payload.userID"},
+		{`{"type": "chat", "msg": ""}`, CodeMissingField, "msg"},
+		{`{"type": "chat", "msg": "` + strings.Repeat("a", MaxGameChatLength+1) + `"}`, CodeInvalidField, "msg"},
+		{`{"type": "emote"}`, CodeMissingField, "emote"},
+		{`{"type": "emote", "emote": "dance"}`, CodeInvalidField, "emote"},
+		{`{"type": "pong"}`, CodeMissingField, "ts"},
+	}
+	for _, c := range cases {
+		_, _, err := DecodeGame([]byte(c.data))
+		if err == nil || err.Code != c.code || err.Field != c.field {
+			t.Errorf("%s: expected %s on %q, got %+v", c.data, c.code, c.field, err)
+		}
+	}
+}
+
+func TestSocketNegotiate(t *testing.T) {
+	s := Socket{Name: "lobby", Versions: []int{3, 2}}
+	if got := s.Subprotocols(); len(got) != 3 || got[0] != "lobby.v3" || got[2] != "lobby" {
+		t.Fatalf("unexpected subprotocols %v", got)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/lobby/ws/x", nil)
+	if v, reason := s.Negotiate(r, "lobby.v2"); v != 2 || reason != "" {
+		t.Fatalf("expected v2, got %d %q", v, reason)
+	}
+	if v, reason := s.Negotiate(r, "lobby"); v != 2 || reason != "" {
+		t.Fatalf("expected the bare name to mean the oldest version, got %d %q", v, reason)
+	}
+
+	if _, reason := s.Negotiate(r, ""); reason != "client must speak the lobby subprotocol" {
+		t.Fatalf("unexpected close reason %q", reason)
+	}
+	r.Header.Set("Sec-WebSocket-Protocol", "token.abc, lobby.v9")
+	_, reason := s.Negotiate(r, "")
+	if reason != "unsupported lobby protocol version; server speaks lobby.v2 through lobby.v3" {
+		t.Fatalf("unexpected close reason %q", reason)
+	}
+	if len(reason) > 123 {
+		t.Fatalf("close reason too long: %d bytes", len(reason))
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	user, card := uuid.New(), uuid.New()
+	data, err := MarshalGameEvent(game.GameEvent{
+		Type:   game.EventSnapSuccess,
+		UserID: user,
+		Card:   &models.Card{ID: card, Suit: "Hearts", Rank: "K", Value: -1},
+		Other:  map[string]interface{}{"idx": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := decodeTestEvent(t, data)
+	if ev.typ != "player_snap_success" || ev.user != user || ev.card.ID != card || ev.card.Value != -1 || ev.card.Rank != "K" || ev.other != `{"idx":2}` {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	// numbering an encoded event matches encoding it numbered
+	numbered, _ := MarshalGameEvent(game.GameEvent{
+		Type:   game.EventSnapSuccess,
+		UserID: user,
+		Card:   &models.Card{ID: card, Suit: "Hearts", Rank: "K", Value: -1},
+		Other:  map[string]interface{}{"idx": 2},
+		Seq:    300,
+	})
+	if got := AppendEventSeq(data, 300); string(got) != string(numbered) {
+		t.Fatalf("expected %x, got %x", numbered, got)
+	}
+
+	// ClientMessage{type: "action_replace", card: {idx: 3}}
+	msg := appendStringField(nil, 1, "action_replace")
+	ref := appendTag(nil, 2, wireVarint)
+	ref = binary.AppendUvarint(ref, 3)
+	msg = appendMessageField(msg, 2, ref)
+	env, decoded, perr := DecodeGameProto(msg)
+	if perr != nil {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+	if a := decoded.(*GameAction); env.Type != "action_replace" || a.Card == nil || *a.Card.Idx != 3 {
+		t.Fatalf("expected replace at 3, got %+v", decoded)
+	}
+
+	// a snap without a card fails the same validation as JSON
+	if _, _, perr := DecodeGameProto(appendStringField(nil, 1, "action_snap")); perr == nil || perr.Field != "card.id" {
+		t.Fatalf("expected missing card.id, got %+v", perr)
+	}
+	if _, _, perr := DecodeGameProto([]byte{0x0a, 0x10}); perr == nil || perr.Code != CodeInvalidProto {
+		t.Fatalf("expected invalid_protobuf for a truncated message, got %+v", perr)
+	}
+}
+
+type testEvent struct {
+	typ, other string
+	user       uuid.UUID
+	card       models.Card
+}
+
+// decodeTestEvent reads back a GameEvent the way a client would.
+func decodeTestEvent(t *testing.T, data []byte) testEvent {
+	var ev testEvent
+	r := protoReader{b: data}
+	for {
+		num, wire, ok, err := r.next()
+		if err != nil || !ok {
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ev
+		}
+		if num == 3 {
+			v, _ := r.bytes()
+			cr := protoReader{b: v}
+			for {
+				cnum, cwire, ok, _ := cr.next()
+				if !ok {
+					break
+				}
+				switch cnum {
+				case 1:
+					b, _ := cr.bytes()
+					ev.card.ID, _ = uuid.FromBytes(b)
+				case 3:
+					b, _ := cr.bytes()
+					ev.card.Rank = string(b)
+				case 4:
+					v, _ := cr.varint()
+					ev.card.Value = int(int64(v>>1) ^ -int64(v&1))
+				default:
+					cr.skip(cwire)
+				}
+			}
+			continue
+		}
+		if wire != wireBytes {
+			r.skip(wire)
+			continue
+		}
+		v, _ := r.bytes()
+		switch num {
+		case 1:
+			ev.typ = string(v)
+		case 2:
+			ev.user, _ = uuid.FromBytes(v)
+		case 5:
+			ev.other = string(v)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// internal/protocol/ratelimit.go
+package protocol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rate limits. Each connection has a token bucket for all of its messages and one for each message type
+// with a rule of its own. Every connection of a user also draws from a bucket shared by that user, so
+// opening more sockets doesn't buy more budget. A message that finds any of its buckets empty is rejected
+// with a "rate_limited" error frame, and a connection that is rejected more than strikeLimit times within
+// strikeWindow should be disconnected.
+
+// Rule sizes a token bucket.
+type Rule struct {
+	Burst int           // messages allowed back to back
+	Per   time.Duration // time for an empty bucket to refill completely
+}
+
+// Limits are the rate limits of one socket.
+type Limits struct {
+	Overall Rule
+	ByType  map[string]Rule
+}
+
+// The rate limits of the sockets that accept client messages.
+var (
+	LobbyLimits = Limits{
+		Overall: Rule{Burst: 30, Per: 10 * time.Second},
+		ByType: map[string]Rule{
+			"chat":                 {Burst: 5, Per: 5 * time.Second},
+			"chat_reaction_add":    {Burst: 10, Per: 10 * time.Second},
+			"chat_reaction_remove": {Burst: 10, Per: 10 * time.Second},
+			"invite":               {Burst: 5, Per: 30 * time.Second},
+			"report":               {Burst: 3, Per: time.Minute},
+			"update_rules":         {Burst: 5, Per: 10 * time.Second},
+			"resync_from":          {Burst: 5, Per: 10 * time.Second},
+			"rtc_signal":           {Burst: 20, Per: 5 * time.Second},
+		},
+	}
+	GameLimits = Limits{
+		Overall: Rule{Burst: 30, Per: 10 * time.Second},
+		ByType: map[string]Rule{
+			"action_snap": {Burst: 5, Per: 2 * time.Second},
+			"chat":        {Burst: 5, Per: 5 * time.Second},
+			"emote":       {Burst: 3, Per: 5 * time.Second},
+			"vote_abort":  {Burst: 3, Per: 10 * time.Second},
+			"report":      {Burst: 3, Per: time.Minute},
+			"ping":        {Burst: 5, Per: 5 * time.Second},
+			"pong":        {Burst: 5, Per: 5 * time.Second},
+			"resync_from": {Burst: 5, Per: 10 * time.Second},
+		},
+	}
+	MatchmakingLimits = Limits{
+		Overall: Rule{Burst: 20, Per: 10 * time.Second},
+	}
+	NotificationsLimits = Limits{
+		Overall: Rule{Burst: 20, Per: 10 * time.Second},
+		ByType: map[string]Rule{
+			"dm_send": {Burst: 5, Per: 5 * time.Second},
+		},
+	}
+)
+
+var userRule = Rule{Burst: 60, Per: 10 * time.Second}
+
+const (
+	strikeLimit  = 10
+	strikeWindow = time.Minute
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill tops the bucket up for the time since it was last used.
+func (b *bucket) refill(r Rule, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = float64(r.Burst)
+	} else {
+		b.tokens = min(float64(r.Burst), b.tokens+now.Sub(b.last).Seconds()*float64(r.Burst)/r.Per.Seconds())
+	}
+	b.last = now
+}
+
+// take refills the bucket and spends a token, if there is one.
+func (b *bucket) take(r Rule, now time.Time) bool {
+	b.refill(r, now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// users holds the buckets shared by each user's connections, for as long as any of them is open.
+var users = struct {
+	sync.Mutex
+	buckets map[uuid.UUID]*userBucket
+}{buckets: make(map[uuid.UUID]*userBucket)}
+
+type userBucket struct {
+	bucket
+	conns int
+}
+
+// Limiter rate-limits the messages read from one connection. It's safe for use by one read loop.
+type Limiter struct {
+	userID  uuid.UUID
+	limits  Limits
+	overall bucket
+	byType  map[string]*bucket
+	strikes bucket
+	now     func() time.Time
+}
+
+// NewLimiter returns a limiter for a connection of userID. Close it when the connection ends.
+func NewLimiter(userID uuid.UUID, limits Limits) *Limiter {
+	users.Lock()
+	ub, ok := users.buckets[userID]
+	if !ok {
+		ub = &userBucket{}
+		users.buckets[userID] = ub
+	}
+	ub.conns++
+	users.Unlock()
+	return &Limiter{userID: userID, limits: limits, byType: make(map[string]*bucket), now: time.Now}
+}
+
+// Close releases the connection's share of its user's bucket.
+func (l *Limiter) Close() {
+	users.Lock()
+	defer users.Unlock()
+	if ub, ok := users.buckets[l.userID]; ok {
+		if ub.conns--; ub.conns <= 0 {
+			delete(users.buckets, l.userID)
+		}
+	}
+}
+
+// Allow records a message of the given type, which may be empty if it couldn't be decoded. It returns the
+// "rate_limited" error to send back if the message should be dropped, and whether the sender has been
+// rejected so often that it should be disconnected.
+func (l *Limiter) Allow(typ string) (rejected *Error, disconnect bool) {
+	now := l.now()
+	typeRule, limited := l.limits.ByType[typ]
+	tb := l.byType[typ]
+	if limited && tb == nil {
+		tb = &bucket{}
+		l.byType[typ] = tb
+	}
+
+	users.Lock()
+	ub := users.buckets[l.userID]
+	ub.refill(userRule, now)
+	l.overall.refill(l.limits.Overall, now)
+	ok := ub.tokens >= 1 && l.overall.tokens >= 1
+	if limited {
+		tb.refill(typeRule, now)
+		ok = ok && tb.tokens >= 1
+	}
+	if ok {
+		ub.tokens--
+		l.overall.tokens--
+		if limited {
+			tb.tokens--
+		}
+	}
+	users.Unlock()
+	if ok {
+		return nil, false
+	}
+
+	if limited && tb.tokens < 1 {
+		rejected = Errorf(CodeRateLimited, "too many %s messages; slow down", typ)
+	} else {
+		rejected = Errorf(CodeRateLimited, "too many messages; slow down")
+	}
+	return rejected, !l.strikes.take(Rule{Burst: strikeLimit, Per: strikeWindow}, now)
+}
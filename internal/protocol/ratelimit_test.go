@@ -0,0 +1,66 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	userID := uuid.New()
+	l := NewLimiter(userID, LobbyLimits)
+	defer l.Close()
+	l.now = clock
+
+	for i := 0; i < 5; i++ {
+		if err, _ := l.Allow("chat"); err != nil {
+			t.Fatalf("chat %d: unexpected %v", i, err)
+		}
+	}
+	err, disconnect := l.Allow("chat")
+	if err == nil || err.Code != CodeRateLimited || disconnect {
+		t.Fatalf("expected the 6th chat to be rate limited, got %v (disconnect %v)", err, disconnect)
+	}
+	if err, _ := l.Allow("ready"); err != nil {
+		t.Fatalf("other types shouldn't be affected by the chat limit, got %v", err)
+	}
+	now = now.Add(time.Second)
+	if err, _ := l.Allow("chat"); err != nil {
+		t.Fatalf("expected a chat token back after a second, got %v", err)
+	}
+
+	for i := 0; ; i++ {
+		_, disconnect := l.Allow("chat")
+		if disconnect {
+			if i != strikeLimit-1 {
+				t.Fatalf("expected a disconnect after %d more rejections, got %d", strikeLimit, i+1)
+			}
+			break
+		}
+	}
+}
+
+func TestLimiterSharesUserBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	userID := uuid.New()
+	var conns []*Limiter
+	for i := 0; i < 3; i++ {
+		l := NewLimiter(userID, LobbyLimits)
+		defer l.Close()
+		l.now = func() time.Time { return now }
+		conns = append(conns, l)
+	}
+	for _, l := range conns {
+		for i := 0; i < userRule.Burst/len(conns); i++ {
+			if err, _ := l.Allow("ready"); err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+		}
+	}
+	if err, _ := conns[0].Allow("ready"); err == nil {
+		t.Fatalf("expected the user's budget to be spent across connections")
+	}
+}
@@ -0,0 +1,70 @@
+// internal/protocol/version.go
+package protocol
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Socket describes a WebSocket endpoint's protocol and the versions of it the server speaks. Clients pick a
+// version through the subprotocol "{name}.v{N}", e.g. "lobby.v1"; offering several lets the server choose
+// the newest one both sides understand. The bare name ("lobby") is what clients sent before versioning and
+// means the oldest supported version.
+type Socket struct {
+	Name     string
+	Versions []int // supported versions, newest first
+}
+
+// The sockets served by the API, with the protocol versions each supports.
+var (
+	LobbySocket         = Socket{Name: "lobby", Versions: []int{1}}
+	GameSocket          = Socket{Name: "game", Versions: []int{1}}
+	SpectateSocket      = Socket{Name: "spectate", Versions: []int{1}}
+	MatchmakingSocket   = Socket{Name: "matchmaking", Versions: []int{1}}
+	NotificationsSocket = Socket{Name: "notifications", Versions: []int{1}}
+)
+
+// Subprotocol returns the subprotocol name for a version, e.g. "lobby.v1".
+func (s Socket) Subprotocol(version int) string {
+	return s.Name + ".v" + strconv.Itoa(version)
+}
+
+// Subprotocols lists the subprotocols to offer during the WebSocket handshake, in order of preference.
+func (s Socket) Subprotocols() []string {
+	protos := make([]string, 0, len(s.Versions)+1)
+	for _, v := range s.Versions {
+		protos = append(protos, s.Subprotocol(v))
+	}
+	return append(protos, s.Name)
+}
+
+// Negotiate returns the protocol version for the subprotocol the handshake settled on. If there is none,
+// it returns a close reason explaining why the client can't be served.
+func (s Socket) Negotiate(r *http.Request, negotiated string) (int, string) {
+	if negotiated == s.Name {
+		return s.Versions[len(s.Versions)-1], ""
+	}
+	for _, v := range s.Versions {
+		if strings.EqualFold(negotiated, s.Subprotocol(v)) {
+			return v, ""
+		}
+	}
+
+	versioned := false
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, proto := range strings.Split(header, ",") {
+			versioned = versioned || strings.HasPrefix(strings.TrimSpace(proto), s.Name+".v")
+		}
+	}
+	if !versioned {
+		return 0, fmt.Sprintf("client must speak the %s subprotocol", s.Name)
+	}
+	// close reasons are capped at 123 bytes, so name the supported range rather than every version
+	supported := s.Subprotocol(s.Versions[0])
+	if oldest := s.Versions[len(s.Versions)-1]; oldest != s.Versions[0] {
+		supported = s.Subprotocol(oldest) + " through " + supported
+	}
+	return 0, fmt.Sprintf("unsupported %s protocol version; server speaks %s", s.Name, supported)
+}
@@ -196,3 +196,39 @@ func Update1v1(winner, loser models.User) (models.User, models.User) {
 	arr = MultiIterationGlicko2(arr, sarr, 10)
 	return arr[0], arr[1]
 }
+
+// Projection is the rating a player would have after finishing a game in a given place.
+type Projection struct {
+	Place  int `json:"place"` // 1 is best
+	Rating int `json:"rating"`
+	Delta  int `json:"delta"`
+}
+
+// Preview projects a player's rating change for each place they could finish in against opponents rated as
+// given, as FinalizeRatings would compute it once the game is over. No one ties, and the opponents finish
+// in the order given around the player. With no opponents, there's no game to rate, and it returns nil.
+func Preview(self int, opponents []int) []Projection {
+	if len(opponents) == 0 {
+		return nil
+	}
+	n := len(opponents) + 1
+	projections := make([]Projection, 0, n)
+	for place := 1; place <= n; place++ {
+		me := models.User{ID: uuid.New(), Elo1v1: self}
+		players := []models.User{me}
+		scores := map[uuid.UUID]int{me.ID: place}
+		next := 1
+		for _, r := range opponents {
+			if next == place {
+				next++
+			}
+			opp := models.User{ID: uuid.New(), Elo1v1: r}
+			players = append(players, opp)
+			scores[opp.ID] = next
+			next++
+		}
+		after := FinalizeRatings(players, scores)[0].Elo1v1
+		projections = append(projections, Projection{Place: place, Rating: after, Delta: after - self})
+	}
+	return projections
+}
@@ -0,0 +1,25 @@
+package rating
+
+import "testing"
+
+func TestPreview(t *testing.T) {
+	projections := Preview(1500, []int{1600, 1400, 1500})
+	if len(projections) != 4 {
+		t.Fatalf("expected a projection for each of 4 places, got %v", projections)
+	}
+	for i, p := range projections {
+		if p.Place != i+1 || p.Delta != p.Rating-1500 {
+			t.Fatalf("unexpected projection %+v", p)
+		}
+		if i > 0 && p.Delta >= projections[i-1].Delta {
+			t.Fatalf("expected a worse place to gain less, got %v", projections)
+		}
+	}
+	if projections[0].Delta <= 0 || projections[3].Delta >= 0 {
+		t.Fatalf("expected first to gain and last to lose, got %v", projections)
+	}
+
+	if Preview(1500, nil) != nil {
+		t.Fatal("expected no projections without opponents")
+	}
+}
@@ -0,0 +1,217 @@
+// internal/redis/redis.go
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// A minimal Redis client speaking RESP2: enough for the commands and pub/sub the server uses to share
+// state between nodes.
+
+// Error is an error reply from the server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Conn is a connection for issuing commands. It's safe for concurrent use; commands run one at a time.
+type Conn struct {
+	mu sync.Mutex
+	c  net.Conn
+	r  *bufio.Reader
+}
+
+// Dial connects to the server at addr, authenticating with password if it isn't empty.
+func Dial(ctx context.Context, addr, password string) (*Conn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	c := NewConn(nc)
+	if password != "" {
+		if _, err := c.Do("AUTH", password); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// NewConn wraps an established connection.
+func NewConn(nc net.Conn) *Conn {
+	return &Conn{c: nc, r: bufio.NewReader(nc)}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.c.Close()
+}
+
+// Do sends a command and returns its reply: a string for simple strings, int64 for integers, []byte or
+// nil for bulk strings, and []interface{} for arrays. An error reply is returned as an Error.
+func (c *Conn) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := writeCommand(c.c, args); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Strings converts an array reply of bulk strings, as from HGETALL or SMEMBERS.
+func Strings(reply interface{}, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply %T", reply)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		b, ok := item.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected redis array item %T", item)
+		}
+		out = append(out, string(b))
+	}
+	return out, nil
+}
+
+// Message is a message received on a subscribed channel.
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// Subscribe opens a dedicated connection subscribed to channels. Messages are delivered on the returned
+// channel, which is closed when ctx is done or the connection fails.
+func Subscribe(ctx context.Context, addr, password string, channels ...string) (<-chan Message, error) {
+	c, err := Dial(ctx, addr, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCommand(c.c, append([]string{"SUBSCRIBE"}, channels...)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	msgs := make(chan Message, 64)
+	go func() {
+		<-ctx.Done()
+		c.Close()
+	}()
+	go func() {
+		defer close(msgs)
+		for {
+			reply, err := readReply(c.r)
+			if err != nil {
+				return
+			}
+			// subscription confirmations are ["subscribe", channel, count]
+			parts, ok := reply.([]interface{})
+			if !ok || len(parts) != 3 {
+				continue
+			}
+			kind, _ := parts[0].([]byte)
+			channel, _ := parts[1].([]byte)
+			payload, _ := parts[2].([]byte)
+			if string(kind) != "message" {
+				continue
+			}
+			select {
+			case msgs <- Message{Channel: string(channel), Payload: payload}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return msgs, nil
+}
+
+func writeCommand(w io.Writer, args []string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, a := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(a)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, a...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("malformed redis reply")
+	}
+	return line[:len(line)-2], nil
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("malformed redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown redis reply type %q", line[0])
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestWriteCommand(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCommand(&buf, []string{"HSET", "k", "f", "v\r\n"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "*4\r\n$4\r\nHSET\r\n$1\r\nk\r\n$1\r\nf\r\n$3\r\nv\r\n\r\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	in := "+OK\r\n:42\r\n$5\r\nhello\r\n$-1\r\n*2\r\n$1\r\na\r\n:1\r\n-ERR nope\r\n"
+	r := bufio.NewReader(strings.NewReader(in))
+	for _, check := range []func(interface{}) bool{
+		func(v interface{}) bool { return v == "OK" },
+		func(v interface{}) bool { return v == int64(42) },
+		func(v interface{}) bool { b, ok := v.([]byte); return ok && string(b) == "hello" },
+		func(v interface{}) bool { return v == nil },
+		func(v interface{}) bool { a, ok := v.([]interface{}); return ok && len(a) == 2 && a[1] == int64(1) },
+		func(v interface{}) bool { return v == Error("ERR nope") },
+	} {
+		v, err := readReply(r)
+		if err != nil || !check(v) {
+			t.Fatalf("unexpected reply %#v, %v", v, err)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		r := bufio.NewReader(server)
+		for _, reply := range []string{"*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "-WRONGTYPE bad\r\n"} {
+			if _, err := readReply(r); err != nil {
+				return
+			}
+			server.Write([]byte(reply))
+		}
+	}()
+
+	c := NewConn(client)
+	got, err := Strings(c.Do("HGETALL", "k"))
+	if err != nil || len(got) != 2 || got[0] != "f" || got[1] != "v" {
+		t.Fatalf("expected [f v], got %v, %v", got, err)
+	}
+	var e Error
+	if _, err := c.Do("GET", "k"); !errors.As(err, &e) || !strings.HasPrefix(string(e), "WRONGTYPE") {
+		t.Fatalf("expected the error reply, got %v", err)
+	}
+}
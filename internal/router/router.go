@@ -0,0 +1,65 @@
+// internal/router/router.go
+
+// Package router is a thin layer over http.ServeMux's method and wildcard patterns (e.g.
+// "GET /lobby/ws/{lobby_id}") that adds middleware chains and versioned mounting.
+package router
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Middleware wraps a handler, e.g. middleware.LogMiddleware(logger) or middleware.RequireRole(role).
+type Middleware func(next http.Handler) http.Handler
+
+// Router registers routes on a shared ServeMux, wrapping each handler in the router's middleware chain.
+// The first middleware in the chain is the outermost.
+type Router struct {
+	mux        *http.ServeMux
+	middleware []Middleware
+}
+
+// New creates a Router with no routes or middleware.
+func New() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Use appends middleware to the chain. It only applies to routes registered afterwards.
+func (rt *Router) Use(mw ...Middleware) {
+	rt.middleware = append(rt.middleware, mw...)
+}
+
+// With returns a router that registers on the same mux, with mw appended to a copy of rt's chain.
+// Use it to put a group of routes behind extra middleware:
+//
+//	admin := rt.With(middleware.RequireRole(auth.RoleAdmin))
+//	admin.HandleFunc("/admin/season", handlers.AdminCreateSeasonHandler)
+func (rt *Router) With(mw ...Middleware) *Router {
+	return &Router{mux: rt.mux, middleware: append(slices.Clone(rt.middleware), mw...)}
+}
+
+// Handle registers h for pattern, wrapped in the router's middleware.
+func (rt *Router) Handle(pattern string, h http.Handler) {
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+	rt.mux.Handle(pattern, h)
+}
+
+// HandleFunc registers h for pattern, wrapped in the router's middleware.
+func (rt *Router) HandleFunc(pattern string, h http.HandlerFunc) {
+	rt.Handle(pattern, h)
+}
+
+// ServeHTTP dispatches the request to the matching route.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+// Mount serves h under prefix (e.g. "/v1") on mux, stripping the prefix so h's routes are registered
+// without it. Wildcards in h's patterns are matched against the stripped path, so r.PathValue works as usual.
+func Mount(mux *http.ServeMux, prefix string, h http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	mux.Handle(prefix+"/", http.StripPrefix(prefix, h))
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterMiddlewareAndMount(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	rt := New()
+	rt.Use(tag("log"))
+	rt.HandleFunc("GET /lobby/ws/{lobby_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("lobby_id")))
+	})
+	admin := rt.With(tag("admin"))
+	admin.HandleFunc("/admin/season", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("season"))
+	})
+
+	mux := http.NewServeMux()
+	Mount(mux, "/v1", rt)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/lobby/ws/abc", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "abc" {
+		t.Fatalf("expected path value abc, got %d %q", rec.Code, rec.Body.String())
+	}
+	if strings.Join(order, ",") != "log" {
+		t.Fatalf("expected only log middleware, got %v", order)
+	}
+
+	order = nil
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/admin/season", nil))
+	if rec.Body.String() != "season" || strings.Join(order, ",") != "log,admin" {
+		t.Fatalf("expected log then admin middleware, got %v (%q)", order, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lobby/ws/abc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected unversioned path to 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,72 @@
+// internal/season/scheduler.go
+package season
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jason-s-yu/cambia/internal/database"
+	"github.com/jason-s-yu/cambia/internal/models"
+)
+
+// RunScheduler finalizes ended seasons every interval until ctx is cancelled. When a season is finalized
+// and nothing has been scheduled after it, the next season of the same length is created automatically
+// unless SEASON_AUTO_ROLLOVER=false.
+func RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := tick(ctx); err != nil {
+			log.Printf("season scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func tick(ctx context.Context) error {
+	due, err := database.ListDueSeasons(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list due seasons: %w", err)
+	}
+	for _, s := range due {
+		if err := database.FinalizeSeason(ctx, s.ID); err != nil {
+			return fmt.Errorf("failed to finalize season %v: %w", s.ID, err)
+		}
+		log.Printf("finalized season %q (%v)", s.Name, s.ID)
+		if os.Getenv("SEASON_AUTO_ROLLOVER") != "false" {
+			rollover(ctx, s)
+		}
+	}
+	return nil
+}
+
+// rollover schedules the season following prev, unless one already exists.
+func rollover(ctx context.Context, prev models.Season) {
+	length := prev.EndsAt.Sub(prev.StartsAt)
+	next := &models.Season{
+		Name:     fmt.Sprintf("Season starting %s", prev.EndsAt.Format("2006-01-02")),
+		StartsAt: prev.EndsAt,
+		EndsAt:   prev.EndsAt.Add(length),
+	}
+	// catch up if the server was down for longer than a season
+	for !next.EndsAt.After(time.Now()) {
+		next.StartsAt, next.EndsAt = next.EndsAt, next.EndsAt.Add(length)
+	}
+	err := database.CreateSeason(ctx, next)
+	if errors.Is(err, database.ErrSeasonOverlap) {
+		return
+	}
+	if err != nil {
+		log.Printf("failed to roll over season %v: %v", prev.ID, err)
+		return
+	}
+	log.Printf("scheduled season %q (%v) from %v to %v", next.Name, next.ID, next.StartsAt, next.EndsAt)
+}
@@ -0,0 +1,240 @@
+package tournament
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/crash"
+)
+
+// Organizer actions recorded in the audit log.
+const (
+	AuditForceReport = "force_report"
+	AuditDisqualify  = "disqualify"
+	AuditPause       = "pause"
+	AuditResume      = "resume"
+	AuditExtendRound = "extend_round"
+)
+
+// AuditEntry records a single organizer action against a tournament.
+type AuditEntry struct {
+	At      time.Time              `json:"at"`
+	ActorID uuid.UUID              `json:"actorID"`
+	Action  string                 `json:"action"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// auditLocked appends an entry to the audit log. Callers must hold t.mu.
+func (t *Tournament) auditLocked(actorID uuid.UUID, action string, details map[string]interface{}) {
+	t.AuditLog = append(t.AuditLog, AuditEntry{At: time.Now(), ActorID: actorID, Action: action, Details: details})
+	log.Printf("tournament %v: %s by %v: %v", t.ID, action, actorID, details)
+}
+
+// Audit returns a copy of the tournament's audit log, oldest first.
+func (t *Tournament) Audit() []AuditEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]AuditEntry(nil), t.AuditLog...)
+}
+
+// currentRoundLocked returns the latest round if the tournament is in progress. Callers must hold t.mu.
+func (t *Tournament) currentRoundLocked() (*Round, error) {
+	if t.Status != StatusInProgress || len(t.Rounds) == 0 {
+		return nil, fmt.Errorf("tournament is not in progress")
+	}
+	return t.Rounds[len(t.Rounds)-1], nil
+}
+
+// ForceReport records a result for a table in the current round on the organizer's say-so, regardless of
+// the state of its game. winner is uuid.Nil for a draw. The table's game, if still running, is forced to
+// score; its own result is then ignored.
+func (t *Tournament) ForceReport(actorID uuid.UUID, table int, winner uuid.UUID) error {
+	t.mu.Lock()
+	round, err := t.currentRoundLocked()
+	if err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	var pairing *Pairing
+	for _, p := range round.Pairings {
+		if p.Table == table {
+			pairing = p
+			break
+		}
+	}
+	if pairing == nil {
+		t.mu.Unlock()
+		return fmt.Errorf("no table %d in round %d", table, round.Number)
+	}
+	if pairing.Reported {
+		t.mu.Unlock()
+		return fmt.Errorf("table %d already reported", table)
+	}
+	if winner != uuid.Nil && winner != pairing.PlayerA && winner != pairing.PlayerB {
+		t.mu.Unlock()
+		return fmt.Errorf("winner %v is not seated at table %d", winner, table)
+	}
+	t.applyResultLocked(pairing, winner)
+	t.auditLocked(actorID, AuditForceReport, map[string]interface{}{
+		"round":  round.Number,
+		"table":  table,
+		"gameID": pairing.GameID,
+		"winner": winner,
+	})
+	gameID, forceEnd := pairing.GameID, t.ForceEndFn
+	t.advanceIfRoundCompleteLocked(round)
+	t.mu.Unlock()
+
+	if gameID != uuid.Nil && forceEnd != nil {
+		forceEnd(gameID)
+	}
+	t.notify()
+	return nil
+}
+
+// Disqualify removes a player from the rest of the tournament. Any unreported game they're playing in the
+// current round is forfeited to their opponent, and they're no longer paired. Their results so far stand,
+// so their opponents' Buchholz is unaffected.
+func (t *Tournament) Disqualify(actorID, userID uuid.UUID, reason string) error {
+	t.mu.Lock()
+	p, ok := t.Players[userID]
+	if !ok {
+		t.mu.Unlock()
+		return fmt.Errorf("user %v is not registered", userID)
+	}
+	if p.Disqualified {
+		t.mu.Unlock()
+		return fmt.Errorf("user %v is already disqualified", userID)
+	}
+	p.Disqualified = true
+
+	var forfeited []uuid.UUID
+	if round, err := t.currentRoundLocked(); err == nil {
+		for _, pr := range round.Pairings {
+			if pr.Reported || (pr.PlayerA != userID && pr.PlayerB != userID) {
+				continue
+			}
+			opponent := pr.PlayerA
+			if opponent == userID {
+				opponent = pr.PlayerB
+			}
+			t.applyResultLocked(pr, opponent)
+			if pr.GameID != uuid.Nil {
+				forfeited = append(forfeited, pr.GameID)
+			}
+		}
+		t.auditLocked(actorID, AuditDisqualify, map[string]interface{}{"userID": userID, "reason": reason, "round": round.Number})
+		t.advanceIfRoundCompleteLocked(round)
+	} else {
+		t.auditLocked(actorID, AuditDisqualify, map[string]interface{}{"userID": userID, "reason": reason})
+	}
+	forceEnd := t.ForceEndFn
+	t.mu.Unlock()
+
+	for _, gameID := range forfeited {
+		if forceEnd != nil {
+			forceEnd(gameID)
+		}
+	}
+	t.notify()
+	return nil
+}
+
+// Pause stops the current round's clock and holds back pairing of the next round until Resume.
+// Games already in progress keep playing, and their results are still recorded.
+func (t *Tournament) Pause(actorID uuid.UUID) error {
+	t.mu.Lock()
+	round, err := t.currentRoundLocked()
+	if err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	if t.Paused {
+		t.mu.Unlock()
+		return fmt.Errorf("tournament is already paused")
+	}
+	t.Paused = true
+	if round.timer != nil {
+		round.timer.Stop()
+		round.timer = nil
+		round.remaining = time.Until(round.Deadline)
+	}
+	t.auditLocked(actorID, AuditPause, map[string]interface{}{"round": round.Number})
+	t.mu.Unlock()
+
+	t.notify()
+	return nil
+}
+
+// Resume restarts the round clock with the time that was left when it was paused, or pairs the next round
+// if the current one finished in the meantime.
+func (t *Tournament) Resume(actorID uuid.UUID) error {
+	t.mu.Lock()
+	round, err := t.currentRoundLocked()
+	if err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	if !t.Paused {
+		t.mu.Unlock()
+		return fmt.Errorf("tournament is not paused")
+	}
+	t.Paused = false
+	t.auditLocked(actorID, AuditResume, map[string]interface{}{"round": round.Number})
+	if round.Completed {
+		t.nextRoundOrCompleteLocked()
+	} else if t.RoundDuration > 0 {
+		round.Deadline = time.Now().Add(round.remaining)
+		t.startRoundClockLocked(round)
+	}
+	t.mu.Unlock()
+
+	t.notify()
+	return nil
+}
+
+// ExtendRound adds time to the current round's clock. If the tournament is paused, the extra time is
+// added to what will be left on resume.
+func (t *Tournament) ExtendRound(actorID uuid.UUID, extra time.Duration) error {
+	if extra <= 0 {
+		return fmt.Errorf("extension must be positive")
+	}
+	t.mu.Lock()
+	round, err := t.currentRoundLocked()
+	if err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	if round.Completed {
+		t.mu.Unlock()
+		return fmt.Errorf("round %d is already complete", round.Number)
+	}
+	if round.Deadline.IsZero() {
+		t.mu.Unlock()
+		return fmt.Errorf("round %d has no clock", round.Number)
+	}
+	if t.Paused {
+		round.remaining += extra
+	} else {
+		round.Deadline = round.Deadline.Add(extra)
+		t.startRoundClockLocked(round)
+	}
+	t.auditLocked(actorID, AuditExtendRound, map[string]interface{}{"round": round.Number, "seconds": extra.Seconds()})
+	t.mu.Unlock()
+
+	t.notify()
+	return nil
+}
+
+// startRoundClockLocked (re)arms the round's timer to fire at its deadline. Callers must hold t.mu.
+func (t *Tournament) startRoundClockLocked(round *Round) {
+	if round.timer != nil {
+		round.timer.Stop()
+	}
+	round.timer = time.AfterFunc(time.Until(round.Deadline), func() {
+		defer crash.Recover("tournament round clock")
+		t.expireRound(round)
+	})
+}
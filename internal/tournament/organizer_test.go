@@ -0,0 +1,125 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+// startTestTournament starts a tournament whose games are given sequential fake IDs.
+func startTestTournament(t *testing.T, n, rounds int, roundDuration time.Duration) (*Tournament, []*Player) {
+	t.Helper()
+	tour := NewSwissTournament(uuid.New(), "test", rounds, roundDuration, game.HouseRules{})
+	tour.CreateGameFn = func(_ *Tournament, _ *Pairing) (uuid.UUID, error) { return uuid.New(), nil }
+	players := newTestPlayers(n)
+	for _, p := range players {
+		tour.Players[p.UserID] = p
+	}
+	if err := tour.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	return tour, players
+}
+
+func TestDisqualifyForfeitsAndStopsPairing(t *testing.T) {
+	tour, players := startTestTournament(t, 4, 2, 0)
+	organizer := tour.OrganizerID
+	dq := players[0].UserID
+
+	if err := tour.Disqualify(organizer, dq, "no-show"); err != nil {
+		t.Fatalf("disqualify: %v", err)
+	}
+	for _, pr := range tour.Rounds[0].Pairings {
+		if (pr.PlayerA == dq || pr.PlayerB == dq) && (!pr.Reported || pr.Winner == dq) {
+			t.Errorf("expected the disqualified player's game to be forfeited, got %+v", pr)
+		}
+	}
+
+	// finish round 1; round 2 must not pair the disqualified player
+	for _, pr := range tour.Rounds[0].Pairings {
+		if !pr.Reported {
+			if err := tour.ForceReport(organizer, pr.Table, pr.PlayerA); err != nil {
+				t.Fatalf("force report: %v", err)
+			}
+		}
+	}
+	if len(tour.Rounds) != 2 {
+		t.Fatalf("expected round 2 to be paired, got %d rounds", len(tour.Rounds))
+	}
+	for _, pr := range tour.Rounds[1].Pairings {
+		if pr.PlayerA == dq || pr.PlayerB == dq {
+			t.Errorf("disqualified player paired in round 2: %+v", pr)
+		}
+	}
+	if got := tour.Standings(); got[len(got)-1].UserID != dq {
+		t.Errorf("expected the disqualified player last in standings, got %v", got[len(got)-1].UserID)
+	}
+	if got := len(tour.Audit()); got != 2 {
+		t.Errorf("expected 2 audit entries, got %d", got)
+	}
+}
+
+func TestPauseHoldsNextRound(t *testing.T) {
+	tour, _ := startTestTournament(t, 2, 2, time.Hour)
+	organizer := tour.OrganizerID
+
+	if err := tour.Pause(organizer); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if err := tour.ExtendRound(organizer, 10*time.Minute); err != nil {
+		t.Fatalf("extend: %v", err)
+	}
+	if err := tour.ForceReport(organizer, 1, uuid.Nil); err != nil {
+		t.Fatalf("force report: %v", err)
+	}
+	if len(tour.Rounds) != 1 {
+		t.Fatalf("expected the next round to wait while paused, got %d rounds", len(tour.Rounds))
+	}
+	if err := tour.Resume(organizer); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(tour.Rounds) != 2 {
+		t.Fatalf("expected round 2 after resume, got %d rounds", len(tour.Rounds))
+	}
+}
+
+func TestFailedGameCreationScoresADraw(t *testing.T) {
+	tour := NewSwissTournament(uuid.New(), "test", 2, 0, game.HouseRules{})
+	calls := 0
+	tour.CreateGameFn = func(_ *Tournament, _ *Pairing) (uuid.UUID, error) {
+		calls++
+		if calls == 1 {
+			return uuid.Nil, fmt.Errorf("transient")
+		}
+		return uuid.New(), nil
+	}
+	for _, p := range newTestPlayers(2) {
+		tour.Players[p.UserID] = p
+	}
+	if err := tour.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if pr := tour.Rounds[0].Pairings[0]; pr.GameID == uuid.Nil || pr.Reported {
+		t.Fatalf("expected the game to be created on a retry, got %+v", pr)
+	}
+
+	tour = NewSwissTournament(uuid.New(), "test", 2, 0, game.HouseRules{})
+	tour.CreateGameFn = func(_ *Tournament, _ *Pairing) (uuid.UUID, error) { return uuid.Nil, fmt.Errorf("down") }
+	for _, p := range newTestPlayers(2) {
+		tour.Players[p.UserID] = p
+	}
+	if err := tour.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if tour.Status != StatusCompleted || len(tour.Rounds) != 2 {
+		t.Fatalf("expected rounds without games not to stall the tournament, got %s after %d rounds", tour.Status, len(tour.Rounds))
+	}
+	for _, s := range tour.Standings() {
+		if s.Score != 2*PointsDraw {
+			t.Errorf("expected every table to be scored a draw, got %+v", s)
+		}
+	}
+}
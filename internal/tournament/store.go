@@ -0,0 +1,42 @@
+package tournament
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Store manages all active tournaments in memory, keyed by tournament ID.
+type Store struct {
+	mu          sync.Mutex
+	tournaments map[uuid.UUID]*Tournament
+}
+
+// NewStore creates and returns a new Store.
+func NewStore() *Store {
+	return &Store{
+		tournaments: make(map[uuid.UUID]*Tournament),
+	}
+}
+
+// Add adds a tournament to the store.
+func (s *Store) Add(t *Tournament) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tournaments[t.ID] = t
+}
+
+// Get retrieves a tournament by ID.
+func (s *Store) Get(id uuid.UUID) (*Tournament, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tournaments[id]
+	return t, ok
+}
+
+// Delete removes a tournament from the store.
+func (s *Store) Delete(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tournaments, id)
+}
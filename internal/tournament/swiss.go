@@ -0,0 +1,89 @@
+package tournament
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// swissSearchBudget caps how many partial pairings the rematch-free search tries before giving up and
+// allowing rematches, since a field where no rematch-free pairing exists can take exponentially long to
+// rule one out.
+const swissSearchBudget = 100000
+
+// swissPairings pairs the given players for the next Swiss round.
+//
+// Players are ordered by score (then seed), and each player is paired with the highest-ranked
+// remaining player they haven't already faced. If no rematch-free pairing is found within
+// swissSearchBudget steps, rematches are allowed as a last resort. When the field is odd, the lowest-ranked player who hasn't had a bye
+// receives one; the bye is returned separately (uuid.Nil if there is none).
+func swissPairings(players []*Player) (pairs [][2]uuid.UUID, bye uuid.UUID) {
+	ordered := make([]*Player, len(players))
+	copy(ordered, players)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Score != ordered[j].Score {
+			return ordered[i].Score > ordered[j].Score
+		}
+		return ordered[i].Seed < ordered[j].Seed
+	})
+
+	if len(ordered)%2 == 1 {
+		byeIdx := len(ordered) - 1
+		for i := len(ordered) - 1; i >= 0; i-- {
+			if !ordered[i].HadBye {
+				byeIdx = i
+				break
+			}
+		}
+		bye = ordered[byeIdx].UserID
+		ordered = append(ordered[:byeIdx:byeIdx], ordered[byeIdx+1:]...)
+	}
+
+	budget := swissSearchBudget
+	if res, ok := pairRecursive(ordered, false, &budget); ok {
+		return res, bye
+	}
+	// with rematches allowed, the first opponent tried always works, so this never backtracks
+	res, _ := pairRecursive(ordered, true, nil)
+	return res, bye
+}
+
+// pairRecursive backtracks over the ordered list, always pairing the first player with the
+// best-ranked compatible opponent. If allowRematch is false, previous opponents are skipped. If budget
+// isn't nil, each call spends one step of it, and the search fails once it runs out.
+func pairRecursive(ordered []*Player, allowRematch bool, budget *int) ([][2]uuid.UUID, bool) {
+	if len(ordered) == 0 {
+		return nil, true
+	}
+	if budget != nil {
+		if *budget <= 0 {
+			return nil, false
+		}
+		*budget--
+	}
+	top := ordered[0]
+	for i := 1; i < len(ordered); i++ {
+		opp := ordered[i]
+		if !allowRematch && top.hasPlayed(opp.UserID) {
+			continue
+		}
+		rest := make([]*Player, 0, len(ordered)-2)
+		rest = append(rest, ordered[1:i]...)
+		rest = append(rest, ordered[i+1:]...)
+		if sub, ok := pairRecursive(rest, allowRematch, budget); ok {
+			return append([][2]uuid.UUID{{top.UserID, opp.UserID}}, sub...), true
+		}
+	}
+	return nil, false
+}
+
+// buchholz returns the sum of the scores of every opponent a player has faced. Byes don't count.
+func buchholz(p *Player, players map[uuid.UUID]*Player) float64 {
+	var sum float64
+	for _, oppID := range p.Opponents {
+		if opp, ok := players[oppID]; ok {
+			sum += opp.Score
+		}
+	}
+	return sum
+}
@@ -0,0 +1,93 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+func newTestPlayers(n int) []*Player {
+	players := make([]*Player, n)
+	for i := range players {
+		players[i] = &Player{UserID: uuid.New(), Seed: i + 1}
+	}
+	return players
+}
+
+func TestSwissPairingsAvoidRematches(t *testing.T) {
+	players := newTestPlayers(4)
+	a, b, c, d := players[0], players[1], players[2], players[3]
+	// round 1 was a-b and c-d; a and c won
+	a.Opponents, b.Opponents = []uuid.UUID{b.UserID}, []uuid.UUID{a.UserID}
+	c.Opponents, d.Opponents = []uuid.UUID{d.UserID}, []uuid.UUID{c.UserID}
+	a.Score, c.Score = 1, 1
+
+	pairs, bye := swissPairings(players)
+	if bye != uuid.Nil {
+		t.Fatalf("expected no bye for an even field, got %v", bye)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(pairs))
+	}
+	for _, pr := range pairs {
+		p0 := map[uuid.UUID]*Player{a.UserID: a, b.UserID: b, c.UserID: c, d.UserID: d}[pr[0]]
+		if p0.hasPlayed(pr[1]) {
+			t.Errorf("rematch paired: %v vs %v", pr[0], pr[1])
+		}
+	}
+	if pairs[0] != [2]uuid.UUID{a.UserID, c.UserID} {
+		t.Errorf("expected the two leaders to meet on table 1, got %v", pairs[0])
+	}
+}
+
+func TestSwissPairingsFallBackToRematchesQuickly(t *testing.T) {
+	// the last player has faced everyone, so no rematch-free pairing exists, and proving it by
+	// exhaustive search would never finish
+	players := newTestPlayers(30)
+	last := players[len(players)-1]
+	for _, p := range players[:len(players)-1] {
+		last.Opponents = append(last.Opponents, p.UserID)
+		p.Opponents = []uuid.UUID{last.UserID}
+	}
+
+	pairs, bye := swissPairings(players)
+	if bye != uuid.Nil {
+		t.Fatalf("expected no bye for an even field, got %v", bye)
+	}
+	if len(pairs) != 15 {
+		t.Fatalf("expected 15 pairs, got %d", len(pairs))
+	}
+}
+
+func TestSwissPairingsByeGoesToLowestWithoutBye(t *testing.T) {
+	players := newTestPlayers(3)
+	players[0].Score = 1
+	players[2].HadBye = true
+	players[2].Score = 1
+
+	_, bye := swissPairings(players)
+	if bye != players[1].UserID {
+		t.Errorf("expected bye for the lowest-ranked player without one, got %v", bye)
+	}
+}
+
+func TestStandingsBuchholzTiebreak(t *testing.T) {
+	tour := NewSwissTournament(uuid.New(), "test", 2, 0, game.HouseRules{})
+	players := newTestPlayers(3)
+	for _, p := range players {
+		tour.Players[p.UserID] = p
+	}
+	// both 0 and 1 have 1 point, but 0 beat a stronger opponent
+	players[0].Score, players[1].Score, players[2].Score = 1, 1, 1
+	players[0].Opponents = []uuid.UUID{players[2].UserID}
+	players[2].Score = 1.5
+
+	standings := tour.Standings()
+	if standings[0].UserID != players[2].UserID {
+		t.Fatalf("expected the points leader first, got %v", standings[0].UserID)
+	}
+	if standings[1].UserID != players[0].UserID {
+		t.Errorf("expected Buchholz to break the tie in favour of player 0, got %v", standings[1].UserID)
+	}
+}
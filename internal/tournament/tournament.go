@@ -0,0 +1,392 @@
+// internal/tournament/tournament.go
+package tournament
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jason-s-yu/cambia/internal/game"
+)
+
+// Status is the lifecycle phase of a tournament.
+type Status string
+
+const (
+	StatusRegistration Status = "registration"
+	StatusInProgress   Status = "in_progress"
+	StatusCompleted    Status = "completed"
+)
+
+// Points awarded per game outcome.
+const (
+	PointsWin  = 1.0
+	PointsDraw = 0.5
+	PointsBye  = 1.0
+)
+
+// createGameAttempts is how many times a pairing's game is tried before the pairing is scored as a draw,
+// so a table whose game can't be created doesn't hold up the round.
+const createGameAttempts = 3
+
+// Player is a single entrant's running record within a tournament.
+type Player struct {
+	UserID    uuid.UUID   `json:"userID"`
+	Seed      int         `json:"seed"` // join order; used as the final tiebreak
+	Score     float64     `json:"score"`
+	Opponents []uuid.UUID `json:"opponents"`
+	HadBye    bool        `json:"hadBye"`
+	// Disqualified players keep their results but are no longer paired.
+	Disqualified bool `json:"disqualified"`
+}
+
+func (p *Player) hasPlayed(id uuid.UUID) bool {
+	for _, o := range p.Opponents {
+		if o == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Pairing is a single table in a round. PlayerB is uuid.Nil for a bye.
+type Pairing struct {
+	Table    int       `json:"table"`
+	PlayerA  uuid.UUID `json:"playerA"`
+	PlayerB  uuid.UUID `json:"playerB"`
+	GameID   uuid.UUID `json:"gameID"`
+	Reported bool      `json:"reported"`
+	Winner   uuid.UUID `json:"winner"` // uuid.Nil with Reported=true means a draw
+}
+
+// Round holds the pairings and clock for one Swiss round.
+type Round struct {
+	Number    int        `json:"number"`
+	Pairings  []*Pairing `json:"pairings"`
+	StartedAt time.Time  `json:"startedAt"`
+	Deadline  time.Time  `json:"deadline"`
+	Completed bool       `json:"completed"`
+
+	timer *time.Timer
+	// remaining is the time left on the clock while the tournament is paused.
+	remaining time.Duration
+}
+
+// Standing is a row in the published standings table.
+type Standing struct {
+	Rank         int       `json:"rank"`
+	UserID       uuid.UUID `json:"userID"`
+	Score        float64   `json:"score"`
+	Buchholz     float64   `json:"buchholz"`
+	Disqualified bool      `json:"disqualified,omitempty"`
+}
+
+// Tournament is an in-memory Swiss-system tournament of head-to-head games.
+type Tournament struct {
+	mu sync.Mutex
+
+	ID            uuid.UUID             `json:"id"`
+	Name          string                `json:"name"`
+	OrganizerID   uuid.UUID             `json:"organizerID"`
+	Format        string                `json:"format"` // currently only "swiss"
+	NumRounds     int                   `json:"numRounds"`
+	RoundDuration time.Duration         `json:"roundDuration"`
+	HouseRules    game.HouseRules       `json:"houseRules"`
+	Status        Status                `json:"status"`
+	Players       map[uuid.UUID]*Player `json:"players"`
+	Rounds        []*Round              `json:"rounds"`
+	// Paused holds the round clock and the pairing of the next round; see Pause.
+	Paused bool `json:"paused"`
+	// AuditLog records every organizer action taken against the tournament.
+	AuditLog []AuditEntry `json:"-"`
+
+	// CreateGameFn creates and starts a game for the given pairing, returning the game ID.
+	// The implementation must eventually call ReportResult for that game.
+	CreateGameFn func(t *Tournament, p *Pairing) (uuid.UUID, error) `json:"-"`
+	// ForceEndFn forces an unfinished game to score immediately, e.g. when the round clock expires.
+	ForceEndFn func(gameID uuid.UUID) `json:"-"`
+	// OnUpdate is called (with the tournament unlocked) whenever standings change or a round starts.
+	OnUpdate func(t *Tournament) `json:"-"`
+	// OnRoundStart is called once a round's games have been created. It runs with t.mu held, so it must
+	// not block or call back into the tournament.
+	OnRoundStart func(t *Tournament, round *Round) `json:"-"`
+}
+
+// NewSwissTournament creates a tournament in the registration phase.
+func NewSwissTournament(organizerID uuid.UUID, name string, numRounds int, roundDuration time.Duration, rules game.HouseRules) *Tournament {
+	id, _ := uuid.NewV7()
+	return &Tournament{
+		ID:            id,
+		Name:          name,
+		OrganizerID:   organizerID,
+		Format:        "swiss",
+		NumRounds:     numRounds,
+		RoundDuration: roundDuration,
+		HouseRules:    rules,
+		Status:        StatusRegistration,
+		Players:       make(map[uuid.UUID]*Player),
+	}
+}
+
+// Join registers a user. Joining is only allowed before the tournament starts.
+func (t *Tournament) Join(userID uuid.UUID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Status != StatusRegistration {
+		return fmt.Errorf("tournament registration is closed")
+	}
+	if _, ok := t.Players[userID]; ok {
+		return fmt.Errorf("user %v already registered", userID)
+	}
+	t.Players[userID] = &Player{UserID: userID, Seed: len(t.Players) + 1}
+	return nil
+}
+
+// Start closes registration and begins the first round.
+func (t *Tournament) Start() error {
+	t.mu.Lock()
+	if t.Status != StatusRegistration {
+		t.mu.Unlock()
+		return fmt.Errorf("tournament already started")
+	}
+	if len(t.Players) < 2 {
+		t.mu.Unlock()
+		return fmt.Errorf("need at least 2 players to start")
+	}
+	t.Status = StatusInProgress
+	t.startNextRoundLocked()
+	t.mu.Unlock()
+
+	t.notify()
+	return nil
+}
+
+// startNextRoundLocked pairs the next round, creates its games, and starts the round clock.
+// Callers must hold t.mu.
+func (t *Tournament) startNextRoundLocked() {
+	players := make([]*Player, 0, len(t.Players))
+	for _, p := range t.Players {
+		if !p.Disqualified {
+			players = append(players, p)
+		}
+	}
+	pairs, bye := swissPairings(players)
+
+	round := &Round{
+		Number:    len(t.Rounds) + 1,
+		StartedAt: time.Now(),
+	}
+	for i, pr := range pairs {
+		round.Pairings = append(round.Pairings, &Pairing{Table: i + 1, PlayerA: pr[0], PlayerB: pr[1]})
+	}
+	if bye != uuid.Nil {
+		p := t.Players[bye]
+		p.Score += PointsBye
+		p.HadBye = true
+		round.Pairings = append(round.Pairings, &Pairing{Table: len(pairs) + 1, PlayerA: bye, Reported: true, Winner: bye})
+	}
+	t.Rounds = append(t.Rounds, round)
+
+	for _, p := range round.Pairings {
+		if p.PlayerB == uuid.Nil || t.CreateGameFn == nil {
+			continue
+		}
+		for attempt := 1; attempt <= createGameAttempts; attempt++ {
+			gameID, err := t.CreateGameFn(t, p)
+			if err == nil {
+				p.GameID = gameID
+				break
+			}
+			log.Printf("tournament %v: failed to create game for table %d (attempt %d): %v", t.ID, p.Table, attempt, err)
+		}
+		if p.GameID == uuid.Nil {
+			// neither player is at fault, so the table is scored as a draw
+			t.applyResultLocked(p, uuid.Nil)
+		}
+	}
+
+	if t.RoundDuration > 0 {
+		round.Deadline = round.StartedAt.Add(t.RoundDuration)
+		t.startRoundClockLocked(round)
+	}
+	if t.OnRoundStart != nil {
+		t.OnRoundStart(t, round)
+	}
+	// every table may have been scored already, if none of the games could be created
+	t.advanceIfRoundCompleteLocked(round)
+}
+
+// expireRound forces every unfinished game in the round to score.
+func (t *Tournament) expireRound(round *Round) {
+	t.mu.Lock()
+	if round.Completed {
+		t.mu.Unlock()
+		return
+	}
+	var pending []uuid.UUID
+	for _, p := range round.Pairings {
+		if !p.Reported && p.GameID != uuid.Nil {
+			pending = append(pending, p.GameID)
+		}
+	}
+	forceEnd := t.ForceEndFn
+	t.mu.Unlock()
+
+	log.Printf("tournament %v: round %d clock expired, forcing %d games to score", t.ID, round.Number, len(pending))
+	for _, gameID := range pending {
+		if forceEnd != nil {
+			forceEnd(gameID)
+		}
+	}
+}
+
+// ReportResult records the outcome of a tournament game. winner is uuid.Nil for a draw.
+// Once every table in the current round has reported, the next round is paired automatically.
+func (t *Tournament) ReportResult(gameID uuid.UUID, winner uuid.UUID) error {
+	t.mu.Lock()
+	if t.Status != StatusInProgress || len(t.Rounds) == 0 {
+		t.mu.Unlock()
+		return fmt.Errorf("tournament is not in progress")
+	}
+	round := t.Rounds[len(t.Rounds)-1]
+	var pairing *Pairing
+	for _, p := range round.Pairings {
+		if p.GameID == gameID {
+			pairing = p
+			break
+		}
+	}
+	if pairing == nil {
+		t.mu.Unlock()
+		return fmt.Errorf("game %v is not part of the current round", gameID)
+	}
+	if pairing.Reported {
+		t.mu.Unlock()
+		return fmt.Errorf("result for game %v already reported", gameID)
+	}
+	if winner != uuid.Nil && winner != pairing.PlayerA && winner != pairing.PlayerB {
+		t.mu.Unlock()
+		return fmt.Errorf("winner %v did not play in game %v", winner, gameID)
+	}
+	t.applyResultLocked(pairing, winner)
+	t.advanceIfRoundCompleteLocked(round)
+	t.mu.Unlock()
+
+	t.notify()
+	return nil
+}
+
+// applyResultLocked awards points and records the opponents for a pairing.
+func (t *Tournament) applyResultLocked(p *Pairing, winner uuid.UUID) {
+	p.Reported = true
+	p.Winner = winner
+	a, b := t.Players[p.PlayerA], t.Players[p.PlayerB]
+	a.Opponents = append(a.Opponents, b.UserID)
+	b.Opponents = append(b.Opponents, a.UserID)
+	switch winner {
+	case uuid.Nil:
+		a.Score += PointsDraw
+		b.Score += PointsDraw
+	case a.UserID:
+		a.Score += PointsWin
+	case b.UserID:
+		b.Score += PointsWin
+	}
+}
+
+// advanceIfRoundCompleteLocked closes the round once all tables have reported, then either pairs
+// the next round or completes the tournament. While paused, the next round waits for Resume.
+func (t *Tournament) advanceIfRoundCompleteLocked(round *Round) {
+	for _, p := range round.Pairings {
+		if !p.Reported {
+			return
+		}
+	}
+	round.Completed = true
+	if round.timer != nil {
+		round.timer.Stop()
+	}
+	if t.Paused {
+		return
+	}
+	t.nextRoundOrCompleteLocked()
+}
+
+// nextRoundOrCompleteLocked pairs the next round, or completes the tournament after the last one.
+// Callers must hold t.mu.
+func (t *Tournament) nextRoundOrCompleteLocked() {
+	if len(t.Rounds) >= t.NumRounds {
+		t.Status = StatusCompleted
+		log.Printf("tournament %v completed after %d rounds", t.ID, len(t.Rounds))
+		return
+	}
+	t.startNextRoundLocked()
+}
+
+// Standings returns the current standings ordered by score, then Buchholz, then seed. Disqualified players
+// are listed last.
+func (t *Tournament) Standings() []Standing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.standingsLocked()
+}
+
+func (t *Tournament) standingsLocked() []Standing {
+	type row struct {
+		Standing
+		seed int
+	}
+	rows := make([]row, 0, len(t.Players))
+	for _, p := range t.Players {
+		rows = append(rows, row{
+			Standing: Standing{UserID: p.UserID, Score: p.Score, Buchholz: buchholz(p, t.Players), Disqualified: p.Disqualified},
+			seed:     p.Seed,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Disqualified != rows[j].Disqualified {
+			return !rows[i].Disqualified
+		}
+		if rows[i].Score != rows[j].Score {
+			return rows[i].Score > rows[j].Score
+		}
+		if rows[i].Buchholz != rows[j].Buchholz {
+			return rows[i].Buchholz > rows[j].Buchholz
+		}
+		return rows[i].seed < rows[j].seed
+	})
+	out := make([]Standing, len(rows))
+	for i, r := range rows {
+		r.Rank = i + 1
+		out[i] = r.Standing
+	}
+	return out
+}
+
+// Snapshot returns a JSON-friendly view of the tournament, including standings.
+func (t *Tournament) Snapshot() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return map[string]interface{}{
+		"id":            t.ID,
+		"name":          t.Name,
+		"organizerID":   t.OrganizerID,
+		"format":        t.Format,
+		"numRounds":     t.NumRounds,
+		"roundDuration": t.RoundDuration.Seconds(),
+		"status":        t.Status,
+		"paused":        t.Paused,
+		"rounds":        t.Rounds,
+		"standings":     t.standingsLocked(),
+	}
+}
+
+// notify invokes OnUpdate if set. Callers must not hold t.mu.
+func (t *Tournament) notify() {
+	if t.OnUpdate != nil {
+		t.OnUpdate(t)
+	}
+}
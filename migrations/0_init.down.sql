@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS ratings;
+DROP TABLE IF EXISTS game_results;
+DROP TABLE IF EXISTS game_actions;
+DROP TABLE IF EXISTS games;
+DROP TABLE IF EXISTS friends;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS set_updated_at();
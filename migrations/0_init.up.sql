@@ -108,11 +108,6 @@ BEFORE UPDATE ON friends
 FOR EACH ROW
 EXECUTE PROCEDURE set_updated_at();
 
-CREATE TRIGGER set_updated_at_lobbies
-BEFORE UPDATE ON lobbies
-FOR EACH ROW
-EXECUTE PROCEDURE set_updated_at();
-
 CREATE TRIGGER set_updated_at_games
 BEFORE UPDATE ON games
 FOR EACH ROW
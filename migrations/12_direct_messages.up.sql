@@ -0,0 +1,15 @@
+-- =================
+--  DIRECT MESSAGES
+-- =================
+-- Private messages between friends. IDs are UUIDv7 assigned by the server, so they sort by creation time.
+CREATE TABLE IF NOT EXISTS direct_messages (
+    id           UUID PRIMARY KEY,
+    sender_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body         TEXT NOT NULL,
+    read_at      TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_direct_messages_pair ON direct_messages (LEAST(sender_id, recipient_id), GREATEST(sender_id, recipient_id), id DESC);
+CREATE INDEX IF NOT EXISTS idx_direct_messages_unread ON direct_messages (recipient_id, sender_id) WHERE read_at IS NULL;
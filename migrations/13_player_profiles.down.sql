@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_game_actions_actor_type;
+DROP INDEX IF EXISTS idx_game_actions_game_index;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
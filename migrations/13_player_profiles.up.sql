@@ -0,0 +1,8 @@
+-- =================
+--  PLAYER PROFILES
+-- =================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+
+-- RecordGameAndResults writes each game's public event log once, in order.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_actions_game_index ON game_actions (game_id, action_index);
+CREATE INDEX IF NOT EXISTS idx_game_actions_actor_type ON game_actions (actor_user_id, action_type);
@@ -0,0 +1,13 @@
+-- ==============
+--  ACHIEVEMENTS
+-- ==============
+-- Progress towards each achievement (see internal/achievements). Rows appear once a game first advances
+-- an achievement; unlocked_at is set when progress reaches the achievement's target.
+CREATE TABLE IF NOT EXISTS user_achievements (
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    achievement_id TEXT NOT NULL,
+    progress       INTEGER NOT NULL DEFAULT 0,
+    unlocked_at    TIMESTAMP,
+    updated_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, achievement_id)
+);
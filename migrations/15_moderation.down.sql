@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_sanctions;
+DROP TABLE IF EXISTS player_reports;
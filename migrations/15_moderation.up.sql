@@ -0,0 +1,38 @@
+-- ============
+--  MODERATION
+-- ============
+-- Player reports, reviewed by moderators. chat_snapshot holds the lobby's recent chat when the report was made.
+CREATE TABLE IF NOT EXISTS player_reports (
+    id            UUID PRIMARY KEY,
+    reporter_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    offender_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason        TEXT NOT NULL,
+    lobby_id      UUID,                     -- lobbies live in memory, so not a foreign key
+    game_id       UUID,
+    chat_snapshot JSONB NOT NULL DEFAULT '[]'::jsonb,
+    status        TEXT NOT NULL DEFAULT 'open',  -- 'open', 'actioned', 'dismissed'
+    resolved_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolution    TEXT,
+    resolved_at   TIMESTAMP,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_reports_status ON player_reports (status, id);
+CREATE INDEX IF NOT EXISTS idx_player_reports_offender ON player_reports (offender_id);
+-- one open report per reporter and offender
+CREATE UNIQUE INDEX IF NOT EXISTS idx_player_reports_open_pair ON player_reports (reporter_id, offender_id) WHERE status = 'open';
+
+-- Mutes and bans issued by moderators. A NULL expires_at is permanent.
+CREATE TABLE IF NOT EXISTS user_sanctions (
+    id         UUID PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL,  -- 'mute' or 'ban'
+    reason     TEXT NOT NULL DEFAULT '',
+    issued_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+    report_id  UUID REFERENCES player_reports(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sanctions_user ON user_sanctions (user_id, kind);
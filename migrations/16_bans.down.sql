@@ -0,0 +1,3 @@
+ALTER TABLE user_sanctions DROP COLUMN IF EXISTS revoked_by;
+ALTER TABLE user_sanctions DROP COLUMN IF EXISTS appeal_notes;
+ALTER TABLE user_sanctions DROP COLUMN IF EXISTS scope;
@@ -0,0 +1,8 @@
+-- ======
+--  BANS
+-- ======
+-- Bans are the user_sanctions rows of kind 'ban'. A ban's scope says what it keeps the user out of:
+-- 'global' (signing in and every socket) or 'matchmaking' (the ranked queue only).
+ALTER TABLE user_sanctions ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT 'global';
+ALTER TABLE user_sanctions ADD COLUMN IF NOT EXISTS appeal_notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sanctions ADD COLUMN IF NOT EXISTS revoked_by UUID REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,7 @@
+-- ==================
+--  GAME CHECKPOINTS
+-- ==================
+-- The full state of an in-progress game as of the start of its latest turn, so the game can be restored
+-- after a crash. checkpoint_turn keeps a late write of an older turn from replacing a newer one.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS checkpoint JSONB;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS checkpoint_turn INTEGER;
@@ -0,0 +1,30 @@
+-- ===========
+--  AUDIT LOG
+-- ===========
+-- Privileged and destructive actions taken by admins and moderators. Rows are only ever appended: actor_id
+-- and target_id aren't foreign keys, so deleting a user leaves their history intact, and a trigger rejects
+-- updates and deletes.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          UUID PRIMARY KEY,
+    actor_id    UUID NOT NULL,
+    action      TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id   UUID,
+    payload     JSONB NOT NULL DEFAULT '{}'::jsonb,
+    request_id  TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_id, id DESC);
+
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION reject_audit_log_change();
@@ -0,0 +1,13 @@
+-- ================
+--  RULE TEMPLATES
+-- ================
+-- A rule template is a named bundle of house rules a user saved, to create lobbies from later.
+CREATE TABLE IF NOT EXISTS rule_templates (
+    id          UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    house_rules JSONB NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
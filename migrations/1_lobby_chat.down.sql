@@ -0,0 +1 @@
+DROP TABLE IF EXISTS lobby_chat_messages;
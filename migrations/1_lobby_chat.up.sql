@@ -0,0 +1,21 @@
+-- =====================
+--  LOBBY CHAT MESSAGES
+-- =====================
+-- Only written when PERSIST_LOBBY_CHAT=true. Lobbies live in memory, so lobby_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS lobby_chat_messages (
+    id         UUID PRIMARY KEY,
+    lobby_id   UUID NOT NULL,
+    user_id    UUID REFERENCES users(id) ON DELETE SET NULL,
+    msg        TEXT NOT NULL,
+    reactions  JSONB NOT NULL DEFAULT '{}'::jsonb, -- emoji => [user ids]
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lobby_chat_messages_lobby ON lobby_chat_messages (lobby_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_lobby_chat_messages_user ON lobby_chat_messages (user_id);
+
+CREATE TRIGGER set_updated_at_lobby_chat_messages
+BEFORE UPDATE ON lobby_chat_messages
+FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
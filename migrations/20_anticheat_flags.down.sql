@@ -0,0 +1 @@
+DROP TABLE IF EXISTS anticheat_flags;
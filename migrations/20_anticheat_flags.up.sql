@@ -0,0 +1,16 @@
+-- ===========
+--  ANTI-CHEAT
+-- ===========
+-- Play flagged by the game server as suggesting a player knew cards they never saw, for moderators to
+-- review. detail holds what was observed, e.g. the card snapped.
+CREATE TABLE IF NOT EXISTS anticheat_flags (
+    id         UUID PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    game_id    UUID NOT NULL,
+    kind       TEXT NOT NULL,  -- 'blind_snap' or 'blind_cambia'
+    turn       INT NOT NULL,
+    detail     JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_anticheat_flags_user ON anticheat_flags (user_id, id);
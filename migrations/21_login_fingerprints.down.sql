@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS smurf_signals;
+DROP TABLE IF EXISTS login_fingerprints;
@@ -0,0 +1,32 @@
+-- =====================
+--  MULTI-ACCOUNT SIGNALS
+-- =====================
+-- Where each login came from. ip_hash and device_hash are SHA-256 hex digests, so accounts can be matched
+-- up without keeping raw addresses. device_hash is NULL when the client sent no device ID.
+CREATE TABLE IF NOT EXISTS login_fingerprints (
+    id          UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_hash     TEXT NOT NULL,
+    device_hash TEXT,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_fingerprints_user ON login_fingerprints (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_fingerprints_ip ON login_fingerprints (ip_hash, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_fingerprints_device ON login_fingerprints (device_hash, created_at)
+    WHERE device_hash IS NOT NULL;
+
+-- Accounts that keep logging in from where a banned or much higher rated account does, for moderators to
+-- review. One row per pair and reason, refreshed on each login.
+CREATE TABLE IF NOT EXISTS smurf_signals (
+    id             UUID PRIMARY KEY,
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    linked_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason         TEXT NOT NULL,  -- 'banned_account' or 'higher_rated'
+    shared_logins  INT NOT NULL,
+    first_seen     TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen      TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, linked_user_id, reason)
+);
+
+CREATE INDEX IF NOT EXISTS idx_smurf_signals_user ON smurf_signals (user_id, id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS daily_challenge_results;
+DROP TABLE IF EXISTS daily_challenges;
@@ -0,0 +1,27 @@
+-- ==================
+--  DAILY CHALLENGES
+-- ==================
+-- Each day's challenge deal, shared by everyone who plays it. The seed is drawn the first time the day's
+-- challenge is asked for.
+CREATE TABLE IF NOT EXISTS daily_challenges (
+    day        DATE PRIMARY KEY,
+    seed       BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One attempt per player per day. A row is added when the attempt starts, so abandoning it doesn't allow
+-- another go at a deal the player has seen; score and turns are filled in when the game ends.
+CREATE TABLE IF NOT EXISTS daily_challenge_results (
+    day         DATE NOT NULL REFERENCES daily_challenges(day) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    game_id     UUID NOT NULL,
+    score       INT,
+    won         BOOLEAN NOT NULL DEFAULT FALSE,
+    turns       INT,
+    started_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP,
+    PRIMARY KEY (day, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_challenge_results_board ON daily_challenge_results (day, score, turns)
+    WHERE score IS NOT NULL;
@@ -0,0 +1,17 @@
+-- =========
+--  LOBBIES
+-- =========
+-- One row per lobby created, with its latest settings. Lobbies themselves live in memory and don't survive
+-- a restart; these rows are the record of them.
+CREATE TABLE IF NOT EXISTS lobbies (
+    id           UUID PRIMARY KEY,
+    host_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    type         TEXT NOT NULL,
+    game_mode    TEXT NOT NULL DEFAULT '',
+    ranked       BOOLEAN NOT NULL DEFAULT FALSE,
+    house_rules  JSONB NOT NULL,
+    settings     JSONB NOT NULL, -- circuit, series, and lobby settings
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lobbies_host ON lobbies (host_user_id, created_at);
@@ -0,0 +1,9 @@
+-- ==============
+--  USER ROLES
+-- ==============
+-- role supersedes is_admin; is_admin is kept in sync by SetUserRole for older readers.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'moderator', 'admin'));
+
+UPDATE users SET role = 'admin' WHERE is_admin AND role = 'user';
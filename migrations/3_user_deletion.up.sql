@@ -0,0 +1,5 @@
+-- ==================
+--  ACCOUNT DELETION
+-- ==================
+-- Deleted accounts keep their row (anonymized) so game_results, game_actions, and ratings stay consistent.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
@@ -0,0 +1,2 @@
+ALTER TABLE games DROP COLUMN IF EXISTS rules_revision;
+ALTER TABLE games DROP COLUMN IF EXISTS engine_version;
@@ -0,0 +1,7 @@
+-- =================
+--  GAME VERSIONING
+-- =================
+-- The engine version and rules revision a game was played under. Games recorded before this existed
+-- were all played under revision 1.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS engine_version TEXT;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS rules_revision INTEGER NOT NULL DEFAULT 1;
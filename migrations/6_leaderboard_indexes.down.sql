@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ratings_mode_user;
+DROP INDEX IF EXISTS idx_users_leaderboard_7p8p;
+DROP INDEX IF EXISTS idx_users_leaderboard_4p;
+DROP INDEX IF EXISTS idx_users_leaderboard_1v1;
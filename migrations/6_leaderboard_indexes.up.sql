@@ -0,0 +1,13 @@
+-- ==================
+--  LEADERBOARDS
+-- ==================
+-- Leaderboards page by (rating DESC, id) over registered, non-deleted users.
+CREATE INDEX IF NOT EXISTS idx_users_leaderboard_1v1 ON users (elo_1v1 DESC, id)
+    WHERE NOT is_ephemeral AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_leaderboard_4p ON users (elo_4p DESC, id)
+    WHERE NOT is_ephemeral AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_leaderboard_7p8p ON users (elo_7p8p DESC, id)
+    WHERE NOT is_ephemeral AND deleted_at IS NULL;
+
+-- Only users who have played a mode appear on its board.
+CREATE INDEX IF NOT EXISTS idx_ratings_mode_user ON ratings (rating_mode, user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE ratings DROP COLUMN IF EXISTS season_id;
+DROP TABLE IF EXISTS season_ratings;
+DROP TABLE IF EXISTS seasons;
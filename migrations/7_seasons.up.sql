@@ -0,0 +1,43 @@
+-- =========
+--  SEASONS
+-- =========
+CREATE TABLE IF NOT EXISTS seasons (
+    id            UUID PRIMARY KEY,
+    name          TEXT NOT NULL,
+    starts_at     TIMESTAMP NOT NULL,
+    ends_at       TIMESTAMP NOT NULL,
+    finalized_at  TIMESTAMP,              -- set once final ranks/rewards are computed and ratings reset
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_seasons_window ON seasons (starts_at, ends_at);
+
+-- Per-season rating per mode. Kept after the season ends so historical results stay queryable.
+CREATE TABLE IF NOT EXISTS season_ratings (
+    season_id     UUID NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating_mode   TEXT NOT NULL,
+    rating        INTEGER NOT NULL,
+    games_played  INTEGER NOT NULL DEFAULT 0,
+    final_rank    INTEGER,                -- set when the season is finalized, placed players only
+    reward_tier   TEXT,                   -- set when the season is finalized, placed players only
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (season_id, rating_mode, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_season_ratings_board ON season_ratings (season_id, rating_mode, rating DESC, user_id);
+
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS season_id UUID REFERENCES seasons(id) ON DELETE SET NULL;
+
+CREATE TRIGGER set_updated_at_seasons
+BEFORE UPDATE ON seasons
+FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
+
+CREATE TRIGGER set_updated_at_season_ratings
+BEFORE UPDATE ON season_ratings
+FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
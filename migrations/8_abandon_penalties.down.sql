@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS ranked_ban_until;
+ALTER TABLE users DROP COLUMN IF EXISTS queue_cooldown_until;
+DROP TABLE IF EXISTS game_abandons;
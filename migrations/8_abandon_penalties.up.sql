@@ -0,0 +1,17 @@
+-- ===================
+--  ABANDON PENALTIES
+-- ===================
+CREATE TABLE IF NOT EXISTS game_abandons (
+    id                UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    game_id           UUID NOT NULL,    -- in-memory game ID; the games row may not exist yet
+    rating_mode       TEXT,
+    rating_forfeited  INTEGER NOT NULL DEFAULT 0,
+    created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, game_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_abandons_user ON game_abandons (user_id, created_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS queue_cooldown_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ranked_ban_until TIMESTAMP;
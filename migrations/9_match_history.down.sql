@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS game_rounds;
+DROP INDEX IF EXISTS idx_games_end_time;
+DROP INDEX IF EXISTS idx_game_results_player;
+DROP INDEX IF EXISTS idx_game_results_game_player;
+ALTER TABLE games DROP COLUMN IF EXISTS ranked;
+ALTER TABLE games DROP COLUMN IF EXISTS rating_mode;
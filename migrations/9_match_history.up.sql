@@ -0,0 +1,22 @@
+-- ===============
+--  MATCH HISTORY
+-- ===============
+ALTER TABLE games ADD COLUMN IF NOT EXISTS rating_mode TEXT;   -- '1v1', '4p', '7p8p', or NULL if unrated
+ALTER TABLE games ADD COLUMN IF NOT EXISTS ranked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- RecordGameAndResults upserts on (game_id, player_id)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_results_game_player ON game_results (game_id, player_id);
+CREATE INDEX IF NOT EXISTS idx_game_results_player ON game_results (player_id);
+CREATE INDEX IF NOT EXISTS idx_games_end_time ON games (end_time DESC, id DESC);
+
+-- Per-round breakdown of a game: each player's score and revealed hand at the end of the round.
+CREATE TABLE IF NOT EXISTS game_rounds (
+    game_id        UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    round_index    SMALLINT NOT NULL,
+    player_id      UUID NOT NULL REFERENCES users(id),
+    score          INTEGER NOT NULL,
+    hand           JSONB,
+    called_cambia  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (game_id, round_index, player_id)
+);
@@ -0,0 +1,8 @@
+// Package migrations embeds the numbered SQL schema migrations, "{version}_{name}.up.sql" and the matching
+// ".down.sql" that reverts it. They're applied by database.MigrateUp.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS